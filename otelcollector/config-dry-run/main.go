@@ -0,0 +1,158 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	shared "github.com/prometheus-collector/shared"
+	configmapsettings "github.com/prometheus-collector/shared/configmap/mp"
+	"gopkg.in/yaml.v2"
+)
+
+type configMap struct {
+	Data map[string]string `yaml:"data"`
+}
+
+type envFlags map[string]string
+
+func (e envFlags) String() string {
+	return fmt.Sprint(map[string]string(e))
+}
+
+func (e envFlags) Set(value string) error {
+	key, val, found := strings.Cut(value, "=")
+	if !found {
+		return fmt.Errorf("expected KEY=VALUE, got %q", value)
+	}
+	e[key] = val
+	return nil
+}
+
+func readConfigMap(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cm configMap
+	if err := yaml.Unmarshal(contents, &cm); err != nil {
+		return nil, fmt.Errorf("parsing configmap %s: %w", path, err)
+	}
+	return cm.Data, nil
+}
+
+// output is where the rendered configs are printed, the config pipeline logs to os.Stdout
+var output io.Writer = os.Stdout
+
+func printYaml(title string, value interface{}) {
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		log.Fatalf("Error marshalling %s: %v", title, err)
+	}
+	fmt.Fprintf(output, "---\n# %s\n%s", title, out)
+}
+
+// Renders the merged prometheus config, the otel collector config and the target allocator config that the
+// agent would generate for the given configmaps, without needing a cluster. The config validator's receiver
+// validation is not run.
+func main() {
+	settingsPtr := flag.String("settings", "", "ama-metrics-settings-configmap yaml file path")
+	promConfigPtr := flag.String("prometheus-config", "", "ama-metrics-prometheus-config configmap yaml file path")
+	controllerTypePtr := flag.String("controller-type", "ReplicaSet", "Controller type: ReplicaSet or DaemonSet")
+	modePtr := flag.String("mode", "simple", "Mode: simple or advanced")
+	osTypePtr := flag.String("os", "linux", "OS type: linux or windows")
+	defaultPromConfigsPtr := flag.String("default-prom-configs", "configmapparser/default-prom-configs", "Default scrape configs directory")
+	defaultTargetsPtr := flag.String("default-targets", "", "Optional file adding default targets to the built-in ones")
+	ingestionProfilesPtr := flag.String("ingestion-profiles", "configmapparser/ingestion-profiles", "Ingestion profiles directory")
+	otelTemplatePtr := flag.String("otelTemplate", "opentelemetry-collector-builder/collector-config-template.yml", "OTel Collector config template file path")
+	extraEnv := envFlags{}
+	flag.Var(extraEnv, "env", "Additional agent environment variable as KEY=VALUE, can be repeated")
+	flag.Parse()
+
+	settings, err := readConfigMap(*settingsPtr)
+	if err != nil {
+		log.Fatalf("Error reading settings configmap: %v", err)
+	}
+	promConfigData, err := readConfigMap(*promConfigPtr)
+	if err != nil {
+		log.Fatalf("Error reading prometheus config configmap: %v", err)
+	}
+	// The other keys of the prometheus config configmap are merged as config fragments, the way the agent does
+	promConfigFragments := map[string]string{}
+	for key, contents := range promConfigData {
+		if key != "prometheus-config" {
+			promConfigFragments[key] = contents
+		}
+	}
+	otelTemplate, err := os.ReadFile(*otelTemplatePtr)
+	if err != nil {
+		log.Fatalf("Error reading otel config template: %v", err)
+	}
+
+	agentEnv := map[string]string{
+		"CONTROLLER_TYPE": *controllerTypePtr,
+		"MODE":            *modePtr,
+		"OS_TYPE":         *osTypePtr,
+	}
+	for key, value := range extraEnv {
+		agentEnv[key] = value
+	}
+
+	// The config pipeline logs to stdout, keep stdout for the rendered configs only
+	os.Stdout = os.Stderr
+	result, err := configmapsettings.DryRun(configmapsettings.DryRunOptions{
+		Settings:                  settings,
+		PrometheusConfig:          promConfigData["prometheus-config"],
+		PrometheusConfigFragments: promConfigFragments,
+		DefaultPromConfigsDir:     *defaultPromConfigsPtr,
+		DefaultTargetsFile:        *defaultTargetsPtr,
+		IngestionProfilesDir:      *ingestionProfilesPtr,
+		Env:                       agentEnv,
+	})
+	if err != nil {
+		log.Fatalf("Error running config pipeline: %v", err)
+	}
+
+	promConfig := result.MergedConfig
+	if promConfig == nil {
+		promConfig = result.DefaultsMergedConfig
+	}
+	if promConfig == nil {
+		log.Println("No custom config or default scrape configs enabled")
+		return
+	}
+	printYaml("Merged prometheus config", promConfig)
+	printYaml("Scrape job provenance", result.Provenance)
+
+	otelConfig, err := shared.GenerateOtelConfig(promConfig, otelTemplate,
+		result.Env["DEBUG_MODE_ENABLED"] == "true", result.Env["CCP_METRICS_ENABLED"] == "true")
+	if err != nil {
+		log.Fatalf("Error generating otel config: %v", err)
+	}
+	if err := shared.ApplyExternalLabels(otelConfig, result.ExternalLabels); err != nil {
+		log.Fatalf("Error adding external labels: %v", err)
+	}
+	if err := shared.ApplyCollectorProcessors(otelConfig, result.CollectorProcessors); err != nil {
+		log.Fatalf("Error adding collector processors: %v", err)
+	}
+	otelConfigYaml, err := yaml.Marshal(otelConfig)
+	if err != nil {
+		log.Fatalf("Error marshalling otel config: %v", err)
+	}
+	fmt.Fprintf(output, "---\n# OTel collector config\n%s", otelConfigYaml)
+
+	if strings.EqualFold(*controllerTypePtr, "ReplicaSet") {
+		taScrapeConfig, err := shared.GetTargetAllocatorScrapeConfig(otelConfigYaml)
+		if err != nil {
+			log.Fatalf("Error getting target allocator scrape config: %v", err)
+		}
+		shared.SetPrometheusExternalLabels(taScrapeConfig, result.ExternalLabels)
+		printYaml("Target allocator config", shared.NewTargetAllocatorConfig(taScrapeConfig, false, nil))
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"os"
+	"path/filepath"
 
 	certCreator "github.com/prometheus-collector/certcreator"
 	certGenerator "github.com/prometheus-collector/certgenerator"
@@ -64,71 +65,12 @@ func updateTAConfigFile(configFilePath string, httpsEnabled bool) {
 		logFatalError(fmt.Sprintf("config-reader::Unable to read file contents from: %s - %v\n", configFilePath, err))
 		os.Exit(1)
 	}
-	var promScrapeConfig map[string]interface{}
-	var otelConfig shared.OtelConfig
-	err = yaml.Unmarshal([]byte(defaultsMergedConfigFileContents), &otelConfig)
+	promScrapeConfig, err := shared.GetTargetAllocatorScrapeConfig(defaultsMergedConfigFileContents)
 	if err != nil {
 		logFatalError(fmt.Sprintf("config-reader::Unable to unmarshal merged otel configuration from: %s - %v\n", configFilePath, err))
 		os.Exit(1)
 	}
 
-	promScrapeConfig = otelConfig.Receivers.Prometheus.Config
-	// Removing $$ added for regex and replacement in relabel_config and metric_relabel_config added by promconfigvalidator.
-	// The $$ are required by the validator's otel get method, but the TA doesnt do env substitution and hence needs to be removed, else TA crashes.
-	scrapeConfigs := promScrapeConfig["scrape_configs"]
-	if scrapeConfigs != nil {
-		var sc = scrapeConfigs.([]interface{})
-		for _, scrapeConfig := range sc {
-			scrapeConfig := scrapeConfig.(map[interface{}]interface{})
-			if scrapeConfig["relabel_configs"] != nil {
-				relabelConfigs := scrapeConfig["relabel_configs"].([]interface{})
-				for _, relabelConfig := range relabelConfigs {
-					relabelConfig := relabelConfig.(map[interface{}]interface{})
-					//replace $$ with $ for regex field
-					if relabelConfig["regex"] != nil {
-						// Adding this check here since regex can be boolean and the conversion will fail
-						if _, isString := relabelConfig["regex"].(string); isString {
-							regexString := relabelConfig["regex"].(string)
-							modifiedRegexString := strings.ReplaceAll(regexString, "$$", "$")
-							relabelConfig["regex"] = modifiedRegexString
-						}
-					}
-					//replace $$ with $ for replacement field
-					if relabelConfig["replacement"] != nil {
-						replacement := relabelConfig["replacement"].(string)
-						modifiedReplacementString := strings.ReplaceAll(replacement, "$$", "$")
-						relabelConfig["replacement"] = modifiedReplacementString
-					}
-				}
-			}
-
-			if scrapeConfig["metric_relabel_configs"] != nil {
-				metricRelabelConfigs := scrapeConfig["metric_relabel_configs"].([]interface{})
-				for _, metricRelabelConfig := range metricRelabelConfigs {
-					metricRelabelConfig := metricRelabelConfig.(map[interface{}]interface{})
-					//replace $$ with $ for regex field
-					if metricRelabelConfig["regex"] != nil {
-						// Adding this check here since regex can be boolean and the conversion will fail
-						if _, isString := metricRelabelConfig["regex"].(string); isString {
-							regexString := metricRelabelConfig["regex"].(string)
-							modifiedRegexString := strings.ReplaceAll(regexString, "$$", "$")
-							metricRelabelConfig["regex"] = modifiedRegexString
-						}
-					}
-
-					//replace $$ with $ for replacement field
-					if metricRelabelConfig["replacement"] != nil {
-						replacement := metricRelabelConfig["replacement"].(string)
-						modifiedReplacementString := strings.ReplaceAll(replacement, "$$", "$")
-						metricRelabelConfig["replacement"] = modifiedReplacementString
-					}
-				}
-			}
-		}
-	}
-
-	var targetAllocatorConfig shared.Config
-
 	// Determine secrets access namespaces.
 	// Default: watch all namespaces for secrets (backward-compatible behavior).
 	// On Kubernetes >= 1.37: use the explicit configmap setting instead.
@@ -158,50 +100,25 @@ func updateTAConfigFile(configFilePath string, httpsEnabled bool) {
 		log.Printf("Kubernetes version %s < 1.37: watching all namespaces for secrets\n", kubeVersion)
 	}
 
-	if os.Getenv("AZMON_OPERATOR_HTTPS_ENABLED") == "true" && httpsEnabled {
+	taHttpsEnabled := os.Getenv("AZMON_OPERATOR_HTTPS_ENABLED") == "true" && httpsEnabled
+	if taHttpsEnabled {
 		fmt.Println("AZMON_OPERATOR_HTTPS_ENABLED is true, setting tls config in TargetAllocator")
-		targetAllocatorConfig = shared.Config{
-			AllocationStrategy: "consistent-hashing",
-			FilterStrategy:     "relabel-config",
-			CollectorSelector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"rsName":                         "ama-metrics",
-					"kubernetes.azure.com/managedby": "aks",
-				},
-			},
-			Config: promScrapeConfig,
-			PrometheusCR: shared.PrometheusCRConfig{
-				ServiceMonitorSelector:  &metav1.LabelSelector{},
-				PodMonitorSelector:      &metav1.LabelSelector{},
-				SecretsAccessNamespaces: secretsAccessNamespaces,
-			},
-			HTTPS: shared.HTTPSServerConfig{
-				Enabled:         true,
-				ListenAddr:      ":8443",
-				TLSCertFilePath: "/etc/operator-targets/server/certs/server.crt",
-				TLSKeyFilePath:  "/etc/operator-targets/server/certs/server.key",
-				CAFilePath:      "/etc/operator-targets/server/certs/ca.crt",
-			},
-		}
 	} else {
 		fmt.Println("AZMON_OPERATOR_HTTPS_ENABLED is not set/false or error in cert creation, not setting tls config in TargetAllocator")
-		targetAllocatorConfig = shared.Config{
-			AllocationStrategy: "consistent-hashing",
-			FilterStrategy:     "relabel-config",
-			CollectorSelector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"rsName":                         "ama-metrics",
-					"kubernetes.azure.com/managedby": "aks",
-				},
-			},
-			Config: promScrapeConfig,
-			PrometheusCR: shared.PrometheusCRConfig{
-				ServiceMonitorSelector:  &metav1.LabelSelector{},
-				PodMonitorSelector:      &metav1.LabelSelector{},
-				SecretsAccessNamespaces: secretsAccessNamespaces,
-			},
-		}
 	}
+	// The collectors receiving the targets add the external labels, the allocator config keeps them so it describes
+	// the same labels as the configs without the allocator
+	if externalLabels, err := shared.ReadExternalLabels(shared.ExternalLabelsPath); err != nil {
+		log.Printf("config-reader::Unable to read the external labels - %v\n", err)
+	} else {
+		shared.SetPrometheusExternalLabels(promScrapeConfig, externalLabels)
+	}
+	// The provenance of the jobs is copied first, so it is there when the allocator reloads the new config
+	taProvenanceFilePath := filepath.Join(filepath.Dir(taConfigFilePath), shared.TargetAllocatorConfigProvenanceFile)
+	if err := shared.CopyFile(shared.ConfigProvenancePath, taProvenanceFilePath); err != nil {
+		log.Printf("config-reader::Unable to copy the config provenance to %s - %v\n", taProvenanceFilePath, err)
+	}
+	targetAllocatorConfig := shared.NewTargetAllocatorConfig(promScrapeConfig, taHttpsEnabled, secretsAccessNamespaces)
 
 	targetAllocatorConfigYaml, _ := yaml.Marshal(targetAllocatorConfig)
 	if err := os.WriteFile(taConfigFilePath, targetAllocatorConfigYaml, 0644); err != nil {
@@ -579,7 +496,7 @@ func main() {
 		fmt.Println("Error starting inotify process:", err)
 	}
 	httpsEnabled := true
-	configmapsettings.Configmapparser()
+	configmapsettings.NewAgentConfigPipeline().Configmapparser()
 	// test
 	if os.Getenv("AZMON_OPERATOR_HTTPS_ENABLED") == "true" {
 		caErr, serErr, cliErr, serverSecretErr, clientSecretErr := createTLSCertificatesAndSecret()
@@ -619,6 +536,8 @@ func main() {
 			updateTAConfigFile("/opt/microsoft/otelcollector/collector-config-default.yml", httpsEnabled)
 		}
 	} else if _, err = os.Stat("/opt/microsoft/otelcollector/collector-config.yml"); err == nil {
+		// With a rejected custom config, this is the last known good config restored by the configmap parser, the TA
+		// config is generated from it so the TLS settings match the current certs
 		updateTAConfigFile("/opt/microsoft/otelcollector/collector-config.yml", httpsEnabled)
 	} else {
 		log.Println("No configs found via configmap, not running config reader")
@@ -95,6 +95,8 @@ func FLBPluginFlush(data unsafe.Pointer, length C.int, tag *C.char) int {
 		return PushInfiniteMetricLogToAppInsightsEvents(records)
 	case fluentbitExportingFailedTag:
 		return RecordExportingFailed(records)
+	case fluentbitConfigDiffTag:
+		return PushConfigDiffToAppInsightsEvents(records)
 	// Prometheus metrics from otelcollector, Prometheus UX, and targetallocator
 	case "prometheus.metrics.otelcollector", "prometheus.metrics.prometheus", "prometheus.metrics.targetallocator", "prometheus.metrics.volume":
 		return SendPrometheusMetricsToAppInsights(records, incomingTag)
@@ -10,6 +10,7 @@ import (
 	"github.com/microsoft/ApplicationInsights-Go/appinsights"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gopkg.in/yaml.v2"
 )
 
 var (
@@ -69,6 +70,15 @@ var (
 		[]string{"computer", "release", "controller_type", "error"},
 	)
 
+	// lastKnownGoodConfigMetric is 1 when the custom config was rejected and the last known good config is used instead
+	lastKnownGoodConfigMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "last_known_good_prometheus_config",
+			Help: "If the last known good custom prometheus config is used after the current one was rejected, with the config version used",
+		},
+		[]string{"computer", "release", "controller_type", "config_version"},
+	)
+
 	// otelcolExportFailuresMetric counts the number of times the otelcollector was unable to export
 	otelcolExportFailuresMetric = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -77,8 +87,23 @@ var (
 		},
 		[]string{"computer", "release", "controller_type"},
 	)
+
+	// ingestionProfileInfoMetric is 1 for the ingestion profile and version used for each default target
+	ingestionProfileInfoMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ingestion_profile_info",
+			Help: "Ingestion profile and version used for a default target",
+		},
+		[]string{"computer", "release", "controller_type", "target", "profile", "version"},
+	)
 )
 
+// selectedIngestionProfile is the ingestion profile used for a default target, as written by the configmap parser
+type selectedIngestionProfile struct {
+	Profile string `yaml:"profile"`
+	Version string `yaml:"version"`
+}
+
 const (
 	prometheusCollectorHealthInterval = 60
 	prometheusCollectorHealthPort = ":2234"
@@ -94,7 +119,9 @@ func ExposePrometheusCollectorHealthMetrics() {
 	r.MustRegister(timeseriesSentMetric)
 	r.MustRegister(bytesSentMetric)
 	r.MustRegister(invalidCustomConfigMetric)
+	r.MustRegister(lastKnownGoodConfigMetric)
 	r.MustRegister(otelcolExportFailuresMetric)
+	r.MustRegister(ingestionProfileInfoMetric)
 
 	handler := promhttp.HandlerFor(r, promhttp.HandlerOpts{})
 	http.Handle("/metrics", handler)
@@ -128,12 +155,22 @@ func ExposePrometheusCollectorHealthMetrics() {
 				invalidConfigErrorString = os.Getenv("INVALID_CONFIG_FATAL_ERROR")
 			}
 			invalidCustomConfigMetric.With(prometheus.Labels{"computer":CommonProperties["computer"], "release":CommonProperties["helmreleasename"], "controller_type":CommonProperties["controllertype"], "error":invalidConfigErrorString}).Set(float64(isInvalidCustomConfig))
+
+			isUsingLastKnownGoodConfig := 0
+			lastKnownGoodConfigVersion := ""
+			if os.Getenv("AZMON_USING_LAST_KNOWN_GOOD_CONFIG") == "true" {
+				isUsingLastKnownGoodConfig = 1
+				lastKnownGoodConfigVersion = os.Getenv("AZMON_LAST_KNOWN_GOOD_CONFIG_VERSION")
+			}
+			lastKnownGoodConfigMetric.With(prometheus.Labels{"computer":CommonProperties["computer"], "release":CommonProperties["helmreleasename"], "controller_type":CommonProperties["controllertype"], "config_version":lastKnownGoodConfigVersion}).Set(float64(isUsingLastKnownGoodConfig))
 		
 			OtelColExportingFailedMutex.Lock()
 			otelcolExportFailuresMetric.With(prometheus.Labels{"computer":CommonProperties["computer"], "release":CommonProperties["helmreleasename"], "controller_type":CommonProperties["controllertype"]}).Add(float64(OtelColExportFailureEventCount))
 			OtelColExportFailureEventCount = 0
 			OtelColExportingFailedMutex.Unlock()
 
+			setIngestionProfileInfoMetric()
+
 			lastTickerStart = time.Now()
 		}
 	}()
@@ -145,3 +182,21 @@ func ExposePrometheusCollectorHealthMetrics() {
 		TelemetryClient.Track(exception)
 	}
 }
+
+// setIngestionProfileInfoMetric sets the ingestion profile info metric from the ingestion profiles selected by the configmap parser
+func setIngestionProfileInfoMetric() {
+	contents, err := os.ReadFile(ingestionProfilesHashFilePath)
+	if err != nil {
+		return
+	}
+	var selectedProfiles map[string]selectedIngestionProfile
+	if err := yaml.Unmarshal(contents, &selectedProfiles); err != nil {
+		Log("Error while unmarshalling ingestion profiles hash file - %v\n", err)
+		return
+	}
+
+	ingestionProfileInfoMetric.Reset()
+	for target, selected := range selectedProfiles {
+		ingestionProfileInfoMetric.With(prometheus.Labels{"computer":CommonProperties["computer"], "release":CommonProperties["helmreleasename"], "controller_type":CommonProperties["controllertype"], "target":target, "profile":selected.Profile, "version":selected.Version}).Set(1)
+	}
+}
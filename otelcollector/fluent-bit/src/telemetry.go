@@ -46,6 +46,8 @@ var (
 	InvalidCustomPrometheusConfig string
 	// Default Collector config
 	DefaultPrometheusConfig string
+	// Config version of the last known good config used after the custom config was rejected
+	LastKnownGoodConfigVersion string
 	// Kubelet metrics keep list regex
 	KubeletKeepListRegex string
 	// CoreDNS metrics keep list regex
@@ -86,6 +88,10 @@ var (
 	NetworkObservabilityHubbleKeepListRegex string
 	// Network Observability Retina metrics keep list regex
 	NetworkObservabilityRetinaKeepListRegex string
+	// Metrics drop list regexes, keyed by default target name
+	MetricsDropListRegexes map[string]string
+	// Label drop list regexes, keyed by default target name
+	LabelDropListRegexes map[string]string
 
 	// Kubelet scrape interval
 	KubeletScrapeInterval string
@@ -181,8 +187,13 @@ const (
 	fluentbitContainerLogsTag             = "prometheus.log.prometheuscollectorcontainer"
 	fluentbitExportingFailedTag           = "prometheus.log.exportingfailed"
 	fluentbitFailedScrapeTag              = "prometheus.log.failedscrape"
+	fluentbitConfigDiffTag                = "prometheus.log.configdiff"
+	configDiffLogPrefix                   = "Effective scrape config changed: " // shared.ConfigDiffLogPrefix, which the plugin cannot import
 	keepListRegexHashFilePath             = "/opt/microsoft/configmapparser/config_def_targets_metrics_keep_list_hash"
 	intervalHashFilePath                  = "/opt/microsoft/configmapparser/config_def_targets_scrape_intervals_hash"
+	metricsDropListHashFilePath           = "/opt/microsoft/configmapparser/config_def_targets_metrics_drop_list_hash"
+	labelDropListHashFilePath             = "/opt/microsoft/configmapparser/config_def_targets_label_drop_list_hash"
+	ingestionProfilesHashFilePath         = "/opt/microsoft/configmapparser/config_def_targets_ingestion_profiles_hash"
 	basicAuthEnabled                      = "BasicAuthEnabled"
 	bearerTokenEnabledWithFile            = "BearerTokenEnabledWithFile"
 	bearerTokenEnabledWithSecret          = "BearerTokenEnabledWithSecret"
@@ -352,6 +363,9 @@ func InitializeTelemetryClient(agentVersion string) (int, error) {
 
 	InvalidCustomPrometheusConfig = os.Getenv("AZMON_INVALID_CUSTOM_PROMETHEUS_CONFIG")
 	DefaultPrometheusConfig = os.Getenv("AZMON_USE_DEFAULT_PROMETHEUS_CONFIG")
+	if os.Getenv("AZMON_USING_LAST_KNOWN_GOOD_CONFIG") == "true" {
+		LastKnownGoodConfigVersion = os.Getenv("AZMON_LAST_KNOWN_GOOD_CONFIG_VERSION")
+	}
 
 	// Reading regex hash file for telemetry
 	regexFileContents, err := ioutil.ReadFile(keepListRegexHashFilePath)
@@ -387,6 +401,10 @@ func InitializeTelemetryClient(agentVersion string) (int, error) {
 		}
 	}
 
+	// Reading drop list hash files for telemetry
+	MetricsDropListRegexes = readDropListHashFile(metricsDropListHashFilePath)
+	LabelDropListRegexes = readDropListHashFile(labelDropListHashFilePath)
+
 	// Reading scrape interval hash file for telemetry
 	intervalFileContents, err := ioutil.ReadFile(intervalHashFilePath)
 	if err != nil {
@@ -425,6 +443,22 @@ func InitializeTelemetryClient(agentVersion string) (int, error) {
 	return 0, nil
 }
 
+// readDropListHashFile reads the drop list regexes of the default targets, keyed by default target name
+func readDropListHashFile(filePath string) map[string]string {
+	dropListFileContents, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		Log("Error while opening drop list hash file %s - %v\n", filePath, err)
+		return nil
+	}
+	var dropListHash map[string]string
+	err = yaml.Unmarshal([]byte(dropListFileContents), &dropListHash)
+	if err != nil {
+		Log("Error while unmarshalling drop list hash file %s - %v\n", filePath, err)
+		return nil
+	}
+	return dropListHash
+}
+
 // Send count of cores/nodes attached to Application Insights periodically
 func SendCoreCountToAppInsightsMetrics() {
 	Log("Starting core count telemetry every %d seconds\n", coresAttachedTelemetryIntervalSeconds)
@@ -909,6 +943,9 @@ func PushMEProcessedAndReceivedCountToAppInsightsMetrics() {
 			if DefaultPrometheusConfig != "" {
 				metric.Properties["DefaultPrometheusConfig"] = DefaultPrometheusConfig
 			}
+			if LastKnownGoodConfigVersion != "" {
+				metric.Properties["LastKnownGoodConfigVersion"] = LastKnownGoodConfigVersion
+			}
 
 			if os.Getenv(envControllerType) == "ReplicaSet" {
 				if KubeletKeepListRegex != "" {
@@ -971,6 +1008,12 @@ func PushMEProcessedAndReceivedCountToAppInsightsMetrics() {
 				if NetworkObservabilityRetinaKeepListRegex != "" {
 					metric.Properties["NetworkObservabilityRetinaRegex"] = NetworkObservabilityRetinaKeepListRegex
 				}
+				for targetName, regex := range MetricsDropListRegexes {
+					metric.Properties[targetName+"MetricsDropListRegex"] = regex
+				}
+				for targetName, regex := range LabelDropListRegexes {
+					metric.Properties[targetName+"LabelDropListRegex"] = regex
+				}
 
 				if KubeletScrapeInterval != "" {
 					metric.Properties["KubeletScrapeInterval"] = KubeletScrapeInterval
@@ -1137,6 +1180,52 @@ func PushInfiniteMetricLogToAppInsightsEvents(records []map[interface{}]interfac
 	return output.FLB_OK
 }
 
+// configDiff is the diff of the effective scrape config logged by the configmap parser
+type configDiff struct {
+	ConfigVersion         string   `json:"configVersion"`
+	PreviousConfigVersion string   `json:"previousConfigVersion"`
+	Additions             []string `json:"additions"`
+	Removals              []string `json:"removals"`
+	Changes               []struct {
+		Job    string `json:"job"`
+		Fields []struct {
+			Field string `json:"field"`
+		} `json:"fields"`
+	} `json:"changes"`
+}
+
+func PushConfigDiffToAppInsightsEvents(records []map[interface{}]interface{}) int {
+	for _, record := range records {
+		_, diffJSON, found := strings.Cut(ToString(record["log"]), configDiffLogPrefix)
+		if !found {
+			continue
+		}
+		var diff configDiff
+		if err := json.Unmarshal([]byte(diffJSON), &diff); err != nil {
+			Log("Error parsing the effective config diff: %v", err)
+			continue
+		}
+
+		changes := []string{}
+		for _, change := range diff.Changes {
+			fields := []string{}
+			for _, field := range change.Fields {
+				fields = append(fields, field.Field)
+			}
+			changes = append(changes, fmt.Sprintf("%s(%s)", change.Job, strings.Join(fields, ",")))
+		}
+		event := appinsights.NewEventTelemetry("effectiveScrapeConfigChanged")
+		event.Properties["configVersion"] = diff.ConfigVersion
+		event.Properties["previousConfigVersion"] = diff.PreviousConfigVersion
+		event.Properties["jobsAdded"] = strings.Join(diff.Additions, ",")
+		event.Properties["jobsRemoved"] = strings.Join(diff.Removals, ",")
+		event.Properties["jobsChanged"] = strings.Join(changes, ",")
+		TelemetryClient.Track(event)
+	}
+
+	return output.FLB_OK
+}
+
 func RecordExportingFailed(records []map[interface{}]interface{}) int {
 	if strings.ToLower(os.Getenv(envPrometheusCollectorHealth)) == "true" {
 		OtelColExportingFailedMutex.Lock()
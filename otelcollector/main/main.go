@@ -1,12 +1,15 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
+	"sync"
 	"syscall"
 
 	shared "github.com/prometheus-collector/shared"
@@ -19,7 +22,6 @@ import (
 )
 
 func main() {
-
 	// Handle SIGTERM
 	go handleShutdown()
 
@@ -85,10 +87,11 @@ func main() {
 		}
 	}
 
+	agentConfig := configmapsettings.NewAgentConfigPipeline()
 	if ccpMetricsEnabled == "true" {
 		ccpconfigmapsettings.Configmapparserforccp()
 	} else {
-		configmapsettings.Configmapparser()
+		agentConfig.Configmapparser()
 	}
 
 	if ccpMetricsEnabled != "true" && osType == "linux" {
@@ -188,7 +191,9 @@ func main() {
 			collectorConfig = "/opt/microsoft/otelcollector/ccp-collector-config-replicaset.yml"
 		} else {
 			collectorConfig = "/opt/microsoft/otelcollector/collector-config-replicaset.yml"
-			configmapsettings.SetGlobalSettingsInCollectorConfig()
+			agentConfig.SetGlobalSettingsInCollectorConfig()
+			agentConfig.SetExternalLabelsInCollectorConfig()
+			agentConfig.SetCollectorProcessorsInCollectorConfig()
 		}
 	} else if azmonUseDefaultPrometheusConfig == "true" {
 		log.Println("Starting otelcollector with only default scrape configs enabled")
@@ -203,30 +208,25 @@ func main() {
 
 	log.Println("startCommand otelcollector")
 
-	if controllerType == "replicaset" {
-		if os.Getenv("AZMON_OPERATOR_HTTPS_ENABLED") == "true" {
-			_ = shared.CollectorTAHttpsCheck(collectorConfig)
-		} else if ccpMetricsEnabled != "true" {
-			_ = shared.RemoveHTTPSSettingsInCollectorConfig(collectorConfig)
-		}
-		_, err := shared.StartCommandWithOutputFile("/opt/microsoft/otelcollector/otelcollector", []string{"--config", collectorConfig}, "/opt/microsoft/otelcollector/collector-log.txt")
-		if err != nil {
-			log.Printf("Error starting otelcollector: %v\n", err)
-		}
-		if os.Getenv("AZMON_OPERATOR_HTTPS_ENABLED") == "true" {
-			// starting inotify here so that it doesnt restart when it is written the first time
-			outputFile := "/opt/inotifyoutput.txt"
-			if err = shared.Inotify(outputFile, "/etc/operator-targets/client/certs"); err != nil {
-				log.Printf("Error starting inotify for watching targetallocator certs: %v\n", err)
-			}
-		}
-	} else {
-		_, err := shared.StartCommandWithOutputFile("/opt/microsoft/otelcollector/otelcollector", []string{"--config", collectorConfig}, "/opt/microsoft/otelcollector/collector-log.txt")
-		if err != nil {
-			log.Printf("Error starting otelcollector: %v\n", err)
+	prepareCollectorConfig(controllerType, ccpMetricsEnabled, collectorConfig)
+	collectorPid, err := shared.StartCommandWithOutputFile("/opt/microsoft/otelcollector/otelcollector", []string{"--config", collectorConfig}, "/opt/microsoft/otelcollector/collector-log.txt")
+	if err != nil {
+		log.Printf("Error starting otelcollector: %v\n", err)
+	}
+	if controllerType == "replicaset" && os.Getenv("AZMON_OPERATOR_HTTPS_ENABLED") == "true" {
+		// starting inotify here so that it doesnt restart when it is written the first time
+		outputFile := "/opt/inotifyoutput.txt"
+		if err = shared.Inotify(outputFile, "/etc/operator-targets/client/certs"); err != nil {
+			log.Printf("Error starting inotify for watching targetallocator certs: %v\n", err)
 		}
 	}
 
+	// With the target allocator, the scrape configs are not part of the collector config in the replicaset
+	if osType == "linux" && ccpMetricsEnabled != "true" && !(controllerType == "replicaset" && azmonOperatorEnabled == "true") && collectorPid != 0 {
+		configReload.enabled = true
+		go watchConfigChanges(agentConfig, "/opt/inotifyoutput.txt", collectorConfig, collectorPid, controllerType, ccpMetricsEnabled)
+	}
+
 	if ccpMetricsEnabled != "true" {
 		log.Println("startCommand prometheusui")
 		shared.StartCommand("/opt/microsoft/otelcollector/prometheusui")
@@ -331,9 +331,112 @@ func main() {
 
 	// Expose a health endpoint for liveness probe
 	http.HandleFunc("/health", healthHandler)
+	// Expose where the scrape jobs of the merged config come from, for troubleshooting
+	http.HandleFunc("/explain", explainHandler)
 	http.ListenAndServe(":8080", nil)
 }
 
+// prepareCollectorConfig updates the TLS settings for the target allocator in the replicaset collector config.
+func prepareCollectorConfig(controllerType string, ccpMetricsEnabled string, collectorConfig string) {
+	if controllerType != "replicaset" {
+		return
+	}
+	if os.Getenv("AZMON_OPERATOR_HTTPS_ENABLED") == "true" {
+		_ = shared.CollectorTAHttpsCheck(collectorConfig)
+	} else if ccpMetricsEnabled != "true" {
+		_ = shared.RemoveHTTPSSettingsInCollectorConfig(collectorConfig)
+	}
+}
+
+// configReloadState is the outcome of the config reloads, reported by the health endpoint.
+type configReloadState struct {
+	mu sync.Mutex
+	// enabled is set when config changes are reloaded instead of restarting the container
+	enabled bool
+	// restartReason is set when a config change could not be reloaded
+	restartReason string
+	// rejectedMessage is set when the last config change was rejected and the previous config is still used
+	rejectedMessage string
+}
+
+var configReload configReloadState
+
+func (s *configReloadState) set(restartReason string, rejectedMessage string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.restartReason = restartReason
+	s.rejectedMessage = rejectedMessage
+}
+
+func (s *configReloadState) get() (restartReason string, rejectedMessage string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.restartReason, s.rejectedMessage
+}
+
+// watchConfigChanges reloads the otelcollector config when inotify reports a change to the settings or the
+// prometheus config, so that ME and mdsd keep running. When the change cannot be reloaded, the health endpoint
+// fails the liveness probe to restart the container as before.
+func watchConfigChanges(agentConfig *configmapsettings.AgentConfigPipeline, inotifyOutputFile string, collectorConfig string, collectorPid int, controllerType string, ccpMetricsEnabled string) {
+	// offset is the size of the inotify output already handled, inotify keeps appending the new events after it
+	var offset int64
+	for range time.Tick(15 * time.Second) {
+		size := inotifyOutputSize(inotifyOutputFile)
+		if size <= offset {
+			continue
+		}
+		offset = waitForConfigEventsToSettle(inotifyOutputFile, size)
+
+		log.Println("Config change detected, reloading the otelcollector config")
+		var rejectedErr *configmapsettings.ConfigRejectedError
+		err := agentConfig.ReloadConfig(collectorConfig)
+		if errors.As(err, &rejectedErr) {
+			shared.EchoError(err.Error())
+			configReload.set("", rejectedErr.Message)
+			continue
+		} else if err != nil {
+			configReload.set(err.Error(), "")
+			return
+		}
+
+		prepareCollectorConfig(controllerType, ccpMetricsEnabled, collectorConfig)
+		process, err := os.FindProcess(collectorPid)
+		if err == nil {
+			err = process.Signal(syscall.SIGHUP)
+		}
+		if err != nil {
+			configReload.set(fmt.Sprintf("signaling otelcollector to reload its config: %v", err), "")
+			return
+		}
+		log.Println("Signaled otelcollector to reload its config")
+		configReload.set("", "")
+	}
+}
+
+// configEventsSettleTime is how long inotify must report no new events before the config is reloaded, so that the
+// configmap volume has finished updating and a burst of events causes a single reload
+const configEventsSettleTime = 5 * time.Second
+
+// waitForConfigEventsToSettle waits until the inotify output stops growing and returns its size.
+func waitForConfigEventsToSettle(inotifyOutputFile string, size int64) int64 {
+	for {
+		time.Sleep(configEventsSettleTime)
+		newSize := inotifyOutputSize(inotifyOutputFile)
+		if newSize == size {
+			return size
+		}
+		size = newSize
+	}
+}
+
+func inotifyOutputSize(inotifyOutputFile string) int64 {
+	fileInfo, err := os.Stat(inotifyOutputFile)
+	if err != nil {
+		return 0
+	}
+	return fileInfo.Size()
+}
+
 // handleShutdown listens for SIGTERM signals and handles cleanup.
 func handleShutdown() {
 	shutdownChan := make(chan os.Signal, 1)
@@ -345,6 +448,32 @@ func handleShutdown() {
 	os.Exit(0) // Exit the application
 }
 
+// explainHandler returns the provenance of the scrape jobs of the merged prometheus config as JSON. A single job is
+// returned with the job query parameter.
+func explainHandler(w http.ResponseWriter, r *http.Request) {
+	provenance, err := shared.ReadConfigProvenance(shared.ConfigProvenancePath)
+	if err != nil {
+		log.Printf("Error reading the config provenance: %v\n", err)
+		http.Error(w, "Error reading the config provenance", http.StatusInternalServerError)
+		return
+	}
+
+	var response interface{} = provenance
+	if jobName := r.URL.Query().Get("job"); jobName != "" {
+		job := provenance.Job(jobName)
+		if job == nil {
+			http.Error(w, fmt.Sprintf("Job %s is not in the merged prometheus config", jobName), http.StatusNotFound)
+			return
+		}
+		response = job
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Error writing the config provenance: %v\n", err)
+	}
+}
+
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	osType := os.Getenv("OS_TYPE")
 	otlpEnabled := strings.ToLower(shared.GetEnv("AZMON_FULL_OTLP_ENABLED", "false")) == "true"
@@ -428,7 +557,14 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 			log.Println(message)
 			goto response
 		}
-		if shared.HasConfigChanged("/opt/inotifyoutput.txt") {
+		if configReload.enabled {
+			if restartReason, _ := configReload.get(); restartReason != "" {
+				status = http.StatusServiceUnavailable
+				message = "config changed and could not be reloaded: " + restartReason
+				log.Println(message)
+				goto response
+			}
+		} else if shared.HasConfigChanged("/opt/inotifyoutput.txt") {
 			status = http.StatusServiceUnavailable
 			message = "inotifyoutput.txt has been updated - config changed"
 			log.Println(message)
@@ -457,10 +593,17 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 		goto response
 	}
 
+	// A rejected config change keeps the liveness probe green since the previous config is still used, but is
+	// reported with its own status code
+	if _, rejectedMessage := configReload.get(); rejectedMessage != "" {
+		status = http.StatusAccepted
+		message = "prometheuscollector is running with the previous config, the config change was rejected: " + rejectedMessage
+	}
+
 response:
 	w.WriteHeader(status)
 	fmt.Fprintln(w, message)
-	if status != http.StatusOK {
+	if status >= http.StatusMultipleChoices {
 		log.Printf("Health check failed: %d, Message: %s\n", status, message)
 		shared.WriteTerminationLog(message)
 	}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusexporter"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/attributesprocessor"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/filterprocessor"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourceprocessor"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/transformprocessor"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/receiver/prometheusreceiver"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/exporter"
@@ -23,6 +25,8 @@ func components() (otelcol.Factories, error) {
 	batchProcessor := batchprocessor.NewFactory()
 	resourceProcessor := resourceprocessor.NewFactory()
 	filterProcessor := filterprocessor.NewFactory()
+	attributesProcessor := attributesprocessor.NewFactory()
+	transformProcessor := transformprocessor.NewFactory()
 
 	factories := otelcol.Factories{
 		Extensions: map[component.Type]extension.Factory{},
@@ -34,9 +38,11 @@ func components() (otelcol.Factories, error) {
 			promExporter.Type(): promExporter,
 		},
 		Processors: map[component.Type]processor.Factory{
-			batchProcessor.Type():    batchProcessor,
-			resourceProcessor.Type(): resourceProcessor,
-			filterProcessor.Type():   filterProcessor,
+			batchProcessor.Type():      batchProcessor,
+			resourceProcessor.Type():   resourceProcessor,
+			filterProcessor.Type():     filterProcessor,
+			attributesProcessor.Type(): attributesProcessor,
+			transformProcessor.Type():  transformProcessor,
 		},
 		Telemetry: otelconftelemetry.NewFactory(),
 	}
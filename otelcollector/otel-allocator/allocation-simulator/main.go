@@ -0,0 +1,117 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// allocation-simulator allocates a snapshot of targets with the allocation strategies of the Target Allocator, over
+// hypothetical collectors, to compare the strategies and the effect of scaling the collectors. It runs offline.
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-logr/logr"
+	"github.com/goccy/go-json"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/spf13/pflag"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/allocation"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/config"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/prehook"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/simulation"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
+)
+
+func main() {
+	flagSet := pflag.NewFlagSet("allocation-simulator", pflag.ExitOnError)
+	configFile := flagSet.String("config", "", "Target Allocator config file, for its fallback and job strategies and the relabel rules of its jobs. Optional.")
+	targetsFile := flagSet.String("targets", "", "JSON file with the targets to allocate, either the saved allocation state or a map of job names to the response of /jobs/:job_id/targets.")
+	collectors := flagSet.Int("collectors", 3, "Number of collectors to allocate the targets to.")
+	strategies := flagSet.StringSlice("strategies", nil, "Allocation strategies to simulate, all of them by default.")
+	output := flagSet.String("output", "text", "Output format, text or json.")
+	_ = flagSet.Parse(os.Args[1:])
+
+	if err := run(*configFile, *targetsFile, *collectors, *strategies, *output, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(configFile, targetsFile string, collectors int, strategies []string, output string, out io.Writer) error {
+	if targetsFile == "" {
+		return fmt.Errorf("the targets file is required")
+	}
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unsupported output format: %s", output)
+	}
+	targets, err := simulation.LoadTargets(targetsFile)
+	if err != nil {
+		return err
+	}
+	options := simulation.Options{Collectors: collectors, Strategies: strategies}
+
+	if configFile != "" {
+		cfg := config.CreateDefaultConfig()
+		if err = config.LoadFromFile(configFile, &cfg); err != nil {
+			return fmt.Errorf("failed to load the config %s: %w", configFile, err)
+		}
+		options.FallbackStrategy = cfg.AllocationFallbackStrategy
+		for _, js := range cfg.JobAllocationStrategies {
+			options.JobStrategies = append(options.JobStrategies, allocation.JobStrategy{JobName: js.JobName, Strategy: js.Strategy})
+		}
+		targets = filterTargets(cfg, targets)
+	}
+
+	reports, err := simulation.Run(logr.Discard(), targets, options)
+	if err != nil {
+		return err
+	}
+	if output == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	}
+	writeText(out, reports)
+	return nil
+}
+
+// filterTargets drops the targets like the Target Allocator does with the relabel rules of the jobs of the config.
+// The targets dumped from the Target Allocator were already kept by the same rules.
+func filterTargets(cfg config.Config, targets []*target.Item) []*target.Item {
+	if cfg.PromConfig == nil || cfg.FilterStrategy == "" {
+		return targets
+	}
+	hook := prehook.New(cfg.FilterStrategy, logr.Discard())
+	if hook == nil {
+		return targets
+	}
+	relabelCfg := map[string][]*relabel.Config{}
+	for _, scrapeConfig := range cfg.PromConfig.ScrapeConfigs {
+		relabelCfg[scrapeConfig.JobName] = scrapeConfig.RelabelConfigs
+	}
+	hook.SetConfig(relabelCfg)
+	return hook.Apply(targets)
+}
+
+func writeText(out io.Writer, reports []simulation.Report) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, report := range reports {
+		fmt.Fprintf(w, "Strategy %s: %d targets, %d collectors, %d unassigned\n", report.Strategy, report.Targets, report.Collectors, report.Unassigned)
+		fmt.Fprintf(w, "Moved when adding a collector: %d, when removing one: %d\n", report.MovedOnScaleUp, report.MovedOnScaleDown)
+		fmt.Fprintln(w, "COLLECTOR\tTARGETS")
+		for _, name := range slices.Sorted(maps.Keys(report.TargetsPerCollector)) {
+			fmt.Fprintf(w, "%s\t%d\n", name, report.TargetsPerCollector[name])
+		}
+		fmt.Fprintln(w, "JOB\tTARGETS\tCOLLECTORS\tMIN PER COLLECTOR\tMAX PER COLLECTOR")
+		for _, jobName := range slices.Sorted(maps.Keys(report.Jobs)) {
+			spread := report.Jobs[jobName]
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", jobName, spread.Targets, spread.Collectors, spread.MinPerCollector, spread.MaxPerCollector)
+		}
+		fmt.Fprintln(w, strings.Repeat("-", 40))
+	}
+	_ = w.Flush()
+}
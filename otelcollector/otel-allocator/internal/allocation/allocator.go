@@ -6,6 +6,7 @@ package allocation
 import (
 	"context"
 	"errors"
+	"fmt"
 	"maps"
 	"runtime"
 	"slices"
@@ -54,21 +55,45 @@ func newAllocator(log logr.Logger, strategy Strategy, opts ...Option) (Allocator
 	if err != nil {
 		return nil, err
 	}
+	weightPerCollector, err := meter.Float64Gauge("opentelemetry_allocator_weight_per_collector", metric.WithDescription("The total weight of the targets assigned to each collector."))
+	if err != nil {
+		return nil, err
+	}
+	collectorsDrained, err := meter.Int64Gauge("opentelemetry_allocator_collectors_drained", metric.WithDescription("Number of collectors not allocated to because of their heartbeats."))
+	if err != nil {
+		return nil, err
+	}
+	targetsRebalanced, err := meter.Int64Counter("opentelemetry_allocator_targets_rebalanced", metric.WithDescription("Number of targets moved to another collector because the target weights changed."))
+	if err != nil {
+		return nil, err
+	}
 
 	chAllocator := &allocator{
 		strategy:                      strategy,
 		collectors:                    make(map[string]*Collector),
+		watchedCollectors:             make(map[string]*Collector),
+		drainedCollectors:             make(map[string]*Collector),
+		heartbeats:                    make(map[string]CollectorHeartbeat),
 		targetItems:                   make(map[target.ItemHash]*target.Item),
 		targetItemsPerJobPerCollector: make(map[string]map[string]map[target.ItemHash]bool),
+		targetWeights:                 make(map[target.ItemHash]float64),
+		reportedWeights:               make(map[target.ItemHash]float64),
+		seededCollectors:              make(map[target.ItemHash]string),
+		strategyByJob:                 make(map[string]Strategy),
 		log:                           log,
 		targetsPerCollector:           targetsPerCollector,
 		collectorsAllocatable:         collectorsAllocatable,
 		timeToAssign:                  timeToAssign,
 		targetsRemaining:              targetsRemaining,
 		targetsUnassigned:             targetsUnassigned,
+		weightPerCollector:            weightPerCollector,
+		targetsRebalanced:             targetsRebalanced,
+		collectorsDrained:             collectorsDrained,
 	}
 	for _, opt := range opts {
-		opt(chAllocator)
+		if err := opt(chAllocator); err != nil {
+			return nil, err
+		}
 	}
 
 	return chAllocator, nil
@@ -77,10 +102,39 @@ func newAllocator(log logr.Logger, strategy Strategy, opts ...Option) (Allocator
 type allocator struct {
 	strategy Strategy
 
+	// fallbackStrategy is set on the strategy of the allocator and the strategies of the jobs
+	fallbackStrategy Strategy
+
+	// jobStrategies are the strategies of the jobs which are not allocated with the strategy of the allocator
+	jobStrategies []jobStrategy
+
+	// strategyByJob caches the strategy of each job with targets, until the job strategies change
+	// job name -> strategy
+	strategyByJob map[string]Strategy
+
+	// nodeTopology is the zone and region of the nodes, set on the topology aware strategies and the collectors
+	// node name -> topology
+	nodeTopology map[string]NodeTopology
+
+	// topologySpilloverRatio is set on the topology aware strategies, unless it is 0
+	topologySpilloverRatio float64
+
 	// collectors is a map from a Collector's name to a Collector instance
 	// collectorKey -> collector pointer
 	collectors map[string]*Collector
 
+	// watchedCollectors are all the collectors set with SetCollectors, including the drained ones
+	watchedCollectors map[string]*Collector
+
+	// drainedCollectors are the collectors not allocated to because of their heartbeats
+	drainedCollectors map[string]*Collector
+
+	// heartbeats is a map from a Collector's name to its last heartbeat
+	heartbeats map[string]CollectorHeartbeat
+
+	// healthConfig is set when collectors are drained based on their heartbeats
+	healthConfig *CollectorHealthConfig
+
 	// targetItems is a map from a target item's hash to the target items allocated state
 	// targetItem hash -> target item pointer
 	targetItems map[target.ItemHash]*target.Item
@@ -88,7 +142,26 @@ type allocator struct {
 	// collectorKey -> job -> target item hash -> true
 	targetItemsPerJobPerCollector map[string]map[string]map[target.ItemHash]bool
 
-	// m protects collectors, targetItems and targetItemsPerJobPerCollector for concurrent use.
+	// targetWeights is the weight each target is counted with in its collector's TotalWeight
+	// targetItem hash -> weight
+	targetWeights map[target.ItemHash]float64
+
+	// reportedWeights are the weights reported by the collectors, which take precedence over the target.WeightLabel
+	// targetItem hash -> weight
+	reportedWeights map[target.ItemHash]float64
+
+	// seededCollectors are the collectors the seeded targets were assigned to before a restart, until they are
+	// assigned again
+	// targetItem hash -> collector name
+	seededCollectors map[target.ItemHash]string
+
+	// ready is set once the targets were seeded or set from discovery
+	ready bool
+
+	// discovered is set once the targets were set from discovery
+	discovered bool
+
+	// m protects collectors, targetItems, targetItemsPerJobPerCollector, the weights and the seeds for concurrent use.
 	m sync.RWMutex
 
 	log logr.Logger
@@ -99,6 +172,9 @@ type allocator struct {
 	timeToAssign          metric.Float64Histogram
 	targetsRemaining      metric.Int64Gauge
 	targetsUnassigned     metric.Int64Gauge
+	weightPerCollector    metric.Float64Gauge
+	targetsRebalanced     metric.Int64Counter
+	collectorsDrained     metric.Int64Gauge
 }
 
 // SetFilter sets the filtering hook to use.
@@ -106,9 +182,22 @@ func (a *allocator) SetFilter(filter Filter) {
 	a.filter = filter
 }
 
-// SetFallbackStrategy sets the fallback strategy to use.
+// SetCollectorHealthConfig sets the limits above which collectors are drained of their targets.
+func (a *allocator) SetCollectorHealthConfig(cfg CollectorHealthConfig) {
+	a.healthConfig = &cfg
+}
+
+// SetFallbackStrategy sets the fallback strategy to use, for the strategy of the allocator and of the jobs.
 func (a *allocator) SetFallbackStrategy(strategy Strategy) {
-	a.strategy.SetFallbackStrategy(strategy)
+	a.m.Lock()
+	defer a.m.Unlock()
+	a.fallbackStrategy = strategy
+	if strategy != nil {
+		a.setTopology(strategy)
+	}
+	for _, s := range a.usedStrategies() {
+		s.SetFallbackStrategy(strategy)
+	}
 }
 
 // SetTargets accepts a list of targets that will be used to make
@@ -130,13 +219,115 @@ func (a *allocator) SetTargets(targets []*target.Item) {
 
 	a.m.Lock()
 	defer a.m.Unlock()
+	a.ready = true
+	a.discovered = true
 
 	// Check for target changes
 	targetsDiff := diff.Maps(a.targetItems, targetMap)
 	// If there are any additions or removals
 	if len(targetsDiff.Additions()) != 0 || len(targetsDiff.Removals()) != 0 {
 		a.handleTargets(targetsDiff)
+		a.reallocateTopologyAwareTargets()
+		a.pruneStrategyByJob()
+	}
+
+	// Targets keep their hash when their weight label changes
+	weightsChanged := false
+	for k, item := range targetMap {
+		if _, reported := a.reportedWeights[k]; !reported {
+			weightsChanged = a.updateTargetWeight(k, item.Weight()) || weightsChanged
+		}
 	}
+	if weightsChanged {
+		a.rebalance()
+	}
+}
+
+// SeedTargets sets the targets saved by this or another Target Allocator, with the collector each one was assigned
+// to, so that they can be served before discovery completes. The targets keep their collector as long as it is
+// allocatable. The next SetTargets removes the seeded targets that were not discovered, and keeps the seeded items of
+// the discovered ones with their collector, since a discovered target has the same hash as its seeded item. Seeding
+// again removes the targets missing from the new seed and moves the others to their new collector, which lets a
+// follower serve the allocation of the leader.
+func (a *allocator) SeedTargets(targets []*target.Item) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	// The discovered targets are more recent than the saved ones
+	if a.discovered {
+		return
+	}
+
+	targetMap := make(map[target.ItemHash]*target.Item, len(targets))
+	for _, item := range targets {
+		if item.CollectorName != "" {
+			a.seededCollectors[item.Hash()] = item.CollectorName
+		}
+		targetMap[item.Hash()] = item
+	}
+	a.handleTargets(diff.Maps(a.targetItems, targetMap))
+	// The targets seeded before keep their hash when they move to another collector
+	for hash := range targetMap {
+		if item, ok := a.targetItems[hash]; ok {
+			if _, seeded := a.seededCollectors[hash]; seeded {
+				if err := a.addTargetToTargetItems(item); err != nil {
+					a.log.Info("Could not assign seeded target", "job", item.JobName, "error", err)
+				}
+			}
+		}
+	}
+	a.ready = true
+}
+
+// Ready reports whether the targets were seeded or set from discovery at least once.
+func (a *allocator) Ready() bool {
+	a.m.RLock()
+	defer a.m.RUnlock()
+	return a.ready
+}
+
+// SetTargetWeights records the weights reported for the targets, replacing the weight from the target.WeightLabel.
+// A weight of 0 or less clears the reported weight. Targets which are not known are ignored.
+func (a *allocator) SetTargetWeights(weights []TargetWeight) {
+	begin := time.Now()
+	defer func() {
+		a.timeToAssign.Record(context.Background(), time.Since(begin).Seconds(), metric.WithAttributes(attribute.String("method", "SetTargetWeights"), attribute.String("strategy", a.strategy.GetName())))
+	}()
+
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	type targetKey struct{ jobName, targetURL string }
+	targetsByKey := make(map[targetKey][]*target.Item, len(a.targetItems))
+	for _, item := range a.targetItems {
+		key := targetKey{item.JobName, item.TargetURL}
+		targetsByKey[key] = append(targetsByKey[key], item)
+	}
+
+	weightsChanged := false
+	for _, weight := range weights {
+		for _, item := range targetsByKey[targetKey{weight.JobName, weight.TargetURL}] {
+			newWeight := weight.Weight
+			if newWeight > 0 {
+				a.reportedWeights[item.Hash()] = newWeight
+			} else {
+				delete(a.reportedWeights, item.Hash())
+				newWeight = item.Weight()
+			}
+			weightsChanged = a.updateTargetWeight(item.Hash(), newWeight) || weightsChanged
+		}
+	}
+	if weightsChanged {
+		a.rebalance()
+	}
+}
+
+// TargetWeights returns a copy of the weight of each target.
+func (a *allocator) TargetWeights() map[target.ItemHash]float64 {
+	a.m.RLock()
+	defer a.m.RUnlock()
+	targetWeightsCopy := make(map[target.ItemHash]float64, len(a.targetWeights))
+	maps.Copy(targetWeightsCopy, a.targetWeights)
+	return targetWeightsCopy
 }
 
 // SetCollectors sets the set of collectors with key=collectorName, value=Collector object.
@@ -147,7 +338,6 @@ func (a *allocator) SetCollectors(collectors map[string]*Collector) {
 		a.timeToAssign.Record(context.Background(), time.Since(begin).Seconds(), metric.WithAttributes(attribute.String("strategy", a.strategy.GetName()), attribute.String("method", "SetCollectors")))
 	}()
 
-	a.collectorsAllocatable.Record(context.Background(), int64(len(collectors)), metric.WithAttributes(attribute.String("strategy", a.strategy.GetName())))
 	if len(collectors) == 0 {
 		a.log.Info("No collector instances present")
 	}
@@ -155,8 +345,87 @@ func (a *allocator) SetCollectors(collectors map[string]*Collector) {
 	a.m.Lock()
 	defer a.m.Unlock()
 
+	a.watchedCollectors = collectors
+	a.reconcileCollectors()
+}
+
+// SetCollectorHeartbeat records the heartbeat of a collector, and drains the collectors which are unhealthy or
+// saturated when a CollectorHealthConfig is set.
+func (a *allocator) SetCollectorHeartbeat(collectorName string, heartbeat CollectorHeartbeat) error {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	if _, ok := a.watchedCollectors[collectorName]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownCollector, collectorName)
+	}
+	if heartbeat.Time.IsZero() {
+		heartbeat.Time = time.Now()
+	}
+	a.heartbeats[collectorName] = heartbeat
+	if c, ok := a.collectors[collectorName]; ok {
+		c.Heartbeat = heartbeat
+	}
+	if c, ok := a.drainedCollectors[collectorName]; ok {
+		c.Heartbeat = heartbeat
+	}
+
+	// Every heartbeat checks all the collectors, so that the ones which stopped sending them are drained too.
+	if a.healthConfig != nil {
+		a.reconcileCollectors()
+	}
+	return nil
+}
+
+// reconcileCollectors allocates to the watched collectors, except for the ones drained because of their heartbeats.
+// The caller of this method has to acquire a lock.
+func (a *allocator) reconcileCollectors() {
+	for name := range a.heartbeats {
+		if _, ok := a.watchedCollectors[name]; !ok {
+			delete(a.heartbeats, name)
+		}
+	}
+
+	now := time.Now()
+	allocatable := make(map[string]*Collector, len(a.watchedCollectors))
+	drainReasons := make(map[string]string)
+	for name, col := range a.watchedCollectors {
+		if a.healthConfig != nil {
+			if reason := a.healthConfig.drainReason(a.heartbeats[name], a.drainedCollectors[name], now); reason != "" {
+				drainReasons[name] = reason
+				continue
+			}
+		}
+		allocatable[name] = col
+	}
+	// Draining all the collectors would leave all the targets unassigned
+	if len(allocatable) == 0 && len(drainReasons) > 0 {
+		a.log.Info("All collectors are unhealthy or saturated, allocating to them anyway", "collectors", len(drainReasons))
+		allocatable, drainReasons = a.watchedCollectors, map[string]string{}
+	}
+
+	drainedCollectors := make(map[string]*Collector, len(drainReasons))
+	for name, reason := range drainReasons {
+		c, ok := a.drainedCollectors[name]
+		if !ok {
+			c = a.newCollector(a.watchedCollectors[name])
+			c.Heartbeat = a.heartbeats[name]
+			c.DrainedSince = now
+			a.log.Info("Draining collector", "collector", name, "reason", reason)
+		}
+		c.DrainReason = reason
+		drainedCollectors[name] = c
+	}
+	for name := range a.drainedCollectors {
+		if _, ok := drainedCollectors[name]; !ok {
+			a.log.Info("Collector recovered", "collector", name)
+		}
+	}
+	a.drainedCollectors = drainedCollectors
+	a.collectorsAllocatable.Record(context.Background(), int64(len(allocatable)), metric.WithAttributes(attribute.String("strategy", a.strategy.GetName())))
+	a.collectorsDrained.Record(context.Background(), int64(len(drainedCollectors)), metric.WithAttributes(attribute.String("strategy", a.strategy.GetName())))
+
 	// Check for collector changes
-	collectorsDiff := diff.Maps(a.collectors, collectors)
+	collectorsDiff := diff.Maps(a.collectors, allocatable)
 	if len(collectorsDiff.Additions()) != 0 || len(collectorsDiff.Removals()) != 0 {
 		a.handleCollectors(collectorsDiff)
 	}
@@ -190,12 +459,39 @@ func (a *allocator) TargetItems() map[target.ItemHash]*target.Item {
 	return targetItemsCopy
 }
 
-// Collectors returns a shallow copy of the collectors map.
+// NumTargetItems returns the number of target items, without copying them like TargetItems.
+func (a *allocator) NumTargetItems() int {
+	a.m.RLock()
+	defer a.m.RUnlock()
+	return len(a.targetItems)
+}
+
+// TargetItemsPerCollector returns the assigned target items of each collector, keyed by collector name and target
+// item hash. The assignments are read under the allocator lock, so they are consistent with each other, unlike the
+// CollectorName of the items of TargetItems which can change while being read.
+func (a *allocator) TargetItemsPerCollector() map[string]map[target.ItemHash]*target.Item {
+	a.m.RLock()
+	defer a.m.RUnlock()
+	targetItemsPerCollector := make(map[string]map[target.ItemHash]*target.Item, len(a.targetItemsPerJobPerCollector))
+	for collectorName, targetItemsPerJob := range a.targetItemsPerJobPerCollector {
+		targetItems := make(map[target.ItemHash]*target.Item)
+		for _, hashes := range targetItemsPerJob {
+			for hash := range hashes {
+				targetItems[hash] = a.targetItems[hash]
+			}
+		}
+		targetItemsPerCollector[collectorName] = targetItems
+	}
+	return targetItemsPerCollector
+}
+
+// Collectors returns a shallow copy of the collectors map, including the drained collectors.
 func (a *allocator) Collectors() map[string]*Collector {
 	a.m.RLock()
 	defer a.m.RUnlock()
 	collectorsCopy := make(map[string]*Collector)
 	maps.Copy(collectorsCopy, a.collectors)
+	maps.Copy(collectorsCopy, a.drainedCollectors)
 	return collectorsCopy
 }
 
@@ -239,11 +535,14 @@ func (a *allocator) handleTargets(diff diff.Changes[target.ItemHash, *target.Ite
 
 func (a *allocator) addTargetToTargetItems(tg *target.Item) error {
 	a.targetItems[tg.Hash()] = tg
+	if _, ok := a.targetWeights[tg.Hash()]; !ok {
+		a.targetWeights[tg.Hash()] = tg.Weight()
+	}
 	if len(a.collectors) == 0 {
 		return nil
 	}
 
-	colOwner, err := a.strategy.GetCollectorForTarget(a.collectors, tg)
+	colOwner, err := a.collectorForTarget(tg)
 	if err != nil {
 		return err
 	}
@@ -255,14 +554,78 @@ func (a *allocator) addTargetToTargetItems(tg *target.Item) error {
 		a.unassignTargetItem(tg)
 	}
 
-	tg.CollectorName = colOwner.Name
-	a.addCollectorTargetItemMapping(tg)
-	a.collectors[colOwner.Name].NumTargets++
-	a.collectors[colOwner.Name].TargetsPerJob[tg.JobName]++
-	a.targetsPerCollector.Record(context.Background(), int64(a.collectors[colOwner.String()].NumTargets), metric.WithAttributes(attribute.String("collector_name", colOwner.String()), attribute.String("strategy", a.strategy.GetName())))
+	a.assignTargetItem(tg, colOwner.Name)
 	return nil
 }
 
+// collectorForTarget returns the collector a seeded target was assigned to before a restart if it is still
+// allocatable, and the collector chosen by the strategy otherwise. A seed is only used once.
+func (a *allocator) collectorForTarget(tg *target.Item) (*Collector, error) {
+	if collectorName, ok := a.seededCollectors[tg.Hash()]; ok {
+		delete(a.seededCollectors, tg.Hash())
+		if c, ok := a.collectors[collectorName]; ok {
+			return c, nil
+		}
+	}
+	return a.strategyForJob(tg.JobName).GetCollectorForTarget(a.collectors, tg)
+}
+
+// assignTargetItem assigns an unassigned target item to the collector.
+func (a *allocator) assignTargetItem(tg *target.Item, collectorName string) {
+	c := a.collectors[collectorName]
+	tg.CollectorName = collectorName
+	a.addCollectorTargetItemMapping(tg)
+	c.NumTargets++
+	c.TargetsPerJob[tg.JobName]++
+	c.TotalWeight += a.targetWeights[tg.Hash()]
+	a.targetsPerCollector.Record(context.Background(), int64(c.NumTargets), metric.WithAttributes(attribute.String("collector_name", collectorName), attribute.String("strategy", a.strategy.GetName())))
+	a.weightPerCollector.Record(context.Background(), c.TotalWeight, metric.WithAttributes(attribute.String("collector_name", collectorName), attribute.String("strategy", a.strategy.GetName())))
+}
+
+// updateTargetWeight changes the weight a target is counted with, and reports whether it changed.
+func (a *allocator) updateTargetWeight(hash target.ItemHash, weight float64) bool {
+	item, ok := a.targetItems[hash]
+	if !ok || a.targetWeights[hash] == weight {
+		return false
+	}
+	if c, ok := a.collectors[item.CollectorName]; ok && item.CollectorName != "" {
+		c.TotalWeight += weight - a.targetWeights[hash]
+		a.weightPerCollector.Record(context.Background(), c.TotalWeight, metric.WithAttributes(attribute.String("collector_name", c.Name), attribute.String("strategy", a.strategy.GetName())))
+	}
+	a.targetWeights[hash] = weight
+	return true
+}
+
+// rebalance moves targets between collectors after a weight change, for strategies which support it. Each strategy
+// only moves the targets of the jobs it allocates.
+func (a *allocator) rebalance() {
+	for _, strategy := range a.usedStrategies() {
+		rebalancer, ok := strategy.(Rebalancer)
+		if !ok {
+			continue
+		}
+		targetItems := a.targetItems
+		if len(a.jobStrategies) > 0 {
+			targetItems = make(map[target.ItemHash]*target.Item, len(a.targetItems))
+			for hash, item := range a.targetItems {
+				if a.strategyForJob(item.JobName) == strategy {
+					targetItems[hash] = item
+				}
+			}
+		}
+		moves := rebalancer.Rebalance(a.collectors, targetItems, a.targetWeights)
+		for hash, collectorName := range moves {
+			item := a.targetItems[hash]
+			a.unassignTargetItem(item)
+			a.assignTargetItem(item, collectorName)
+		}
+		if len(moves) > 0 {
+			a.log.V(1).Info("Moved targets after a weight change", "targets", len(moves), "strategy", strategy.GetName())
+			a.targetsRebalanced.Add(context.Background(), int64(len(moves)), metric.WithAttributes(attribute.String("strategy", strategy.GetName())))
+		}
+	}
+}
+
 // unassignTargetItem unassigns the target item from its Collector. The target item is still tracked.
 func (a *allocator) unassignTargetItem(item *target.Item) {
 	collectorName := item.CollectorName
@@ -278,7 +641,13 @@ func (a *allocator) unassignTargetItem(item *target.Item) {
 	if c.TargetsPerJob[item.JobName] == 0 {
 		delete(c.TargetsPerJob, item.JobName)
 	}
+	c.TotalWeight -= a.targetWeights[item.Hash()]
+	if c.NumTargets == 0 {
+		// avoid accumulating floating point errors
+		c.TotalWeight = 0
+	}
 	a.targetsPerCollector.Record(context.Background(), int64(c.NumTargets), metric.WithAttributes(attribute.String("collector_name", item.CollectorName), attribute.String("strategy", a.strategy.GetName())))
+	a.weightPerCollector.Record(context.Background(), c.TotalWeight, metric.WithAttributes(attribute.String("collector_name", item.CollectorName), attribute.String("strategy", a.strategy.GetName())))
 	delete(a.targetItemsPerJobPerCollector[item.CollectorName][item.JobName], item.Hash())
 	if len(a.targetItemsPerJobPerCollector[item.CollectorName][item.JobName]) == 0 {
 		delete(a.targetItemsPerJobPerCollector[item.CollectorName], item.JobName)
@@ -290,6 +659,9 @@ func (a *allocator) unassignTargetItem(item *target.Item) {
 func (a *allocator) removeTargetItem(item *target.Item) {
 	a.unassignTargetItem(item)
 	delete(a.targetItems, item.Hash())
+	delete(a.targetWeights, item.Hash())
+	delete(a.reportedWeights, item.Hash())
+	delete(a.seededCollectors, item.Hash())
 }
 
 // removeCollector removes a Collector from the allocator.
@@ -303,6 +675,7 @@ func (a *allocator) removeCollector(collector *Collector) {
 	}
 	delete(a.targetItemsPerJobPerCollector, collector.Name)
 	a.targetsPerCollector.Record(context.Background(), 0, metric.WithAttributes(attribute.String("collector_name", collector.Name), attribute.String("strategy", a.strategy.GetName())))
+	a.weightPerCollector.Record(context.Background(), 0, metric.WithAttributes(attribute.String("collector_name", collector.Name), attribute.String("strategy", a.strategy.GetName())))
 }
 
 // addCollectorTargetItemMapping keeps track of which collector has which jobs and targets
@@ -328,11 +701,14 @@ func (a *allocator) handleCollectors(diff diff.Changes[string, *Collector]) {
 	}
 	// Insert the new collectors
 	for _, i := range diff.Additions() {
-		a.collectors[i.Name] = NewCollector(i.Name, i.NodeName)
+		a.collectors[i.Name] = a.newCollector(i)
+		a.collectors[i.Name].Heartbeat = a.heartbeats[i.Name]
 	}
 
-	// Set collectors on the strategy
-	a.strategy.SetCollectors(a.collectors)
+	// Set collectors on the strategies
+	for _, strategy := range a.usedStrategies() {
+		strategy.SetCollectors(a.collectors)
+	}
 
 	// Re-Allocate all targets
 	var assignmentErrors []error
@@ -343,6 +719,7 @@ func (a *allocator) handleCollectors(diff diff.Changes[string, *Collector]) {
 			item.CollectorName = ""
 		}
 	}
+	a.reallocateTopologyAwareTargets()
 	// Check for unassigned targets
 	unassignedTargets := len(assignmentErrors)
 	if unassignedTargets > 0 {
@@ -352,6 +729,16 @@ func (a *allocator) handleCollectors(diff diff.Changes[string, *Collector]) {
 	}
 }
 
+// newCollector returns a copy of a watched collector without targets, with the topology of its node when it is known.
+func (a *allocator) newCollector(col *Collector) *Collector {
+	c := NewCollector(col.Name, col.NodeName)
+	c.Zone, c.Region = col.Zone, col.Region
+	if topology, ok := a.nodeTopology[col.NodeName]; ok {
+		c.Zone, c.Region = topology.Zone, topology.Region
+	}
+	return c
+}
+
 const minChunkSize = 100 // for small target counts, it's not worth it to spawn a lot of goroutines
 
 // buildTargetMap builds a map of targets, using their hashes as keys. It does this concurrently, and the concurrency
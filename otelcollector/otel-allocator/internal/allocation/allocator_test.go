@@ -38,6 +38,24 @@ func TestSetTargets(t *testing.T) {
 	})
 }
 
+func TestTargetItemsPerCollector(t *testing.T) {
+	RunForAllStrategies(t, func(t *testing.T, allocator Allocator) {
+		allocator.SetCollectors(MakeNCollectors(3, 0))
+		allocator.SetTargets(MakeNNewTargetsWithEmptyCollectors(30, 0))
+
+		targetItemsPerCollector := allocator.TargetItemsPerCollector()
+		assigned := 0
+		for collectorName, targetItems := range targetItemsPerCollector {
+			for hash, item := range targetItems {
+				assert.Equal(t, collectorName, item.CollectorName)
+				assert.Equal(t, item, allocator.TargetItems()[hash])
+				assigned++
+			}
+		}
+		assert.Equal(t, 30, assigned)
+	})
+}
+
 func TestCanSetSingleTarget(t *testing.T) {
 	RunForAllStrategies(t, func(t *testing.T, allocator Allocator) {
 		cols := MakeNCollectors(3, 0)
@@ -0,0 +1,82 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package allocation
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnknownCollector is returned for a heartbeat from a collector the allocator does not know about.
+var ErrUnknownCollector = errors.New("unknown collector")
+
+// collectorRecoveryRatio is the fraction of a limit a drained collector has to get under to be allocated to again,
+// so that collectors close to a limit do not flap.
+const collectorRecoveryRatio = 0.9
+
+// CollectorHeartbeat is the state a collector reports to the target allocator on every sync.
+type CollectorHeartbeat struct {
+	// SeriesCount is the number of series of the last scrape of all the collector's targets.
+	SeriesCount int64 `json:"series_count"`
+	// Targets is the number of targets the collector scrapes.
+	Targets int `json:"targets"`
+	// ScrapeFailures is the number of targets whose last scrape failed.
+	ScrapeFailures int `json:"scrape_failures"`
+	// MemoryUsageRatio is the memory used by the collector relative to its memory limit, or 0 when it has none.
+	MemoryUsageRatio float64 `json:"memory_usage_ratio"`
+	// Time is when the heartbeat was received.
+	Time time.Time `json:"-"`
+}
+
+// CollectorHealthConfig holds the limits above which a collector is drained of its targets, based on its heartbeats.
+// A zero limit is not checked, and collectors which never sent a heartbeat are always allocated to.
+type CollectorHealthConfig struct {
+	// MaxSeries is the series count above which a collector is saturated.
+	MaxSeries int64
+	// MaxMemoryUsageRatio is the memory usage ratio above which a collector is saturated.
+	MaxMemoryUsageRatio float64
+	// MaxScrapeFailureRatio is the ratio of failed scrapes above which a collector is unhealthy.
+	MaxScrapeFailureRatio float64
+	// HeartbeatTimeout is how long after its last heartbeat a collector is considered unhealthy.
+	HeartbeatTimeout time.Duration
+	// RecoveryDelay is the minimum time a collector stays drained.
+	RecoveryDelay time.Duration
+}
+
+// WithCollectorHealth drains the collectors reporting heartbeats over the limits of cfg.
+func WithCollectorHealth(cfg CollectorHealthConfig) Option {
+	return func(allocator Allocator) error {
+		allocator.SetCollectorHealthConfig(cfg)
+		return nil
+	}
+}
+
+// drainReason returns why the collector should not be allocated to, or an empty string if it can be. drained is the
+// state of the collector if it is currently drained.
+func (c CollectorHealthConfig) drainReason(heartbeat CollectorHeartbeat, drained *Collector, now time.Time) string {
+	if heartbeat.Time.IsZero() {
+		return ""
+	}
+	margin := 1.0
+	if drained != nil {
+		if now.Sub(drained.DrainedSince) < c.RecoveryDelay {
+			return drained.DrainReason
+		}
+		margin = collectorRecoveryRatio
+	}
+
+	switch {
+	case c.HeartbeatTimeout > 0 && now.Sub(heartbeat.Time) > c.HeartbeatTimeout:
+		return fmt.Sprintf("no heartbeat since %s", heartbeat.Time.Format(time.RFC3339))
+	case c.MaxSeries > 0 && float64(heartbeat.SeriesCount) > float64(c.MaxSeries)*margin:
+		return fmt.Sprintf("%d series over the limit of %d", heartbeat.SeriesCount, c.MaxSeries)
+	case c.MaxMemoryUsageRatio > 0 && heartbeat.MemoryUsageRatio > c.MaxMemoryUsageRatio*margin:
+		return fmt.Sprintf("memory usage ratio %.2f over the limit of %.2f", heartbeat.MemoryUsageRatio, c.MaxMemoryUsageRatio)
+	case c.MaxScrapeFailureRatio > 0 && heartbeat.Targets > 0 &&
+		float64(heartbeat.ScrapeFailures)/float64(heartbeat.Targets) > c.MaxScrapeFailureRatio*margin:
+		return fmt.Sprintf("%d of %d scrapes failed", heartbeat.ScrapeFailures, heartbeat.Targets)
+	}
+	return ""
+}
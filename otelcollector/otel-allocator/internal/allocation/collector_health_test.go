@@ -0,0 +1,151 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package allocation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectorHealthDrainsSaturatedCollector(t *testing.T) {
+	s, err := New("consistent-hashing", logger, WithCollectorHealth(CollectorHealthConfig{MaxSeries: 1000}))
+	require.NoError(t, err)
+
+	s.SetCollectors(MakeNCollectors(3, 0))
+	s.SetTargets(MakeNNewTargetsWithEmptyCollectors(30, 0))
+
+	require.NoError(t, s.SetCollectorHeartbeat("collector-0", CollectorHeartbeat{SeriesCount: 2000}))
+	collector := s.Collectors()["collector-0"]
+	require.NotNil(t, collector)
+	assert.Equal(t, 0, collector.NumTargets)
+	assert.Contains(t, collector.DrainReason, "2000 series")
+	assert.False(t, collector.DrainedSince.IsZero())
+	for _, item := range s.TargetItems() {
+		assert.NotEqual(t, "collector-0", item.CollectorName)
+	}
+
+	// healthy heartbeats do not drain
+	require.NoError(t, s.SetCollectorHeartbeat("collector-1", CollectorHeartbeat{SeriesCount: 500}))
+	assert.Empty(t, s.Collectors()["collector-1"].DrainReason)
+	assert.Equal(t, int64(500), s.Collectors()["collector-1"].Heartbeat.SeriesCount)
+}
+
+func TestCollectorHealthRecovery(t *testing.T) {
+	s, err := New("consistent-hashing", logger, WithCollectorHealth(CollectorHealthConfig{MaxSeries: 1000}))
+	require.NoError(t, err)
+
+	s.SetCollectors(MakeNCollectors(2, 0))
+	s.SetTargets(MakeNNewTargetsWithEmptyCollectors(20, 0))
+
+	require.NoError(t, s.SetCollectorHeartbeat("collector-0", CollectorHeartbeat{SeriesCount: 2000}))
+	assert.NotEmpty(t, s.Collectors()["collector-0"].DrainReason)
+
+	// just under the limit is not enough to recover
+	require.NoError(t, s.SetCollectorHeartbeat("collector-0", CollectorHeartbeat{SeriesCount: 950}))
+	assert.NotEmpty(t, s.Collectors()["collector-0"].DrainReason)
+
+	require.NoError(t, s.SetCollectorHeartbeat("collector-0", CollectorHeartbeat{SeriesCount: 100}))
+	collector := s.Collectors()["collector-0"]
+	assert.Empty(t, collector.DrainReason)
+	assert.NotZero(t, collector.NumTargets)
+}
+
+func TestCollectorHealthRecoveryDelay(t *testing.T) {
+	cfg := CollectorHealthConfig{MaxSeries: 1000, RecoveryDelay: time.Minute}
+	now := time.Now()
+	heartbeat := CollectorHeartbeat{SeriesCount: 100, Time: now}
+
+	drained := &Collector{DrainReason: "saturated", DrainedSince: now.Add(-30 * time.Second)}
+	assert.Equal(t, "saturated", cfg.drainReason(heartbeat, drained, now))
+
+	drained.DrainedSince = now.Add(-2 * time.Minute)
+	assert.Empty(t, cfg.drainReason(heartbeat, drained, now))
+}
+
+func TestCollectorHealthDrainReasons(t *testing.T) {
+	cfg := CollectorHealthConfig{
+		MaxSeries:             1000,
+		MaxMemoryUsageRatio:   0.8,
+		MaxScrapeFailureRatio: 0.5,
+		HeartbeatTimeout:      time.Minute,
+	}
+	now := time.Now()
+	tests := []struct {
+		name      string
+		heartbeat CollectorHeartbeat
+		expected  string
+	}{
+		{
+			name:      "no heartbeat",
+			heartbeat: CollectorHeartbeat{},
+			expected:  "",
+		},
+		{
+			name:      "healthy",
+			heartbeat: CollectorHeartbeat{SeriesCount: 10, Targets: 4, ScrapeFailures: 1, MemoryUsageRatio: 0.2, Time: now},
+			expected:  "",
+		},
+		{
+			name:      "heartbeat timed out",
+			heartbeat: CollectorHeartbeat{Time: now.Add(-2 * time.Minute)},
+			expected:  "no heartbeat since",
+		},
+		{
+			name:      "memory pressure",
+			heartbeat: CollectorHeartbeat{MemoryUsageRatio: 0.95, Time: now},
+			expected:  "memory usage ratio 0.95",
+		},
+		{
+			name:      "scrape failures",
+			heartbeat: CollectorHeartbeat{Targets: 4, ScrapeFailures: 3, Time: now},
+			expected:  "3 of 4 scrapes failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := cfg.drainReason(tt.heartbeat, nil, now)
+			if tt.expected == "" {
+				assert.Empty(t, reason)
+			} else {
+				assert.Contains(t, reason, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollectorHealthNeverDrainsAllCollectors(t *testing.T) {
+	s, err := New("consistent-hashing", logger, WithCollectorHealth(CollectorHealthConfig{MaxSeries: 1000}))
+	require.NoError(t, err)
+
+	s.SetCollectors(MakeNCollectors(2, 0))
+	s.SetTargets(MakeNNewTargetsWithEmptyCollectors(20, 0))
+
+	require.NoError(t, s.SetCollectorHeartbeat("collector-0", CollectorHeartbeat{SeriesCount: 2000}))
+	require.NoError(t, s.SetCollectorHeartbeat("collector-1", CollectorHeartbeat{SeriesCount: 2000}))
+	for _, collector := range s.Collectors() {
+		assert.Empty(t, collector.DrainReason)
+	}
+	assert.Len(t, s.TargetItems(), 20)
+	for _, item := range s.TargetItems() {
+		assert.NotEmpty(t, item.CollectorName)
+	}
+}
+
+func TestCollectorHeartbeatUnknownCollector(t *testing.T) {
+	s, err := New("consistent-hashing", logger)
+	require.NoError(t, err)
+
+	s.SetCollectors(MakeNCollectors(1, 0))
+	assert.ErrorIs(t, s.SetCollectorHeartbeat("unknown", CollectorHeartbeat{}), ErrUnknownCollector)
+
+	// without a health config heartbeats are only recorded
+	require.NoError(t, s.SetCollectorHeartbeat("collector-0", CollectorHeartbeat{SeriesCount: 1 << 40}))
+	collector := s.Collectors()["collector-0"]
+	assert.Equal(t, int64(1<<40), collector.Heartbeat.SeriesCount)
+	assert.False(t, collector.Heartbeat.Time.IsZero())
+	assert.Empty(t, collector.DrainReason)
+}
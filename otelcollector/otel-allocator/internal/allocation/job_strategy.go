@@ -0,0 +1,164 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package allocation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
+)
+
+// JobStrategy allocates the targets of the jobs matching JobName with Strategy, instead of the strategy of the
+// allocator, e.g. per-node for the jobs scraping node-local endpoints.
+type JobStrategy struct {
+	// JobName is a regular expression which has to match the whole job name.
+	JobName  string
+	Strategy string
+}
+
+// jobStrategy is a JobStrategy with its job name regular expression compiled.
+type jobStrategy struct {
+	jobName  *regexp.Regexp
+	strategy Strategy
+}
+
+// WithJobStrategies sets the strategies of the jobs matching the JobStrategy job names. New returns an error for an
+// invalid job name or an unregistered strategy.
+func WithJobStrategies(jobStrategies []JobStrategy) Option {
+	return func(allocator Allocator) error {
+		return allocator.SetJobStrategies(jobStrategies)
+	}
+}
+
+// compileJobStrategies returns the job strategies, or an error for an invalid job name or an unregistered strategy.
+// The strategies are created once, and the strategy of the allocator is reused. The caller of this method has to
+// acquire a lock.
+func (a *allocator) compileJobStrategies(jobStrategies []JobStrategy) ([]jobStrategy, error) {
+	byName := map[string]Strategy{a.strategy.GetName(): a.strategy}
+	for _, js := range a.jobStrategies {
+		byName[js.strategy.GetName()] = js.strategy
+	}
+	compiled := make([]jobStrategy, 0, len(jobStrategies))
+	for _, js := range jobStrategies {
+		jobName, err := regexp.Compile("^(?:" + js.JobName + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid job name of the %s strategy: %w", js.Strategy, err)
+		}
+		strategy, ok := byName[js.Strategy]
+		if !ok {
+			newStrategy, registered := strategies[js.Strategy]
+			if !registered {
+				return nil, fmt.Errorf("unregistered strategy for jobs %s: %s", js.JobName, js.Strategy)
+			}
+			strategy = newStrategy()
+			byName[js.Strategy] = strategy
+		}
+		compiled = append(compiled, jobStrategy{jobName: jobName, strategy: strategy})
+	}
+	return compiled, nil
+}
+
+// SetJobStrategies replaces the strategies of the jobs. A job gets the strategy of the first JobStrategy matching its
+// name, and the strategy of the allocator otherwise. The targets of the jobs whose strategy changed are allocated again.
+func (a *allocator) SetJobStrategies(jobStrategies []JobStrategy) error {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	compiled, err := a.compileJobStrategies(jobStrategies)
+	if err != nil {
+		return err
+	}
+
+	previous := a.jobStrategies
+	a.jobStrategies = compiled
+	a.strategyByJob = make(map[string]Strategy)
+	for _, js := range compiled {
+		if js.strategy == a.strategy {
+			continue
+		}
+		if a.fallbackStrategy != nil {
+			js.strategy.SetFallbackStrategy(a.fallbackStrategy)
+		}
+		a.setTopology(js.strategy)
+		js.strategy.SetCollectors(a.collectors)
+	}
+
+	var moved []*target.Item
+	for _, item := range a.targetItems {
+		if a.strategyForJob(item.JobName) != matchJobStrategy(previous, item.JobName, a.strategy) {
+			a.unassignTargetItem(item)
+			moved = append(moved, item)
+		}
+	}
+	var assignmentErrors []error
+	for _, item := range moved {
+		if err := a.addTargetToTargetItems(item); err != nil {
+			assignmentErrors = append(assignmentErrors, err)
+		}
+	}
+	if len(assignmentErrors) > 0 {
+		a.log.Info("Could not assign targets after a job strategy change", "targets", len(assignmentErrors), "error", errors.Join(assignmentErrors...))
+	}
+	return nil
+}
+
+// StrategyForJob returns the name of the strategy the targets of the job are allocated with. The job is not cached,
+// since it may have no targets.
+func (a *allocator) StrategyForJob(jobName string) string {
+	a.m.RLock()
+	defer a.m.RUnlock()
+	if strategy, ok := a.strategyByJob[jobName]; ok {
+		return strategy.GetName()
+	}
+	return matchJobStrategy(a.jobStrategies, jobName, a.strategy).GetName()
+}
+
+// strategyForJob returns the strategy of the job, and caches it. The caller of this method has to acquire a lock.
+func (a *allocator) strategyForJob(jobName string) Strategy {
+	if strategy, ok := a.strategyByJob[jobName]; ok {
+		return strategy
+	}
+	strategy := matchJobStrategy(a.jobStrategies, jobName, a.strategy)
+	a.strategyByJob[jobName] = strategy
+	return strategy
+}
+
+// pruneStrategyByJob removes the cached strategies of the jobs without targets, so that the cache does not grow with
+// every job ever discovered. The caller of this method has to acquire a lock.
+func (a *allocator) pruneStrategyByJob() {
+	jobs := make(map[string]struct{}, len(a.strategyByJob))
+	for _, item := range a.targetItems {
+		jobs[item.JobName] = struct{}{}
+	}
+	for jobName := range a.strategyByJob {
+		if _, ok := jobs[jobName]; !ok {
+			delete(a.strategyByJob, jobName)
+		}
+	}
+}
+
+// matchJobStrategy returns the strategy of the first job strategy matching the job name, or the default strategy.
+func matchJobStrategy(jobStrategies []jobStrategy, jobName string, defaultStrategy Strategy) Strategy {
+	for _, js := range jobStrategies {
+		if js.jobName.MatchString(jobName) {
+			return js.strategy
+		}
+	}
+	return defaultStrategy
+}
+
+// usedStrategies returns the strategy of the allocator and the other strategies of the jobs. The caller of this
+// method has to acquire a lock.
+func (a *allocator) usedStrategies() []Strategy {
+	used := []Strategy{a.strategy}
+	for _, js := range a.jobStrategies {
+		if !slices.Contains(used, js.strategy) {
+			used = append(used, js.strategy)
+		}
+	}
+	return used
+}
@@ -0,0 +1,127 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package allocation
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
+)
+
+// makeNodeTargets returns a target of the job on each of the first n nodes of MakeNCollectors.
+func makeNodeTargets(n int, jobName string) []*target.Item {
+	var targets []*target.Item
+	for i := range n {
+		nodeLabels := labels.FromStrings("__meta_kubernetes_pod_node_name", fmt.Sprintf("node-%d", i), "i", fmt.Sprint(i))
+		targets = append(targets, target.NewItem(jobName, fmt.Sprintf("node-url-%d", i), nodeLabels, ""))
+	}
+	return targets
+}
+
+func TestJobStrategies(t *testing.T) {
+	allocator, err := New("consistent-hashing", logger, WithJobStrategies([]JobStrategy{
+		{JobName: "podMonitor/kube-system/node-.*", Strategy: "per-node"},
+	}))
+	require.NoError(t, err)
+	allocator.SetCollectors(MakeNCollectors(3, 0))
+
+	nodeTargets := makeNodeTargets(3, "podMonitor/kube-system/node-exporter/0")
+	allocator.SetTargets(append(MakeNTargetsForJob(10, "cluster-job", 0), nodeTargets...))
+
+	assert.Equal(t, "per-node", allocator.StrategyForJob("podMonitor/kube-system/node-exporter/0"))
+	assert.Equal(t, "consistent-hashing", allocator.StrategyForJob("cluster-job"))
+	// the job name has to match as a whole
+	assert.Equal(t, "consistent-hashing", allocator.StrategyForJob("serviceMonitor/podMonitor/kube-system/node-exporter/0"))
+
+	for i, item := range nodeTargets {
+		assert.Equal(t, fmt.Sprintf("collector-%d", i), allocator.TargetItems()[item.Hash()].CollectorName)
+	}
+	for _, item := range allocator.TargetItems() {
+		assert.NotEmpty(t, item.CollectorName)
+	}
+}
+
+func TestSetJobStrategiesReassignsTargets(t *testing.T) {
+	allocator, err := New("consistent-hashing", logger)
+	require.NoError(t, err)
+	allocator.SetCollectors(MakeNCollectors(3, 0))
+	// nodes without a collector, so that the per-node strategy leaves the targets unassigned
+	nodeTargets := makeNodeTargets(5, "node-job")
+	allocator.SetTargets(append(MakeNTargetsForJob(10, "cluster-job", 0), nodeTargets...))
+	clusterAssignments := map[target.ItemHash]string{}
+	for hash, item := range allocator.TargetItems() {
+		require.NotEmpty(t, item.CollectorName)
+		if item.JobName == "cluster-job" {
+			clusterAssignments[hash] = item.CollectorName
+		}
+	}
+
+	require.NoError(t, allocator.SetJobStrategies([]JobStrategy{{JobName: "node-job", Strategy: "per-node"}}))
+	for i, item := range nodeTargets {
+		expected := ""
+		if i < 3 {
+			expected = fmt.Sprintf("collector-%d", i)
+		}
+		assert.Equal(t, expected, allocator.TargetItems()[item.Hash()].CollectorName)
+	}
+	// the targets of the other jobs do not move
+	for hash, collectorName := range clusterAssignments {
+		assert.Equal(t, collectorName, allocator.TargetItems()[hash].CollectorName)
+	}
+	collectors := allocator.Collectors()
+	assert.Equal(t, 13, collectors["collector-0"].NumTargets+collectors["collector-1"].NumTargets+collectors["collector-2"].NumTargets)
+
+	require.NoError(t, allocator.SetJobStrategies(nil))
+	assert.Equal(t, "consistent-hashing", allocator.StrategyForJob("node-job"))
+	for _, item := range allocator.TargetItems() {
+		assert.NotEmpty(t, item.CollectorName)
+	}
+}
+
+func TestJobStrategiesWithFallback(t *testing.T) {
+	allocator, err := New("least-weighted", logger,
+		WithFallbackStrategy("consistent-hashing"),
+		WithJobStrategies([]JobStrategy{{JobName: "node-job", Strategy: "per-node"}}))
+	require.NoError(t, err)
+	allocator.SetCollectors(MakeNCollectors(3, 0))
+	// targets without a node are allocated with the fallback strategy of the per-node strategy
+	allocator.SetTargets(MakeNTargetsForJob(4, "node-job", 0))
+
+	for _, item := range allocator.TargetItems() {
+		assert.NotEmpty(t, item.CollectorName)
+	}
+}
+
+func TestSetJobStrategiesInvalid(t *testing.T) {
+	allocator, err := New("consistent-hashing", logger)
+	require.NoError(t, err)
+
+	assert.Error(t, allocator.SetJobStrategies([]JobStrategy{{JobName: "node-(", Strategy: "per-node"}}))
+	assert.Error(t, allocator.SetJobStrategies([]JobStrategy{{JobName: "node-job", Strategy: "unknown"}}))
+
+	_, err = New("consistent-hashing", logger, WithJobStrategies([]JobStrategy{{JobName: "node-(", Strategy: "per-node"}}))
+	assert.Error(t, err)
+	_, err = New("consistent-hashing", logger, WithJobStrategies([]JobStrategy{{JobName: "node-job", Strategy: "unknown"}}))
+	assert.Error(t, err)
+}
+
+func TestStrategyByJobIsPruned(t *testing.T) {
+	a, err := New("consistent-hashing", logger)
+	require.NoError(t, err)
+	a.SetCollectors(MakeNCollectors(3, 0))
+
+	a.SetTargets(append(MakeNTargetsForJob(5, "kept-job", 0), MakeNTargetsForJob(5, "removed-job", 5)...))
+	a.SetTargets(MakeNTargetsForJob(5, "kept-job", 0))
+	assert.Equal(t, "consistent-hashing", a.StrategyForJob("unknown-job"))
+
+	strategyByJob := a.(*allocator).strategyByJob
+	assert.Contains(t, strategyByJob, "kept-job")
+	assert.NotContains(t, strategyByJob, "removed-job")
+	assert.NotContains(t, strategyByJob, "unknown-job")
+}
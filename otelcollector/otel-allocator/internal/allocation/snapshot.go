@@ -0,0 +1,91 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package allocation
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
+)
+
+// Snapshot is the assignment of the targets to the collectors at a point in time. It is saved so that a restarted
+// Target Allocator can keep serving the same assignments before its discovery completes.
+type Snapshot struct {
+	Time       time.Time           `json:"time"`
+	Strategy   string              `json:"strategy"`
+	Collectors []SnapshotCollector `json:"collectors,omitempty"`
+	Targets    []SnapshotTarget    `json:"targets"`
+}
+
+// SnapshotCollector is a collector known when a Snapshot was taken.
+type SnapshotCollector struct {
+	Name     string `json:"name"`
+	NodeName string `json:"node,omitempty"`
+}
+
+// SnapshotTarget is an assigned target in a Snapshot. The hash is kept as computed during relabeling, as the labels
+// alone are not enough to compute it again.
+type SnapshotTarget struct {
+	Hash          target.ItemHash `json:"hash"`
+	JobName       string          `json:"job"`
+	TargetURL     string          `json:"target"`
+	Labels        labels.Labels   `json:"labels"`
+	Weight        float64         `json:"weight,omitempty"`
+	CollectorName string          `json:"collector"`
+}
+
+// NewSnapshot returns the current assignments of the allocator. Unassigned targets are left out.
+func NewSnapshot(allocator Allocator, strategy string) *Snapshot {
+	weights := allocator.TargetWeights()
+	snapshot := &Snapshot{Time: time.Now(), Strategy: strategy}
+	for hash, item := range allocator.TargetItems() {
+		if item.CollectorName == "" {
+			continue
+		}
+		snapshot.Targets = append(snapshot.Targets, SnapshotTarget{
+			Hash:          hash,
+			JobName:       item.JobName,
+			TargetURL:     item.TargetURL,
+			Labels:        item.Labels,
+			Weight:        weights[hash],
+			CollectorName: item.CollectorName,
+		})
+	}
+	slices.SortFunc(snapshot.Targets, func(a, b SnapshotTarget) int {
+		return cmp.Compare(a.Hash, b.Hash)
+	})
+	for _, collector := range allocator.Collectors() {
+		snapshot.Collectors = append(snapshot.Collectors, SnapshotCollector{Name: collector.Name, NodeName: collector.NodeName})
+	}
+	slices.SortFunc(snapshot.Collectors, func(a, b SnapshotCollector) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return snapshot
+}
+
+// CollectorMap returns the collectors of the snapshot, keyed by name.
+func (s *Snapshot) CollectorMap() map[string]*Collector {
+	collectors := make(map[string]*Collector, len(s.Collectors))
+	for _, c := range s.Collectors {
+		collectors[c.Name] = NewCollector(c.Name, c.NodeName)
+	}
+	return collectors
+}
+
+// Items returns the targets of the snapshot, with the collector they were assigned to.
+func (s *Snapshot) Items() []*target.Item {
+	items := make([]*target.Item, 0, len(s.Targets))
+	for _, t := range s.Targets {
+		options := []target.ItemOption{target.WithHash(t.Hash)}
+		if t.Weight > 0 {
+			options = append(options, target.WithWeight(t.Weight))
+		}
+		items = append(items, target.NewItem(t.JobName, t.TargetURL, t.Labels, t.CollectorName, options...))
+	}
+	return items
+}
@@ -0,0 +1,112 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package allocation
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
+)
+
+func assignments(allocator Allocator) map[target.ItemHash]string {
+	result := make(map[target.ItemHash]string)
+	for hash, item := range allocator.TargetItems() {
+		result[hash] = item.CollectorName
+	}
+	return result
+}
+
+func TestSeedTargetsKeepsAssignments(t *testing.T) {
+	previous, err := New("least-weighted", logger)
+	require.NoError(t, err)
+	previous.SetCollectors(MakeNCollectors(3, 0))
+	previous.SetTargets(MakeNNewTargetsWithEmptyCollectors(30, 0))
+	snapshot := NewSnapshot(previous, "least-weighted")
+	require.Len(t, snapshot.Targets, 30)
+
+	restarted, err := New("least-weighted", logger)
+	require.NoError(t, err)
+	assert.False(t, restarted.Ready())
+	restarted.SeedTargets(snapshot.Items())
+	assert.True(t, restarted.Ready())
+
+	// the seeded targets are assigned once the collectors are known, and stay there once discovered
+	restarted.SetCollectors(MakeNCollectors(3, 0))
+	assert.Equal(t, assignments(previous), assignments(restarted))
+	restarted.SetTargets(MakeNNewTargetsWithEmptyCollectors(30, 0))
+	assert.Equal(t, assignments(previous), assignments(restarted))
+	for name, collector := range restarted.Collectors() {
+		assert.Equal(t, previous.Collectors()[name].NumTargets, collector.NumTargets)
+	}
+}
+
+func TestSeedTargetsWithoutCollector(t *testing.T) {
+	s, err := New("least-weighted", logger)
+	require.NoError(t, err)
+	s.SetCollectors(MakeNCollectors(2, 0))
+
+	items := MakeNNewTargetsWithEmptyCollectors(4, 0)
+	for _, item := range items {
+		item.CollectorName = "collector-5"
+	}
+	s.SeedTargets(items)
+	for _, item := range s.TargetItems() {
+		assert.Contains(t, []string{"collector-0", "collector-1"}, item.CollectorName)
+	}
+}
+
+func TestSeedTargetsAgainMovesTargets(t *testing.T) {
+	s, err := New("consistent-hashing", logger)
+	require.NoError(t, err)
+	s.SetCollectors(MakeNCollectors(3, 0))
+	s.SeedTargets(MakeNNewTargets(9, 3, 0))
+
+	// a follower seeds the targets the leader moved
+	moved := MakeNNewTargets(9, 3, 0)
+	for _, item := range moved {
+		item.CollectorName = "collector-0"
+	}
+	s.SeedTargets(moved)
+	for _, item := range s.TargetItems() {
+		assert.Equal(t, "collector-0", item.CollectorName)
+	}
+	assert.Equal(t, 9, s.Collectors()["collector-0"].NumTargets)
+	assert.Equal(t, 0, s.Collectors()["collector-1"].NumTargets)
+}
+
+func TestSeedTargetsRemovedByDiscovery(t *testing.T) {
+	s, err := New("consistent-hashing", logger)
+	require.NoError(t, err)
+	s.SetCollectors(MakeNCollectors(2, 0))
+	s.SeedTargets(MakeNNewTargets(10, 2, 0))
+	assert.Len(t, s.TargetItems(), 10)
+
+	s.SetTargets(MakeNNewTargetsWithEmptyCollectors(4, 0))
+	assert.Len(t, s.TargetItems(), 4)
+
+	// seeding after discovery would bring back stale targets
+	s.SeedTargets(MakeNNewTargets(10, 2, 0))
+	assert.Len(t, s.TargetItems(), 4)
+}
+
+func TestSnapshotItems(t *testing.T) {
+	item := target.NewItem("test-job", "10.0.0.1:8080", labels.EmptyLabels(), "collector-0", target.WithHash(42))
+	snapshot := &Snapshot{Targets: []SnapshotTarget{{
+		Hash:          item.Hash(),
+		JobName:       item.JobName,
+		TargetURL:     item.TargetURL,
+		Weight:        3,
+		CollectorName: item.CollectorName,
+	}}}
+
+	items := snapshot.Items()
+	require.Len(t, items, 1)
+	assert.Equal(t, target.ItemHash(42), items[0].Hash())
+	assert.Equal(t, "collector-0", items[0].CollectorName)
+	assert.Equal(t, 3.0, items[0].Weight())
+}
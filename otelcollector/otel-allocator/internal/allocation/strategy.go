@@ -5,6 +5,7 @@ package allocation
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/buraksezer/consistent"
 	"github.com/go-logr/logr"
@@ -14,37 +15,48 @@ import (
 
 type AllocatorProvider func(log logr.Logger, opts ...Option) Allocator
 
-var strategies = map[string]Strategy{
-	leastWeightedStrategyName:     newleastWeightedStrategy(),
-	consistentHashingStrategyName: newConsistentHashingStrategy(),
-	perNodeStrategyName:           newPerNodeStrategy(),
+// strategies create the registered strategies. Each allocator gets its own instances, as some strategies keep the
+// collectors and their fallback strategy.
+var strategies = map[string]func() Strategy{
+	leastWeightedStrategyName:     newleastWeightedStrategy,
+	consistentHashingStrategyName: newConsistentHashingStrategy,
+	perNodeStrategyName:           newPerNodeStrategy,
+	weightedStrategyName:          newWeightedStrategy,
+	TopologyAwareStrategyName:     newTopologyAwareStrategy,
 }
 
-type Option func(Allocator)
+// Option configures an allocator when it is created. An error is returned by New.
+type Option func(Allocator) error
 
 type Filter interface {
 	Apply([]*target.Item) []*target.Item
 }
 
 func WithFilter(filter Filter) Option {
-	return func(allocator Allocator) {
+	return func(allocator Allocator) error {
 		allocator.SetFilter(filter)
+		return nil
 	}
 }
 
 func WithFallbackStrategy(fallbackStrategy string) Option {
-	strategy, ok := strategies[fallbackStrategy]
+	newStrategy, ok := strategies[fallbackStrategy]
 	if fallbackStrategy != "" && !ok {
 		panic(fmt.Errorf("unregistered strategy used as fallback: %s", fallbackStrategy))
 	}
-	return func(allocator Allocator) {
+	return func(allocator Allocator) error {
+		var strategy Strategy
+		if newStrategy != nil {
+			strategy = newStrategy()
+		}
 		allocator.SetFallbackStrategy(strategy)
+		return nil
 	}
 }
 
 func New(name string, log logr.Logger, opts ...Option) (Allocator, error) {
-	if strategy, ok := strategies[name]; ok {
-		return newAllocator(log.WithValues("allocator", name), strategy, opts...)
+	if newStrategy, ok := strategies[name]; ok {
+		return newAllocator(log.WithValues("allocator", name), newStrategy(), opts...)
 	}
 	return nil, fmt.Errorf("unregistered strategy: %s", name)
 }
@@ -61,10 +73,30 @@ type Allocator interface {
 	SetCollectors(collectors map[string]*Collector)
 	SetTargets(targets []*target.Item)
 	TargetItems() map[target.ItemHash]*target.Item
+	NumTargetItems() int
+	TargetItemsPerCollector() map[string]map[target.ItemHash]*target.Item
 	Collectors() map[string]*Collector
 	GetTargetsForCollectorAndJob(collector, job string) []*target.Item
 	SetFilter(filter Filter)
 	SetFallbackStrategy(strategy Strategy)
+	SetTargetWeights(weights []TargetWeight)
+	TargetWeights() map[target.ItemHash]float64
+	SetCollectorHealthConfig(cfg CollectorHealthConfig)
+	SetCollectorHeartbeat(collectorName string, heartbeat CollectorHeartbeat) error
+	SeedTargets(targets []*target.Item)
+	Ready() bool
+	SetJobStrategies(jobStrategies []JobStrategy) error
+	StrategyForJob(jobName string) string
+	SetNodeTopology(nodes map[string]NodeTopology)
+	SetTopologySpilloverRatio(ratio float64)
+}
+
+// TargetWeight is the weight of a target reported by the collector scraping it, usually the number of series of its
+// last scrape. The target is identified by its job and address, as the collector does not know the target's hash.
+type TargetWeight struct {
+	JobName   string  `json:"job"`
+	TargetURL string  `json:"target"`
+	Weight    float64 `json:"weight"`
 }
 
 type Strategy interface {
@@ -78,16 +110,33 @@ type Strategy interface {
 	SetFallbackStrategy(Strategy)
 }
 
+// Rebalancer is implemented by strategies which move already assigned targets when the target weights change.
+type Rebalancer interface {
+	// Rebalance returns the new collector name for the targets to move, keyed by target hash. The collectors and targets
+	// must not be modified.
+	Rebalance(collectors map[string]*Collector, targets map[target.ItemHash]*target.Item, weights map[target.ItemHash]float64) map[target.ItemHash]string
+}
+
 var _ consistent.Member = Collector{}
 
 // Collector Creates a struct that holds Collector information.
 // This struct will be parsed into endpoint with Collector and jobs info.
 // This struct can be extended with information like annotations and labels in the future.
 type Collector struct {
-	Name          string
-	NodeName      string
+	Name     string
+	NodeName string
+	// Zone and Region are the topology of the node of the collector, when the nodes are watched.
+	Zone          string
+	Region        string
 	NumTargets    int
 	TargetsPerJob map[string]int
+	// TotalWeight is the sum of the weights of the assigned targets.
+	TotalWeight float64
+	// Heartbeat is the last heartbeat received from the collector, if any.
+	Heartbeat CollectorHeartbeat
+	// DrainReason is set when the collector gets no targets because of its heartbeats, since DrainedSince.
+	DrainReason  string
+	DrainedSince time.Time
 }
 
 func (c Collector) Hash() string {
@@ -0,0 +1,289 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package allocation
+
+import (
+	"errors"
+	"maps"
+	"slices"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
+)
+
+// TopologyAwareStrategyName is exported as the collectors only get their topology when the nodes are watched, which
+// is only done for this strategy.
+const TopologyAwareStrategyName = "topology-aware"
+
+// DefaultTopologySpilloverRatio is the spillover ratio of the topology aware strategies when none is set.
+const DefaultTopologySpilloverRatio = 1.5
+
+// Labels of the targets with their zone and region, set by the endpointslice role or with attach_metadata.node.
+const (
+	endpointSliceZoneLabel = "__meta_kubernetes_endpointslice_endpoint_zone"
+	nodeZoneLabel          = "__meta_kubernetes_node_label_topology_kubernetes_io_zone"
+	nodeRegionLabel        = "__meta_kubernetes_node_label_topology_kubernetes_io_region"
+)
+
+var (
+	_ Strategy      = &topologyAwareStrategy{}
+	_ TopologyAware = &topologyAwareStrategy{}
+)
+
+// NodeTopology is the zone and region of a node, from its topology.kubernetes.io labels.
+type NodeTopology struct {
+	Zone   string
+	Region string
+}
+
+// TopologyAware is implemented by strategies which allocate the targets based on the topology of their nodes.
+type TopologyAware interface {
+	// SetNodeTopology sets the topology of the nodes, keyed by node name.
+	SetNodeTopology(nodes map[string]NodeTopology)
+	// SetSpilloverRatio sets how many times the average number of targets per collector the collectors of a zone can
+	// get before the targets of the zone go to other zones.
+	SetSpilloverRatio(ratio float64)
+}
+
+// WithTopologySpilloverRatio sets the spillover ratio of the topology aware strategies.
+func WithTopologySpilloverRatio(ratio float64) Option {
+	return func(allocator Allocator) error {
+		allocator.SetTopologySpilloverRatio(ratio)
+		return nil
+	}
+}
+
+// SetNodeTopology sets the topology of the nodes on the collectors and the topology aware strategies. The targets
+// allocated with a topology aware strategy are allocated again when the topology changes.
+func (a *allocator) SetNodeTopology(nodes map[string]NodeTopology) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	if maps.Equal(a.nodeTopology, nodes) {
+		return
+	}
+	a.nodeTopology = nodes
+	for _, strategy := range a.topologyAwareStrategies() {
+		strategy.SetNodeTopology(nodes)
+	}
+	for _, collectors := range []map[string]*Collector{a.collectors, a.drainedCollectors} {
+		for _, c := range collectors {
+			if topology, ok := nodes[c.NodeName]; ok {
+				c.Zone, c.Region = topology.Zone, topology.Region
+			}
+		}
+	}
+
+	a.reallocateTopologyAwareTargets()
+}
+
+// SetTopologySpilloverRatio sets the spillover ratio of the topology aware strategies. It applies to the targets
+// allocated afterwards.
+func (a *allocator) SetTopologySpilloverRatio(ratio float64) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	a.topologySpilloverRatio = ratio
+	for _, strategy := range a.topologyAwareStrategies() {
+		strategy.SetSpilloverRatio(ratio)
+	}
+}
+
+// reallocateTopologyAwareTargets allocates the targets of the topology aware strategies allocated out of their zone
+// again, which moves them back to it when its collectors are no longer saturated. As the targets are allocated one at
+// a time, the first targets of a zone can spill over before the load of the other zones is known. This is repeated
+// for the targets still out of their zone until no target moves, which ends as the targets allocated in their zone
+// are not moved. The caller of this method has to acquire a lock.
+func (a *allocator) reallocateTopologyAwareTargets() {
+	if !slices.ContainsFunc(a.usedStrategies(), func(strategy Strategy) bool {
+		_, ok := strategy.(TopologyAware)
+		return ok
+	}) {
+		return
+	}
+	var outOfZone []*target.Item
+	for _, item := range a.targetItems {
+		if a.isOutOfZone(item) {
+			outOfZone = append(outOfZone, item)
+		}
+	}
+	var assignmentErrors []error
+	for moved := len(outOfZone) > 0; moved; {
+		moved = false
+		assignmentErrors = nil
+		remaining := outOfZone[:0]
+		for _, item := range outOfZone {
+			collectorName := item.CollectorName
+			if err := a.addTargetToTargetItems(item); err != nil {
+				assignmentErrors = append(assignmentErrors, err)
+			}
+			moved = moved || item.CollectorName != collectorName
+			if a.isOutOfZone(item) {
+				remaining = append(remaining, item)
+			}
+		}
+		outOfZone = remaining
+	}
+	if len(assignmentErrors) > 0 {
+		a.log.Info("Could not reallocate targets to their zone", "targets", len(assignmentErrors), "error", errors.Join(assignmentErrors...))
+	}
+}
+
+// isOutOfZone reports whether the target is allocated with a topology aware strategy to a collector out of its zone.
+// The caller of this method has to acquire a lock.
+func (a *allocator) isOutOfZone(item *target.Item) bool {
+	strategy, ok := a.strategyForJob(item.JobName).(*topologyAwareStrategy)
+	if !ok {
+		return false
+	}
+	col, assigned := a.collectors[item.CollectorName]
+	if !assigned {
+		return false
+	}
+	zone := strategy.targetTopology(item).Zone
+	return zone != "" && col.Zone != zone
+}
+
+// setTopology sets the node topology and the spillover ratio of the allocator on the strategy, if it is topology
+// aware. The caller of this method has to acquire a lock.
+func (a *allocator) setTopology(strategy Strategy) {
+	topologyAware, ok := strategy.(TopologyAware)
+	if !ok {
+		return
+	}
+	if a.nodeTopology != nil {
+		topologyAware.SetNodeTopology(a.nodeTopology)
+	}
+	if a.topologySpilloverRatio > 0 {
+		topologyAware.SetSpilloverRatio(a.topologySpilloverRatio)
+	}
+}
+
+// topologyAwareStrategies returns the used strategies and the fallback strategy which are topology aware. The caller
+// of this method has to acquire a lock.
+func (a *allocator) topologyAwareStrategies() []TopologyAware {
+	var result []TopologyAware
+	for _, strategy := range append(a.usedStrategies(), a.fallbackStrategy) {
+		if topologyAware, ok := strategy.(TopologyAware); ok {
+			result = append(result, topologyAware)
+		}
+	}
+	return result
+}
+
+// topologyAwareStrategy assigns the targets to the least loaded collector of their zone, to avoid cross-zone traffic.
+// When the collectors of the zone are above the spillover ratio of the average load, or there are none, the targets
+// go to the least loaded collector of their region, and of any zone last. The targets of unknown zones are assigned to
+// the least loaded collector.
+type topologyAwareStrategy struct {
+	nodes          map[string]NodeTopology
+	spilloverRatio float64
+}
+
+func newTopologyAwareStrategy() Strategy {
+	return &topologyAwareStrategy{
+		nodes:          make(map[string]NodeTopology),
+		spilloverRatio: DefaultTopologySpilloverRatio,
+	}
+}
+
+func (*topologyAwareStrategy) GetName() string {
+	return TopologyAwareStrategyName
+}
+
+func (s *topologyAwareStrategy) GetCollectorForTarget(collectors map[string]*Collector, item *target.Item) (*Collector, error) {
+	topology := s.targetTopology(item)
+	var inZone, inRegion []*Collector
+	total := 0
+	for _, col := range collectors {
+		total += col.NumTargets
+		if topology.Zone != "" && col.Zone == topology.Zone {
+			inZone = append(inZone, col)
+		}
+		if topology.Region != "" && col.Region == topology.Region {
+			inRegion = append(inRegion, col)
+		}
+	}
+
+	// if a collector is already assigned in the zone of the target, or the zone has no collectors, do nothing
+	current, assigned := collectors[item.CollectorName]
+	if assigned && (len(inZone) == 0 || current.Zone == topology.Zone) {
+		return current, nil
+	}
+	// an assigned target is already counted in the total
+	if !assigned {
+		total++
+	}
+	average := float64(total) / float64(len(collectors))
+	if col := leastLoadedCollector(inZone, item.JobName); col != nil && !s.isSaturated(col, average) {
+		return col, nil
+	}
+
+	// the zone is saturated, keep a target which already spilled over in the region of the target
+	if assigned && (len(inRegion) == 0 || current.Region == topology.Region) {
+		return current, nil
+	}
+	if col := leastLoadedCollector(inRegion, item.JobName); col != nil && !s.isSaturated(col, average) {
+		return col, nil
+	}
+	if assigned {
+		return current, nil
+	}
+	all := make([]*Collector, 0, len(collectors))
+	for _, col := range collectors {
+		all = append(all, col)
+	}
+	return leastLoadedCollector(all, item.JobName), nil
+}
+
+// targetTopology returns the topology of the node of the target, or the one from its labels.
+func (s *topologyAwareStrategy) targetTopology(item *target.Item) NodeTopology {
+	topology := s.nodes[item.GetNodeName()]
+	if topology.Zone == "" {
+		topology.Zone = item.Labels.Get(endpointSliceZoneLabel)
+	}
+	if topology.Zone == "" {
+		topology.Zone = item.Labels.Get(nodeZoneLabel)
+	}
+	if topology.Region == "" {
+		topology.Region = item.Labels.Get(nodeRegionLabel)
+	}
+	return topology
+}
+
+// isSaturated reports whether a target would put the collector above the spillover ratio of the average number of
+// targets per collector.
+func (s *topologyAwareStrategy) isSaturated(col *Collector, average float64) bool {
+	return float64(col.NumTargets+1) > s.spilloverRatio*average
+}
+
+// leastLoadedCollector returns the collector with the fewest targets, then the fewest targets of the job and finally
+// the first by name, or nil without collectors.
+func leastLoadedCollector(collectors []*Collector, jobName string) *Collector {
+	var col *Collector
+	for _, v := range collectors {
+		if col == nil || v.NumTargets < col.NumTargets {
+			col = v
+		} else if v.NumTargets == col.NumTargets {
+			vPerJob := v.TargetsPerJob[jobName]
+			colPerJob := col.TargetsPerJob[jobName]
+			if vPerJob < colPerJob || (vPerJob == colPerJob && v.Name < col.Name) {
+				col = v
+			}
+		}
+	}
+	return col
+}
+
+func (*topologyAwareStrategy) SetCollectors(map[string]*Collector) {}
+
+func (*topologyAwareStrategy) SetFallbackStrategy(Strategy) {}
+
+func (s *topologyAwareStrategy) SetNodeTopology(nodes map[string]NodeTopology) {
+	if nodes == nil {
+		nodes = make(map[string]NodeTopology)
+	}
+	s.nodes = nodes
+}
+
+func (s *topologyAwareStrategy) SetSpilloverRatio(ratio float64) {
+	s.spilloverRatio = ratio
+}
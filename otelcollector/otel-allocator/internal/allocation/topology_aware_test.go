@@ -0,0 +1,142 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package allocation
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
+)
+
+func makeZonedCollectors(zones ...string) map[string]*Collector {
+	collectors := make(map[string]*Collector, len(zones))
+	for i, zone := range zones {
+		c := NewCollector(fmt.Sprintf("collector-%d", i), fmt.Sprintf("node-%d", i))
+		c.Zone, c.Region = zone, "eastus"
+		collectors[c.Name] = c
+	}
+	return collectors
+}
+
+func makeZonedTargets(n int, zone string, startingIndex int) []*target.Item {
+	targets := make([]*target.Item, 0, n)
+	for i := startingIndex; i < n+startingIndex; i++ {
+		targets = append(targets, target.NewItem("test-job", fmt.Sprintf("test-url-%d", i), labels.New(
+			labels.Label{Name: "i", Value: fmt.Sprint(i)},
+			labels.Label{Name: endpointSliceZoneLabel, Value: zone},
+		), ""))
+	}
+	return targets
+}
+
+func TestTopologyAwareAllocatesInZone(t *testing.T) {
+	allocator, err := New(TopologyAwareStrategyName, logger)
+	require.NoError(t, err)
+	allocator.SetCollectors(makeZonedCollectors("eastus-1", "eastus-2", "eastus-3"))
+
+	var targets []*target.Item
+	for i, zone := range []string{"eastus-1", "eastus-2", "eastus-3"} {
+		targets = append(targets, makeZonedTargets(3, zone, i*3)...)
+	}
+	allocator.SetTargets(targets)
+
+	collectors := allocator.Collectors()
+	for _, item := range allocator.TargetItems() {
+		require.NotEmpty(t, item.CollectorName)
+		assert.Equal(t, item.Labels.Get(endpointSliceZoneLabel), collectors[item.CollectorName].Zone)
+	}
+}
+
+func TestTopologyAwareSpillsOver(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		spilloverRatio float64
+		maxInZone      int
+	}{
+		{name: "default ratio", maxInZone: 7},
+		{name: "no imbalance", spilloverRatio: 1, maxInZone: 5},
+		{name: "large ratio", spilloverRatio: 10, maxInZone: 10},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var opts []Option
+			if tc.spilloverRatio > 0 {
+				opts = append(opts, WithTopologySpilloverRatio(tc.spilloverRatio))
+			}
+			allocator, err := New(TopologyAwareStrategyName, logger, opts...)
+			require.NoError(t, err)
+			allocator.SetCollectors(makeZonedCollectors("eastus-1", "eastus-2"))
+			allocator.SetTargets(makeZonedTargets(10, "eastus-1", 0))
+
+			collectors := allocator.Collectors()
+			assert.Equal(t, tc.maxInZone, collectors["collector-0"].NumTargets)
+			assert.Equal(t, 10-tc.maxInZone, collectors["collector-1"].NumTargets)
+		})
+	}
+}
+
+func TestTopologyAwareNodeTopology(t *testing.T) {
+	allocator, err := New(TopologyAwareStrategyName, logger)
+	require.NoError(t, err)
+	allocator.SetCollectors(MakeNCollectors(2, 0))
+
+	var targets []*target.Item
+	for i := range 4 {
+		targets = append(targets, target.NewItem("test-job", fmt.Sprintf("test-url-%d", i), labels.New(
+			labels.Label{Name: "i", Value: fmt.Sprint(i)},
+			labels.Label{Name: "__meta_kubernetes_pod_node_name", Value: fmt.Sprintf("node-%d", 2+i%2)},
+		), ""))
+	}
+	allocator.SetTargets(targets)
+	for _, item := range allocator.TargetItems() {
+		require.NotEmpty(t, item.CollectorName)
+	}
+
+	// the collectors run on node-0 and node-1, and the targets on node-2 and node-3 of the same zones
+	allocator.SetNodeTopology(map[string]NodeTopology{
+		"node-0": {Zone: "eastus-1", Region: "eastus"},
+		"node-1": {Zone: "eastus-2", Region: "eastus"},
+		"node-2": {Zone: "eastus-1", Region: "eastus"},
+		"node-3": {Zone: "eastus-2", Region: "eastus"},
+	})
+
+	collectors := allocator.Collectors()
+	assert.Equal(t, "eastus-1", collectors["collector-0"].Zone)
+	assert.Equal(t, "eastus-2", collectors["collector-1"].Zone)
+	for _, item := range allocator.TargetItems() {
+		nodeName := item.GetNodeName()
+		if nodeName == "node-2" {
+			assert.Equal(t, "collector-0", item.CollectorName)
+		} else {
+			assert.Equal(t, "collector-1", item.CollectorName)
+		}
+	}
+	assert.Len(t, allocator.GetTargetsForCollectorAndJob("collector-0", "test-job"), 2)
+	assert.Len(t, allocator.GetTargetsForCollectorAndJob("collector-1", "test-job"), 2)
+}
+
+func TestTopologyAwareKeepsAssignmentWithoutZoneCollectors(t *testing.T) {
+	allocator, err := New(TopologyAwareStrategyName, logger)
+	require.NoError(t, err)
+	allocator.SetCollectors(makeZonedCollectors("eastus-1", "eastus-2"))
+	allocator.SetTargets(makeZonedTargets(4, "eastus-3", 0))
+
+	assignments := map[target.ItemHash]string{}
+	for hash, item := range allocator.TargetItems() {
+		require.NotEmpty(t, item.CollectorName)
+		assignments[hash] = item.CollectorName
+	}
+	assert.Equal(t, 2, allocator.Collectors()["collector-0"].NumTargets)
+
+	// adding a collector in another zone doesn't move the targets
+	collectors := makeZonedCollectors("eastus-1", "eastus-2", "westus-1")
+	allocator.SetCollectors(collectors)
+	for hash, item := range allocator.TargetItems() {
+		assert.Equal(t, assignments[hash], item.CollectorName)
+	}
+}
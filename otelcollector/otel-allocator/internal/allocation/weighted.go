@@ -0,0 +1,144 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package allocation
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
+)
+
+const (
+	weightedStrategyName = "weighted"
+
+	// weightImbalanceTolerance is how far above the average total weight a collector can be before targets are moved
+	// away from it when the weights change.
+	weightImbalanceTolerance = 0.1
+	// maxRebalanceRatio bounds the fraction of the targets moved when the weights change.
+	maxRebalanceRatio = 0.05
+)
+
+var (
+	_ Strategy   = &weightedStrategy{}
+	_ Rebalancer = &weightedStrategy{}
+)
+
+// weightedStrategy assigns new targets to the collector with the lowest total weight, where the weight of a target is
+// the series count reported by the collector scraping it, or set through the target.WeightLabel. Assigned targets
+// only move when the weights change and the collectors become imbalanced, a few at a time.
+type weightedStrategy struct{}
+
+func newWeightedStrategy() Strategy {
+	return &weightedStrategy{}
+}
+
+func (*weightedStrategy) GetName() string {
+	return weightedStrategyName
+}
+
+func (*weightedStrategy) GetCollectorForTarget(collectors map[string]*Collector, item *target.Item) (*Collector, error) {
+	// if a collector is already assigned, do nothing
+	if item.CollectorName != "" {
+		if col, ok := collectors[item.CollectorName]; ok {
+			return col, nil
+		}
+	}
+
+	var col *Collector
+	for _, v := range collectors {
+		if col == nil || compareCollectorLoad(v, col) < 0 {
+			col = v
+		}
+	}
+	return col, nil
+}
+
+// compareCollectorLoad orders collectors by total weight, then by number of targets and finally by name for a
+// deterministic assignment.
+func compareCollectorLoad(a, b *Collector) int {
+	return cmp.Or(
+		cmp.Compare(a.TotalWeight, b.TotalWeight),
+		cmp.Compare(a.NumTargets, b.NumTargets),
+		cmp.Compare(a.Name, b.Name),
+	)
+}
+
+func (*weightedStrategy) SetCollectors(map[string]*Collector) {}
+
+func (*weightedStrategy) SetFallbackStrategy(Strategy) {}
+
+// Rebalance moves targets from the heaviest to the lightest collector while the heaviest one is above the average
+// total weight by more than weightImbalanceTolerance, moving at most maxRebalanceRatio of the targets.
+func (*weightedStrategy) Rebalance(collectors map[string]*Collector, targets map[target.ItemHash]*target.Item, weights map[target.ItemHash]float64) map[target.ItemHash]string {
+	moves := make(map[target.ItemHash]string)
+	if len(collectors) < 2 || len(targets) == 0 {
+		return moves
+	}
+
+	totals := make(map[string]float64, len(collectors))
+	targetsPerCollector := make(map[string][]*target.Item, len(collectors))
+	names := make([]string, 0, len(collectors))
+	var totalWeight float64
+	for name, col := range collectors {
+		totals[name] = col.TotalWeight
+		totalWeight += col.TotalWeight
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, item := range targets {
+		if _, ok := collectors[item.CollectorName]; ok {
+			targetsPerCollector[item.CollectorName] = append(targetsPerCollector[item.CollectorName], item)
+		}
+	}
+	for _, items := range targetsPerCollector {
+		slices.SortFunc(items, func(a, b *target.Item) int {
+			return cmp.Compare(a.Hash(), b.Hash())
+		})
+	}
+
+	threshold := totalWeight / float64(len(collectors)) * (1 + weightImbalanceTolerance)
+	maxMoves := max(1, int(float64(len(targets))*maxRebalanceRatio))
+	for len(moves) < maxMoves {
+		heaviest, lightest := names[0], names[0]
+		for _, name := range names {
+			if totals[name] > totals[heaviest] {
+				heaviest = name
+			}
+			if totals[name] < totals[lightest] {
+				lightest = name
+			}
+		}
+		if totals[heaviest] <= threshold {
+			break
+		}
+
+		// Move the target bringing both collectors closest to each other. A target weighing as much as the
+		// difference or more would only swap the imbalance around.
+		gap := totals[heaviest] - totals[lightest]
+		best := -1
+		for i, item := range targetsPerCollector[heaviest] {
+			weight := weights[item.Hash()]
+			if weight >= gap {
+				continue
+			}
+			if best == -1 || math.Abs(gap/2-weight) < math.Abs(gap/2-weights[targetsPerCollector[heaviest][best].Hash()]) {
+				best = i
+			}
+		}
+		if best == -1 {
+			break
+		}
+
+		item := targetsPerCollector[heaviest][best]
+		weight := weights[item.Hash()]
+		targetsPerCollector[heaviest] = slices.Delete(targetsPerCollector[heaviest], best, best+1)
+		targetsPerCollector[lightest] = append(targetsPerCollector[lightest], item)
+		totals[heaviest] -= weight
+		totals[lightest] += weight
+		moves[item.Hash()] = lightest
+	}
+	return moves
+}
@@ -0,0 +1,159 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package allocation
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
+)
+
+func makeWeightedTargets(weights ...float64) []*target.Item {
+	targets := make([]*target.Item, len(weights))
+	for i, weight := range weights {
+		label := labels.New(labels.Label{Name: "i", Value: fmt.Sprint(i)})
+		targets[i] = target.NewItem("test-job", fmt.Sprintf("test-url-%d", i), label, "", target.WithWeight(weight))
+	}
+	return targets
+}
+
+func totalWeights(a Allocator) map[string]float64 {
+	totals := make(map[string]float64)
+	for name, col := range a.Collectors() {
+		totals[name] = col.TotalWeight
+	}
+	return totals
+}
+
+func TestWeightedAssignsByTotalWeight(t *testing.T) {
+	s, err := New(weightedStrategyName, logger)
+	require.NoError(t, err)
+
+	s.SetCollectors(MakeNCollectors(2, 0))
+	targets := makeWeightedTargets(100, 10, 10, 10, 10, 10)
+	s.SetTargets(targets[:1])
+	s.SetTargets(targets)
+
+	// the heavy target is balanced by all the light ones
+	assert.Equal(t, map[string]float64{"collector-0": 100, "collector-1": 50}, totalWeights(s))
+	for _, col := range s.Collectors() {
+		if col.TotalWeight == 100 {
+			assert.Equal(t, 1, col.NumTargets)
+		}
+	}
+}
+
+func TestWeightedTotalWeightFollowsTargets(t *testing.T) {
+	s, err := New(weightedStrategyName, logger)
+	require.NoError(t, err)
+
+	s.SetCollectors(MakeNCollectors(3, 0))
+	targets := makeWeightedTargets(5, 5, 5, 5, 5, 5)
+	s.SetTargets(targets)
+	assert.Equal(t, map[string]float64{"collector-0": 10, "collector-1": 10, "collector-2": 10}, totalWeights(s))
+
+	s.SetTargets(targets[:3])
+	var total float64
+	for _, weight := range totalWeights(s) {
+		total += weight
+	}
+	assert.Equal(t, float64(15), total)
+
+	s.SetCollectors(MakeNCollectors(1, 0))
+	assert.Equal(t, map[string]float64{"collector-0": 15}, totalWeights(s))
+}
+
+func TestWeightedReportedWeights(t *testing.T) {
+	s, err := New(weightedStrategyName, logger)
+	require.NoError(t, err)
+
+	s.SetCollectors(MakeNCollectors(2, 0))
+	targets := makeWeightedTargets(1, 1, 1, 1)
+	s.SetTargets(targets)
+	assert.Equal(t, map[string]float64{"collector-0": 2, "collector-1": 2}, totalWeights(s))
+
+	// unknown targets are ignored
+	s.SetTargetWeights([]TargetWeight{
+		{JobName: "test-job", TargetURL: "test-url-0", Weight: 1000},
+		{JobName: "test-job", TargetURL: "unknown", Weight: 1000},
+	})
+	weights := s.TargetWeights()
+	assert.Len(t, weights, len(targets))
+	assert.Equal(t, float64(1000), weights[targets[0].Hash()])
+	// the other target on the heavy collector is moved away
+	heavy := s.TargetItems()[targets[0].Hash()].CollectorName
+	assert.Equal(t, 1, s.Collectors()[heavy].NumTargets)
+	assert.Equal(t, float64(1000), s.Collectors()[heavy].TotalWeight)
+
+	// the reported weight takes precedence over the weight of the target until it is cleared
+	s.SetTargets(targets)
+	assert.Equal(t, float64(1000), s.TargetWeights()[targets[0].Hash()])
+	s.SetTargetWeights([]TargetWeight{{JobName: "test-job", TargetURL: "test-url-0", Weight: 0}})
+	assert.Equal(t, float64(1), s.TargetWeights()[targets[0].Hash()])
+}
+
+func TestWeightedRebalanceIsBounded(t *testing.T) {
+	s, err := New(weightedStrategyName, logger)
+	require.NoError(t, err)
+
+	s.SetCollectors(MakeNCollectors(2, 0))
+	targets := MakeNTargetsForJob(100, "test-job", 0)
+	s.SetTargets(targets)
+	before := s.TargetItems()
+	assignments := make(map[target.ItemHash]string, len(before))
+	for hash, item := range before {
+		assignments[hash] = item.CollectorName
+	}
+
+	// make all the targets of one collector heavier
+	var weights []TargetWeight
+	for _, item := range before {
+		if item.CollectorName == "collector-0" {
+			weights = append(weights, TargetWeight{JobName: item.JobName, TargetURL: item.TargetURL, Weight: 3})
+		}
+	}
+	s.SetTargetWeights(weights)
+
+	moved := 0
+	for hash, item := range s.TargetItems() {
+		if assignments[hash] != item.CollectorName {
+			moved++
+		}
+	}
+	assert.Equal(t, int(float64(len(targets))*maxRebalanceRatio), moved)
+	totals := totalWeights(s)
+	assert.Less(t, totals["collector-0"], float64(150))
+	assert.Equal(t, float64(200), totals["collector-0"]+totals["collector-1"])
+}
+
+func TestWeightedRebalanceWithinTolerance(t *testing.T) {
+	strategy := &weightedStrategy{}
+	collectors := map[string]*Collector{
+		"collector-0": {Name: "collector-0", NumTargets: 2, TotalWeight: 21},
+		"collector-1": {Name: "collector-1", NumTargets: 2, TotalWeight: 19},
+	}
+	targets := makeWeightedTargets(11, 10, 10, 9)
+	weights := make(map[target.ItemHash]float64)
+	for i, item := range targets {
+		item.CollectorName = fmt.Sprintf("collector-%d", i/2)
+		weights[item.Hash()] = item.Weight()
+	}
+	targetMap := make(map[target.ItemHash]*target.Item)
+	for _, item := range targets {
+		targetMap[item.Hash()] = item
+	}
+
+	assert.Empty(t, strategy.Rebalance(collectors, targetMap, weights))
+
+	// targets heavier than the gap are not moved either
+	collectors["collector-0"].TotalWeight, collectors["collector-1"].TotalWeight = 80, 60
+	weights[targets[0].Hash()], weights[targets[1].Hash()] = 40, 40
+	weights[targets[2].Hash()], weights[targets[3].Hash()] = 30, 30
+	assert.Empty(t, strategy.Rebalance(collectors, targetMap, weights))
+}
@@ -30,6 +30,9 @@ type Watcher struct {
 	minUpdateInterval            time.Duration
 	collectorNotReadyGracePeriod time.Duration
 	collectorsDiscovered         metric.Int64Gauge
+	// nodeTopologyFn gets the topology of the nodes, which are only watched when it is set
+	nodeTopologyFn func(nodes map[string]allocation.NodeTopology)
+	nodeStore      cache.Store
 }
 
 func NewCollectorWatcher(logger logr.Logger, client kubernetes.Interface, collectorNotReadyGracePeriod time.Duration) (*Watcher, error) {
@@ -48,6 +51,13 @@ func NewCollectorWatcher(logger logr.Logger, client kubernetes.Interface, collec
 	}, nil
 }
 
+// WatchNodeTopology makes Watch also watch the nodes of the cluster, to set the zone and region of the collectors from
+// the topology labels of their node. fn gets the topology of all the nodes before the collectors are updated. It has to
+// be called before Watch.
+func (k *Watcher) WatchNodeTopology(fn func(nodes map[string]allocation.NodeTopology)) {
+	k.nodeTopologyFn = fn
+}
+
 func (k *Watcher) Watch(
 	collectorNamespace string,
 	labelSelector *metav1.LabelSelector,
@@ -69,8 +79,6 @@ func (k *Watcher) Watch(
 	informer := informerFactory.Core().V1().Pods().Informer()
 
 	notify := make(chan struct{}, 1)
-	go k.rateLimitedCollectorHandler(notify, informer.GetStore(), fn)
-
 	notifyFunc := func(_ any) {
 		select {
 		case notify <- struct{}{}:
@@ -88,6 +96,26 @@ func (k *Watcher) Watch(
 		return err
 	}
 
+	if k.nodeTopologyFn != nil {
+		nodeInformer := informers.NewSharedInformerFactory(k.k8sClient, 30*time.Second).Core().V1().Nodes().Informer()
+		_, err = nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: notifyFunc,
+			UpdateFunc: func(oldObj, newObj any) {
+				// nodes are updated often, but their topology rarely changes
+				if nodeTopology(oldObj.(*v1.Node)) != nodeTopology(newObj.(*v1.Node)) {
+					notifyFunc(newObj)
+				}
+			},
+			DeleteFunc: notifyFunc,
+		})
+		if err != nil {
+			return err
+		}
+		k.nodeStore = nodeInformer.GetStore()
+		go nodeInformer.Run(k.close)
+	}
+
+	go k.rateLimitedCollectorHandler(notify, informer.GetStore(), fn)
 	informer.Run(k.close)
 	return nil
 }
@@ -114,6 +142,12 @@ func (k *Watcher) rateLimitedCollectorHandler(notify chan struct{}, store cache.
 
 // runOnCollectors runs the provided function on the set of collectors from the Store.
 func (k *Watcher) runOnCollectors(store cache.Store, fn func(collectors map[string]*allocation.Collector)) {
+	var nodes map[string]allocation.NodeTopology
+	if k.nodeStore != nil {
+		nodes = k.nodeTopologies()
+		k.nodeTopologyFn(nodes)
+	}
+
 	objects := store.List()
 	collectorMap := make(map[string]*allocation.Collector, len(objects))
 	for _, obj := range objects {
@@ -127,12 +161,36 @@ func (k *Watcher) runOnCollectors(store cache.Store, fn func(collectors map[stri
 			continue
 		}
 
-		collectorMap[pod.Name] = allocation.NewCollector(pod.Name, pod.Spec.NodeName)
+		col := allocation.NewCollector(pod.Name, pod.Spec.NodeName)
+		if topology, ok := nodes[pod.Spec.NodeName]; ok {
+			col.Zone, col.Region = topology.Zone, topology.Region
+		}
+		collectorMap[pod.Name] = col
 	}
 	k.collectorsDiscovered.Record(context.Background(), int64(len(collectorMap)))
 	fn(collectorMap)
 }
 
+// nodeTopologies returns the topology of the nodes from the store which have topology labels.
+func (k *Watcher) nodeTopologies() map[string]allocation.NodeTopology {
+	objects := k.nodeStore.List()
+	nodes := make(map[string]allocation.NodeTopology, len(objects))
+	for _, obj := range objects {
+		node := obj.(*v1.Node)
+		if topology := nodeTopology(node); topology != (allocation.NodeTopology{}) {
+			nodes[node.Name] = topology
+		}
+	}
+	return nodes
+}
+
+func nodeTopology(node *v1.Node) allocation.NodeTopology {
+	return allocation.NodeTopology{
+		Zone:   node.Labels[v1.LabelTopologyZone],
+		Region: node.Labels[v1.LabelTopologyRegion],
+	}
+}
+
 func (k *Watcher) Close() {
 	close(k.close)
 }
@@ -488,6 +488,72 @@ func Test_gracePeriodWithNonReadyPodCondition(t *testing.T) {
 	}
 }
 
+func Test_nodeTopology(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		podWatcher := getTestPodWatcher(0 * time.Second)
+		nodes := []*v1.Node{
+			{ObjectMeta: metav1.ObjectMeta{Name: "test-node", Labels: map[string]string{
+				v1.LabelTopologyZone:   "eastus-1",
+				v1.LabelTopologyRegion: "eastus",
+			}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "other-node"}},
+		}
+		for _, node := range nodes {
+			_, err := podWatcher.k8sClient.CoreV1().Nodes().Create(context.Background(), node, metav1.CreateOptions{})
+			require.NoError(t, err)
+		}
+		otherPod := pod("test-pod2")
+		otherPod.Spec.NodeName = "other-node"
+		for _, p := range []*v1.Pod{pod("test-pod1"), otherPod} {
+			_, err := podWatcher.k8sClient.CoreV1().Pods("test-ns").Create(context.Background(), p, metav1.CreateOptions{})
+			require.NoError(t, err)
+		}
+
+		var actualCollectors map[string]*allocation.Collector
+		var actualNodes map[string]allocation.NodeTopology
+		mapMutex := sync.Mutex{}
+		podWatcher.WatchNodeTopology(func(nodes map[string]allocation.NodeTopology) {
+			mapMutex.Lock()
+			defer mapMutex.Unlock()
+			actualNodes = nodes
+		})
+		go func() {
+			err := podWatcher.Watch("test-ns", &labelSelector, func(colMap map[string]*allocation.Collector) {
+				mapMutex.Lock()
+				defer mapMutex.Unlock()
+				actualCollectors = colMap
+			})
+			require.NoError(t, err)
+		}()
+		synctest.Wait()
+		time.Sleep(podWatcher.minUpdateInterval)
+		synctest.Wait()
+
+		mapMutex.Lock()
+		assert.Equal(t, map[string]allocation.NodeTopology{
+			"test-node": {Zone: "eastus-1", Region: "eastus"},
+		}, actualNodes)
+		assert.Equal(t, map[string]*allocation.Collector{
+			"test-pod1": {
+				Name:          "test-pod1",
+				NodeName:      "test-node",
+				Zone:          "eastus-1",
+				Region:        "eastus",
+				TargetsPerJob: map[string]int{},
+			},
+			"test-pod2": {
+				Name:          "test-pod2",
+				NodeName:      "other-node",
+				TargetsPerJob: map[string]int{},
+			},
+		}, actualCollectors)
+		mapMutex.Unlock()
+
+		close(podWatcher.close)
+		synctest.Wait()
+	})
+}
+
 // this tests runWatch in the case of watcher channel closing.
 func Test_closeChannel(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
@@ -14,6 +14,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -45,6 +46,17 @@ const (
 	DefaultAllocationStrategy                          = "consistent-hashing"
 	DefaultFilterStrategy                              = "relabel-config"
 	DefaultCollectorNotReadyGracePeriod                = 30 * time.Second
+	DefaultCollectorHeartbeatTimeout                   = 2 * time.Minute
+	DefaultCollectorRecoveryDelay                      = 5 * time.Minute
+	DefaultAllocationStateConfigMapName                = "target-allocator-state"
+	DefaultAllocationStateSaveInterval                 = 30 * time.Second
+	DefaultLeaderElectionLeaseName                     = "target-allocator-leader"
+	DefaultLeaderElectionLeaseDuration                 = 15 * time.Second
+	DefaultLeaderElectionRenewDeadline                 = 10 * time.Second
+	DefaultLeaderElectionRetryPeriod                   = 2 * time.Second
+	DefaultRecommendationTolerance                     = 0.1
+	DefaultRecommendationStabilization                 = 5 * time.Minute
+	DefaultRecommendationInterval                      = 30 * time.Second
 )
 
 var DefaultKubeConfigFilePath = filepath.Join(homedir.HomeDir(), ".kube", "config")
@@ -60,20 +72,94 @@ var defaultScrapeProtocolsCR = []monitoringv1.ScrapeProtocol{
 var NopLogger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.Level(math.MaxInt)}))
 
 type Config struct {
-	ListenAddr                   string                `yaml:"listen_addr,omitempty"`
-	KubeConfigFilePath           string                `yaml:"kube_config_file_path,omitempty"`
-	ClusterConfig                *rest.Config          `yaml:"-"`
-	RootLogger                   logr.Logger           `yaml:"-"`
-	CollectorSelector            *metav1.LabelSelector `yaml:"collector_selector,omitempty"`
-	CollectorNamespace           string                `yaml:"collector_namespace,omitempty"`
-	PromConfig                   *promconfig.Config    `yaml:"config"`
-	AllocationStrategy           string                `yaml:"allocation_strategy,omitempty"`
-	AllocationFallbackStrategy   string                `yaml:"allocation_fallback_strategy,omitempty"`
-	FilterStrategy               string                `yaml:"filter_strategy,omitempty"`
-	PrometheusCR                 PrometheusCRConfig    `yaml:"prometheus_cr,omitempty"`
-	HTTPS                        HTTPSServerConfig     `yaml:"https,omitempty"`
-	CollectorNotReadyGracePeriod time.Duration         `yaml:"collector_not_ready_grace_period,omitempty"`
-	AllowInsecureAuthSecrets     bool                  `yaml:"allow_insecure_auth_secrets,omitempty"`
+	ListenAddr                   string                  `yaml:"listen_addr,omitempty"`
+	KubeConfigFilePath           string                  `yaml:"kube_config_file_path,omitempty"`
+	ClusterConfig                *rest.Config            `yaml:"-"`
+	RootLogger                   logr.Logger             `yaml:"-"`
+	CollectorSelector            *metav1.LabelSelector   `yaml:"collector_selector,omitempty"`
+	CollectorNamespace           string                  `yaml:"collector_namespace,omitempty"`
+	PromConfig                   *promconfig.Config      `yaml:"config"`
+	AllocationStrategy           string                  `yaml:"allocation_strategy,omitempty"`
+	AllocationFallbackStrategy   string                  `yaml:"allocation_fallback_strategy,omitempty"`
+	JobAllocationStrategies      []JobAllocationStrategy `yaml:"job_allocation_strategies,omitempty"`
+	FilterStrategy               string                  `yaml:"filter_strategy,omitempty"`
+	PrometheusCR                 PrometheusCRConfig      `yaml:"prometheus_cr,omitempty"`
+	HTTPS                        HTTPSServerConfig       `yaml:"https,omitempty"`
+	CollectorNotReadyGracePeriod time.Duration           `yaml:"collector_not_ready_grace_period,omitempty"`
+	AllowInsecureAuthSecrets     bool                    `yaml:"allow_insecure_auth_secrets,omitempty"`
+	CollectorHealth              CollectorHealthConfig   `yaml:"collector_health,omitempty"`
+	AllocationState              AllocationStateConfig   `yaml:"allocation_state,omitempty"`
+	LeaderElection               LeaderElectionConfig    `yaml:"leader_election,omitempty"`
+	Topology                     TopologyConfig          `yaml:"topology,omitempty"`
+	// ProvenanceFile is the provenance of the scrape config jobs written by the config reader, served by /explain
+	ProvenanceFile string `yaml:"provenance_file,omitempty"`
+	// ReplicaRecommendation recommends a number of collectors, served by /collectors/recommendation
+	ReplicaRecommendation ReplicaRecommendationConfig `yaml:"replica_recommendation,omitempty"`
+}
+
+// JobAllocationStrategy allocates the targets of the jobs whose name matches the JobName regular expression with
+// another allocation strategy than AllocationStrategy. A job gets the strategy of the first one matching its name.
+type JobAllocationStrategy struct {
+	JobName  string `yaml:"job_name"`
+	Strategy string `yaml:"strategy"`
+}
+
+// CollectorHealthConfig configures draining the targets of collectors whose heartbeats report them as unhealthy or
+// saturated. A zero limit is not checked.
+type CollectorHealthConfig struct {
+	Enabled               bool          `yaml:"enabled,omitempty"`
+	MaxSeries             int64         `yaml:"max_series,omitempty"`
+	MaxMemoryUsageRatio   float64       `yaml:"max_memory_usage_ratio,omitempty"`
+	MaxScrapeFailureRatio float64       `yaml:"max_scrape_failure_ratio,omitempty"`
+	HeartbeatTimeout      time.Duration `yaml:"heartbeat_timeout,omitempty"`
+	RecoveryDelay         time.Duration `yaml:"recovery_delay,omitempty"`
+}
+
+// AllocationStateConfig configures saving the assignments of the targets to the collectors, so that a restarted
+// Target Allocator keeps them instead of reshuffling all the targets. The assignments are saved to a ConfigMap in the
+// collector namespace unless a file path is set.
+type AllocationStateConfig struct {
+	Enabled            bool          `yaml:"enabled,omitempty"`
+	ConfigMapName      string        `yaml:"configmap_name,omitempty"`
+	ConfigMapNamespace string        `yaml:"configmap_namespace,omitempty"`
+	FilePath           string        `yaml:"file_path,omitempty"`
+	SaveInterval       time.Duration `yaml:"save_interval,omitempty"`
+}
+
+// LeaderElectionConfig configures running several Target Allocator replicas, of which only the leader discovers and
+// allocates the targets. The followers serve the allocation the leader saves to the allocation state ConfigMap.
+// The identity is the address the other replicas reach this one at, and defaults to the POD_IP environment variable
+// or the hostname, with the port of the listen address.
+type LeaderElectionConfig struct {
+	Enabled        bool          `yaml:"enabled,omitempty"`
+	LeaseName      string        `yaml:"lease_name,omitempty"`
+	LeaseNamespace string        `yaml:"lease_namespace,omitempty"`
+	Identity       string        `yaml:"identity,omitempty"`
+	LeaseDuration  time.Duration `yaml:"lease_duration,omitempty"`
+	RenewDeadline  time.Duration `yaml:"renew_deadline,omitempty"`
+	RetryPeriod    time.Duration `yaml:"retry_period,omitempty"`
+}
+
+// TopologyConfig configures the topology-aware allocation strategy. The collectors of a zone get the targets of the
+// zone until they have SpilloverRatio times the average number of targets per collector. The allocator uses
+// allocation.DefaultTopologySpilloverRatio when it is not set.
+type TopologyConfig struct {
+	SpilloverRatio float64 `yaml:"spillover_ratio,omitempty"`
+}
+
+// ReplicaRecommendationConfig configures recommending a number of collectors for the targets, from the number of
+// targets or series a collector can scrape. A zero budget is not used. The recommendation only changes when the load
+// differs from the budgets of the current collectors by more than Tolerance, and only decreases once it stayed lower
+// for ScaleDownStabilization.
+type ReplicaRecommendationConfig struct {
+	Enabled                bool          `yaml:"enabled,omitempty"`
+	TargetsPerCollector    int           `yaml:"targets_per_collector,omitempty"`
+	SeriesPerCollector     int64         `yaml:"series_per_collector,omitempty"`
+	MinReplicas            int           `yaml:"min_replicas,omitempty"`
+	MaxReplicas            int           `yaml:"max_replicas,omitempty"`
+	Tolerance              float64       `yaml:"tolerance,omitempty"`
+	ScaleDownStabilization time.Duration `yaml:"scale_down_stabilization,omitempty"`
+	Interval               time.Duration `yaml:"interval,omitempty"`
 }
 
 type PrometheusCRConfig struct {
@@ -390,6 +476,26 @@ func CreateDefaultConfig() Config {
 			ScrapeProtocols:                 defaultScrapeProtocolsCR,
 		},
 		CollectorNotReadyGracePeriod: DefaultCollectorNotReadyGracePeriod,
+		CollectorHealth: CollectorHealthConfig{
+			HeartbeatTimeout: DefaultCollectorHeartbeatTimeout,
+			RecoveryDelay:    DefaultCollectorRecoveryDelay,
+		},
+		AllocationState: AllocationStateConfig{
+			ConfigMapName: DefaultAllocationStateConfigMapName,
+			SaveInterval:  DefaultAllocationStateSaveInterval,
+		},
+		LeaderElection: LeaderElectionConfig{
+			LeaseName:     DefaultLeaderElectionLeaseName,
+			LeaseDuration: DefaultLeaderElectionLeaseDuration,
+			RenewDeadline: DefaultLeaderElectionRenewDeadline,
+			RetryPeriod:   DefaultLeaderElectionRetryPeriod,
+		},
+		ReplicaRecommendation: ReplicaRecommendationConfig{
+			MinReplicas:            1,
+			Tolerance:              DefaultRecommendationTolerance,
+			ScaleDownStabilization: DefaultRecommendationStabilization,
+			Interval:               DefaultRecommendationInterval,
+		},
 	}
 }
 
@@ -439,9 +545,58 @@ func ValidateConfig(config *Config) error {
 	if len(config.PrometheusCR.AllowNamespaces) != 0 && len(config.PrometheusCR.DenyNamespaces) != 0 {
 		return errors.New("only one of allowNamespaces or denyNamespaces can be set")
 	}
+	if config.CollectorHealth.MaxSeries < 0 || config.CollectorHealth.MaxMemoryUsageRatio < 0 || config.CollectorHealth.MaxScrapeFailureRatio < 0 {
+		return errors.New("collector health limits cannot be negative")
+	}
+	for _, jobStrategy := range config.JobAllocationStrategies {
+		if jobStrategy.Strategy == "" {
+			return fmt.Errorf("the allocation strategy of the jobs %s must be set", jobStrategy.JobName)
+		}
+		if _, err := regexp.Compile(jobStrategy.JobName); err != nil {
+			return fmt.Errorf("invalid job name of the %s allocation strategy: %w", jobStrategy.Strategy, err)
+		}
+	}
+	// the spillover ratio is only used by the topology-aware strategy, which has a default for an unset ratio
+	if config.UsesAllocationStrategy("topology-aware") && config.Topology.SpilloverRatio != 0 && config.Topology.SpilloverRatio < 1 {
+		return errors.New("topology spillover ratio must be at least 1")
+	}
+	if recommendation := config.ReplicaRecommendation; recommendation.Enabled {
+		if recommendation.TargetsPerCollector <= 0 && recommendation.SeriesPerCollector <= 0 {
+			return errors.New("replica recommendation requires a targets or series per collector budget")
+		}
+		if recommendation.TargetsPerCollector < 0 || recommendation.SeriesPerCollector < 0 || recommendation.MinReplicas < 0 {
+			return errors.New("replica recommendation budgets and minimum replicas cannot be negative")
+		}
+		if recommendation.MaxReplicas != 0 && recommendation.MaxReplicas < recommendation.MinReplicas {
+			return errors.New("replica recommendation maximum replicas cannot be lower than the minimum replicas")
+		}
+		if recommendation.Tolerance < 0 || recommendation.Interval <= 0 {
+			return errors.New("replica recommendation tolerance cannot be negative and interval must be positive")
+		}
+	}
+	if config.AllocationState.Enabled && config.AllocationState.SaveInterval <= 0 {
+		return errors.New("allocation state save interval must be positive")
+	}
+	if config.LeaderElection.Enabled && (!config.AllocationState.Enabled || config.AllocationState.FilePath != "") {
+		return errors.New("leader election requires the allocation state to be saved to a ConfigMap")
+	}
 	return nil
 }
 
+// UsesAllocationStrategy reports whether the strategy is the allocation strategy, the fallback strategy or the
+// strategy of some jobs.
+func (c *Config) UsesAllocationStrategy(strategy string) bool {
+	if c.AllocationStrategy == strategy || c.AllocationFallbackStrategy == strategy {
+		return true
+	}
+	for _, jobStrategy := range c.JobAllocationStrategies {
+		if jobStrategy.Strategy == strategy {
+			return true
+		}
+	}
+	return false
+}
+
 func (c HTTPSServerConfig) NewTLSConfig(logger logr.Logger) (*tls.Config, *certwatcher.CertWatcher, error) {
 	// Create certwatcher for server certificate/key reloading
 	certWatcher, err := certwatcher.New(c.TLSCertFilePath, c.TLSKeyFilePath)
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp/syntax"
 	"testing"
 	"time"
 
@@ -60,6 +61,26 @@ func TestLoadFromFile(t *testing.T) {
 					ScrapeProtocols:                 defaultScrapeProtocolsCR,
 				},
 				CollectorNotReadyGracePeriod: 30 * time.Second,
+				CollectorHealth: CollectorHealthConfig{
+					HeartbeatTimeout: DefaultCollectorHeartbeatTimeout,
+					RecoveryDelay:    DefaultCollectorRecoveryDelay,
+				},
+				AllocationState: AllocationStateConfig{
+					ConfigMapName: DefaultAllocationStateConfigMapName,
+					SaveInterval:  DefaultAllocationStateSaveInterval,
+				},
+				LeaderElection: LeaderElectionConfig{
+					LeaseName:     DefaultLeaderElectionLeaseName,
+					LeaseDuration: DefaultLeaderElectionLeaseDuration,
+					RenewDeadline: DefaultLeaderElectionRenewDeadline,
+					RetryPeriod:   DefaultLeaderElectionRetryPeriod,
+				},
+				ReplicaRecommendation: ReplicaRecommendationConfig{
+					MinReplicas:            1,
+					Tolerance:              DefaultRecommendationTolerance,
+					ScaleDownStabilization: DefaultRecommendationStabilization,
+					Interval:               DefaultRecommendationInterval,
+				},
 				HTTPS: HTTPSServerConfig{
 					Enabled:         true,
 					ListenAddr:      ":8443",
@@ -156,6 +177,26 @@ func TestLoadFromFile(t *testing.T) {
 					ScrapeProtocols:                 defaultScrapeProtocolsCR,
 				},
 				CollectorNotReadyGracePeriod: 30 * time.Second,
+				CollectorHealth: CollectorHealthConfig{
+					HeartbeatTimeout: DefaultCollectorHeartbeatTimeout,
+					RecoveryDelay:    DefaultCollectorRecoveryDelay,
+				},
+				AllocationState: AllocationStateConfig{
+					ConfigMapName: DefaultAllocationStateConfigMapName,
+					SaveInterval:  DefaultAllocationStateSaveInterval,
+				},
+				LeaderElection: LeaderElectionConfig{
+					LeaseName:     DefaultLeaderElectionLeaseName,
+					LeaseDuration: DefaultLeaderElectionLeaseDuration,
+					RenewDeadline: DefaultLeaderElectionRenewDeadline,
+					RetryPeriod:   DefaultLeaderElectionRetryPeriod,
+				},
+				ReplicaRecommendation: ReplicaRecommendationConfig{
+					MinReplicas:            1,
+					Tolerance:              DefaultRecommendationTolerance,
+					ScaleDownStabilization: DefaultRecommendationStabilization,
+					Interval:               DefaultRecommendationInterval,
+				},
 				HTTPS: HTTPSServerConfig{
 					Enabled:         true,
 					ListenAddr:      ":8443",
@@ -327,6 +368,26 @@ func TestLoadFromFile(t *testing.T) {
 					},
 				},
 				CollectorNotReadyGracePeriod: 30 * time.Second,
+				CollectorHealth: CollectorHealthConfig{
+					HeartbeatTimeout: DefaultCollectorHeartbeatTimeout,
+					RecoveryDelay:    DefaultCollectorRecoveryDelay,
+				},
+				AllocationState: AllocationStateConfig{
+					ConfigMapName: DefaultAllocationStateConfigMapName,
+					SaveInterval:  DefaultAllocationStateSaveInterval,
+				},
+				LeaderElection: LeaderElectionConfig{
+					LeaseName:     DefaultLeaderElectionLeaseName,
+					LeaseDuration: DefaultLeaderElectionLeaseDuration,
+					RenewDeadline: DefaultLeaderElectionRenewDeadline,
+					RetryPeriod:   DefaultLeaderElectionRetryPeriod,
+				},
+				ReplicaRecommendation: ReplicaRecommendationConfig{
+					MinReplicas:            1,
+					Tolerance:              DefaultRecommendationTolerance,
+					ScaleDownStabilization: DefaultRecommendationStabilization,
+					Interval:               DefaultRecommendationInterval,
+				},
 			},
 			wantErr: assert.NoError,
 		},
@@ -424,6 +485,26 @@ func TestLoadFromFile(t *testing.T) {
 					},
 				},
 				CollectorNotReadyGracePeriod: 30 * time.Second,
+				CollectorHealth: CollectorHealthConfig{
+					HeartbeatTimeout: DefaultCollectorHeartbeatTimeout,
+					RecoveryDelay:    DefaultCollectorRecoveryDelay,
+				},
+				AllocationState: AllocationStateConfig{
+					ConfigMapName: DefaultAllocationStateConfigMapName,
+					SaveInterval:  DefaultAllocationStateSaveInterval,
+				},
+				LeaderElection: LeaderElectionConfig{
+					LeaseName:     DefaultLeaderElectionLeaseName,
+					LeaseDuration: DefaultLeaderElectionLeaseDuration,
+					RenewDeadline: DefaultLeaderElectionRenewDeadline,
+					RetryPeriod:   DefaultLeaderElectionRetryPeriod,
+				},
+				ReplicaRecommendation: ReplicaRecommendationConfig{
+					MinReplicas:            1,
+					Tolerance:              DefaultRecommendationTolerance,
+					ScaleDownStabilization: DefaultRecommendationStabilization,
+					Interval:               DefaultRecommendationInterval,
+				},
 			},
 			wantErr: assert.NoError,
 		},
@@ -545,6 +626,26 @@ func TestLoadFromFile(t *testing.T) {
 					},
 				},
 				CollectorNotReadyGracePeriod: 30 * time.Second,
+				CollectorHealth: CollectorHealthConfig{
+					HeartbeatTimeout: DefaultCollectorHeartbeatTimeout,
+					RecoveryDelay:    DefaultCollectorRecoveryDelay,
+				},
+				AllocationState: AllocationStateConfig{
+					ConfigMapName: DefaultAllocationStateConfigMapName,
+					SaveInterval:  DefaultAllocationStateSaveInterval,
+				},
+				LeaderElection: LeaderElectionConfig{
+					LeaseName:     DefaultLeaderElectionLeaseName,
+					LeaseDuration: DefaultLeaderElectionLeaseDuration,
+					RenewDeadline: DefaultLeaderElectionRenewDeadline,
+					RetryPeriod:   DefaultLeaderElectionRetryPeriod,
+				},
+				ReplicaRecommendation: ReplicaRecommendationConfig{
+					MinReplicas:            1,
+					Tolerance:              DefaultRecommendationTolerance,
+					ScaleDownStabilization: DefaultRecommendationStabilization,
+					Interval:               DefaultRecommendationInterval,
+				},
 			},
 			wantErr: assert.NoError,
 		},
@@ -647,6 +748,26 @@ func TestLoadFromFile(t *testing.T) {
 					},
 				},
 				CollectorNotReadyGracePeriod: 30 * time.Second,
+				CollectorHealth: CollectorHealthConfig{
+					HeartbeatTimeout: DefaultCollectorHeartbeatTimeout,
+					RecoveryDelay:    DefaultCollectorRecoveryDelay,
+				},
+				AllocationState: AllocationStateConfig{
+					ConfigMapName: DefaultAllocationStateConfigMapName,
+					SaveInterval:  DefaultAllocationStateSaveInterval,
+				},
+				LeaderElection: LeaderElectionConfig{
+					LeaseName:     DefaultLeaderElectionLeaseName,
+					LeaseDuration: DefaultLeaderElectionLeaseDuration,
+					RenewDeadline: DefaultLeaderElectionRenewDeadline,
+					RetryPeriod:   DefaultLeaderElectionRetryPeriod,
+				},
+				ReplicaRecommendation: ReplicaRecommendationConfig{
+					MinReplicas:            1,
+					Tolerance:              DefaultRecommendationTolerance,
+					ScaleDownStabilization: DefaultRecommendationStabilization,
+					Interval:               DefaultRecommendationInterval,
+				},
 			},
 			wantErr: assert.NoError,
 		},
@@ -768,6 +889,26 @@ func TestLoadFromFile(t *testing.T) {
 					},
 				},
 				CollectorNotReadyGracePeriod: 30 * time.Second,
+				CollectorHealth: CollectorHealthConfig{
+					HeartbeatTimeout: DefaultCollectorHeartbeatTimeout,
+					RecoveryDelay:    DefaultCollectorRecoveryDelay,
+				},
+				AllocationState: AllocationStateConfig{
+					ConfigMapName: DefaultAllocationStateConfigMapName,
+					SaveInterval:  DefaultAllocationStateSaveInterval,
+				},
+				LeaderElection: LeaderElectionConfig{
+					LeaseName:     DefaultLeaderElectionLeaseName,
+					LeaseDuration: DefaultLeaderElectionLeaseDuration,
+					RenewDeadline: DefaultLeaderElectionRenewDeadline,
+					RetryPeriod:   DefaultLeaderElectionRetryPeriod,
+				},
+				ReplicaRecommendation: ReplicaRecommendationConfig{
+					MinReplicas:            1,
+					Tolerance:              DefaultRecommendationTolerance,
+					ScaleDownStabilization: DefaultRecommendationStabilization,
+					Interval:               DefaultRecommendationInterval,
+				},
 			},
 			wantErr: assert.NoError,
 		},
@@ -873,6 +1014,127 @@ func TestValidateConfig(t *testing.T) {
 			},
 			expectedErr: errors.New("only one of allowNamespaces or denyNamespaces can be set"),
 		},
+		{
+			name: "job strategy without strategy",
+			fileConfig: Config{
+				PrometheusCR:            PrometheusCRConfig{Enabled: true},
+				CollectorNamespace:      "default",
+				JobAllocationStrategies: []JobAllocationStrategy{{JobName: "kubelet"}},
+			},
+			expectedErr: errors.New("the allocation strategy of the jobs kubelet must be set"),
+		},
+		{
+			name: "job strategy with invalid job name",
+			fileConfig: Config{
+				PrometheusCR:            PrometheusCRConfig{Enabled: true},
+				CollectorNamespace:      "default",
+				JobAllocationStrategies: []JobAllocationStrategy{{JobName: "kubelet(", Strategy: "per-node"}},
+			},
+			expectedErr: fmt.Errorf("invalid job name of the per-node allocation strategy: %w", &syntax.Error{Code: syntax.ErrMissingParen, Expr: "kubelet("}),
+		},
+		{
+			name: "job strategies",
+			fileConfig: Config{
+				PrometheusCR:            PrometheusCRConfig{Enabled: true},
+				CollectorNamespace:      "default",
+				JobAllocationStrategies: []JobAllocationStrategy{{JobName: "kubelet|cadvisor", Strategy: "per-node"}},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "topology spillover ratio below 1",
+			fileConfig: Config{
+				PrometheusCR:       PrometheusCRConfig{Enabled: true},
+				CollectorNamespace: "default",
+				AllocationStrategy: "topology-aware",
+				Topology:           TopologyConfig{SpilloverRatio: 0.5},
+			},
+			expectedErr: errors.New("topology spillover ratio must be at least 1"),
+		},
+		{
+			name: "topology spillover ratio of a job strategy",
+			fileConfig: Config{
+				PrometheusCR:            PrometheusCRConfig{Enabled: true},
+				CollectorNamespace:      "default",
+				AllocationStrategy:      "consistent-hashing",
+				JobAllocationStrategies: []JobAllocationStrategy{{JobName: "kube-state-metrics", Strategy: "topology-aware"}},
+				Topology:                TopologyConfig{SpilloverRatio: 0.5},
+			},
+			expectedErr: errors.New("topology spillover ratio must be at least 1"),
+		},
+		{
+			name: "topology spillover ratio unset",
+			fileConfig: Config{
+				PrometheusCR:       PrometheusCRConfig{Enabled: true},
+				CollectorNamespace: "default",
+				AllocationStrategy: "topology-aware",
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "topology spillover ratio unused",
+			fileConfig: Config{
+				PrometheusCR:       PrometheusCRConfig{Enabled: true},
+				CollectorNamespace: "default",
+				AllocationStrategy: "consistent-hashing",
+				Topology:           TopologyConfig{SpilloverRatio: 0.5},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "replica recommendation without budget",
+			fileConfig: Config{
+				PrometheusCR:          PrometheusCRConfig{Enabled: true},
+				CollectorNamespace:    "default",
+				ReplicaRecommendation: ReplicaRecommendationConfig{Enabled: true, Interval: time.Minute},
+			},
+			expectedErr: errors.New("replica recommendation requires a targets or series per collector budget"),
+		},
+		{
+			name: "replica recommendation with negative budget",
+			fileConfig: Config{
+				PrometheusCR:          PrometheusCRConfig{Enabled: true},
+				CollectorNamespace:    "default",
+				ReplicaRecommendation: ReplicaRecommendationConfig{Enabled: true, TargetsPerCollector: 100, SeriesPerCollector: -1, Interval: time.Minute},
+			},
+			expectedErr: errors.New("replica recommendation budgets and minimum replicas cannot be negative"),
+		},
+		{
+			name: "replica recommendation with maximum below minimum",
+			fileConfig: Config{
+				PrometheusCR:          PrometheusCRConfig{Enabled: true},
+				CollectorNamespace:    "default",
+				ReplicaRecommendation: ReplicaRecommendationConfig{Enabled: true, TargetsPerCollector: 100, MinReplicas: 3, MaxReplicas: 2, Interval: time.Minute},
+			},
+			expectedErr: errors.New("replica recommendation maximum replicas cannot be lower than the minimum replicas"),
+		},
+		{
+			name: "replica recommendation without interval",
+			fileConfig: Config{
+				PrometheusCR:          PrometheusCRConfig{Enabled: true},
+				CollectorNamespace:    "default",
+				ReplicaRecommendation: ReplicaRecommendationConfig{Enabled: true, TargetsPerCollector: 100},
+			},
+			expectedErr: errors.New("replica recommendation tolerance cannot be negative and interval must be positive"),
+		},
+		{
+			name: "replica recommendation",
+			fileConfig: Config{
+				PrometheusCR:          PrometheusCRConfig{Enabled: true},
+				CollectorNamespace:    "default",
+				ReplicaRecommendation: ReplicaRecommendationConfig{Enabled: true, SeriesPerCollector: 2000000, MinReplicas: 1, MaxReplicas: 10, Tolerance: 0.1, Interval: time.Minute},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "replica recommendation disabled",
+			fileConfig: Config{
+				PrometheusCR:          PrometheusCRConfig{Enabled: true},
+				CollectorNamespace:    "default",
+				ReplicaRecommendation: ReplicaRecommendationConfig{TargetsPerCollector: -1},
+			},
+			expectedErr: nil,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -929,44 +1191,33 @@ func TestGetSecretsAllowList(t *testing.T) {
 	testCases := []struct {
 		name                     string
 		promCRConfig             PrometheusCRConfig
-		collectorNamespace       string
 		expectedSecretsAllowList map[string]struct{}
 	}{
 		{
-			name:                     "no secrets namespaces configured, defaults to collector namespace",
+			name:                     "no secrets namespaces configured, watches nothing",
 			promCRConfig:             PrometheusCRConfig{Enabled: true},
-			collectorNamespace:       "ta-namespace",
-			expectedSecretsAllowList: map[string]struct{}{"ta-namespace": {}},
-		},
-		{
-			name:                     "no secrets namespaces and no collector namespace",
-			promCRConfig:             PrometheusCRConfig{Enabled: true},
-			collectorNamespace:       "",
 			expectedSecretsAllowList: map[string]struct{}{},
 		},
 		{
-			name:                     "single namespace overrides default",
-			promCRConfig:             PrometheusCRConfig{Enabled: true, SecretNamespaces: []string{"ns1"}},
-			collectorNamespace:       "ta-namespace",
+			name:                     "single namespace",
+			promCRConfig:             PrometheusCRConfig{Enabled: true, SecretsAccessNamespaces: []string{"ns1"}},
 			expectedSecretsAllowList: map[string]struct{}{"ns1": {}},
 		},
 		{
 			name:                     "multiple namespaces",
-			promCRConfig:             PrometheusCRConfig{Enabled: true, SecretNamespaces: []string{"ns1", "ns2", "ns3"}},
-			collectorNamespace:       "ta-namespace",
+			promCRConfig:             PrometheusCRConfig{Enabled: true, SecretsAccessNamespaces: []string{"ns1", "ns2", "ns3"}},
 			expectedSecretsAllowList: map[string]struct{}{"ns1": {}, "ns2": {}, "ns3": {}},
 		},
 		{
-			name:                     "empty slice defaults to collector namespace",
-			promCRConfig:             PrometheusCRConfig{Enabled: true, SecretNamespaces: []string{}},
-			collectorNamespace:       "ta-namespace",
-			expectedSecretsAllowList: map[string]struct{}{"ta-namespace": {}},
+			name:                     "empty slice watches nothing",
+			promCRConfig:             PrometheusCRConfig{Enabled: true, SecretsAccessNamespaces: []string{}},
+			expectedSecretsAllowList: map[string]struct{}{},
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			secretsAllowList := tc.promCRConfig.GetSecretsAllowList(tc.collectorNamespace)
+			secretsAllowList := tc.promCRConfig.GetSecretsAllowList()
 			assert.Equal(t, tc.expectedSecretsAllowList, secretsAllowList)
 		})
 	}
@@ -0,0 +1,121 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// ErrLeadershipLost is returned by Run when the Target Allocator stops leading. It is not expected to lead again,
+// as its discovery and allocation cannot be handed over cleanly, so it has to restart as a follower.
+var ErrLeadershipLost = errors.New("leadership lost")
+
+// Config configures the election of the leader between the Target Allocator replicas.
+type Config struct {
+	LeaseName      string
+	LeaseNamespace string
+	// Identity is the address the other replicas reach this one at, to forward requests to the leader.
+	Identity      string
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// Elector elects a leader between the Target Allocator replicas through a Kubernetes Lease.
+type Elector struct {
+	log     logr.Logger
+	elector *leaderelection.LeaderElector
+
+	// leading tracks the onStartedLeading call, which Run waits for
+	leading sync.WaitGroup
+	mtx     sync.Mutex
+	stopped bool
+}
+
+// NewElector returns an Elector calling onStartedLeading with a context canceled when the leadership is lost. Run
+// waits for onStartedLeading to return.
+func NewElector(log logr.Logger, client kubernetes.Interface, cfg Config, onStartedLeading func(ctx context.Context)) (*Elector, error) {
+	meter := otel.GetMeterProvider().Meter("targetallocator")
+	isLeader, err := meter.Int64Gauge("opentelemetry_allocator_leader", metric.WithDescription("Whether this Target Allocator is the leader."))
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Elector{log: log.WithValues("component", "opentelemetry-targetallocator-leader-election")}
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: cfg.LeaseName, Namespace: cfg.LeaseNamespace},
+		Client:     client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: cfg.Identity},
+	}
+	e.elector, err = leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   cfg.LeaseDuration,
+		RenewDeadline:   cfg.RenewDeadline,
+		RetryPeriod:     cfg.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            cfg.LeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				e.mtx.Lock()
+				if e.stopped {
+					e.mtx.Unlock()
+					return
+				}
+				e.leading.Add(1)
+				e.mtx.Unlock()
+				defer e.leading.Done()
+
+				e.log.Info("Started leading", "identity", cfg.Identity)
+				isLeader.Record(context.Background(), 1)
+				onStartedLeading(ctx)
+			},
+			OnStoppedLeading: func() {
+				isLeader.Record(context.Background(), 0)
+			},
+			OnNewLeader: func(identity string) {
+				e.log.Info("New leader elected", "leader", identity)
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	isLeader.Record(context.Background(), 0)
+	return e, nil
+}
+
+// Run takes part in the election until ctx is canceled, or returns ErrLeadershipLost once this replica led and could
+// not renew its lease.
+func (e *Elector) Run(ctx context.Context) error {
+	e.elector.Run(ctx)
+	e.mtx.Lock()
+	e.stopped = true
+	e.mtx.Unlock()
+	e.leading.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return ErrLeadershipLost
+}
+
+// IsLeader reports whether this replica is the leader.
+func (e *Elector) IsLeader() bool {
+	return e.elector.IsLeader()
+}
+
+// Leader returns the identity of the current leader, or an empty string if it is not known yet.
+func (e *Elector) Leader() string {
+	return e.elector.GetLeader()
+}
@@ -0,0 +1,102 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var logger = logf.Log.WithName("unit-tests")
+
+func testConfig(identity string) Config {
+	return Config{
+		LeaseName:      "test-lease",
+		LeaseNamespace: "test-namespace",
+		Identity:       identity,
+		LeaseDuration:  time.Second,
+		RenewDeadline:  500 * time.Millisecond,
+		RetryPeriod:    100 * time.Millisecond,
+	}
+}
+
+// startElector runs an elector until the returned cancel func is called, returning a channel closed when it leads and
+// a channel receiving the result of Run.
+func startElector(t *testing.T, client kubernetes.Interface, identity string) (*Elector, <-chan struct{}, <-chan error, context.CancelFunc) {
+	leading := make(chan struct{})
+	elector, err := NewElector(logger, client, testConfig(identity), func(ctx context.Context) {
+		close(leading)
+		<-ctx.Done()
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() {
+		done <- elector.Run(ctx)
+	}()
+	return elector, leading, done, cancel
+}
+
+func TestElectorFailover(t *testing.T) {
+	client := fake.NewClientset()
+	first, firstLeading, firstDone, firstCancel := startElector(t, client, "10.0.0.1:8080")
+	defer firstCancel()
+	select {
+	case <-firstLeading:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the first replica should lead")
+	}
+	assert.True(t, first.IsLeader())
+
+	second, secondLeading, secondDone, secondCancel := startElector(t, client, "10.0.0.2:8080")
+	defer secondCancel()
+	require.Eventually(t, func() bool {
+		return second.Leader() == "10.0.0.1:8080"
+	}, 5*time.Second, 10*time.Millisecond, "the follower should know the leader")
+	assert.False(t, second.IsLeader())
+
+	// the leader releases its lease when stopped
+	firstCancel()
+	require.NoError(t, <-firstDone)
+	select {
+	case <-secondLeading:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the second replica should take over")
+	}
+	assert.Equal(t, "10.0.0.2:8080", second.Leader())
+
+	secondCancel()
+	require.NoError(t, <-secondDone)
+}
+
+func TestElectorLeadershipLost(t *testing.T) {
+	client := fake.NewClientset()
+	_, leading, done, cancel := startElector(t, client, "10.0.0.1:8080")
+	defer cancel()
+	select {
+	case <-leading:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the replica should lead")
+	}
+
+	client.PrependReactor("update", "leases", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("API server unavailable")
+	})
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrLeadershipLost)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the replica should stop leading when it cannot renew its lease")
+	}
+}
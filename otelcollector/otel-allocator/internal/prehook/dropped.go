@@ -0,0 +1,80 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prehook
+
+import (
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+)
+
+// maxDroppedTargetsPerJob bounds the dropped targets kept for each job, as a job can discover many more targets than
+// it keeps.
+const maxDroppedTargetsPerJob = 100
+
+// The internal rules added around the relabel_configs of each job by addNoShardingConfig.
+const (
+	noShardingRuleName          = "disable-sharding"
+	dropNoShardingLabelRuleName = "drop-disable-sharding-label"
+)
+
+// DroppedTarget is a discovered target dropped by the relabel rules of its job.
+type DroppedTarget struct {
+	TargetURL        string
+	DiscoveredLabels labels.Labels
+	// RuleIndex is the index of the rule that dropped the target in the relabel_configs of the job, or -1 when the
+	// target was dropped by an internal rule.
+	RuleIndex int
+	// InternalRule is the name of the internal rule that dropped the target, if the rule is not one of the job.
+	InternalRule string
+	Rule         *relabel.Config
+}
+
+// DroppedTargets are the targets of a job dropped by the last filtering, up to maxDroppedTargetsPerJob of them.
+type DroppedTargets struct {
+	Targets []DroppedTarget
+	// Count is the number of targets dropped, including the ones that were not kept.
+	Count int
+}
+
+// DroppedTargetsRecorder is implemented by the hooks which keep the targets they dropped.
+type DroppedTargetsRecorder interface {
+	// DroppedTargets returns the dropped targets by job name.
+	DroppedTargets() map[string]DroppedTargets
+}
+
+// recordDroppedTarget adds a target dropped by the rule at ruleIndex of the relabel configs of the hook, which are the
+// relabel_configs of the job between the rules added by addNoShardingConfig.
+func recordDroppedTarget(dropped map[string]DroppedTargets, jobName, targetURL string, discoveredLabels labels.Labels, cfgs []*relabel.Config, ruleIndex int) {
+	jobDropped := dropped[jobName]
+	jobDropped.Count++
+	if len(jobDropped.Targets) < maxDroppedTargetsPerJob {
+		droppedTarget := DroppedTarget{
+			TargetURL:        targetURL,
+			DiscoveredLabels: discoveredLabels,
+			RuleIndex:        ruleIndex - 1,
+			Rule:             cfgs[ruleIndex],
+		}
+		switch ruleIndex {
+		case 0:
+			droppedTarget.RuleIndex = -1
+			droppedTarget.InternalRule = noShardingRuleName
+		case len(cfgs) - 1:
+			droppedTarget.RuleIndex = -1
+			droppedTarget.InternalRule = dropNoShardingLabelRuleName
+		}
+		jobDropped.Targets = append(jobDropped.Targets, droppedTarget)
+	}
+	dropped[jobName] = jobDropped
+}
+
+// processBuilder applies the relabel configs like relabel.ProcessBuilder, and also returns the index of the config
+// which dropped the target.
+func processBuilder(builder *labels.Builder, cfgs []*relabel.Config) (bool, int) {
+	for i, cfg := range cfgs {
+		if !relabel.ProcessBuilder(builder, cfg) {
+			return false, i
+		}
+	}
+	return true, -1
+}
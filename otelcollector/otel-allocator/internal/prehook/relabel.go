@@ -6,6 +6,7 @@ package prehook
 import (
 	"maps"
 	"slices"
+	"sync"
 
 	"github.com/go-logr/logr"
 	"github.com/prometheus/prometheus/model/labels"
@@ -22,6 +23,10 @@ const (
 type relabelConfigTargetFilter struct {
 	log        logr.Logger
 	relabelCfg map[string][]*relabel.Config
+
+	// droppedMtx protects dropped, the targets dropped by the last Apply, read by the server
+	droppedMtx sync.RWMutex
+	dropped    map[string]DroppedTargets
 }
 
 func newRelabelConfigTargetFilter(log logr.Logger) Hook {
@@ -40,32 +45,53 @@ func (tf *relabelConfigTargetFilter) Apply(targets []*target.Item) []*target.Ite
 	}
 
 	builder := labels.NewBuilder(labels.EmptyLabels())
+	dropped := make(map[string]DroppedTargets)
 	writeIndex := 0
 	for _, tItem := range targets {
 		builder.Reset(tItem.Labels)
-		keepTarget := relabel.ProcessBuilder(builder, tf.relabelCfg[tItem.JobName]...)
+		cfgs := tf.relabelCfg[tItem.JobName]
+		keepTarget, ruleIndex := processBuilder(builder, cfgs)
 
 		if keepTarget {
 			// Compute hash immediately while we have the builder, skipping meta labels.
 			// This avoids materializing the filtered labels.
 			hash := target.HashFromBuilder(builder, tItem.JobName)
+			opts := []target.ItemOption{target.WithHash(hash)}
+			if weight := builder.Get(target.WeightLabel); weight != "" {
+				opts = append(opts, target.WithWeight(target.ParseWeight(weight)))
+			}
 			targets[writeIndex] = target.NewItem(
 				tItem.JobName,
 				tItem.TargetURL,
 				tItem.Labels,
 				tItem.CollectorName,
-				target.WithHash(hash),
+				opts...,
 			)
 			writeIndex++
+		} else {
+			recordDroppedTarget(dropped, tItem.JobName, tItem.TargetURL, tItem.Labels, cfgs, ruleIndex)
 		}
 	}
 
+	tf.droppedMtx.Lock()
+	tf.dropped = dropped
+	tf.droppedMtx.Unlock()
+
 	targets = targets[:writeIndex]
 	targets = slices.Clip(targets)
 	tf.log.V(2).Info("Filtering complete", "seen", numTargets, "kept", len(targets))
 	return targets
 }
 
+// DroppedTargets returns the targets dropped by the last Apply.
+func (tf *relabelConfigTargetFilter) DroppedTargets() map[string]DroppedTargets {
+	tf.droppedMtx.RLock()
+	defer tf.droppedMtx.RUnlock()
+	dropped := make(map[string]DroppedTargets, len(tf.dropped))
+	maps.Copy(dropped, tf.dropped)
+	return dropped
+}
+
 func (tf *relabelConfigTargetFilter) SetConfig(cfgs map[string][]*relabel.Config) {
 	relabelCfgCopy := make(map[string][]*relabel.Config)
 	for key, val := range cfgs {
@@ -685,3 +685,72 @@ func TestApplyMultipleJobsIndependent(t *testing.T) {
 		assert.Equal(t, "job-keep", item.JobName)
 	}
 }
+
+func TestApplyRecordsDroppedTargets(t *testing.T) {
+	hook := New("relabel-config", logger)
+	recorder, ok := hook.(DroppedTargetsRecorder)
+	assert.True(t, ok)
+
+	targets := make([]*target.Item, maxDroppedTargetsPerJob+10)
+	for i := range targets {
+		ls := labels.New(
+			labels.Label{Name: "i", Value: strconv.Itoa(i)},
+			labels.Label{Name: "__meta_kubernetes_namespace", Value: "default"},
+		)
+		targets[i] = target.NewItem("job1", fmt.Sprintf("url-%d", i), ls, "")
+	}
+	keepRule := &relabel.Config{
+		SourceLabels:         model.LabelNames{"__meta_kubernetes_namespace"},
+		Regex:                relabel.MustNewRegexp("default"),
+		Separator:            ";",
+		Action:               "keep",
+		Replacement:          "$1",
+		NameValidationScheme: model.UTF8Validation,
+	}
+	dropRule := &relabel.Config{
+		SourceLabels:         model.LabelNames{"i"},
+		Regex:                relabel.MustNewRegexp("0"),
+		Separator:            ";",
+		Action:               "keep",
+		Replacement:          "$1",
+		NameValidationScheme: model.UTF8Validation,
+	}
+	hook.SetConfig(map[string][]*relabel.Config{"job1": {keepRule, dropRule}})
+
+	result := hook.Apply(targets)
+	assert.Len(t, result, 1)
+
+	dropped := recorder.DroppedTargets()
+	assert.Equal(t, len(targets)-1, dropped["job1"].Count)
+	assert.Len(t, dropped["job1"].Targets, maxDroppedTargetsPerJob)
+	first := dropped["job1"].Targets[0]
+	assert.Equal(t, "url-1", first.TargetURL)
+	assert.Equal(t, 1, first.RuleIndex)
+	assert.Equal(t, dropRule, first.Rule)
+	assert.Equal(t, "1", first.DiscoveredLabels.Get("i"))
+
+	// The dropped targets are the ones of the last filtering
+	hook.SetConfig(map[string][]*relabel.Config{"job1": {keepRule}})
+	hook.Apply(targets[:2])
+	assert.Empty(t, recorder.DroppedTargets())
+}
+
+func TestRecordDroppedTargetRuleIndex(t *testing.T) {
+	userRule := &relabel.Config{Action: relabel.Drop, Regex: relabel.MustNewRegexp(".*")}
+	cfgs := addNoShardingConfig([]*relabel.Config{userRule})
+	dropped := make(map[string]DroppedTargets)
+
+	for ruleIndex := range cfgs {
+		recordDroppedTarget(dropped, "job1", "url", labels.EmptyLabels(), cfgs, ruleIndex)
+	}
+
+	targets := dropped["job1"].Targets
+	assert.Len(t, targets, 3)
+	assert.Equal(t, -1, targets[0].RuleIndex)
+	assert.Equal(t, noShardingRuleName, targets[0].InternalRule)
+	assert.Equal(t, 0, targets[1].RuleIndex)
+	assert.Empty(t, targets[1].InternalRule)
+	assert.Equal(t, userRule, targets[1].Rule)
+	assert.Equal(t, -1, targets[2].RuleIndex)
+	assert.Equal(t, dropNoShardingLabelRuleName, targets[2].InternalRule)
+}
@@ -0,0 +1,221 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package recommendation recommends the number of collectors for the allocated targets, so that an autoscaler can
+// scale the collectors through a custom or external metrics adapter.
+package recommendation
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/allocation"
+)
+
+// Reasons for a recommendation.
+const (
+	ReasonTargets       = "targets"
+	ReasonSeries        = "series"
+	ReasonTolerance     = "tolerance"
+	ReasonStabilization = "stabilization"
+	ReasonMinReplicas   = "min_replicas"
+	ReasonMaxReplicas   = "max_replicas"
+)
+
+// Config holds the budgets of a collector and the hysteresis of the recommendation. A zero budget is not used.
+type Config struct {
+	// TargetsPerCollector is the number of targets a collector can scrape.
+	TargetsPerCollector int
+	// SeriesPerCollector is the number of series a collector can scrape, compared with the series count of the
+	// collector heartbeats.
+	SeriesPerCollector int64
+	MinReplicas        int
+	MaxReplicas        int
+	// Tolerance is the ratio by which the load can differ from the budgets of the current collectors before another
+	// number of collectors is recommended.
+	Tolerance float64
+	// ScaleDownStabilization is how long the recommendation stays at the highest recommendation of the period before
+	// it decreases.
+	ScaleDownStabilization time.Duration
+}
+
+// Recommendation is the recommended number of collectors and the load it is based on.
+type Recommendation struct {
+	// Replicas is the recommended number of collectors.
+	Replicas int `json:"replicas"`
+	// DesiredReplicas is the number of collectors for the load, before the tolerance, the stabilization and the limits.
+	DesiredReplicas int `json:"desired_replicas"`
+	// CurrentReplicas is the number of collectors the targets are allocated to.
+	CurrentReplicas int `json:"current_replicas"`
+	Targets         int `json:"targets"`
+	// Series is the series count of the collector heartbeats, extrapolated to the targets of the collectors which did
+	// not send any.
+	Series              int64     `json:"series"`
+	TargetsPerCollector int       `json:"targets_per_collector,omitempty"`
+	SeriesPerCollector  int64     `json:"series_per_collector,omitempty"`
+	Reason              string    `json:"reason"`
+	Time                time.Time `json:"time"`
+}
+
+// timedReplicas is a recommendation within the scale down stabilization period.
+type timedReplicas struct {
+	time     time.Time
+	replicas int
+}
+
+// Recommender periodically recommends a number of collectors from the targets and the series of the allocator.
+type Recommender struct {
+	log       logr.Logger
+	allocator allocation.Allocator
+	cfg       Config
+	interval  time.Duration
+	close     chan struct{}
+	now       func() time.Time
+
+	recommendedCollectors metric.Int64Gauge
+
+	mtx sync.RWMutex
+	// history are the recommendations within the scale down stabilization period, oldest first
+	history        []timedReplicas
+	recommendation Recommendation
+	// recommended is set once the first recommendation is made
+	recommended bool
+}
+
+func NewRecommender(log logr.Logger, allocator allocation.Allocator, cfg Config, interval time.Duration) (*Recommender, error) {
+	meter := otel.GetMeterProvider().Meter("targetallocator")
+	recommendedCollectors, err := meter.Int64Gauge("opentelemetry_allocator_recommended_collectors", metric.WithDescription("Recommended number of collectors for the allocated targets."))
+	if err != nil {
+		return nil, err
+	}
+	return &Recommender{
+		log:                   log.WithValues("component", "opentelemetry-targetallocator-recommendation"),
+		allocator:             allocator,
+		cfg:                   cfg,
+		interval:              interval,
+		close:                 make(chan struct{}),
+		now:                   time.Now,
+		recommendedCollectors: recommendedCollectors,
+	}, nil
+}
+
+// Run recommends a number of collectors every interval, once the allocator is ready.
+func (r *Recommender) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if r.allocator.Ready() {
+				r.Recommend()
+			}
+		case <-r.close:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (r *Recommender) Close() {
+	close(r.close)
+}
+
+// Recommendation returns the last recommendation, and false before the first one.
+func (r *Recommender) Recommendation() (Recommendation, bool) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.recommendation, r.recommended
+}
+
+// Recommend recommends a number of collectors for the current targets and series of the allocator, and records it.
+func (r *Recommender) Recommend() Recommendation {
+	collectors := r.allocator.Collectors()
+	recommendation := Recommendation{
+		CurrentReplicas:     len(collectors),
+		Targets:             r.allocator.NumTargetItems(),
+		Series:              seriesCount(collectors),
+		TargetsPerCollector: r.cfg.TargetsPerCollector,
+		SeriesPerCollector:  r.cfg.SeriesPerCollector,
+		Time:                r.now(),
+	}
+
+	// desired is the fractional number of collectors for the load, from the budget the load is the highest for
+	desired, reason := 0.0, ReasonTargets
+	if r.cfg.TargetsPerCollector > 0 {
+		desired = float64(recommendation.Targets) / float64(r.cfg.TargetsPerCollector)
+	}
+	if r.cfg.SeriesPerCollector > 0 {
+		if series := float64(recommendation.Series) / float64(r.cfg.SeriesPerCollector); series > desired {
+			desired, reason = series, ReasonSeries
+		}
+	}
+	recommendation.DesiredReplicas = int(math.Ceil(desired))
+
+	replicas := recommendation.DesiredReplicas
+	current := recommendation.CurrentReplicas
+	if current > 0 && replicas != current && math.Abs(desired/float64(current)-1) <= r.cfg.Tolerance {
+		replicas, reason = current, ReasonTolerance
+	}
+	if replicas < r.cfg.MinReplicas {
+		replicas, reason = r.cfg.MinReplicas, ReasonMinReplicas
+	}
+	if r.cfg.MaxReplicas > 0 && replicas > r.cfg.MaxReplicas {
+		replicas, reason = r.cfg.MaxReplicas, ReasonMaxReplicas
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if stabilized := r.stabilize(recommendation.Time, replicas); stabilized > replicas {
+		replicas, reason = stabilized, ReasonStabilization
+	}
+	recommendation.Replicas, recommendation.Reason = replicas, reason
+
+	if replicas != r.recommendation.Replicas {
+		r.log.Info("Recommending another number of collectors", "replicas", replicas, "current", current, "reason", reason)
+	}
+	r.recommendation = recommendation
+	r.recommended = true
+	r.recommendedCollectors.Record(context.Background(), int64(replicas))
+	return recommendation
+}
+
+// stabilize records the recommendation and returns the highest recommendation of the scale down stabilization period,
+// so that the recommendation only decreases once the load stayed lower for the whole period. The caller of this method
+// has to acquire a lock.
+func (r *Recommender) stabilize(now time.Time, replicas int) int {
+	start := 0
+	for start < len(r.history) && now.Sub(r.history[start].time) > r.cfg.ScaleDownStabilization {
+		start++
+	}
+	r.history = append(r.history[start:], timedReplicas{time: now, replicas: replicas})
+	highest := 0
+	for _, recommendation := range r.history {
+		highest = max(highest, recommendation.replicas)
+	}
+	return highest
+}
+
+// seriesCount returns the series count of the collector heartbeats. The series of the collectors without heartbeats
+// are extrapolated from the series per target of the others.
+func seriesCount(collectors map[string]*allocation.Collector) int64 {
+	var series int64
+	reportedTargets, targets := 0, 0
+	for _, c := range collectors {
+		targets += c.NumTargets
+		if !c.Heartbeat.Time.IsZero() {
+			series += c.Heartbeat.SeriesCount
+			reportedTargets += c.NumTargets
+		}
+	}
+	if reportedTargets == 0 || reportedTargets == targets {
+		return series
+	}
+	return int64(math.Round(float64(series) * float64(targets) / float64(reportedTargets)))
+}
@@ -0,0 +1,102 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package recommendation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/allocation"
+)
+
+var logger = logf.Log.WithName("unit-tests")
+
+func newAllocator(t *testing.T, collectors, targets int) allocation.Allocator {
+	allocator, err := allocation.New("least-weighted", logger)
+	require.NoError(t, err)
+	allocator.SetCollectors(allocation.MakeNCollectors(collectors, 0))
+	allocator.SetTargets(allocation.MakeNNewTargetsWithEmptyCollectors(targets, 0))
+	return allocator
+}
+
+func newRecommender(t *testing.T, allocator allocation.Allocator, cfg Config) (*Recommender, *time.Time) {
+	recommender, err := NewRecommender(logger, allocator, cfg, time.Minute)
+	require.NoError(t, err)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	recommender.now = func() time.Time { return now }
+	return recommender, &now
+}
+
+func TestRecommendTargets(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		cfg      Config
+		replicas int
+		reason   string
+	}{
+		{name: "budget", cfg: Config{TargetsPerCollector: 100}, replicas: 10, reason: ReasonTargets},
+		{name: "partial collector", cfg: Config{TargetsPerCollector: 300}, replicas: 4, reason: ReasonTargets},
+		{name: "max replicas", cfg: Config{TargetsPerCollector: 100, MaxReplicas: 8}, replicas: 8, reason: ReasonMaxReplicas},
+		{name: "min replicas", cfg: Config{TargetsPerCollector: 100000, MinReplicas: 2}, replicas: 2, reason: ReasonMinReplicas},
+		{name: "tolerance", cfg: Config{TargetsPerCollector: 320, Tolerance: 0.1}, replicas: 3, reason: ReasonTolerance},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			recommender, _ := newRecommender(t, newAllocator(t, 3, 1000), tc.cfg)
+			_, recommended := recommender.Recommendation()
+			assert.False(t, recommended)
+			recommendation := recommender.Recommend()
+			assert.Equal(t, tc.replicas, recommendation.Replicas)
+			assert.Equal(t, tc.reason, recommendation.Reason)
+			assert.Equal(t, 3, recommendation.CurrentReplicas)
+			assert.Equal(t, 1000, recommendation.Targets)
+			last, recommended := recommender.Recommendation()
+			assert.True(t, recommended)
+			assert.Equal(t, recommendation, last)
+		})
+	}
+}
+
+func TestRecommendSeries(t *testing.T) {
+	allocator := newAllocator(t, 4, 400)
+	require.NoError(t, allocator.SetCollectorHeartbeat("collector-0", allocation.CollectorHeartbeat{SeriesCount: 300000}))
+	require.NoError(t, allocator.SetCollectorHeartbeat("collector-1", allocation.CollectorHeartbeat{SeriesCount: 500000}))
+
+	recommender, _ := newRecommender(t, allocator, Config{TargetsPerCollector: 200, SeriesPerCollector: 250000})
+	recommendation := recommender.Recommend()
+	// the collectors without heartbeats are counted with the average series per target of the others
+	assert.Equal(t, int64(1600000), recommendation.Series)
+	assert.Equal(t, 7, recommendation.DesiredReplicas)
+	assert.Equal(t, 7, recommendation.Replicas)
+	assert.Equal(t, ReasonSeries, recommendation.Reason)
+}
+
+func TestRecommendScaleDownStabilization(t *testing.T) {
+	allocator := newAllocator(t, 3, 1000)
+	recommender, now := newRecommender(t, allocator, Config{TargetsPerCollector: 100, ScaleDownStabilization: 5 * time.Minute})
+	assert.Equal(t, 10, recommender.Recommend().Replicas)
+
+	allocator.SetTargets(allocation.MakeNNewTargetsWithEmptyCollectors(300, 0))
+	*now = now.Add(time.Minute)
+	recommendation := recommender.Recommend()
+	assert.Equal(t, 10, recommendation.Replicas)
+	assert.Equal(t, 3, recommendation.DesiredReplicas)
+	assert.Equal(t, ReasonStabilization, recommendation.Reason)
+
+	// scaling up is not delayed
+	allocator.SetTargets(allocation.MakeNNewTargetsWithEmptyCollectors(1200, 0))
+	*now = now.Add(time.Minute)
+	assert.Equal(t, 12, recommender.Recommend().Replicas)
+
+	allocator.SetTargets(allocation.MakeNNewTargetsWithEmptyCollectors(300, 0))
+	*now = now.Add(4 * time.Minute)
+	assert.Equal(t, 12, recommender.Recommend().Replicas)
+	*now = now.Add(2 * time.Minute)
+	recommendation = recommender.Recommend()
+	assert.Equal(t, 3, recommendation.Replicas)
+	assert.Equal(t, ReasonTargets, recommendation.Reason)
+}
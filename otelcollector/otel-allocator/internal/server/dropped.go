@@ -0,0 +1,154 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/prometheus/model/labels"
+	"gopkg.in/yaml.v2"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/prehook"
+)
+
+type droppedTargetsJSON struct {
+	// Count is the number of dropped targets, which can be more than the targets listed.
+	Count   int                 `json:"count"`
+	Targets []droppedTargetJSON `json:"targets"`
+}
+
+type droppedTargetJSON struct {
+	TargetURL        string        `json:"target"`
+	DiscoveredLabels labels.Labels `json:"discovered_labels"`
+	// RuleIndex is the index of the rule that dropped the target in the relabel_configs of the job, or -1 when the
+	// target was dropped by an internal rule.
+	RuleIndex int `json:"rule_index"`
+	// InternalRule is the name of the internal rule that dropped the target, if the rule is not one of the job.
+	InternalRule string `json:"internal_rule,omitempty"`
+	Rule         string `json:"rule"`
+}
+
+// WithDroppedTargets serves the targets dropped by the relabel rules of their job.
+func WithDroppedTargets(recorder prehook.DroppedTargetsRecorder) Option {
+	return func(s *Server) {
+		s.droppedTargets = recorder
+	}
+}
+
+// droppedTargetsByJob returns the dropped targets by job name, optionally only the ones of a job.
+func (s *Server) droppedTargetsByJob(jobName string) map[string]droppedTargetsJSON {
+	result := map[string]droppedTargetsJSON{}
+	if s.droppedTargets == nil {
+		return result
+	}
+	for job, dropped := range s.droppedTargets.DroppedTargets() {
+		if jobName != "" && job != jobName {
+			continue
+		}
+		jobDropped := droppedTargetsJSON{Count: dropped.Count, Targets: make([]droppedTargetJSON, 0, len(dropped.Targets))}
+		for _, t := range dropped.Targets {
+			jobDropped.Targets = append(jobDropped.Targets, droppedTargetJSON{
+				TargetURL:        t.TargetURL,
+				DiscoveredLabels: t.DiscoveredLabels,
+				RuleIndex:        t.RuleIndex,
+				InternalRule:     t.InternalRule,
+				Rule:             formatRelabelRule(t.Rule),
+			})
+		}
+		result[job] = jobDropped
+	}
+	return result
+}
+
+// DroppedTargetsHandler returns the targets dropped by the relabel rules of their job, with the rule that dropped them.
+// The dropped targets of a single job are returned with the job_id query parameter.
+func (s *Server) DroppedTargetsHandler(c *gin.Context) {
+	if strings.Contains(c.Request.Header.Get("Accept"), "text/html") {
+		s.DroppedTargetsHTMLHandler(c)
+		return
+	}
+	s.jsonHandler(c.Writer, s.droppedTargetsByJob(c.Query("job_id")))
+}
+
+// DroppedTargetsHTMLHandler displays the dropped targets in a table, with a row per target.
+func (s *Server) DroppedTargetsHTMLHandler(c *gin.Context) {
+	c.Writer.Header().Set("X-Content-Type-Options", "nosniff")
+	c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	dropped := s.droppedTargetsByJob(c.Query("job_id"))
+	jobNames := slices.Sorted(maps.Keys(dropped))
+
+	WriteHTMLPageHeader(c.Writer, HeaderData{
+		Title: "OpenTelemetry Target Allocator - Dropped Targets",
+	})
+	WriteHTMLPropertiesTable(c.Writer, PropertiesTableData{
+		Headers: []string{"Job", "Dropped Targets", "Listed Targets"},
+		Rows: func() [][]Cell {
+			var rows [][]Cell
+			for _, jobName := range jobNames {
+				rows = append(rows, []Cell{
+					droppedTargetsJobAnchorLink(jobName),
+					NewCell(strconv.Itoa(dropped[jobName].Count)),
+					NewCell(strconv.Itoa(len(dropped[jobName].Targets))),
+				})
+			}
+			return rows
+		}(),
+	})
+	WriteHTMLPropertiesTable(c.Writer, PropertiesTableData{
+		Headers: []string{"Job", "Target", "Rule Index", "Rule", "Discovered Labels"},
+		Rows: func() [][]Cell {
+			var rows [][]Cell
+			for _, jobName := range jobNames {
+				for _, t := range dropped[jobName].Targets {
+					rows = append(rows, []Cell{
+						jobAnchorLink(jobName),
+						NewCell(t.TargetURL),
+						droppedTargetRuleCell(t),
+						{Text: t.Rule, Preformatted: true},
+						{Text: t.DiscoveredLabels.String(), Preformatted: true},
+					})
+				}
+			}
+			return rows
+		}(),
+	})
+	WriteHTMLPageFooter(c.Writer)
+}
+
+func droppedTargetsAnchorLink() Cell {
+	return Cell{
+		Link: "/debug/dropped_targets",
+		Text: "Dropped Targets",
+	}
+}
+
+func droppedTargetsJobAnchorLink(jobName string) Cell {
+	return Cell{
+		Link: "/debug/dropped_targets?job_id=" + url.QueryEscape(jobName),
+		Text: jobName,
+	}
+}
+
+// droppedTargetRuleCell shows the index of the rule of the job that dropped the target, or the internal rule.
+func droppedTargetRuleCell(t droppedTargetJSON) Cell {
+	if t.InternalRule != "" {
+		return NewCell(fmt.Sprintf("internal (%s)", t.InternalRule))
+	}
+	return NewCell(strconv.Itoa(t.RuleIndex))
+}
+
+func formatRelabelRule(rule any) string {
+	out, err := yaml.Marshal(rule)
+	if err != nil {
+		return fmt.Sprintf("%v", rule)
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,128 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// roleHeader is set on the readiness probe responses to the role of the Target Allocator replica.
+	roleHeader = "X-Target-Allocator-Role"
+
+	roleLeader   = "leader"
+	roleFollower = "follower"
+)
+
+// LeaderElection tells a Target Allocator replica whether it is the leader, and where to reach the leader.
+type LeaderElection interface {
+	IsLeader() bool
+	// Leader returns the address of the leader, or an empty string if it is not known yet.
+	Leader() string
+}
+
+// WithLeaderElection makes the followers forward the requests changing the allocation to the leader, as only the
+// leader allocates.
+func WithLeaderElection(election LeaderElection) Option {
+	return func(s *Server) {
+		s.leaderElection = election
+	}
+}
+
+// role returns the role of the replica, or an empty string without leader election.
+func (s *Server) role() string {
+	switch {
+	case s.leaderElection == nil:
+		return ""
+	case s.leaderElection.IsLeader():
+		return roleLeader
+	default:
+		return roleFollower
+	}
+}
+
+// forwardToLeader forwards the request to the leader when this replica is a follower. Requests received over TLS are
+// forwarded to the https server of the leader, which listens on the same port as the one of this replica.
+func (s *Server) forwardToLeader(c *gin.Context) {
+	if s.role() != roleFollower {
+		c.Next()
+		return
+	}
+	leader := s.leaderElection.Leader()
+	if leader == "" {
+		c.Status(http.StatusServiceUnavailable)
+		s.jsonHandler(c.Writer, map[string]string{"error": "no leader elected"})
+		c.Abort()
+		return
+	}
+	leaderURL := &url.URL{Scheme: "http", Host: leader}
+	var transport http.RoundTripper
+	if c.Request.TLS != nil && s.httpsServer != nil {
+		host, _, err := net.SplitHostPort(leader)
+		if err != nil {
+			host = leader
+		}
+		_, port, err := net.SplitHostPort(s.httpsServer.Addr)
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			s.jsonHandler(c.Writer, map[string]string{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		leaderURL = &url.URL{Scheme: "https", Host: net.JoinHostPort(host, port)}
+		transport = s.leaderTransport
+	}
+	proxy := httputil.NewSingleHostReverseProxy(leaderURL)
+	proxy.Transport = transport
+	proxy.ErrorHandler = func(w http.ResponseWriter, _ *http.Request, err error) {
+		s.logger.Error(err, "failed to forward request to the leader", "leader", leader)
+		w.WriteHeader(http.StatusBadGateway)
+		s.jsonHandler(w, map[string]string{"error": err.Error()})
+	}
+	proxy.ServeHTTP(c.Writer, c.Request)
+	c.Abort()
+}
+
+// newLeaderTransport returns the transport forwarding requests to the https server of the leader. The replicas share
+// the serving certificate, which is issued for the service rather than for their addresses, so the follower presents
+// it as its client certificate and only accepts a leader presenting the same certificate.
+func newLeaderTransport(tlsConfig *tls.Config) *http.Transport {
+	certificate := func() (*tls.Certificate, error) {
+		if tlsConfig.GetCertificate != nil {
+			return tlsConfig.GetCertificate(nil)
+		}
+		if len(tlsConfig.Certificates) == 0 {
+			return nil, errors.New("no serving certificate configured")
+		}
+		return &tlsConfig.Certificates[0], nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return certificate()
+		},
+		InsecureSkipVerify: true, //nolint:gosec // pinned to the serving certificate in VerifyConnection
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			own, err := certificate()
+			if err != nil {
+				return err
+			}
+			if len(cs.PeerCertificates) == 0 || len(own.Certificate) == 0 ||
+				!bytes.Equal(cs.PeerCertificates[0].Raw, own.Certificate[0]) {
+				return errors.New("the leader does not present the serving certificate of the target allocator")
+			}
+			return nil
+		},
+	}
+	return transport
+}
@@ -11,7 +11,7 @@ import (
 var _ allocation.Allocator = &mockAllocator{}
 
 // mockAllocator implements the Allocator interface, but all funcs other than
-// TargetItems() are a no-op.
+// TargetItems() and TargetItemsPerCollector() are a no-op.
 type mockAllocator struct {
 	targetItems map[target.ItemHash]*target.Item
 }
@@ -22,7 +22,37 @@ func (*mockAllocator) Collectors() map[string]*allocation.Collector
 func (*mockAllocator) GetTargetsForCollectorAndJob(string, string) []*target.Item { return nil }
 func (*mockAllocator) SetFilter(allocation.Filter)                                {}
 func (*mockAllocator) SetFallbackStrategy(allocation.Strategy)                    {}
+func (*mockAllocator) SetTargetWeights([]allocation.TargetWeight)                 {}
+func (*mockAllocator) TargetWeights() map[target.ItemHash]float64                 { return nil }
+func (*mockAllocator) SetCollectorHealthConfig(allocation.CollectorHealthConfig)  {}
+func (*mockAllocator) SeedTargets([]*target.Item)                                 {}
+func (*mockAllocator) Ready() bool                                                { return true }
+func (*mockAllocator) SetJobStrategies([]allocation.JobStrategy) error            { return nil }
+func (*mockAllocator) StrategyForJob(string) string                               { return "" }
+func (*mockAllocator) SetNodeTopology(map[string]allocation.NodeTopology)         {}
+func (*mockAllocator) SetTopologySpilloverRatio(float64)                          {}
+func (*mockAllocator) SetCollectorHeartbeat(string, allocation.CollectorHeartbeat) error {
+	return nil
+}
 
 func (m *mockAllocator) TargetItems() map[target.ItemHash]*target.Item {
 	return m.targetItems
 }
+
+func (m *mockAllocator) NumTargetItems() int {
+	return len(m.targetItems)
+}
+
+func (m *mockAllocator) TargetItemsPerCollector() map[string]map[target.ItemHash]*target.Item {
+	targetItemsPerCollector := make(map[string]map[target.ItemHash]*target.Item)
+	for hash, item := range m.targetItems {
+		if item.CollectorName == "" {
+			continue
+		}
+		if _, ok := targetItemsPerCollector[item.CollectorName]; !ok {
+			targetItemsPerCollector[item.CollectorName] = make(map[target.ItemHash]*target.Item)
+		}
+		targetItemsPerCollector[item.CollectorName][hash] = item
+	}
+	return targetItemsPerCollector
+}
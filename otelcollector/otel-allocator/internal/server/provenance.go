@@ -0,0 +1,132 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+)
+
+// Sources of the jobs generated by the Target Allocator from the Prometheus CRs. The sources of the other jobs come
+// from the provenance file.
+const (
+	sourceServiceMonitor = "service-monitor"
+	sourcePodMonitor     = "pod-monitor"
+	sourceProbe          = "probe"
+	sourceScrapeConfig   = "scrape-config"
+	sourceUnknown        = "unknown"
+)
+
+// jobPrefixSources maps the job name prefixes of the Prometheus CR jobs to their source.
+var jobPrefixSources = map[string]string{
+	"serviceMonitor": sourceServiceMonitor,
+	"podMonitor":     sourcePodMonitor,
+	"probe":          sourceProbe,
+	"scrapeConfig":   sourceScrapeConfig,
+}
+
+type configProvenanceJSON struct {
+	ConfigVersion string              `json:"configVersion,omitempty"`
+	Jobs          []jobProvenanceJSON `json:"jobs"`
+}
+
+type jobProvenanceJSON struct {
+	Job           string                  `json:"job"`
+	Source        string                  `json:"source"`
+	DefaultTarget string                  `json:"defaultTarget,omitempty"`
+	File          string                  `json:"file,omitempty"`
+	ModifiedBy    []settingProvenanceJSON `json:"modifiedBy,omitempty"`
+	Resource      *resourceProvenanceJSON `json:"resource,omitempty"`
+}
+
+type settingProvenanceJSON struct {
+	Setting string `json:"setting"`
+	Value   string `json:"value"`
+}
+
+// resourceProvenanceJSON is the Prometheus CR a job was generated from.
+type resourceProvenanceJSON struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// WithProvenanceFile serves the provenance of the jobs written by the config reader to path on /explain.
+func WithProvenanceFile(path string) Option {
+	return func(s *Server) {
+		s.provenanceFile = path
+	}
+}
+
+// ExplainHandler returns where the jobs of the current scrape configs come from. A single job is returned with the job
+// query parameter.
+func (s *Server) ExplainHandler(c *gin.Context) {
+	provenance, err := s.readProvenanceFile()
+	if err != nil {
+		s.logger.Error(err, "failed to read the provenance file", "path", s.provenanceFile)
+		s.errorHandler(c.Writer, err)
+		return
+	}
+	s.mtx.RLock()
+	jobNames := s.scrapeConfigJobs
+	s.mtx.RUnlock()
+
+	result := configProvenanceJSON{ConfigVersion: provenance.ConfigVersion, Jobs: []jobProvenanceJSON{}}
+	for _, jobName := range jobNames {
+		result.Jobs = append(result.Jobs, explainJob(jobName, provenance))
+	}
+
+	if jobName := c.Query("job"); jobName != "" {
+		for _, job := range result.Jobs {
+			if job.Job == jobName {
+				s.jsonHandler(c.Writer, job)
+				return
+			}
+		}
+		c.Status(http.StatusNotFound)
+		s.jsonHandler(c.Writer, fmt.Sprintf("job %s is not in the scrape configs", jobName))
+		return
+	}
+	s.jsonHandler(c.Writer, result)
+}
+
+// readProvenanceFile returns the provenance written by the config reader, which is empty without a file.
+func (s *Server) readProvenanceFile() (configProvenanceJSON, error) {
+	provenance := configProvenanceJSON{}
+	if s.provenanceFile == "" {
+		return provenance, nil
+	}
+	contents, err := os.ReadFile(s.provenanceFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return provenance, nil
+	} else if err != nil {
+		return provenance, err
+	}
+	err = json.Unmarshal(contents, &provenance)
+	return provenance, err
+}
+
+// explainJob returns the provenance of a job from the provenance file, or from the job name for the jobs generated
+// from the Prometheus CRs, named <kind>/<namespace>/<name>[/<endpoint index>].
+func explainJob(jobName string, provenance configProvenanceJSON) jobProvenanceJSON {
+	for _, job := range provenance.Jobs {
+		if job.Job == jobName {
+			return job
+		}
+	}
+	parts := strings.Split(jobName, "/")
+	if source, ok := jobPrefixSources[parts[0]]; ok && len(parts) >= 3 {
+		return jobProvenanceJSON{
+			Job:      jobName,
+			Source:   source,
+			Resource: &resourceProvenanceJSON{Namespace: parts[1], Name: parts[2]},
+		}
+	}
+	return jobProvenanceJSON{Job: jobName, Source: sourceUnknown}
+}
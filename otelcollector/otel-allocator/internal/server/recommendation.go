@@ -0,0 +1,36 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/recommendation"
+)
+
+// WithReplicaRecommender serves the number of collectors recommended by the recommender.
+func WithReplicaRecommender(recommender *recommendation.Recommender) Option {
+	return func(s *Server) {
+		s.recommender = recommender
+	}
+}
+
+// ReplicaRecommendationHandler returns the last recommended number of collectors, with the load it is based on. It
+// returns 503 until the first recommendation, as the allocator is not ready yet or the first interval has not passed.
+func (s *Server) ReplicaRecommendationHandler(c *gin.Context) {
+	if s.recommender == nil {
+		c.Status(http.StatusNotFound)
+		s.jsonHandler(c.Writer, map[string]string{"error": "replica recommendation is not enabled"})
+		return
+	}
+	recommendation, ok := s.recommender.Recommendation()
+	if !ok {
+		c.Status(http.StatusServiceUnavailable)
+		s.jsonHandler(c.Writer, map[string]string{"error": "no replica recommendation yet"})
+		return
+	}
+	s.jsonHandler(c.Writer, recommendation)
+}
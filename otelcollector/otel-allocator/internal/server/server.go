@@ -8,6 +8,7 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"maps"
 	"net/http"
 	"net/http/pprof"
 	"net/url"
@@ -31,12 +32,15 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/allocation"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/prehook"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/recommendation"
 	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
 )
 
 type collectorJSON struct {
-	Link string        `json:"_link"`
-	Jobs []*targetJSON `json:"targets"`
+	Link        string        `json:"_link"`
+	Jobs        []*targetJSON `json:"targets"`
+	TotalWeight float64       `json:"total_weight"`
 }
 
 type linkJSON struct {
@@ -62,6 +66,17 @@ type Server struct {
 	ScrapeConfigMarshalledSecretResponse []byte
 	httpDuration                         metric.Float64Histogram
 	allowInsecureAuthSecrets             bool
+	watch                                *watchHub
+	// hasJobs is set when the scrape configs have jobs whose targets have to be discovered
+	hasJobs        bool
+	leaderElection LeaderElection
+	// leaderTransport forwards the requests received over TLS to the https server of the leader
+	leaderTransport *http.Transport
+	// scrapeConfigJobs are the jobs of the current scrape configs, explained by /explain
+	scrapeConfigJobs []string
+	provenanceFile   string
+	droppedTargets   prehook.DroppedTargetsRecorder
+	recommender      *recommendation.Recommender
 }
 
 type Option func(*Server)
@@ -74,6 +89,7 @@ func WithTLSConfig(tlsConfig *tls.Config, httpsListenAddr string) Option {
 		s.setRouter(httpsRouter)
 
 		s.httpsServer = &http.Server{Addr: httpsListenAddr, Handler: httpsRouter, ReadHeaderTimeout: 90 * time.Second, TLSConfig: tlsConfig}
+		s.leaderTransport = newLeaderTransport(tlsConfig)
 	}
 }
 
@@ -96,10 +112,17 @@ func (s *Server) setRouter(router *gin.Engine) {
 	router.GET("/debug/targets", s.TargetsHTMLHandler)
 	router.GET("/debug/scrape_configs", s.ScrapeConfigsHTMLHandler)
 	router.GET("/debug/jobs", s.JobsHTMLHandler)
+	router.GET("/debug/dropped_targets", s.DroppedTargetsHTMLHandler)
 
 	router.GET("/scrape_configs", s.ScrapeConfigsHandler)
 	router.GET("/jobs", s.JobsHandler)
 	router.GET("/jobs/:job_id/targets", s.TargetsHandler)
+	router.GET("/dropped_targets", s.DroppedTargetsHandler)
+	router.GET("/explain", s.ExplainHandler)
+	router.POST("/targets/weights", s.forwardToLeader, s.TargetWeightsHandler)
+	router.POST("/collectors/:collector_id/heartbeat", s.forwardToLeader, s.CollectorHeartbeatHandler)
+	router.GET("/collectors/recommendation", s.forwardToLeader, s.ReplicaRecommendationHandler)
+	router.GET("/watch", s.WatchHandler)
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 	router.GET("/livez", s.LivenessProbeHandler)
 	router.GET("/readyz", s.ReadinessProbeHandler)
@@ -116,6 +139,7 @@ func NewServer(log logr.Logger, allocator allocation.Allocator, listenAddr strin
 		logger:       log,
 		allocator:    allocator,
 		httpDuration: httpDuration,
+		watch:        newWatchHub(defaultWatchInterval),
 	}
 
 	gin.SetMode(gin.ReleaseMode)
@@ -243,6 +267,11 @@ func (s *Server) UpdateScrapeConfigResponse(configs map[string]*promconfig.Scrap
 	if err != nil {
 		return err
 	}
+	s.mtx.Lock()
+	s.hasJobs = len(configs) > 0
+	s.scrapeConfigJobs = slices.Sorted(maps.Keys(configs))
+	s.mtx.Unlock()
+	s.watch.invalidate()
 	return nil
 }
 
@@ -267,12 +296,21 @@ func (s *Server) ScrapeConfigsHandler(c *gin.Context) {
 	}
 }
 
+// ReadinessProbeHandler reports the Target Allocator as ready once it has scrape configs and, when they have jobs,
+// once the targets were seeded from a saved allocation or discovered, so that collectors are not handed an empty
+// allocation. With leader election, followers are ready once they loaded the allocation of the leader, and the role
+// of the replica is set in the X-Target-Allocator-Role header.
 func (s *Server) ReadinessProbeHandler(c *gin.Context) {
 	s.mtx.RLock()
 	result := s.scrapeConfigResponse
+	hasJobs := s.hasJobs
 	s.mtx.RUnlock()
 
-	if result != nil {
+	if role := s.role(); role != "" {
+		c.Header(roleHeader, role)
+	}
+
+	if result != nil && (!hasJobs || s.allocator == nil || s.allocator.Ready()) {
 		c.Status(http.StatusOK)
 	} else {
 		c.Status(http.StatusServiceUnavailable)
@@ -313,18 +351,29 @@ func (s *Server) IndexHandler(c *gin.Context) {
 
 	WriteHTMLPropertiesTable(c.Writer, PropertiesTableData{
 		Headers: []string{"Category", "Count"},
-		Rows: [][]Cell{
-			{scrapeConfigAnchorLink(), Text(strconv.Itoa(s.getScrapeConfigCount()))},
-			{jobsAnchorLink(), Text(strconv.Itoa(s.getJobCount()))},
-			{targetsAnchorLink(), Text(strconv.Itoa(len(s.allocator.TargetItems())))},
-		},
+		Rows: func() [][]Cell {
+			rows := [][]Cell{
+				{scrapeConfigAnchorLink(), Text(strconv.Itoa(s.getScrapeConfigCount()))},
+				{jobsAnchorLink(), Text(strconv.Itoa(s.getJobCount()))},
+				{targetsAnchorLink(), Text(strconv.Itoa(len(s.allocator.TargetItems())))},
+			}
+			if s.droppedTargets != nil {
+				droppedCount := 0
+				for _, dropped := range s.droppedTargets.DroppedTargets() {
+					droppedCount += dropped.Count
+				}
+				rows = append(rows, []Cell{droppedTargetsAnchorLink(), Text(strconv.Itoa(droppedCount))})
+			}
+			return rows
+		}(),
 	})
 	WriteHTMLPropertiesTable(c.Writer, PropertiesTableData{
-		Headers: []string{"Collector", "Job Count", "Target Count"},
+		Headers: []string{"Collector", "Job Count", "Target Count", "Total Weight"},
 		Rows: func() [][]Cell {
 			var rows [][]Cell
+			collectors := s.allocator.Collectors()
 			collectorNames := []string{}
-			for k := range s.allocator.Collectors() {
+			for k := range collectors {
 				collectorNames = append(collectorNames, k)
 			}
 			slices.Sort(collectorNames)
@@ -332,7 +381,8 @@ func (s *Server) IndexHandler(c *gin.Context) {
 			for _, colName := range collectorNames {
 				jobCount := strconv.Itoa(s.getJobCountForCollector(colName))
 				targetCount := strconv.Itoa(s.getTargetCountForCollector(colName))
-				rows = append(rows, []Cell{collectorAnchorLink(colName), NewCell(jobCount), NewCell(targetCount)})
+				totalWeight := formatWeight(collectors[colName].TotalWeight)
+				rows = append(rows, []Cell{collectorAnchorLink(colName), NewCell(jobCount), NewCell(targetCount), NewCell(totalWeight)})
 			}
 			return rows
 		}(),
@@ -348,7 +398,7 @@ func targetsAnchorLink() Cell {
 }
 
 // TargetsHTMLHandler displays the targets in a table format. Each target is a row in the table.
-// The table has four columns: Job, Target, Collector, and Endpoint Slice.
+// The table has five columns: Job, Target, Collector, Endpoint Slice and Weight.
 // The Job, Target, and Collector columns are links to the respective pages.
 func (s *Server) TargetsHTMLHandler(c *gin.Context) {
 	c.Writer.Header().Set("X-Content-Type-Options", "nosniff")
@@ -359,15 +409,17 @@ func (s *Server) TargetsHTMLHandler(c *gin.Context) {
 	})
 
 	WriteHTMLPropertiesTable(c.Writer, PropertiesTableData{
-		Headers: []string{"Job", "Target", "Collector", "Endpoint Slice"},
+		Headers: []string{"Job", "Target", "Collector", "Endpoint Slice", "Weight"},
 		Rows: func() [][]Cell {
 			var rows [][]Cell
+			weights := s.allocator.TargetWeights()
 			for _, v := range s.sortedTargetItems() {
 				rows = append(rows, []Cell{
 					jobAnchorLink(v.JobName),
 					targetAnchorLink(v),
 					collectorAnchorLink(v.CollectorName),
 					NewCell(v.GetEndpointSliceName()),
+					NewCell(formatWeight(weights[v.Hash()])),
 				})
 			}
 			return rows
@@ -434,6 +486,7 @@ func (s *Server) TargetHTMLHandler(c *gin.Context) {
 			{NewCell("Container Port Name"), NewCell(target.Labels.Get("__meta_kubernetes_pod_container_port_name"))},
 			{NewCell("Node Name"), NewCell(target.GetNodeName())},
 			{NewCell("Endpoint Slice Name"), NewCell(target.GetEndpointSliceName())},
+			{NewCell("Weight"), NewCell(formatWeight(s.allocator.TargetWeights()[target.Hash()]))},
 		},
 	})
 	WriteHTMLPropertiesTable(c.Writer, PropertiesTableData{
@@ -511,6 +564,12 @@ func (s *Server) JobHTMLHandler(c *gin.Context) {
 	WriteHTMLPageHeader(c.Writer, HeaderData{
 		Title: "Job: " + jobId,
 	})
+	WriteHTMLPropertiesTable(c.Writer, PropertiesTableData{
+		Headers: []string{"", ""},
+		Rows: [][]Cell{
+			{NewCell("Allocation Strategy"), NewCell(s.allocator.StrategyForJob(jobId))},
+		},
+	})
 	WriteHTMLPropertiesTable(c.Writer, PropertiesTableData{
 		Headers: []string{"Collector", "Target Count"},
 		Rows: func() [][]Cell {
@@ -566,13 +625,7 @@ func (s *Server) CollectorHTMLHandler(c *gin.Context) {
 		return
 	}
 
-	found := false
-	for _, v := range s.allocator.Collectors() {
-		if v.Name == collectorId {
-			found = true
-			break
-		}
-	}
+	collector, found := s.allocator.Collectors()[collectorId]
 	if !found {
 		c.Status(http.StatusNotFound)
 		WriteHTMLNotFound(c.Writer, NotFoundData{
@@ -586,15 +639,21 @@ func (s *Server) CollectorHTMLHandler(c *gin.Context) {
 		Title: "Collector: " + collectorId,
 	})
 	WriteHTMLPropertiesTable(c.Writer, PropertiesTableData{
-		Headers: []string{"Job", "Target", "Endpoint Slice"},
+		Headers: []string{"", ""},
+		Rows:    collectorPropertiesRows(collector),
+	})
+	WriteHTMLPropertiesTable(c.Writer, PropertiesTableData{
+		Headers: []string{"Job", "Target", "Endpoint Slice", "Weight"},
 		Rows: func() [][]Cell {
 			var rows [][]Cell
+			weights := s.allocator.TargetWeights()
 			for _, v := range s.sortedTargetItems() {
 				if v.CollectorName == collectorId {
 					rows = append(rows, []Cell{
 						jobAnchorLink(v.JobName),
 						targetAnchorLink(v),
 						NewCell(v.GetEndpointSliceName()),
+						NewCell(formatWeight(weights[v.Hash()])),
 					})
 				}
 			}
@@ -604,6 +663,27 @@ func (s *Server) CollectorHTMLHandler(c *gin.Context) {
 	WriteHTMLPageFooter(c.Writer)
 }
 
+// collectorPropertiesRows returns the allocation state of a collector, and its last heartbeat if it sent one.
+func collectorPropertiesRows(collector *allocation.Collector) [][]Cell {
+	rows := [][]Cell{
+		{NewCell("Target Count"), NewCell(strconv.Itoa(collector.NumTargets))},
+		{NewCell("Total Weight"), NewCell(formatWeight(collector.TotalWeight))},
+	}
+	if collector.DrainReason != "" {
+		rows = append(rows, []Cell{NewCell("Drained"), NewCell(fmt.Sprintf("%s (since %s)", collector.DrainReason, collector.DrainedSince.Format(time.RFC3339)))})
+	}
+	heartbeat := collector.Heartbeat
+	if !heartbeat.Time.IsZero() {
+		rows = append(rows,
+			[]Cell{NewCell("Last Heartbeat"), NewCell(heartbeat.Time.Format(time.RFC3339))},
+			[]Cell{NewCell("Series Count"), NewCell(strconv.FormatInt(heartbeat.SeriesCount, 10))},
+			[]Cell{NewCell("Scrape Failures"), NewCell(fmt.Sprintf("%d of %d targets", heartbeat.ScrapeFailures, heartbeat.Targets))},
+			[]Cell{NewCell("Memory Usage Ratio"), NewCell(strconv.FormatFloat(heartbeat.MemoryUsageRatio, 'f', 2, 64))},
+		)
+	}
+	return rows
+}
+
 func scrapeConfigAnchorLink() Cell {
 	return Cell{
 		Link: "/scrape_configs",
@@ -671,6 +751,41 @@ func (s *Server) TargetsHandler(c *gin.Context) {
 	}
 }
 
+// TargetWeightsHandler records the weights the collectors report for the targets they scrape, usually the number of
+// series of the last scrape. The weighted allocation strategy uses them to balance the collectors.
+func (s *Server) TargetWeightsHandler(c *gin.Context) {
+	var weights []allocation.TargetWeight
+	if err := json.NewDecoder(c.Request.Body).Decode(&weights); err != nil {
+		c.Status(http.StatusBadRequest)
+		s.jsonHandler(c.Writer, map[string]string{"error": err.Error()})
+		return
+	}
+	s.allocator.SetTargetWeights(weights)
+	c.Status(http.StatusNoContent)
+}
+
+// CollectorHeartbeatHandler records the heartbeat a collector sends on every sync with its series count, scrape
+// failures and memory usage, which the allocator uses to drain unhealthy or saturated collectors.
+func (s *Server) CollectorHeartbeatHandler(c *gin.Context) {
+	collectorId, err := url.PathUnescape(c.Params.ByName("collector_id"))
+	if err != nil {
+		s.errorHandler(c.Writer, err)
+		return
+	}
+	var heartbeat allocation.CollectorHeartbeat
+	if err := json.NewDecoder(c.Request.Body).Decode(&heartbeat); err != nil {
+		c.Status(http.StatusBadRequest)
+		s.jsonHandler(c.Writer, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := s.allocator.SetCollectorHeartbeat(collectorId, heartbeat); err != nil {
+		c.Status(http.StatusNotFound)
+		s.jsonHandler(c.Writer, map[string]string{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
 func (s *Server) errorHandler(w http.ResponseWriter, err error) {
 	w.WriteHeader(http.StatusInternalServerError)
 	s.jsonHandler(w, err)
@@ -739,8 +854,9 @@ func GetAllTargetsByJob(allocator allocation.Allocator, job string) map[string]c
 	for _, col := range allocator.Collectors() {
 		targets := GetAllTargetsByCollectorAndJob(allocator, col.Name, job)
 		displayData[col.Name] = collectorJSON{
-			Link: fmt.Sprintf("/debug/jobs/%s/targets?collector_id=%s", url.QueryEscape(job), col.Name),
-			Jobs: targets,
+			Link:        fmt.Sprintf("/debug/jobs/%s/targets?collector_id=%s", url.QueryEscape(job), col.Name),
+			Jobs:        targets,
+			TotalWeight: col.TotalWeight,
 		}
 	}
 	return displayData
@@ -776,6 +892,10 @@ func registerPprof(g *gin.RouterGroup) {
 	})
 }
 
+func formatWeight(weight float64) string {
+	return strconv.FormatFloat(weight, 'f', -1, 64)
+}
+
 func targetJsonFromTargetItem(item *target.Item) *targetJSON {
 	return &targetJSON{
 		TargetURL: []string{item.TargetURL},
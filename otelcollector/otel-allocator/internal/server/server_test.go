@@ -8,9 +8,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -27,6 +31,8 @@ import (
 
 	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/allocation"
 	allocatorconfig "github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/config"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/prehook"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/recommendation"
 	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
 )
 
@@ -1223,6 +1229,123 @@ func TestServer_ReadinessNotReady(t *testing.T) {
 	assert.Equal(t, http.StatusServiceUnavailable, result.StatusCode)
 }
 
+func TestServer_ReadinessWaitsForTargets(t *testing.T) {
+	allocator, err := allocation.New("consistent-hashing", logger)
+	require.NoError(t, err)
+	s, err := NewServer(logger, allocator, "")
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateScrapeConfigResponse(map[string]*promconfig.ScrapeConfig{
+		"test-job": {JobName: "test-job"},
+	}))
+
+	ready := func() int {
+		request := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/readyz", http.NoBody)
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, request)
+		return w.Result().StatusCode
+	}
+	// the jobs have no targets until they are seeded or discovered
+	assert.Equal(t, http.StatusServiceUnavailable, ready())
+	allocator.SeedTargets(allocation.MakeNNewTargets(2, 1, 0))
+	assert.Equal(t, http.StatusOK, ready())
+
+	// scrape configs without jobs have no targets to wait for
+	allocator, err = allocation.New("consistent-hashing", logger)
+	require.NoError(t, err)
+	s, err = NewServer(logger, allocator, "")
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateScrapeConfigResponse(map[string]*promconfig.ScrapeConfig{}))
+	assert.Equal(t, http.StatusOK, ready())
+}
+
+type fakeLeaderElection struct {
+	isLeader bool
+	leader   string
+}
+
+func (f *fakeLeaderElection) IsLeader() bool { return f.isLeader }
+func (f *fakeLeaderElection) Leader() string { return f.leader }
+
+func TestServer_LeaderElection(t *testing.T) {
+	var forwarded []string
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = append(forwarded, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer leader.Close()
+
+	election := &fakeLeaderElection{}
+	s, err := NewServer(logger, &mockAllocator{}, "", WithLeaderElection(election))
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateScrapeConfigResponse(map[string]*promconfig.ScrapeConfig{}))
+	serve := func(method, path, body string) *http.Response {
+		request := httptest.NewRequestWithContext(t.Context(), method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, request)
+		return w.Result()
+	}
+
+	// followers cannot forward before a leader is elected
+	result := serve(http.MethodPost, "/targets/weights", "[]")
+	assert.Equal(t, http.StatusServiceUnavailable, result.StatusCode)
+
+	election.leader = strings.TrimPrefix(leader.URL, "http://")
+	result = serve(http.MethodPost, "/targets/weights", "[]")
+	assert.Equal(t, http.StatusNoContent, result.StatusCode)
+	result = serve(http.MethodPost, "/collectors/collector-0/heartbeat", "{}")
+	assert.Equal(t, http.StatusNoContent, result.StatusCode)
+	assert.Equal(t, []string{"/targets/weights", "/collectors/collector-0/heartbeat"}, forwarded)
+	result = serve(http.MethodGet, "/readyz", "")
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	assert.Equal(t, "follower", result.Header.Get("X-Target-Allocator-Role"))
+
+	// the leader handles the requests itself
+	election.isLeader = true
+	result = serve(http.MethodPost, "/targets/weights", "[]")
+	assert.Equal(t, http.StatusNoContent, result.StatusCode)
+	assert.Len(t, forwarded, 2)
+	result = serve(http.MethodGet, "/readyz", "")
+	assert.Equal(t, "leader", result.Header.Get("X-Target-Allocator-Role"))
+}
+
+func TestServer_LeaderElectionTLS(t *testing.T) {
+	var forwarded []string
+	leader := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = append(forwarded, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer leader.Close()
+	leaderURL, err := url.Parse(leader.URL)
+	require.NoError(t, err)
+
+	// the leader identity has the port of the http server, requests received over TLS go to the https port
+	election := &fakeLeaderElection{leader: net.JoinHostPort(leaderURL.Hostname(), "8080")}
+	tlsConfig := &tls.Config{Certificates: leader.TLS.Certificates, MinVersion: tls.VersionTLS12}
+	s, err := NewServer(logger, &mockAllocator{}, "", WithLeaderElection(election), WithTLSConfig(tlsConfig, leaderURL.Host))
+	require.NoError(t, err)
+	serve := func(handler http.Handler) *http.Response {
+		request := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/targets/weights", strings.NewReader("[]"))
+		request.TLS = &tls.ConnectionState{}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, request)
+		return w.Result()
+	}
+
+	result := serve(s.httpsServer.Handler)
+	assert.Equal(t, http.StatusNoContent, result.StatusCode)
+	assert.Equal(t, []string{"/targets/weights"}, forwarded)
+
+	// a leader presenting another certificate is rejected
+	s, err = NewServer(logger, &mockAllocator{}, "", WithLeaderElection(election), WithTLSConfig(&tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{[]byte("other")}}},
+		MinVersion:   tls.VersionTLS12,
+	}, leaderURL.Host))
+	require.NoError(t, err)
+	result = serve(s.httpsServer.Handler)
+	assert.Equal(t, http.StatusBadGateway, result.StatusCode)
+	assert.Len(t, forwarded, 1)
+}
+
 func TestServer_TargetHTMLHandlerNotFound(t *testing.T) {
 	allocator, _ := allocation.New("consistent-hashing", logger)
 	s, err := NewServer(logger, allocator, "")
@@ -1367,3 +1490,217 @@ func TestServer_EmptyTargetReturnsEmptyList(t *testing.T) {
 	require.NoError(t, err)
 	assert.Empty(t, items)
 }
+
+func TestServer_TargetWeightsHandler(t *testing.T) {
+	weighted, _ := allocation.New("weighted", logger)
+	s, err := NewServer(logger, weighted, "")
+	require.NoError(t, err)
+
+	weighted.SetCollectors(map[string]*allocation.Collector{
+		"test-collector": {Name: "test-collector"},
+	})
+	weighted.SetTargets([]*target.Item{baseTargetItem, testJobTargetItemTwo})
+
+	body := `[{"job": "test-job", "target": "test-url", "weight": 1200}]`
+	request := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/targets/weights", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+
+	request = httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/jobs/test-job/targets", http.NoBody)
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	bodyBytes, err := io.ReadAll(w.Result().Body)
+	require.NoError(t, err)
+
+	var resp map[string]collectorJSON
+	require.NoError(t, json.Unmarshal(bodyBytes, &resp))
+	assert.Equal(t, float64(1201), resp["test-collector"].TotalWeight)
+	assert.Len(t, resp["test-collector"].Jobs, 2)
+
+	request = httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/targets/weights", strings.NewReader("{"))
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestServer_CollectorHeartbeatHandler(t *testing.T) {
+	allocator, _ := allocation.New("consistent-hashing", logger)
+	s, err := NewServer(logger, allocator, "")
+	require.NoError(t, err)
+
+	allocator.SetCollectors(map[string]*allocation.Collector{
+		"test-collector": {Name: "test-collector"},
+	})
+
+	body := `{"series_count": 1200, "targets": 4, "scrape_failures": 1, "memory_usage_ratio": 0.5}`
+	request := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/collectors/test-collector/heartbeat", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+	assert.Equal(t, int64(1200), allocator.Collectors()["test-collector"].Heartbeat.SeriesCount)
+
+	request = httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/debug/collector?collector_id=test-collector", http.NoBody)
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	page, err := io.ReadAll(w.Result().Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(page), "Last Heartbeat")
+	assert.Contains(t, string(page), "1 of 4 targets")
+
+	request = httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/collectors/unknown/heartbeat", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+
+	request = httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/collectors/test-collector/heartbeat", strings.NewReader("{"))
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestServer_ReplicaRecommendationHandler(t *testing.T) {
+	allocator, _ := allocation.New("consistent-hashing", logger)
+	s, err := NewServer(logger, allocator, "")
+	require.NoError(t, err)
+	request := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/collectors/recommendation", http.NoBody)
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+
+	allocator.SetCollectors(allocation.MakeNCollectors(2, 0))
+	allocator.SetTargets(allocation.MakeNNewTargetsWithEmptyCollectors(25, 0))
+	recommender, err := recommendation.NewRecommender(logger, allocator, recommendation.Config{TargetsPerCollector: 10}, time.Minute)
+	require.NoError(t, err)
+	s, err = NewServer(logger, allocator, "", WithReplicaRecommender(recommender))
+	require.NoError(t, err)
+
+	// no recommendation before the first one
+	request = httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/collectors/recommendation", http.NoBody)
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Result().StatusCode)
+
+	recommender.Recommend()
+	request = httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/collectors/recommendation", http.NoBody)
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var result recommendation.Recommendation
+	require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&result))
+	assert.Equal(t, 3, result.Replicas)
+	assert.Equal(t, 2, result.CurrentReplicas)
+	assert.Equal(t, 25, result.Targets)
+	assert.Equal(t, recommendation.ReasonTargets, result.Reason)
+}
+
+func TestServer_ExplainHandler(t *testing.T) {
+	provenanceFile := filepath.Join(t.TempDir(), "config-provenance.json")
+	provenance := `{"configVersion": "ver1", "jobs": [{"job": "kubelet", "source": "default-target", "defaultTarget": "kubelet", "file": "kubeletDefault.yml", "modifiedBy": [{"setting": "default-targets-metrics-keep-list.kubelet", "value": "up"}]}]}`
+	require.NoError(t, os.WriteFile(provenanceFile, []byte(provenance), 0o600))
+	s, err := NewServer(logger, nil, "", WithProvenanceFile(provenanceFile))
+	require.NoError(t, err)
+
+	require.NoError(t, s.UpdateScrapeConfigResponse(map[string]*promconfig.ScrapeConfig{
+		"kubelet":                         {JobName: "kubelet"},
+		"serviceMonitor/default/my-app/0": {JobName: "serviceMonitor/default/my-app/0"},
+		"podMonitor/monitoring/my-pods/1": {JobName: "podMonitor/monitoring/my-pods/1"},
+		"job-without-provenance":          {JobName: "job-without-provenance"},
+	}))
+
+	request := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/explain", http.NoBody)
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var result configProvenanceJSON
+	require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&result))
+	assert.Equal(t, configProvenanceJSON{
+		ConfigVersion: "ver1",
+		Jobs: []jobProvenanceJSON{
+			{Job: "job-without-provenance", Source: sourceUnknown},
+			{
+				Job:           "kubelet",
+				Source:        "default-target",
+				DefaultTarget: "kubelet",
+				File:          "kubeletDefault.yml",
+				ModifiedBy:    []settingProvenanceJSON{{Setting: "default-targets-metrics-keep-list.kubelet", Value: "up"}},
+			},
+			{Job: "podMonitor/monitoring/my-pods/1", Source: sourcePodMonitor, Resource: &resourceProvenanceJSON{Namespace: "monitoring", Name: "my-pods"}},
+			{Job: "serviceMonitor/default/my-app/0", Source: sourceServiceMonitor, Resource: &resourceProvenanceJSON{Namespace: "default", Name: "my-app"}},
+		},
+	}, result)
+
+	request = httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/explain?job="+url.QueryEscape("serviceMonitor/default/my-app/0"), http.NoBody)
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var job jobProvenanceJSON
+	require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&job))
+	assert.Equal(t, sourceServiceMonitor, job.Source)
+
+	request = httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/explain?job=unknown", http.NoBody)
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+type mockDroppedTargetsRecorder map[string]prehook.DroppedTargets
+
+func (m mockDroppedTargetsRecorder) DroppedTargets() map[string]prehook.DroppedTargets {
+	return m
+}
+
+func TestServer_DroppedTargetsHandler(t *testing.T) {
+	rule := &relabel.Config{
+		SourceLabels: model.LabelNames{"__meta_kubernetes_service_label_app"},
+		Regex:        relabel.MustNewRegexp("my-app"),
+		Separator:    ";",
+		Action:       relabel.Keep,
+		Replacement:  "$1",
+	}
+	recorder := mockDroppedTargetsRecorder{
+		"serviceMonitor/default/my-app/0": {
+			Count: 3,
+			Targets: []prehook.DroppedTarget{{
+				TargetURL:        "10.0.0.1:8080",
+				DiscoveredLabels: labels.New(labels.Label{Name: "__meta_kubernetes_service_label_app", Value: "other-app"}),
+				RuleIndex:        2,
+				Rule:             rule,
+			}},
+		},
+		"other-job": {Count: 1},
+	}
+	allocator, _ := allocation.New("consistent-hashing", logger)
+	s, err := NewServer(logger, allocator, "", WithDroppedTargets(recorder))
+	require.NoError(t, err)
+
+	request := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/dropped_targets?job_id="+url.QueryEscape("serviceMonitor/default/my-app/0"), http.NoBody)
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var result map[string]droppedTargetsJSON
+	require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&result))
+	require.Len(t, result, 1)
+	dropped := result["serviceMonitor/default/my-app/0"]
+	assert.Equal(t, 3, dropped.Count)
+	require.Len(t, dropped.Targets, 1)
+	assert.Equal(t, "10.0.0.1:8080", dropped.Targets[0].TargetURL)
+	assert.Equal(t, 2, dropped.Targets[0].RuleIndex)
+	assert.Contains(t, dropped.Targets[0].Rule, "action: keep")
+	assert.Equal(t, "other-app", dropped.Targets[0].DiscoveredLabels.Get("__meta_kubernetes_service_label_app"))
+
+	request = httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/debug/dropped_targets", http.NoBody)
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	page, err := io.ReadAll(w.Result().Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(page), "10.0.0.1:8080")
+	assert.Contains(t, string(page), "other-job")
+
+	request = httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", http.NoBody)
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	page, err = io.ReadAll(w.Result().Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(page), "/debug/dropped_targets")
+}
@@ -0,0 +1,197 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/diff"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
+)
+
+const (
+	// defaultWatchInterval is how often the allocation is checked for changes to push to the watching collectors.
+	defaultWatchInterval = time.Second
+	// watchKeepAliveInterval is how often a comment is sent on idle watches, to keep proxies from closing them.
+	watchKeepAliveInterval = 30 * time.Second
+)
+
+// watchEvent is sent to a watching collector whenever its scrape configs or targets change. The first event of a
+// watch is a full event with the scrape configs and all the targets of the collector, the following ones only hold
+// what changed since the previous event.
+type watchEvent struct {
+	Full          bool                       `json:"full,omitempty"`
+	ScrapeConfigs json.RawMessage            `json:"scrape_configs,omitempty"`
+	Jobs          map[string]*jobTargetsJSON `json:"jobs,omitempty"`
+}
+
+// jobTargetsJSON holds the targets of a job added and removed for a collector, keyed by target hash.
+type jobTargetsJSON struct {
+	Added   map[string]*targetJSON `json:"added,omitempty"`
+	Removed []string               `json:"removed,omitempty"`
+}
+
+// allocationSnapshot is the allocation at a point in time, shared by all the watches.
+type allocationSnapshot struct {
+	scrapeConfigs       []byte
+	secretScrapeConfigs []byte
+	// targets are the targets assigned to each collector
+	targets map[string]map[target.ItemHash]*target.Item
+}
+
+// watchHub computes the allocation snapshot for the watches, at most once per interval unless the scrape configs
+// change, and notifies them of the new snapshots.
+type watchHub struct {
+	interval time.Duration
+
+	mtx      sync.Mutex
+	snapshot *allocationSnapshot
+	taken    time.Time
+	updated  chan struct{}
+}
+
+func newWatchHub(interval time.Duration) *watchHub {
+	return &watchHub{interval: interval, updated: make(chan struct{})}
+}
+
+// notify returns a channel closed the next time the scrape configs change.
+func (h *watchHub) notify() <-chan struct{} {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.updated
+}
+
+// invalidate discards the current snapshot and wakes up the watches.
+func (h *watchHub) invalidate() {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.taken = time.Time{}
+	close(h.updated)
+	h.updated = make(chan struct{})
+}
+
+// current returns the allocation snapshot, taking a new one if the last one is older than the interval.
+func (h *watchHub) current(s *Server) *allocationSnapshot {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.snapshot != nil && time.Since(h.taken) < h.interval {
+		return h.snapshot
+	}
+
+	snapshot := &allocationSnapshot{}
+	s.mtx.RLock()
+	snapshot.scrapeConfigs = s.scrapeConfigResponse
+	snapshot.secretScrapeConfigs = s.ScrapeConfigMarshalledSecretResponse
+	s.mtx.RUnlock()
+	snapshot.targets = s.allocator.TargetItemsPerCollector()
+	h.snapshot, h.taken = snapshot, time.Now()
+	return snapshot
+}
+
+// WatchHandler streams the scrape configs and the targets of a collector as server-sent events, pushing the changes
+// as soon as they are allocated instead of waiting for the collector to poll for them.
+func (s *Server) WatchHandler(c *gin.Context) {
+	collectorId := c.Request.URL.Query().Get("collector_id")
+	if collectorId == "" {
+		c.Status(http.StatusBadRequest)
+		s.jsonHandler(c.Writer, map[string]string{"error": "collector_id is required"})
+		return
+	}
+	withSecrets := c.Request.TLS != nil || s.allowInsecureAuthSecrets
+
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+
+	var sent *allocationSnapshot
+	ticker := time.NewTicker(s.watch.interval)
+	defer ticker.Stop()
+	lastWrite := time.Now()
+	for {
+		snapshot := s.watch.current(s)
+		if snapshot != sent {
+			if event := newWatchEvent(sent, snapshot, collectorId, withSecrets); event != nil {
+				data, err := json.Marshal(event)
+				if err != nil {
+					s.logger.Error(err, "failed to encode watch event", "collector", collectorId)
+					return
+				}
+				if _, err := c.Writer.Write(append(append([]byte("data: "), data...), '\n', '\n')); err != nil {
+					return
+				}
+				c.Writer.Flush()
+				lastWrite = time.Now()
+			}
+			sent = snapshot
+		}
+		if time.Since(lastWrite) >= watchKeepAliveInterval {
+			if _, err := c.Writer.WriteString(": keepalive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+			lastWrite = time.Now()
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-s.watch.notify():
+		case <-ticker.C:
+		}
+	}
+}
+
+// newWatchEvent returns the event bringing a collector from the previous snapshot to the current one, or nil if
+// nothing changed for it. The event is a full one without a previous snapshot.
+func newWatchEvent(previous, current *allocationSnapshot, collectorId string, withSecrets bool) *watchEvent {
+	event := &watchEvent{Jobs: make(map[string]*jobTargetsJSON)}
+	scrapeConfigs := current.scrapeConfigs
+	if withSecrets {
+		scrapeConfigs = current.secretScrapeConfigs
+	}
+
+	var previousTargets map[target.ItemHash]*target.Item
+	if previous == nil {
+		event.Full = true
+		event.ScrapeConfigs = scrapeConfigs
+	} else {
+		previousScrapeConfigs := previous.scrapeConfigs
+		if withSecrets {
+			previousScrapeConfigs = previous.secretScrapeConfigs
+		}
+		if !bytes.Equal(previousScrapeConfigs, scrapeConfigs) {
+			event.ScrapeConfigs = scrapeConfigs
+		}
+		previousTargets = previous.targets[collectorId]
+	}
+
+	changes := diff.Maps(previousTargets, current.targets[collectorId])
+	jobTargets := func(jobName string) *jobTargetsJSON {
+		if _, ok := event.Jobs[jobName]; !ok {
+			event.Jobs[jobName] = &jobTargetsJSON{}
+		}
+		return event.Jobs[jobName]
+	}
+	for hash, item := range changes.Removals() {
+		jobTargets(item.JobName).Removed = append(jobTargets(item.JobName).Removed, hash.String())
+	}
+	for hash, item := range changes.Additions() {
+		job := jobTargets(item.JobName)
+		if job.Added == nil {
+			job.Added = make(map[string]*targetJSON)
+		}
+		job.Added[hash.String()] = targetJsonFromTargetItem(item)
+	}
+
+	if !event.Full && event.ScrapeConfigs == nil && len(event.Jobs) == 0 {
+		return nil
+	}
+	return event
+}
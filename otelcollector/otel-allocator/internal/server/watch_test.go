@@ -0,0 +1,99 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	promconfig "github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/allocation"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
+)
+
+func readWatchEvent(t *testing.T, reader *bufio.Reader) *watchEvent {
+	t.Helper()
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			event := &watchEvent{}
+			require.NoError(t, json.Unmarshal([]byte(data), event))
+			return event
+		}
+	}
+}
+
+func TestServer_WatchHandler(t *testing.T) {
+	allocator, _ := allocation.New("least-weighted", logger)
+	s, err := NewServer(logger, allocator, "")
+	require.NoError(t, err)
+	s.watch = newWatchHub(10 * time.Millisecond)
+
+	allocator.SetCollectors(map[string]*allocation.Collector{
+		"test-collector": {Name: "test-collector"},
+	})
+	first := target.NewItem("test-job", "test-url", labels.New(labels.Label{Name: "test_label", Value: "first"}), "")
+	second := target.NewItem("test-job", "test-url2", labels.New(labels.Label{Name: "test_label", Value: "second"}), "")
+	allocator.SetTargets([]*target.Item{first})
+	require.NoError(t, s.UpdateScrapeConfigResponse(map[string]*promconfig.ScrapeConfig{
+		"test-job": {JobName: "test-job"},
+	}))
+
+	ts := httptest.NewServer(s.server.Handler)
+	defer ts.Close()
+	request, err := http.NewRequestWithContext(t.Context(), http.MethodGet, ts.URL+"/watch?collector_id=test-collector", http.NoBody)
+	require.NoError(t, err)
+	resp, err := ts.Client().Do(request)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	reader := bufio.NewReader(resp.Body)
+
+	event := readWatchEvent(t, reader)
+	assert.True(t, event.Full)
+	assert.Contains(t, string(event.ScrapeConfigs), "test-job")
+	require.Contains(t, event.Jobs, "test-job")
+	assert.Equal(t, []string{"test-url"}, event.Jobs["test-job"].Added[first.Hash().String()].TargetURL)
+
+	allocator.SetTargets([]*target.Item{first, second})
+	event = readWatchEvent(t, reader)
+	assert.False(t, event.Full)
+	assert.Empty(t, event.ScrapeConfigs)
+	assert.Len(t, event.Jobs["test-job"].Added, 1)
+	assert.Contains(t, event.Jobs["test-job"].Added, second.Hash().String())
+	assert.Empty(t, event.Jobs["test-job"].Removed)
+
+	allocator.SetTargets([]*target.Item{second})
+	event = readWatchEvent(t, reader)
+	assert.Empty(t, event.Jobs["test-job"].Added)
+	assert.Equal(t, []string{first.Hash().String()}, event.Jobs["test-job"].Removed)
+
+	require.NoError(t, s.UpdateScrapeConfigResponse(map[string]*promconfig.ScrapeConfig{
+		"test-job-2": {JobName: "test-job-2"},
+	}))
+	event = readWatchEvent(t, reader)
+	assert.Contains(t, string(event.ScrapeConfigs), "test-job-2")
+	assert.Empty(t, event.Jobs)
+}
+
+func TestServer_WatchHandlerRequiresCollector(t *testing.T) {
+	allocator, _ := allocation.New("least-weighted", logger)
+	s, err := NewServer(logger, allocator, "")
+	require.NoError(t, err)
+
+	request := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/watch", http.NoBody)
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
@@ -0,0 +1,235 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package simulation runs the allocation strategies offline over a set of targets, to compare how they would spread
+// the targets over hypothetical collectors before changing the strategy or the number of collectors of a cluster.
+package simulation
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/go-logr/logr"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/allocation"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
+)
+
+const perNodeStrategy = "per-node"
+
+// Options are the hypothetical collectors and the strategies to simulate.
+type Options struct {
+	// Collectors is the number of collectors to allocate the targets to. It is ignored by the per-node strategy,
+	// which gets a collector on each node of the targets.
+	Collectors int
+	// Strategies are the strategies to simulate, all the registered ones when empty.
+	Strategies []string
+	// FallbackStrategy is used by the per-node strategy for the targets without a node.
+	FallbackStrategy string
+	// JobStrategies allocate the targets of some jobs with another strategy than the simulated one.
+	JobStrategies []allocation.JobStrategy
+}
+
+// Report is the allocation of the targets with a strategy.
+type Report struct {
+	Strategy   string `json:"strategy"`
+	Collectors int    `json:"collectors"`
+	Targets    int    `json:"targets"`
+	// Unassigned is the number of targets which were not assigned to any collector.
+	Unassigned          int                  `json:"unassigned"`
+	TargetsPerCollector map[string]int       `json:"targets_per_collector"`
+	Jobs                map[string]JobSpread `json:"jobs"`
+	// MovedOnScaleUp is the number of targets which move to another collector when a collector is added.
+	MovedOnScaleUp int `json:"moved_on_scale_up"`
+	// MovedOnScaleDown is the number of targets which move to another collector, or are no longer assigned, when the
+	// last collector is removed. The targets of the removed collector are included.
+	MovedOnScaleDown int `json:"moved_on_scale_down"`
+}
+
+// JobSpread is how the targets of a job are spread over the collectors.
+type JobSpread struct {
+	Targets    int `json:"targets"`
+	Collectors int `json:"collectors"`
+	// MinPerCollector and MaxPerCollector are over all the collectors, including the ones without targets of the job.
+	MinPerCollector int `json:"min_per_collector"`
+	MaxPerCollector int `json:"max_per_collector"`
+}
+
+// Run allocates the targets with each strategy and returns a report per strategy, in the order of the strategy names.
+// The targets are not modified.
+func Run(log logr.Logger, targets []*target.Item, options Options) ([]Report, error) {
+	if options.Collectors < 1 {
+		return nil, fmt.Errorf("the number of collectors must be at least 1, got %d", options.Collectors)
+	}
+	strategies := options.Strategies
+	if len(strategies) == 0 {
+		strategies = allocation.GetRegisteredAllocatorNames()
+	}
+	strategies = slices.Sorted(slices.Values(strategies))
+
+	var reports []Report
+	for _, strategy := range strategies {
+		report, err := simulate(log, strategy, targets, options)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func simulate(log logr.Logger, strategy string, targets []*target.Item, options Options) (Report, error) {
+	collectors := makeCollectors(strategy, targets, options.Collectors)
+
+	allocator, err := newAllocator(log, strategy, options)
+	if err != nil {
+		return Report{}, err
+	}
+	allocator.SetCollectors(collectors)
+	allocator.SetTargets(cloneTargets(targets))
+	assignments := assignmentsOf(allocator)
+	report := newReport(strategy, collectors, assignments, allocator.TargetItems())
+
+	allocator.SetCollectors(withAddedCollector(collectors))
+	report.MovedOnScaleUp = countMoved(assignments, assignmentsOf(allocator))
+
+	allocator, err = newAllocator(log, strategy, options)
+	if err != nil {
+		return Report{}, err
+	}
+	allocator.SetCollectors(collectors)
+	allocator.SetTargets(cloneTargets(targets))
+	assignments = assignmentsOf(allocator)
+	allocator.SetCollectors(withoutLastCollector(collectors))
+	report.MovedOnScaleDown = countMoved(assignments, assignmentsOf(allocator))
+
+	return report, nil
+}
+
+func newAllocator(log logr.Logger, strategy string, options Options) (allocation.Allocator, error) {
+	var opts []allocation.Option
+	if options.FallbackStrategy != "" {
+		opts = append(opts, allocation.WithFallbackStrategy(options.FallbackStrategy))
+	}
+	allocator, err := allocation.New(strategy, log, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err = allocator.SetJobStrategies(options.JobStrategies); err != nil {
+		return nil, err
+	}
+	return allocator, nil
+}
+
+// makeCollectors returns n collectors named like the ones of a collector StatefulSet, or a collector on each node of
+// the targets for the per-node strategy.
+func makeCollectors(strategy string, targets []*target.Item, n int) map[string]*allocation.Collector {
+	if strategy != perNodeStrategy {
+		return allocation.MakeNCollectors(n, 0)
+	}
+	nodes := map[string]bool{}
+	for _, item := range targets {
+		if node := item.GetNodeName(); node != "" {
+			nodes[node] = true
+		}
+	}
+	collectors := map[string]*allocation.Collector{}
+	for i, node := range slices.Sorted(maps.Keys(nodes)) {
+		name := fmt.Sprintf("collector-%d", i)
+		collectors[name] = allocation.NewCollector(name, node)
+	}
+	return collectors
+}
+
+// withAddedCollector returns the collectors with one more, on a node of its own.
+func withAddedCollector(collectors map[string]*allocation.Collector) map[string]*allocation.Collector {
+	result := cloneCollectors(collectors)
+	name := fmt.Sprintf("collector-%d", len(collectors))
+	result[name] = allocation.NewCollector(name, fmt.Sprintf("simulated-node-%d", len(collectors)))
+	return result
+}
+
+// withoutLastCollector returns the collectors without the one a StatefulSet would remove when scaled down.
+func withoutLastCollector(collectors map[string]*allocation.Collector) map[string]*allocation.Collector {
+	result := cloneCollectors(collectors)
+	delete(result, fmt.Sprintf("collector-%d", len(collectors)-1))
+	return result
+}
+
+func cloneCollectors(collectors map[string]*allocation.Collector) map[string]*allocation.Collector {
+	result := make(map[string]*allocation.Collector, len(collectors))
+	for name, collector := range collectors {
+		result[name] = allocation.NewCollector(collector.Name, collector.NodeName)
+		result[name].Zone, result[name].Region = collector.Zone, collector.Region
+	}
+	return result
+}
+
+// cloneTargets copies the targets, as the allocator sets their collector.
+func cloneTargets(targets []*target.Item) []*target.Item {
+	result := make([]*target.Item, 0, len(targets))
+	for _, item := range targets {
+		result = append(result, unassigned(item))
+	}
+	return result
+}
+
+// assignmentsOf returns the collector of each target of the allocator, empty for the unassigned ones.
+func assignmentsOf(allocator allocation.Allocator) map[target.ItemHash]string {
+	assignments := map[target.ItemHash]string{}
+	for hash, item := range allocator.TargetItems() {
+		assignments[hash] = item.CollectorName
+	}
+	return assignments
+}
+
+// countMoved returns the number of assigned targets whose collector changed.
+func countMoved(before, after map[target.ItemHash]string) int {
+	moved := 0
+	for hash, collectorName := range before {
+		if collectorName != "" && after[hash] != collectorName {
+			moved++
+		}
+	}
+	return moved
+}
+
+func newReport(strategy string, collectors map[string]*allocation.Collector, assignments map[target.ItemHash]string, items map[target.ItemHash]*target.Item) Report {
+	report := Report{
+		Strategy:            strategy,
+		Collectors:          len(collectors),
+		Targets:             len(items),
+		TargetsPerCollector: map[string]int{},
+		Jobs:                map[string]JobSpread{},
+	}
+	for name := range collectors {
+		report.TargetsPerCollector[name] = 0
+	}
+	perJobPerCollector := map[string]map[string]int{}
+	for hash, item := range items {
+		collectorName := assignments[hash]
+		if collectorName == "" {
+			report.Unassigned++
+			continue
+		}
+		report.TargetsPerCollector[collectorName]++
+		if perJobPerCollector[item.JobName] == nil {
+			perJobPerCollector[item.JobName] = map[string]int{}
+		}
+		perJobPerCollector[item.JobName][collectorName]++
+	}
+	for jobName, perCollector := range perJobPerCollector {
+		spread := JobSpread{Collectors: len(perCollector), MinPerCollector: -1}
+		for name := range collectors {
+			count := perCollector[name]
+			spread.Targets += count
+			if spread.MinPerCollector < 0 || count < spread.MinPerCollector {
+				spread.MinPerCollector = count
+			}
+			spread.MaxPerCollector = max(spread.MaxPerCollector, count)
+		}
+		report.Jobs[jobName] = spread
+	}
+	return report
+}
@@ -0,0 +1,161 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package simulation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/allocation"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
+)
+
+var logger = logf.Log.WithName("unit-tests")
+
+func TestRun(t *testing.T) {
+	targets := append(allocation.MakeNTargetsForJob(30, "job-a", 0), allocation.MakeNTargetsForJob(10, "job-b", 30)...)
+
+	reports, err := Run(logger, targets, Options{Collectors: 4, Strategies: []string{"least-weighted", "consistent-hashing"}})
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	assert.Equal(t, "consistent-hashing", reports[0].Strategy)
+	assert.Equal(t, "least-weighted", reports[1].Strategy)
+
+	for _, report := range reports {
+		assert.Equal(t, 4, report.Collectors, report.Strategy)
+		assert.Equal(t, 40, report.Targets, report.Strategy)
+		assert.Zero(t, report.Unassigned, report.Strategy)
+		assert.Len(t, report.TargetsPerCollector, 4, report.Strategy)
+		total := 0
+		for _, count := range report.TargetsPerCollector {
+			total += count
+		}
+		assert.Equal(t, 40, total, report.Strategy)
+		assert.Equal(t, 30, report.Jobs["job-a"].Targets, report.Strategy)
+		assert.Equal(t, 10, report.Jobs["job-b"].Targets, report.Strategy)
+		// the targets of the removed collector move at least
+		assert.GreaterOrEqual(t, report.MovedOnScaleDown, report.TargetsPerCollector["collector-3"], report.Strategy)
+	}
+
+	leastWeighted := reports[1]
+	for name, count := range leastWeighted.TargetsPerCollector {
+		assert.Equal(t, 10, count, name)
+	}
+	// least-weighted only reassigns the targets of removed collectors
+	assert.Zero(t, leastWeighted.MovedOnScaleUp)
+	assert.Equal(t, 10, leastWeighted.MovedOnScaleDown)
+
+	// the input targets are not assigned
+	for _, item := range targets {
+		assert.Empty(t, item.CollectorName)
+	}
+}
+
+func TestRun_AllStrategies(t *testing.T) {
+	targets := allocation.MakeNNewTargetsWithEmptyCollectors(20, 0)
+
+	reports, err := Run(logger, targets, Options{Collectors: 3, FallbackStrategy: "consistent-hashing"})
+	require.NoError(t, err)
+	require.Len(t, reports, len(allocation.GetRegisteredAllocatorNames()))
+	for _, report := range reports {
+		assert.Equal(t, 20, report.Targets, report.Strategy)
+		assert.Zero(t, report.Unassigned, report.Strategy)
+		if report.Strategy == perNodeStrategy {
+			// all the targets are on node-0
+			assert.Equal(t, 1, report.Collectors)
+			assert.Equal(t, map[string]int{"collector-0": 20}, report.TargetsPerCollector)
+			assert.Equal(t, 20, report.MovedOnScaleDown)
+		}
+	}
+}
+
+func TestRun_InvalidOptions(t *testing.T) {
+	_, err := Run(logger, nil, Options{Collectors: 0})
+	assert.Error(t, err)
+
+	_, err = Run(logger, nil, Options{Collectors: 1, Strategies: []string{"unknown"}})
+	assert.Error(t, err)
+}
+
+func TestLoadTargets(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		contents string
+	}{
+		{
+			name: "target groups by job",
+			contents: `{
+  "job-a": [{"targets": ["10.0.0.1:8080", "10.0.0.2:8080"], "labels": {"__meta_kubernetes_pod_node_name": "node-0"}}],
+  "job-b": [{"targets": ["10.0.0.3:8080"], "labels": {}}]
+}`,
+		},
+		{
+			name: "targets by job and collector",
+			contents: `{
+  "job-a": {
+    "collector-0": {"_link": "/jobs/job-a/targets?collector_id=collector-0", "targets": [{"targets": ["10.0.0.1:8080"], "labels": {"__meta_kubernetes_pod_node_name": "node-0"}}]},
+    "collector-1": {"_link": "/jobs/job-a/targets?collector_id=collector-1", "targets": [{"targets": ["10.0.0.2:8080"], "labels": {"__meta_kubernetes_pod_node_name": "node-0"}}]}
+  },
+  "job-b": {"collector-0": {"_link": "/jobs/job-b/targets?collector_id=collector-0", "targets": [{"targets": ["10.0.0.3:8080"], "labels": {}}]}}
+}`,
+		},
+		{
+			name: "allocation state",
+			contents: `{
+  "time": "2025-01-01T00:00:00Z",
+  "strategy": "least-weighted",
+  "collectors": [{"name": "collector-0"}],
+  "targets": [
+    {"hash": 1, "job": "job-a", "target": "10.0.0.1:8080", "labels": {"__meta_kubernetes_pod_node_name": "node-0"}, "collector": "collector-0"},
+    {"hash": 2, "job": "job-a", "target": "10.0.0.2:8080", "labels": {"__meta_kubernetes_pod_node_name": "node-0"}, "collector": "collector-0"},
+    {"hash": 3, "job": "job-b", "target": "10.0.0.3:8080", "labels": {}, "collector": "collector-0"}
+  ]
+}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "targets.json")
+			require.NoError(t, os.WriteFile(path, []byte(tc.contents), 0600))
+
+			items, err := LoadTargets(path)
+			require.NoError(t, err)
+			require.Len(t, items, 3)
+			jobs := map[string][]string{}
+			for _, item := range items {
+				assert.Empty(t, item.CollectorName)
+				jobs[item.JobName] = append(jobs[item.JobName], item.TargetURL)
+			}
+			assert.ElementsMatch(t, []string{"10.0.0.1:8080", "10.0.0.2:8080"}, jobs["job-a"])
+			assert.Equal(t, []string{"10.0.0.3:8080"}, jobs["job-b"])
+			assert.Equal(t, "node-0", items[0].GetNodeName())
+		})
+	}
+}
+
+func TestLoadTargets_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"job-a": "10.0.0.1:8080"}`), 0600))
+
+	_, err := LoadTargets(path)
+	assert.ErrorContains(t, err, "job job-a")
+
+	_, err = LoadTargets(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+// the labels of the loaded targets are kept, as the per-node strategy and the filters rely on them
+func TestLoadTargets_Labels(t *testing.T) {
+	items, err := parseTargets([]byte(`{"job-a": [{"targets": ["10.0.0.1:8080"], "labels": {"a": "b"}}]}`))
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	expected := labels.FromStrings("__address__", "10.0.0.1:8080", "a", "b")
+	assert.Equal(t, expected, items[0].Labels)
+	assert.Equal(t, target.NewItem("job-a", "10.0.0.1:8080", expected, "").Hash(), items[0].Hash())
+}
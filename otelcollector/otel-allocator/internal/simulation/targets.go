@@ -0,0 +1,119 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package simulation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/allocation"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
+)
+
+// targetGroupJSON is a group of targets with the same labels, as served on /jobs/:job_id/targets.
+type targetGroupJSON struct {
+	Targets []string      `json:"targets"`
+	Labels  labels.Labels `json:"labels"`
+}
+
+// collectorTargetsJSON are the targets of a job assigned to a collector, as served on /jobs/:job_id/targets without
+// the collector_id query parameter.
+type collectorTargetsJSON struct {
+	Targets []targetGroupJSON `json:"targets"`
+}
+
+// LoadTargets reads the targets to allocate from a JSON file, which is either:
+//   - the allocation state saved with allocation_state.file_path,
+//   - a map of job names to the response of /jobs/:job_id/targets, with or without the collector_id query parameter.
+//
+// The collectors the targets were assigned to are dropped. The targets without an __address__ label get one.
+func LoadTargets(path string) ([]*target.Item, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	items, err := parseTargets(contents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the targets of %s: %w", path, err)
+	}
+	return items, nil
+}
+
+func parseTargets(contents []byte) ([]*target.Item, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(contents, &fields); err != nil {
+		return nil, err
+	}
+	if _, hasStrategy := fields["strategy"]; hasStrategy && isJSONArray(fields["targets"]) {
+		var snapshot allocation.Snapshot
+		if err := json.Unmarshal(contents, &snapshot); err != nil {
+			return nil, err
+		}
+		items := make([]*target.Item, 0, len(snapshot.Targets))
+		for _, item := range snapshot.Items() {
+			items = append(items, unassigned(item))
+		}
+		return items, nil
+	}
+
+	var items []*target.Item
+	for _, jobName := range slices.Sorted(maps.Keys(fields)) {
+		groups, err := parseJobTargets(fields[jobName])
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", jobName, err)
+		}
+		for _, group := range groups {
+			for _, targetURL := range group.Targets {
+				items = append(items, target.NewItem(jobName, targetURL, withAddress(group.Labels, targetURL), ""))
+			}
+		}
+	}
+	return items, nil
+}
+
+// parseJobTargets returns the target groups of a job, either listed or keyed by collector.
+func parseJobTargets(contents json.RawMessage) ([]targetGroupJSON, error) {
+	if isJSONArray(contents) {
+		var groups []targetGroupJSON
+		err := json.Unmarshal(contents, &groups)
+		return groups, err
+	}
+	var byCollector map[string]collectorTargetsJSON
+	if err := json.Unmarshal(contents, &byCollector); err != nil {
+		return nil, err
+	}
+	var groups []targetGroupJSON
+	for _, collectorName := range slices.Sorted(maps.Keys(byCollector)) {
+		groups = append(groups, byCollector[collectorName].Targets...)
+	}
+	return groups, nil
+}
+
+// withAddress returns the labels of a target with its address, like the discovered targets, so that the targets of a
+// group do not have the same hash.
+func withAddress(targetLabels labels.Labels, targetURL string) labels.Labels {
+	if targetLabels.Has(model.AddressLabel) {
+		return targetLabels
+	}
+	builder := labels.NewBuilder(targetLabels)
+	builder.Set(model.AddressLabel, targetURL)
+	return builder.Labels()
+}
+
+func isJSONArray(contents json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(contents)
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// unassigned returns a copy of the item without its collector, keeping its hash and weight.
+func unassigned(item *target.Item) *target.Item {
+	return target.NewItem(item.JobName, item.TargetURL, item.Labels, "", target.WithHash(item.Hash()), target.WithWeight(item.Weight()))
+}
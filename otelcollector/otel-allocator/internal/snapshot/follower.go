@@ -0,0 +1,88 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/allocation"
+)
+
+// Follower serves the allocation of the leader on the Target Allocators which are not leading, by loading the
+// snapshots the leader saves into their allocator. It is closed when its Target Allocator becomes the leader.
+type Follower struct {
+	log       logr.Logger
+	store     Store
+	allocator allocation.Allocator
+	interval  time.Duration
+	close     chan struct{}
+	closeOnce sync.Once
+
+	// mtx makes sure no snapshot is loaded once closed, as the allocator then belongs to the leader
+	mtx    sync.Mutex
+	closed bool
+	// loaded is the time of the last loaded snapshot
+	loaded time.Time
+}
+
+func NewFollower(log logr.Logger, store Store, allocator allocation.Allocator, interval time.Duration) *Follower {
+	return &Follower{
+		log:       log.WithValues("component", "opentelemetry-targetallocator-follower"),
+		store:     store,
+		allocator: allocator,
+		interval:  interval,
+		close:     make(chan struct{}),
+	}
+}
+
+// Run loads the snapshot of the leader every interval until closed.
+func (f *Follower) Run(ctx context.Context) error {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		f.load(ctx)
+		select {
+		case <-ticker.C:
+		case <-f.close:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Close stops loading snapshots. No snapshot is loaded once it returns.
+func (f *Follower) Close() {
+	f.closeOnce.Do(func() {
+		close(f.close)
+	})
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.closed = true
+}
+
+func (f *Follower) load(ctx context.Context) {
+	snapshot, err := f.store.Load(ctx)
+	if err != nil {
+		f.log.Error(err, "Failed to load the allocation snapshot of the leader")
+		return
+	}
+	if snapshot == nil {
+		return
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.closed || snapshot.Time.Equal(f.loaded) {
+		return
+	}
+	f.allocator.SetCollectors(snapshot.CollectorMap())
+	f.allocator.SeedTargets(snapshot.Items())
+	f.loaded = snapshot.Time
+	f.log.V(1).Info("Loaded the allocation snapshot of the leader", "targets", len(snapshot.Targets), "time", snapshot.Time)
+}
@@ -0,0 +1,53 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/allocation"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
+)
+
+func assignments(allocator allocation.Allocator) map[target.ItemHash]string {
+	result := make(map[target.ItemHash]string)
+	for hash, item := range allocator.TargetItems() {
+		result[hash] = item.CollectorName
+	}
+	return result
+}
+
+func TestFollower(t *testing.T) {
+	store := NewConfigMapStore(fake.NewClientset(), "test-namespace", "test-state")
+	leader := newAllocation(t)
+	saver := NewSaver(logger, store, leader, "least-weighted", time.Minute)
+	saver.save(t.Context())
+
+	replica, err := allocation.New("least-weighted", logger)
+	require.NoError(t, err)
+	follower := NewFollower(logger, store, replica, time.Minute)
+	follower.load(t.Context())
+	assert.True(t, replica.Ready())
+	assert.Equal(t, assignments(leader), assignments(replica))
+	assert.Len(t, replica.Collectors(), 3)
+
+	// the follower keeps up with the targets moved by the leader
+	leader.SetCollectors(allocation.MakeNCollectors(2, 0))
+	saver.save(t.Context())
+	follower.load(t.Context())
+	assert.Equal(t, assignments(leader), assignments(replica))
+	assert.Len(t, replica.Collectors(), 2)
+
+	// nothing is loaded once the replica leads
+	follower.Close()
+	leader.SetCollectors(allocation.MakeNCollectors(1, 0))
+	saver.save(t.Context())
+	follower.load(t.Context())
+	assert.Len(t, replica.Collectors(), 2)
+}
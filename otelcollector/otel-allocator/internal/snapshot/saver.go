@@ -0,0 +1,100 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/allocation"
+)
+
+// Saver seeds the allocator with the saved snapshot on startup, and saves a new snapshot whenever the assignments
+// change.
+type Saver struct {
+	log       logr.Logger
+	store     Store
+	allocator allocation.Allocator
+	strategy  string
+	interval  time.Duration
+	close     chan struct{}
+
+	// saved are the encoded assignments of the last saved snapshot
+	saved []byte
+}
+
+func NewSaver(log logr.Logger, store Store, allocator allocation.Allocator, strategy string, interval time.Duration) *Saver {
+	return &Saver{
+		log:       log.WithValues("component", "opentelemetry-targetallocator-snapshot"),
+		store:     store,
+		allocator: allocator,
+		strategy:  strategy,
+		interval:  interval,
+		close:     make(chan struct{}),
+	}
+}
+
+// Seed loads the saved snapshot and seeds the allocator with its targets. The snapshot is ignored if it was taken with
+// another allocation strategy.
+func (s *Saver) Seed(ctx context.Context) error {
+	snapshot, err := s.store.Load(ctx)
+	if err != nil || snapshot == nil {
+		return err
+	}
+	if snapshot.Strategy != s.strategy {
+		s.log.Info("Ignoring the saved allocation, taken with another strategy", "strategy", snapshot.Strategy)
+		return nil
+	}
+	s.log.Info("Seeding the allocation from the saved snapshot", "targets", len(snapshot.Targets), "time", snapshot.Time)
+	s.allocator.SeedTargets(snapshot.Items())
+	return nil
+}
+
+// Run saves the snapshot every interval when the assignments changed, and a last time when closed.
+func (s *Saver) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.save(ctx)
+		case <-s.close:
+			s.save(ctx)
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (s *Saver) Close() {
+	close(s.close)
+}
+
+// save saves the snapshot if the assignments changed since the last save. Nothing is saved before the allocator is
+// ready, so that a snapshot is not overwritten before it was used.
+func (s *Saver) save(ctx context.Context) {
+	if !s.allocator.Ready() {
+		return
+	}
+	snapshot := allocation.NewSnapshot(s.allocator, s.strategy)
+	assignments, err := json.Marshal([]any{snapshot.Collectors, snapshot.Targets})
+	if err != nil {
+		s.log.Error(err, "Failed to encode the allocation snapshot")
+		return
+	}
+	if bytes.Equal(assignments, s.saved) {
+		return
+	}
+	if err := s.store.Save(ctx, snapshot); err != nil {
+		s.log.Error(err, "Failed to save the allocation snapshot")
+		return
+	}
+	s.saved = assignments
+	s.log.V(1).Info("Saved the allocation snapshot", "targets", len(snapshot.Targets))
+}
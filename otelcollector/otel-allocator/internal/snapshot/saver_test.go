@@ -0,0 +1,69 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshot
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/allocation"
+)
+
+type countingStore struct {
+	Store
+	saves int
+}
+
+func (c *countingStore) Save(ctx context.Context, snapshot *allocation.Snapshot) error {
+	c.saves++
+	return c.Store.Save(ctx, snapshot)
+}
+
+func TestSaver(t *testing.T) {
+	store := &countingStore{Store: NewFileStore(filepath.Join(t.TempDir(), "snapshot.json.gz"))}
+
+	// nothing is saved before the allocator is ready
+	allocator, err := allocation.New("least-weighted", logger)
+	require.NoError(t, err)
+	saver := NewSaver(logger, store, allocator, "least-weighted", time.Minute)
+	saver.save(t.Context())
+	assert.Zero(t, store.saves)
+
+	previous := newAllocation(t)
+	saver = NewSaver(logger, store, previous, "least-weighted", time.Minute)
+	saver.save(t.Context())
+	saver.save(t.Context())
+	assert.Equal(t, 1, store.saves, "the snapshot is only saved when the assignments change")
+
+	restarted, err := allocation.New("least-weighted", logger)
+	require.NoError(t, err)
+	require.NoError(t, NewSaver(logger, store, restarted, "least-weighted", time.Minute).Seed(t.Context()))
+	assert.True(t, restarted.Ready())
+	assert.Len(t, restarted.TargetItems(), 10)
+
+	// snapshots of another strategy are not seeded
+	otherStrategy, err := allocation.New("consistent-hashing", logger)
+	require.NoError(t, err)
+	require.NoError(t, NewSaver(logger, store, otherStrategy, "consistent-hashing", time.Minute).Seed(t.Context()))
+	assert.False(t, otherStrategy.Ready())
+	assert.Empty(t, otherStrategy.TargetItems())
+}
+
+func TestSaverSavesOnClose(t *testing.T) {
+	store := &countingStore{Store: NewFileStore(filepath.Join(t.TempDir(), "snapshot.json.gz"))}
+	saver := NewSaver(logger, store, newAllocation(t), "least-weighted", time.Hour)
+
+	done := make(chan error)
+	go func() {
+		done <- saver.Run(t.Context())
+	}()
+	saver.Close()
+	require.NoError(t, <-done)
+	assert.Equal(t, 1, store.saves)
+}
@@ -0,0 +1,157 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/allocation"
+)
+
+// configMapKey is the key of the snapshot in the ConfigMap. The snapshot is compressed, as ConfigMaps are limited to
+// 1MiB and the labels of the targets are repetitive.
+const configMapKey = "snapshot.json.gz"
+
+// Store saves and loads the snapshot of the allocation.
+type Store interface {
+	// Load returns the saved snapshot, or nil if none was saved.
+	Load(ctx context.Context) (*allocation.Snapshot, error)
+	Save(ctx context.Context, snapshot *allocation.Snapshot) error
+}
+
+func encode(snapshot *allocation.Snapshot) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(writer).Encode(snapshot); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decode(data []byte) (*allocation.Snapshot, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	snapshot := &allocation.Snapshot{}
+	if err := json.NewDecoder(reader).Decode(snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+var _ Store = &FileStore{}
+
+// FileStore saves the snapshot to a local file.
+type FileStore struct {
+	path string
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (f *FileStore) Load(context.Context) (*allocation.Snapshot, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decode(data)
+}
+
+// Save writes the snapshot to a temporary file renamed over the previous one, so that a crash never leaves a
+// partial snapshot behind.
+func (f *FileStore) Save(_ context.Context, snapshot *allocation.Snapshot) error {
+	data, err := encode(snapshot)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+var _ Store = &ConfigMapStore{}
+
+// ConfigMapStore saves the snapshot to a ConfigMap, created on the first save.
+type ConfigMapStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+func NewConfigMapStore(client kubernetes.Interface, namespace, name string) *ConfigMapStore {
+	return &ConfigMapStore{client: client, namespace: namespace, name: name}
+}
+
+func (c *ConfigMapStore) Load(ctx context.Context) (*allocation.Snapshot, error) {
+	configMap, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(ctx, c.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, ok := configMap.BinaryData[configMapKey]
+	if !ok {
+		return nil, nil
+	}
+	return decode(data)
+}
+
+func (c *ConfigMapStore) Save(ctx context.Context, snapshot *allocation.Snapshot) error {
+	data, err := encode(snapshot)
+	if err != nil {
+		return err
+	}
+	configMaps := c.client.CoreV1().ConfigMaps(c.namespace)
+	configMap, err := configMaps.Get(ctx, c.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: c.name, Namespace: c.namespace},
+			BinaryData: map[string][]byte{configMapKey: data},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if configMap.BinaryData == nil {
+		configMap.BinaryData = make(map[string][]byte)
+	}
+	configMap.BinaryData[configMapKey] = data
+	if _, err := configMaps.Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update ConfigMap %s/%s: %w", c.namespace, c.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshot
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/allocation"
+)
+
+var logger = logf.Log.WithName("unit-tests")
+
+func newAllocation(t *testing.T) allocation.Allocator {
+	allocator, err := allocation.New("least-weighted", logger)
+	require.NoError(t, err)
+	allocator.SetCollectors(allocation.MakeNCollectors(3, 0))
+	allocator.SetTargets(allocation.MakeNNewTargetsWithEmptyCollectors(10, 0))
+	return allocator
+}
+
+func TestStores(t *testing.T) {
+	stores := map[string]Store{
+		"file":      NewFileStore(filepath.Join(t.TempDir(), "snapshot.json.gz")),
+		"configmap": NewConfigMapStore(fake.NewClientset(), "test-namespace", "test-state"),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			loaded, err := store.Load(t.Context())
+			require.NoError(t, err)
+			assert.Nil(t, loaded, "nothing was saved yet")
+
+			// the second save replaces the first one
+			require.NoError(t, store.Save(t.Context(), &allocation.Snapshot{Strategy: "consistent-hashing"}))
+			snapshot := allocation.NewSnapshot(newAllocation(t), "least-weighted")
+			require.NoError(t, store.Save(t.Context(), snapshot))
+
+			loaded, err = store.Load(t.Context())
+			require.NoError(t, err)
+			require.NotNil(t, loaded)
+			assert.Equal(t, "least-weighted", loaded.Strategy)
+			assert.True(t, snapshot.Time.Equal(loaded.Time))
+			assert.Equal(t, snapshot.Targets, loaded.Targets)
+		})
+	}
+}
+
+func TestConfigMapStoreSavesToConfigMap(t *testing.T) {
+	client := fake.NewClientset()
+	store := NewConfigMapStore(client, "test-namespace", "test-state")
+	require.NoError(t, store.Save(t.Context(), &allocation.Snapshot{Strategy: "least-weighted"}))
+
+	configMap, err := client.CoreV1().ConfigMaps("test-namespace").Get(t.Context(), "test-state", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, configMap.BinaryData, configMapKey)
+}
@@ -45,6 +45,10 @@ type Discoverer struct {
 	processTargetsDuration      metric.Float64Histogram
 	processTargetGroupsDuration metric.Float64Histogram
 	reloadInterval              time.Duration
+	// mtxConfig guards configsMap, scrapeConfigsHash and standby, as configs are applied from several watchers.
+	mtxConfig sync.Mutex
+	// standby is set until Activate when the discovery only runs on the leader.
+	standby bool
 }
 
 // DiscovererOption configures optional Discoverer behavior.
@@ -57,6 +61,12 @@ func WithReloadInterval(d time.Duration) DiscovererOption {
 	return func(disc *Discoverer) { disc.reloadInterval = d }
 }
 
+// WithStandby keeps the discoverer from discovering targets until Activate is called. The scrape configs are still
+// applied to the scrapeConfigsUpdater, so that a Target Allocator which is not the leader serves them.
+func WithStandby() DiscovererOption {
+	return func(disc *Discoverer) { disc.standby = true }
+}
+
 const defaultReloadInterval = 5 * time.Second
 
 type discoveryHook interface {
@@ -105,6 +115,8 @@ func NewDiscoverer(log logr.Logger, manager *discovery.Manager, hook discoveryHo
 }
 
 func (m *Discoverer) ApplyConfig(source allocatorWatcher.EventSource, scrapeConfigs []*promconfig.ScrapeConfig) error {
+	m.mtxConfig.Lock()
+	defer m.mtxConfig.Unlock()
 	m.configsMap[source] = scrapeConfigs
 	jobToScrapeConfig := make(map[string]*promconfig.ScrapeConfig)
 
@@ -137,6 +149,27 @@ func (m *Discoverer) ApplyConfig(source allocatorWatcher.EventSource, scrapeConf
 	if m.hook != nil {
 		m.hook.SetConfig(relabelCfg)
 	}
+	if m.standby {
+		return nil
+	}
+	return m.manager.ApplyConfig(discoveryCfg)
+}
+
+// Activate starts discovering the targets of the configs applied while on standby.
+func (m *Discoverer) Activate() error {
+	m.mtxConfig.Lock()
+	defer m.mtxConfig.Unlock()
+	if !m.standby {
+		return nil
+	}
+	m.standby = false
+
+	discoveryCfg := make(map[string]discovery.Configs)
+	for _, configs := range m.configsMap {
+		for _, scrapeConfig := range configs {
+			discoveryCfg[scrapeConfig.JobName] = scrapeConfig.ServiceDiscoveryConfigs
+		}
+	}
 	return m.manager.ApplyConfig(discoveryCfg)
 }
 
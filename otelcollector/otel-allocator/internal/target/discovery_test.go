@@ -560,6 +560,50 @@ var _ scrapeConfigsUpdater = &mockScrapeConfigUpdater{}
 // mockScrapeConfigUpdater is a mock implementation of the scrapeConfigsUpdater.
 // If a job with name "error" is provided to the UpdateScrapeConfigResponse,
 // it will return an error for testing purposes.
+func TestDiscovererStandby(t *testing.T) {
+	scu := &mockScrapeConfigUpdater{}
+	registry := prometheus.NewRegistry()
+	sdMetrics, err := discovery.CreateAndRegisterSDMetrics(registry)
+	require.NoError(t, err)
+	d := discovery.NewManager(t.Context(), config.NopLogger, registry, sdMetrics)
+	results := make(chan []string, 10)
+	manager, err := NewDiscoverer(ctrl.Log.WithName("test"), d, nil, scu, func(targets []*Item) {
+		var result []string
+		for _, t := range targets {
+			result = append(result, t.TargetURL)
+		}
+		results <- result
+	}, WithStandby(), WithReloadInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer manager.Close()
+
+	go func() {
+		_ = d.Run()
+	}()
+	go func() {
+		assert.NoError(t, manager.Run())
+	}()
+
+	cfg := config.CreateDefaultConfig()
+	require.NoError(t, config.LoadFromFile("./testdata/test.yaml", &cfg))
+	require.NoError(t, manager.ApplyConfig(allocatorWatcher.EventSourcePrometheusCR, cfg.PromConfig.ScrapeConfigs))
+	// the scrape configs are served on standby, but their targets are not discovered
+	assert.Len(t, scu.mockCfg, len(cfg.PromConfig.ScrapeConfigs))
+	select {
+	case result := <-results:
+		t.Fatalf("targets discovered on standby: %v", result)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	require.NoError(t, manager.Activate())
+	select {
+	case result := <-results:
+		assert.Len(t, result, 6)
+	case <-time.After(10 * time.Second):
+		t.Fatal("targets not discovered once active")
+	}
+}
+
 type mockScrapeConfigUpdater struct {
 	mockCfg map[string]*promconfig.ScrapeConfig
 }
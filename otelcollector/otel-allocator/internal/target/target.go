@@ -5,6 +5,7 @@ package target
 
 import (
 	"encoding/binary"
+	"math"
 	"strconv"
 	"strings"
 	"sync"
@@ -40,6 +41,10 @@ var (
 	relevantLabelNames           = append(nodeLabels, endpointSliceTargetKindLabel, endpointSliceTargetNameLabel)
 )
 
+// WeightLabel can be set through relabeling to give a target a weight other than 1, for allocation strategies which
+// balance collectors by weight. Like other labels starting with "__", it is dropped by the collector after relabeling.
+const WeightLabel = "__target_weight"
+
 type ItemHash uint64
 
 func (h ItemHash) String() string {
@@ -53,6 +58,7 @@ type Item struct {
 	Labels        labels.Labels
 	CollectorName string
 	hash          ItemHash
+	weight        float64
 }
 
 type ItemOption func(*Item)
@@ -65,6 +71,13 @@ func WithHash(hash ItemHash) ItemOption {
 	}
 }
 
+// WithWeight sets the weight of the item, as read from the WeightLabel after relabeling.
+func WithWeight(weight float64) ItemOption {
+	return func(i *Item) {
+		i.weight = weight
+	}
+}
+
 func (t *Item) Hash() ItemHash {
 	if t.hash == 0 {
 		t.hash = ItemHash(LabelsHashWithJobName(t.Labels, t.JobName))
@@ -81,7 +94,8 @@ func HashFromBuilder(builder *labels.Builder, jobName string) ItemHash {
 	builder.Range(func(l labels.Label) {
 		// Skip meta labels - they are discarded after relabeling in Prometheus.
 		// For details, see https://github.com/prometheus/prometheus/blob/e6cfa720fbe6280153fab13090a483dbd40bece3/scrape/target.go#L534
-		if strings.HasPrefix(l.Name, model.MetaLabelPrefix) {
+		// The weight is skipped too, so that a target keeps its identity when its weight changes.
+		if strings.HasPrefix(l.Name, model.MetaLabelPrefix) || l.Name == WeightLabel {
 			return
 		}
 		_, _ = hash.WriteString(l.Name)
@@ -110,6 +124,23 @@ func (t *Item) GetNodeName() string {
 	return relevantLabels.Get(endpointSliceTargetNameLabel)
 }
 
+// Weight returns the weight of the target, set with WithWeight or through the WeightLabel, and 1 otherwise.
+func (t *Item) Weight() float64 {
+	if t.weight > 0 {
+		return t.weight
+	}
+	return ParseWeight(t.Labels.Get(WeightLabel))
+}
+
+// ParseWeight parses the value of the WeightLabel, returning 1 when it is not a positive number.
+func ParseWeight(value string) float64 {
+	weight, err := strconv.ParseFloat(value, 64)
+	if err != nil || !(weight > 0) || math.IsInf(weight, 1) {
+		return 1
+	}
+	return weight
+}
+
 // GetEndpointSliceName returns the name of the EndpointSlice that the target is part of.
 // If the target is not part of an EndpointSlice, it returns an empty string.
 func (t *Item) GetEndpointSliceName() string {
@@ -201,6 +201,50 @@ func TestGetEndpointSliceName(t *testing.T) {
 	}
 }
 
+func TestItemWeight(t *testing.T) {
+	tests := []struct {
+		name     string
+		labels   labels.Labels
+		opts     []ItemOption
+		expected float64
+	}{
+		{
+			name:     "no weight",
+			labels:   labels.New(labels.Label{Name: "app", Value: "test"}),
+			expected: 1,
+		},
+		{
+			name:     "weight label",
+			labels:   labels.New(labels.Label{Name: WeightLabel, Value: "2.5"}),
+			expected: 2.5,
+		},
+		{
+			name:     "invalid weight label",
+			labels:   labels.New(labels.Label{Name: WeightLabel, Value: "-3"}),
+			expected: 1,
+		},
+		{
+			name:     "weight option takes precedence",
+			labels:   labels.New(labels.Label{Name: WeightLabel, Value: "2.5"}),
+			opts:     []ItemOption{WithWeight(40)},
+			expected: 40,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := NewItem("job", "http://10.0.0.1:8080", tt.labels, "", tt.opts...)
+			assert.Equal(t, tt.expected, item.Weight())
+		})
+	}
+}
+
+func TestHashFromBuilderIgnoresWeight(t *testing.T) {
+	builder := labels.NewBuilder(labels.New(labels.Label{Name: "app", Value: "test"}))
+	hash := HashFromBuilder(builder, "job")
+	builder.Set(WeightLabel, "10")
+	assert.Equal(t, hash, HashFromBuilder(builder, "job"))
+}
+
 func TestLabelsHashWithJobName(t *testing.T) {
 	ls := labels.New(
 		labels.Label{Name: "app", Value: "test"},
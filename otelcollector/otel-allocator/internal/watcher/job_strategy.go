@@ -0,0 +1,48 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package watcher
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	allocatorconfig "github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/config"
+)
+
+// AllocationStrategyAnnotation sets the allocation strategy of the jobs generated from a ServiceMonitor, PodMonitor,
+// Probe or ScrapeConfig, instead of the allocation strategy of the Target Allocator.
+const AllocationStrategyAnnotation = "opentelemetry.io/target-allocator-strategy"
+
+// JobStrategies returns the allocation strategies of the jobs of the Prometheus CRs annotated with
+// AllocationStrategyAnnotation, as of the last LoadConfig.
+func (w *PrometheusCRWatcher) JobStrategies() []allocatorconfig.JobAllocationStrategy {
+	w.jobStrategiesMtx.RLock()
+	defer w.jobStrategiesMtx.RUnlock()
+	return w.jobStrategies
+}
+
+func (w *PrometheusCRWatcher) setJobStrategies(jobStrategies []allocatorconfig.JobAllocationStrategy) {
+	w.jobStrategiesMtx.Lock()
+	defer w.jobStrategiesMtx.Unlock()
+	w.jobStrategies = jobStrategies
+}
+
+// annotatedJobStrategies returns the strategies of the jobs of the annotated resources, whose job names start with
+// <jobPrefix>/<namespace>/<name>, followed by the endpoint index for monitors.
+func annotatedJobStrategies[T metav1.Object](jobPrefix string, resources map[string]T) []allocatorconfig.JobAllocationStrategy {
+	var jobStrategies []allocatorconfig.JobAllocationStrategy
+	for _, key := range slices.Sorted(maps.Keys(resources)) {
+		resource := resources[key]
+		strategy, ok := resource.GetAnnotations()[AllocationStrategyAnnotation]
+		if !ok {
+			continue
+		}
+		jobName := regexp.QuoteMeta(jobPrefix+"/"+resource.GetNamespace()+"/"+resource.GetName()) + "(?:/.*)?"
+		jobStrategies = append(jobStrategies, allocatorconfig.JobAllocationStrategy{JobName: jobName, Strategy: strategy})
+	}
+	return jobStrategies
+}
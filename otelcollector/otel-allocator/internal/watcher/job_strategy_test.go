@@ -0,0 +1,37 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package watcher
+
+import (
+	"regexp"
+	"testing"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	allocatorconfig "github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/config"
+)
+
+func TestAnnotatedJobStrategies(t *testing.T) {
+	podMonitors := map[string]*monitoringv1.PodMonitor{
+		"kube-system/node-exporter": {ObjectMeta: metav1.ObjectMeta{
+			Name:        "node-exporter",
+			Namespace:   "kube-system",
+			Annotations: map[string]string{AllocationStrategyAnnotation: "per-node"},
+		}},
+		"default/app": {ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}},
+	}
+
+	jobStrategies := annotatedJobStrategies("podMonitor", podMonitors)
+	require.Equal(t, []allocatorconfig.JobAllocationStrategy{
+		{JobName: `podMonitor/kube-system/node-exporter(?:/.*)?`, Strategy: "per-node"},
+	}, jobStrategies)
+
+	jobName := regexp.MustCompile("^(?:" + jobStrategies[0].JobName + ")$")
+	assert.True(t, jobName.MatchString("podMonitor/kube-system/node-exporter/0"))
+	assert.False(t, jobName.MatchString("podMonitor/kube-system/node-exporter-2/0"))
+	assert.False(t, jobName.MatchString("podMonitor/default/app/0"))
+}
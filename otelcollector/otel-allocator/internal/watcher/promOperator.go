@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/blang/semver/v4"
@@ -172,6 +174,8 @@ type PrometheusCRWatcher struct {
 	store                           *assets.StoreBuilder
 	prometheusCR                    *monitoringv1.Prometheus
 	denyFSAccessThroughSMs          bool
+	jobStrategies                   []allocatorconfig.JobAllocationStrategy
+	jobStrategiesMtx                sync.RWMutex
 }
 
 func getNamespaceInformer(ctx context.Context, allowList, denyList map[string]struct{}, promOperatorLogger *slog.Logger, clientset kubernetes.Interface, operatorMetrics *operator.Metrics) (cache.SharedIndexInformer, error) {
@@ -631,6 +635,13 @@ func (w *PrometheusCRWatcher) LoadConfig(ctx context.Context) (*promconfig.Confi
 			scrapeConfigInstances = selection.ValidResources()
 		}
 
+		w.setJobStrategies(slices.Concat(
+			annotatedJobStrategies("serviceMonitor", serviceMonitorInstances),
+			annotatedJobStrategies("podMonitor", podMonitorInstances),
+			annotatedJobStrategies("probe", probeInstances),
+			annotatedJobStrategies("scrapeConfig", scrapeConfigInstances),
+		))
+
 		generatedConfig, err := w.configGenerator.GenerateServerConfiguration(
 			w.prometheusCR,
 			serviceMonitorInstances,
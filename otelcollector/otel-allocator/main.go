@@ -8,8 +8,10 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 
 	"github.com/oklog/run"
@@ -29,8 +31,11 @@ import (
 	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/allocation"
 	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/collector"
 	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/config"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/leader"
 	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/prehook"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/recommendation"
 	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/server"
+	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/snapshot"
 	"github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/target"
 	allocatorWatcher "github.com/open-telemetry/opentelemetry-operator/cmd/otel-allocator/internal/watcher"
 )
@@ -52,6 +57,8 @@ func main() {
 		collectorWatcher *collector.Watcher
 		targetDiscoverer *target.Discoverer
 		certWatcher      *certwatcher.CertWatcher
+		elector          *leader.Elector
+		follower         *snapshot.Follower
 
 		discoveryCancel context.CancelFunc
 		runGroup        run.Group
@@ -95,12 +102,101 @@ func main() {
 	otel.SetMeterProvider(meterProvider)
 
 	allocatorPrehook = prehook.New(cfg.FilterStrategy, log)
-	allocator, allocErr := allocation.New(cfg.AllocationStrategy, log, allocation.WithFilter(allocatorPrehook), allocation.WithFallbackStrategy(cfg.AllocationFallbackStrategy))
+	allocationOptions := []allocation.Option{allocation.WithFilter(allocatorPrehook), allocation.WithFallbackStrategy(cfg.AllocationFallbackStrategy)}
+	if len(cfg.JobAllocationStrategies) > 0 {
+		allocationOptions = append(allocationOptions, allocation.WithJobStrategies(jobStrategies(cfg.JobAllocationStrategies)))
+	}
+	if cfg.CollectorHealth.Enabled {
+		allocationOptions = append(allocationOptions, allocation.WithCollectorHealth(allocation.CollectorHealthConfig{
+			MaxSeries:             cfg.CollectorHealth.MaxSeries,
+			MaxMemoryUsageRatio:   cfg.CollectorHealth.MaxMemoryUsageRatio,
+			MaxScrapeFailureRatio: cfg.CollectorHealth.MaxScrapeFailureRatio,
+			HeartbeatTimeout:      cfg.CollectorHealth.HeartbeatTimeout,
+			RecoveryDelay:         cfg.CollectorHealth.RecoveryDelay,
+		}))
+	}
+	watchNodeTopology := cfg.UsesAllocationStrategy(allocation.TopologyAwareStrategyName)
+	// the topology aware strategies use allocation.DefaultTopologySpilloverRatio when the config does not set a ratio
+	if watchNodeTopology && cfg.Topology.SpilloverRatio != 0 {
+		allocationOptions = append(allocationOptions, allocation.WithTopologySpilloverRatio(cfg.Topology.SpilloverRatio))
+	}
+	allocator, allocErr := allocation.New(cfg.AllocationStrategy, log, allocationOptions...)
 	if allocErr != nil {
 		setupLog.Error(allocErr, "Unable to initialize allocation strategy")
 		os.Exit(1)
 	}
 
+	var snapshotSaver *snapshot.Saver
+	if cfg.AllocationState.Enabled {
+		var store snapshot.Store
+		if cfg.AllocationState.FilePath != "" {
+			store = snapshot.NewFileStore(cfg.AllocationState.FilePath)
+		} else {
+			namespace := cfg.AllocationState.ConfigMapNamespace
+			if namespace == "" {
+				namespace = cfg.CollectorNamespace
+			}
+			store = snapshot.NewConfigMapStore(k8sClient, namespace, cfg.AllocationState.ConfigMapName)
+		}
+		snapshotSaver = snapshot.NewSaver(log, store, allocator, cfg.AllocationStrategy, cfg.AllocationState.SaveInterval)
+		if cfg.LeaderElection.Enabled {
+			// Replicas serve the allocation of the leader until they lead
+			follower = snapshot.NewFollower(log, store, allocator, cfg.AllocationState.SaveInterval)
+		} else if seedErr := snapshotSaver.Seed(ctx); seedErr != nil {
+			// A missing or unreadable snapshot only means the targets are allocated from scratch
+			setupLog.Error(seedErr, "Unable to seed the allocation from the saved snapshot")
+		}
+	}
+
+	// Without leader election, the discovery and allocation run with the other actors. With it, they only run on
+	// the leader, which exits when it stops leading to restart as a follower.
+	allocationGroup := &runGroup
+	electionCtx, electionCancel := context.WithCancel(ctx)
+	defer electionCancel()
+	if cfg.LeaderElection.Enabled {
+		allocationGroup = &run.Group{}
+		identity, identityErr := leaderIdentity(cfg)
+		if identityErr != nil {
+			setupLog.Error(identityErr, "Unable to determine the leader election identity")
+			os.Exit(1)
+		}
+		leaseNamespace := cfg.LeaderElection.LeaseNamespace
+		if leaseNamespace == "" {
+			leaseNamespace = cfg.CollectorNamespace
+		}
+		var electorErr error
+		elector, electorErr = leader.NewElector(log, k8sClient, leader.Config{
+			LeaseName:      cfg.LeaderElection.LeaseName,
+			LeaseNamespace: leaseNamespace,
+			Identity:       identity,
+			LeaseDuration:  cfg.LeaderElection.LeaseDuration,
+			RenewDeadline:  cfg.LeaderElection.RenewDeadline,
+			RetryPeriod:    cfg.LeaderElection.RetryPeriod,
+		}, func(leaderCtx context.Context) {
+			// the allocation served as a follower is kept, so that the collectors keep their targets
+			follower.Close()
+			if activateErr := targetDiscoverer.Activate(); activateErr != nil {
+				setupLog.Error(activateErr, "Unable to start discovering targets")
+				electionCancel()
+				return
+			}
+			allocationGroup.Add(
+				func() error {
+					<-leaderCtx.Done()
+					return nil
+				},
+				func(_ error) {})
+			if leaderErr := allocationGroup.Run(); leaderErr != nil {
+				setupLog.Error(leaderErr, "Allocation exited")
+			}
+			electionCancel()
+		})
+		if electorErr != nil {
+			setupLog.Error(electorErr, "Unable to initialize leader election")
+			os.Exit(1)
+		}
+	}
+
 	httpOptions := []server.Option{}
 	if cfg.HTTPS.Enabled {
 		var tlsConfig *tls.Config
@@ -115,6 +211,32 @@ func main() {
 	if cfg.AllowInsecureAuthSecrets {
 		httpOptions = append(httpOptions, server.WithInsecureAuthSecrets())
 	}
+	if elector != nil {
+		httpOptions = append(httpOptions, server.WithLeaderElection(elector))
+	}
+	if recorder, ok := allocatorPrehook.(prehook.DroppedTargetsRecorder); ok {
+		httpOptions = append(httpOptions, server.WithDroppedTargets(recorder))
+	}
+	if cfg.ProvenanceFile != "" {
+		httpOptions = append(httpOptions, server.WithProvenanceFile(cfg.ProvenanceFile))
+	}
+	var recommender *recommendation.Recommender
+	if cfg.ReplicaRecommendation.Enabled {
+		var recommenderErr error
+		recommender, recommenderErr = recommendation.NewRecommender(log, allocator, recommendation.Config{
+			TargetsPerCollector:    cfg.ReplicaRecommendation.TargetsPerCollector,
+			SeriesPerCollector:     cfg.ReplicaRecommendation.SeriesPerCollector,
+			MinReplicas:            cfg.ReplicaRecommendation.MinReplicas,
+			MaxReplicas:            cfg.ReplicaRecommendation.MaxReplicas,
+			Tolerance:              cfg.ReplicaRecommendation.Tolerance,
+			ScaleDownStabilization: cfg.ReplicaRecommendation.ScaleDownStabilization,
+		}, cfg.ReplicaRecommendation.Interval)
+		if recommenderErr != nil {
+			setupLog.Error(recommenderErr, "Unable to initialize the replica recommendation")
+			os.Exit(1)
+		}
+		httpOptions = append(httpOptions, server.WithReplicaRecommender(recommender))
+	}
 	srv, serverErr := server.NewServer(log, allocator, cfg.ListenAddr, httpOptions...)
 	if serverErr != nil {
 		panic(serverErr)
@@ -129,7 +251,11 @@ func main() {
 	}
 	discoveryManager = discovery.NewManager(discoveryCtx, config.NopLogger, prometheus.DefaultRegisterer, sdMetrics)
 
-	targetDiscoverer, targetErr := target.NewDiscoverer(log, discoveryManager, allocatorPrehook, srv, allocator.SetTargets)
+	var discovererOptions []target.DiscovererOption
+	if elector != nil {
+		discovererOptions = append(discovererOptions, target.WithStandby())
+	}
+	targetDiscoverer, targetErr := target.NewDiscoverer(log, discoveryManager, allocatorPrehook, srv, allocator.SetTargets, discovererOptions...)
 	if targetErr != nil {
 		panic(targetErr)
 	}
@@ -138,6 +264,9 @@ func main() {
 		setupLog.Error(collectorWatcherErr, "Unable to initialize collector watcher")
 		os.Exit(1)
 	}
+	if watchNodeTopology {
+		collectorWatcher.WatchNodeTopology(allocator.SetNodeTopology)
+	}
 	signal.Notify(interrupts, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	defer close(interrupts)
 
@@ -154,6 +283,7 @@ func main() {
 			setupLog.Error(loadErr, "Can't load initial Prometheus configuration from Prometheus CRs")
 			os.Exit(1)
 		}
+		setJobStrategies(allocator, cfg.JobAllocationStrategies, promWatcher.JobStrategies())
 		loadErr = targetDiscoverer.ApplyConfig(allocatorWatcher.EventSourcePrometheusCR, promConfig.ScrapeConfigs)
 		if loadErr != nil {
 			setupLog.Error(loadErr, "Can't load initial scrape targets from Prometheus CRs")
@@ -173,7 +303,17 @@ func main() {
 				}
 			})
 	}
-	runGroup.Add(
+	// Initial loading of the config file's scrape config
+	if cfg.PromConfig != nil && len(cfg.PromConfig.ScrapeConfigs) > 0 {
+		applyErr := targetDiscoverer.ApplyConfig(allocatorWatcher.EventSourceConfigMap, cfg.PromConfig.ScrapeConfigs)
+		if applyErr != nil {
+			setupLog.Error(applyErr, "Unable to apply initial configuration")
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("Prometheus config empty, skipping initial discovery configuration")
+	}
+	allocationGroup.Add(
 		func() error {
 			discoveryManagerErr := discoveryManager.Run()
 			setupLog.Info("Discovery manager exited")
@@ -183,19 +323,8 @@ func main() {
 			setupLog.Info("Closing discovery manager")
 			discoveryCancel()
 		})
-	runGroup.Add(
+	allocationGroup.Add(
 		func() error {
-			// Initial loading of the config file's scrape config
-			if cfg.PromConfig != nil && len(cfg.PromConfig.ScrapeConfigs) > 0 {
-				applyErr := targetDiscoverer.ApplyConfig(allocatorWatcher.EventSourceConfigMap, cfg.PromConfig.ScrapeConfigs)
-				if applyErr != nil {
-					setupLog.Error(err, "Unable to apply initial configuration")
-					return err
-				}
-			} else {
-				setupLog.Info("Prometheus config empty, skipping initial discovery configuration")
-			}
-
 			tErr := targetDiscoverer.Run()
 			setupLog.Info("Target discoverer exited")
 			return tErr
@@ -204,7 +333,7 @@ func main() {
 			setupLog.Info("Closing target discoverer")
 			targetDiscoverer.Close()
 		})
-	runGroup.Add(
+	allocationGroup.Add(
 		func() error {
 			watchErr := collectorWatcher.Watch(cfg.CollectorNamespace, cfg.CollectorSelector, allocator.SetCollectors)
 			setupLog.Info("Collector watcher exited")
@@ -255,6 +384,45 @@ func main() {
 				certWatcherCancel()
 			})
 	}
+	if snapshotSaver != nil {
+		allocationGroup.Add(
+			func() error {
+				saverErr := snapshotSaver.Run(ctx)
+				setupLog.Info("Allocation snapshot saver exited")
+				return saverErr
+			},
+			func(_ error) {
+				setupLog.Info("Closing allocation snapshot saver")
+				snapshotSaver.Close()
+			})
+	}
+	if recommender != nil {
+		allocationGroup.Add(
+			func() error {
+				recommenderErr := recommender.Run(ctx)
+				setupLog.Info("Replica recommender exited")
+				return recommenderErr
+			},
+			func(_ error) {
+				setupLog.Info("Closing replica recommender")
+				recommender.Close()
+			})
+	}
+	if elector != nil {
+		runGroup.Add(
+			func() error {
+				go func() {
+					_ = follower.Run(electionCtx)
+				}()
+				electionErr := elector.Run(electionCtx)
+				setupLog.Info("Leader election exited")
+				return electionErr
+			},
+			func(_ error) {
+				setupLog.Info("Closing leader election")
+				electionCancel()
+			})
+	}
 	meter := otel.GetMeterProvider().Meter("targetallocator")
 	eventsMetric, err := meter.Int64Counter("opentelemetry_allocator_events", metric.WithDescription("Number of events in the channel."))
 	if err != nil {
@@ -271,6 +439,9 @@ func main() {
 						setupLog.Error(err, "Unable to load configuration")
 						continue
 					}
+					if promWatcher, ok := event.Watcher.(*allocatorWatcher.PrometheusCRWatcher); ok {
+						setJobStrategies(allocator, cfg.JobAllocationStrategies, promWatcher.JobStrategies())
+					}
 					err = targetDiscoverer.ApplyConfig(event.Source, loadConfig.ScrapeConfigs)
 					if err != nil {
 						setupLog.Error(err, "Unable to apply configuration")
@@ -307,3 +478,46 @@ func main() {
 	}
 	setupLog.Info("Target allocator exited.")
 }
+
+// leaderIdentity returns the address the other Target Allocator replicas reach this one at.
+func leaderIdentity(cfg *config.Config) (string, error) {
+	if cfg.LeaderElection.Identity != "" {
+		return cfg.LeaderElection.Identity, nil
+	}
+	_, port, err := net.SplitHostPort(cfg.ListenAddr)
+	if err != nil {
+		return "", err
+	}
+	host, ok := os.LookupEnv("POD_IP")
+	if !ok {
+		if host, err = os.Hostname(); err != nil {
+			return "", err
+		}
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// jobStrategies converts the job allocation strategies of the config for the allocator.
+func jobStrategies(jobAllocationStrategies []config.JobAllocationStrategy) []allocation.JobStrategy {
+	result := make([]allocation.JobStrategy, 0, len(jobAllocationStrategies))
+	for _, js := range jobAllocationStrategies {
+		result = append(result, allocation.JobStrategy{JobName: js.JobName, Strategy: js.Strategy})
+	}
+	return result
+}
+
+// setJobStrategies sets the strategies of the jobs of the annotated Prometheus CRs, which take precedence over the
+// job strategies of the config. Annotations with an unregistered strategy are ignored.
+func setJobStrategies(allocator allocation.Allocator, configStrategies, annotatedStrategies []config.JobAllocationStrategy) {
+	var valid []config.JobAllocationStrategy
+	for _, js := range annotatedStrategies {
+		if !slices.Contains(allocation.GetRegisteredAllocatorNames(), js.Strategy) {
+			setupLog.Info("Ignoring the unregistered allocation strategy of a Prometheus CR", "strategy", js.Strategy, "jobs", js.JobName)
+			continue
+		}
+		valid = append(valid, js)
+	}
+	if err := allocator.SetJobStrategies(jobStrategies(slices.Concat(valid, configStrategies))); err != nil {
+		setupLog.Error(err, "Unable to set the allocation strategies of the jobs")
+	}
+}
@@ -4,8 +4,10 @@ go 1.25.0
 
 require (
 	github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusexporter v0.154.0
+	github.com/open-telemetry/opentelemetry-collector-contrib/processor/attributesprocessor v0.154.0
 	github.com/open-telemetry/opentelemetry-collector-contrib/processor/filterprocessor v0.154.0
 	github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourceprocessor v0.154.0
+	github.com/open-telemetry/opentelemetry-collector-contrib/processor/transformprocessor v0.154.0
 	github.com/open-telemetry/opentelemetry-collector-contrib/receiver/prometheusreceiver v0.154.0
 	github.com/prometheus-collector/shared v0.0.0-00010101000000-000000000000
 	go.opentelemetry.io/collector/component v1.60.0
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -21,12 +22,41 @@ import (
 var RESET = "\033[0m"
 var RED = "\033[31m"
 
+// report collects the findings for all the scrape jobs, written out with -report json or when running in the agent
+var report = &shared.ValidationReport{Valid: true, Findings: []shared.ValidationFinding{}}
+var reportFormat string
+var reportFile string
+
+func writeReport() {
+	// Write the report to a file so the health endpoint can surface it as metrics
+	if os.Getenv("CONFIG_VALIDATOR_RUNNING_IN_AGENT") == "true" {
+		if err := shared.WriteValidationReport(reportFile, report); err != nil {
+			log.Printf("prom-config-validator::Unable to write validation report: %v\n", err)
+		}
+	}
+
+	if reportFormat == "json" {
+		reportJson, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Printf("prom-config-validator::Unable to marshal validation report: %v\n", err)
+			return
+		}
+		fmt.Println(string(reportJson))
+	}
+}
+
 func logFatalError(message string) {
 	// Do not set env var if customer is running outside of agent to just validate config
 	if os.Getenv("CONFIG_VALIDATOR_RUNNING_IN_AGENT") == "true" {
 		setFatalErrorMessageAsEnvVar(message)
 	}
 
+	// An invalid report already has the error findings the message is made of
+	if report.Valid {
+		report.AddFinding(shared.ValidationFinding{Severity: shared.ValidationSeverityError, Message: strings.TrimSpace(message)})
+	}
+	writeReport()
+
 	// Always log the full message
 	log.Fatalf("%s%s%s", RED, message, RESET)
 }
@@ -58,179 +88,188 @@ func setFatalErrorMessageAsEnvVar(message string) {
 	file.Close()
 }
 
-func generateOtelConfig(promFilePath string, outputFilePath string, otelConfigTemplatePath string) error {
-	var otelConfig shared.OtelConfig
-
+// generateOtelConfig writes the collector config for the prometheus config and returns its contents
+func generateOtelConfig(promFilePath string, outputFilePath string, otelConfigTemplatePath string, externalLabelsPath string, labelLimitChangesPath string) ([]byte, error) {
 	otelConfigFileContents, err := ioutil.ReadFile(otelConfigTemplatePath)
 	if err != nil {
-		return err
-	}
-	err = yaml.Unmarshal([]byte(otelConfigFileContents), &otelConfig)
-	if err != nil {
-		return err
+		return nil, err
 	}
 
 	promConfigFileContents, err := ioutil.ReadFile(promFilePath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var prometheusConfig map[string]interface{}
 	err = yaml.Unmarshal([]byte(promConfigFileContents), &prometheusConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	scrapeConfigs := prometheusConfig["scrape_configs"]
-	if scrapeConfigs != nil {
-		var sc = scrapeConfigs.([]interface{})
-		for _, scrapeConfig := range sc {
-			scrapeConfig := scrapeConfig.(map[interface{}]interface{})
-			if scrapeConfig["relabel_configs"] != nil {
-				relabelConfigs := scrapeConfig["relabel_configs"].([]interface{})
-				for _, relabelConfig := range relabelConfigs {
-					relabelConfig := relabelConfig.(map[interface{}]interface{})
-					//replace $ with $$ for regex field
-					if relabelConfig["regex"] != nil {
-						// Adding this check here since regex can be boolean and the conversion will fail
-						if _, isString := relabelConfig["regex"].(string); isString {
-							regexString := relabelConfig["regex"].(string)
-							modifiedRegexString := strings.ReplaceAll(regexString, "$$", "$")
-							modifiedRegexString = strings.ReplaceAll(modifiedRegexString, "$", "$$")
-							// Doing the below since we dont want to substitute $ with $$ for env variables NODE_NAME and NODE_IP.
-							modifiedRegexString = strings.ReplaceAll(modifiedRegexString, "$$NODE_NAME", "${env:NODE_NAME}")
-							modifiedRegexString = strings.ReplaceAll(modifiedRegexString, "$$NODE_IP", "${env:NODE_IP}")
-							relabelConfig["regex"] = modifiedRegexString
-						}
-					}
-					//replace $ with $$ for replacement field
-					if relabelConfig["replacement"] != nil {
-						replacement := relabelConfig["replacement"].(string)
-						modifiedReplacementString := strings.ReplaceAll(replacement, "$$", "$")
-						modifiedReplacementString = strings.ReplaceAll(modifiedReplacementString, "$", "$$")
-						modifiedReplacementString = strings.ReplaceAll(modifiedReplacementString, "$$NODE_NAME", "${env:NODE_NAME}")
-						modifiedReplacementString = strings.ReplaceAll(modifiedReplacementString, "$$NODE_IP", "${env:NODE_IP}")
-						relabelConfig["replacement"] = modifiedReplacementString
-					}
-				}
-			}
+	// The collector rejects a config with error findings, so the config is rejected before generating the otel config
+	errorMessages := []string{}
+	for _, finding := range shared.ValidatePrometheusConfig(prometheusConfig) {
+		report.AddFinding(finding)
+		if finding.Severity == shared.ValidationSeverityError {
+			errorMessages = append(errorMessages, fmt.Sprintf("%s: %s", finding.Path, finding.Message))
+		}
+	}
+	if len(errorMessages) > 0 {
+		return nil, fmt.Errorf("invalid prometheus config:\n\t%s", strings.Join(errorMessages, "\n\t"))
+	}
+	if labelLimitChangesPath != "" {
+		labelLimitChanges, err := shared.ReadLabelLimitChanges(labelLimitChangesPath)
+		if err != nil {
+			log.Printf("prom-config-validator::Unable to read the label limit changes: %v\n", err)
+		}
+		for _, finding := range shared.LabelLimitChangeFindings(prometheusConfig, labelLimitChanges) {
+			report.AddFinding(finding)
+		}
+	}
 
-			if scrapeConfig["metric_relabel_configs"] != nil {
-				metricRelabelConfigs := scrapeConfig["metric_relabel_configs"].([]interface{})
-				for _, metricRelabelConfig := range metricRelabelConfigs {
-					metricRelabelConfig := metricRelabelConfig.(map[interface{}]interface{})
-					//replace $ with $$ for regex field
-					if metricRelabelConfig["regex"] != nil {
-						// Adding this check here since regex can be boolean and the conversion will fail
-						if _, isString := metricRelabelConfig["regex"].(string); isString {
-							regexString := metricRelabelConfig["regex"].(string)
-							modifiedRegexString := strings.ReplaceAll(regexString, "$$", "$")
-							modifiedRegexString = strings.ReplaceAll(modifiedRegexString, "$", "$$")
-							modifiedRegexString = strings.ReplaceAll(modifiedRegexString, "$$NODE_NAME", "${env:NODE_NAME}")
-							modifiedRegexString = strings.ReplaceAll(modifiedRegexString, "$$NODE_IP", "${env:NODE_IP}")
-							metricRelabelConfig["regex"] = modifiedRegexString
-						}
-					}
-
-					//replace $ with $$ for replacement field
-					if metricRelabelConfig["replacement"] != nil {
-						replacement := metricRelabelConfig["replacement"].(string)
-						modifiedReplacementString := strings.ReplaceAll(replacement, "$$", "$")
-						modifiedReplacementString = strings.ReplaceAll(modifiedReplacementString, "$", "$$")
-						modifiedReplacementString = strings.ReplaceAll(modifiedReplacementString, "$$NODE_NAME", "${env:NODE_NAME}")
-						modifiedReplacementString = strings.ReplaceAll(modifiedReplacementString, "$$NODE_IP", "${env:NODE_IP}")
-						metricRelabelConfig["replacement"] = modifiedReplacementString
-					}
-				}
-			}
+	otelConfig, err := shared.GenerateOtelConfig(prometheusConfig, otelConfigFileContents, os.Getenv("DEBUG_MODE_ENABLED") == "true", os.Getenv("CCP_METRICS_ENABLED") == "true")
+	if err != nil {
+		return nil, err
+	}
 
-			if scrapeConfig["static_configs"] != nil {
-				staticConfigs := scrapeConfig["static_configs"].([]interface{})
-				for _, staticConfig := range staticConfigs {
-					staticConfig := staticConfig.(map[interface{}]interface{})
-					if staticConfig["targets"] != nil {
-						targets := staticConfig["targets"].([]interface{})
-						for i, target := range targets {
-							if _, isString := target.(string); isString {
-								targetValue := target.(string)
-								modifiedtargetValue := strings.ReplaceAll(targetValue, "$$NODE_NAME", "$NODE_NAME")
-								modifiedtargetValue = strings.ReplaceAll(modifiedtargetValue, "$$NODE_IP", "$NODE_IP")
-								modifiedtargetValue = strings.ReplaceAll(modifiedtargetValue, "$NODE_NAME", "${env:NODE_NAME}")
-								modifiedtargetValue = strings.ReplaceAll(modifiedtargetValue, "$NODE_IP", "${env:NODE_IP}")
-								staticConfig["targets"].([]interface{})[i] = modifiedtargetValue
-							}
-						}
-					}
-				}
-			}
+	if externalLabelsPath != "" {
+		// The labels are validated by the settings parser, a bad file should not reject the scrape configs
+		externalLabels, err := shared.ReadExternalLabels(externalLabelsPath)
+		if err == nil {
+			err = shared.ApplyExternalLabels(otelConfig, externalLabels)
+		}
+		if err != nil {
+			report.AddFinding(shared.ValidationFinding{Severity: shared.ValidationSeverityWarning, Message: fmt.Sprintf("External labels were not added: %v", err)})
+			log.Printf("prom-config-validator::External labels were not added: %v\n", err)
 		}
 	}
 
-	// Need this here even though it is present in the receiver's config validate method since we only do the $ manipulation for regex and replacement fields
-	// in scrape configs sections and the load method which is called before the validate method fails to unmarshal due to single $.
-	// Either approach will fail but the receiver's config load wont return the right error message
-	unsupportedFeatures := make([]string, 0, 4)
+	if shared.IsGlobalSettingsConfigured(prometheusConfig) {
+		setEnvVarString := fmt.Sprintf("AZMON_GLOBAL_SETTINGS_CONFIGURED=true\n")
+		file, err := os.OpenFile("/opt/microsoft/prom_config_validator_env_var", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Println("prom-config-validator::Unable to open file - prom_config_validator_env_var")
+		}
+		_, err = file.Write([]byte(setEnvVarString))
+		if err != nil {
+			log.Println("prom-config-validator::Unable to write to the file prom_config_validator_env_var")
+		}
+		file.Close()
+		if err != nil {
+			log.Println("prom-config-validator::Unable to close file prom_config_validator_env_var", err)
+		} else {
+			log.Printf("prom-config-validator::Successfully set env variables for global config in file prom_config_validator_env_var\n")
+		}
+	}
 
-	if prometheusConfig["remote_write"] != nil {
-		unsupportedFeatures = append(unsupportedFeatures, "remote_write")
+	mergedConfig, err := yaml.Marshal(otelConfig)
+	if err != nil {
+		return nil, err
 	}
-	if prometheusConfig["remote_read"] != nil {
-		unsupportedFeatures = append(unsupportedFeatures, "remote_read")
+
+	if err := ioutil.WriteFile(outputFilePath, mergedConfig, 0644); err != nil {
+		return nil, err
 	}
-	if prometheusConfig["rule_files"] != nil {
-		unsupportedFeatures = append(unsupportedFeatures, "rule_files")
+	log.Printf("prom-config-validator::Successfully generated otel config\n")
+	return mergedConfig, nil
+}
+
+// readCollectorProcessors reads the processors of the collector-processors setting. The processors are validated by
+// the settings parser, a bad file is reported and the default pipeline is used.
+func readCollectorProcessors(processorsPath string) *shared.CollectorProcessors {
+	if processorsPath == "" {
+		return nil
 	}
-	if prometheusConfig["alerting"] != nil {
-		unsupportedFeatures = append(unsupportedFeatures, "alerting")
+	processors, err := shared.ReadCollectorProcessors(processorsPath)
+	if err != nil {
+		rejectCollectorProcessors(err)
+		return nil
 	}
-	if len(unsupportedFeatures) != 0 {
-		return fmt.Errorf("unsupported features:\n\t%s", strings.Join(unsupportedFeatures, "\n\t"))
+	return processors
+}
+
+// writeOtelConfigWithProcessors writes the generated collector config with the processors added to its pipeline
+func writeOtelConfigWithProcessors(otelConfigYaml []byte, processors *shared.CollectorProcessors, outputFilePath string) error {
+	var otelConfig shared.OtelConfig
+	if err := yaml.Unmarshal(otelConfigYaml, &otelConfig); err != nil {
+		return err
+	}
+	if err := shared.ApplyCollectorProcessors(&otelConfig, processors); err != nil {
+		return err
+	}
+	configWithProcessors, err := yaml.Marshal(otelConfig)
+	if err != nil {
+		return err
 	}
+	return ioutil.WriteFile(outputFilePath, configWithProcessors, 0644)
+}
 
-	globalSettingsFromMergedOtelConfig := prometheusConfig["global"]
+// rejectCollectorProcessors reports the error of the collector processors, which are left out of the pipeline
+func rejectCollectorProcessors(err error) {
+	message := fmt.Sprintf("prom-config-validator::The collector processors were rejected, using the default pipeline: %v", err)
+	report.AddFinding(shared.ValidationFinding{Severity: shared.ValidationSeverityWarning, Message: message})
+	log.Printf("%s%s%s\n", RED, message, RESET)
 
-	if globalSettingsFromMergedOtelConfig != nil {
-		globalSettings := globalSettingsFromMergedOtelConfig.(map[interface{}]interface{})
-		scrapeInterval := globalSettings["scrape_interval"]
-		if (len(globalSettings) > 1) || (len(globalSettings) == 1 && scrapeInterval != "15s") {
-			setEnvVarString := fmt.Sprintf("AZMON_GLOBAL_SETTINGS_CONFIGURED=true\n")
-			file, err := os.OpenFile("/opt/microsoft/prom_config_validator_env_var", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-			if err != nil {
-				log.Println("prom-config-validator::Unable to open file - prom_config_validator_env_var")
-			}
-			_, err = file.Write([]byte(setEnvVarString))
-			if err != nil {
-				log.Println("prom-config-validator::Unable to write to the file prom_config_validator_env_var")
-			}
-			file.Close()
-			if err != nil {
-				log.Println("prom-config-validator::Unable to close file prom_config_validator_env_var", err)
-			} else {
-				log.Printf("prom-config-validator::Successfully set env variables for global config in file prom_config_validator_env_var\n")
-			}
+	if os.Getenv("CONFIG_VALIDATOR_RUNNING_IN_AGENT") == "true" {
+		file, err := os.OpenFile("/opt/microsoft/prom_config_validator_env_var", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Println("prom-config-validator::Unable to open file - prom_config_validator_env_var")
+			return
+		}
+		defer file.Close()
+		if _, err := file.WriteString("AZMON_INVALID_COLLECTOR_PROCESSORS=true\n"); err != nil {
+			log.Println("prom-config-validator::Unable to write to the file prom_config_validator_env_var")
 		}
 	}
+}
 
-	otelConfig.Receivers.Prometheus.Config = prometheusConfig
+// validateCollectorConfig loads the collector config with the collector factories, the way the collector does at
+// startup, and validates it
+func validateCollectorConfig(configFilePath string) error {
+	flags := new(flag.FlagSet)
+	//parserProvider.Flags(flags)
+	configFlagEx := new(stringArrayValue)
+	flags.Var(configFlagEx, "config", "Locations to the config file(s), note that only a"+
+		" single location can be set per flag entry e.g. `-config=file:/path/to/first --config=file:path/to/second`.")
+	configFlag := fmt.Sprintf("--config=%s", configFilePath)
+
+	err := flags.Parse([]string{
+		configFlag,
+	})
+	if err != nil {
+		return fmt.Errorf("prom-config-validator::Error parsing flags - %v\n", err)
+	}
 
-	if os.Getenv("DEBUG_MODE_ENABLED") == "true" {
-		otelConfig.Service.Pipelines.Metrics.Exporters = []interface{}{"otlp_grpc", "prometheus"}
-		if os.Getenv("CCP_METRICS_ENABLED") != "true" {
-			otelConfig.Service.Pipelines.MetricsTelemetry.Receivers = []interface{}{"prometheus"}
-			otelConfig.Service.Pipelines.MetricsTelemetry.Exporters = []interface{}{"prometheus/telemetry"}
-			otelConfig.Service.Pipelines.MetricsTelemetry.Processors = []interface{}{"filter/telemetry"}
-		}
+	factories, err := components()
+	if err != nil {
+		return fmt.Errorf("prom-config-validator::Failed to build components: %v\n", err)
 	}
 
-	mergedConfig, err := yaml.Marshal(otelConfig)
+	fmp := fileprovider.NewFactory()
+	envp := envprovider.NewFactory()
+	providers := []confmap.ProviderFactory{fmp, envp}
+	cp, err := otelcol.NewConfigProvider(
+		otelcol.ConfigProviderSettings{
+			ResolverSettings: confmap.ResolverSettings{
+				URIs:              []string{fmt.Sprintf("file:%s", configFilePath)},
+				ProviderFactories: providers,
+			},
+		},
+	)
 	if err != nil {
-		return err
+		return fmt.Errorf("prom-config-validator::Cannot load configuration's parser: %w\n", err)
 	}
 
-	if err := ioutil.WriteFile(outputFilePath, mergedConfig, 0644); err != nil {
-		return err
+	log.Printf("prom-config-validator::Loading configuration...\n")
+	cfg, err := cp.Get(context.Background(), factories)
+	if err != nil {
+		return fmt.Errorf("prom-config-validator::Cannot load configuration: %v", err)
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return fmt.Errorf("prom-config-validator::Invalid configuration: %w\n", err)
 	}
-	log.Printf("prom-config-validator::Successfully generated otel config\n")
 	return nil
 }
 
@@ -252,7 +291,16 @@ func main() {
 	configFilePtr := flag.String("config", "", "Config file to validate")
 	outFilePtr := flag.String("output", "", "Output file path for writing collector config")
 	otelTemplatePathPtr := flag.String("otelTemplate", "", "OTel Collector config template file path")
+	externalLabelsPathPtr := flag.String("externalLabels", "", "Optional file with the external labels to add to every series")
+	processorsPathPtr := flag.String("processors", "", "Optional file with the processors to add to the collector pipeline")
+	labelLimitChangesPathPtr := flag.String("labelLimitChanges", "", "Optional file with the label limits the settings parser set on the custom scrape jobs")
+	flag.StringVar(&reportFormat, "report", "", "Print a validation report with the findings for every scrape job. Supported formats: json")
+	flag.StringVar(&reportFile, "reportFile", shared.PromConfigValidatorReportPath, "File the validation report is written to when running in the agent")
 	flag.Parse()
+	if reportFormat != "" && reportFormat != "json" {
+		logFatalError(fmt.Sprintf("prom-config-validator::Unsupported report format %s\n", reportFormat))
+		os.Exit(1)
+	}
 	promFilePath := *configFilePtr
 	otelConfigTemplatePath := *otelTemplatePathPtr
 	if otelConfigTemplatePath == "" {
@@ -267,65 +315,45 @@ func main() {
 			outputFilePath = "merged-otel-config.yaml"
 		}
 
-		err := generateOtelConfig(promFilePath, outputFilePath, otelConfigTemplatePath)
+		otelConfigYaml, err := generateOtelConfig(promFilePath, outputFilePath, otelConfigTemplatePath, *externalLabelsPathPtr, *labelLimitChangesPathPtr)
 		if err != nil {
 			logFatalError(fmt.Sprintf("Generating otel config failed: %v\n", err))
 			os.Exit(1)
 		}
 
-		flags := new(flag.FlagSet)
-		//parserProvider.Flags(flags)
-		configFlagEx := new(stringArrayValue)
-		flags.Var(configFlagEx, "config", "Locations to the config file(s), note that only a"+
-			" single location can be set per flag entry e.g. `-config=file:/path/to/first --config=file:path/to/second`.")
-		configFlag := fmt.Sprintf("--config=%s", outputFilePath)
-
-		err = flags.Parse([]string{
-			configFlag,
-		})
-		if err != nil {
-			logFatalError(fmt.Sprintf("prom-config-validator::Error parsing flags - %v\n", err))
-			os.Exit(1)
-		}
-
-		factories, err := components()
-		if err != nil {
-			logFatalError(fmt.Sprintf("prom-config-validator::Failed to build components: %v\n", err))
-			os.Exit(1)
-		}
-
-		fmp := fileprovider.NewFactory()
-		envp := envprovider.NewFactory()
-		providers := []confmap.ProviderFactory{fmp, envp}
-		cp, err := otelcol.NewConfigProvider(
-			otelcol.ConfigProviderSettings{
-				ResolverSettings: confmap.ResolverSettings{
-					URIs:              []string{fmt.Sprintf("file:%s", outputFilePath)},
-					ProviderFactories: providers,
-				},
-			},
-		)
-		if err != nil {
-			logFatalError(fmt.Errorf("prom-config-validator::Cannot load configuration's parser: %w\n", err).Error())
-			os.Exit(1)
+		// The collector processors from the settings are validated on their own so invalid ones only fall back to the
+		// default pipeline instead of rejecting the scrape configs
+		var processorsErr error
+		if processors := readCollectorProcessors(*processorsPathPtr); processors != nil {
+			processorsErr = writeOtelConfigWithProcessors(otelConfigYaml, processors, outputFilePath)
+			if processorsErr == nil {
+				processorsErr = validateCollectorConfig(outputFilePath)
+			}
+			if processorsErr == nil {
+				log.Printf("prom-config-validator::Added %d processors to the collector pipeline\n", len(processors.Processors))
+				log.Printf("prom-config-validator::Successfully loaded and validated prometheus config\n")
+				writeReport()
+				os.Exit(0)
+			}
+			if err := ioutil.WriteFile(outputFilePath, otelConfigYaml, 0644); err != nil {
+				logFatalError(fmt.Sprintf("Generating otel config failed: %v\n", err))
+				os.Exit(1)
+			}
 		}
 
-		log.Printf("prom-config-validator::Loading configuration...\n")
-		cfg, err := cp.Get(context.Background(), factories)
-		if err != nil {
-			logFatalError(fmt.Sprintf("prom-config-validator::Cannot load configuration: %v", err))
+		if err := validateCollectorConfig(outputFilePath); err != nil {
+			logFatalError(err.Error())
 			os.Exit(1)
 		}
-
-		err = cfg.Validate()
-		if err != nil {
-			logFatalError(fmt.Errorf("prom-config-validator::Invalid configuration: %w\n", err).Error())
-			os.Exit(1)
+		// Only blame the processors when the config is valid without them
+		if processorsErr != nil {
+			rejectCollectorProcessors(processorsErr)
 		}
 	} else {
 		logFatalError("prom-config-validator::Please provide a config file using the --config flag to validate\n")
 		os.Exit(1)
 	}
 	log.Printf("prom-config-validator::Successfully loaded and validated prometheus config\n")
+	writeReport()
 	os.Exit(0)
 }
@@ -25,6 +25,12 @@ type Config struct {
 	CollectorID             string                `mapstructure:"collector_id"`
 	HTTPSDConfig            *PromHTTPSDConfig     `mapstructure:"http_sd_config"`
 	HTTPScrapeConfig        *PromHTTPClientConfig `mapstructure:"http_scrape_config"`
+	// Watch streams the scrape configs and targets from the target allocator as soon as they change, instead of
+	// polling for them. The jobs fall back to polling when the target allocator cannot be watched.
+	Watch bool `mapstructure:"watch"`
+	// ReportLoad sends the series count, scrape failures and memory usage of the collector to the target allocator
+	// on every sync, to balance the targets by series count and drain saturated collectors.
+	ReportLoad bool `mapstructure:"report_load"`
 }
 
 // PromHTTPSDConfig is a redeclaration of promHTTP.SDConfig because we need custom unmarshaling
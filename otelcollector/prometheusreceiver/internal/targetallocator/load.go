@@ -0,0 +1,161 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package targetallocator // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/prometheusreceiver/internal/targetallocator"
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/scrape"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// samplesPostRelabelingMetric is the report series holding the number of series a scrape produced.
+const samplesPostRelabelingMetric = "scrape_samples_post_metric_relabeling"
+
+// heartbeat is the load a collector reports to the target allocator on every sync.
+type heartbeat struct {
+	SeriesCount      int64   `json:"series_count"`
+	Targets          int     `json:"targets"`
+	ScrapeFailures   int     `json:"scrape_failures"`
+	MemoryUsageRatio float64 `json:"memory_usage_ratio"`
+}
+
+// targetWeight is the series count of a target, used by the target allocator to balance collectors.
+type targetWeight struct {
+	JobName   string  `json:"job"`
+	TargetURL string  `json:"target"`
+	Weight    float64 `json:"weight"`
+}
+
+// loadTracker records the series count of the last scrape of every target.
+type loadTracker struct {
+	next storage.AppendableV2
+
+	mtx    sync.Mutex
+	series map[*scrape.Target]int64
+}
+
+func newLoadTracker() *loadTracker {
+	return &loadTracker{series: make(map[*scrape.Target]int64)}
+}
+
+func (l *loadTracker) AppenderV2(ctx context.Context) storage.AppenderV2 {
+	app := &loadAppender{AppenderV2: l.next.AppenderV2(ctx), tracker: l}
+	app.target, _ = scrape.TargetFromContext(ctx)
+	return app
+}
+
+func (l *loadTracker) record(t *scrape.Target, series int64) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.series[t] = series
+}
+
+// collect returns the heartbeat and the target weights for the active targets, forgetting the targets which are not
+// active anymore.
+func (l *loadTracker) collect(active map[string][]*scrape.Target) (heartbeat, []targetWeight) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	var hb heartbeat
+	var weights []targetWeight
+	seen := make(map[*scrape.Target]struct{})
+	lb := labels.NewBuilder(labels.EmptyLabels())
+	for _, targets := range active {
+		for _, t := range targets {
+			seen[t] = struct{}{}
+			hb.Targets++
+			if t.Health() == scrape.HealthBad {
+				hb.ScrapeFailures++
+			}
+			series, ok := l.series[t]
+			if !ok {
+				continue
+			}
+			hb.SeriesCount += series
+			discovered := t.DiscoveredLabels(lb)
+			weights = append(weights, targetWeight{
+				JobName:   discovered.Get(model.JobLabel),
+				TargetURL: discovered.Get(model.AddressLabel),
+				Weight:    float64(series),
+			})
+		}
+	}
+	for t := range l.series {
+		if _, ok := seen[t]; !ok {
+			delete(l.series, t)
+		}
+	}
+	hb.MemoryUsageRatio = memoryUsageRatio()
+	return hb, weights
+}
+
+// loadAppender passes all the samples through, recording the series count report sample of the target.
+type loadAppender struct {
+	storage.AppenderV2
+	tracker *loadTracker
+	target  *scrape.Target
+}
+
+func (a *loadAppender) Append(ref storage.SeriesRef, ls labels.Labels, st, t int64, v float64, h *histogram.Histogram, fh *histogram.FloatHistogram, opts storage.AppendV2Options) (storage.SeriesRef, error) {
+	if a.target != nil && h == nil && fh == nil && ls.Get(model.MetricNameLabel) == samplesPostRelabelingMetric {
+		a.tracker.record(a.target, int64(v))
+	}
+	return a.AppenderV2.Append(ref, ls, st, t, v, h, fh, opts)
+}
+
+// memoryUsageRatio returns the memory obtained from the OS relative to the soft memory limit, or 0 without a limit.
+func memoryUsageRatio() float64 {
+	limit := debug.SetMemoryLimit(-1)
+	if limit <= 0 || limit == math.MaxInt64 {
+		return 0
+	}
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return float64(ms.Sys-ms.HeapReleased) / float64(limit)
+}
+
+// reportLoad sends the heartbeat of the collector and the series count of its targets to the target allocator.
+func (m *Manager) reportLoad(httpClient *http.Client) error {
+	hb, weights := m.loadTracker.collect(m.scrapeManager.TargetsActive())
+	heartbeatURL := fmt.Sprintf("%s/collectors/%s/heartbeat", m.cfg.Endpoint, url.PathEscape(m.cfg.CollectorID))
+	if err := postJSON(httpClient, heartbeatURL, hb); err != nil {
+		return fmt.Errorf("failed to send heartbeat: %w", err)
+	}
+	if len(weights) == 0 {
+		return nil
+	}
+	if err := postJSON(httpClient, m.cfg.Endpoint+"/targets/weights", weights); err != nil {
+		return fmt.Errorf("failed to send target weights: %w", err)
+	}
+	return nil
+}
+
+func postJSON(httpClient *http.Client, postURL string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Post(postURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
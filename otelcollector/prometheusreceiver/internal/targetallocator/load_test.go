@@ -0,0 +1,108 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package targetallocator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/promslog"
+	promconfig "github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/scrape"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/util/teststorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/receiver/prometheusreceiver/internal/metadata"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/receiver/prometheusreceiver/internal/sharedpromconfig"
+)
+
+func newTestTarget(job, address string) *scrape.Target {
+	return scrape.NewTarget(
+		labels.FromStrings(model.JobLabel, job, model.AddressLabel, address),
+		&promconfig.ScrapeConfig{JobName: job},
+		model.LabelSet{model.AddressLabel: model.LabelValue(address)},
+		nil,
+	)
+}
+
+func TestManagerAppendable(t *testing.T) {
+	store := teststorage.New(t)
+
+	manager := NewManager(receivertest.NewNopSettings(metadata.Type), &Config{CollectorID: "test-collector"}, nil)
+	assert.Equal(t, storage.AppendableV2(store), manager.Appendable(store))
+
+	manager = NewManager(receivertest.NewNopSettings(metadata.Type), &Config{CollectorID: "test-collector", ReportLoad: true}, nil)
+	assert.Equal(t, manager.loadTracker, manager.Appendable(store))
+}
+
+func TestLoadTrackerCollect(t *testing.T) {
+	tracker := newLoadTracker()
+	tracker.next = teststorage.New(t)
+	scraped := newTestTarget("test-job", "10.0.0.1:8080")
+	notScraped := newTestTarget("test-job", "10.0.0.2:8080")
+	removed := newTestTarget("test-job", "10.0.0.3:8080")
+
+	for _, target := range []*scrape.Target{scraped, removed} {
+		app := tracker.AppenderV2(scrape.ContextWithTarget(t.Context(), target))
+		_, err := app.Append(0, labels.FromStrings(model.MetricNameLabel, "up"), 0, 1000, 1, nil, nil, storage.AOptions{})
+		require.NoError(t, err)
+		_, err = app.Append(0, labels.FromStrings(model.MetricNameLabel, samplesPostRelabelingMetric), 0, 1000, 42, nil, nil, storage.AOptions{})
+		require.NoError(t, err)
+		require.NoError(t, app.Commit())
+	}
+
+	hb, weights := tracker.collect(map[string][]*scrape.Target{"test-job": {scraped, notScraped}})
+	assert.Equal(t, int64(42), hb.SeriesCount)
+	assert.Equal(t, 2, hb.Targets)
+	assert.Equal(t, []targetWeight{{JobName: "test-job", TargetURL: "10.0.0.1:8080", Weight: 42}}, weights)
+	// targets which are not active anymore are forgotten
+	assert.Len(t, tracker.series, 1)
+}
+
+func TestManagerReportLoad(t *testing.T) {
+	var mtx sync.Mutex
+	requests := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		mtx.Lock()
+		requests[r.URL.EscapedPath()] = body
+		mtx.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := &Config{
+		Interval:    30 * time.Second,
+		CollectorID: "collector/0",
+		ReportLoad:  true,
+	}
+	cfg.Endpoint = server.URL
+	manager := NewManager(receivertest.NewNopSettings(metadata.Type), cfg, sharedpromconfig.NewConfig(&promconfig.Config{}))
+	scrapeManager, err := scrape.NewManager(&scrape.Options{}, promslog.NewNopLogger(), nil, nil, manager.Appendable(teststorage.New(t)), prometheus.NewRegistry())
+	require.NoError(t, err)
+	defer scrapeManager.Stop()
+	manager.scrapeManager = scrapeManager
+
+	require.NoError(t, manager.reportLoad(server.Client()))
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Contains(t, requests, "/collectors/collector%2F0/heartbeat")
+	var hb heartbeat
+	require.NoError(t, json.Unmarshal(requests["/collectors/collector%2F0/heartbeat"], &hb))
+	assert.Zero(t, hb.SeriesCount)
+	assert.Zero(t, hb.Targets)
+	// no weights are sent without scraped targets
+	assert.NotContains(t, requests, "/targets/weights")
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/prometheus/prometheus/discovery"
 	promHTTP "github.com/prometheus/prometheus/discovery/http"
 	"github.com/prometheus/prometheus/scrape"
+	"github.com/prometheus/prometheus/storage"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/receiver"
 	"go.uber.org/zap"
@@ -43,6 +44,15 @@ type Manager struct {
 	scrapeManager        *scrape.Manager
 	discoveryManager     *discovery.Manager
 	wg                   sync.WaitGroup
+	// applyMtx serializes applying the scrape configs from polling and watching the target allocator.
+	applyMtx sync.Mutex
+	// watching is set while the scrape configs and targets are streamed from the target allocator.
+	watching atomic.Bool
+	// watchApplied is set when the scrape configs were applied from the watch since the last sync.
+	watchApplied   bool
+	watchedTargets *watchedTargets
+	// loadTracker records the series count of the targets when the load is reported to the target allocator.
+	loadTracker *loadTracker
 
 	// configUpdateCount tracks how many times the config has changed, for
 	// testing.
@@ -52,14 +62,29 @@ type Manager struct {
 }
 
 func NewManager(set receiver.Settings, cfg *Config, promCfg *sharedpromconfig.Config) *Manager {
-	return &Manager{
+	m := &Manager{
 		shutdown:          make(chan struct{}),
 		settings:          set,
 		cfg:               cfg,
 		promCfg:           promCfg,
 		configUpdateCount: &atomic.Int64{},
 		configUpdated:     make(chan struct{}, 10),
+		watchedTargets:    newWatchedTargets(),
 	}
+	if cfg != nil && cfg.ReportLoad {
+		m.loadTracker = newLoadTracker()
+	}
+	return m
+}
+
+// Appendable wraps the appendable of the scrape manager to record the series count of the targets, when their load
+// is reported to the target allocator.
+func (m *Manager) Appendable(next storage.AppendableV2) storage.AppendableV2 {
+	if m.loadTracker == nil {
+		return next
+	}
+	m.loadTracker.next = next
+	return m.loadTracker
 }
 
 func (m *Manager) Start(ctx context.Context, host component.Host, sm *scrape.Manager, dm *discovery.Manager) error {
@@ -98,6 +123,16 @@ func (m *Manager) Start(ctx context.Context, host component.Host, sm *scrape.Man
 	if err != nil {
 		return err
 	}
+	if m.cfg.Watch {
+		watchCtx, cancelWatch := context.WithCancel(context.Background())
+		m.wg.Go(func() {
+			<-m.shutdown
+			cancelWatch()
+		})
+		m.wg.Go(func() {
+			m.runWatch(watchCtx, httpClient)
+		})
+	}
 	m.wg.Go(func() {
 		targetAllocatorIntervalTicker := time.NewTicker(m.cfg.Interval)
 		for {
@@ -109,6 +144,11 @@ func (m *Manager) Start(ctx context.Context, host component.Host, sm *scrape.Man
 					continue
 				}
 				savedHash = hash
+				if m.loadTracker != nil {
+					if newErr = m.reportLoad(httpClient); newErr != nil {
+						m.settings.Logger.Warn("Failed to report load to the target allocator", zap.Error(newErr))
+					}
+				}
 			case <-m.shutdown:
 				targetAllocatorIntervalTicker.Stop()
 				m.settings.Logger.Info("Stopping target allocator")
@@ -126,7 +166,16 @@ func (m *Manager) Shutdown() {
 
 // sync request jobs from targetAllocator and update underlying receiver, if the response does not match the provided compareHash.
 // baseDiscoveryCfg can be used to provide additional ScrapeConfigs which will be added to the retrieved jobs.
+// Nothing is synced while the target allocator is watched, and the jobs are always updated after the watch stops.
 func (m *Manager) sync(compareHash uint64, httpClient *http.Client) (uint64, error) {
+	m.applyMtx.Lock()
+	defer m.applyMtx.Unlock()
+	if m.watching.Load() {
+		return 0, nil
+	}
+	if m.watchApplied {
+		compareHash = 0
+	}
 	m.settings.Logger.Debug("Syncing target allocator jobs")
 	scrapeConfigsResponse, err := getScrapeConfigsResponse(httpClient, m.cfg.Endpoint)
 	if err != nil {
@@ -144,6 +193,42 @@ func (m *Manager) sync(compareHash uint64, httpClient *http.Client) (uint64, err
 		return hash, nil
 	}
 
+	err = m.applyScrapeConfigs(scrapeConfigsResponse, m.httpSDConfigs)
+	if err != nil {
+		return 0, err
+	}
+	m.watchApplied = false
+	return hash, nil
+}
+
+// httpSDConfigs returns the HTTP SD config polling the targets of a job from the target allocator.
+func (m *Manager) httpSDConfigs(jobName string) (discovery.Configs, error) {
+	var httpSD promHTTP.SDConfig
+	if m.cfg.HTTPSDConfig == nil {
+		httpSD = promHTTP.SDConfig{
+			RefreshInterval: model.Duration(30 * time.Second),
+		}
+	} else {
+		httpSD = promHTTP.SDConfig(*m.cfg.HTTPSDConfig)
+	}
+	escapedJob := url.QueryEscape(jobName)
+	httpSD.URL = fmt.Sprintf("%s/jobs/%s/targets?collector_id=%s", m.cfg.Endpoint, escapedJob, m.cfg.CollectorID)
+
+	err := configureSDHTTPClientConfigFromTA(&httpSD, m.cfg)
+	if err != nil {
+		m.settings.Logger.Error("Failed to configure http client config", zap.Error(err))
+		return nil, err
+	}
+
+	httpSD.HTTPClientConfig.FollowRedirects = false
+	return discovery.Configs{
+		&httpSD,
+	}, nil
+}
+
+// applyScrapeConfigs updates the underlying receiver with the jobs of the target allocator, discovering their targets
+// with the service discovery configs returned by sdConfigs.
+func (m *Manager) applyScrapeConfigs(scrapeConfigsResponse map[string]*promconfig.ScrapeConfig, sdConfigs func(jobName string) (discovery.Configs, error)) error {
 	// Copy initial scrape configurations
 	initialConfig := make([]*promconfig.ScrapeConfig, len(m.initialScrapeConfigs))
 	copy(initialConfig, m.initialScrapeConfigs)
@@ -153,27 +238,11 @@ func (m *Manager) sync(compareHash uint64, httpClient *http.Client) (uint64, err
 	globalCfg := m.promCfg.Get().GlobalConfig
 
 	for jobName, scrapeConfig := range scrapeConfigsResponse {
-		var httpSD promHTTP.SDConfig
-		if m.cfg.HTTPSDConfig == nil {
-			httpSD = promHTTP.SDConfig{
-				RefreshInterval: model.Duration(30 * time.Second),
-			}
-		} else {
-			httpSD = promHTTP.SDConfig(*m.cfg.HTTPSDConfig)
-		}
-		escapedJob := url.QueryEscape(jobName)
-		httpSD.URL = fmt.Sprintf("%s/jobs/%s/targets?collector_id=%s", m.cfg.Endpoint, escapedJob, m.cfg.CollectorID)
-
-		err = configureSDHTTPClientConfigFromTA(&httpSD, m.cfg)
+		sdConfig, err := sdConfigs(jobName)
 		if err != nil {
-			m.settings.Logger.Error("Failed to configure http client config", zap.Error(err))
-			return 0, err
-		}
-
-		httpSD.HTTPClientConfig.FollowRedirects = false
-		scrapeConfig.ServiceDiscoveryConfigs = discovery.Configs{
-			&httpSD,
+			return err
 		}
+		scrapeConfig.ServiceDiscoveryConfigs = sdConfig
 
 		if m.cfg.HTTPScrapeConfig != nil {
 			scrapeConfig.HTTPClientConfig = commonconfig.HTTPClientConfig(*m.cfg.HTTPScrapeConfig)
@@ -187,7 +256,7 @@ func (m *Manager) sync(compareHash uint64, httpClient *http.Client) (uint64, err
 		err = scrapeConfig.Validate(globalCfg)
 		if err != nil {
 			m.settings.Logger.Error("Failed to validate the scrape configuration", zap.Error(err))
-			return 0, err
+			return err
 		}
 
 		newScrapeConfigs = append(newScrapeConfigs, scrapeConfig)
@@ -198,10 +267,10 @@ func (m *Manager) sync(compareHash uint64, httpClient *http.Client) (uint64, err
 	})
 
 	cfgSnapshot := m.promCfg.Get()
-	err = m.applyCfg(&cfgSnapshot)
+	err := m.applyCfg(&cfgSnapshot)
 	if err != nil {
 		m.settings.Logger.Error("Failed to apply new scrape configuration", zap.Error(err))
-		return 0, err
+		return err
 	}
 
 	if m.configUpdateCount != nil {
@@ -211,7 +280,7 @@ func (m *Manager) sync(compareHash uint64, httpClient *http.Client) (uint64, err
 		default:
 		}
 	}
-	return hash, nil
+	return nil
 }
 
 func (m *Manager) applyCfg(cfg *promconfig.Config) error {
@@ -249,10 +318,13 @@ func getScrapeConfigsResponse(httpClient *http.Client, baseURL string) (map[stri
 	if err != nil {
 		return nil, err
 	}
+	return parseScrapeConfigs(body)
+}
 
+func parseScrapeConfigs(body []byte) (map[string]*promconfig.ScrapeConfig, error) {
 	jobToScrapeConfig := map[string]*promconfig.ScrapeConfig{}
 	envReplacedBody := instantiateShard(body, os.LookupEnv)
-	err = yaml.Unmarshal(envReplacedBody, &jobToScrapeConfig)
+	err := yaml.Unmarshal(envReplacedBody, &jobToScrapeConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,266 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package targetallocator // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/prometheusreceiver/internal/targetallocator"
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"go.uber.org/zap"
+)
+
+func init() {
+	discovery.RegisterConfig(&watchSDConfig{})
+}
+
+// watchEvent is pushed by the target allocator whenever the scrape configs or the targets of the collector change.
+// A full event replaces all the targets, the other ones only hold the targets added and removed.
+type watchEvent struct {
+	Full          bool                      `json:"full"`
+	ScrapeConfigs json.RawMessage           `json:"scrape_configs"`
+	Jobs          map[string]watchJobChange `json:"jobs"`
+}
+
+// watchJobChange holds the targets of a job added and removed, keyed by target hash.
+type watchJobChange struct {
+	Added   map[string]watchTarget `json:"added"`
+	Removed []string               `json:"removed"`
+}
+
+type watchTarget struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// watchedTargets holds the targets of every job streamed from the target allocator.
+type watchedTargets struct {
+	mtx     sync.Mutex
+	jobs    map[string]map[string]watchTarget
+	updated map[string]chan struct{}
+}
+
+func newWatchedTargets() *watchedTargets {
+	return &watchedTargets{
+		jobs:    make(map[string]map[string]watchTarget),
+		updated: make(map[string]chan struct{}),
+	}
+}
+
+// apply updates the targets with an event and wakes up the discoverers of the jobs which changed.
+func (w *watchedTargets) apply(event *watchEvent) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	changed := make(map[string]struct{}, len(event.Jobs))
+	if event.Full {
+		for jobName := range w.jobs {
+			changed[jobName] = struct{}{}
+		}
+		w.jobs = make(map[string]map[string]watchTarget)
+	}
+	for jobName, change := range event.Jobs {
+		changed[jobName] = struct{}{}
+		targets, ok := w.jobs[jobName]
+		if !ok {
+			targets = make(map[string]watchTarget)
+			w.jobs[jobName] = targets
+		}
+		for _, hash := range change.Removed {
+			delete(targets, hash)
+		}
+		for hash, t := range change.Added {
+			targets[hash] = t
+		}
+		if len(targets) == 0 {
+			delete(w.jobs, jobName)
+		}
+	}
+	for jobName := range changed {
+		if ch, ok := w.updated[jobName]; ok {
+			close(ch)
+			delete(w.updated, jobName)
+		}
+	}
+}
+
+// group returns the targets of a job as a target group, and a channel closed when they change.
+func (w *watchedTargets) group(jobName string) (*targetgroup.Group, <-chan struct{}) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	hashes := make([]string, 0, len(w.jobs[jobName]))
+	for hash := range w.jobs[jobName] {
+		hashes = append(hashes, hash)
+	}
+	slices.Sort(hashes)
+	group := &targetgroup.Group{Source: "target_allocator/" + jobName}
+	for _, hash := range hashes {
+		t := w.jobs[jobName][hash]
+		for _, address := range t.Targets {
+			labelSet := make(model.LabelSet, len(t.Labels)+1)
+			for name, value := range t.Labels {
+				labelSet[model.LabelName(name)] = model.LabelValue(value)
+			}
+			labelSet[model.AddressLabel] = model.LabelValue(address)
+			group.Targets = append(group.Targets, labelSet)
+		}
+	}
+
+	ch, ok := w.updated[jobName]
+	if !ok {
+		ch = make(chan struct{})
+		w.updated[jobName] = ch
+	}
+	return group, ch
+}
+
+// watchSDConfig discovers the targets of a job streamed from the target allocator. It is set by the Manager in
+// place of the HTTP SD config of the jobs while it watches the target allocator.
+type watchSDConfig struct {
+	JobName string `yaml:"job_name"`
+	targets *watchedTargets
+}
+
+func (*watchSDConfig) Name() string {
+	return "target_allocator_watch"
+}
+
+func (c *watchSDConfig) NewDiscoverer(discovery.DiscovererOptions) (discovery.Discoverer, error) {
+	if c.targets == nil {
+		return nil, errors.New("target allocator watch discovery can only be set by the target allocator")
+	}
+	return &watchDiscoverer{jobName: c.JobName, targets: c.targets}, nil
+}
+
+func (*watchSDConfig) NewDiscovererMetrics(prometheus.Registerer, discovery.RefreshMetricsInstantiator) discovery.DiscovererMetrics {
+	return &discovery.NoopDiscovererMetrics{}
+}
+
+type watchDiscoverer struct {
+	jobName string
+	targets *watchedTargets
+}
+
+func (d *watchDiscoverer) Run(ctx context.Context, up chan<- []*targetgroup.Group) {
+	for {
+		group, updated := d.targets.group(d.jobName)
+		select {
+		case up <- []*targetgroup.Group{group}:
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case <-updated:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// watch streams the scrape configs and targets from the target allocator until the stream fails or ctx is canceled.
+// The jobs discover their targets from the stream while it is up.
+func (m *Manager) watch(ctx context.Context, httpClient *http.Client) error {
+	watchURL := fmt.Sprintf("%s/watch?collector_id=%s", m.cfg.Endpoint, url.QueryEscape(m.cfg.CollectorID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, watchURL, http.NoBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	defer m.stopWatching()
+
+	reader := bufio.NewReader(resp.Body)
+	var data []byte
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return errors.New("watch closed by the target allocator")
+			}
+			return err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		switch {
+		case len(line) == 0:
+			if data == nil {
+				continue
+			}
+			if err := m.handleWatchEvent(data); err != nil {
+				return err
+			}
+			data = nil
+		case bytes.HasPrefix(line, []byte("data:")):
+			data = append(data, bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" "))...)
+		}
+	}
+}
+
+func (m *Manager) handleWatchEvent(data []byte) error {
+	var event watchEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return fmt.Errorf("failed to decode watch event: %w", err)
+	}
+	m.watchedTargets.apply(&event)
+	if len(event.ScrapeConfigs) == 0 {
+		return nil
+	}
+
+	scrapeConfigs, err := parseScrapeConfigs(event.ScrapeConfigs)
+	if err != nil {
+		return fmt.Errorf("failed to decode scrape configs: %w", err)
+	}
+	m.applyMtx.Lock()
+	defer m.applyMtx.Unlock()
+	m.watching.Store(true)
+	m.watchApplied = true
+	m.settings.Logger.Debug("Applying scrape configs from the target allocator watch")
+	return m.applyScrapeConfigs(scrapeConfigs, func(jobName string) (discovery.Configs, error) {
+		return discovery.Configs{&watchSDConfig{JobName: jobName, targets: m.watchedTargets}}, nil
+	})
+}
+
+// stopWatching makes the next sync go back to polling the target allocator.
+func (m *Manager) stopWatching() {
+	m.applyMtx.Lock()
+	defer m.applyMtx.Unlock()
+	m.watching.Store(false)
+}
+
+// runWatch watches the target allocator, retrying after an interval when the watch fails. The jobs fall back to
+// polling while the watch is down.
+func (m *Manager) runWatch(ctx context.Context, httpClient *http.Client) {
+	for {
+		err := m.watch(ctx, httpClient)
+		if ctx.Err() != nil {
+			return
+		}
+		m.settings.Logger.Warn("Failed to watch the target allocator, falling back to polling", zap.Error(err))
+		select {
+		case <-time.After(m.cfg.Interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package targetallocator
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/promslog"
+	promconfig "github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/discovery"
+	promHTTP "github.com/prometheus/prometheus/discovery/http"
+	"github.com/prometheus/prometheus/scrape"
+	"github.com/prometheus/prometheus/util/teststorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/receiver/prometheusreceiver/internal/metadata"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/receiver/prometheusreceiver/internal/sharedpromconfig"
+)
+
+func TestWatchedTargets(t *testing.T) {
+	targets := newWatchedTargets()
+	targets.apply(&watchEvent{Full: true, Jobs: map[string]watchJobChange{
+		"job1": {Added: map[string]watchTarget{
+			"1": {Targets: []string{"10.0.0.1:8080"}, Labels: map[string]string{"pod": "a"}},
+			"2": {Targets: []string{"10.0.0.2:8080"}, Labels: map[string]string{"pod": "b"}},
+		}},
+	}})
+
+	group, updated := targets.group("job1")
+	assert.Equal(t, "target_allocator/job1", group.Source)
+	assert.Equal(t, []model.LabelSet{
+		{model.AddressLabel: "10.0.0.1:8080", "pod": "a"},
+		{model.AddressLabel: "10.0.0.2:8080", "pod": "b"},
+	}, group.Targets)
+	_, otherUpdated := targets.group("job2")
+
+	targets.apply(&watchEvent{Jobs: map[string]watchJobChange{"job1": {Removed: []string{"1"}}}})
+	select {
+	case <-updated:
+	default:
+		t.Fatal("the job should be notified of its changes")
+	}
+	select {
+	case <-otherUpdated:
+		t.Fatal("the other jobs should not be notified")
+	default:
+	}
+	group, _ = targets.group("job1")
+	assert.Equal(t, []model.LabelSet{{model.AddressLabel: "10.0.0.2:8080", "pod": "b"}}, group.Targets)
+
+	// a full event replaces all the targets
+	targets.apply(&watchEvent{Full: true})
+	group, _ = targets.group("job1")
+	assert.Empty(t, group.Targets)
+}
+
+func TestManagerWatchFallsBackToPolling(t *testing.T) {
+	scrapeConfigs := `{"job1": {"job_name": "job1", "scrape_interval": "30s", "scrape_timeout": "30s", "metrics_path": "/metrics", "scheme": "http"}}`
+	closeWatch := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/scrape_configs":
+			_, _ = w.Write([]byte(scrapeConfigs))
+		case "/watch":
+			select {
+			case <-closeWatch:
+				// the target allocator cannot be watched anymore
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			default:
+			}
+			assert.Equal(t, "test-collector", r.URL.Query().Get("collector_id"))
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprintf(w, ": keepalive\n\ndata: {\"full\": true, \"scrape_configs\": %s, \"jobs\": {\"job1\": {\"added\": {\"1\": {\"targets\": [\"10.0.0.1:8080\"], \"labels\": {}}}}}}\n\n", scrapeConfigs)
+			w.(http.Flusher).Flush()
+			select {
+			case <-closeWatch:
+			case <-r.Context().Done():
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &Config{
+		Interval:    50 * time.Millisecond,
+		CollectorID: "test-collector",
+		Watch:       true,
+	}
+	cfg.Endpoint = server.URL
+	promCfg, err := promconfig.Load("", nil)
+	require.NoError(t, err)
+	sharedCfg := sharedpromconfig.NewConfig(promCfg)
+	manager := NewManager(receivertest.NewNopSettings(metadata.Type), cfg, sharedCfg)
+
+	promLogger := promslog.NewNopLogger()
+	reg := prometheus.NewRegistry()
+	sdMetrics, err := discovery.CreateAndRegisterSDMetrics(reg)
+	require.NoError(t, err)
+	discoveryManager := discovery.NewManager(t.Context(), promLogger, reg, sdMetrics)
+	require.NotNil(t, discoveryManager)
+	scrapeManager, err := scrape.NewManager(&scrape.Options{}, promLogger, nil, nil, teststorage.New(t), reg)
+	require.NoError(t, err)
+	defer scrapeManager.Stop()
+
+	require.NoError(t, manager.Start(t.Context(), componenttest.NewNopHost(), scrapeManager, discoveryManager))
+	defer manager.Shutdown()
+
+	sdConfig := func() discovery.Config {
+		for _, scrapeConfig := range sharedCfg.Get().ScrapeConfigs {
+			if scrapeConfig.JobName == "job1" && len(scrapeConfig.ServiceDiscoveryConfigs) == 1 {
+				return scrapeConfig.ServiceDiscoveryConfigs[0]
+			}
+		}
+		return nil
+	}
+	require.Eventually(t, func() bool {
+		_, ok := sdConfig().(*watchSDConfig)
+		return ok && manager.watching.Load()
+	}, 5*time.Second, 10*time.Millisecond, "the jobs should discover their targets from the watch")
+	group, _ := manager.watchedTargets.group("job1")
+	assert.Len(t, group.Targets, 1)
+
+	close(closeWatch)
+	require.Eventually(t, func() bool {
+		_, ok := sdConfig().(*promHTTP.SDConfig)
+		return ok
+	}, 5*time.Second, 10*time.Millisecond, "the jobs should fall back to polling")
+}
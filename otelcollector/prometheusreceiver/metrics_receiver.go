@@ -184,7 +184,7 @@ func (r *pReceiver) initPrometheusComponents(
 			Set(reflect.ValueOf(true))
 	}
 
-	scrapeManager, err := scrape.NewManager(scrapeOpts, logger, nil, nil, store, r.registerer)
+	scrapeManager, err := scrape.NewManager(scrapeOpts, logger, nil, nil, r.targetAllocatorManager.Appendable(store), r.registerer)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,167 @@
+package shared
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// CollectorProcessorsPath is where the settings parser writes the processors of the collector-processors setting,
+	// read by the config validator
+	CollectorProcessorsPath = "/opt/microsoft/configmapparser/config_collector_processors"
+
+	// The user processors run after the batch processor, before or after the resource processor adding the cluster,
+	// job and instance labels
+	CollectorProcessorsBeforeResource = "before_resource"
+	CollectorProcessorsAfterResource  = "after_resource"
+)
+
+// collectorProcessorTypes are the processor types that can be added to the metrics pipeline. The collector and the
+// config validator are built with their factories.
+var collectorProcessorTypes = []string{"filter", "attributes", "transform", "resource"}
+
+// CollectorProcessors are processors added to the metrics pipeline of the collector, for example:
+//
+//	position: before_resource
+//	processors:
+//	  - name: filter/drop-test-namespaces
+//	    config:
+//	      metrics:
+//	        datapoint:
+//	          - 'attributes["namespace"] == "test"'
+//	  - name: attributes/hash-user
+//	    config:
+//	      actions:
+//	        - key: user_id
+//	          action: hash
+//
+// The processors run in order.
+type CollectorProcessors struct {
+	Position   string               `yaml:"position,omitempty"`
+	Processors []CollectorProcessor `yaml:"processors"`
+}
+
+// CollectorProcessor is a processor with its id, <type>/<name>, and its config for the processor factory
+type CollectorProcessor struct {
+	Name   string      `yaml:"name"`
+	Config interface{} `yaml:"config"`
+}
+
+// Validate checks the processor types and names and the position. The processor configs are validated by the
+// collector factories when the config validator loads the collector config.
+func (p *CollectorProcessors) Validate() error {
+	if p.Position != "" && p.Position != CollectorProcessorsBeforeResource && p.Position != CollectorProcessorsAfterResource {
+		return fmt.Errorf("position %q is not %s or %s", p.Position, CollectorProcessorsBeforeResource, CollectorProcessorsAfterResource)
+	}
+	names := make(map[string]bool)
+	for _, processor := range p.Processors {
+		processorType, name, found := strings.Cut(processor.Name, "/")
+		if !found || name == "" {
+			return fmt.Errorf("processor %q is not named <type>/<name>", processor.Name)
+		}
+		if !slices.Contains(collectorProcessorTypes, processorType) {
+			return fmt.Errorf("processor %s: type %s is not supported, use one of %s", processor.Name, processorType, strings.Join(collectorProcessorTypes, ", "))
+		}
+		if names[processor.Name] {
+			return fmt.Errorf("processor %s is defined more than once", processor.Name)
+		}
+		names[processor.Name] = true
+		if processor.Config == nil {
+			return fmt.Errorf("processor %s has no config", processor.Name)
+		}
+	}
+	return nil
+}
+
+// ReadCollectorProcessors reads and validates the processors written by the settings parser. Nil is returned when the
+// file does not exist or has no processors.
+func ReadCollectorProcessors(path string) (*CollectorProcessors, error) {
+	contents, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var processors CollectorProcessors
+	if err := yaml.UnmarshalStrict(contents, &processors); err != nil {
+		return nil, err
+	}
+	if err := processors.Validate(); err != nil {
+		return nil, err
+	}
+	if len(processors.Processors) == 0 {
+		return nil, nil
+	}
+	return &processors, nil
+}
+
+// ApplyCollectorProcessors adds the processors to the collector config and to its metrics pipeline, after the batch
+// processor and before or after the resource processor depending on the position.
+func ApplyCollectorProcessors(otelConfig *OtelConfig, processors *CollectorProcessors) error {
+	if processors == nil || len(processors.Processors) == 0 {
+		return nil
+	}
+	configProcessors, _ := otelConfig.Processors.(map[interface{}]interface{})
+	if configProcessors == nil {
+		configProcessors = make(map[interface{}]interface{})
+		otelConfig.Processors = configProcessors
+	}
+	pipelineProcessors, _ := otelConfig.Service.Pipelines.Metrics.Processors.([]interface{})
+
+	added := make([]interface{}, 0, len(processors.Processors))
+	for _, processor := range processors.Processors {
+		if _, exists := configProcessors[processor.Name]; exists {
+			return fmt.Errorf("processor %s is already defined by the agent", processor.Name)
+		}
+		configProcessors[processor.Name] = escapeConfmapValues(processor.Config)
+		added = append(added, processor.Name)
+	}
+
+	index := len(pipelineProcessors)
+	if resourceIndex := slices.Index(pipelineProcessors, interface{}("resource")); resourceIndex >= 0 {
+		index = resourceIndex
+		if processors.Position == CollectorProcessorsAfterResource {
+			index++
+		}
+	}
+	otelConfig.Service.Pipelines.Metrics.Processors = slices.Insert(pipelineProcessors, index, added...)
+	return nil
+}
+
+// ApplyCollectorProcessorsInCollectorConfig adds the processors to a collector config file that is not generated by the
+// config validator, such as the replicaset config used with the target allocator.
+func ApplyCollectorProcessorsInCollectorConfig(configPath string, processors *CollectorProcessors) error {
+	if processors == nil || len(processors.Processors) == 0 {
+		return nil
+	}
+	return updateCollectorConfigFile(configPath, "collector processors", func(otelConfig *OtelConfig) error {
+		return ApplyCollectorProcessors(otelConfig, processors)
+	})
+}
+
+// escapeConfmapValues returns a copy of a processor config with the $ escaped in every string, so regexes and OTTL
+// statements are not treated as env substitution by the otel confmap loader
+func escapeConfmapValues(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case string:
+		return escapeConfmapValue(typed)
+	case map[interface{}]interface{}:
+		escaped := make(map[interface{}]interface{}, len(typed))
+		for key, item := range typed {
+			escaped[key] = escapeConfmapValues(item)
+		}
+		return escaped
+	case []interface{}:
+		escaped := make([]interface{}, len(typed))
+		for i, item := range typed {
+			escaped[i] = escapeConfmapValues(item)
+		}
+		return escaped
+	default:
+		return value
+	}
+}
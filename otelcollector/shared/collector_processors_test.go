@@ -0,0 +1,105 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const testCollectorProcessors = `position: before_resource
+processors:
+  - name: filter/drop-test
+    config:
+      metrics:
+        metric:
+          - 'IsMatch(name, "^test_.*$")'
+  - name: attributes/hash-user
+    config:
+      actions:
+        - key: user_id
+          action: hash
+`
+
+func TestCollectorProcessorsValidate(t *testing.T) {
+	tests := map[string]string{
+		"processors:\n  - name: filter/a\n    config: {}\n":                                     "",
+		"position: first\nprocessors: []\n":                                                     "position",
+		"processors:\n  - name: filter\n    config: {}\n":                                       "<type>/<name>",
+		"processors:\n  - name: batch/a\n    config: {}\n":                                      "not supported",
+		"processors:\n  - name: filter/a\n    config: {}\n  - name: filter/a\n    config: {}\n": "more than once",
+		"processors:\n  - name: filter/a\n":                                                     "no config",
+	}
+	for config, expectedError := range tests {
+		var processors CollectorProcessors
+		if err := yaml.Unmarshal([]byte(config), &processors); err != nil {
+			t.Fatalf("failed to parse %q: %v", config, err)
+		}
+		err := processors.Validate()
+		if expectedError == "" && err != nil {
+			t.Errorf("Validate(%q) = %v, expected no error", config, err)
+		} else if expectedError != "" && (err == nil || !strings.Contains(err.Error(), expectedError)) {
+			t.Errorf("Validate(%q) = %v, expected an error containing %q", config, err, expectedError)
+		}
+	}
+}
+
+func TestReadCollectorProcessors(t *testing.T) {
+	dir := t.TempDir()
+	if processors, err := ReadCollectorProcessors(filepath.Join(dir, "missing")); processors != nil || err != nil {
+		t.Errorf("expected no processors for a missing file, got %v, %v", processors, err)
+	}
+
+	path := filepath.Join(dir, "processors")
+	if err := os.WriteFile(path, []byte(testCollectorProcessors), 0644); err != nil {
+		t.Fatal(err)
+	}
+	processors, err := ReadCollectorProcessors(path)
+	if err != nil || len(processors.Processors) != 2 {
+		t.Fatalf("unexpected processors %v, %v", processors, err)
+	}
+
+	if err := os.WriteFile(path, []byte("processor: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadCollectorProcessors(path); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+func TestApplyCollectorProcessors(t *testing.T) {
+	var processors CollectorProcessors
+	if err := yaml.Unmarshal([]byte(testCollectorProcessors), &processors); err != nil {
+		t.Fatal(err)
+	}
+
+	for position, expected := range map[string][]interface{}{
+		CollectorProcessorsBeforeResource: {"batch", "filter/drop-test", "attributes/hash-user", "resource"},
+		CollectorProcessorsAfterResource:  {"batch", "resource", "filter/drop-test", "attributes/hash-user"},
+	} {
+		var otelConfig OtelConfig
+		if err := yaml.Unmarshal([]byte(testExternalLabelsOtelConfig+"service:\n  pipelines:\n    metrics:\n      processors: [batch, resource]\n"), &otelConfig); err != nil {
+			t.Fatal(err)
+		}
+		processors.Position = position
+		if err := ApplyCollectorProcessors(&otelConfig, &processors); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if actual := otelConfig.Service.Pipelines.Metrics.Processors; !reflect.DeepEqual(actual, expected) {
+			t.Errorf("position %s: expected pipeline %v, got %v", position, expected, actual)
+		}
+
+		filterConfig := otelConfig.Processors.(map[interface{}]interface{})["filter/drop-test"]
+		metric := filterConfig.(map[interface{}]interface{})["metrics"].(map[interface{}]interface{})["metric"].([]interface{})[0]
+		if metric != `IsMatch(name, "^test_.*$$")` {
+			t.Errorf("expected the $ in the condition to be escaped, got %v", metric)
+		}
+
+		if err := ApplyCollectorProcessors(&otelConfig, &processors); err == nil {
+			t.Error("expected an error for processors already in the config")
+		}
+	}
+}
@@ -49,6 +49,35 @@ func RemoveHTTPSSettingsInCollectorConfig(configpath string) error {
 	return nil
 }
 
+// updateCollectorConfigFile applies a change to a collector config file
+func updateCollectorConfigFile(configPath string, change string, apply func(*OtelConfig) error) error {
+	configFileContents, err := os.ReadFile(configPath)
+	if err != nil {
+		log.Printf("Unable to read file contents from: %s - %v\n", configPath, err)
+		return err
+	}
+	var otelConfig OtelConfig
+	if err := yaml.Unmarshal(configFileContents, &otelConfig); err != nil {
+		log.Printf("Unable to unmarshal otel configuration from: %s - %v\n", configPath, err)
+		return err
+	}
+	if err := apply(&otelConfig); err != nil {
+		log.Printf("Unable to add the %s to: %s - %v\n", change, configPath, err)
+		return err
+	}
+	updatedConfigYaml, err := yaml.Marshal(otelConfig)
+	if err != nil {
+		log.Printf("Unable to marshal updated otel configuration - %v\n", err)
+		return err
+	}
+	if err := os.WriteFile(configPath, updatedConfigYaml, 0644); err != nil {
+		log.Printf("Unable to write updated configuration to: %s - %v\n", configPath, err)
+		return err
+	}
+	log.Printf("Added the %s to %s\n", change, configPath)
+	return nil
+}
+
 func CollectorTAHttpsCheck(collectorConfig string) error {
 	caCertPath := "/etc/operator-targets/client/certs/ca.crt"
 	removeHttps := false
@@ -78,7 +107,7 @@ func CollectorTAHttpsCheck(collectorConfig string) error {
 		for i := 0; i <= retries_https; i++ {
 			certPEM, err := ioutil.ReadFile(caCertPath)
 			if err != nil {
-				log.Printf("Failed to read CA cert file from path: %s - (%d/%d): %v\n", caCertPath, i+1, retries_https)
+				log.Printf("Failed to read CA cert file from path: %s - (%d/%d): %v\n", caCertPath, i+1, retries_https, err)
 				removeHttps = true
 				// break
 			} else {
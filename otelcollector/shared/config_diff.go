@@ -0,0 +1,241 @@
+package shared
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ConfigDiffLogPrefix starts the log line with the JSON diff of the effective scrape config, picked up by fluent-bit
+// to send it as a telemetry event. The fluent-bit plugin is built without this module and has its own copy, kept equal
+// by TestConfigDiffLogPrefixMatchesFluentBit.
+const ConfigDiffLogPrefix = "Effective scrape config changed: "
+
+// KeepListField is the field of a job change for the keep rules on the metric names in metric_relabel_configs, which
+// are compared apart from the other metric relabel rules
+const KeepListField = "keep_list"
+
+// ConfigDiff is the difference between the scrape jobs of two effective configs. A job changed between the configs
+// is listed in Changes with the fields that changed, not in the additions and removals.
+type ConfigDiff struct {
+	ConfigVersion         string            `json:"configVersion,omitempty"`
+	PreviousConfigVersion string            `json:"previousConfigVersion,omitempty"`
+	Additions             []string          `json:"additions,omitempty"`
+	Removals              []string          `json:"removals,omitempty"`
+	Changes               []ScrapeJobChange `json:"changes,omitempty"`
+}
+
+// ScrapeJobChange is a job of both configs with the fields that changed
+type ScrapeJobChange struct {
+	Job    string        `json:"job"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// FieldChange is a field of a scrape job with its previous and current value, empty when the field is not set. The
+// values that are not strings are in YAML, and the secrets they hold are replaced by SecretPlaceholder.
+type FieldChange struct {
+	Field    string `json:"field"`
+	Previous string `json:"previous,omitempty"`
+	Current  string `json:"current,omitempty"`
+}
+
+// SecretPlaceholder replaces the value of the secret fields in the field changes, so that logging a diff does not
+// expose them. A changed secret is still reported as a change of the field holding it.
+const SecretPlaceholder = "<secret>"
+
+// secretFields are the fields holding a secret in the scrape configs and their http client configs, at any depth
+var secretFields = map[string]bool{
+	"password":      true,
+	"bearer_token":  true,
+	"credentials":   true,
+	"client_secret": true,
+	"secret_key":    true,
+	"token":         true,
+	"key":           true,
+	"secrets":       true,
+}
+
+// jobField is the value of a field of a scrape job, with its secrets redacted for the diff
+type jobField struct {
+	value    string
+	redacted string
+}
+
+// IsEmpty returns true when the scrape jobs are the same
+func (d *ConfigDiff) IsEmpty() bool {
+	return len(d.Additions) == 0 && len(d.Removals) == 0 && len(d.Changes) == 0
+}
+
+// DiffScrapeConfigs compares the scrape_configs of two prometheus configs by job name, in the order of the job names
+func DiffScrapeConfigs(previous, current []interface{}) (*ConfigDiff, error) {
+	previousJobs, err := scrapeJobsByName(previous)
+	if err != nil {
+		return nil, err
+	}
+	currentJobs, err := scrapeJobsByName(current)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &ConfigDiff{}
+	for _, jobName := range slices.Sorted(maps.Keys(currentJobs)) {
+		previousFields, found := previousJobs[jobName]
+		if !found {
+			diff.Additions = append(diff.Additions, jobName)
+			continue
+		}
+		if changes := diffFields(previousFields, currentJobs[jobName]); len(changes) > 0 {
+			diff.Changes = append(diff.Changes, ScrapeJobChange{Job: jobName, Fields: changes})
+		}
+	}
+	for _, jobName := range slices.Sorted(maps.Keys(previousJobs)) {
+		if _, found := currentJobs[jobName]; !found {
+			diff.Removals = append(diff.Removals, jobName)
+		}
+	}
+	return diff, nil
+}
+
+// Summary returns the changed jobs and fields on one line, e.g. "added: a; removed: b; changed: c(scrape_interval)"
+func (d *ConfigDiff) Summary() string {
+	parts := []string{}
+	if len(d.Additions) > 0 {
+		parts = append(parts, "added: "+strings.Join(d.Additions, ","))
+	}
+	if len(d.Removals) > 0 {
+		parts = append(parts, "removed: "+strings.Join(d.Removals, ","))
+	}
+	if len(d.Changes) > 0 {
+		changes := []string{}
+		for _, change := range d.Changes {
+			fields := []string{}
+			for _, field := range change.Fields {
+				fields = append(fields, field.Field)
+			}
+			changes = append(changes, fmt.Sprintf("%s(%s)", change.Job, strings.Join(fields, ",")))
+		}
+		parts = append(parts, "changed: "+strings.Join(changes, ","))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// scrapeJobsByName returns the fields of each scrape job as strings, keyed by job name
+func scrapeJobsByName(scrapeConfigs []interface{}) (map[string]map[string]jobField, error) {
+	jobs := map[string]map[string]jobField{}
+	for _, scrapeConfig := range scrapeConfigs {
+		job, ok := scrapeConfig.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid scrape config %v", scrapeConfig)
+		}
+		jobName := fmt.Sprint(job["job_name"])
+		fields := map[string]jobField{}
+		for key, value := range job {
+			field := fmt.Sprint(key)
+			if field == "job_name" {
+				continue
+			}
+			if field == "metric_relabel_configs" {
+				keepList, otherRules := splitKeepList(value)
+				if len(keepList) > 0 {
+					keepListRegex := strings.Join(keepList, "\n")
+					fields[KeepListField] = jobField{value: keepListRegex, redacted: keepListRegex}
+				}
+				if len(otherRules) == 0 {
+					continue
+				}
+				value = otherRules
+			}
+			fieldValue, err := fieldString(value)
+			if err != nil {
+				return nil, fmt.Errorf("job %s: %w", jobName, err)
+			}
+			redacted := SecretPlaceholder
+			if !secretFields[field] {
+				if redacted, err = fieldString(redactSecrets(value)); err != nil {
+					return nil, fmt.Errorf("job %s: %w", jobName, err)
+				}
+			}
+			fields[field] = jobField{value: fieldValue, redacted: redacted}
+		}
+		jobs[jobName] = fields
+	}
+	return jobs, nil
+}
+
+// splitKeepList returns the regexes of the keep rules on the metric names and the other metric relabel rules
+func splitKeepList(metricRelabelConfigs interface{}) ([]string, []interface{}) {
+	rules, ok := metricRelabelConfigs.([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	keepList := []string{}
+	otherRules := []interface{}{}
+	for _, rule := range rules {
+		ruleMap, ok := rule.(map[interface{}]interface{})
+		if ok && ruleMap["action"] == "keep" && len(ruleMap) == 3 {
+			sourceLabels, _ := ruleMap["source_labels"].([]interface{})
+			if regex, isString := ruleMap["regex"].(string); isString && len(sourceLabels) == 1 && sourceLabels[0] == "__name__" {
+				keepList = append(keepList, regex)
+				continue
+			}
+		}
+		otherRules = append(otherRules, rule)
+	}
+	return keepList, otherRules
+}
+
+// redactSecrets returns a copy of the value with the values of the secret fields replaced by SecretPlaceholder
+func redactSecrets(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		redacted := make(map[interface{}]interface{}, len(v))
+		for key, fieldValue := range v {
+			if secretFields[fmt.Sprint(key)] {
+				redacted[key] = SecretPlaceholder
+			} else {
+				redacted[key] = redactSecrets(fieldValue)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactSecrets(item)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
+func fieldString(value interface{}) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	contents, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(contents)), nil
+}
+
+// diffFields returns the fields of a job that changed, in field order, with their redacted values
+func diffFields(previous, current map[string]jobField) []FieldChange {
+	changes := []FieldChange{}
+	fields := slices.Sorted(maps.Keys(current))
+	for field := range previous {
+		if _, found := current[field]; !found {
+			fields = append(fields, field)
+		}
+	}
+	slices.Sort(fields)
+	for _, field := range fields {
+		if previous[field].value != current[field].value {
+			changes = append(changes, FieldChange{Field: field, Previous: previous[field].redacted, Current: current[field].redacted})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,161 @@
+package shared
+
+import (
+	"os"
+	"reflect"
+	"regexp"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const testPreviousScrapeConfigs = `
+- job_name: kubelet
+  scrape_interval: 30s
+  metric_relabel_configs:
+    - source_labels: [__name__]
+      action: keep
+      regex: kubelet_running_pods
+- job_name: my-app
+  scrape_interval: 30s
+  relabel_configs:
+    - source_labels: [__meta_kubernetes_pod_label_app]
+      action: keep
+      regex: my-app
+- job_name: removed-job
+  static_configs:
+    - targets: ["removed:8080"]
+`
+
+const testCurrentScrapeConfigs = `
+- job_name: kubelet
+  scrape_interval: 30s
+  metric_relabel_configs:
+    - source_labels: [__name__]
+      action: keep
+      regex: kubelet_running_pods|kubelet_node_name
+- job_name: my-app
+  scrape_interval: 15s
+  relabel_configs:
+    - source_labels: [__meta_kubernetes_pod_label_app]
+      action: keep
+      regex: my-app
+- job_name: added-job
+  static_configs:
+    - targets: ["added:8080"]
+`
+
+func unmarshalTestScrapeConfigs(t *testing.T, contents string) []interface{} {
+	t.Helper()
+	var scrapeConfigs []interface{}
+	if err := yaml.Unmarshal([]byte(contents), &scrapeConfigs); err != nil {
+		t.Fatalf("invalid test scrape configs: %v", err)
+	}
+	return scrapeConfigs
+}
+
+func TestDiffScrapeConfigs(t *testing.T) {
+	previous := unmarshalTestScrapeConfigs(t, testPreviousScrapeConfigs)
+	current := unmarshalTestScrapeConfigs(t, testCurrentScrapeConfigs)
+
+	diff, err := DiffScrapeConfigs(previous, current)
+	if err != nil {
+		t.Fatalf("DiffScrapeConfigs(): %v", err)
+	}
+	expected := &ConfigDiff{
+		Additions: []string{"added-job"},
+		Removals:  []string{"removed-job"},
+		Changes: []ScrapeJobChange{
+			{Job: "kubelet", Fields: []FieldChange{{Field: KeepListField, Previous: "kubelet_running_pods", Current: "kubelet_running_pods|kubelet_node_name"}}},
+			{Job: "my-app", Fields: []FieldChange{{Field: "scrape_interval", Previous: "30s", Current: "15s"}}},
+		},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("DiffScrapeConfigs() = %+v, expected %+v", diff, expected)
+	}
+	if summary := diff.Summary(); summary != "added: added-job; removed: removed-job; changed: kubelet(keep_list),my-app(scrape_interval)" {
+		t.Errorf("Summary() = %q", summary)
+	}
+
+	diff, err = DiffScrapeConfigs(current, current)
+	if err != nil {
+		t.Fatalf("DiffScrapeConfigs(): %v", err)
+	}
+	if !diff.IsEmpty() {
+		t.Errorf("expected no changes for the same scrape configs, got %+v", diff)
+	}
+}
+
+func TestDiffScrapeConfigsRelabelRules(t *testing.T) {
+	previous := unmarshalTestScrapeConfigs(t, testPreviousScrapeConfigs)
+	current := unmarshalTestScrapeConfigs(t, `
+- job_name: my-app
+  scrape_interval: 30s
+  relabel_configs:
+    - source_labels: [__meta_kubernetes_pod_label_app]
+      action: keep
+      regex: my-other-app
+  metric_relabel_configs:
+    - source_labels: [__name__]
+      action: drop
+      regex: go_.*
+`)
+
+	diff, err := DiffScrapeConfigs(previous[1:2], current)
+	if err != nil {
+		t.Fatalf("DiffScrapeConfigs(): %v", err)
+	}
+	if len(diff.Changes) != 1 || len(diff.Changes[0].Fields) != 2 {
+		t.Fatalf("expected 2 changed fields for my-app, got %+v", diff.Changes)
+	}
+	metricRelabelConfigs, relabelConfigs := diff.Changes[0].Fields[0], diff.Changes[0].Fields[1]
+	if metricRelabelConfigs.Field != "metric_relabel_configs" || metricRelabelConfigs.Previous != "" {
+		t.Errorf("unexpected metric_relabel_configs change %+v", metricRelabelConfigs)
+	}
+	if relabelConfigs.Field != "relabel_configs" || relabelConfigs.Current == relabelConfigs.Previous {
+		t.Errorf("unexpected relabel_configs change %+v", relabelConfigs)
+	}
+}
+
+func TestDiffScrapeConfigsRedactsSecrets(t *testing.T) {
+	previous := unmarshalTestScrapeConfigs(t, `
+- job_name: my-app
+  bearer_token: previous-token
+  basic_auth:
+    username: user
+    password: previous-password
+`)
+	current := unmarshalTestScrapeConfigs(t, `
+- job_name: my-app
+  bearer_token: current-token
+  basic_auth:
+    username: user
+    password: current-password
+`)
+
+	diff, err := DiffScrapeConfigs(previous, current)
+	if err != nil {
+		t.Fatalf("DiffScrapeConfigs(): %v", err)
+	}
+	expected := []FieldChange{
+		{Field: "basic_auth", Previous: "password: <secret>\nusername: user", Current: "password: <secret>\nusername: user"},
+		{Field: "bearer_token", Previous: SecretPlaceholder, Current: SecretPlaceholder},
+	}
+	if len(diff.Changes) != 1 || !reflect.DeepEqual(diff.Changes[0].Fields, expected) {
+		t.Errorf("DiffScrapeConfigs() changes = %+v, expected the fields %+v", diff.Changes, expected)
+	}
+}
+
+func TestConfigDiffLogPrefixMatchesFluentBit(t *testing.T) {
+	source, err := os.ReadFile("../fluent-bit/src/telemetry.go")
+	if err != nil {
+		t.Fatalf("reading the fluent-bit telemetry source: %v", err)
+	}
+	match := regexp.MustCompile(`configDiffLogPrefix\s*=\s*"([^"]*)"`).FindSubmatch(source)
+	if match == nil {
+		t.Fatal("configDiffLogPrefix not found in the fluent-bit telemetry source")
+	}
+	if string(match[1]) != ConfigDiffLogPrefix {
+		t.Errorf("fluent-bit configDiffLogPrefix is %q, expected %q", match[1], ConfigDiffLogPrefix)
+	}
+}
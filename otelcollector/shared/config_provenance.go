@@ -0,0 +1,102 @@
+package shared
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+)
+
+const (
+	// ConfigProvenancePath is where the settings parser writes the provenance of the scrape jobs it merged, served by
+	// the explain endpoint of the main container
+	ConfigProvenancePath = "/opt/microsoft/configmapparser/config_provenance.json"
+
+	// TargetAllocatorConfigProvenanceFile is the name of the provenance file the config reader writes next to the
+	// target allocator config
+	TargetAllocatorConfigProvenanceFile = "config-provenance.json"
+)
+
+// Sources of the scrape jobs
+const (
+	ProvenanceSourceDefaultTarget  = "default-target"
+	ProvenanceSourcePodAnnotations = "pod-annotations"
+	ProvenanceSourceCustomConfig   = "custom-config"
+	ProvenanceSourceConfigFragment = "custom-config-fragment"
+)
+
+// ConfigProvenance describes where the scrape jobs of the merged prometheus config come from
+type ConfigProvenance struct {
+	ConfigVersion string                `json:"configVersion,omitempty" yaml:"configVersion,omitempty"`
+	Jobs          []ScrapeJobProvenance `json:"jobs" yaml:"jobs"`
+}
+
+// ScrapeJobProvenance is where a scrape job comes from and the settings that changed it
+type ScrapeJobProvenance struct {
+	Job    string `json:"job" yaml:"job"`
+	Source string `json:"source" yaml:"source"`
+	// DefaultTarget is the name of the default target of the default-target and pod-annotations jobs
+	DefaultTarget string `json:"defaultTarget,omitempty" yaml:"defaultTarget,omitempty"`
+	// File is the default scrape config file or the custom config key the job is defined in
+	File       string              `json:"file,omitempty" yaml:"file,omitempty"`
+	ModifiedBy []SettingProvenance `json:"modifiedBy,omitempty" yaml:"modifiedBy,omitempty"`
+}
+
+// SettingProvenance is a setting of the settings configmap applied to a scrape job, as <section>.<key>, and its value
+type SettingProvenance struct {
+	Setting string `json:"setting" yaml:"setting"`
+	Value   string `json:"value" yaml:"value"`
+}
+
+// AddJobs records the jobs defined in a scrape config file or custom config key
+func (p *ConfigProvenance) AddJobs(jobNames []string, source, defaultTarget, file string, modifiedBy []SettingProvenance) {
+	for _, jobName := range jobNames {
+		p.Jobs = append(p.Jobs, ScrapeJobProvenance{
+			Job:           jobName,
+			Source:        source,
+			DefaultTarget: defaultTarget,
+			File:          file,
+			ModifiedBy:    slices.Clone(modifiedBy),
+		})
+	}
+}
+
+// Job returns the provenance of a job, or nil when the job is not known
+func (p *ConfigProvenance) Job(jobName string) *ScrapeJobProvenance {
+	if p == nil {
+		return nil
+	}
+	for i := range p.Jobs {
+		if p.Jobs[i].Job == jobName {
+			return &p.Jobs[i]
+		}
+	}
+	return nil
+}
+
+// WriteConfigProvenance writes the provenance as JSON
+func WriteConfigProvenance(path string, provenance *ConfigProvenance) error {
+	contents, err := json.MarshalIndent(provenance, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, contents, 0644)
+}
+
+// ReadConfigProvenance reads the provenance written by the settings parser. An empty provenance is returned when the
+// file does not exist.
+func ReadConfigProvenance(path string) (*ConfigProvenance, error) {
+	contents, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &ConfigProvenance{Jobs: []ScrapeJobProvenance{}}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var provenance ConfigProvenance
+	if err := json.Unmarshal(contents, &provenance); err != nil {
+		return nil, err
+	}
+	if provenance.Jobs == nil {
+		provenance.Jobs = []ScrapeJobProvenance{}
+	}
+	return &provenance, nil
+}
@@ -0,0 +1,42 @@
+package shared
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConfigProvenanceRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config_provenance.json")
+
+	provenance, err := ReadConfigProvenance(path)
+	if err != nil {
+		t.Fatalf("ReadConfigProvenance() without a file: %v", err)
+	}
+	if len(provenance.Jobs) != 0 || provenance.Jobs == nil {
+		t.Errorf("expected an empty list of jobs without a file, got %v", provenance.Jobs)
+	}
+
+	modifiedBy := []SettingProvenance{{Setting: "default-targets-metrics-keep-list.kubelet", Value: "up"}}
+	provenance = &ConfigProvenance{ConfigVersion: "ver1"}
+	provenance.AddJobs([]string{"kubelet"}, ProvenanceSourceDefaultTarget, "kubelet", "kubeletDefault.yml", modifiedBy)
+	provenance.AddJobs([]string{"my-job"}, ProvenanceSourceCustomConfig, "", "prometheus-config", nil)
+	modifiedBy[0].Value = "changed"
+	if err := WriteConfigProvenance(path, provenance); err != nil {
+		t.Fatalf("WriteConfigProvenance(): %v", err)
+	}
+
+	read, err := ReadConfigProvenance(path)
+	if err != nil {
+		t.Fatalf("ReadConfigProvenance(): %v", err)
+	}
+	if !reflect.DeepEqual(read, provenance) {
+		t.Errorf("ReadConfigProvenance() = %+v, expected %+v", read, provenance)
+	}
+	if job := read.Job("kubelet"); job == nil || job.ModifiedBy[0].Value != "up" {
+		t.Errorf("Job(kubelet) = %+v, expected the keep list setting", job)
+	}
+	if job := read.Job("unknown"); job != nil {
+		t.Errorf("Job(unknown) = %+v, expected nil", job)
+	}
+}
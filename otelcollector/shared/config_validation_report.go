@@ -0,0 +1,275 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+)
+
+const (
+	ValidationSeverityError   = "error"
+	ValidationSeverityWarning = "warning"
+	ValidationSeverityInfo    = "info"
+
+	// PromConfigValidatorReportPath is where the config validator writes its report when running in the agent
+	PromConfigValidatorReportPath = "/opt/microsoft/prom_config_validator_report.json"
+	// PromConfigValidatorDefaultsReportPath is where the config validator writes the report of the default scrape
+	// configs validated after the custom config was rejected, so the report of the rejection is kept
+	PromConfigValidatorDefaultsReportPath = "/opt/microsoft/prom_config_validator_defaults_report.json"
+
+	// LabelLimitChangesPath is where the settings parser writes the label limits it set on the custom scrape jobs
+	LabelLimitChangesPath = "/opt/microsoft/configmapparser/label_limit_changes.json"
+
+	// Label limits the agent sets on every custom scrape job
+	CustomScrapeLabelLimit            = 63
+	CustomScrapeLabelNameLengthLimit  = 511
+	CustomScrapeLabelValueLengthLimit = 1023
+)
+
+// ValidationFinding is a single issue or change found while validating a prometheus config
+type ValidationFinding struct {
+	Job      string `json:"job,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// ValidationReport is the machine-readable result of validating a prometheus config
+type ValidationReport struct {
+	Valid    bool                `json:"valid"`
+	Findings []ValidationFinding `json:"findings"`
+}
+
+// AddFinding appends a finding to the report and marks it invalid for error findings
+func (r *ValidationReport) AddFinding(finding ValidationFinding) {
+	r.Findings = append(r.Findings, finding)
+	if finding.Severity == ValidationSeverityError {
+		r.Valid = false
+	}
+}
+
+// WriteValidationReport writes the report as json to the given file
+func WriteValidationReport(path string, report *ValidationReport) error {
+	contents, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, contents, 0644)
+}
+
+// ReadValidationReport reads a report written by WriteValidationReport
+func ReadValidationReport(path string) (*ValidationReport, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report ValidationReport
+	if err := json.Unmarshal(contents, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// LabelLimitChange is a label limit the settings parser set on a custom scrape job, either a field the job left unset
+// or one whose Previous value it overrode
+type LabelLimitChange struct {
+	Job      string `json:"job"`
+	Field    string `json:"field"`
+	Value    int    `json:"value"`
+	Previous *int   `json:"previous,omitempty"`
+}
+
+// WriteLabelLimitChanges writes the label limit changes as json to the given file
+func WriteLabelLimitChanges(path string, changes []LabelLimitChange) error {
+	contents, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, contents, 0644)
+}
+
+// ReadLabelLimitChanges reads the label limit changes written by the settings parser. No changes are returned when the
+// file does not exist.
+func ReadLabelLimitChanges(path string) ([]LabelLimitChange, error) {
+	contents, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []LabelLimitChange{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var changes []LabelLimitChange
+	if err := json.Unmarshal(contents, &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// LabelLimitChangeFindings returns an info finding for each label limit the settings parser set on a job of the
+// prometheus config, so the limits a job is scraped with are not a surprise
+func LabelLimitChangeFindings(prometheusConfig map[string]interface{}, changes []LabelLimitChange) []ValidationFinding {
+	findings := []ValidationFinding{}
+	scrapeConfigs, _ := prometheusConfig["scrape_configs"].([]interface{})
+	for _, change := range changes {
+		index := slices.IndexFunc(scrapeConfigs, func(scrapeConfig interface{}) bool {
+			scrapeMap, _ := scrapeConfig.(map[interface{}]interface{})
+			return scrapeMap["job_name"] == change.Job
+		})
+		// The changes of the custom jobs are not reported when only the default scrape configs are validated
+		if index < 0 {
+			continue
+		}
+		message := fmt.Sprintf("%s set to %d by the agent", change.Field, change.Value)
+		if change.Previous != nil {
+			message = fmt.Sprintf("%s of %d overridden with %d by the agent", change.Field, *change.Previous, change.Value)
+		}
+		findings = append(findings, ValidationFinding{
+			Job:      change.Job,
+			Path:     fmt.Sprintf("scrape_configs[%d].%s", index, change.Field),
+			Severity: ValidationSeverityInfo,
+			Message:  message,
+		})
+	}
+	return findings
+}
+
+// ValidatePrometheusConfig walks every scrape job in the prometheus config and returns findings for unsupported
+// sections, duplicate job names, invalid relabel regexes, $ escaping applied for the otel collector and static labels
+// exceeding the label limits of the job. It does not modify the config.
+func ValidatePrometheusConfig(prometheusConfig map[string]interface{}) []ValidationFinding {
+	findings := []ValidationFinding{}
+
+	for _, feature := range UnsupportedPrometheusFeatures(prometheusConfig) {
+		findings = append(findings, ValidationFinding{
+			Path:     feature,
+			Severity: ValidationSeverityError,
+			Message:  fmt.Sprintf("%s is not supported", feature),
+		})
+	}
+
+	scrapeConfigs, _ := prometheusConfig["scrape_configs"].([]interface{})
+	jobPaths := make(map[string]string)
+	for i, scrapeConfig := range scrapeConfigs {
+		scrapeConfigPath := fmt.Sprintf("scrape_configs[%d]", i)
+		scrapeConfig, ok := scrapeConfig.(map[interface{}]interface{})
+		if !ok {
+			findings = append(findings, ValidationFinding{
+				Path:     scrapeConfigPath,
+				Severity: ValidationSeverityError,
+				Message:  "scrape config is not a map",
+			})
+			continue
+		}
+
+		jobName, _ := scrapeConfig["job_name"].(string)
+		if jobName == "" {
+			findings = append(findings, ValidationFinding{
+				Path:     scrapeConfigPath + ".job_name",
+				Severity: ValidationSeverityError,
+				Message:  "job_name is missing",
+			})
+		} else if firstPath, exists := jobPaths[jobName]; exists {
+			findings = append(findings, ValidationFinding{
+				Job:      jobName,
+				Path:     scrapeConfigPath + ".job_name",
+				Severity: ValidationSeverityError,
+				Message:  fmt.Sprintf("duplicate job name, already defined at %s", firstPath),
+			})
+		} else {
+			jobPaths[jobName] = scrapeConfigPath
+		}
+
+		for _, relabelKey := range []string{"relabel_configs", "metric_relabel_configs"} {
+			relabelConfigs, _ := scrapeConfig[relabelKey].([]interface{})
+			for j, relabelConfig := range relabelConfigs {
+				relabelConfig, ok := relabelConfig.(map[interface{}]interface{})
+				if !ok {
+					continue
+				}
+				relabelConfigPath := fmt.Sprintf("%s.%s[%d]", scrapeConfigPath, relabelKey, j)
+				if regexString, isString := relabelConfig["regex"].(string); isString {
+					// Relabel regexes are fully anchored in prometheus
+					if _, err := regexp.Compile("^(?:" + regexString + ")$"); err != nil {
+						findings = append(findings, ValidationFinding{
+							Job:      jobName,
+							Path:     relabelConfigPath + ".regex",
+							Severity: ValidationSeverityError,
+							Message:  fmt.Sprintf("invalid regex: %v", err),
+						})
+					}
+				}
+				for _, field := range []string{"regex", "replacement"} {
+					if value, isString := relabelConfig[field].(string); isString && escapeDollarSigns(value) != value {
+						findings = append(findings, ValidationFinding{
+							Job:      jobName,
+							Path:     relabelConfigPath + "." + field,
+							Severity: ValidationSeverityInfo,
+							Message:  fmt.Sprintf("$ escaped for the otel collector: %q -> %q", value, escapeDollarSigns(value)),
+						})
+					}
+				}
+			}
+		}
+
+		findings = append(findings, staticLabelLimitFindings(jobName, scrapeConfigPath, scrapeConfig)...)
+	}
+
+	return findings
+}
+
+// staticLabelLimitFindings returns a warning for each static config whose labels exceed the label limits of the job,
+// which are the limits set in its scrape config once the scrape limits settings were merged. Every sample of the
+// targets of such a static config is over the limit, so their scrapes fail.
+func staticLabelLimitFindings(jobName, scrapeConfigPath string, scrapeConfig map[interface{}]interface{}) []ValidationFinding {
+	findings := []ValidationFinding{}
+	labelLimit, _ := scrapeConfig["label_limit"].(int)
+	nameLengthLimit, _ := scrapeConfig["label_name_length_limit"].(int)
+	valueLengthLimit, _ := scrapeConfig["label_value_length_limit"].(int)
+
+	staticConfigs, _ := scrapeConfig["static_configs"].([]interface{})
+	for i, staticConfig := range staticConfigs {
+		staticConfig, _ := staticConfig.(map[interface{}]interface{})
+		labels, _ := staticConfig["labels"].(map[interface{}]interface{})
+		labelsPath := fmt.Sprintf("%s.static_configs[%d].labels", scrapeConfigPath, i)
+		if labelLimit > 0 && len(labels) > labelLimit {
+			findings = append(findings, ValidationFinding{
+				Job:      jobName,
+				Path:     labelsPath,
+				Severity: ValidationSeverityWarning,
+				Message:  fmt.Sprintf("%d static labels exceed the label_limit of %d", len(labels), labelLimit),
+			})
+		}
+		for _, name := range slices.Sorted(maps.Keys(stringKeys(labels))) {
+			if nameLengthLimit > 0 && len(name) > nameLengthLimit {
+				findings = append(findings, ValidationFinding{
+					Job:      jobName,
+					Path:     labelsPath + "." + name,
+					Severity: ValidationSeverityWarning,
+					Message:  fmt.Sprintf("label name exceeds the label_name_length_limit of %d", nameLengthLimit),
+				})
+			}
+			if value := fmt.Sprint(labels[name]); valueLengthLimit > 0 && len(value) > valueLengthLimit {
+				findings = append(findings, ValidationFinding{
+					Job:      jobName,
+					Path:     labelsPath + "." + name,
+					Severity: ValidationSeverityWarning,
+					Message:  fmt.Sprintf("label value exceeds the label_value_length_limit of %d", valueLengthLimit),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// stringKeys returns the values of a yaml map keyed by their string keys
+func stringKeys(m map[interface{}]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		if name, ok := key.(string); ok {
+			result[name] = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,167 @@
+package shared
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func findingsByPath(findings []ValidationFinding) map[string]ValidationFinding {
+	byPath := make(map[string]ValidationFinding)
+	for _, finding := range findings {
+		byPath[finding.Path] = finding
+	}
+	return byPath
+}
+
+func TestValidatePrometheusConfig(t *testing.T) {
+	promConfig := parsePromConfig(t, `alerting:
+  alertmanagers: []
+scrape_configs:
+- job_name: app
+  label_limit: 63
+  label_name_length_limit: 511
+  label_value_length_limit: 1023
+  relabel_configs:
+  - source_labels: [__address__]
+    regex: (.*
+    target_label: instance
+  - source_labels: [__address__]
+    regex: (.*)
+    replacement: $1
+    target_label: instance
+- job_name: app
+`)
+
+	findings := ValidatePrometheusConfig(promConfig)
+	byPath := findingsByPath(findings)
+
+	expected := map[string]string{
+		"alerting": ValidationSeverityError,
+		"scrape_configs[0].relabel_configs[0].regex":       ValidationSeverityError,
+		"scrape_configs[0].relabel_configs[1].replacement": ValidationSeverityInfo,
+		"scrape_configs[1].job_name":                       ValidationSeverityError,
+	}
+	for path, severity := range expected {
+		finding, ok := byPath[path]
+		if !ok {
+			t.Errorf("expected a finding for %s, got: %+v", path, findings)
+			continue
+		}
+		if finding.Severity != severity {
+			t.Errorf("expected severity %s for %s, got %s", severity, path, finding.Severity)
+		}
+	}
+	if len(findings) != len(expected) {
+		t.Errorf("expected %d findings, got %d: %+v", len(expected), len(findings), findings)
+	}
+	if byPath["scrape_configs[1].job_name"].Job != "app" {
+		t.Errorf("expected the duplicate job finding to have job app, got %q", byPath["scrape_configs[1].job_name"].Job)
+	}
+}
+
+func TestValidatePrometheusConfigStaticLabelLimits(t *testing.T) {
+	promConfig := parsePromConfig(t, `scrape_configs:
+- job_name: within-limits
+  label_limit: 2
+  static_configs:
+  - targets: [localhost:9090]
+    labels:
+      team: a
+- job_name: over-limits
+  label_limit: 2
+  label_name_length_limit: 5
+  label_value_length_limit: 3
+  static_configs:
+  - targets: [localhost:9090]
+    labels:
+      team: abcd
+      region: a
+      zone: b
+- job_name: no-limits
+  static_configs:
+  - targets: [localhost:9090]
+    labels:
+      team: abcd
+      region: a
+      zone: b
+`)
+
+	findings := ValidatePrometheusConfig(promConfig)
+	expected := map[string]string{
+		"scrape_configs[1].static_configs[0].labels":        "3 static labels exceed the label_limit of 2",
+		"scrape_configs[1].static_configs[0].labels.region": "label name exceeds the label_name_length_limit of 5",
+		"scrape_configs[1].static_configs[0].labels.team":   "label value exceeds the label_value_length_limit of 3",
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("expected %d findings, got %d: %+v", len(expected), len(findings), findings)
+	}
+	for _, finding := range findings {
+		if finding.Job != "over-limits" || finding.Severity != ValidationSeverityWarning || expected[finding.Path] != finding.Message {
+			t.Errorf("unexpected finding: %+v", finding)
+		}
+	}
+}
+
+func TestValidationReportRoundTrip(t *testing.T) {
+	report := &ValidationReport{Valid: true}
+	report.AddFinding(ValidationFinding{Job: "app", Severity: ValidationSeverityInfo, Message: "info"})
+	if !report.Valid {
+		t.Fatal("expected report to stay valid after an info finding")
+	}
+	report.AddFinding(ValidationFinding{Job: "app", Severity: ValidationSeverityError, Message: "error"})
+	if report.Valid {
+		t.Fatal("expected report to be invalid after an error finding")
+	}
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := WriteValidationReport(path, report); err != nil {
+		t.Fatalf("failed to write report: %v", err)
+	}
+	readReport, err := ReadValidationReport(path)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	if readReport.Valid || len(readReport.Findings) != 2 {
+		t.Errorf("unexpected report after round trip: %+v", readReport)
+	}
+}
+
+func TestLabelLimitChangeFindings(t *testing.T) {
+	promConfig := parsePromConfig(t, `scrape_configs:
+- job_name: stamped
+- job_name: overridden
+  label_limit: 100
+`)
+	previous := 100
+	changes := []LabelLimitChange{
+		{Job: "stamped", Field: "label_name_length_limit", Value: CustomScrapeLabelNameLengthLimit},
+		{Job: "overridden", Field: "label_limit", Value: 10, Previous: &previous},
+		{Job: "removed", Field: "label_limit", Value: CustomScrapeLabelLimit},
+	}
+	path := filepath.Join(t.TempDir(), "label_limit_changes.json")
+	if err := WriteLabelLimitChanges(path, changes); err != nil {
+		t.Fatal(err)
+	}
+	readChanges, err := ReadLabelLimitChanges(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byPath := findingsByPath(LabelLimitChangeFindings(promConfig, readChanges))
+	expected := map[string]string{
+		"scrape_configs[0].label_name_length_limit": "label_name_length_limit set to 511 by the agent",
+		"scrape_configs[1].label_limit":             "label_limit of 100 overridden with 10 by the agent",
+	}
+	if len(byPath) != len(expected) {
+		t.Errorf("expected %d findings, got %+v", len(expected), byPath)
+	}
+	for path, message := range expected {
+		if finding := byPath[path]; finding.Message != message || finding.Severity != ValidationSeverityInfo {
+			t.Errorf("expected info finding %q for %s, got %+v", message, path, finding)
+		}
+	}
+
+	if changes, err := ReadLabelLimitChanges(filepath.Join(t.TempDir(), "missing.json")); err != nil || len(changes) != 0 {
+		t.Errorf("expected no changes for a missing file, got %+v, %v", changes, err)
+	}
+}
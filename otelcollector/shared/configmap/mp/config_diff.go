@@ -0,0 +1,107 @@
+package configmapsettings
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/prometheus-collector/shared"
+	"gopkg.in/yaml.v2"
+)
+
+// effectiveScrapeConfigsFile keeps the scrape configs of the effective config in the last known good config dir, which
+// survives the container restarts done to apply a configmap change, so the next config can be compared with it
+const effectiveScrapeConfigsFile = "effective-scrape-configs.yml"
+
+// effectiveScrapeConfigs are the scrape configs of the collector config in use and the config version they are from
+type effectiveScrapeConfigs struct {
+	ConfigVersion string        `yaml:"configVersion"`
+	ScrapeConfigs []interface{} `yaml:"scrape_configs"`
+}
+
+// recordEffectiveConfigDiff logs the difference between the scrape jobs of the collector config in use and the ones
+// of the previous effective config, then keeps them for the next comparison. Nothing is logged the first time or when
+// the jobs did not change.
+func (p *configPipeline) recordEffectiveConfigDiff(collectorConfig string) {
+	scrapeConfigs, err := p.collectorScrapeConfigs(collectorConfig)
+	if err != nil {
+		log.Printf("Error reading the scrape configs of %s: %v. The changes to the effective config will not be logged\n", collectorConfig, err)
+		return
+	}
+	current := effectiveScrapeConfigs{ConfigVersion: p.env.Getenv("AZMON_AGENT_CFG_FILE_VERSION"), ScrapeConfigs: scrapeConfigs}
+
+	snapshotPath := filepath.Join(p.paths.lastKnownGoodConfigDir, effectiveScrapeConfigsFile)
+	previous, err := p.readEffectiveScrapeConfigs(snapshotPath)
+	if err != nil {
+		log.Printf("Error reading the previous effective scrape configs: %v\n", err)
+	} else if previous != nil {
+		if diff, err := shared.DiffScrapeConfigs(previous.ScrapeConfigs, current.ScrapeConfigs); err != nil {
+			log.Printf("Error comparing the effective scrape configs: %v\n", err)
+		} else if !diff.IsEmpty() {
+			diff.ConfigVersion, diff.PreviousConfigVersion = current.ConfigVersion, previous.ConfigVersion
+			logConfigDiff(diff)
+		}
+	}
+
+	if err := p.fs.MkdirAll(p.paths.lastKnownGoodConfigDir, fs.FileMode(0755)); err != nil {
+		log.Printf("Error creating the dir %s: %v\n", p.paths.lastKnownGoodConfigDir, err)
+		return
+	}
+	contents, err := yaml.Marshal(current)
+	if err == nil {
+		err = p.fs.WriteFile(snapshotPath, contents, fs.FileMode(0644))
+	}
+	if err != nil {
+		log.Printf("Error saving the effective scrape configs: %v\n", err)
+	}
+}
+
+// logConfigDiff logs the diff as one JSON line for fluent-bit to send as a telemetry event, and as a summary
+func logConfigDiff(diff *shared.ConfigDiff) {
+	contents, err := json.Marshal(diff)
+	if err != nil {
+		log.Printf("Error marshaling the effective config diff: %v\n", err)
+		return
+	}
+	log.Println(shared.ConfigDiffLogPrefix + string(contents))
+	shared.EchoWarning(fmt.Sprintf("The effective scrape config changed from config version %s to %s - %s",
+		diff.PreviousConfigVersion, diff.ConfigVersion, diff.Summary()))
+}
+
+// collectorScrapeConfigs returns the scrape configs of the prometheus receiver of a collector config, none when the
+// collector config does not exist
+func (p *configPipeline) collectorScrapeConfigs(collectorConfig string) ([]interface{}, error) {
+	contents, err := p.fs.ReadFile(collectorConfig)
+	if os.IsNotExist(err) {
+		return []interface{}{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var otelConfig shared.OtelConfig
+	if err := yaml.Unmarshal(contents, &otelConfig); err != nil {
+		return nil, err
+	}
+	scrapeConfigs, _ := otelConfig.Receivers.Prometheus.Config["scrape_configs"].([]interface{})
+	if scrapeConfigs == nil {
+		scrapeConfigs = []interface{}{}
+	}
+	return scrapeConfigs, nil
+}
+
+// readEffectiveScrapeConfigs returns the saved effective scrape configs, or nil when there are none
+func (p *configPipeline) readEffectiveScrapeConfigs(path string) (*effectiveScrapeConfigs, error) {
+	contents, err := p.fs.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var scrapeConfigs effectiveScrapeConfigs
+	if err := yaml.Unmarshal(contents, &scrapeConfigs); err != nil {
+		return nil, err
+	}
+	return &scrapeConfigs, nil
+}
@@ -0,0 +1,71 @@
+package configmapsettings
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus-collector/shared"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EffectiveConfigDiff", func() {
+	var (
+		workDir         string
+		collectorConfig string
+		mapEnv          *MapEnvironment
+	)
+
+	writeCollectorConfig := func(scrapeConfigs string) {
+		Expect(os.WriteFile(collectorConfig, []byte("receivers:\n  prometheus:\n    config:\n      scrape_configs:\n"+scrapeConfigs), 0644)).To(Succeed())
+	}
+
+	loggedDiff := func(output string) *shared.ConfigDiff {
+		_, line, found := strings.Cut(output, shared.ConfigDiffLogPrefix)
+		if !found {
+			return nil
+		}
+		var diff shared.ConfigDiff
+		Expect(json.NewDecoder(strings.NewReader(line)).Decode(&diff)).To(Succeed())
+		return &diff
+	}
+
+	BeforeEach(func() {
+		workDir = GinkgoT().TempDir()
+		testPipeline.paths.lastKnownGoodConfigDir = filepath.Join(workDir, "last-known-good")
+		mapEnv = NewMapEnvironment(map[string]string{"AZMON_AGENT_CFG_FILE_VERSION": "ver1"})
+		testPipeline.env = mapEnv
+		collectorConfig = filepath.Join(workDir, "collector-config.yml")
+	})
+
+	It("should log the jobs that changed since the previous effective config", func() {
+		writeCollectorConfig("        - job_name: kubelet\n          scrape_interval: 30s\n        - job_name: removed-job\n          scrape_interval: 30s\n")
+		Expect(captureOutput(func() { testPipeline.recordEffectiveConfigDiff(collectorConfig) })).NotTo(ContainSubstring(shared.ConfigDiffLogPrefix))
+
+		mapEnv.Setenv("AZMON_AGENT_CFG_FILE_VERSION", "ver2", false)
+		writeCollectorConfig("        - job_name: kubelet\n          scrape_interval: 15s\n        - job_name: added-job\n          scrape_interval: 30s\n")
+		diff := loggedDiff(captureOutput(func() { testPipeline.recordEffectiveConfigDiff(collectorConfig) }))
+		Expect(diff).To(Equal(&shared.ConfigDiff{
+			ConfigVersion:         "ver2",
+			PreviousConfigVersion: "ver1",
+			Additions:             []string{"added-job"},
+			Removals:              []string{"removed-job"},
+			Changes: []shared.ScrapeJobChange{
+				{Job: "kubelet", Fields: []shared.FieldChange{{Field: "scrape_interval", Previous: "30s", Current: "15s"}}},
+			},
+		}))
+
+		Expect(captureOutput(func() { testPipeline.recordEffectiveConfigDiff(collectorConfig) })).NotTo(ContainSubstring(shared.ConfigDiffLogPrefix))
+	})
+
+	It("should log the removal of all the jobs when there is no collector config", func() {
+		writeCollectorConfig("        - job_name: kubelet\n")
+		testPipeline.recordEffectiveConfigDiff(collectorConfig)
+
+		output := captureOutput(func() { testPipeline.recordEffectiveConfigDiff(filepath.Join(workDir, "missing-collector-config.yml")) })
+		Expect(output).To(ContainSubstring(shared.ConfigDiffLogPrefix + `{"configVersion":"ver1","previousConfigVersion":"ver1","removals":["kubelet"]}`))
+	})
+})
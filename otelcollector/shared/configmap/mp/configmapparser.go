@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/prometheus-collector/shared"
@@ -15,16 +16,16 @@ const (
 	defaultConfigFileVersion   = "ver1"
 )
 
-func setConfigSchemaVersionEnv() {
-	fileInfo, err := os.Stat(schemaVersionFile)
+func (p *configPipeline) setConfigSchemaVersionEnv() {
+	fileInfo, err := p.fs.Stat(p.paths.schemaVersionFile)
 	if err != nil || fileInfo.Size() == 0 {
-		shared.SetEnvAndSourceBashrcOrPowershell("AZMON_AGENT_CFG_SCHEMA_VERSION", defaultConfigSchemaVersion, true)
+		p.env.Setenv("AZMON_AGENT_CFG_SCHEMA_VERSION", defaultConfigSchemaVersion, true)
 		return
 	}
-	content, err := os.ReadFile(schemaVersionFile)
+	content, err := p.fs.ReadFile(p.paths.schemaVersionFile)
 	if err != nil {
 		shared.EchoError("Error reading schema version file:" + err.Error())
-		shared.SetEnvAndSourceBashrcOrPowershell("AZMON_AGENT_CFG_SCHEMA_VERSION", defaultConfigSchemaVersion, true)
+		p.env.Setenv("AZMON_AGENT_CFG_SCHEMA_VERSION", defaultConfigSchemaVersion, true)
 		return
 	}
 	trimmedContent := strings.TrimSpace(string(content))
@@ -32,19 +33,19 @@ func setConfigSchemaVersionEnv() {
 	if len(configSchemaVersion) > 10 {
 		configSchemaVersion = configSchemaVersion[:10]
 	}
-	shared.SetEnvAndSourceBashrcOrPowershell("AZMON_AGENT_CFG_SCHEMA_VERSION", configSchemaVersion, true)
+	p.env.Setenv("AZMON_AGENT_CFG_SCHEMA_VERSION", configSchemaVersion, true)
 }
 
-func setConfigFileVersionEnv() {
-	fileInfo, err := os.Stat(configVersionFile)
+func (p *configPipeline) setConfigFileVersionEnv() {
+	fileInfo, err := p.fs.Stat(p.paths.configVersionFile)
 	if err != nil || fileInfo.Size() == 0 {
-		shared.SetEnvAndSourceBashrcOrPowershell("AZMON_AGENT_CFG_FILE_VERSION", defaultConfigFileVersion, true)
+		p.env.Setenv("AZMON_AGENT_CFG_FILE_VERSION", defaultConfigFileVersion, true)
 		return
 	}
-	content, err := os.ReadFile(configVersionFile)
+	content, err := p.fs.ReadFile(p.paths.configVersionFile)
 	if err != nil {
 		shared.EchoError("Error reading config version file:" + err.Error())
-		shared.SetEnvAndSourceBashrcOrPowershell("AZMON_AGENT_CFG_FILE_VERSION", defaultConfigFileVersion, true)
+		p.env.Setenv("AZMON_AGENT_CFG_FILE_VERSION", defaultConfigFileVersion, true)
 		return
 	}
 	trimmedContent := strings.TrimSpace(string(content))
@@ -52,29 +53,29 @@ func setConfigFileVersionEnv() {
 	if len(configFileVersion) > 10 {
 		configFileVersion = configFileVersion[:10]
 	}
-	shared.SetEnvAndSourceBashrcOrPowershell("AZMON_AGENT_CFG_FILE_VERSION", configFileVersion, true)
+	p.env.Setenv("AZMON_AGENT_CFG_FILE_VERSION", configFileVersion, true)
 }
 
-func parseSettingsForPodAnnotations(metricsConfigBySection map[string]map[string]string) {
+func (p *configPipeline) parseSettingsForPodAnnotations(metricsConfigBySection map[string]map[string]string) {
 	shared.EchoSectionDivider("Start Processing - parseSettingsForPodAnnotations")
-	if err := configurePodAnnotationSettings(metricsConfigBySection); err != nil {
+	if err := p.configurePodAnnotationSettings(metricsConfigBySection); err != nil {
 		log.Printf("%v\n", err)
 		return
 	}
-	handlePodAnnotationsFile(podAnnotationEnvVarPath)
+	p.handlePodAnnotationsFile(p.paths.podAnnotationEnvVarPath)
 	shared.EchoSectionDivider("End Processing - parseSettingsForPodAnnotations")
 }
 
-func handlePodAnnotationsFile(filename string) {
+func (p *configPipeline) handlePodAnnotationsFile(filename string) {
 	// Check if the file exists
-	_, e := os.Stat(filename)
+	_, e := p.fs.Stat(filename)
 	if os.IsNotExist(e) {
 		log.Printf("File does not exist: %s\n", filename)
 		return
 	}
 
 	// Open the file for reading
-	file, err := os.Open(filename)
+	file, err := p.fs.Open(filename)
 	if err != nil {
 		log.Printf("Error opening file: %s\n", err)
 		return
@@ -95,9 +96,9 @@ func handlePodAnnotationsFile(filename string) {
 		value := line[index+1:]
 
 		if key == "AZMON_PROMETHEUS_POD_ANNOTATION_NAMESPACES_REGEX" {
-			shared.SetEnvAndSourceBashrcOrPowershell(key, value, false)
+			p.env.Setenv(key, value, false)
 		} else {
-			shared.SetEnvAndSourceBashrcOrPowershell(key, value, false)
+			p.env.Setenv(key, value, false)
 		}
 
 	}
@@ -107,80 +108,83 @@ func handlePodAnnotationsFile(filename string) {
 	}
 }
 
-func parsePrometheusCollectorConfig(metricsConfigBySection map[string]map[string]string) {
+func (p *configPipeline) parsePrometheusCollectorConfig(metricsConfigBySection map[string]map[string]string) {
 	shared.EchoSectionDivider("Start Processing - parsePrometheusCollectorConfig")
-	parseConfigAndSetEnvInFile(metricsConfigBySection)
-	handleEnvFileError(collectorSettingsEnvVarPath)
+	p.parseConfigAndSetEnvInFile(metricsConfigBySection)
+	p.handleEnvFileError(p.paths.collectorSettingsEnvVarPath)
 	shared.EchoSectionDivider("End Processing - parsePrometheusCollectorConfig")
 }
 
-func parseDefaultScrapeSettings(metricsConfigBySection map[string]map[string]string) {
+func (p *configPipeline) parseDefaultScrapeSettings(metricsConfigBySection map[string]map[string]string) {
 	shared.EchoSectionDivider("Start Processing - parseDefaultScrapeSettings")
-	tomlparserDefaultScrapeSettings(metricsConfigBySection)
-	handleEnvFileError(defaultSettingsEnvVarPath)
+	p.tomlparserDefaultScrapeSettings(metricsConfigBySection)
+	p.handleEnvFileError(p.paths.defaultSettingsEnvVarPath)
 	shared.EchoSectionDivider("End Processing - parseDefaultScrapeSettings")
 }
 
-func parseDebugModeSettings(metricsConfigBySection map[string]map[string]string) {
+func (p *configPipeline) parseDebugModeSettings(metricsConfigBySection map[string]map[string]string) {
 	shared.EchoSectionDivider("Start Processing - parseDebugModeSettings")
-	if err := ConfigureDebugModeSettings(metricsConfigBySection); err != nil {
+	if err := p.ConfigureDebugModeSettings(metricsConfigBySection); err != nil {
 		shared.EchoError(err.Error())
 		return
 	}
-	handleEnvFileError(debugModeEnvVarPath)
+	p.handleEnvFileError(p.paths.debugModeEnvVarPath)
 	shared.EchoSectionDivider("End Processing - parseDebugModeSettings")
 }
 
-func parseOpentelemetryMetricsSettings(metricsConfigBySection map[string]map[string]string) {
+func (p *configPipeline) parseOpentelemetryMetricsSettings(metricsConfigBySection map[string]map[string]string) {
 	shared.EchoSectionDivider("Start Processing - parseOpentelemetryMetricsSettings")
-	if err := ConfigureOpentelemetryMetricsSettings(metricsConfigBySection); err != nil {
+	if err := p.ConfigureOpentelemetryMetricsSettings(metricsConfigBySection); err != nil {
 		shared.EchoError(err.Error())
 		return
 	}
-	handleEnvFileError(opentelemetryMetricsEnvVarPath)
+	p.handleEnvFileError(p.paths.opentelemetryMetricsEnvVarPath)
 	shared.EchoSectionDivider("End Processing - parseOpentelemetryMetricsSettings")
 }
 
-func parseKSMSettings(metricsConfigBySection map[string]map[string]string) {
+func (p *configPipeline) parseKSMSettings(metricsConfigBySection map[string]map[string]string) {
 	shared.EchoSectionDivider("Start Processing - parseKSMSettings")
 	if _, ok := metricsConfigBySection["ksm-config"]; ok {
 		log.Println("ksm-config section found in metricsConfigBySection")
-		file, err := os.Create(ksmConfigEnvVarPath)
+		file, err := p.fs.Create(p.paths.ksmConfigEnvVarPath)
 		if err != nil {
 			shared.EchoError(fmt.Sprintf("Exception while opening file for writing prometheus-collector config environment variables: %v\n", err))
 			return
 		}
 		defer file.Close()
 
-		file.WriteString(fmt.Sprintf("AZMON_KSM_CONFIG_ENABLED=%v\n", "true"))
+		fmt.Fprintf(file, "AZMON_KSM_CONFIG_ENABLED=%v\n", "true")
 		log.Printf("Setting AZMON_KSM_CONFIG_ENABLED environment variable: %v\n", "true")
-		handleEnvFileError(ksmConfigEnvVarPath)
+		p.handleEnvFileError(p.paths.ksmConfigEnvVarPath)
 	} else {
 		log.Println("ksm-config section NOT found in metricsConfigBySection")
 	}
 }
 
-func handleEnvFileError(filename string) {
-	err := shared.SetEnvVarsFromFile(filename)
+func (p *configPipeline) handleEnvFileError(filename string) {
+	err := p.setEnvVarsFromFile(filename)
 	if err != nil {
 		log.Printf("Error when setting env for %s: %v\n", filename, err)
 	}
 }
 
-func Configmapparser() {
-	setConfigFileVersionEnv()
-	setConfigSchemaVersionEnv()
+// parseSettingsAndMergeConfigs parses the settings configmap, sets the resulting environment variables and
+// writes the merged prometheus config. It does not run the config validator. The parsed settings sections are returned.
+func (p *configPipeline) parseSettingsAndMergeConfigs() map[string]map[string]string {
+	p.setConfigFileVersionEnv()
+	p.setConfigSchemaVersionEnv()
+	p.loadDefaultTargets()
 
 	var metricsConfigBySection map[string]map[string]string
 	var err error
-	if os.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION") == "v2" {
-		filePaths := []string{"/etc/config/settings/cluster-metrics", "/etc/config/settings/prometheus-collector-settings"}
+	if p.env.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION") == "v2" {
+		filePaths := []string{filepath.Join(p.paths.configMapSettingsDir, "cluster-metrics"), filepath.Join(p.paths.configMapSettingsDir, "prometheus-collector-settings")}
 		metricsConfigBySection, err = shared.ParseMetricsFiles(filePaths)
 		if err != nil {
 			log.Printf("Using defaults as error parsing files: %v\n", err)
 		}
-	} else if os.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION") == "v1" {
-		configDir := "/etc/config/settings"
+	} else if p.env.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION") == "v1" {
+		configDir := p.paths.configMapSettingsDir
 		metricsConfigBySection, err = shared.ParseV1Config(configDir)
 		if err != nil {
 			log.Printf("Using defaults as error parsing config: %v\n", err)
@@ -191,10 +195,10 @@ func Configmapparser() {
 
 	// Detect configmap presence and set CONFIGMAP_VERSION
 	configmapVer := "not_present"
-	if os.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION") == "v2" {
+	if p.env.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION") == "v2" {
 		configmapVer = "v2"
-	} else if os.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION") == "v1" {
-		files, err := os.ReadDir("/etc/config/settings")
+	} else if p.env.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION") == "v1" {
+		files, err := p.fs.ReadDir(p.paths.configMapSettingsDir)
 		if err == nil {
 			for _, file := range files {
 				if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
@@ -205,75 +209,111 @@ func Configmapparser() {
 			}
 		}
 	}
-	shared.SetEnvAndSourceBashrcOrPowershell("CONFIGMAP_VERSION", configmapVer, true)
+	p.env.Setenv("CONFIGMAP_VERSION", configmapVer, true)
 
-	parseSettingsForPodAnnotations(metricsConfigBySection)
-	parsePrometheusCollectorConfig(metricsConfigBySection)
-	parseDefaultScrapeSettings(metricsConfigBySection)
-	parseDebugModeSettings(metricsConfigBySection)
-	parseOpentelemetryMetricsSettings(metricsConfigBySection)
-	parseKSMSettings(metricsConfigBySection)
+	p.parseCustomDefaultTargets(metricsConfigBySection)
+	p.parseSettingsForPodAnnotations(metricsConfigBySection)
+	p.parsePrometheusCollectorConfig(metricsConfigBySection)
+	p.parseDefaultScrapeSettings(metricsConfigBySection)
+	p.parseDebugModeSettings(metricsConfigBySection)
+	p.parseOpentelemetryMetricsSettings(metricsConfigBySection)
+	p.parseKSMSettings(metricsConfigBySection)
 
-	tomlparserTargetsMetricsKeepList(metricsConfigBySection)
-	tomlparserScrapeInterval(metricsConfigBySection)
+	p.tomlparserTargetsMetricsKeepList(metricsConfigBySection)
+	p.tomlparserScrapeInterval(metricsConfigBySection)
+	p.tomlparserScrapeLimits(metricsConfigBySection)
+	p.tomlparserExternalLabels(metricsConfigBySection)
+	p.tomlparserCollectorProcessors()
 
-	azmonOperatorEnabled := os.Getenv("AZMON_OPERATOR_ENABLED")
-	containerType := os.Getenv("CONTAINER_TYPE")
+	azmonOperatorEnabled := p.env.Getenv("AZMON_OPERATOR_ENABLED")
+	containerType := p.env.Getenv("CONTAINER_TYPE")
 
 	if azmonOperatorEnabled == "true" || containerType == "ConfigReaderSidecar" {
-		prometheusConfigMerger(true)
+		p.prometheusConfigMerger(true)
 	} else {
-		prometheusConfigMerger(false)
+		p.prometheusConfigMerger(false)
 	}
+	return metricsConfigBySection
+}
+
+func (a *AgentConfigPipeline) Configmapparser() {
+	a.pipeline.configmapparser()
+}
+
+func (p *configPipeline) configmapparser() {
+	p.saveReloadBaseline()
+	p.appliedSections = p.parseSettingsAndMergeConfigs()
+	defer func() {
+		p.startupEnv = p.env.StartupVars()
+		p.appliedEnv = p.env.Vars()
+	}()
 
-	shared.SetEnvAndSourceBashrcOrPowershell("AZMON_INVALID_CUSTOM_PROMETHEUS_CONFIG", "false", true)
-	shared.SetEnvAndSourceBashrcOrPowershell("CONFIG_VALIDATOR_RUNNING_IN_AGENT", "true", true)
+	p.env.Setenv("AZMON_INVALID_CUSTOM_PROMETHEUS_CONFIG", "false", true)
+	p.env.Setenv(usingLastKnownGoodConfigEnvVar, "false", true)
+	p.env.Setenv("CONFIG_VALIDATOR_RUNNING_IN_AGENT", "true", true)
 
 	// Running promconfigvalidator if promMergedConfig.yml exists
-	if shared.FileExists("/opt/promMergedConfig.yml") {
-		if !shared.FileExists("/opt/microsoft/otelcollector/collector-config.yml") {
-			err := shared.StartCommandAndWait("/opt/promconfigvalidator",
-				"--config", "/opt/promMergedConfig.yml",
-				"--output", "/opt/microsoft/otelcollector/collector-config.yml",
-				"--otelTemplate", "/opt/microsoft/otelcollector/collector-config-template.yml",
+	if fileExists(p.fs, p.paths.promMergedConfigPath) {
+		if !fileExists(p.fs, p.paths.collectorConfigPath) {
+			err := shared.StartCommandAndWait(p.paths.promConfigValidatorPath,
+				"--config", p.paths.promMergedConfigPath,
+				"--output", p.paths.collectorConfigPath,
+				"--otelTemplate", p.paths.collectorConfigTemplatePath,
+				"--externalLabels", p.paths.externalLabelsEnvVarPath,
+				"--processors", p.paths.collectorProcessorsEnvVarPath,
+				"--labelLimitChanges", p.paths.labelLimitChangesEnvVarPath,
 			)
 			if err != nil {
 				log.Println("prom-config-validator::Prometheus custom config validation failed. The custom config will not be used")
 				log.Printf("Command execution failed: %v\n", err)
-				shared.SetEnvAndSourceBashrcOrPowershell("AZMON_INVALID_CUSTOM_PROMETHEUS_CONFIG", "true", true)
-				if shared.FileExists(mergedDefaultConfigPath) {
-					log.Println("prom-config-validator::Running validator on just default scrape configs")
-					shared.StartCommandAndWait("/opt/promconfigvalidator", "--config", mergedDefaultConfigPath, "--output", "/opt/collector-config-with-defaults.yml", "--otelTemplate", "/opt/microsoft/otelcollector/collector-config-template.yml")
-					if !shared.FileExists("/opt/collector-config-with-defaults.yml") {
-						log.Println("prom-config-validator::Prometheus default scrape config validation failed. No scrape configs will be used")
-					} else {
-						shared.CopyFile("/opt/collector-config-with-defaults.yml", "/opt/microsoft/otelcollector/collector-config-default.yml")
+				p.env.Setenv("AZMON_INVALID_CUSTOM_PROMETHEUS_CONFIG", "true", true)
+				if p.restoreLastKnownGoodConfig(p.paths.collectorConfigPath) {
+					p.env.Setenv("AZMON_SET_GLOBAL_SETTINGS", "true", true)
+				} else {
+					if fileExists(p.fs, p.paths.mergedDefaultConfigPath) {
+						log.Println("prom-config-validator::Running validator on just default scrape configs")
+						// The report of the default scrape configs goes to another file, to keep the one of the rejected custom config
+						shared.StartCommandAndWait(p.paths.promConfigValidatorPath, "--config", p.paths.mergedDefaultConfigPath, "--output", p.paths.collectorConfigWithDefaultsPath, "--otelTemplate", p.paths.collectorConfigTemplatePath, "--externalLabels", p.paths.externalLabelsEnvVarPath, "--processors", p.paths.collectorProcessorsEnvVarPath, "--reportFile", shared.PromConfigValidatorDefaultsReportPath)
+						if !fileExists(p.fs, p.paths.collectorConfigWithDefaultsPath) {
+							log.Println("prom-config-validator::Prometheus default scrape config validation failed. No scrape configs will be used")
+						} else {
+							copyFile(p.fs, p.paths.collectorConfigWithDefaultsPath, p.paths.collectorConfigDefaultPath)
+						}
 					}
+					p.env.Setenv("AZMON_USE_DEFAULT_PROMETHEUS_CONFIG", "true", true)
 				}
-				shared.SetEnvAndSourceBashrcOrPowershell("AZMON_USE_DEFAULT_PROMETHEUS_CONFIG", "true", true)
 			} else {
-				shared.SetEnvAndSourceBashrcOrPowershell("AZMON_SET_GLOBAL_SETTINGS", "true", true)
+				p.env.Setenv("AZMON_SET_GLOBAL_SETTINGS", "true", true)
+				p.saveLastKnownGoodConfig(p.paths.collectorConfigPath)
 			}
 		}
-	} else if _, err := os.Stat(mergedDefaultConfigPath); err == nil {
+	} else if _, err := p.fs.Stat(p.paths.mergedDefaultConfigPath); err == nil {
 		log.Println("prom-config-validator::No custom prometheus config found. Only using default scrape configs")
-		err := shared.StartCommandAndWait("/opt/promconfigvalidator", "--config", mergedDefaultConfigPath, "--output", "/opt/collector-config-with-defaults.yml", "--otelTemplate", "/opt/microsoft/otelcollector/collector-config-template.yml")
+		p.clearLastKnownGoodConfig()
+		err := shared.StartCommandAndWait(p.paths.promConfigValidatorPath, "--config", p.paths.mergedDefaultConfigPath, "--output", p.paths.collectorConfigWithDefaultsPath, "--otelTemplate", p.paths.collectorConfigTemplatePath, "--externalLabels", p.paths.externalLabelsEnvVarPath, "--processors", p.paths.collectorProcessorsEnvVarPath)
 		if err != nil {
 			log.Println("prom-config-validator::Prometheus default scrape config validation failed. No scrape configs will be used")
 			log.Printf("Command execution failed: %v\n", err)
 		} else {
 			log.Println("prom-config-validator::Prometheus default scrape config validation succeeded, using this as collector config")
-			shared.CopyFile("/opt/collector-config-with-defaults.yml", "/opt/microsoft/otelcollector/collector-config-default.yml")
+			copyFile(p.fs, p.paths.collectorConfigWithDefaultsPath, p.paths.collectorConfigDefaultPath)
 		}
-		shared.SetEnvAndSourceBashrcOrPowershell("AZMON_USE_DEFAULT_PROMETHEUS_CONFIG", "true", true)
+		p.env.Setenv("AZMON_USE_DEFAULT_PROMETHEUS_CONFIG", "true", true)
 	} else {
 		// This else block is needed, when there is no custom config mounted as config map or default configs enabled
 		log.Println("prom-config-validator::No custom config via configmap or default scrape configs enabled.")
-		shared.SetEnvAndSourceBashrcOrPowershell("AZMON_USE_DEFAULT_PROMETHEUS_CONFIG", "true", true)
+		p.clearLastKnownGoodConfig()
+		p.env.Setenv("AZMON_USE_DEFAULT_PROMETHEUS_CONFIG", "true", true)
+	}
+
+	if p.env.Getenv("AZMON_USE_DEFAULT_PROMETHEUS_CONFIG") == "true" {
+		p.recordEffectiveConfigDiff(p.paths.collectorConfigDefaultPath)
+	} else {
+		p.recordEffectiveConfigDiff(p.paths.collectorConfigPath)
 	}
 
-	if _, err := os.Stat("/opt/microsoft/prom_config_validator_env_var"); err == nil {
-		file, err := os.Open("/opt/microsoft/prom_config_validator_env_var")
+	if _, err := p.fs.Stat(p.paths.promConfigValidatorEnvVarPath); err == nil {
+		file, err := p.fs.Open(p.paths.promConfigValidatorEnvVarPath)
 		if err != nil {
 			shared.EchoError("Error opening file:" + err.Error())
 			return
@@ -281,7 +321,7 @@ func Configmapparser() {
 		defer file.Close()
 
 		// Create or truncate envvars.env file
-		envFile, err := os.Create("/opt/envvars.env")
+		envFile, err := p.fs.Create(p.paths.envVarsFilePath)
 		if err != nil {
 			shared.EchoError("Error creating env file:" + err.Error())
 			return
@@ -295,7 +335,7 @@ func Configmapparser() {
 			if len(parts) == 2 {
 				key := parts[0]
 				value := parts[1]
-				shared.SetEnvAndSourceBashrcOrPowershell(key, value, true)
+				p.env.Setenv(key, value, true)
 
 				// Write to envvars.env
 				fmt.Fprintf(envFile, "%s=%s\n", key, value)
@@ -307,18 +347,18 @@ func Configmapparser() {
 		}
 
 		// Source prom_config_validator_env_var
-		filename := "/opt/microsoft/prom_config_validator_env_var"
-		err = shared.SetEnvVarsFromFile(filename)
+		filename := p.paths.promConfigValidatorEnvVarPath
+		err = p.setEnvVarsFromFile(filename)
 		if err != nil {
 			log.Printf("Error when settinng env for /opt/microsoft/prom_config_validator_env_var: %v\n", err)
 		}
 
-		filename = "/opt/envvars.env"
-		err = shared.SetEnvVarsFromFile(filename)
+		filename = p.paths.envVarsFilePath
+		err = p.setEnvVarsFromFile(filename)
 		if err != nil {
 			log.Printf("Error when settinng env for /opt/envvars.env: %v\n", err)
 		}
 	}
 
-	log.Printf("prom-config-validator::Use default prometheus config: %s\n", os.Getenv("AZMON_USE_DEFAULT_PROMETHEUS_CONFIG"))
+	log.Printf("prom-config-validator::Use default prometheus config: %s\n", p.env.Getenv("AZMON_USE_DEFAULT_PROMETHEUS_CONFIG"))
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -16,6 +17,10 @@ import (
  * 2) Test that the Prometheus config created is as expected for the settings given.
  */
 var _ = Describe("Configmapparser", Ordered, func() {
+	BeforeEach(func() {
+		testPipeline.paths.configMapSettingsDir = filepath.Join(GinkgoT().TempDir(), "settings")
+	})
+
 	AfterEach(func() {
 		cleanupEnvVars()
 	})
@@ -41,7 +46,7 @@ var _ = Describe("Configmapparser", Ordered, func() {
 
 			expectedContentsFilePath := "./testdata/default-linux-rs.yaml"
 
-			Configmapparser()
+			testPipeline.configmapparser()
 
 			envVars := map[string]string {
 				"AZMON_AGENT_CFG_SCHEMA_VERSION": "v1",
@@ -77,24 +82,31 @@ var _ = Describe("Configmapparser", Ordered, func() {
 			err := checkEnvVars(envVars)
 			Expect(err).NotTo(HaveOccurred())
 
-			checkHashMaps(configMapKeepListEnvVarPath, map[string]string {
-				"KUBELET_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",kubeletRegex_minimal_mac),
-				"COREDNS_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",coreDNSRegex_minimal_mac),
-				"CADVISOR_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",cadvisorRegex_minimal_mac),
-				"KUBEPROXY_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",kubeproxyRegex_minimal_mac),
-				"APISERVER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",apiserverRegex_minimal_mac),
-				"KUBESTATE_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",kubestateRegex_minimal_mac),
-				"NODEEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",nodeexporterRegex_minimal_mac),
-				"WINDOWSEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",windowsexporterRegex_minimal_mac),
-				"WINDOWSKUBEPROXY_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",windowskubeproxyRegex_minimal_mac),
+			checkHashMaps(testPipeline.paths.configMapKeepListEnvVarPath, map[string]string {
+				"KUBELET_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("kubelet")),
+				"COREDNS_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("coredns")),
+				"CADVISOR_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("cadvisor")),
+				"KUBEPROXY_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("kubeproxy")),
+				"APISERVER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("apiserver")),
+				"KUBESTATE_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("kubestate")),
+				"NODEEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("nodeexporter")),
+				"WINDOWSEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("windowsexporter")),
+				"WINDOWSKUBEPROXY_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("windowskubeproxy")),
 				"POD_ANNOTATION_METRICS_KEEP_LIST_REGEX": "",
-				"KAPPIEBASIC_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",kappiebasicRegex_minimal_mac),
-				"NETWORKOBSERVABILITYRETINA_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",networkobservabilityRetinaRegex_minimal_mac),
-				"NETWORKOBSERVABILITYHUBBLE_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",networkobservabilityHubbleRegex_minimal_mac),
-				"NETWORKOBSERVABILITYCILIUM_METRICS_KEEP_LIST_REGEX": "",
+				"KAPPIEBASIC_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("kappiebasic")),
+				"NETWORKOBSERVABILITYRETINA_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("networkobservabilityRetina")),
+				"NETWORKOBSERVABILITYHUBBLE_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("networkobservabilityHubble")),
+				"NETWORKOBSERVABILITYCILIUM_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("networkobservabilityCilium")),
+				"ACSTORCAPACITYPROVISONER_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("acstor-capacity-provisioner")),
+				"ACSTORMETRICSEXPORTER_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("acstor-metrics-exporter")),
+				"LOCALCSIDRIVER_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("local-csi-driver")),
+				"ZTUNNEL_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("ztunnel")),
+				"ISTIOCNI_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("istio-cni")),
+				"CONTROLPLANE_ISTIO_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("controlplane-istio")),
+				"DCGMEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("dcgmexporter")),
 			})
 
-			checkHashMaps(scrapeIntervalEnvVarPath, map[string]string {
+			checkHashMaps(testPipeline.paths.scrapeIntervalEnvVarPath, map[string]string {
 				"KUBELET_SCRAPE_INTERVAL": "30s",
 				"COREDNS_SCRAPE_INTERVAL": "30s",
 				"CADVISOR_SCRAPE_INTERVAL": "30s",
@@ -110,9 +122,16 @@ var _ = Describe("Configmapparser", Ordered, func() {
 				"NETWORKOBSERVABILITYRETINA_SCRAPE_INTERVAL": "30s",
 				"NETWORKOBSERVABILITYHUBBLE_SCRAPE_INTERVAL": "30s",
 				"NETWORKOBSERVABILITYCILIUM_SCRAPE_INTERVAL": "30s",
+				"ACSTORCAPACITYPROVISIONER_SCRAPE_INTERVAL": "30s",
+				"ACSTORMETRICSEXPORTER_SCRAPE_INTERVAL": "30s",
+				"LOCALCSIDRIVER_SCRAPE_INTERVAL": "30s",
+				"ZTUNNEL_SCRAPE_INTERVAL": "30s",
+				"ISTIOCNI_SCRAPE_INTERVAL": "30s",
+				"CONTROLPLANE_ISTIO_SCRAPE_INTERVAL": "30s",
+				"DCGMEXPORTER_SCRAPE_INTERVAL": "30s",
 			})
 
-			mergedFileContents, err := ioutil.ReadFile(mergedDefaultConfigPath)
+			mergedFileContents, err := ioutil.ReadFile(testPipeline.paths.mergedDefaultConfigPath)
 			Expect(err).NotTo(HaveOccurred())
 			expectedFileContents, err := ioutil.ReadFile(expectedContentsFilePath)
 			Expect(err).NotTo(HaveOccurred())
@@ -134,7 +153,7 @@ var _ = Describe("Configmapparser", Ordered, func() {
 			setupProcessedFiles()
 			expectedContentsFilePath := "./testdata/default-linux-ds.yaml"
 
-			Configmapparser()
+			testPipeline.configmapparser()
 
 			envVars := map[string]string {
 				"AZMON_AGENT_CFG_SCHEMA_VERSION": "v1",
@@ -170,24 +189,31 @@ var _ = Describe("Configmapparser", Ordered, func() {
 			err := checkEnvVars(envVars)
 			Expect(err).NotTo(HaveOccurred())
 
-			checkHashMaps(configMapKeepListEnvVarPath, map[string]string {
-				"KUBELET_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",kubeletRegex_minimal_mac),
-				"COREDNS_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",coreDNSRegex_minimal_mac),
-				"CADVISOR_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",cadvisorRegex_minimal_mac),
-				"KUBEPROXY_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",kubeproxyRegex_minimal_mac),
-				"APISERVER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",apiserverRegex_minimal_mac),
-				"KUBESTATE_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",kubestateRegex_minimal_mac),
-				"NODEEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",nodeexporterRegex_minimal_mac),
-				"WINDOWSEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",windowsexporterRegex_minimal_mac),
-				"WINDOWSKUBEPROXY_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",windowskubeproxyRegex_minimal_mac),
+			checkHashMaps(testPipeline.paths.configMapKeepListEnvVarPath, map[string]string {
+				"KUBELET_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("kubelet")),
+				"COREDNS_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("coredns")),
+				"CADVISOR_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("cadvisor")),
+				"KUBEPROXY_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("kubeproxy")),
+				"APISERVER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("apiserver")),
+				"KUBESTATE_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("kubestate")),
+				"NODEEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("nodeexporter")),
+				"WINDOWSEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("windowsexporter")),
+				"WINDOWSKUBEPROXY_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("windowskubeproxy")),
 				"POD_ANNOTATION_METRICS_KEEP_LIST_REGEX": "",
-				"KAPPIEBASIC_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",kappiebasicRegex_minimal_mac),
-				"NETWORKOBSERVABILITYRETINA_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",networkobservabilityRetinaRegex_minimal_mac),
-				"NETWORKOBSERVABILITYHUBBLE_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",networkobservabilityHubbleRegex_minimal_mac),
-				"NETWORKOBSERVABILITYCILIUM_METRICS_KEEP_LIST_REGEX": "",
+				"KAPPIEBASIC_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("kappiebasic")),
+				"NETWORKOBSERVABILITYRETINA_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("networkobservabilityRetina")),
+				"NETWORKOBSERVABILITYHUBBLE_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("networkobservabilityHubble")),
+				"NETWORKOBSERVABILITYCILIUM_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("networkobservabilityCilium")),
+				"ACSTORCAPACITYPROVISONER_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("acstor-capacity-provisioner")),
+				"ACSTORMETRICSEXPORTER_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("acstor-metrics-exporter")),
+				"LOCALCSIDRIVER_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("local-csi-driver")),
+				"ZTUNNEL_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("ztunnel")),
+				"ISTIOCNI_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("istio-cni")),
+				"CONTROLPLANE_ISTIO_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("controlplane-istio")),
+				"DCGMEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("dcgmexporter")),
 			})
 
-			checkHashMaps(scrapeIntervalEnvVarPath, map[string]string {
+			checkHashMaps(testPipeline.paths.scrapeIntervalEnvVarPath, map[string]string {
 				"KUBELET_SCRAPE_INTERVAL": "30s",
 				"COREDNS_SCRAPE_INTERVAL": "30s",
 				"CADVISOR_SCRAPE_INTERVAL": "30s",
@@ -203,9 +229,16 @@ var _ = Describe("Configmapparser", Ordered, func() {
 				"NETWORKOBSERVABILITYRETINA_SCRAPE_INTERVAL": "30s",
 				"NETWORKOBSERVABILITYHUBBLE_SCRAPE_INTERVAL": "30s",
 				"NETWORKOBSERVABILITYCILIUM_SCRAPE_INTERVAL": "30s",
+				"ACSTORCAPACITYPROVISIONER_SCRAPE_INTERVAL": "30s",
+				"ACSTORMETRICSEXPORTER_SCRAPE_INTERVAL": "30s",
+				"LOCALCSIDRIVER_SCRAPE_INTERVAL": "30s",
+				"ZTUNNEL_SCRAPE_INTERVAL": "30s",
+				"ISTIOCNI_SCRAPE_INTERVAL": "30s",
+				"CONTROLPLANE_ISTIO_SCRAPE_INTERVAL": "30s",
+				"DCGMEXPORTER_SCRAPE_INTERVAL": "30s",
 			})
 
-			mergedFileContents, err := ioutil.ReadFile(mergedDefaultConfigPath)
+			mergedFileContents, err := ioutil.ReadFile(testPipeline.paths.mergedDefaultConfigPath)
 			Expect(err).NotTo(HaveOccurred())
 			expectedFileContents, err := ioutil.ReadFile(expectedContentsFilePath)
 			Expect(err).NotTo(HaveOccurred())
@@ -238,7 +271,7 @@ var _ = Describe("Configmapparser", Ordered, func() {
 
 			fmt.Println("testing replicaset defaults empty")
 
-			Configmapparser()
+			testPipeline.configmapparser()
 
 			envVars := map[string]string {
 				"AZMON_AGENT_CFG_SCHEMA_VERSION": "v1",
@@ -247,7 +280,7 @@ var _ = Describe("Configmapparser", Ordered, func() {
 				"AZMON_DEFAULT_METRIC_ACCOUNT_NAME":                "",
 				"AZMON_CLUSTER_LABEL":                              "",
 				"AZMON_CLUSTER_ALIAS":                              "",
-				"AZMON_OPERATOR_ENABLED_CHART_SETTING":              "false",
+				"AZMON_OPERATOR_ENABLED_CHART_SETTING":              "true",
 				"AZMON_OPERATOR_ENABLED":                            "true",
 				"AZMON_OPERATOR_ENABLED_CFG_MAP_SETTING":            "",
 				"AZMON_PROMETHEUS_KUBELET_SCRAPING_ENABLED":         "true",
@@ -274,24 +307,31 @@ var _ = Describe("Configmapparser", Ordered, func() {
 			err := checkEnvVars(envVars)
 			Expect(err).NotTo(HaveOccurred())
 			
-			checkHashMaps(configMapKeepListEnvVarPath, map[string]string {
-				"KUBELET_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",kubeletRegex_minimal_mac),
-				"COREDNS_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",coreDNSRegex_minimal_mac),
-				"CADVISOR_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",cadvisorRegex_minimal_mac),
-				"KUBEPROXY_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",kubeproxyRegex_minimal_mac),
-				"APISERVER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",apiserverRegex_minimal_mac),
-				"KUBESTATE_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",kubestateRegex_minimal_mac),
-				"NODEEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",nodeexporterRegex_minimal_mac),
-				"WINDOWSEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",windowsexporterRegex_minimal_mac),
-				"WINDOWSKUBEPROXY_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",windowskubeproxyRegex_minimal_mac),
+			checkHashMaps(testPipeline.paths.configMapKeepListEnvVarPath, map[string]string {
+				"KUBELET_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("kubelet")),
+				"COREDNS_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("coredns")),
+				"CADVISOR_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("cadvisor")),
+				"KUBEPROXY_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("kubeproxy")),
+				"APISERVER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("apiserver")),
+				"KUBESTATE_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("kubestate")),
+				"NODEEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("nodeexporter")),
+				"WINDOWSEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("windowsexporter")),
+				"WINDOWSKUBEPROXY_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("windowskubeproxy")),
 				"POD_ANNOTATION_METRICS_KEEP_LIST_REGEX": "",
-				"KAPPIEBASIC_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",kappiebasicRegex_minimal_mac),
-				"NETWORKOBSERVABILITYRETINA_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",networkobservabilityRetinaRegex_minimal_mac),
-				"NETWORKOBSERVABILITYHUBBLE_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",networkobservabilityHubbleRegex_minimal_mac),
-				"NETWORKOBSERVABILITYCILIUM_METRICS_KEEP_LIST_REGEX": "",
+				"KAPPIEBASIC_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("kappiebasic")),
+				"NETWORKOBSERVABILITYRETINA_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("networkobservabilityRetina")),
+				"NETWORKOBSERVABILITYHUBBLE_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("networkobservabilityHubble")),
+				"NETWORKOBSERVABILITYCILIUM_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("networkobservabilityCilium")),
+				"ACSTORCAPACITYPROVISONER_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("acstor-capacity-provisioner")),
+				"ACSTORMETRICSEXPORTER_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("acstor-metrics-exporter")),
+				"LOCALCSIDRIVER_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("local-csi-driver")),
+				"ZTUNNEL_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("ztunnel")),
+				"ISTIOCNI_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("istio-cni")),
+				"CONTROLPLANE_ISTIO_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("controlplane-istio")),
+				"DCGMEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("dcgmexporter")),
 			})
 
-			checkHashMaps(scrapeIntervalEnvVarPath, map[string]string {
+			checkHashMaps(testPipeline.paths.scrapeIntervalEnvVarPath, map[string]string {
 				"KUBELET_SCRAPE_INTERVAL": "30s",
 				"COREDNS_SCRAPE_INTERVAL": "30s",
 				"CADVISOR_SCRAPE_INTERVAL": "30s",
@@ -307,9 +347,16 @@ var _ = Describe("Configmapparser", Ordered, func() {
 				"NETWORKOBSERVABILITYRETINA_SCRAPE_INTERVAL": "30s",
 				"NETWORKOBSERVABILITYHUBBLE_SCRAPE_INTERVAL": "30s",
 				"NETWORKOBSERVABILITYCILIUM_SCRAPE_INTERVAL": "30s",
+				"ACSTORCAPACITYPROVISIONER_SCRAPE_INTERVAL": "30s",
+				"ACSTORMETRICSEXPORTER_SCRAPE_INTERVAL": "30s",
+				"LOCALCSIDRIVER_SCRAPE_INTERVAL": "30s",
+				"ZTUNNEL_SCRAPE_INTERVAL": "30s",
+				"ISTIOCNI_SCRAPE_INTERVAL": "30s",
+				"CONTROLPLANE_ISTIO_SCRAPE_INTERVAL": "30s",
+				"DCGMEXPORTER_SCRAPE_INTERVAL": "30s",
 			})
 
-			mergedFileContents, err := ioutil.ReadFile(mergedDefaultConfigPath)
+			mergedFileContents, err := ioutil.ReadFile(testPipeline.paths.mergedDefaultConfigPath)
 			Expect(err).NotTo(HaveOccurred())
 			expectedFileContents, err := ioutil.ReadFile("./testdata/default-linux-rs.yaml")
 			Expect(err).NotTo(HaveOccurred())
@@ -341,11 +388,11 @@ var _ = Describe("Configmapparser", Ordered, func() {
 				"MAC": "true",
 			})
 
-			schemaVersionFile = createTempFile("schema-version", "v1")
-			configVersionFile = createTempFile("config-version", "ver1")
-			configMapMountPathForPodAnnotation = createTempFile("podannotation", `podannotationnamespaceregex = ".*|value"`)
-			collectorSettingsMountPath = createTempFile("collector-settings", `cluster_alias = "alias"`)
-			defaultSettingsMountPath = createTempFile("default-settings", `
+			testPipeline.paths.schemaVersionFile = createSettingsFile("schema-version", "v1")
+			testPipeline.paths.configVersionFile = createSettingsFile("config-version", "ver1")
+			testPipeline.paths.configMapMountPathForPodAnnotation = createSettingsFile("pod-annotation-based-scraping", `podannotationnamespaceregex = ".*|value"`)
+			testPipeline.paths.collectorSettingsMountPath = createSettingsFile("prometheus-collector-settings", `cluster_alias = "alias"`)
+			testPipeline.paths.defaultSettingsMountPath = createSettingsFile("default-scrape-settings-enabled", `
 				kubelet = true
 				coredns = true
 				cadvisor = true
@@ -361,8 +408,8 @@ var _ = Describe("Configmapparser", Ordered, func() {
 				networkobservabilityCilium = true
 				prometheuscollectorhealth = true
 			`)
-			configMapDebugMountPath = createTempFile("debug-mode", `enabled = true`)
-			configMapKeepListMountPath = createTempFile("keep-list", `
+			testPipeline.paths.configMapDebugMountPath = createSettingsFile("debug-mode", `enabled = true`)
+			testPipeline.paths.configMapKeepListMountPath = createSettingsFile("default-targets-metrics-keep-list", `
 				kubelet = "test.*|test2"
 				coredns = "test.*|test2"
 				cadvisor = "test.*|test2"
@@ -379,7 +426,7 @@ var _ = Describe("Configmapparser", Ordered, func() {
 				networkobservabilityCilium = "test.*|test2"
 				minimalingestionprofile = true
 			`)
-			configMapScrapeIntervalMountPath = createTempFile("scrape-interval", `
+			testPipeline.paths.configMapScrapeIntervalMountPath = createSettingsFile("default-targets-scrape-interval-settings", `
 				kubelet = "15s"
 				coredns = "15s"
 				cadvisor = "15s"
@@ -399,7 +446,7 @@ var _ = Describe("Configmapparser", Ordered, func() {
 
 			setupProcessedFiles()
 
-			Configmapparser()
+			testPipeline.configmapparser()
 
 			envVars := map[string]string {
 				"AZMON_AGENT_CFG_SCHEMA_VERSION": "v1",
@@ -435,24 +482,31 @@ var _ = Describe("Configmapparser", Ordered, func() {
 			err := checkEnvVars(envVars)
 			Expect(err).NotTo(HaveOccurred())
 
-			checkHashMaps(configMapKeepListEnvVarPath, map[string]string {
-				"KUBELET_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",kubeletRegex_minimal_mac),
-				"COREDNS_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",coreDNSRegex_minimal_mac),
-				"CADVISOR_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",cadvisorRegex_minimal_mac),
-				"KUBEPROXY_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",kubeproxyRegex_minimal_mac),
-				"APISERVER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",apiserverRegex_minimal_mac),
-				"KUBESTATE_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",kubestateRegex_minimal_mac),
-				"NODEEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",nodeexporterRegex_minimal_mac),
-				"WINDOWSEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",windowsexporterRegex_minimal_mac),
-				"WINDOWSKUBEPROXY_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",windowskubeproxyRegex_minimal_mac),
+			checkHashMaps(testPipeline.paths.configMapKeepListEnvVarPath, map[string]string {
+				"KUBELET_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",shippedMinimalRegex("kubelet")),
+				"COREDNS_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",shippedMinimalRegex("coredns")),
+				"CADVISOR_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",shippedMinimalRegex("cadvisor")),
+				"KUBEPROXY_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",shippedMinimalRegex("kubeproxy")),
+				"APISERVER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",shippedMinimalRegex("apiserver")),
+				"KUBESTATE_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",shippedMinimalRegex("kubestate")),
+				"NODEEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",shippedMinimalRegex("nodeexporter")),
+				"WINDOWSEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",shippedMinimalRegex("windowsexporter")),
+				"WINDOWSKUBEPROXY_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",shippedMinimalRegex("windowskubeproxy")),
 				"POD_ANNOTATION_METRICS_KEEP_LIST_REGEX": "test.*|test2",
-				"KAPPIEBASIC_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",kappiebasicRegex_minimal_mac),
-				"NETWORKOBSERVABILITYRETINA_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",networkobservabilityRetinaRegex_minimal_mac),
-				"NETWORKOBSERVABILITYHUBBLE_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",networkobservabilityHubbleRegex_minimal_mac),
-				"NETWORKOBSERVABILITYCILIUM_METRICS_KEEP_LIST_REGEX": "test.*|test2",
+				"KAPPIEBASIC_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",shippedMinimalRegex("kappiebasic")),
+				"NETWORKOBSERVABILITYRETINA_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",shippedMinimalRegex("networkobservabilityRetina")),
+				"NETWORKOBSERVABILITYHUBBLE_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",shippedMinimalRegex("networkobservabilityHubble")),
+				"NETWORKOBSERVABILITYCILIUM_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("test.*|test2|%s",shippedMinimalRegex("networkobservabilityCilium")),
+				"ACSTORCAPACITYPROVISONER_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("acstor-capacity-provisioner")),
+				"ACSTORMETRICSEXPORTER_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("acstor-metrics-exporter")),
+				"LOCALCSIDRIVER_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("local-csi-driver")),
+				"ZTUNNEL_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("ztunnel")),
+				"ISTIOCNI_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("istio-cni")),
+				"CONTROLPLANE_ISTIO_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("controlplane-istio")),
+				"DCGMEXPORTER_METRICS_KEEP_LIST_REGEX": fmt.Sprintf("|%s",shippedMinimalRegex("dcgmexporter")),
 			})
 
-			checkHashMaps(scrapeIntervalEnvVarPath, map[string]string {
+			checkHashMaps(testPipeline.paths.scrapeIntervalEnvVarPath, map[string]string {
 				"KUBELET_SCRAPE_INTERVAL": "15s",
 				"COREDNS_SCRAPE_INTERVAL": "15s",
 				"CADVISOR_SCRAPE_INTERVAL": "15s",
@@ -468,6 +522,13 @@ var _ = Describe("Configmapparser", Ordered, func() {
 				"NETWORKOBSERVABILITYRETINA_SCRAPE_INTERVAL": "15s",
 				"NETWORKOBSERVABILITYHUBBLE_SCRAPE_INTERVAL": "15s",
 				"NETWORKOBSERVABILITYCILIUM_SCRAPE_INTERVAL": "15s",
+				"ACSTORCAPACITYPROVISIONER_SCRAPE_INTERVAL": "30s",
+				"ACSTORMETRICSEXPORTER_SCRAPE_INTERVAL": "30s",
+				"LOCALCSIDRIVER_SCRAPE_INTERVAL": "30s",
+				"ZTUNNEL_SCRAPE_INTERVAL": "30s",
+				"ISTIOCNI_SCRAPE_INTERVAL": "30s",
+				"CONTROLPLANE_ISTIO_SCRAPE_INTERVAL": "30s",
+				"DCGMEXPORTER_SCRAPE_INTERVAL": "30s",
 			})
 		})
 
@@ -497,13 +558,13 @@ var _ = Describe("Configmapparser", Ordered, func() {
 				"MAC": "true",
 			})
 
-			schemaVersionFile = createTempFile("schema-version", "v1")
-			configVersionFile = createTempFile("config-version", "ver1")
-			configMapMountPathForPodAnnotation = createTempFile("podannotation", "")
-			collectorSettingsMountPath = createTempFile("collector-settings", "")
-			defaultSettingsMountPath = createTempFile("default-settings", "")
-			configMapDebugMountPath = createTempFile("debug-mode", "")
-			configMapKeepListMountPath = createTempFile("keep-list", `
+			testPipeline.paths.schemaVersionFile = createSettingsFile("schema-version", "v1")
+			testPipeline.paths.configVersionFile = createSettingsFile("config-version", "ver1")
+			testPipeline.paths.configMapMountPathForPodAnnotation = createSettingsFile("pod-annotation-based-scraping", "")
+			testPipeline.paths.collectorSettingsMountPath = createSettingsFile("prometheus-collector-settings", "")
+			testPipeline.paths.defaultSettingsMountPath = createSettingsFile("default-scrape-settings-enabled", "")
+			testPipeline.paths.configMapDebugMountPath = createSettingsFile("debug-mode", "")
+			testPipeline.paths.configMapKeepListMountPath = createSettingsFile("default-targets-metrics-keep-list", `
 				kubelet = "test.*|test2"
 				coredns = "test.*|test2"
 				cadvisor = "test.*|test2"
@@ -520,13 +581,13 @@ var _ = Describe("Configmapparser", Ordered, func() {
 				networkobservabilityCilium = "test.*|test2"
 				minimalingestionprofile = false
 			`)
-			configMapScrapeIntervalMountPath = createTempFile("scrape-interval", ``)
+			testPipeline.paths.configMapScrapeIntervalMountPath = createSettingsFile("default-targets-scrape-interval-settings", ``)
 
 			setupProcessedFiles()
 
-			Configmapparser()
+			testPipeline.configmapparser()
 
-			checkHashMaps(configMapKeepListEnvVarPath, map[string]string {
+			checkHashMaps(testPipeline.paths.configMapKeepListEnvVarPath, map[string]string {
 				"KUBELET_METRICS_KEEP_LIST_REGEX": "test.*|test2",
 				"COREDNS_METRICS_KEEP_LIST_REGEX": "test.*|test2",
 				"CADVISOR_METRICS_KEEP_LIST_REGEX": "test.*|test2",
@@ -541,6 +602,13 @@ var _ = Describe("Configmapparser", Ordered, func() {
 				"NETWORKOBSERVABILITYRETINA_METRICS_KEEP_LIST_REGEX": "test.*|test2",
 				"NETWORKOBSERVABILITYHUBBLE_METRICS_KEEP_LIST_REGEX": "test.*|test2",
 				"NETWORKOBSERVABILITYCILIUM_METRICS_KEEP_LIST_REGEX": "test.*|test2",
+				"ACSTORCAPACITYPROVISONER_KEEP_LIST_REGEX": "",
+				"ACSTORMETRICSEXPORTER_KEEP_LIST_REGEX": "",
+				"LOCALCSIDRIVER_KEEP_LIST_REGEX": "",
+				"ZTUNNEL_METRICS_KEEP_LIST_REGEX": "",
+				"ISTIOCNI_METRICS_KEEP_LIST_REGEX": "",
+				"CONTROLPLANE_ISTIO_KEEP_LIST_REGEX": "",
+				"DCGMEXPORTER_METRICS_KEEP_LIST_REGEX": "",
 			})
 		})
 
@@ -556,27 +624,27 @@ var _ = Describe("Configmapparser", Ordered, func() {
 				"MAC": "true",
 			})
 
-			schemaVersionFile = createTempFile("schema-version", "v1")
-			configVersionFile = createTempFile("config-version", "ver1")
-			configMapMountPathForPodAnnotation = createTempFile("podannotation", "")
-			collectorSettingsMountPath = createTempFile("collector-settings", "")
-			defaultSettingsMountPath = createTempFile("default-settings", "")
-			configMapDebugMountPath = createTempFile("debug-mode", "")
-			configMapKeepListMountPath = createTempFile("keep-list", `
+			testPipeline.paths.schemaVersionFile = createSettingsFile("schema-version", "v1")
+			testPipeline.paths.configVersionFile = createSettingsFile("config-version", "ver1")
+			testPipeline.paths.configMapMountPathForPodAnnotation = createSettingsFile("pod-annotation-based-scraping", "")
+			testPipeline.paths.collectorSettingsMountPath = createSettingsFile("prometheus-collector-settings", "")
+			testPipeline.paths.defaultSettingsMountPath = createSettingsFile("default-scrape-settings-enabled", "")
+			testPipeline.paths.configMapDebugMountPath = createSettingsFile("debug-mode", "")
+			testPipeline.paths.configMapKeepListMountPath = createSettingsFile("default-targets-metrics-keep-list", `
 				minimalingestionprofile = false
 			`)
-			configMapScrapeIntervalMountPath = createTempFile("scrape-interval", ``)
+			testPipeline.paths.configMapScrapeIntervalMountPath = createSettingsFile("default-targets-scrape-interval-settings", ``)
 
-			podAnnotationEnvVarPath = createTempFile("podannotation-envvar", "")
-			collectorSettingsEnvVarPath = createTempFile("collector-settings-envvar", "")
-			defaultSettingsEnvVarPath = createTempFile("default-settings-envvar", "")
-			debugModeEnvVarPath = createTempFile("debug-mode-envvar", "")
-			configMapKeepListEnvVarPath = createTempFile("keep-list-envvar", "")
-			scrapeIntervalEnvVarPath = createTempFile("scrape-interval-envvar", "")
+			testPipeline.paths.podAnnotationEnvVarPath = createTempFile("podannotation-envvar", "")
+			testPipeline.paths.collectorSettingsEnvVarPath = createTempFile("collector-settings-envvar", "")
+			testPipeline.paths.defaultSettingsEnvVarPath = createTempFile("default-settings-envvar", "")
+			testPipeline.paths.debugModeEnvVarPath = createTempFile("debug-mode-envvar", "")
+			testPipeline.paths.configMapKeepListEnvVarPath = createTempFile("keep-list-envvar", "")
+			testPipeline.paths.scrapeIntervalEnvVarPath = createTempFile("scrape-interval-envvar", "")
 
-			Configmapparser()
+			testPipeline.configmapparser()
 
-			checkHashMaps(configMapKeepListEnvVarPath, map[string]string {
+			checkHashMaps(testPipeline.paths.configMapKeepListEnvVarPath, map[string]string {
 				"KUBELET_METRICS_KEEP_LIST_REGEX": "",
 				"COREDNS_METRICS_KEEP_LIST_REGEX": "",
 				"CADVISOR_METRICS_KEEP_LIST_REGEX": "",
@@ -591,11 +659,26 @@ var _ = Describe("Configmapparser", Ordered, func() {
 				"NETWORKOBSERVABILITYRETINA_METRICS_KEEP_LIST_REGEX": "",
 				"NETWORKOBSERVABILITYHUBBLE_METRICS_KEEP_LIST_REGEX": "",
 				"NETWORKOBSERVABILITYCILIUM_METRICS_KEEP_LIST_REGEX": "",
+				"ACSTORCAPACITYPROVISONER_KEEP_LIST_REGEX": "",
+				"ACSTORMETRICSEXPORTER_KEEP_LIST_REGEX": "",
+				"LOCALCSIDRIVER_KEEP_LIST_REGEX": "",
+				"ZTUNNEL_METRICS_KEEP_LIST_REGEX": "",
+				"ISTIOCNI_METRICS_KEEP_LIST_REGEX": "",
+				"CONTROLPLANE_ISTIO_KEEP_LIST_REGEX": "",
+				"DCGMEXPORTER_METRICS_KEEP_LIST_REGEX": "",
 			})
 		})
 	})	
 })
 
+// createSettingsFile writes a section of the settings configmap to the settings dir of the spec
+func createSettingsFile(section string, content string) string {
+	Expect(os.MkdirAll(testPipeline.paths.configMapSettingsDir, 0755)).To(Succeed())
+	path := filepath.Join(testPipeline.paths.configMapSettingsDir, section)
+	Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	return path
+}
+
 func createTempFile(name string, content string) string {
 	tempFile, err := ioutil.TempFile("", name)
 	Expect(err).NotTo(HaveOccurred())
@@ -606,8 +689,8 @@ func createTempFile(name string, content string) string {
 
 func checkEnvVars(envVars map[string]string) error {
 	for key, value := range envVars {
-		if os.Getenv(key) != value {
-			return fmt.Errorf("Expected %s to be %s, but got %s", key, value, os.Getenv(key))
+		if testPipeline.env.Getenv(key) != value {
+			return fmt.Errorf("Expected %s to be %s, but got %s", key, value, testPipeline.env.Getenv(key))
 		}
 	}
 	return nil
@@ -624,46 +707,59 @@ func checkHashMaps(filepath string, expectedHash map[string]string) {
 
 func setEnvVars(envVars map[string]string) {
 	for key, value := range envVars {
-		os.Setenv(key, value)
+		testPipeline.env.Setenv(key, value, false)
 	}
 }
 
 func setupConfigFiles(defaultPath bool) {
 	if defaultPath {
-		configMapDebugMountPath   = "/etc/config/settings/debug-mode"
-		replicaSetCollectorConfig = "/opt/microsoft/otelcollector/collector-config-replicaset.yml"
-		defaultSettingsMountPath = "/etc/config/settings/default-scrape-settings"
-		configMapKeepListMountPath = "/etc/config/settings/default-targets-metrics-keep-list"
-		configMapMountPathForPodAnnotation = "/etc/config/settings/pod-annotation-based-scraping"
-		collectorSettingsMountPath = "/etc/config/settings/prometheus-collector-settings"
-		schemaVersionFile = "/etc/config/settings/schema-version"
-		configVersionFile = "/etc/config/settings/config-version"
-		configMapScrapeIntervalMountPath = "/etc/config/settings/default-targets-scrape-interval-settings"
+		testPipeline.paths.configMapDebugMountPath   = "/etc/config/settings/debug-mode"
+		testPipeline.paths.replicaSetCollectorConfig = "/opt/microsoft/otelcollector/collector-config-replicaset.yml"
+		testPipeline.paths.defaultSettingsMountPath = "/etc/config/settings/default-scrape-settings"
+		testPipeline.paths.configMapKeepListMountPath = "/etc/config/settings/default-targets-metrics-keep-list"
+		testPipeline.paths.configMapMountPathForPodAnnotation = "/etc/config/settings/pod-annotation-based-scraping"
+		testPipeline.paths.collectorSettingsMountPath = "/etc/config/settings/prometheus-collector-settings"
+		testPipeline.paths.schemaVersionFile = "/etc/config/settings/schema-version"
+		testPipeline.paths.configVersionFile = "/etc/config/settings/config-version"
+		testPipeline.paths.configMapScrapeIntervalMountPath = "/etc/config/settings/default-targets-scrape-interval-settings"
 	} else {
-		schemaVersionFile = createTempFile("schema-version", "v1")
-		configVersionFile = createTempFile("config-version", "ver1")
-		configMapMountPathForPodAnnotation = createTempFile("podannotation", "")
-		collectorSettingsMountPath = createTempFile("collector-settings", "")
-		defaultSettingsMountPath = createTempFile("default-settings", "")
-		configMapDebugMountPath = createTempFile("debug-mode", "")
-		configMapKeepListMountPath = createTempFile("keep-list", "")
-		configMapScrapeIntervalMountPath = createTempFile("scrape-interval", "")
-		replicaSetCollectorConfig = "./testdata/collector-config-replicaset.yml"
+		testPipeline.paths.schemaVersionFile = createSettingsFile("schema-version", "v1")
+		testPipeline.paths.configVersionFile = createSettingsFile("config-version", "ver1")
+		testPipeline.paths.configMapMountPathForPodAnnotation = createSettingsFile("pod-annotation-based-scraping", "")
+		testPipeline.paths.collectorSettingsMountPath = createSettingsFile("prometheus-collector-settings", "")
+		testPipeline.paths.defaultSettingsMountPath = createSettingsFile("default-scrape-settings-enabled", "")
+		testPipeline.paths.configMapDebugMountPath = createSettingsFile("debug-mode", "")
+		testPipeline.paths.configMapKeepListMountPath = createSettingsFile("default-targets-metrics-keep-list", "")
+		testPipeline.paths.configMapScrapeIntervalMountPath = createSettingsFile("default-targets-scrape-interval-settings", "")
+		testPipeline.paths.replicaSetCollectorConfig = "./testdata/collector-config-replicaset.yml"
 	}
 }
 
 func setupProcessedFiles() {
-	podAnnotationEnvVarPath = createTempFile("podannotation-envvar", "")
-	collectorSettingsEnvVarPath = createTempFile("collector-settings-envvar", "")
-	defaultSettingsEnvVarPath = createTempFile("default-settings-envvar", "")
-	debugModeEnvVarPath = createTempFile("debug-mode-envvar", "")
-	configMapKeepListEnvVarPath = createTempFile("keep-list-envvar", "")
-	scrapeIntervalEnvVarPath = createTempFile("scrape-interval-envvar", "")
-
-	defaultPromConfigPathPrefix = "../../../configmapparser/default-prom-configs/"
-	mergedDefaultConfigPath = createTempFile("merged-default-config", "")
-	regexHashFile = configMapKeepListEnvVarPath
-	intervalHashFile = scrapeIntervalEnvVarPath
+	testPipeline.paths.podAnnotationEnvVarPath = createTempFile("podannotation-envvar", "")
+	testPipeline.paths.podAnnotationSettingsEnvVarPath = createTempFile("podannotation-settings-envvar", "")
+	testPipeline.paths.collectorSettingsEnvVarPath = createTempFile("collector-settings-envvar", "")
+	testPipeline.paths.defaultSettingsEnvVarPath = createTempFile("default-settings-envvar", "")
+	testPipeline.paths.debugModeEnvVarPath = createTempFile("debug-mode-envvar", "")
+	testPipeline.paths.configMapKeepListEnvVarPath = createTempFile("keep-list-envvar", "")
+	testPipeline.paths.configMapMetricsDropListEnvVarPath = createTempFile("metrics-drop-list-envvar", "")
+	testPipeline.paths.configMapLabelDropListEnvVarPath = createTempFile("label-drop-list-envvar", "")
+	testPipeline.paths.scrapeIntervalEnvVarPath = createTempFile("scrape-interval-envvar", "")
+	testPipeline.paths.scrapeLimitsEnvVarPath = createTempFile("scrape-limits-envvar", "")
+	testPipeline.paths.externalLabelsEnvVarPath = createTempFile("external-labels-envvar", "")
+	testPipeline.paths.collectorProcessorsEnvVarPath = createTempFile("collector-processors-envvar", "")
+	testPipeline.paths.configProvenanceEnvVarPath = createTempFile("config-provenance", "")
+	testPipeline.paths.ingestionProfilesEnvVarPath = createTempFile("ingestion-profiles-envvar", "")
+	testPipeline.paths.ingestionProfilesDir = shippedIngestionProfilesDir
+
+	testPipeline.paths.defaultPromConfigsDir = "../../../configmapparser/default-prom-configs"
+	defaultScrapeConfigsDir, err := ioutil.TempDir("", "default-scrape-configs")
+	Expect(err).NotTo(HaveOccurred())
+	testPipeline.paths.defaultScrapeConfigsDir = defaultScrapeConfigsDir
+	testPipeline.paths.mergedDefaultConfigPath = createTempFile("merged-default-config", "")
+	lastKnownGoodDir, err := ioutil.TempDir("", "last-known-good")
+	Expect(err).NotTo(HaveOccurred())
+	testPipeline.paths.lastKnownGoodConfigDir = lastKnownGoodDir
 }
 
 func cleanupEnvVars() {
@@ -704,8 +800,9 @@ func cleanupEnvVars() {
 		"AZMON_INVALID_CUSTOM_PROMETHEUS_CONFIG",
 		"CONFIG_VALIDATOR_RUNNING_IN_AGENT",
 		"AZMON_USE_DEFAULT_PROMETHEUS_CONFIG",
+		"AZMON_SKIPPED_CUSTOM_PROMETHEUS_CONFIG_FRAGMENTS",
 	}
 	for _, envVar := range allEnvVars {
-		os.Unsetenv(envVar)
+		testPipeline.env.Unsetenv(envVar)
 	}
 }
\ No newline at end of file
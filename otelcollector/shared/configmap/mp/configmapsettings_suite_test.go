@@ -7,6 +7,14 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// testPipeline is the config pipeline the specs run, with the agent's paths under a temporary directory unless a spec
+// points them elsewhere, and an environment of its own
+var testPipeline *configPipeline
+
+var _ = BeforeEach(func() {
+	testPipeline = newConfigPipeline(newConfigPaths(GinkgoT().TempDir()), osFileSystem{}, NewMapEnvironment(nil))
+})
+
 func TestConfigmapSettings(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Configmap Settings Suite")
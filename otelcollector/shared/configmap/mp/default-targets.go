@@ -0,0 +1,694 @@
+package configmapsettings
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultTarget describes a default scrape target: how it is configured through the settings configmap and which
+// default scrape config file is used for it on each controller type, OS and mode.
+type DefaultTarget struct {
+	// Name is the key of the target in the default-targets-scrape-enabled, default-targets-metrics-keep-list,
+	// default-targets-metrics-drop-list, default-targets-label-drop-list and default-targets-scrape-interval-settings
+	// sections of the settings configmap.
+	Name string `yaml:"name"`
+	// EnabledByDefault is used when the target is not set in the settings configmap.
+	EnabledByDefault bool `yaml:"enabledByDefault"`
+	// EnabledEnvVar, KeepListEnvVar and ScrapeIntervalEnvVar are the keys the settings are passed on with.
+	// They are derived from the name when not set in the default targets file.
+	EnabledEnvVar        string `yaml:"enabledEnvVar"`
+	KeepListEnvVar       string `yaml:"keepListEnvVar"`
+	ScrapeIntervalEnvVar string `yaml:"scrapeIntervalEnvVar"`
+	// MinimalIngestionProfileRegex is OR'ed with the keep list regex when the minimal ingestion profile is enabled and
+	// the target is not in the minimal profile file, which has the regexes of the built-in targets.
+	MinimalIngestionProfileRegex string `yaml:"minimalIngestionProfileRegex"`
+	// Configs are the default scrape config files of the target. The first one matching the agent is used.
+	Configs []DefaultTargetConfig `yaml:"configs"`
+
+	// enabledExternally is set when the enabled env var is set by another settings parser
+	enabledExternally bool
+	// requireScrapeInterval targets are only scraped when their scrape interval is set
+	requireScrapeInterval bool
+	// alwaysSetScrapeInterval targets get their scrape interval setting written to the scrape config even when it
+	// is not set, other targets keep the interval of their scrape config file
+	alwaysSetScrapeInterval bool
+	// precondition is checked before the scrape config file is used
+	precondition func(p *configPipeline) bool
+	// customize makes target specific changes to the scrape config file
+	customize func(p *configPipeline, configFile string)
+}
+
+// DefaultTargetConfig is a default scrape config file and the agents it is used on.
+type DefaultTargetConfig struct {
+	// File is the name of the scrape config file in the default-prom-configs directory.
+	File string `yaml:"file"`
+	// ControllerType is replicaset or daemonset, any controller type when empty.
+	ControllerType string `yaml:"controllerType"`
+	// OSType is linux or windows, any OS when empty.
+	OSType string `yaml:"osType"`
+	// Mode is simple or advanced, any mode when empty.
+	Mode                    string `yaml:"mode"`
+	RequireMAC              bool   `yaml:"requireMAC"`
+	RequireWindowsDaemonset bool   `yaml:"requireWindowsDaemonset"`
+	// Placeholders are env vars replaced in the file, written as $$NAME$$.
+	Placeholders []string `yaml:"placeholders"`
+
+	// requireDSUpMetric is set for the replicaset configs that scrape the up metric of the daemonset targets
+	requireDSUpMetric bool
+	noKeepList        bool
+	// inPlace configs are modified in the default-prom-configs directory instead of a copy in the working directory
+	inPlace bool
+	// notForSidecar configs are not used by the config reader sidecar
+	notForSidecar bool
+}
+
+// defaultTargetsFile is the format of the file that adds default targets to the built-in ones.
+type defaultTargetsFile struct {
+	Targets []DefaultTarget `yaml:"targets"`
+}
+
+// defaultTargetAgent is what a default target config is matched against.
+type defaultTargetAgent struct {
+	controllerType      string
+	osType              string
+	advancedMode        bool
+	windowsDaemonset    bool
+	mac                 bool
+	configReaderSidecar bool
+}
+
+var nonAlphaNumericRegex = regexp.MustCompile(`[^0-9a-zA-Z]+`)
+
+var builtInDefaultTargets = []DefaultTarget{
+	{
+		Name:                    "kubelet",
+		EnabledByDefault:        true,
+		EnabledEnvVar:           "AZMON_PROMETHEUS_KUBELET_SCRAPING_ENABLED",
+		KeepListEnvVar:          "KUBELET_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar:    "KUBELET_SCRAPE_INTERVAL",
+		alwaysSetScrapeInterval: true,
+		Configs: []DefaultTargetConfig{
+			{File: kubeletDefaultFileRsSimple, ControllerType: replicasetControllerType, Mode: "simple"},
+			{File: kubeletDefaultFileRsAdvancedWindowsDaemonset, ControllerType: replicasetControllerType, Mode: "advanced", RequireWindowsDaemonset: true, requireDSUpMetric: true, noKeepList: true},
+			{File: kubeletDefaultFileRsAdvanced, ControllerType: replicasetControllerType, Mode: "advanced", requireDSUpMetric: true, noKeepList: true},
+			{File: kubeletDefaultFileDs, ControllerType: daemonsetControllerType, Mode: "advanced", OSType: "linux", Placeholders: []string{"NODE_IP", "NODE_NAME", "OS_TYPE"}},
+			{File: kubeletDefaultFileDs, ControllerType: daemonsetControllerType, Mode: "advanced", OSType: "windows", RequireWindowsDaemonset: true, Placeholders: []string{"NODE_IP", "NODE_NAME", "OS_TYPE"}},
+		},
+	},
+	{
+		Name:                 "coredns",
+		EnabledEnvVar:        "AZMON_PROMETHEUS_COREDNS_SCRAPING_ENABLED",
+		KeepListEnvVar:       "COREDNS_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar: "COREDNS_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: coreDNSDefaultFile, ControllerType: replicasetControllerType},
+		},
+	},
+	{
+		Name:                  "cadvisor",
+		EnabledByDefault:      true,
+		EnabledEnvVar:         "AZMON_PROMETHEUS_CADVISOR_SCRAPING_ENABLED",
+		KeepListEnvVar:        "CADVISOR_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar:  "CADVISOR_SCRAPE_INTERVAL",
+		requireScrapeInterval: true,
+		Configs: []DefaultTargetConfig{
+			{File: cadvisorDefaultFileRsSimple, ControllerType: replicasetControllerType, Mode: "simple"},
+			{File: cadvisorDefaultFileRsAdvanced, ControllerType: replicasetControllerType, Mode: "advanced", requireDSUpMetric: true, noKeepList: true},
+			{File: cadvisorDefaultFileDs, ControllerType: daemonsetControllerType, Mode: "advanced", OSType: "linux", Placeholders: []string{"NODE_IP", "NODE_NAME"}},
+		},
+	},
+	{
+		Name:                 "kubeproxy",
+		EnabledEnvVar:        "AZMON_PROMETHEUS_KUBEPROXY_SCRAPING_ENABLED",
+		KeepListEnvVar:       "KUBEPROXY_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar: "KUBEPROXY_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: kubeProxyDefaultFile, ControllerType: replicasetControllerType},
+		},
+	},
+	{
+		Name:                 "apiserver",
+		EnabledEnvVar:        "AZMON_PROMETHEUS_APISERVER_SCRAPING_ENABLED",
+		KeepListEnvVar:       "APISERVER_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar: "APISERVER_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: apiserverDefaultFile, ControllerType: replicasetControllerType},
+		},
+	},
+	{
+		Name:                 "kubestate",
+		EnabledByDefault:     true,
+		EnabledEnvVar:        "AZMON_PROMETHEUS_KUBESTATE_SCRAPING_ENABLED",
+		KeepListEnvVar:       "KUBESTATE_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar: "KUBESTATE_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: kubeStateDefaultFile, ControllerType: replicasetControllerType, Placeholders: []string{"KUBE_STATE_NAME", "POD_NAMESPACE"}},
+		},
+	},
+	{
+		Name:                    "nodeexporter",
+		EnabledByDefault:        true,
+		EnabledEnvVar:           "AZMON_PROMETHEUS_NODEEXPORTER_SCRAPING_ENABLED",
+		KeepListEnvVar:          "NODEEXPORTER_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar:    "NODEEXPORTER_SCRAPE_INTERVAL",
+		alwaysSetScrapeInterval: true,
+		Configs: []DefaultTargetConfig{
+			{File: nodeExporterDefaultFileRsAdvanced, ControllerType: replicasetControllerType, Mode: "advanced", requireDSUpMetric: true, noKeepList: true, Placeholders: []string{"NODE_EXPORTER_NAME", "POD_NAMESPACE"}},
+			{File: nodeExporterDefaultFileRsSimple, ControllerType: replicasetControllerType, Mode: "simple", Placeholders: []string{"NODE_EXPORTER_NAME", "POD_NAMESPACE"}},
+			{File: nodeExporterDefaultFileDs, ControllerType: daemonsetControllerType, Mode: "advanced", OSType: "linux", Placeholders: []string{"NODE_IP", "NODE_EXPORTER_TARGETPORT", "NODE_NAME"}},
+		},
+	},
+	{
+		// Kappie and network observability are not supported to be scraped automatically outside the daemonset.
+		// If needed, the customer can disable the daemonset target and enable replicaset scraping through the custom configmap.
+		Name:                    "kappiebasic",
+		EnabledByDefault:        true,
+		EnabledEnvVar:           "AZMON_PROMETHEUS_KAPPIEBASIC_SCRAPING_ENABLED",
+		KeepListEnvVar:          "KAPPIEBASIC_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar:    "KAPPIEBASIC_SCRAPE_INTERVAL",
+		alwaysSetScrapeInterval: true,
+		Configs: []DefaultTargetConfig{
+			{File: kappieBasicDefaultFileDs, ControllerType: daemonsetControllerType, Mode: "advanced", RequireMAC: true, Placeholders: []string{"NODE_IP", "NODE_NAME"}},
+		},
+	},
+	{
+		Name:                 "networkobservabilityRetina",
+		EnabledByDefault:     true,
+		EnabledEnvVar:        "AZMON_PROMETHEUS_NETWORKOBSERVABILITYRETINA_SCRAPING_ENABLED",
+		KeepListEnvVar:       "NETWORKOBSERVABILITYRETINA_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar: "NETWORKOBSERVABILITYRETINA_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: networkObservabilityRetinaDefaultFileDs, ControllerType: daemonsetControllerType, Mode: "advanced", RequireMAC: true, Placeholders: []string{"NODE_IP", "NODE_NAME"}},
+		},
+	},
+	{
+		Name:                 "networkobservabilityHubble",
+		EnabledByDefault:     true,
+		EnabledEnvVar:        "AZMON_PROMETHEUS_NETWORKOBSERVABILITYHUBBLE_SCRAPING_ENABLED",
+		KeepListEnvVar:       "NETWORKOBSERVABILITYHUBBLE_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar: "NETWORKOBSERVABILITYHUBBLE_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: networkObservabilityHubbleDefaultFileDs, ControllerType: daemonsetControllerType, Mode: "advanced", OSType: "linux", RequireMAC: true, Placeholders: []string{"NODE_IP", "NODE_NAME"}},
+		},
+	},
+	{
+		Name:                 "networkobservabilityCilium",
+		EnabledByDefault:     true,
+		EnabledEnvVar:        "AZMON_PROMETHEUS_NETWORKOBSERVABILITYCILIUM_SCRAPING_ENABLED",
+		KeepListEnvVar:       "NETWORKOBSERVABILITYCILIUM_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar: "NETWORKOBSERVABILITYCILIUM_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: networkObservabilityCiliumDefaultFileDs, ControllerType: daemonsetControllerType, Mode: "advanced", OSType: "linux", RequireMAC: true, Placeholders: []string{"NODE_IP", "NODE_NAME"}},
+		},
+	},
+	{
+		Name:                 "ztunnel",
+		EnabledEnvVar:        "AZMON_PROMETHEUS_ZTUNNEL_SCRAPING_ENABLED",
+		KeepListEnvVar:       "ZTUNNEL_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar: "ZTUNNEL_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: ztunnelDefaultFile, ControllerType: replicasetControllerType, OSType: "linux", inPlace: true, Placeholders: []string{"NODE_IP", "NODE_NAME"}},
+		},
+	},
+	{
+		Name:                 "istio-cni",
+		EnabledEnvVar:        "AZMON_PROMETHEUS_ISTIOCNI_SCRAPING_ENABLED",
+		KeepListEnvVar:       "ISTIOCNI_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar: "ISTIOCNI_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: istioCniDefaultFile, ControllerType: replicasetControllerType, OSType: "linux", inPlace: true, Placeholders: []string{"NODE_IP", "NODE_NAME"}},
+		},
+	},
+	{
+		// Istio control plane (MCP) metrics are scraped from MESH_MEMBER_METRICS_FQDN, passed from the AKS RP
+		Name:                 "controlplane-istio",
+		EnabledEnvVar:        "AZMON_PROMETHEUS_CONTROLPLANE_ISTIO_ENABLED",
+		KeepListEnvVar:       "CONTROLPLANE_ISTIO_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar: "CONTROLPLANE_ISTIO_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: controlplaneIstioDefaultFile, ControllerType: replicasetControllerType, inPlace: true, Placeholders: []string{"MESH_MEMBER_METRICS_FQDN"}},
+		},
+		precondition: (*configPipeline).isMeshMemberMetricsFqdnValid,
+	},
+	{
+		Name:                 "prometheuscollectorhealth",
+		EnabledEnvVar:        "AZMON_PROMETHEUS_COLLECTOR_HEALTH_SCRAPING_ENABLED",
+		ScrapeIntervalEnvVar: "PROMETHEUS_COLLECTOR_HEALTH_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: prometheusCollectorHealthDefaultFile},
+		},
+	},
+	{
+		Name:                 "windowsexporter",
+		EnabledEnvVar:        "AZMON_PROMETHEUS_WINDOWSEXPORTER_SCRAPING_ENABLED",
+		KeepListEnvVar:       "WINDOWSEXPORTER_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar: "WINDOWSEXPORTER_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: windowsExporterDefaultRsSimpleFile, ControllerType: replicasetControllerType, Mode: "simple", OSType: "linux", notForSidecar: true, Placeholders: []string{"NODE_IP", "NODE_NAME"}},
+			{File: windowsExporterDefaultDsFile, ControllerType: daemonsetControllerType, Mode: "advanced", OSType: "windows", RequireWindowsDaemonset: true, Placeholders: []string{"NODE_IP", "NODE_NAME"}},
+		},
+	},
+	{
+		Name:                 "windowskubeproxy",
+		EnabledEnvVar:        "AZMON_PROMETHEUS_WINDOWSKUBEPROXY_SCRAPING_ENABLED",
+		KeepListEnvVar:       "WINDOWSKUBEPROXY_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar: "WINDOWSKUBEPROXY_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: windowsKubeProxyDefaultFileRsSimpleFile, ControllerType: replicasetControllerType, Mode: "simple", OSType: "linux", notForSidecar: true, Placeholders: []string{"NODE_IP", "NODE_NAME"}},
+			{File: windowsKubeProxyDefaultDsFile, ControllerType: daemonsetControllerType, Mode: "advanced", OSType: "windows", RequireWindowsDaemonset: true, Placeholders: []string{"NODE_IP", "NODE_NAME"}},
+		},
+	},
+	{
+		// Pod annotation based scraping is enabled through the pod-annotation-based-scraping section
+		Name:                 "podannotations",
+		EnabledEnvVar:        "AZMON_PROMETHEUS_POD_ANNOTATION_SCRAPING_ENABLED",
+		KeepListEnvVar:       "POD_ANNOTATION_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar: "POD_ANNOTATION_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: podAnnotationsDefaultFile, ControllerType: replicasetControllerType},
+		},
+		enabledExternally: true,
+		precondition: func(p *configPipeline) bool {
+			_, exists := p.env.LookupEnv("AZMON_PROMETHEUS_POD_ANNOTATION_NAMESPACES_REGEX")
+			return exists
+		},
+		customize: (*configPipeline).customizePodAnnotationsConfig,
+	},
+	{
+		Name:                 "acstor-capacity-provisioner",
+		EnabledByDefault:     true,
+		EnabledEnvVar:        "AZMON_PROMETHEUS_ACSTORCAPACITYPROVISIONER_SCRAPING_ENABLED",
+		KeepListEnvVar:       "ACSTORCAPACITYPROVISONER_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar: "ACSTORCAPACITYPROVISIONER_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: acstorCapacityProvisionerDefaultFile, ControllerType: replicasetControllerType},
+		},
+	},
+	{
+		Name:                 "acstor-metrics-exporter",
+		EnabledByDefault:     true,
+		EnabledEnvVar:        "AZMON_PROMETHEUS_ACSTORMETRICSEXPORTER_SCRAPING_ENABLED",
+		KeepListEnvVar:       "ACSTORMETRICSEXPORTER_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar: "ACSTORMETRICSEXPORTER_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: acstorMetricsExporterDefaultFile, ControllerType: replicasetControllerType},
+		},
+	},
+	{
+		Name:                 "local-csi-driver",
+		EnabledByDefault:     true,
+		EnabledEnvVar:        "AZMON_PROMETHEUS_LOCALCSIDRIVER_SCRAPING_ENABLED",
+		KeepListEnvVar:       "LOCALCSIDRIVER_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar: "LOCALCSIDRIVER_SCRAPE_INTERVAL",
+		Configs: []DefaultTargetConfig{
+			{File: LocalCSIDriverDefaultFile, ControllerType: replicasetControllerType},
+		},
+	},
+	{
+		Name:                    "dcgmexporter",
+		EnabledByDefault:        true,
+		EnabledEnvVar:           "AZMON_PROMETHEUS_DCGMEXPORTER_SCRAPING_ENABLED",
+		KeepListEnvVar:          "DCGMEXPORTER_METRICS_KEEP_LIST_REGEX",
+		ScrapeIntervalEnvVar:    "DCGMEXPORTER_SCRAPE_INTERVAL",
+		alwaysSetScrapeInterval: true,
+		Configs: []DefaultTargetConfig{
+			{File: dcgmExporterDefaultFile, ControllerType: replicasetControllerType, OSType: "linux"},
+		},
+	},
+}
+
+// loadDefaultTargets sets defaultTargets to the built-in default targets and the ones defined in the default targets
+// file. Invalid targets in the file are skipped.
+func (p *configPipeline) loadDefaultTargets() {
+	p.defaultTargets = builtInDefaultTargets
+
+	contents, err := p.fs.ReadFile(p.paths.defaultTargetsFilePath)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		log.Printf("Error reading default targets file %s: %v. Only the built-in default targets will be used\n", p.paths.defaultTargetsFilePath, err)
+		return
+	}
+
+	var targetsFile defaultTargetsFile
+	if err := yaml.UnmarshalStrict(contents, &targetsFile); err != nil {
+		log.Printf("Error parsing default targets file %s: %v. Only the built-in default targets will be used\n", p.paths.defaultTargetsFilePath, err)
+		return
+	}
+
+	targets := make([]DefaultTarget, len(builtInDefaultTargets), len(builtInDefaultTargets)+len(targetsFile.Targets))
+	copy(targets, builtInDefaultTargets)
+	for _, target := range targetsFile.Targets {
+		target.setDefaultEnvVars()
+		if err := validateDefaultTarget(target, targets); err != nil {
+			log.Printf("Skipping default target %q from %s: %v\n", target.Name, p.paths.defaultTargetsFilePath, err)
+			continue
+		}
+		log.Printf("Adding default target %s from %s\n", target.Name, p.paths.defaultTargetsFilePath)
+		targets = append(targets, target)
+	}
+	p.defaultTargets = targets
+}
+
+// setDefaultEnvVars derives the env vars that are not set from the target name the same way as the built-in targets,
+// for example my-exporter gets AZMON_PROMETHEUS_MYEXPORTER_SCRAPING_ENABLED.
+func (t *DefaultTarget) setDefaultEnvVars() {
+	envName := strings.ToUpper(nonAlphaNumericRegex.ReplaceAllString(t.Name, ""))
+	if t.EnabledEnvVar == "" {
+		t.EnabledEnvVar = fmt.Sprintf("AZMON_PROMETHEUS_%s_SCRAPING_ENABLED", envName)
+	}
+	if t.KeepListEnvVar == "" {
+		t.KeepListEnvVar = fmt.Sprintf("%s_METRICS_KEEP_LIST_REGEX", envName)
+	}
+	if t.ScrapeIntervalEnvVar == "" {
+		t.ScrapeIntervalEnvVar = fmt.Sprintf("%s_SCRAPE_INTERVAL", envName)
+	}
+}
+
+func validateDefaultTarget(target DefaultTarget, existingTargets []DefaultTarget) error {
+	if target.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	for _, existing := range existingTargets {
+		if existing.Name == target.Name {
+			return fmt.Errorf("a default target with this name already exists")
+		}
+		for _, envVar := range []string{target.EnabledEnvVar, target.KeepListEnvVar, target.ScrapeIntervalEnvVar} {
+			if envVar == existing.EnabledEnvVar || envVar == existing.KeepListEnvVar || envVar == existing.ScrapeIntervalEnvVar {
+				return fmt.Errorf("env var %s is already used by default target %s", envVar, existing.Name)
+			}
+		}
+	}
+	if target.MinimalIngestionProfileRegex != "" && !isValidRegex(target.MinimalIngestionProfileRegex) {
+		return fmt.Errorf("invalid minimal ingestion profile regex: %s", target.MinimalIngestionProfileRegex)
+	}
+	if len(target.Configs) == 0 {
+		return fmt.Errorf("at least one config is required")
+	}
+	for _, config := range target.Configs {
+		if config.File == "" || filepath.Base(config.File) != config.File {
+			return fmt.Errorf("config file must be a file name in the default-prom-configs directory, got %q", config.File)
+		}
+		if config.ControllerType != "" && config.ControllerType != replicasetControllerType && config.ControllerType != daemonsetControllerType {
+			return fmt.Errorf("unsupported controllerType %q for %s", config.ControllerType, config.File)
+		}
+		if config.OSType != "" && config.OSType != "linux" && config.OSType != "windows" {
+			return fmt.Errorf("unsupported osType %q for %s", config.OSType, config.File)
+		}
+		if config.Mode != "" && config.Mode != "simple" && config.Mode != "advanced" {
+			return fmt.Errorf("unsupported mode %q for %s", config.Mode, config.File)
+		}
+	}
+	return nil
+}
+
+func (p *configPipeline) getDefaultTargetAgent(operatorEnabled bool) defaultTargetAgent {
+	return defaultTargetAgent{
+		controllerType:      strings.TrimSpace(strings.ToLower(p.env.Getenv("CONTROLLER_TYPE"))),
+		osType:              strings.TrimSpace(strings.ToLower(p.env.Getenv("OS_TYPE"))),
+		advancedMode:        strings.TrimSpace(strings.ToLower(p.env.Getenv("MODE"))) == "advanced",
+		windowsDaemonset:    strings.TrimSpace(strings.ToLower(p.env.Getenv("WINMODE"))) == "advanced",
+		mac:                 strings.ToLower(p.env.Getenv("MAC")) == "true",
+		configReaderSidecar: operatorEnabled && p.isConfigReaderSidecar(),
+	}
+}
+
+// matches reports whether the config is used on the agent. The config reader sidecar generates the replicaset
+// config, regardless of its OS type.
+func (c *DefaultTargetConfig) matches(agent defaultTargetAgent) bool {
+	forSidecar := false
+	switch c.ControllerType {
+	case replicasetControllerType:
+		forSidecar = agent.configReaderSidecar && !c.notForSidecar
+		if agent.controllerType != replicasetControllerType && !forSidecar {
+			return false
+		}
+	case daemonsetControllerType:
+		if agent.controllerType != daemonsetControllerType || agent.configReaderSidecar {
+			return false
+		}
+	}
+	if c.OSType != "" && c.OSType != agent.osType && !forSidecar {
+		return false
+	}
+	if (c.Mode == "advanced" && !agent.advancedMode) || (c.Mode == "simple" && agent.advancedMode) {
+		return false
+	}
+	if (c.RequireMAC && !agent.mac) || (c.RequireWindowsDaemonset && !agent.windowsDaemonset) {
+		return false
+	}
+	return !c.requireDSUpMetric || sendDSUpMetric
+}
+
+// configFor returns the first config of the target used on the agent, or nil if the target is not scraped by it.
+func (t *DefaultTarget) configFor(agent defaultTargetAgent) *DefaultTargetConfig {
+	for i := range t.Configs {
+		if t.Configs[i].matches(agent) {
+			return &t.Configs[i]
+		}
+	}
+	return nil
+}
+
+// enabledConfigFor returns the config of the target used on the agent when the target is enabled, or nil.
+func (t *DefaultTarget) enabledConfigFor(p *configPipeline, agent defaultTargetAgent) *DefaultTargetConfig {
+	if enabled, exists := p.env.LookupEnv(t.EnabledEnvVar); !exists || strings.ToLower(enabled) != "true" {
+		return nil
+	}
+	if _, exists := p.intervalHash[t.ScrapeIntervalEnvVar]; !exists && t.requireScrapeInterval {
+		return nil
+	}
+	config := t.configFor(agent)
+	if config == nil || (t.precondition != nil && !t.precondition(p)) {
+		return nil
+	}
+	return config
+}
+
+// scrapeIntervalSetting returns the scrape interval to write to the scrape config of the target, and whether to
+// write it.
+func (t *DefaultTarget) scrapeIntervalSetting(p *configPipeline) (string, bool) {
+	scrapeInterval, exists := p.intervalHash[t.ScrapeIntervalEnvVar]
+	return scrapeInterval, exists || t.alwaysSetScrapeInterval
+}
+
+func (p *configPipeline) replacePlaceholders(configFile string, placeholders []string) error {
+	contents, err := p.fs.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	for _, placeholder := range placeholders {
+		contents = []byte(strings.ReplaceAll(string(contents), fmt.Sprintf("$$%s$$", placeholder), p.env.Getenv(placeholder)))
+	}
+	return p.fs.WriteFile(configFile, contents, 0644)
+}
+
+func (p *configPipeline) isMeshMemberMetricsFqdnValid() bool {
+	// The FQDN is a full URL like "https://mcp.metrics.endpoint.example.com"
+	// The http_sd_configs endpoint is at /v1/targets and requires Bearer token auth
+	meshMemberMetricsFqdn := p.env.Getenv("MESH_MEMBER_METRICS_FQDN")
+	if meshMemberMetricsFqdn == "" {
+		log.Println("Istio control plane metrics enabled but MESH_MEMBER_METRICS_FQDN environment variable is not set, skipping Istio scraping")
+		return false
+	}
+	log.Printf("Istio control plane metrics enabled with FQDN: %s\n", meshMemberMetricsFqdn)
+	parsedURL, parseErr := url.Parse(meshMemberMetricsFqdn)
+	if parseErr != nil {
+		log.Printf("Failed to parse MESH_MEMBER_METRICS_FQDN as URL: %s, skipping Istio scraping\n", parseErr)
+		return false
+	} else if parsedURL.Host == "" {
+		log.Printf("MESH_MEMBER_METRICS_FQDN must include scheme and host (e.g., https://host): %s, skipping Istio scraping\n", meshMemberMetricsFqdn)
+		return false
+	}
+	return true
+}
+
+// customizePodAnnotationsConfig restricts the pod annotations job to the namespace regex and applies the other
+// settings of the pod-annotation-based-scraping section
+func (p *configPipeline) customizePodAnnotationsConfig(configFile string) {
+	p.appendPodAnnotationNamespacesRelabelConfig(configFile)
+	if err := p.applyPodAnnotationSettings(configFile, p.loadedPodAnnotationSettings); err != nil {
+		log.Printf("Error applying the pod annotation settings to %s: %v\n", configFile, err)
+	}
+}
+
+func (p *configPipeline) appendPodAnnotationNamespacesRelabelConfig(configFile string) {
+	podannotationNamespacesRegex := p.env.Getenv("AZMON_PROMETHEUS_POD_ANNOTATION_NAMESPACES_REGEX")
+	// Trim the first and last escaped quotes if they exist
+	if len(podannotationNamespacesRegex) > 1 && podannotationNamespacesRegex[0] == '"' && podannotationNamespacesRegex[len(podannotationNamespacesRegex)-1] == '"' {
+		podannotationNamespacesRegex = podannotationNamespacesRegex[1 : len(podannotationNamespacesRegex)-1]
+	}
+	// Additional trim to remove single quotes if present
+	podannotationNamespacesRegex = strings.Trim(podannotationNamespacesRegex, "'")
+
+	if podannotationNamespacesRegex != "" {
+		relabelConfig := []map[string]interface{}{
+			{"source_labels": []string{"__meta_kubernetes_namespace"}, "action": "keep", "regex": podannotationNamespacesRegex},
+		}
+		p.AppendRelabelConfig(configFile, relabelConfig, podannotationNamespacesRegex)
+	}
+}
+
+// applyPodAnnotationSettings sets the label selector on the pod annotations job, then adds a job for each namespace
+// with settings, named <job>-ns-<namespace>, and a job for each indexed port of each job, named <job>-port-<index>. The
+// pods of the namespaces with settings are dropped from the pod annotations job. The settings are rejected when two
+// jobs get the same name, e.g. for the namespaces a and a-port-1.
+func (p *configPipeline) applyPodAnnotationSettings(configFile string, settings podAnnotationSettings) error {
+	if settings.LabelSelector == "" && settings.IndexedPortCount == 0 && len(settings.Namespaces) == 0 {
+		return nil
+	}
+	config, err := p.loadYAMLFromFile(configFile)
+	if err != nil {
+		return err
+	}
+	scrapeConfigs, _ := config["scrape_configs"].([]interface{})
+	if len(scrapeConfigs) == 0 {
+		return fmt.Errorf("no scrape configs found")
+	}
+	baseJob, ok := scrapeConfigs[0].(map[interface{}]interface{})
+	if !ok {
+		return fmt.Errorf("invalid scrape config")
+	}
+
+	if settings.LabelSelector != "" {
+		for _, sdConfig := range kubernetesSDConfigs(baseJob) {
+			sdConfig["selectors"] = []interface{}{map[interface{}]interface{}{"role": "pod", "label": settings.LabelSelector}}
+		}
+	}
+
+	jobs := []map[interface{}]interface{}{baseJob}
+	namespaces := slices.Sorted(maps.Keys(settings.Namespaces))
+	for _, namespace := range namespaces {
+		job, err := cloneScrapeConfig(baseJob)
+		if err != nil {
+			return err
+		}
+		job["job_name"] = fmt.Sprintf("%v-ns-%s", baseJob["job_name"], namespace)
+		for _, sdConfig := range kubernetesSDConfigs(job) {
+			sdConfig["namespaces"] = map[interface{}]interface{}{"names": []interface{}{namespace}}
+		}
+		settings.Namespaces[namespace].apply(job)
+		jobs = append(jobs, job)
+	}
+	if len(namespaces) > 0 {
+		baseJob["relabel_configs"] = append(relabelConfigs(baseJob), map[interface{}]interface{}{
+			"source_labels": []interface{}{"__meta_kubernetes_namespace"},
+			"action":        "drop",
+			"regex":         strings.Join(namespaces, "|"),
+		})
+	}
+
+	for _, job := range slices.Clone(jobs) {
+		for index := 1; index <= settings.IndexedPortCount; index++ {
+			indexedJob, err := cloneScrapeConfig(job)
+			if err != nil {
+				return err
+			}
+			indexedJob["job_name"] = fmt.Sprintf("%v-port-%d", job["job_name"], index)
+			useIndexedPodAnnotations(indexedJob, index)
+			jobs = append(jobs, indexedJob)
+		}
+	}
+
+	jobNames := map[string]bool{}
+	updatedScrapeConfigs := make([]interface{}, 0, len(jobs))
+	for _, job := range jobs {
+		jobName := fmt.Sprint(job["job_name"])
+		if jobNames[jobName] {
+			return fmt.Errorf("the job name %s is used for two jobs of the pod annotation settings", jobName)
+		}
+		jobNames[jobName] = true
+		updatedScrapeConfigs = append(updatedScrapeConfigs, job)
+	}
+	config["scrape_configs"] = updatedScrapeConfigs
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return p.fs.WriteFile(configFile, out, 0644)
+}
+
+// apply sets the settings of the namespace on its job
+func (n podAnnotationNamespaceSettings) apply(job map[interface{}]interface{}) {
+	if n.ScrapeInterval != "" {
+		job["scrape_interval"] = n.ScrapeInterval
+	}
+	if n.Scheme != "" {
+		job["scheme"] = n.Scheme
+	}
+	tlsConfig := map[interface{}]interface{}{}
+	if n.TLSCAFile != "" {
+		tlsConfig["ca_file"] = n.TLSCAFile
+	}
+	if n.TLSServerName != "" {
+		tlsConfig["server_name"] = n.TLSServerName
+	}
+	if n.TLSInsecureSkipVerify {
+		tlsConfig["insecure_skip_verify"] = true
+	}
+	if len(tlsConfig) > 0 {
+		job["tls_config"] = tlsConfig
+	}
+	if n.BearerTokenFile != "" {
+		job["authorization"] = map[interface{}]interface{}{"type": "Bearer", "credentials_file": n.BearerTokenFile}
+	}
+}
+
+// useIndexedPodAnnotations makes the job scrape the prometheus.io/port-<index> and prometheus.io/path-<index>
+// annotations of the pods instead of prometheus.io/port and prometheus.io/path, keeping the pods with the port
+func useIndexedPodAnnotations(job map[interface{}]interface{}, index int) {
+	indexedLabels := map[string]string{}
+	for _, annotation := range []string{"port", "path"} {
+		label := "__meta_kubernetes_pod_annotation_prometheus_io_" + annotation
+		indexedLabels[label] = fmt.Sprintf("%s_%d", label, index)
+	}
+	for _, relabelConfig := range relabelConfigs(job) {
+		relabelMap, _ := relabelConfig.(map[interface{}]interface{})
+		sourceLabels, _ := relabelMap["source_labels"].([]interface{})
+		for i, sourceLabel := range sourceLabels {
+			if indexedLabel, exists := indexedLabels[fmt.Sprint(sourceLabel)]; exists {
+				sourceLabels[i] = indexedLabel
+			}
+		}
+	}
+	job["relabel_configs"] = append(relabelConfigs(job), map[interface{}]interface{}{
+		"source_labels": []interface{}{indexedLabels["__meta_kubernetes_pod_annotation_prometheus_io_port"]},
+		"action":        "keep",
+		"regex":         `\d+`,
+	})
+}
+
+func kubernetesSDConfigs(job map[interface{}]interface{}) []map[interface{}]interface{} {
+	sdConfigs := []map[interface{}]interface{}{}
+	list, _ := job["kubernetes_sd_configs"].([]interface{})
+	for _, sdConfig := range list {
+		if sdConfigMap, ok := sdConfig.(map[interface{}]interface{}); ok {
+			sdConfigs = append(sdConfigs, sdConfigMap)
+		}
+	}
+	return sdConfigs
+}
+
+func relabelConfigs(job map[interface{}]interface{}) []interface{} {
+	list, _ := job["relabel_configs"].([]interface{})
+	return list
+}
+
+func cloneScrapeConfig(job map[interface{}]interface{}) (map[interface{}]interface{}, error) {
+	out, err := yaml.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	var clone map[interface{}]interface{}
+	err = yaml.Unmarshal(out, &clone)
+	return clone, err
+}
@@ -0,0 +1,139 @@
+package configmapsettings
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DefaultTargets", func() {
+	findTarget := func(name string) *DefaultTarget {
+		for i := range testPipeline.defaultTargets {
+			if testPipeline.defaultTargets[i].Name == name {
+				return &testPipeline.defaultTargets[i]
+			}
+		}
+		return nil
+	}
+
+	AfterEach(func() {
+		cleanupEnvVars()
+	})
+
+	Context("when the default targets file does not exist", func() {
+		It("should only use the built-in default targets", func() {
+			testPipeline.paths.defaultTargetsFilePath = "/path/to/nonexistent/file"
+			testPipeline.loadDefaultTargets()
+			Expect(testPipeline.defaultTargets).To(HaveLen(len(builtInDefaultTargets)))
+		})
+	})
+
+	Context("when the default targets file adds targets", func() {
+		BeforeEach(func() {
+			testPipeline.paths.defaultTargetsFilePath = createTempFile("default-targets", `targets:
+- name: my-exporter
+  enabledByDefault: true
+  minimalIngestionProfileRegex: "my_metric_.*"
+  configs:
+  - file: myExporterDefault.yml
+    controllerType: replicaset
+- name: kubelet
+  configs:
+  - file: kubeletCustom.yml
+- name: bad-file
+  configs:
+  - file: ../bad-file.yml
+- name: bad-mode
+  configs:
+  - file: badMode.yml
+    mode: other
+`)
+			testPipeline.loadDefaultTargets()
+		})
+
+		It("should add the valid targets with env vars derived from the name", func() {
+			Expect(testPipeline.defaultTargets).To(HaveLen(len(builtInDefaultTargets) + 1))
+			target := findTarget("my-exporter")
+			Expect(target).NotTo(BeNil())
+			Expect(target.EnabledEnvVar).To(Equal("AZMON_PROMETHEUS_MYEXPORTER_SCRAPING_ENABLED"))
+			Expect(target.KeepListEnvVar).To(Equal("MYEXPORTER_METRICS_KEEP_LIST_REGEX"))
+			Expect(target.ScrapeIntervalEnvVar).To(Equal("MYEXPORTER_SCRAPE_INTERVAL"))
+		})
+
+		It("should include the targets in the default settings, keep list and scrape intervals", func() {
+			defaultSettings, err := (&FilesystemConfigLoader{pipeline: testPipeline}).SetDefaultScrapeSettings()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(defaultSettings).To(HaveKeyWithValue("my-exporter", "true"))
+			Expect(defaultSettings).NotTo(HaveKey("podannotations"))
+
+			keepListRegexes := testPipeline.populateRegexValuesWithMinimalIngestionProfile(RegexValues{
+				keepLists:               map[string]string{"my-exporter": "foo"},
+				minimalingestionprofile: "true",
+			})
+			Expect(keepListRegexes).To(HaveKeyWithValue("MYEXPORTER_METRICS_KEEP_LIST_REGEX", "foo|my_metric_.*"))
+			Expect(keepListRegexes).To(HaveKeyWithValue("POD_ANNOTATION_METRICS_KEEP_LIST_REGEX", ""))
+			Expect(keepListRegexes).NotTo(HaveKey(""))
+
+			setEnvVars(map[string]string{"AZMON_AGENT_CFG_SCHEMA_VERSION": "v2"})
+			intervals := testPipeline.processConfigMap(map[string]map[string]string{
+				"default-targets-scrape-interval-settings": {"my-exporter": "45s"},
+			})
+			Expect(intervals).To(HaveKeyWithValue("MYEXPORTER_SCRAPE_INTERVAL", "45s"))
+			Expect(intervals).To(HaveKeyWithValue("KUBELET_SCRAPE_INTERVAL", "30s"))
+		})
+	})
+
+	Context("when matching default target configs to the agent", func() {
+		It("should use the replicaset configs for the config reader sidecar", func() {
+			sidecar := defaultTargetAgent{osType: "windows", configReaderSidecar: true}
+			Expect(findTarget("kubelet").configFor(sidecar).File).To(Equal(kubeletDefaultFileRsSimple))
+			Expect(findTarget("ztunnel").configFor(sidecar).File).To(Equal(ztunnelDefaultFile))
+			Expect(findTarget("windowsexporter").configFor(sidecar)).To(BeNil())
+			Expect(findTarget("cadvisor").configFor(defaultTargetAgent{controllerType: "daemonset", advancedMode: true, configReaderSidecar: true})).To(BeNil())
+		})
+
+		It("should use the daemonset configs by OS type, mode and MAC", func() {
+			linuxDs := defaultTargetAgent{controllerType: "daemonset", osType: "linux", advancedMode: true}
+			windowsDs := defaultTargetAgent{controllerType: "daemonset", osType: "windows", advancedMode: true, windowsDaemonset: true}
+			Expect(findTarget("kubelet").configFor(linuxDs).File).To(Equal(kubeletDefaultFileDs))
+			Expect(findTarget("kubelet").configFor(windowsDs).File).To(Equal(kubeletDefaultFileDs))
+			Expect(findTarget("windowsexporter").configFor(windowsDs).File).To(Equal(windowsExporterDefaultDsFile))
+			Expect(findTarget("kappiebasic").configFor(linuxDs)).To(BeNil())
+			linuxDs.mac = true
+			Expect(findTarget("kappiebasic").configFor(linuxDs).File).To(Equal(kappieBasicDefaultFileDs))
+			Expect(findTarget("kubestate").configFor(linuxDs)).To(BeNil())
+		})
+	})
+
+	Context("when the scrape interval of a target is not set", func() {
+		BeforeEach(func() {
+			setEnvVars(map[string]string{
+				"AZMON_PROMETHEUS_CADVISOR_SCRAPING_ENABLED": "true",
+				"AZMON_PROMETHEUS_COREDNS_SCRAPING_ENABLED":  "true",
+			})
+		})
+
+		It("should only scrape cadvisor with a scrape interval", func() {
+			replicaset := defaultTargetAgent{controllerType: replicasetControllerType}
+			Expect(findTarget("cadvisor").enabledConfigFor(testPipeline, replicaset)).To(BeNil())
+			Expect(findTarget("coredns").enabledConfigFor(testPipeline, replicaset)).NotTo(BeNil())
+
+			testPipeline.intervalHash["CADVISOR_SCRAPE_INTERVAL"] = "15s"
+			Expect(findTarget("cadvisor").enabledConfigFor(testPipeline, replicaset).File).To(Equal(cadvisorDefaultFileRsSimple))
+		})
+
+		It("should always set the scrape interval of kubelet, nodeexporter, kappiebasic and dcgmexporter", func() {
+			for _, name := range []string{"kubelet", "nodeexporter", "kappiebasic", "dcgmexporter"} {
+				scrapeInterval, set := findTarget(name).scrapeIntervalSetting(testPipeline)
+				Expect(set).To(BeTrue(), name)
+				Expect(scrapeInterval).To(BeEmpty(), name)
+			}
+			_, set := findTarget("coredns").scrapeIntervalSetting(testPipeline)
+			Expect(set).To(BeFalse())
+
+			testPipeline.intervalHash["COREDNS_SCRAPE_INTERVAL"] = "45s"
+			scrapeInterval, set := findTarget("coredns").scrapeIntervalSetting(testPipeline)
+			Expect(set).To(BeTrue())
+			Expect(scrapeInterval).To(Equal("45s"))
+		})
+	})
+})
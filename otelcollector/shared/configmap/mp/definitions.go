@@ -1,30 +1,133 @@
 package configmapsettings
 
+import "path/filepath"
+
+// configPaths are the files and directories the config pipeline reads and writes.
+type configPaths struct {
+	configMapSettingsDir                   string
+	configMapMountPath                     string
+	customConfigFragmentsDir               string
+	schemaVersionFile                      string
+	configVersionFile                      string
+	configMapDebugMountPath                string
+	configMapOpentelemetryMetricsMountPath string
+	collectorProcessorsMountPath           string
+	replicaSetCollectorConfig              string
+	debugModeEnvVarPath                    string
+	defaultSettingsMountPath               string
+	defaultSettingsMountPathv2             string
+	defaultSettingsEnvVarPath              string
+	configMapMountPathForPodAnnotation     string
+	podAnnotationEnvVarPath                string
+	podAnnotationSettingsEnvVarPath        string
+	collectorSettingsMountPath             string
+	collectorSettingsEnvVarPath            string
+	opentelemetryMetricsEnvVarPath         string
+	ksmConfigEnvVarPath                    string
+	configMapKeepListMountPath             string
+	configMapKeepListEnvVarPath            string
+	configMapMetricsDropListEnvVarPath     string
+	configMapLabelDropListEnvVarPath       string
+	configMapScrapeIntervalMountPath       string
+	scrapeIntervalEnvVarPath               string
+	scrapeLimitsEnvVarPath                 string
+	externalLabelsEnvVarPath               string
+	collectorProcessorsEnvVarPath          string
+	configProvenanceEnvVarPath             string
+	labelLimitChangesEnvVarPath            string
+	promMergedConfigPath                   string
+	mergedDefaultConfigPath                string
+	// defaultPromConfigsDir holds the default scrape config files, some of which are modified in place
+	defaultPromConfigsDir string
+	// defaultPromConfigsBaselineDir holds the default scrape config files as shipped, which a reload starts from
+	defaultPromConfigsBaselineDir string
+	// defaultScrapeConfigsDir holds the copies of the default scrape config files that are not modified in place
+	defaultScrapeConfigsDir         string
+	defaultTargetsFilePath          string
+	ingestionProfilesDir            string
+	ingestionProfilesEnvVarPath     string
+	collectorConfigPath             string
+	collectorConfigDefaultPath      string
+	collectorConfigTemplatePath     string
+	collectorConfigWithDefaultsPath string
+	lastKnownGoodConfigDir          string
+	promConfigValidatorPath         string
+	promConfigValidatorEnvVarPath   string
+	envVarsFilePath                 string
+}
+
+// newConfigPaths returns the paths used in the agent containers, under root instead of / when it is not empty.
+func newConfigPaths(root string) configPaths {
+	settingsDir := root + "/etc/config/settings"
+	parserDir := root + "/opt/microsoft/configmapparser"
+	collectorDir := root + "/opt/microsoft/otelcollector"
+	return configPaths{
+		configMapSettingsDir:                   settingsDir,
+		configMapMountPath:                     settingsDir + "/prometheus/prometheus-config",
+		customConfigFragmentsDir:               settingsDir + "/prometheus-fragments",
+		schemaVersionFile:                      settingsDir + "/schema-version",
+		configVersionFile:                      settingsDir + "/config-version",
+		configMapDebugMountPath:                settingsDir + "/debug-mode",
+		configMapOpentelemetryMetricsMountPath: settingsDir + "/opentelemetry-metrics",
+		collectorProcessorsMountPath:           settingsDir + "/collector-processors",
+		replicaSetCollectorConfig:              collectorDir + "/collector-config-replicaset.yml",
+		debugModeEnvVarPath:                    parserDir + "/config_debug_mode_env_var",
+		defaultSettingsMountPath:               settingsDir + "/default-scrape-settings-enabled",
+		defaultSettingsMountPathv2:             settingsDir + "/default-targets-scrape-enabled",
+		defaultSettingsEnvVarPath:              parserDir + "/config_default_scrape_settings_env_var",
+		configMapMountPathForPodAnnotation:     settingsDir + "/pod-annotation-based-scraping",
+		podAnnotationEnvVarPath:                parserDir + "/config_def_pod_annotation_based_scraping",
+		podAnnotationSettingsEnvVarPath:        parserDir + "/config_pod_annotation_settings",
+		collectorSettingsMountPath:             settingsDir + "/prometheus-collector-settings",
+		collectorSettingsEnvVarPath:            parserDir + "/config_prometheus_collector_settings_env_var",
+		opentelemetryMetricsEnvVarPath:         parserDir + "/config_opentelemetry_metrics_env_var",
+		ksmConfigEnvVarPath:                    parserDir + "/config_ksm_config_env_var",
+		configMapKeepListMountPath:             settingsDir + "/default-targets-metrics-keep-list",
+		configMapKeepListEnvVarPath:            parserDir + "/config_def_targets_metrics_keep_list_hash",
+		configMapMetricsDropListEnvVarPath:     parserDir + "/config_def_targets_metrics_drop_list_hash",
+		configMapLabelDropListEnvVarPath:       parserDir + "/config_def_targets_label_drop_list_hash",
+		configMapScrapeIntervalMountPath:       settingsDir + "/default-targets-scrape-interval-settings",
+		scrapeIntervalEnvVarPath:               parserDir + "/config_def_targets_scrape_intervals_hash",
+		scrapeLimitsEnvVarPath:                 parserDir + "/config_scrape_limits_hash",
+		externalLabelsEnvVarPath:               parserDir + "/config_external_labels",
+		collectorProcessorsEnvVarPath:          parserDir + "/config_collector_processors",
+		configProvenanceEnvVarPath:             parserDir + "/config_provenance.json",
+		labelLimitChangesEnvVarPath:            parserDir + "/label_limit_changes.json",
+		promMergedConfigPath:                   root + "/opt/promMergedConfig.yml",
+		mergedDefaultConfigPath:                root + "/opt/defaultsMergedConfig.yml",
+		defaultPromConfigsDir:                  collectorDir + "/default-prom-configs",
+		defaultPromConfigsBaselineDir:          collectorDir + "/default-prom-configs-baseline",
+		defaultScrapeConfigsDir:                parserDir + "/default-scrape-configs",
+		defaultTargetsFilePath:                 collectorDir + "/default-targets.yaml",
+		ingestionProfilesDir:                   collectorDir + "/ingestion-profiles",
+		ingestionProfilesEnvVarPath:            parserDir + "/config_def_targets_ingestion_profiles_hash",
+		collectorConfigPath:                    collectorDir + "/collector-config.yml",
+		collectorConfigDefaultPath:             collectorDir + "/collector-config-default.yml",
+		collectorConfigTemplatePath:            collectorDir + "/collector-config-template.yml",
+		collectorConfigWithDefaultsPath:        root + "/opt/collector-config-with-defaults.yml",
+		lastKnownGoodConfigDir:                 root + "/etc/config/last-known-good",
+		promConfigValidatorPath:                root + "/opt/promconfigvalidator",
+		promConfigValidatorEnvVarPath:          root + "/opt/microsoft/prom_config_validator_env_var",
+		envVarsFilePath:                        root + "/opt/envvars.env",
+	}
+}
+
+// directories returns the directories the files of the config pipeline are in.
+func (c configPaths) directories() []string {
+	return []string{
+		c.configMapSettingsDir,
+		filepath.Dir(c.configMapMountPath),
+		filepath.Dir(c.debugModeEnvVarPath),
+		filepath.Dir(c.replicaSetCollectorConfig),
+		filepath.Dir(c.promMergedConfigPath),
+		c.defaultPromConfigsDir,
+		c.defaultScrapeConfigsDir,
+		c.ingestionProfilesDir,
+		c.lastKnownGoodConfigDir,
+	}
+}
+
 var (
-	schemaVersionFile                            = "/etc/config/settings/schema-version"
-	configVersionFile                            = "/etc/config/settings/config-version"
-	configMapDebugMountPath                      = "/etc/config/settings/debug-mode"
-	configMapOpentelemetryMetricsMountPath       = "/etc/config/settings/opentelemetry-metrics"
-	replicaSetCollectorConfig                    = "/opt/microsoft/otelcollector/collector-config-replicaset.yml"
-	debugModeEnvVarPath                          = "/opt/microsoft/configmapparser/config_debug_mode_env_var"
-	defaultSettingsMountPath                     = "/etc/config/settings/default-scrape-settings-enabled"
-	defaultSettingsMountPathv2                   = "/etc/config/settings/default-targets-scrape-enabled"
-	defaultSettingsEnvVarPath                    = "/opt/microsoft/configmapparser/config_default_scrape_settings_env_var"
-	configMapMountPathForPodAnnotation           = "/etc/config/settings/pod-annotation-based-scraping"
-	podAnnotationEnvVarPath                      = "/opt/microsoft/configmapparser/config_def_pod_annotation_based_scraping"
-	collectorSettingsMountPath                   = "/etc/config/settings/prometheus-collector-settings"
-	collectorSettingsEnvVarPath                  = "/opt/microsoft/configmapparser/config_prometheus_collector_settings_env_var"
-	opentelemetryMetricsEnvVarPath               = "/opt/microsoft/configmapparser/config_opentelemetry_metrics_env_var"
-	ksmConfigEnvVarPath                          = "/opt/microsoft/configmapparser/config_ksm_config_env_var"
-	configMapKeepListMountPath                   = "/etc/config/settings/default-targets-metrics-keep-list"
-	configMapKeepListEnvVarPath                  = "/opt/microsoft/configmapparser/config_def_targets_metrics_keep_list_hash"
-	configMapScrapeIntervalMountPath             = "/etc/config/settings/default-targets-scrape-interval-settings"
-	scrapeIntervalEnvVarPath                     = "/opt/microsoft/configmapparser/config_def_targets_scrape_intervals_hash"
-	promMergedConfigPath                         = "/opt/promMergedConfig.yml"
-	mergedDefaultConfigPath                      = "/opt/defaultsMergedConfig.yml"
-	defaultPromConfigPathPrefix                  = "/opt/microsoft/otelcollector/default-prom-configs/"
-	regexHashFile                                = "/opt/microsoft/configmapparser/config_def_targets_metrics_keep_list_hash"
-	intervalHashFile                             = "/opt/microsoft/configmapparser/config_def_targets_scrape_intervals_hash"
 	kubeletDefaultFileRsSimple                   = "kubeletDefaultRsSimple.yml"
 	kubeletDefaultFileRsAdvanced                 = "kubeletDefaultRsAdvanced.yml"
 	kubeletDefaultFileDs                         = "kubeletDefaultDs.yml"
@@ -59,33 +162,22 @@ var (
 )
 
 type RegexValues struct {
-	kubelet                    string
-	coredns                    string
-	cadvisor                   string
-	kubeproxy                  string
-	apiserver                  string
-	kubestate                  string
-	nodeexporter               string
-	kappiebasic                string
-	windowsexporter            string
-	windowskubeproxy           string
-	networkobservabilityretina string
-	networkobservabilityhubble string
-	networkobservabilitycilium string
-	podannotations             string
-	minimalingestionprofile    string
-	acstorcapacityprovisioner  string
-	acstormetricsexporter      string
-	localcsidriver             string
-	ztunnel                    string
-	istiocni                   string
-	dcgmexporter               string
-	controlplaneistio          string
+	// keepLists holds the keep list regex from the settings configmap, keyed by default target name
+	keepLists map[string]string
+	// metricsDropLists and labelDropLists hold the metric and label drop list regex from the settings configmap,
+	// keyed by default target name
+	metricsDropLists        map[string]string
+	labelDropLists          map[string]string
+	minimalingestionprofile string
+	// ingestionProfiles holds the name of the ingestion profile used, keyed by default target name
+	ingestionProfiles map[string]string
 }
 
 // FilesystemConfigLoader implements ConfigLoader for file-based configuration loading.
 type FilesystemConfigLoader struct {
 	ConfigMapMountPath string
+
+	pipeline *configPipeline
 }
 
 // ConfigProcessor handles the processing of configuration settings.
@@ -101,33 +193,15 @@ type ConfigProcessor struct {
 	ControlplaneClusterAutoscaler           string
 	ControlplaneNodeAutoProvisioning        string
 	ControlplaneEtcd                        string
-	ControlplaneIstio                       string
 	NoDefaultsEnabled                       bool
 	TargetallocatorHttpsEnabled             bool
 	TargetallocatorHttpsEnabledChartSetting bool
 	SecretsAccessNamespaces                 []string
 
-	Kubelet                    string
-	Coredns                    string
-	Cadvisor                   string
-	Kubeproxy                  string
-	Apiserver                  string
-	Kubestate                  string
-	NodeExporter               string
-	PrometheusCollectorHealth  string
-	PodAnnotation              string
-	Windowsexporter            string
-	Windowskubeproxy           string
-	Kappiebasic                string
-	NetworkObservabilityRetina string
-	NetworkObservabilityHubble string
-	NetworkObservabilityCilium string
-	AcstorCapacityProvisioner  string
-	AcstorMetricsExporter      string
-	LocalCSIDriver             string
-	Ztunnel                    string
-	IstioCni                   string
-	DcgmExporter               string
+	// DefaultTargetSettings holds the scrape enabled setting, keyed by default target name
+	DefaultTargetSettings map[string]string
+
+	pipeline *configPipeline
 }
 
 // ConfigParser is an interface for parsing configurations.
@@ -142,11 +216,15 @@ type Configurator struct {
 	ConfigParser   *ConfigProcessor
 	ConfigWriter   *FileConfigWriter
 	ConfigFilePath string
+
+	pipeline *configPipeline
 }
 
 type FileConfigWriter struct {
 	ConfigProcessor *ConfigProcessor
 	Config          map[string]string
+
+	pipeline *configPipeline
 }
 
 // ConfigLoader is an interface for loading configurations.
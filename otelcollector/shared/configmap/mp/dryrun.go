@@ -0,0 +1,137 @@
+package configmapsettings
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus-collector/shared"
+	"gopkg.in/yaml.v2"
+)
+
+// DryRunOptions are the inputs for rendering the merged prometheus config without a cluster.
+type DryRunOptions struct {
+	// Settings holds the data of the ama-metrics-settings-configmap, keyed by configmap key.
+	Settings map[string]string
+	// PrometheusConfig is the custom prometheus config from the ama-metrics-prometheus-config configmaps, if any.
+	PrometheusConfig string
+	// PrometheusConfigFragments are more custom prometheus config fragments, keyed by fragment name: the key of the
+	// ama-metrics-prometheus-config configmap, or <configmap>/<key> for a configmap mounted in the fragments directory.
+	PrometheusConfigFragments map[string]string
+	// DefaultPromConfigsDir is the directory containing the default scrape config files.
+	DefaultPromConfigsDir string
+	// DefaultTargetsFile is an optional file adding default targets to the built-in ones.
+	DefaultTargetsFile string
+	// IngestionProfilesDir is an optional directory of ingestion profiles added to the built-in ones.
+	IngestionProfilesDir string
+	// Env seeds the environment the agent would be started with, e.g. CONTROLLER_TYPE, OS_TYPE, MODE.
+	Env map[string]string
+}
+
+// DryRunResult holds what the config pipeline would have produced in the agent.
+type DryRunResult struct {
+	// MergedConfig is the default and custom prometheus config merged together.
+	MergedConfig map[string]interface{}
+	// DefaultsMergedConfig is the merged config with only the default scrape targets.
+	DefaultsMergedConfig map[string]interface{}
+	// Env is the environment after all the settings have been parsed.
+	Env map[string]string
+	// Sections are the parsed sections of the settings configmap.
+	Sections map[string]map[string]string
+	// ExternalLabels are the labels of the external-labels section, added to every series by the collector.
+	ExternalLabels map[string]string
+	// CollectorProcessors are the processors of the collector-processors setting, added to the collector pipeline.
+	CollectorProcessors *shared.CollectorProcessors
+	// Provenance is where each merged scrape job comes from and the settings applied to it.
+	Provenance *shared.ConfigProvenance
+}
+
+// DryRun runs the settings parsers and the prometheus config merger against the given configmap contents
+// inside a temporary directory, without touching the process environment or the agent's file system.
+// The config validator is not run.
+func DryRun(opts DryRunOptions) (*DryRunResult, error) {
+	workDir, err := os.MkdirTemp("", "prom-config-dry-run")
+	if err != nil {
+		return nil, fmt.Errorf("creating work dir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	p := newConfigPipeline(newConfigPaths(workDir), osFileSystem{}, NewMapEnvironment(opts.Env))
+	for _, dir := range p.paths.directories() {
+		if err := p.fs.MkdirAll(dir, fs.FileMode(0755)); err != nil {
+			return nil, fmt.Errorf("creating dir %s: %w", dir, err)
+		}
+	}
+	if opts.DefaultTargetsFile != "" {
+		p.paths.defaultTargetsFilePath = opts.DefaultTargetsFile
+	}
+	if opts.IngestionProfilesDir != "" {
+		p.paths.ingestionProfilesDir = opts.IngestionProfilesDir
+	}
+
+	for key, value := range opts.Settings {
+		if err := p.fs.WriteFile(filepath.Join(p.paths.configMapSettingsDir, key), []byte(value), fs.FileMode(0644)); err != nil {
+			return nil, fmt.Errorf("writing setting %s: %w", key, err)
+		}
+	}
+	if opts.PrometheusConfig != "" {
+		if err := p.fs.WriteFile(p.paths.configMapMountPath, []byte(opts.PrometheusConfig), fs.FileMode(0644)); err != nil {
+			return nil, fmt.Errorf("writing prometheus config: %w", err)
+		}
+	}
+	for name, contents := range opts.PrometheusConfigFragments {
+		if err := p.writeConfigFragment(name, contents); err != nil {
+			return nil, fmt.Errorf("writing prometheus config fragment %s: %w", name, err)
+		}
+	}
+
+	// Some default files are modified in place, so work on a copy.
+	if err := copyDir(p.fs, opts.DefaultPromConfigsDir, p.paths.defaultPromConfigsDir); err != nil {
+		return nil, fmt.Errorf("copying default prometheus configs: %w", err)
+	}
+
+	sections := p.parseSettingsAndMergeConfigs()
+
+	result := &DryRunResult{Env: p.env.Vars(), Sections: sections, ExternalLabels: p.loadExternalLabels(), Provenance: p.scrapeJobProvenance}
+	if result.CollectorProcessors, err = shared.ReadCollectorProcessors(p.paths.collectorProcessorsEnvVarPath); err != nil {
+		return nil, fmt.Errorf("reading collector processors: %w", err)
+	}
+	if result.MergedConfig, err = p.readDryRunConfig(p.paths.promMergedConfigPath); err != nil {
+		return nil, err
+	}
+	if result.DefaultsMergedConfig, err = p.readDryRunConfig(p.paths.mergedDefaultConfigPath); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// writeConfigFragment writes a custom prometheus config fragment where readConfigFragments finds it
+func (p *configPipeline) writeConfigFragment(name, contents string) error {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return fmt.Errorf("invalid fragment name")
+	}
+	file := filepath.Join(filepath.Dir(p.paths.configMapMountPath), name)
+	if strings.Contains(name, "/") {
+		file = filepath.Join(p.paths.customConfigFragmentsDir, filepath.FromSlash(name))
+	}
+	if err := p.fs.MkdirAll(filepath.Dir(file), fs.FileMode(0755)); err != nil {
+		return err
+	}
+	return p.fs.WriteFile(file, []byte(contents), fs.FileMode(0644))
+}
+
+func (p *configPipeline) readDryRunConfig(path string) (map[string]interface{}, error) {
+	contents, err := p.fs.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	config := make(map[string]interface{})
+	if err := yaml.Unmarshal(contents, &config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filepath.Base(path), err)
+	}
+	return config, nil
+}
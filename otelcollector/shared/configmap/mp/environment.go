@@ -0,0 +1,200 @@
+package configmapsettings
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/prometheus-collector/shared"
+)
+
+// Environment abstracts how the config pipeline reads and publishes environment variables,
+// so that the pipeline can be run without side effects on the process environment or .bashrc.
+type Environment interface {
+	Getenv(key string) string
+	LookupEnv(key string) (string, bool)
+	Setenv(key, value string, echo bool) error
+	// SetenvInProcess sets the variable for the current process only, without publishing it to the other processes.
+	SetenvInProcess(key, value string) error
+	Unsetenv(key string) error
+	// Vars returns the variables used by the config pipeline that are currently set.
+	Vars() map[string]string
+	// StartupVars returns the values the variables used by the config pipeline had before it first set them.
+	StartupVars() map[string]string
+}
+
+// processEnvironment reads from the process environment and sources every variable it sets,
+// which is what the agent containers rely on. It keeps track of the variables the config pipeline
+// uses, so that only those are reported rather than the whole process environment.
+type processEnvironment struct {
+	mu sync.Mutex
+	// startup holds the value of each used variable before it was first changed, nil when it was not set
+	startup map[string]*string
+}
+
+func newProcessEnvironment() *processEnvironment {
+	return &processEnvironment{startup: make(map[string]*string)}
+}
+
+// use records the current value of the variable the first time the config pipeline uses it.
+func (pe *processEnvironment) use(key string) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	if _, exists := pe.startup[key]; exists {
+		return
+	}
+	if value, ok := os.LookupEnv(key); ok {
+		pe.startup[key] = &value
+	} else {
+		pe.startup[key] = nil
+	}
+}
+
+func (pe *processEnvironment) Getenv(key string) string {
+	pe.use(key)
+	return os.Getenv(key)
+}
+
+func (pe *processEnvironment) LookupEnv(key string) (string, bool) {
+	pe.use(key)
+	return os.LookupEnv(key)
+}
+
+func (pe *processEnvironment) Setenv(key, value string, echo bool) error {
+	pe.use(key)
+	return shared.SetEnvAndSourceBashrcOrPowershell(key, value, echo)
+}
+
+func (pe *processEnvironment) SetenvInProcess(key, value string) error {
+	pe.use(key)
+	return os.Setenv(key, value)
+}
+
+func (pe *processEnvironment) Unsetenv(key string) error {
+	pe.use(key)
+	return os.Unsetenv(key)
+}
+
+func (pe *processEnvironment) Vars() map[string]string {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	vars := make(map[string]string, len(pe.startup))
+	for key := range pe.startup {
+		if value, ok := os.LookupEnv(key); ok {
+			vars[key] = value
+		}
+	}
+	return vars
+}
+
+func (pe *processEnvironment) StartupVars() map[string]string {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	vars := make(map[string]string, len(pe.startup))
+	for key, value := range pe.startup {
+		if value != nil {
+			vars[key] = *value
+		}
+	}
+	return vars
+}
+
+// MapEnvironment is an in-memory Environment used for dry runs.
+type MapEnvironment struct {
+	mu      sync.RWMutex
+	vars    map[string]string
+	startup map[string]string
+}
+
+// NewMapEnvironment returns a MapEnvironment seeded with a copy of vars.
+func NewMapEnvironment(vars map[string]string) *MapEnvironment {
+	me := &MapEnvironment{vars: make(map[string]string, len(vars)), startup: make(map[string]string, len(vars))}
+	for k, v := range vars {
+		me.vars[k] = v
+		me.startup[k] = v
+	}
+	return me
+}
+
+func (me *MapEnvironment) Getenv(key string) string {
+	value, _ := me.LookupEnv(key)
+	return value
+}
+
+func (me *MapEnvironment) LookupEnv(key string) (string, bool) {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+	value, ok := me.vars[key]
+	return value, ok
+}
+
+func (me *MapEnvironment) Setenv(key, value string, echo bool) error {
+	me.mu.Lock()
+	me.vars[key] = value
+	me.mu.Unlock()
+	if echo {
+		shared.EchoVar(key, value)
+	}
+	return nil
+}
+
+func (me *MapEnvironment) SetenvInProcess(key, value string) error {
+	return me.Setenv(key, value, false)
+}
+
+func (me *MapEnvironment) Unsetenv(key string) error {
+	me.mu.Lock()
+	delete(me.vars, key)
+	me.mu.Unlock()
+	return nil
+}
+
+// Vars returns a copy of all the variables currently set.
+func (me *MapEnvironment) Vars() map[string]string {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+	vars := make(map[string]string, len(me.vars))
+	for k, v := range me.vars {
+		vars[k] = v
+	}
+	return vars
+}
+
+// StartupVars returns a copy of the variables the environment was seeded with.
+func (me *MapEnvironment) StartupVars() map[string]string {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+	vars := make(map[string]string, len(me.startup))
+	for k, v := range me.startup {
+		vars[k] = v
+	}
+	return vars
+}
+
+// setEnvVarsFromFile sets every KEY=VALUE line of the file in the current environment.
+func (p *configPipeline) setEnvVarsFromFile(filename string) error {
+	if _, e := p.fs.Stat(filename); os.IsNotExist(e) {
+		return fmt.Errorf("File does not exist: %s", filename)
+	}
+	file, err := p.fs.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		parts := strings.Split(line, "=")
+		if len(parts) != 2 {
+			log.Printf("Skipping invalid line: %s\n", line)
+			continue
+		}
+		p.env.Setenv(parts[0], parts[1], false)
+	}
+
+	return scanner.Err()
+}
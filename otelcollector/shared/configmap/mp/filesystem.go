@@ -0,0 +1,101 @@
+package configmapsettings
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// fileSystem abstracts the file operations of the config pipeline, so that it can be pointed at any tree of
+// settings and generated files.
+type fileSystem interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	Open(name string) (io.ReadCloser, error)
+	Create(name string) (io.WriteCloser, error)
+	Stat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Glob(pattern string) ([]string, error)
+	MkdirAll(path string, perm fs.FileMode) error
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+}
+
+// osFileSystem is the file system of the process, which is what the agent containers use.
+type osFileSystem struct{}
+
+func (osFileSystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (osFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+func (osFileSystem) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (osFileSystem) Create(name string) (io.WriteCloser, error) {
+	return os.Create(name)
+}
+
+func (osFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (osFileSystem) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+func (osFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (osFileSystem) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (osFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// fileExists reports whether the file exists in the file system
+func fileExists(fsys fileSystem, name string) bool {
+	_, err := fsys.Stat(name)
+	return err == nil
+}
+
+// copyFile copies the contents of the source file to the destination file in the file system
+func copyFile(fsys fileSystem, source, destination string) error {
+	contents, err := fsys.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	return fsys.WriteFile(destination, contents, fs.FileMode(0644))
+}
+
+// copyDir copies every file of the source directory to the destination directory in the file system
+func copyDir(fsys fileSystem, source, destination string) error {
+	entries, err := fsys.ReadDir(source)
+	if err != nil {
+		return err
+	}
+	if err := fsys.MkdirAll(destination, fs.FileMode(0755)); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if err := copyFile(fsys, filepath.Join(source, entry.Name()), filepath.Join(destination, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -11,7 +11,7 @@ require (
 	github.com/onsi/gomega v1.35.1
 	github.com/prometheus-collector/shared v0.0.0-00010101000000-000000000000
 	gopkg.in/yaml.v2 v2.4.0
-
+	k8s.io/apimachinery v0.32.0
 )
 
 require (
@@ -41,7 +41,6 @@ require (
 	google.golang.org/protobuf v1.36.5 // indirect
 	gopkg.in/inf.v0 v0.9.1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
-	k8s.io/apimachinery v0.32.0 // indirect
 	k8s.io/klog/v2 v2.130.1 // indirect
 	k8s.io/utils v0.0.0-20241104100929-3ea5e8cea738 // indirect
 	sigs.k8s.io/json v0.0.0-20241010143419-9aa6b5e7a4b3 // indirect
@@ -0,0 +1,215 @@
+package configmapsettings
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	ingestionProfilesSection = "ingestion-profiles"
+	// defaultIngestionProfileKey sets the profile of the default targets not set in the ingestion-profiles section
+	defaultIngestionProfileKey = "default"
+
+	minimalIngestionProfile = "minimal"
+	fullIngestionProfile    = "full"
+	builtInProfileVersion   = "built-in"
+)
+
+// ingestionProfile is a named set of metrics kept for the default targets, defined in a file of the ingestion profiles
+// directory, for example:
+//
+//	name: standard
+//	version: "1.0.0"
+//	extends: minimal
+//	targets:
+//	  cadvisor: "container_memory_usage_bytes|container_oom_events_total"
+type ingestionProfile struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	// Extends is the profile whose regexes are OR'ed with the ones of this profile
+	Extends string `yaml:"extends"`
+	// Targets are the regexes of the metrics kept, keyed by default target name. The metrics of a target without a
+	// regex are not filtered by the profile.
+	Targets map[string]string `yaml:"targets"`
+}
+
+// selectedIngestionProfile is the ingestion profile used for a default target
+type selectedIngestionProfile struct {
+	Profile string `yaml:"profile"`
+	Version string `yaml:"version"`
+}
+
+// builtInIngestionProfiles returns the minimal profile, which only uses the minimal ingestion profile regexes of the
+// custom default targets until the minimal profile file is loaded, and the full profile keeping all the metrics.
+func builtInIngestionProfiles() map[string]*ingestionProfile {
+	return map[string]*ingestionProfile{
+		minimalIngestionProfile: {Name: minimalIngestionProfile, Version: builtInProfileVersion, Targets: map[string]string{}},
+		fullIngestionProfile:    {Name: fullIngestionProfile, Version: builtInProfileVersion, Targets: map[string]string{}},
+	}
+}
+
+// loadIngestionProfiles sets the ingestion profiles to the built-in profiles and the ones from the ingestion profiles
+// directory, which replace the built-in ones of the same name. Invalid profiles are skipped.
+func (p *configPipeline) loadIngestionProfiles() {
+	profiles := builtInIngestionProfiles()
+
+	files, err := p.fs.Glob(filepath.Join(p.paths.ingestionProfilesDir, "*.yaml"))
+	if err != nil {
+		log.Printf("Error listing the ingestion profiles in %s: %v. Only the built-in ingestion profiles will be used\n", p.paths.ingestionProfilesDir, err)
+	}
+	for _, file := range files {
+		profile, err := p.readIngestionProfile(file)
+		if err != nil {
+			log.Printf("Skipping ingestion profile %s: %v\n", file, err)
+			continue
+		}
+		log.Printf("Loaded ingestion profile %s version %s\n", profile.Name, profile.Version)
+		profiles[profile.Name] = profile
+	}
+	if profiles[minimalIngestionProfile].Version == builtInProfileVersion {
+		log.Printf("Error loading the %s ingestion profile from %s, the metrics of the built-in default targets will not be filtered by it\n", minimalIngestionProfile, p.paths.ingestionProfilesDir)
+	}
+
+	for name, profile := range profiles {
+		if err := validateIngestionProfileExtends(profile, profiles); err != nil {
+			log.Printf("Skipping ingestion profile %s: %v\n", name, err)
+			delete(profiles, name)
+		}
+	}
+	p.ingestionProfiles = profiles
+}
+
+func (p *configPipeline) readIngestionProfile(file string) (*ingestionProfile, error) {
+	contents, err := p.fs.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var profile ingestionProfile
+	if err := yaml.UnmarshalStrict(contents, &profile); err != nil {
+		return nil, err
+	}
+	if name := strings.TrimSuffix(filepath.Base(file), ".yaml"); profile.Name != name {
+		return nil, fmt.Errorf("the profile name %q does not match the file name", profile.Name)
+	}
+	if profile.Version == "" {
+		return nil, fmt.Errorf("version is required")
+	}
+	if profile.Targets == nil {
+		profile.Targets = map[string]string{}
+	}
+	for targetName, regex := range profile.Targets {
+		if !isValidRegex(regex) {
+			return nil, fmt.Errorf("invalid regex for %s: %s", targetName, regex)
+		}
+	}
+	return &profile, nil
+}
+
+// validateIngestionProfileExtends checks that the profiles extended by the profile exist, without a cycle
+func validateIngestionProfileExtends(profile *ingestionProfile, profiles map[string]*ingestionProfile) error {
+	seen := map[string]bool{profile.Name: true}
+	for current := profile; current.Extends != ""; {
+		extended, exists := profiles[current.Extends]
+		if !exists {
+			return fmt.Errorf("extended profile %s does not exist", current.Extends)
+		}
+		if seen[extended.Name] {
+			return fmt.Errorf("profile %s extends itself", extended.Name)
+		}
+		seen[extended.Name] = true
+		current = extended
+	}
+	return nil
+}
+
+// getIngestionProfile returns the profile with the name, from the loaded profiles or the built-in ones until loaded
+func (p *configPipeline) getIngestionProfile(name string) *ingestionProfile {
+	if p.ingestionProfiles == nil {
+		return builtInIngestionProfiles()[name]
+	}
+	return p.ingestionProfiles[name]
+}
+
+// regexFor returns the regex of the metrics of the default target kept by the profile and the profiles it extends, or
+// an empty string if they do not filter the metrics of the target. The minimal profile uses the minimal ingestion
+// profile regex of the default target definition when the target is not in the profile, so custom default targets need
+// no profile.
+func (profile *ingestionProfile) regexFor(p *configPipeline, targetName string) string {
+	regexes := []string{}
+	for current := profile; current != nil; current = p.getIngestionProfile(current.Extends) {
+		regex, exists := current.Targets[targetName]
+		if !exists && current.Name == minimalIngestionProfile {
+			regex = p.minimalIngestionProfileRegexFor(targetName)
+		}
+		if regex != "" {
+			regexes = append(regexes, regex)
+		}
+	}
+	return strings.Join(regexes, "|")
+}
+
+func (p *configPipeline) minimalIngestionProfileRegexFor(targetName string) string {
+	for _, target := range p.defaultTargets {
+		if target.Name == targetName {
+			return target.MinimalIngestionProfileRegex
+		}
+	}
+	return ""
+}
+
+// parseIngestionProfileSettings returns the ingestion profile name of each default target from the ingestion-profiles
+// section. The default key sets the profile of the targets not in the section, and falls back to the minimal or full
+// profile depending on the minimal ingestion profile setting. Unknown profiles are logged and replaced by the default.
+func (p *configPipeline) parseIngestionProfileSettings(settings map[string]string, minimalIngestionProfileEnabled bool) map[string]string {
+	defaultProfile := fullIngestionProfile
+	if minimalIngestionProfileEnabled {
+		defaultProfile = minimalIngestionProfile
+	}
+	if name := strings.TrimSpace(settings[defaultIngestionProfileKey]); name != "" {
+		if p.getIngestionProfile(name) == nil {
+			log.Printf("Unknown ingestion profile %q set as %s in the %s section, using the %s profile\n", name, defaultIngestionProfileKey, ingestionProfilesSection, defaultProfile)
+		} else {
+			defaultProfile = name
+		}
+	}
+
+	profileNames := make(map[string]string)
+	for _, target := range p.defaultTargets {
+		if target.KeepListEnvVar == "" {
+			continue
+		}
+		profileNames[target.Name] = defaultProfile
+		if name := strings.TrimSpace(settings[target.Name]); name != "" {
+			if p.getIngestionProfile(name) == nil {
+				log.Printf("Unknown ingestion profile %q set for %s in the %s section, using the %s profile\n", name, target.Name, ingestionProfilesSection, defaultProfile)
+				continue
+			}
+			profileNames[target.Name] = name
+		}
+	}
+	for key := range settings {
+		if _, exists := profileNames[key]; !exists && key != defaultIngestionProfileKey {
+			log.Printf("Ignoring %s in the %s section, it is not a default target\n", key, ingestionProfilesSection)
+		}
+	}
+	return profileNames
+}
+
+// writeSelectedIngestionProfiles writes the name and version of the ingestion profile used for each default target,
+// reported in the health metrics.
+func (p *configPipeline) writeSelectedIngestionProfiles(profileNames map[string]string) error {
+	selected := make(map[string]selectedIngestionProfile, len(profileNames))
+	for targetName, name := range profileNames {
+		selected[targetName] = selectedIngestionProfile{Profile: name, Version: p.getIngestionProfile(name).Version}
+	}
+	out, err := yaml.Marshal(selected)
+	if err != nil {
+		return err
+	}
+	return p.fs.WriteFile(p.paths.ingestionProfilesEnvVarPath, out, fs.FileMode(0644))
+}
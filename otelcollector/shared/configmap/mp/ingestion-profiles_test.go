@@ -0,0 +1,136 @@
+package configmapsettings
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+)
+
+// shippedIngestionProfilesDir is the directory of the ingestion profiles copied to the image
+const shippedIngestionProfilesDir = "../../../configmapparser/ingestion-profiles"
+
+// shippedMinimalRegex returns the regex of the default target in the minimal ingestion profile shipped with the image
+func shippedMinimalRegex(targetName string) string {
+	contents, err := os.ReadFile(filepath.Join(shippedIngestionProfilesDir, "minimal.yaml"))
+	Expect(err).NotTo(HaveOccurred())
+	var profile ingestionProfile
+	Expect(yaml.UnmarshalStrict(contents, &profile)).To(Succeed())
+	return profile.Targets[targetName]
+}
+
+var _ = Describe("IngestionProfiles", func() {
+	BeforeEach(func() {
+		testPipeline.paths.ingestionProfilesDir = GinkgoT().TempDir()
+	})
+
+	writeProfile := func(name, contents string) {
+		Expect(os.WriteFile(filepath.Join(testPipeline.paths.ingestionProfilesDir, name+".yaml"), []byte(contents), 0644)).To(Succeed())
+	}
+
+	Context("when loading the profiles", func() {
+		It("should load the profiles shipped with the image", func() {
+			testPipeline.paths.ingestionProfilesDir = shippedIngestionProfilesDir
+			testPipeline.loadIngestionProfiles()
+			Expect(testPipeline.ingestionProfiles).To(HaveKey("minimal"))
+			Expect(testPipeline.ingestionProfiles).To(HaveKey("standard"))
+			Expect(testPipeline.ingestionProfiles).To(HaveKey("full"))
+			Expect(testPipeline.getIngestionProfile("minimal").Version).To(Equal("1.0.0"))
+			for _, target := range testPipeline.defaultTargets {
+				if target.KeepListEnvVar == "" || target.Name == "podannotations" {
+					Expect(testPipeline.getIngestionProfile("minimal").Targets).NotTo(HaveKey(target.Name))
+					continue
+				}
+				Expect(testPipeline.getIngestionProfile("minimal").regexFor(testPipeline, target.Name)).NotTo(BeEmpty(), target.Name)
+			}
+			Expect(testPipeline.getIngestionProfile("minimal").Targets).To(HaveLen(20))
+			Expect(testPipeline.getIngestionProfile("minimal").regexFor(testPipeline, "dcgmexporter")).To(Equal("DCGM_.*"))
+			Expect(testPipeline.getIngestionProfile("standard").regexFor(testPipeline, "kubelet")).To(HaveSuffix("|" + shippedMinimalRegex("kubelet")))
+			Expect(testPipeline.getIngestionProfile("full").regexFor(testPipeline, "kubelet")).To(BeEmpty())
+		})
+
+		It("should fall back to the built-in profiles", func() {
+			testPipeline.loadIngestionProfiles()
+			Expect(testPipeline.getIngestionProfile("minimal").Version).To(Equal(builtInProfileVersion))
+			Expect(testPipeline.getIngestionProfile("minimal").regexFor(testPipeline, "cadvisor")).To(BeEmpty())
+			Expect(testPipeline.getIngestionProfile("standard")).To(BeNil())
+		})
+
+		It("should combine the regexes of the extended profiles", func() {
+			writeProfile("minimal", "name: minimal\nversion: \"2.0.0\"\ntargets:\n  kubelet: kubelet_up\n")
+			writeProfile("extra", "name: extra\nversion: \"1.0.0\"\nextends: minimal\ntargets:\n  kubelet: kubelet_pods\n")
+			testPipeline.loadIngestionProfiles()
+			Expect(testPipeline.getIngestionProfile("minimal").Version).To(Equal("2.0.0"))
+			Expect(testPipeline.getIngestionProfile("extra").regexFor(testPipeline, "kubelet")).To(Equal("kubelet_pods|kubelet_up"))
+			Expect(testPipeline.getIngestionProfile("extra").regexFor(testPipeline, "cadvisor")).To(BeEmpty())
+		})
+
+		It("should skip invalid profiles", func() {
+			writeProfile("noversion", "name: noversion\n")
+			writeProfile("badregex", "name: badregex\nversion: \"1\"\ntargets:\n  kubelet: \"kubelet_(up\"\n")
+			writeProfile("misnamed", "name: other\nversion: \"1\"\n")
+			writeProfile("unknownfield", "name: unknownfield\nversion: \"1\"\nmetrics: {}\n")
+			writeProfile("orphan", "name: orphan\nversion: \"1\"\nextends: missing\n")
+			writeProfile("loop", "name: loop\nversion: \"1\"\nextends: loop\n")
+			testPipeline.loadIngestionProfiles()
+			Expect(testPipeline.ingestionProfiles).To(HaveLen(2))
+			Expect(testPipeline.ingestionProfiles).To(HaveKey("minimal"))
+			Expect(testPipeline.ingestionProfiles).To(HaveKey("full"))
+		})
+	})
+
+	Context("when selecting the profiles", func() {
+		BeforeEach(func() {
+			minimal, err := os.ReadFile(filepath.Join(shippedIngestionProfilesDir, "minimal.yaml"))
+			Expect(err).NotTo(HaveOccurred())
+			writeProfile("minimal", string(minimal))
+			writeProfile("standard", "name: standard\nversion: \"1.0.0\"\nextends: minimal\ntargets:\n  cadvisor: container_oom_events_total\n")
+			testPipeline.loadIngestionProfiles()
+		})
+
+		It("should use minimal or full depending on the minimal ingestion profile setting", func() {
+			Expect(testPipeline.parseIngestionProfileSettings(nil, true)).To(HaveKeyWithValue("kubelet", "minimal"))
+			Expect(testPipeline.parseIngestionProfileSettings(nil, false)).To(HaveKeyWithValue("kubelet", "full"))
+		})
+
+		It("should use the profile set for the target over the default one", func() {
+			profileNames := testPipeline.parseIngestionProfileSettings(map[string]string{
+				"default":    "standard",
+				"kubestate":  "full",
+				"coredns":    "unknown",
+				"notatarget": "full",
+			}, true)
+			Expect(profileNames).To(HaveKeyWithValue("kubelet", "standard"))
+			Expect(profileNames).To(HaveKeyWithValue("kubestate", "full"))
+			Expect(profileNames).To(HaveKeyWithValue("coredns", "standard"))
+			Expect(profileNames).NotTo(HaveKey("notatarget"))
+		})
+
+		It("should keep the metrics of the selected profile along with the keep list", func() {
+			keepListRegexes := testPipeline.populateRegexValuesWithMinimalIngestionProfile(RegexValues{
+				keepLists:               map[string]string{"cadvisor": "container_cpu_load_average_10s"},
+				minimalingestionprofile: "true",
+				ingestionProfiles:       map[string]string{"cadvisor": "standard", "kubelet": "full"},
+			})
+			Expect(keepListRegexes).To(HaveKeyWithValue("CADVISOR_METRICS_KEEP_LIST_REGEX", "container_cpu_load_average_10s|container_oom_events_total|"+shippedMinimalRegex("cadvisor")))
+			Expect(keepListRegexes).To(HaveKeyWithValue("KUBELET_METRICS_KEEP_LIST_REGEX", ""))
+			Expect(keepListRegexes).To(HaveKeyWithValue("COREDNS_METRICS_KEEP_LIST_REGEX", "|"+shippedMinimalRegex("coredns")))
+		})
+
+		It("should write the name and version of the selected profiles", func() {
+			testPipeline.paths.ingestionProfilesEnvVarPath = filepath.Join(GinkgoT().TempDir(), "ingestion-profiles")
+
+			Expect(testPipeline.writeSelectedIngestionProfiles(map[string]string{"kubelet": "standard", "cadvisor": "minimal"})).To(Succeed())
+			contents, err := os.ReadFile(testPipeline.paths.ingestionProfilesEnvVarPath)
+			Expect(err).NotTo(HaveOccurred())
+			var selected map[string]selectedIngestionProfile
+			Expect(yaml.Unmarshal(contents, &selected)).To(Succeed())
+			Expect(selected).To(Equal(map[string]selectedIngestionProfile{
+				"kubelet":  {Profile: "standard", Version: "1.0.0"},
+				"cadvisor": {Profile: "minimal", Version: "1.0.0"},
+			}))
+		})
+	})
+})
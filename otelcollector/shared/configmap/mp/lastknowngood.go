@@ -0,0 +1,104 @@
+package configmapsettings
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus-collector/shared"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	lastKnownGoodCollectorConfigFile = "collector-config.yml"
+	lastKnownGoodMetadataFile        = "metadata.yaml"
+
+	// usingLastKnownGoodConfigEnvVar is set to true when the custom config was rejected and the last known good config
+	// is used instead, with the version of that config in lastKnownGoodConfigVersionEnvVar
+	usingLastKnownGoodConfigEnvVar   = "AZMON_USING_LAST_KNOWN_GOOD_CONFIG"
+	lastKnownGoodConfigVersionEnvVar = "AZMON_LAST_KNOWN_GOOD_CONFIG_VERSION"
+)
+
+// LastKnownGoodConfig describes the snapshot of the last custom config that passed validation
+type LastKnownGoodConfig struct {
+	ConfigVersion string `yaml:"configVersion"`
+	SavedAt       string `yaml:"savedAt"`
+}
+
+// saveLastKnownGoodConfig snapshots a validated collector config with the custom scrape configs, along with the
+// config version of the settings configmap, so it can be used when a later config is rejected. The snapshot dir is
+// an emptyDir volume and survives container restarts.
+func (p *configPipeline) saveLastKnownGoodConfig(collectorConfig string) {
+	if err := p.fs.MkdirAll(p.paths.lastKnownGoodConfigDir, fs.FileMode(0755)); err != nil {
+		log.Printf("Error creating the last known good config dir %s: %v\n", p.paths.lastKnownGoodConfigDir, err)
+		return
+	}
+	if err := copyFile(p.fs, collectorConfig, filepath.Join(p.paths.lastKnownGoodConfigDir, lastKnownGoodCollectorConfigFile)); err != nil {
+		log.Printf("Error saving the last known good collector config: %v\n", err)
+		return
+	}
+	metadata, err := yaml.Marshal(LastKnownGoodConfig{
+		ConfigVersion: p.env.Getenv("AZMON_AGENT_CFG_FILE_VERSION"),
+		SavedAt:       time.Now().UTC().Format(time.RFC3339),
+	})
+	if err == nil {
+		err = p.fs.WriteFile(filepath.Join(p.paths.lastKnownGoodConfigDir, lastKnownGoodMetadataFile), metadata, fs.FileMode(0644))
+	}
+	if err != nil {
+		log.Printf("Error saving the last known good config metadata: %v\n", err)
+		return
+	}
+	log.Printf("Saved the collector config as the last known good config for config version %s\n", p.env.Getenv("AZMON_AGENT_CFG_FILE_VERSION"))
+}
+
+// readLastKnownGoodConfig returns the metadata of the last known good config, or nil when there is no snapshot
+func (p *configPipeline) readLastKnownGoodConfig() (*LastKnownGoodConfig, error) {
+	if !fileExists(p.fs, filepath.Join(p.paths.lastKnownGoodConfigDir, lastKnownGoodCollectorConfigFile)) {
+		return nil, nil
+	}
+	contents, err := p.fs.ReadFile(filepath.Join(p.paths.lastKnownGoodConfigDir, lastKnownGoodMetadataFile))
+	if err != nil {
+		return nil, err
+	}
+	var lastKnownGood LastKnownGoodConfig
+	if err := yaml.Unmarshal(contents, &lastKnownGood); err != nil {
+		return nil, err
+	}
+	return &lastKnownGood, nil
+}
+
+// restoreLastKnownGoodConfig writes the last known good collector config to collectorConfig after the custom config
+// was rejected. False is returned when there is no snapshot to use.
+func (p *configPipeline) restoreLastKnownGoodConfig(collectorConfig string) bool {
+	lastKnownGood, err := p.readLastKnownGoodConfig()
+	if err != nil {
+		log.Printf("Error reading the last known good config: %v\n", err)
+		return false
+	}
+	if lastKnownGood == nil {
+		return false
+	}
+	if err := copyFile(p.fs, filepath.Join(p.paths.lastKnownGoodConfigDir, lastKnownGoodCollectorConfigFile), collectorConfig); err != nil {
+		log.Printf("Error restoring the last known good collector config: %v\n", err)
+		return false
+	}
+	shared.EchoWarning(fmt.Sprintf("The custom prometheus config was rejected, using the last known good config of config version %s saved at %s",
+		lastKnownGood.ConfigVersion, lastKnownGood.SavedAt))
+	p.env.Setenv(usingLastKnownGoodConfigEnvVar, "true", true)
+	p.env.Setenv(lastKnownGoodConfigVersionEnvVar, lastKnownGood.ConfigVersion, true)
+	return true
+}
+
+// clearLastKnownGoodConfig removes the snapshot once no custom config is used, so removing the custom config and
+// adding an invalid one later does not bring back scrape configs that were removed
+func (p *configPipeline) clearLastKnownGoodConfig() {
+	// The dir is a volume mount and is kept
+	for _, file := range []string{lastKnownGoodCollectorConfigFile, lastKnownGoodMetadataFile} {
+		if err := p.fs.Remove(filepath.Join(p.paths.lastKnownGoodConfigDir, file)); err != nil && !os.IsNotExist(err) {
+			log.Printf("Error removing the last known good config: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package configmapsettings
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LastKnownGoodConfig", func() {
+	var (
+		workDir     string
+		mapEnv      *MapEnvironment
+		validConfig string
+	)
+
+	BeforeEach(func() {
+		workDir = GinkgoT().TempDir()
+		testPipeline.paths.lastKnownGoodConfigDir = filepath.Join(workDir, "last-known-good")
+		mapEnv = NewMapEnvironment(map[string]string{"AZMON_AGENT_CFG_FILE_VERSION": "ver1"})
+		testPipeline.env = mapEnv
+
+		validConfig = filepath.Join(workDir, "valid-collector-config.yml")
+		Expect(os.WriteFile(validConfig, []byte("valid config"), 0644)).To(Succeed())
+	})
+
+	It("should restore the last validated config with its config version", func() {
+		testPipeline.saveLastKnownGoodConfig(validConfig)
+		mapEnv.Setenv("AZMON_AGENT_CFG_FILE_VERSION", "ver2", false)
+
+		collectorConfig := filepath.Join(workDir, "collector-config.yml")
+		Expect(os.WriteFile(collectorConfig, []byte("rejected config"), 0644)).To(Succeed())
+		Expect(testPipeline.restoreLastKnownGoodConfig(collectorConfig)).To(BeTrue())
+
+		Expect(os.ReadFile(collectorConfig)).To(Equal([]byte("valid config")))
+		Expect(mapEnv.Vars()).To(HaveKeyWithValue(usingLastKnownGoodConfigEnvVar, "true"))
+		Expect(mapEnv.Vars()).To(HaveKeyWithValue(lastKnownGoodConfigVersionEnvVar, "ver1"))
+	})
+
+	It("should not restore anything without a snapshot", func() {
+		Expect(testPipeline.restoreLastKnownGoodConfig(filepath.Join(workDir, "collector-config.yml"))).To(BeFalse())
+		Expect(mapEnv.Vars()).NotTo(HaveKey(usingLastKnownGoodConfigEnvVar))
+	})
+
+	It("should not restore a cleared snapshot", func() {
+		testPipeline.saveLastKnownGoodConfig(validConfig)
+		testPipeline.clearLastKnownGoodConfig()
+		Expect(testPipeline.restoreLastKnownGoodConfig(filepath.Join(workDir, "collector-config.yml"))).To(BeFalse())
+	})
+
+})
@@ -0,0 +1,69 @@
+package configmapsettings
+
+import (
+	"github.com/prometheus-collector/shared"
+)
+
+// configPipeline holds where the config pipeline reads and writes its files and environment, and the state shared
+// between the settings parsers and the prometheus config merger.
+type configPipeline struct {
+	paths configPaths
+	fs    fileSystem
+	env   Environment
+
+	// defaultTargets are the built-in default targets and the ones added by the default targets file or the
+	// custom default targets settings
+	defaultTargets []DefaultTarget
+	// ingestionProfiles are the profiles loaded from the ingestion profiles directory, or the built-in ones until loaded
+	ingestionProfiles map[string]*ingestionProfile
+
+	regexHash    map[string]string
+	intervalHash map[string]string
+	// metricsDropListHash and labelDropListHash are keyed by default target name
+	metricsDropListHash map[string]string
+	labelDropListHash   map[string]string
+
+	// loadedScrapeLimits are the scrape limits used when merging the prometheus config
+	loadedScrapeLimits scrapeLimitsSettings
+	// loadedPodAnnotationSettings are the pod annotation settings used when merging the prometheus config
+	loadedPodAnnotationSettings podAnnotationSettings
+
+	mergedDefaultConfigs map[interface{}]interface{}
+	// scrapeJobProvenance is the provenance of the jobs merged by prometheusConfigMerger
+	scrapeJobProvenance *shared.ConfigProvenance
+	// labelLimitChanges are the label limits setScrapeLimitsPerScrape set on the custom scrape jobs
+	labelLimitChanges []shared.LabelLimitChange
+
+	// startupEnv holds the variables used by the pipeline as they were before the settings were applied, which a
+	// reload starts from
+	startupEnv map[string]string
+	// appliedEnv and appliedSections are the environment and settings sections of the config currently in use
+	appliedEnv      map[string]string
+	appliedSections map[string]map[string]string
+}
+
+// newConfigPipeline returns a config pipeline reading and writing the given paths of fs and the variables of env.
+func newConfigPipeline(paths configPaths, fs fileSystem, env Environment) *configPipeline {
+	return &configPipeline{
+		paths:               paths,
+		fs:                  fs,
+		env:                 env,
+		defaultTargets:      builtInDefaultTargets,
+		regexHash:           make(map[string]string),
+		intervalHash:        make(map[string]string),
+		metricsDropListHash: make(map[string]string),
+		labelDropListHash:   make(map[string]string),
+		scrapeJobProvenance: &shared.ConfigProvenance{},
+	}
+}
+
+// AgentConfigPipeline is the config pipeline of an agent container, which reads and writes the files and environment
+// of the container. It keeps the settings it applied, so the config can be reloaded with ReloadConfig.
+type AgentConfigPipeline struct {
+	pipeline *configPipeline
+}
+
+// NewAgentConfigPipeline returns the config pipeline of the agent container.
+func NewAgentConfigPipeline() *AgentConfigPipeline {
+	return &AgentConfigPipeline{pipeline: newConfigPipeline(newConfigPaths(""), osFileSystem{}, newProcessEnvironment())}
+}
@@ -0,0 +1,225 @@
+package configmapsettings
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/prometheus-collector/shared"
+	"gopkg.in/yaml.v2"
+)
+
+// skippedConfigFragmentsEnvVar lists the custom config fragments skipped because of a conflict, comma separated
+const skippedConfigFragmentsEnvVar = "AZMON_SKIPPED_CUSTOM_PROMETHEUS_CONFIG_FRAGMENTS"
+
+// configFragment is a custom prometheus config from a key of a mounted configmap
+type configFragment struct {
+	// name is the path of the fragment relative to the directory it was found in, such as prometheus-config for the
+	// ama-metrics-prometheus-config configmap or team-a/prometheus-config for a configmap mounted as team-a under the
+	// fragments directory
+	name     string
+	contents string
+}
+
+// readConfigFragments returns the custom prometheus config fragments: the keys of the ama-metrics-prometheus-config
+// configmap, prometheus-config first, then the keys of each configmap mounted in a directory of the fragments
+// directory, in name order.
+func (p *configPipeline) readConfigFragments() []configFragment {
+	fragments := []configFragment{}
+	if contents, err := p.fs.ReadFile(p.paths.configMapMountPath); err == nil {
+		fragments = append(fragments, configFragment{name: filepath.Base(p.paths.configMapMountPath), contents: string(contents)})
+	} else if !os.IsNotExist(err) {
+		shared.EchoError(fmt.Sprintf("Exception while parsing configmap for prometheus config: %s. Custom prometheus config will not be used. Please check configmap for errors", err))
+	}
+	for _, file := range p.listConfigMapKeys(filepath.Dir(p.paths.configMapMountPath)) {
+		if file != p.paths.configMapMountPath {
+			fragments = p.appendConfigFragment(fragments, filepath.Dir(p.paths.configMapMountPath), file)
+		}
+	}
+
+	entries, err := p.fs.ReadDir(p.paths.customConfigFragmentsDir)
+	if err != nil && !os.IsNotExist(err) {
+		shared.EchoError(fmt.Sprintf("Error reading the custom prometheus config fragments in %s: %v", p.paths.customConfigFragmentsDir, err))
+	}
+	for _, entry := range entries {
+		dir := filepath.Join(p.paths.customConfigFragmentsDir, entry.Name())
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if info, err := p.fs.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		for _, file := range p.listConfigMapKeys(dir) {
+			fragments = p.appendConfigFragment(fragments, p.paths.customConfigFragmentsDir, file)
+		}
+	}
+	return fragments
+}
+
+// listConfigMapKeys returns the files of the keys of a configmap volume, skipping its ..data links
+func (p *configPipeline) listConfigMapKeys(dir string) []string {
+	entries, err := p.fs.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	files := []string{}
+	for _, entry := range entries {
+		file := filepath.Join(dir, entry.Name())
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if info, err := p.fs.Stat(file); err != nil || info.IsDir() {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+func (p *configPipeline) appendConfigFragment(fragments []configFragment, dir, file string) []configFragment {
+	contents, err := p.fs.ReadFile(file)
+	if err != nil {
+		shared.EchoError(fmt.Sprintf("Error reading the custom prometheus config fragment %s: %v. It will not be used", file, err))
+		return fragments
+	}
+	name, _ := filepath.Rel(dir, file)
+	return append(fragments, configFragment{name: filepath.ToSlash(name), contents: string(contents)})
+}
+
+// defaultTargetJobNames returns the default target scraping each job name, for the enabled default targets used on
+// this agent. The job names are read from the default scrape config files before they are customized.
+func (p *configPipeline) defaultTargetJobNames(operatorEnabled bool) map[string]string {
+	jobNames := make(map[string]string)
+	if strings.ToLower(p.env.Getenv("AZMON_PROMETHEUS_NO_DEFAULT_SCRAPING_ENABLED")) != "false" {
+		return jobNames
+	}
+
+	agent := p.getDefaultTargetAgent(operatorEnabled)
+	for _, target := range p.defaultTargets {
+		config := target.enabledConfigFor(p, agent)
+		if config == nil {
+			continue
+		}
+		defaultConfig, err := p.loadYAMLFromFile(filepath.Join(p.paths.defaultPromConfigsDir, config.File))
+		if err != nil {
+			log.Printf("Error loading YAML from file %s: %s\n", config.File, err)
+			continue
+		}
+		for _, jobName := range scrapeJobNames(defaultConfig) {
+			jobNames[jobName] = target.Name
+		}
+	}
+	return jobNames
+}
+
+// scrapeJobNames returns the job names of the scrape configs of a prometheus config
+func scrapeJobNames(config map[interface{}]interface{}) []string {
+	jobNames := []string{}
+	scrapeConfigs, _ := config["scrape_configs"].([]interface{})
+	for _, scrapeConfig := range scrapeConfigs {
+		scrapeMap, _ := scrapeConfig.(map[interface{}]interface{})
+		if jobName, ok := scrapeMap["job_name"].(string); ok {
+			jobNames = append(jobNames, jobName)
+		}
+	}
+	return jobNames
+}
+
+// mergeConfigFragments merges the scrape configs and other sections of the custom prometheus config fragments, in
+// order. A fragment is skipped when it is not valid YAML, has the same job name twice, defines a job name already
+// defined by a previous fragment or by a default target, or has a global section different from the one of a
+// previous fragment. The skipped fragments are returned with the reason, keyed by fragment name.
+func mergeConfigFragments(fragments []configFragment, defaultJobNames map[string]string) (map[interface{}]interface{}, map[string]error) {
+	merged := make(map[interface{}]interface{})
+	skipped := make(map[string]error)
+	// jobFragments is the fragment defining each job name
+	jobFragments := make(map[string]string)
+	globalFragment := ""
+
+	for _, fragment := range fragments {
+		var config map[interface{}]interface{}
+		if err := yaml.Unmarshal([]byte(fragment.contents), &config); err != nil {
+			skipped[fragment.name] = fmt.Errorf("invalid YAML: %v", err)
+			continue
+		}
+		if len(config) == 0 {
+			continue
+		}
+
+		err := func() error {
+			fragmentJobNames := make(map[string]bool)
+			for _, jobName := range scrapeJobNames(config) {
+				if fragmentJobNames[jobName] {
+					return fmt.Errorf("job %s is defined more than once", jobName)
+				}
+				fragmentJobNames[jobName] = true
+				if other, exists := jobFragments[jobName]; exists {
+					return fmt.Errorf("job %s is already defined in %s", jobName, other)
+				}
+				if target, exists := defaultJobNames[jobName]; exists {
+					return fmt.Errorf("job %s is already scraped by the %s default target", jobName, target)
+				}
+			}
+			if global, exists := config["global"]; exists && globalFragment != "" && !reflect.DeepEqual(global, merged["global"]) {
+				return fmt.Errorf("the global section conflicts with the one in %s", globalFragment)
+			}
+			return nil
+		}()
+		if err != nil {
+			skipped[fragment.name] = err
+			continue
+		}
+
+		for _, jobName := range scrapeJobNames(config) {
+			jobFragments[jobName] = fragment.name
+		}
+		if _, exists := config["global"]; exists && globalFragment == "" {
+			globalFragment = fragment.name
+		}
+		merged = deepMerge(merged, config)
+	}
+	return merged, skipped
+}
+
+// parseConfigFragments returns the custom prometheus config made of the fragments that do not conflict. The skipped
+// fragments are reported and listed in an env var.
+func (p *configPipeline) parseConfigFragments(operatorEnabled bool) string {
+	defer func() {
+		if r := recover(); r != nil {
+			shared.EchoError(fmt.Sprintf("Recovered from panic: %v\n", r))
+		}
+	}()
+
+	fragments := p.readConfigFragments()
+	if len(fragments) == 0 {
+		shared.EchoWarning("Custom prometheus config does not exist, using only default scrape targets if they are enabled")
+		return ""
+	}
+
+	merged, skipped := mergeConfigFragments(fragments, p.defaultTargetJobNames(operatorEnabled))
+	skippedNames := []string{}
+	for _, fragment := range fragments {
+		if err, exists := skipped[fragment.name]; exists {
+			shared.EchoError(fmt.Sprintf("Skipping custom prometheus config fragment %s: %v", fragment.name, err))
+			skippedNames = append(skippedNames, fragment.name)
+		}
+	}
+	p.env.Setenv(skippedConfigFragmentsEnvVar, strings.Join(skippedNames, ","), true)
+	p.recordCustomConfigProvenance(fragments, skipped)
+	if len(merged) == 0 {
+		return ""
+	}
+	if len(fragments) > 1 {
+		shared.EchoStr(fmt.Sprintf("Merged %d of %d custom prometheus config fragments", len(fragments)-len(skipped), len(fragments)))
+	}
+
+	config, err := yaml.Marshal(merged)
+	if err != nil {
+		shared.EchoError(fmt.Sprintf("Error marshalling the custom prometheus config fragments: %v", err))
+		return ""
+	}
+	return string(config)
+}
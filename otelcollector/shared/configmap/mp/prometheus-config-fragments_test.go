@@ -0,0 +1,110 @@
+package configmapsettings
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+)
+
+var _ = Describe("ConfigFragments", func() {
+	Context("when merging the fragments", func() {
+		var (
+			merged  map[interface{}]interface{}
+			skipped map[string]error
+		)
+
+		BeforeEach(func() {
+			merged, skipped = mergeConfigFragments([]configFragment{
+				{name: "prometheus-config", contents: "global:\n  scrape_interval: 30s\nscrape_configs:\n- job_name: app\n"},
+				{name: "team-a/prometheus-config", contents: "global:\n  scrape_interval: 30s\nscrape_configs:\n- job_name: team-a\n"},
+				{name: "team-b/prometheus-config", contents: "scrape_configs:\n- job_name: app\n"},
+				{name: "team-c/prometheus-config", contents: "global:\n  scrape_interval: 15s\nscrape_configs:\n- job_name: team-c\n"},
+				{name: "team-d/prometheus-config", contents: "scrape_configs:\n- job_name: team-d\n- job_name: team-d\n"},
+				{name: "team-e/prometheus-config", contents: "scrape_configs:\n- job_name: kubelet\n"},
+				{name: "team-f/prometheus-config", contents: "scrape_configs: [\n"},
+				{name: "team-g/prometheus-config", contents: "scrape_configs:\n- job_name: team-g\n"},
+			}, map[string]string{"kubelet": "kubelet"})
+		})
+
+		It("should merge the fragments that do not conflict", func() {
+			Expect(scrapeJobNames(merged)).To(Equal([]string{"app", "team-a", "team-g"}))
+			Expect(merged["global"]).To(Equal(map[interface{}]interface{}{"scrape_interval": "30s"}))
+		})
+
+		It("should report each skipped fragment", func() {
+			Expect(skipped).To(HaveLen(5))
+			Expect(skipped["team-b/prometheus-config"]).To(MatchError("job app is already defined in prometheus-config"))
+			Expect(skipped["team-c/prometheus-config"]).To(MatchError("the global section conflicts with the one in prometheus-config"))
+			Expect(skipped["team-d/prometheus-config"]).To(MatchError("job team-d is defined more than once"))
+			Expect(skipped["team-e/prometheus-config"]).To(MatchError("job kubelet is already scraped by the kubelet default target"))
+			Expect(skipped["team-f/prometheus-config"]).To(MatchError(ContainSubstring("invalid YAML")))
+		})
+	})
+
+	Context("when reading the mounted fragments", func() {
+		BeforeEach(func() {
+			settingsDir := GinkgoT().TempDir()
+			testPipeline.paths.configMapMountPath = filepath.Join(settingsDir, "prometheus", "prometheus-config")
+			testPipeline.paths.customConfigFragmentsDir = filepath.Join(settingsDir, "prometheus-fragments")
+		})
+
+		AfterEach(func() {
+			cleanupEnvVars()
+		})
+
+		It("should read the prometheus-config key first, then the other keys and configmaps", func() {
+			Expect(testPipeline.writeConfigFragment("prometheus-config", "scrape_configs:\n- job_name: app\n")).To(Succeed())
+			Expect(testPipeline.writeConfigFragment("extra", "scrape_configs:\n- job_name: extra\n")).To(Succeed())
+			Expect(testPipeline.writeConfigFragment("team-b/jobs", "scrape_configs:\n- job_name: app\n")).To(Succeed())
+			Expect(testPipeline.writeConfigFragment("team-a/jobs", "scrape_configs:\n- job_name: team-a\n")).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(testPipeline.paths.customConfigFragmentsDir, "team-a", "..data"), 0755)).To(Succeed())
+
+			fragments := testPipeline.readConfigFragments()
+			names := []string{}
+			for _, fragment := range fragments {
+				names = append(names, fragment.name)
+			}
+			Expect(names).To(Equal([]string{"prometheus-config", "extra", "team-a/jobs", "team-b/jobs"}))
+
+			setEnvVars(map[string]string{"AZMON_PROMETHEUS_NO_DEFAULT_SCRAPING_ENABLED": "true"})
+			var config map[interface{}]interface{}
+			Expect(yaml.Unmarshal([]byte(testPipeline.parseConfigFragments(false)), &config)).To(Succeed())
+			Expect(scrapeJobNames(config)).To(Equal([]string{"app", "extra", "team-a"}))
+			Expect(testPipeline.env.Getenv(skippedConfigFragmentsEnvVar)).To(Equal("team-b/jobs"))
+		})
+
+		It("should not return a config without fragments", func() {
+			Expect(testPipeline.parseConfigFragments(false)).To(BeEmpty())
+		})
+
+		It("should reject fragment names outside of the fragments directories", func() {
+			Expect(testPipeline.writeConfigFragment("../settings", "")).NotTo(Succeed())
+		})
+	})
+
+	Context("when default targets are enabled", func() {
+		BeforeEach(func() {
+			testPipeline.paths.defaultPromConfigsDir = "../../../configmapparser/default-prom-configs"
+		})
+
+		AfterEach(func() {
+			cleanupEnvVars()
+		})
+
+		It("should return the job names of the enabled default targets", func() {
+			setEnvVars(map[string]string{
+				"AZMON_PROMETHEUS_NO_DEFAULT_SCRAPING_ENABLED": "false",
+				"AZMON_PROMETHEUS_KUBELET_SCRAPING_ENABLED":    "true",
+				"AZMON_PROMETHEUS_COREDNS_SCRAPING_ENABLED":    "false",
+				"CONTROLLER_TYPE": "ReplicaSet",
+				"OS_TYPE":         "linux",
+			})
+			jobNames := testPipeline.defaultTargetJobNames(false)
+			Expect(jobNames).To(HaveKeyWithValue("kubelet", "kubelet"))
+			Expect(jobNames).NotTo(HaveKey("kube-dns"))
+		})
+	})
+})
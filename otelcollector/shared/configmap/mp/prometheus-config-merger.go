@@ -4,10 +4,11 @@ import (
 	"fmt"
 	"io/fs"
 	"log"
-	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/prometheus-collector/shared"
@@ -16,7 +17,6 @@ import (
 )
 
 const (
-	configMapMountPath               = "/etc/config/settings/prometheus/prometheus-config"
 	replicasetControllerType         = "replicaset"
 	daemonsetControllerType          = "daemonset"
 	configReaderSidecarContainerType = "configreadersidecar"
@@ -24,63 +24,53 @@ const (
 	sendDSUpMetric                   = false
 )
 
-var (
-	regexHash    = make(map[string]string)
-	intervalHash = make(map[string]string)
-)
-
-var mergedDefaultConfigs map[interface{}]interface{}
-
-func parseConfigMap() string {
-	defer func() {
-		if r := recover(); r != nil {
-			shared.EchoError(fmt.Sprintf("Recovered from panic: %v\n", r))
-		}
-	}()
-
-	if _, err := os.Stat(configMapMountPath); os.IsNotExist(err) {
-		shared.EchoWarning("Custom prometheus config does not exist, using only default scrape targets if they are enabled")
-		return ""
-	}
-
-	config, err := os.ReadFile(configMapMountPath)
-	if err != nil {
-		shared.EchoError(fmt.Sprintf("Exception while parsing configmap for prometheus config: %s. Custom prometheus config will not be used. Please check configmap for errors", err))
-		return ""
-	}
-
-	// shared.EchoVar("Successfully parsed configmap for prometheus config", string(config))
-	return string(config)
-}
-
-func loadRegexHash() {
-	data, err := os.ReadFile(regexHashFile)
+func (p *configPipeline) loadRegexHash() {
+	data, err := p.fs.ReadFile(p.paths.configMapKeepListEnvVarPath)
 	if err != nil {
 		log.Printf("Exception in loadRegexHash for prometheus config: %v. Keep list regexes will not be used\n", err)
 		return
 	}
 
-	err = yaml.Unmarshal(data, &regexHash)
+	err = yaml.Unmarshal(data, &p.regexHash)
 	if err != nil {
 		log.Printf("Exception in loadRegexHash for prometheus config: %v. Keep list regexes will not be used\n", err)
 	}
 }
 
-func loadIntervalHash() {
-	data, err := os.ReadFile(intervalHashFile)
+func (p *configPipeline) loadIntervalHash() {
+	data, err := p.fs.ReadFile(p.paths.scrapeIntervalEnvVarPath)
 	if err != nil {
 		log.Printf("Exception in loadIntervalHash for prometheus config: %v. Scrape interval will not be used\n", err)
 		return
 	}
 
-	err = yaml.Unmarshal(data, &intervalHash)
+	err = yaml.Unmarshal(data, &p.intervalHash)
 	if err != nil {
 		log.Printf("Exception in loadIntervalHash for prometheus config: %v. Scrape interval will not be used\n", err)
 	}
 }
 
-func isConfigReaderSidecar() bool {
-	containerType := os.Getenv("CONTAINER_TYPE")
+func (p *configPipeline) loadDropListHashes() {
+	for hashFile, hash := range map[string]*map[string]string{
+		p.paths.configMapMetricsDropListEnvVarPath: &p.metricsDropListHash,
+		p.paths.configMapLabelDropListEnvVarPath:   &p.labelDropListHash,
+	} {
+		*hash = make(map[string]string)
+		data, err := p.fs.ReadFile(hashFile)
+		if err != nil {
+			log.Printf("Exception in loadDropListHashes for prometheus config: %v. Drop list regexes will not be used\n", err)
+			continue
+		}
+
+		err = yaml.Unmarshal(data, hash)
+		if err != nil {
+			log.Printf("Exception in loadDropListHashes for prometheus config: %v. Drop list regexes will not be used\n", err)
+		}
+	}
+}
+
+func (p *configPipeline) isConfigReaderSidecar() bool {
+	containerType := p.env.Getenv("CONTAINER_TYPE")
 	if containerType != "" {
 		currentContainerType := strings.ToLower(strings.TrimSpace(containerType))
 		if currentContainerType == configReaderSidecarContainerType {
@@ -90,11 +80,11 @@ func isConfigReaderSidecar() bool {
 	return false
 }
 
-func UpdateScrapeIntervalConfig(yamlConfigFile string, scrapeIntervalSetting string) {
+func (p *configPipeline) UpdateScrapeIntervalConfig(yamlConfigFile string, scrapeIntervalSetting string) {
 	log.Printf("Updating scrape interval config for %s\n", yamlConfigFile)
 
 	// Read YAML config file
-	data, err := os.ReadFile(yamlConfigFile)
+	data, err := p.fs.ReadFile(yamlConfigFile)
 	if err != nil {
 		log.Printf("Error reading config file %s: %v. The scrape interval will not be updated\n", yamlConfigFile, err)
 		return
@@ -125,7 +115,7 @@ func UpdateScrapeIntervalConfig(yamlConfigFile string, scrapeIntervalSetting str
 		}
 
 		// Write updated YAML back to file
-		err = os.WriteFile(yamlConfigFile, []byte(cfgYamlWithScrapeConfig), fs.FileMode(0644))
+		err = p.fs.WriteFile(yamlConfigFile, []byte(cfgYamlWithScrapeConfig), fs.FileMode(0644))
 		if err != nil {
 			log.Printf("Error writing to file %s: %v. The scrape interval will not be updated\n", yamlConfigFile, err)
 			return
@@ -135,23 +125,53 @@ func UpdateScrapeIntervalConfig(yamlConfigFile string, scrapeIntervalSetting str
 	}
 }
 
-func AppendMetricRelabelConfig(yamlConfigFile, keepListRegex string) error {
+func (p *configPipeline) AppendMetricRelabelConfig(yamlConfigFile, keepListRegex string) error {
 	log.Printf("Starting to append keep list regex or minimal ingestion regex to %s\n", yamlConfigFile)
 
-	content, err := os.ReadFile(yamlConfigFile)
+	keepListMetricRelabelConfig := map[string]interface{}{
+		"source_labels": []interface{}{"__name__"},
+		"action":        "keep",
+		"regex":         keepListRegex,
+	}
+	return p.appendMetricRelabelConfigs(yamlConfigFile, "keep list regex", keepListMetricRelabelConfig)
+}
+
+// AppendDropListMetricRelabelConfigs appends the rules dropping the metrics matching metricsDropListRegex and the labels
+// matching labelDropListRegex. Empty regexes are skipped.
+func (p *configPipeline) AppendDropListMetricRelabelConfigs(yamlConfigFile, metricsDropListRegex, labelDropListRegex string) error {
+	log.Printf("Starting to append drop list regexes to %s\n", yamlConfigFile)
+
+	dropListMetricRelabelConfigs := []map[string]interface{}{}
+	if metricsDropListRegex != "" {
+		dropListMetricRelabelConfigs = append(dropListMetricRelabelConfigs, map[string]interface{}{
+			"source_labels": []interface{}{"__name__"},
+			"action":        "drop",
+			"regex":         metricsDropListRegex,
+		})
+	}
+	if labelDropListRegex != "" {
+		dropListMetricRelabelConfigs = append(dropListMetricRelabelConfigs, map[string]interface{}{
+			"action": "labeldrop",
+			"regex":  labelDropListRegex,
+		})
+	}
+	if len(dropListMetricRelabelConfigs) == 0 {
+		return nil
+	}
+	return p.appendMetricRelabelConfigs(yamlConfigFile, "drop list regex", dropListMetricRelabelConfigs...)
+}
+
+// appendMetricRelabelConfigs appends the metric relabel configs to every scrape config of the file. what names the
+// configs in the errors.
+func (p *configPipeline) appendMetricRelabelConfigs(yamlConfigFile, what string, metricRelabelConfigs ...map[string]interface{}) error {
+	content, err := p.fs.ReadFile(yamlConfigFile)
 	if err != nil {
-		return fmt.Errorf("error reading config file %s: %v. The keep list regex will not be used", yamlConfigFile, err)
+		return fmt.Errorf("error reading config file %s: %v. The %s will not be used", yamlConfigFile, err, what)
 	}
 
 	var config map[string]interface{}
 	if err := yaml.Unmarshal(content, &config); err != nil {
-		return fmt.Errorf("error unmarshalling YAML for %s: %v. The keep list regex will not be used", yamlConfigFile, err)
-	}
-
-	keepListMetricRelabelConfig := map[string]interface{}{
-		"source_labels": []interface{}{"__name__"},
-		"action":        "keep",
-		"regex":         keepListRegex,
+		return fmt.Errorf("error unmarshalling YAML for %s: %v. The %s will not be used", yamlConfigFile, err, what)
 	}
 
 	if scrapeConfigs, ok := config["scrape_configs"].([]interface{}); ok {
@@ -169,11 +189,11 @@ func AppendMetricRelabelConfig(yamlConfigFile, keepListRegex string) error {
 				}
 
 				// Update or add metric_relabel_configs
-				if metricRelabelCfgs, ok := stringScfgMap["metric_relabel_configs"].([]interface{}); ok {
-					stringScfgMap["metric_relabel_configs"] = append(metricRelabelCfgs, keepListMetricRelabelConfig)
-				} else {
-					stringScfgMap["metric_relabel_configs"] = []interface{}{keepListMetricRelabelConfig}
+				metricRelabelCfgs, _ := stringScfgMap["metric_relabel_configs"].([]interface{})
+				for _, metricRelabelConfig := range metricRelabelConfigs {
+					metricRelabelCfgs = append(metricRelabelCfgs, metricRelabelConfig)
 				}
+				stringScfgMap["metric_relabel_configs"] = metricRelabelCfgs
 
 				// Convert back to map[interface{}]interface{} for YAML marshalling
 				interfaceScfgMap := make(map[interface{}]interface{})
@@ -195,21 +215,21 @@ func AppendMetricRelabelConfig(yamlConfigFile, keepListRegex string) error {
 
 	cfgYamlWithMetricRelabelConfig, err := yaml.Marshal(config)
 	if err != nil {
-		return fmt.Errorf("error marshalling YAML for %s: %v. The keep list regex will not be used", yamlConfigFile, err)
+		return fmt.Errorf("error marshalling YAML for %s: %v. The %s will not be used", yamlConfigFile, err, what)
 	}
 
-	if err := os.WriteFile(yamlConfigFile, cfgYamlWithMetricRelabelConfig, os.ModePerm); err != nil {
-		return fmt.Errorf("error writing to file %s: %v. The keep list regex will not be used", yamlConfigFile, err)
+	if err := p.fs.WriteFile(yamlConfigFile, cfgYamlWithMetricRelabelConfig, os.ModePerm); err != nil {
+		return fmt.Errorf("error writing to file %s: %v. The %s will not be used", yamlConfigFile, err, what)
 	}
 
 	return nil
 }
 
-func AppendRelabelConfig(yamlConfigFile string, relabelConfig []map[string]interface{}, keepRegex string) {
+func (p *configPipeline) AppendRelabelConfig(yamlConfigFile string, relabelConfig []map[string]interface{}, keepRegex string) {
 	log.Printf("Adding relabel config for %s\n", yamlConfigFile)
 
 	// Read YAML config file
-	data, err := os.ReadFile(yamlConfigFile)
+	data, err := p.fs.ReadFile(yamlConfigFile)
 	if err != nil {
 		log.Printf("Error reading config file %s: %v. The relabel config will not be added\n", yamlConfigFile, err)
 		return
@@ -247,7 +267,7 @@ func AppendRelabelConfig(yamlConfigFile string, relabelConfig []map[string]inter
 		}
 
 		// Write updated YAML back to file
-		err = os.WriteFile(yamlConfigFile, []byte(cfgYamlWithRelabelConfig), fs.FileMode(0644))
+		err = p.fs.WriteFile(yamlConfigFile, []byte(cfgYamlWithRelabelConfig), fs.FileMode(0644))
 		if err != nil {
 			log.Printf("Error writing to file %s: %v. The relabel config will not be added\n", yamlConfigFile, err)
 			return
@@ -257,1152 +277,60 @@ func AppendRelabelConfig(yamlConfigFile string, relabelConfig []map[string]inter
 	}
 }
 
-func populateDefaultPrometheusConfig() {
+// populateDefaultPrometheusConfig merges the scrape configs of the enabled default targets used on this agent.
+// With the operator enabled, the config reader sidecar generates the replicaset scrape configs.
+func (p *configPipeline) populateDefaultPrometheusConfig(operatorEnabled bool) {
 	defaultConfigs := []string{}
-	currentControllerType := strings.TrimSpace(strings.ToLower(os.Getenv("CONTROLLER_TYPE")))
-
-	// Default values
-	advancedMode := false
-	windowsDaemonset := false
-
-	// Get current mode (advanced or not...)
-	currentMode := strings.TrimSpace(strings.ToLower(os.Getenv("MODE")))
-	if currentMode == "advanced" {
-		advancedMode = true
-	}
+	agent := p.getDefaultTargetAgent(operatorEnabled)
 
-	// Get if windowsdaemonset is enabled or not (i.e., WINMODE env = advanced or not...)
-	winMode := strings.TrimSpace(strings.ToLower(os.Getenv("WINMODE")))
-	if winMode == "advanced" {
-		windowsDaemonset = true
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_KUBELET_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		kubeletMetricsKeepListRegex, exists := regexHash["KUBELET_METRICS_KEEP_LIST_REGEX"]
-		kubeletScrapeInterval := intervalHash["KUBELET_SCRAPE_INTERVAL"]
-		if currentControllerType == replicasetControllerType {
-			if !advancedMode {
-				UpdateScrapeIntervalConfig(kubeletDefaultFileRsSimple, kubeletScrapeInterval)
-				if exists && kubeletMetricsKeepListRegex != "" {
-					log.Printf("Using regex for Kubelet: %s\n", kubeletMetricsKeepListRegex)
-					AppendMetricRelabelConfig(kubeletDefaultFileRsSimple, kubeletMetricsKeepListRegex)
-				}
-				defaultConfigs = append(defaultConfigs, kubeletDefaultFileRsSimple)
-			} else if windowsDaemonset && sendDSUpMetric {
-				UpdateScrapeIntervalConfig(kubeletDefaultFileRsAdvancedWindowsDaemonset, kubeletScrapeInterval)
-				defaultConfigs = append(defaultConfigs, kubeletDefaultFileRsAdvancedWindowsDaemonset)
-			} else if sendDSUpMetric {
-				UpdateScrapeIntervalConfig(kubeletDefaultFileRsAdvanced, kubeletScrapeInterval)
-				defaultConfigs = append(defaultConfigs, kubeletDefaultFileRsAdvanced)
-			}
-		} else {
-			if advancedMode && (windowsDaemonset || strings.ToLower(os.Getenv("OS_TYPE")) == "linux") {
-				UpdateScrapeIntervalConfig(kubeletDefaultFileDs, kubeletScrapeInterval)
-				if exists && kubeletMetricsKeepListRegex != "" {
-					AppendMetricRelabelConfig(kubeletDefaultFileDs, kubeletMetricsKeepListRegex)
-				}
-				contents, err := os.ReadFile(kubeletDefaultFileDs)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$OS_TYPE$$", os.Getenv("OS_TYPE")))
-					err = os.WriteFile(kubeletDefaultFileDs, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, kubeletDefaultFileDs)
-					}
-				}
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_COREDNS_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" && currentControllerType == replicasetControllerType {
-		corednsMetricsKeepListRegex, exists := regexHash["COREDNS_METRICS_KEEP_LIST_REGEX"]
-		corednsScrapeInterval, intervalExists := intervalHash["COREDNS_SCRAPE_INTERVAL"]
-		if intervalExists {
-			UpdateScrapeIntervalConfig(coreDNSDefaultFile, corednsScrapeInterval)
-		}
-		if exists && corednsMetricsKeepListRegex != "" {
-			AppendMetricRelabelConfig(coreDNSDefaultFile, corednsMetricsKeepListRegex)
-		}
-		defaultConfigs = append(defaultConfigs, coreDNSDefaultFile)
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_CADVISOR_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		cadvisorMetricsKeepListRegex, exists := regexHash["CADVISOR_METRICS_KEEP_LIST_REGEX"]
-		cadvisorScrapeInterval, intervalExists := intervalHash["CADVISOR_SCRAPE_INTERVAL"]
-		if intervalExists {
-			if currentControllerType == replicasetControllerType {
-				if !advancedMode {
-					UpdateScrapeIntervalConfig(cadvisorDefaultFileRsSimple, cadvisorScrapeInterval)
-					if exists && cadvisorMetricsKeepListRegex != "" {
-						AppendMetricRelabelConfig(cadvisorDefaultFileRsSimple, cadvisorMetricsKeepListRegex)
-					}
-					defaultConfigs = append(defaultConfigs, cadvisorDefaultFileRsSimple)
-				} else if sendDSUpMetric {
-					UpdateScrapeIntervalConfig(cadvisorDefaultFileRsAdvanced, cadvisorScrapeInterval)
-					defaultConfigs = append(defaultConfigs, cadvisorDefaultFileRsAdvanced)
-				}
-			} else {
-				if advancedMode && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" {
-					UpdateScrapeIntervalConfig(cadvisorDefaultFileDs, cadvisorScrapeInterval)
-					if exists && cadvisorMetricsKeepListRegex != "" {
-						AppendMetricRelabelConfig(cadvisorDefaultFileDs, cadvisorMetricsKeepListRegex)
-					}
-					contents, err := os.ReadFile(cadvisorDefaultFileDs)
-					if err == nil {
-						contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-						contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-						err = os.WriteFile(cadvisorDefaultFileDs, contents, 0644)
-						if err == nil {
-							defaultConfigs = append(defaultConfigs, cadvisorDefaultFileDs)
-						}
-					}
-				}
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_KUBEPROXY_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" && currentControllerType == replicasetControllerType {
-		kubeproxyMetricsKeepListRegex, exists := regexHash["KUBEPROXY_METRICS_KEEP_LIST_REGEX"]
-		kubeproxyScrapeInterval, intervalExists := intervalHash["KUBEPROXY_SCRAPE_INTERVAL"]
-		if intervalExists {
-			UpdateScrapeIntervalConfig(kubeProxyDefaultFile, kubeproxyScrapeInterval)
-		}
-		if exists && kubeproxyMetricsKeepListRegex != "" {
-			AppendMetricRelabelConfig(kubeProxyDefaultFile, kubeproxyMetricsKeepListRegex)
-		}
-		defaultConfigs = append(defaultConfigs, kubeProxyDefaultFile)
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_APISERVER_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" && currentControllerType == replicasetControllerType {
-		apiserverMetricsKeepListRegex, exists := regexHash["APISERVER_METRICS_KEEP_LIST_REGEX"]
-		apiserverScrapeInterval, intervalExists := intervalHash["APISERVER_SCRAPE_INTERVAL"]
-		if intervalExists {
-			UpdateScrapeIntervalConfig(apiserverDefaultFile, apiserverScrapeInterval)
-		}
-		if exists && apiserverMetricsKeepListRegex != "" {
-			AppendMetricRelabelConfig(apiserverDefaultFile, apiserverMetricsKeepListRegex)
-		}
-		defaultConfigs = append(defaultConfigs, apiserverDefaultFile)
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_KUBESTATE_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" && currentControllerType == replicasetControllerType {
-		kubestateMetricsKeepListRegex, exists := regexHash["KUBESTATE_METRICS_KEEP_LIST_REGEX"]
-		kubestateScrapeInterval, intervalExists := intervalHash["KUBESTATE_SCRAPE_INTERVAL"]
-		log.Printf("path %s: %s\n", "kubeStateDefaultFile", kubeStateDefaultFile)
-
-		if intervalExists {
-			UpdateScrapeIntervalConfig(kubeStateDefaultFile, kubestateScrapeInterval)
-		}
-		if exists && kubestateMetricsKeepListRegex != "" {
-			AppendMetricRelabelConfig(kubeStateDefaultFile, kubestateMetricsKeepListRegex)
-		}
-		contents, err := os.ReadFile(kubeStateDefaultFile)
-		if err == nil {
-			contents = []byte(strings.ReplaceAll(string(contents), "$$KUBE_STATE_NAME$$", os.Getenv("KUBE_STATE_NAME")))
-			contents = []byte(strings.ReplaceAll(string(contents), "$$POD_NAMESPACE$$", os.Getenv("POD_NAMESPACE")))
-			err = os.WriteFile(kubeStateDefaultFile, contents, 0644)
-			if err == nil {
-				defaultConfigs = append(defaultConfigs, kubeStateDefaultFile)
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_NODEEXPORTER_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		nodeexporterMetricsKeepListRegex, exists := regexHash["NODEEXPORTER_METRICS_KEEP_LIST_REGEX"]
-		nodeexporterScrapeInterval := intervalHash["NODEEXPORTER_SCRAPE_INTERVAL"]
-		if currentControllerType == replicasetControllerType {
-			if advancedMode && sendDSUpMetric {
-				UpdateScrapeIntervalConfig(nodeExporterDefaultFileRsAdvanced, nodeexporterScrapeInterval)
-				contents, err := os.ReadFile(nodeExporterDefaultFileRsAdvanced)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_EXPORTER_NAME$$", os.Getenv("NODE_EXPORTER_NAME")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$POD_NAMESPACE$$", os.Getenv("POD_NAMESPACE")))
-					err = os.WriteFile(nodeExporterDefaultFileRsAdvanced, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, nodeExporterDefaultFileRsAdvanced)
-					}
-				}
-			} else if !advancedMode {
-				UpdateScrapeIntervalConfig(nodeExporterDefaultFileRsSimple, nodeexporterScrapeInterval)
-				if exists && nodeexporterMetricsKeepListRegex != "" {
-					AppendMetricRelabelConfig(nodeExporterDefaultFileRsSimple, nodeexporterMetricsKeepListRegex)
-				}
-				contents, err := os.ReadFile(nodeExporterDefaultFileRsSimple)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_EXPORTER_NAME$$", os.Getenv("NODE_EXPORTER_NAME")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$POD_NAMESPACE$$", os.Getenv("POD_NAMESPACE")))
-					err = os.WriteFile(nodeExporterDefaultFileRsSimple, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, nodeExporterDefaultFileRsSimple)
-					}
-				}
-			}
-		} else {
-			if advancedMode && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" {
-				UpdateScrapeIntervalConfig(nodeExporterDefaultFileDs, nodeexporterScrapeInterval)
-				if exists && nodeexporterMetricsKeepListRegex != "" {
-					AppendMetricRelabelConfig(nodeExporterDefaultFileDs, nodeexporterMetricsKeepListRegex)
-				}
-				contents, err := os.ReadFile(nodeExporterDefaultFileDs)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_EXPORTER_TARGETPORT$$", os.Getenv("NODE_EXPORTER_TARGETPORT")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-					err = os.WriteFile(nodeExporterDefaultFileDs, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, nodeExporterDefaultFileDs)
-					}
-				}
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_KAPPIEBASIC_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		kappiebasicMetricsKeepListRegex, exists := regexHash["KAPPIEBASIC_METRICS_KEEP_LIST_REGEX"]
-		kappiebasicScrapeInterval := intervalHash["KAPPIEBASIC_SCRAPE_INTERVAL"]
-		if currentControllerType == replicasetControllerType {
-			// Do nothing - Kappie is not supported to be scrapped automatically outside ds.
-			// If needed, the customer can disable this ds target and enable rs scraping through custom config map
-		} else {
-			if advancedMode && strings.ToLower(os.Getenv("MAC")) == "true" {
-				UpdateScrapeIntervalConfig(kappieBasicDefaultFileDs, kappiebasicScrapeInterval)
-				if exists && kappiebasicMetricsKeepListRegex != "" {
-					AppendMetricRelabelConfig(kappieBasicDefaultFileDs, kappiebasicMetricsKeepListRegex)
-				}
-				contents, err := os.ReadFile(kappieBasicDefaultFileDs)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-					err = os.WriteFile(kappieBasicDefaultFileDs, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, kappieBasicDefaultFileDs)
-					}
-				}
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_NETWORKOBSERVABILITYRETINA_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		networkobservabilityRetinaMetricsKeepListRegex, exists := regexHash["NETWORKOBSERVABILITYRETINA_METRICS_KEEP_LIST_REGEX"]
-		networkobservabilityRetinaScrapeInterval, intervalExists := intervalHash["NETWORKOBSERVABILITYRETINA_SCRAPE_INTERVAL"]
-		if currentControllerType == replicasetControllerType {
-			// Do nothing - Network observability Retina is not supported to be scrapped automatically outside ds.
-			// If needed, the customer can disable this ds target and enable rs scraping through custom config map
-		} else {
-			if advancedMode && strings.ToLower(os.Getenv("MAC")) == "true" {
-				if intervalExists {
-					UpdateScrapeIntervalConfig(networkObservabilityRetinaDefaultFileDs, networkobservabilityRetinaScrapeInterval)
-				}
-				if exists && networkobservabilityRetinaMetricsKeepListRegex != "" {
-					AppendMetricRelabelConfig(networkObservabilityRetinaDefaultFileDs, networkobservabilityRetinaMetricsKeepListRegex)
-				}
-				contents, err := os.ReadFile(networkObservabilityRetinaDefaultFileDs)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-					err = os.WriteFile(networkObservabilityRetinaDefaultFileDs, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, networkObservabilityRetinaDefaultFileDs)
-					}
-				}
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_NETWORKOBSERVABILITYHUBBLE_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		networkobservabilityHubbleMetricsKeepListRegex, exists := regexHash["NETWORKOBSERVABILITYHUBBLE_METRICS_KEEP_LIST_REGEX"]
-		networkobservabilityHubbleScrapeInterval, intervalExists := intervalHash["NETWORKOBSERVABILITYHUBBLE_SCRAPE_INTERVAL"]
-		if currentControllerType == replicasetControllerType {
-			// Do nothing - Network observability Hubble is not supported to be scrapped automatically outside ds.
-			// If needed, the customer can disable this ds target and enable rs scraping through custom config map
-		} else {
-			if advancedMode && strings.ToLower(os.Getenv("MAC")) == "true" && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" {
-				if intervalExists {
-					UpdateScrapeIntervalConfig(networkObservabilityHubbleDefaultFileDs, networkobservabilityHubbleScrapeInterval)
-				}
-				if exists && networkobservabilityHubbleMetricsKeepListRegex != "" {
-					AppendMetricRelabelConfig(networkObservabilityHubbleDefaultFileDs, networkobservabilityHubbleMetricsKeepListRegex)
-				}
-				contents, err := os.ReadFile(networkObservabilityHubbleDefaultFileDs)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-					err = os.WriteFile(networkObservabilityHubbleDefaultFileDs, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, networkObservabilityHubbleDefaultFileDs)
-					}
-				}
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_NETWORKOBSERVABILITYCILIUM_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		networkobservabilityCiliumMetricsKeepListRegex, exists := regexHash["NETWORKOBSERVABILITYCILIUM_METRICS_KEEP_LIST_REGEX"]
-		networkobservabilityCiliumScrapeInterval, intervalExists := intervalHash["NETWORKOBSERVABILITYCILIUM_SCRAPE_INTERVAL"]
-		if currentControllerType == replicasetControllerType {
-			// Do nothing - Network observability Cilium is not supported to be scrapped automatically outside ds.
-			// If needed, the customer can disable this ds target and enable rs scraping through custom config map
-		} else {
-			if advancedMode && strings.ToLower(os.Getenv("MAC")) == "true" && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" {
-				if intervalExists {
-					UpdateScrapeIntervalConfig(networkObservabilityCiliumDefaultFileDs, networkobservabilityCiliumScrapeInterval)
-				}
-				if exists && networkobservabilityCiliumMetricsKeepListRegex != "" {
-					AppendMetricRelabelConfig(networkObservabilityCiliumDefaultFileDs, networkobservabilityCiliumMetricsKeepListRegex)
-				}
-				contents, err := os.ReadFile(networkObservabilityCiliumDefaultFileDs)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-					err = os.WriteFile(networkObservabilityCiliumDefaultFileDs, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, networkObservabilityCiliumDefaultFileDs)
-					}
-				}
-			}
-		}
-	}
-
-	// Add ztunnel support
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_ZTUNNEL_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		ztunnelMetricsKeepListRegex, exists := regexHash["ZTUNNEL_METRICS_KEEP_LIST_REGEX"]
-		ztunnelScrapeInterval, intervalExists := intervalHash["ZTUNNEL_SCRAPE_INTERVAL"]
-		if currentControllerType == replicasetControllerType && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" {
-			fullZtunnelPath := fmt.Sprintf("%s%s", defaultPromConfigPathPrefix, ztunnelDefaultFile)
-			if intervalExists {
-				UpdateScrapeIntervalConfig(fullZtunnelPath, ztunnelScrapeInterval)
-			}
-			if exists && ztunnelMetricsKeepListRegex != "" {
-				AppendMetricRelabelConfig(fullZtunnelPath, ztunnelMetricsKeepListRegex)
-			}
-			contents, err := os.ReadFile(fullZtunnelPath)
-			if err == nil {
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-				err = os.WriteFile(fullZtunnelPath, contents, 0644)
-				if err == nil {
-					defaultConfigs = append(defaultConfigs, fullZtunnelPath)
-				}
-			}
-		}
-	}
-
-	// Add istio-cni support
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_ISTIOCNI_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		istiocniMetricsKeepListRegex, exists := regexHash["ISTIOCNI_METRICS_KEEP_LIST_REGEX"]
-		istiocniScrapeInterval, intervalExists := intervalHash["ISTIOCNI_SCRAPE_INTERVAL"]
-		if currentControllerType == replicasetControllerType && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" {
-			fullIstioCniPath := fmt.Sprintf("%s%s", defaultPromConfigPathPrefix, istioCniDefaultFile)
-			if intervalExists {
-				UpdateScrapeIntervalConfig(fullIstioCniPath, istiocniScrapeInterval)
-			}
-			if exists && istiocniMetricsKeepListRegex != "" {
-				AppendMetricRelabelConfig(fullIstioCniPath, istiocniMetricsKeepListRegex)
-			}
-			contents, err := os.ReadFile(fullIstioCniPath)
-			if err == nil {
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-				err = os.WriteFile(fullIstioCniPath, contents, 0644)
-				if err == nil {
-					defaultConfigs = append(defaultConfigs, fullIstioCniPath)
-				}
-			}
-		}
-	}
-
-	// Add Istio Control Plane (MCP) metrics scraping support
-	// This uses the MESH_MEMBER_METRICS_FQDN environment variable passed from the AKS RP
-	// The FQDN is a full URL like "https://mcp.metrics.endpoint.example.com"
-	// The http_sd_configs endpoint is at /v1/targets and requires Bearer token auth
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_CONTROLPLANE_ISTIO_ENABLED"); exists && strings.ToLower(enabled) == "true" && currentControllerType == replicasetControllerType {
-		meshMemberMetricsFqdn := os.Getenv("MESH_MEMBER_METRICS_FQDN")
-		if meshMemberMetricsFqdn != "" {
-			log.Printf("Istio control plane metrics enabled with FQDN: %s\n", meshMemberMetricsFqdn)
-			parsedURL, parseErr := url.Parse(meshMemberMetricsFqdn)
-
-			// Validate the URL format
-			if parseErr != nil {
-				log.Printf("Failed to parse MESH_MEMBER_METRICS_FQDN as URL: %s, skipping Istio scraping\n", parseErr)
-			} else if parsedURL.Host == "" {
-				log.Printf("MESH_MEMBER_METRICS_FQDN must include scheme and host (e.g., https://host): %s, skipping Istio scraping\n", meshMemberMetricsFqdn)
-			} else {
-				controlplaneIstioKeepListRegex, exists := regexHash["CONTROLPLANE_ISTIO_KEEP_LIST_REGEX"]
-				controlplaneIstioScrapeInterval, intervalExists := intervalHash["CONTROLPLANE_ISTIO_SCRAPE_INTERVAL"]
-				fullControlplaneIstioPath := fmt.Sprintf("%s%s", defaultPromConfigPathPrefix, controlplaneIstioDefaultFile)
-				if intervalExists {
-					UpdateScrapeIntervalConfig(fullControlplaneIstioPath, controlplaneIstioScrapeInterval)
-				}
-				if exists && controlplaneIstioKeepListRegex != "" {
-					AppendMetricRelabelConfig(fullControlplaneIstioPath, controlplaneIstioKeepListRegex)
-				}
-				contents, err := os.ReadFile(fullControlplaneIstioPath)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$MESH_MEMBER_METRICS_FQDN$$", meshMemberMetricsFqdn))
-					err = os.WriteFile(fullControlplaneIstioPath, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, fullControlplaneIstioPath)
-					}
-				}
-			}
-		} else {
-			log.Println("Istio control plane metrics enabled but MESH_MEMBER_METRICS_FQDN environment variable is not set, skipping Istio scraping")
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_COLLECTOR_HEALTH_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		prometheusCollectorHealthInterval, intervalExists := intervalHash["PROMETHEUS_COLLECTOR_HEALTH_SCRAPE_INTERVAL"]
-		if intervalExists {
-			UpdateScrapeIntervalConfig(prometheusCollectorHealthDefaultFile, prometheusCollectorHealthInterval)
-		}
-		defaultConfigs = append(defaultConfigs, prometheusCollectorHealthDefaultFile)
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_WINDOWSEXPORTER_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		winexporterMetricsKeepListRegex, exists := regexHash["WINDOWSEXPORTER_METRICS_KEEP_LIST_REGEX"]
-		windowsexporterScrapeInterval, intervalExists := intervalHash["WINDOWSEXPORTER_SCRAPE_INTERVAL"]
-		if currentControllerType == replicasetControllerType && !advancedMode && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" {
-			if intervalExists {
-				UpdateScrapeIntervalConfig(windowsExporterDefaultRsSimpleFile, windowsexporterScrapeInterval)
-			}
-			if exists && winexporterMetricsKeepListRegex != "" {
-				AppendMetricRelabelConfig(windowsExporterDefaultRsSimpleFile, winexporterMetricsKeepListRegex)
-			}
-			contents, err := os.ReadFile(windowsExporterDefaultRsSimpleFile)
-			if err == nil {
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-				err = os.WriteFile(windowsExporterDefaultRsSimpleFile, contents, 0644)
-				if err == nil {
-					defaultConfigs = append(defaultConfigs, windowsExporterDefaultRsSimpleFile)
-				}
-			}
-		} else if currentControllerType == daemonsetControllerType && advancedMode && windowsDaemonset && strings.ToLower(os.Getenv("OS_TYPE")) == "windows" {
-			if intervalExists {
-				UpdateScrapeIntervalConfig(windowsExporterDefaultDsFile, windowsexporterScrapeInterval)
-			}
-			if exists && winexporterMetricsKeepListRegex != "" {
-				AppendMetricRelabelConfig(windowsExporterDefaultDsFile, winexporterMetricsKeepListRegex)
-			}
-			contents, err := os.ReadFile(windowsExporterDefaultDsFile)
-			if err == nil {
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-				err = os.WriteFile(windowsExporterDefaultDsFile, contents, 0644)
-				if err == nil {
-					defaultConfigs = append(defaultConfigs, windowsExporterDefaultDsFile)
-				}
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_WINDOWSKUBEPROXY_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		winkubeproxyMetricsKeepListRegex, exists := regexHash["WINDOWSKUBEPROXY_METRICS_KEEP_LIST_REGEX"]
-		windowskubeproxyScrapeInterval, intervalExists := intervalHash["WINDOWSKUBEPROXY_SCRAPE_INTERVAL"]
-		if currentControllerType == replicasetControllerType && !advancedMode && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" {
-			if intervalExists {
-				UpdateScrapeIntervalConfig(windowsKubeProxyDefaultFileRsSimpleFile, windowskubeproxyScrapeInterval)
-			}
-			if exists && winkubeproxyMetricsKeepListRegex != "" {
-				AppendMetricRelabelConfig(windowsKubeProxyDefaultFileRsSimpleFile, winkubeproxyMetricsKeepListRegex)
-			}
-			contents, err := os.ReadFile(windowsKubeProxyDefaultFileRsSimpleFile)
-			if err == nil {
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-				err = os.WriteFile(windowsKubeProxyDefaultFileRsSimpleFile, contents, 0644)
-				if err == nil {
-					defaultConfigs = append(defaultConfigs, windowsKubeProxyDefaultFileRsSimpleFile)
-				}
-			}
-		} else if currentControllerType == daemonsetControllerType && advancedMode && windowsDaemonset && strings.ToLower(os.Getenv("OS_TYPE")) == "windows" {
-			if intervalExists {
-				UpdateScrapeIntervalConfig(windowsKubeProxyDefaultDsFile, windowskubeproxyScrapeInterval)
-			}
-			if exists && winkubeproxyMetricsKeepListRegex != "" {
-				AppendMetricRelabelConfig(windowsKubeProxyDefaultDsFile, winkubeproxyMetricsKeepListRegex)
-			}
-			contents, err := os.ReadFile(windowsKubeProxyDefaultDsFile)
-			if err == nil {
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-				err = os.WriteFile(windowsKubeProxyDefaultDsFile, contents, 0644)
-				if err == nil {
-					defaultConfigs = append(defaultConfigs, windowsKubeProxyDefaultDsFile)
-				}
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_POD_ANNOTATION_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" && currentControllerType == replicasetControllerType {
-		if podannotationNamespacesRegex, exists := os.LookupEnv("AZMON_PROMETHEUS_POD_ANNOTATION_NAMESPACES_REGEX"); exists {
-			podannotationMetricsKeepListRegex := regexHash["POD_ANNOTATION_METRICS_KEEP_LIST_REGEX"]
-			podannotationScrapeInterval, intervalExists := intervalHash["POD_ANNOTATION_SCRAPE_INTERVAL"]
-
-			if intervalExists {
-				UpdateScrapeIntervalConfig(podAnnotationsDefaultFile, podannotationScrapeInterval)
-			}
-			if podannotationMetricsKeepListRegex != "" {
-				AppendMetricRelabelConfig(podAnnotationsDefaultFile, podannotationMetricsKeepListRegex)
-			}
-			// Trim the first and last escaped quotes if they exist
-			if len(podannotationNamespacesRegex) > 1 && podannotationNamespacesRegex[0] == '"' && podannotationNamespacesRegex[len(podannotationNamespacesRegex)-1] == '"' {
-				podannotationNamespacesRegex = podannotationNamespacesRegex[1 : len(podannotationNamespacesRegex)-1]
-			}
-			// Additional trim to remove single quotes if present
-			podannotationNamespacesRegex = strings.Trim(podannotationNamespacesRegex, "'")
-
-			if podannotationNamespacesRegex != "" {
-				relabelConfig := []map[string]interface{}{
-					{"source_labels": []string{"__meta_kubernetes_namespace"}, "action": "keep", "regex": podannotationNamespacesRegex},
-				}
-				AppendRelabelConfig(podAnnotationsDefaultFile, relabelConfig, podannotationNamespacesRegex)
-			}
-			defaultConfigs = append(defaultConfigs, podAnnotationsDefaultFile)
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_ACSTORCAPACITYPROVISIONER_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" && currentControllerType == replicasetControllerType {
-		acstorCapacityProvisionerKeepListRegex, exists := regexHash["ACSTORCAPACITYPROVISONER_KEEP_LIST_REGEX"]
-		acstorCapacityProvisionerScrapeInterval, intervalExists := intervalHash["ACSTORCAPACITYPROVISIONER_SCRAPE_INTERVAL"]
-		if intervalExists {
-			UpdateScrapeIntervalConfig(acstorCapacityProvisionerDefaultFile, acstorCapacityProvisionerScrapeInterval)
-		}
-		if exists && acstorCapacityProvisionerKeepListRegex != "" {
-			AppendMetricRelabelConfig(acstorCapacityProvisionerDefaultFile, acstorCapacityProvisionerKeepListRegex)
-		}
-		defaultConfigs = append(defaultConfigs, acstorCapacityProvisionerDefaultFile)
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_ACSTORMETRICSEXPORTER_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" && currentControllerType == replicasetControllerType {
-		acstorMetricsExporterKeepListRegex, exists := regexHash["ACSTORMETRICSEXPORTER_KEEP_LIST_REGEX"]
-		acstorMetricsExporterScrapeInterval, intervalExists := intervalHash["ACSTORMETRICSEXPORTER_SCRAPE_INTERVAL"]
-		if intervalExists {
-			UpdateScrapeIntervalConfig(acstorMetricsExporterDefaultFile, acstorMetricsExporterScrapeInterval)
-		}
-		if exists && acstorMetricsExporterKeepListRegex != "" {
-			AppendMetricRelabelConfig(acstorMetricsExporterDefaultFile, acstorMetricsExporterKeepListRegex)
-		}
-		defaultConfigs = append(defaultConfigs, acstorMetricsExporterDefaultFile)
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_LOCALCSIDRIVER_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" && currentControllerType == replicasetControllerType {
-		LocalCSIDriverKeepListRegex, exists := regexHash["LOCALCSIDRIVER_KEEP_LIST_REGEX"]
-		LocalCSIDriverScrapeInterval, intervalExists := intervalHash["LOCALCSIDRIVER_SCRAPE_INTERVAL"]
-		if intervalExists {
-			UpdateScrapeIntervalConfig(LocalCSIDriverDefaultFile, LocalCSIDriverScrapeInterval)
-		}
-		if exists && LocalCSIDriverKeepListRegex != "" {
-			AppendMetricRelabelConfig(LocalCSIDriverDefaultFile, LocalCSIDriverKeepListRegex)
-		}
-		defaultConfigs = append(defaultConfigs, LocalCSIDriverDefaultFile)
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_DCGMEXPORTER_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		dcgmexporterMetricsKeepListRegex, exists := regexHash["DCGMEXPORTER_METRICS_KEEP_LIST_REGEX"]
-		dcgmexporterScrapeInterval := intervalHash["DCGMEXPORTER_SCRAPE_INTERVAL"]
-		if currentControllerType == replicasetControllerType && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" {
-			UpdateScrapeIntervalConfig(dcgmExporterDefaultFile, dcgmexporterScrapeInterval)
-			if exists && dcgmexporterMetricsKeepListRegex != "" {
-				AppendMetricRelabelConfig(dcgmExporterDefaultFile, dcgmexporterMetricsKeepListRegex)
-			}
-			defaultConfigs = append(defaultConfigs, dcgmExporterDefaultFile)
-		}
-	}
-
-	mergedDefaultConfigs = mergeDefaultScrapeConfigs(defaultConfigs)
-	// if mergedDefaultConfigs != nil {
-	// 	fmt.Printf("Merged default scrape targets: %v\n", mergedDefaultConfigs)
-	// }
-}
-
-func populateDefaultPrometheusConfigWithOperator() {
-	defaultConfigs := []string{}
-
-	envControllerType := os.Getenv("CONTROLLER_TYPE")
-	currentControllerType := ""
-	if envControllerType != "" {
-		currentControllerType = strings.TrimSpace(strings.ToLower(envControllerType))
-	}
-
-	// Default values
-	advancedMode := false
-	windowsDaemonset := false
-
-	envMode := os.Getenv("MODE")
-	currentMode := "default"
-	if envMode != "" {
-		currentMode = strings.TrimSpace(strings.ToLower(envMode))
-	}
-	if currentMode == "advanced" {
-		advancedMode = true
-	}
-
-	// Get if windowsdaemonset is enabled or not (i.e., WINMODE env = advanced or not...)
-	winMode := "default"
-	if envWinMode := os.Getenv("WINMODE"); envWinMode != "" {
-		winMode = strings.TrimSpace(strings.ToLower(envWinMode))
-	}
-	if winMode == "advanced" {
-		windowsDaemonset = true
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_KUBELET_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		kubeletMetricsKeepListRegex, exists := regexHash["KUBELET_METRICS_KEEP_LIST_REGEX"]
-		kubeletScrapeInterval := intervalHash["KUBELET_SCRAPE_INTERVAL"]
-		if isConfigReaderSidecar() || currentControllerType == replicasetControllerType {
-			if !advancedMode {
-				UpdateScrapeIntervalConfig(kubeletDefaultFileRsSimple, kubeletScrapeInterval)
-				if exists && kubeletMetricsKeepListRegex != "" {
-					log.Printf("Using regex for Kubelet: %s\n", kubeletMetricsKeepListRegex)
-					AppendMetricRelabelConfig(kubeletDefaultFileRsSimple, kubeletMetricsKeepListRegex)
-				}
-				defaultConfigs = append(defaultConfigs, kubeletDefaultFileRsSimple)
-			} else if windowsDaemonset && sendDSUpMetric {
-				UpdateScrapeIntervalConfig(kubeletDefaultFileRsAdvancedWindowsDaemonset, kubeletScrapeInterval)
-				defaultConfigs = append(defaultConfigs, kubeletDefaultFileRsAdvancedWindowsDaemonset)
-			} else if sendDSUpMetric {
-				UpdateScrapeIntervalConfig(kubeletDefaultFileRsAdvanced, kubeletScrapeInterval)
-				defaultConfigs = append(defaultConfigs, kubeletDefaultFileRsAdvanced)
-			}
-		} else {
-			if advancedMode && currentControllerType == daemonsetControllerType && (windowsDaemonset || strings.ToLower(os.Getenv("OS_TYPE")) == "linux") {
-				UpdateScrapeIntervalConfig(kubeletDefaultFileDs, kubeletScrapeInterval)
-				if exists && kubeletMetricsKeepListRegex != "" {
-					AppendMetricRelabelConfig(kubeletDefaultFileDs, kubeletMetricsKeepListRegex)
-				}
-				contents, err := os.ReadFile(kubeletDefaultFileDs)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$OS_TYPE$$", os.Getenv("OS_TYPE")))
-					err = os.WriteFile(kubeletDefaultFileDs, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, kubeletDefaultFileDs)
-					}
-				}
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_COREDNS_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" && (isConfigReaderSidecar() || currentControllerType == replicasetControllerType) {
-		corednsMetricsKeepListRegex, exists := regexHash["COREDNS_METRICS_KEEP_LIST_REGEX"]
-		corednsScrapeInterval, intervalExists := intervalHash["COREDNS_SCRAPE_INTERVAL"]
-		if intervalExists {
-			UpdateScrapeIntervalConfig(coreDNSDefaultFile, corednsScrapeInterval)
-		}
-		if exists && corednsMetricsKeepListRegex != "" {
-			AppendMetricRelabelConfig(coreDNSDefaultFile, corednsMetricsKeepListRegex)
-		}
-		defaultConfigs = append(defaultConfigs, coreDNSDefaultFile)
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_CADVISOR_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		cadvisorMetricsKeepListRegex, exists := regexHash["CADVISOR_METRICS_KEEP_LIST_REGEX"]
-		cadvisorScrapeInterval, intervalExists := intervalHash["CADVISOR_SCRAPE_INTERVAL"]
-		if intervalExists {
-			if isConfigReaderSidecar() || currentControllerType == replicasetControllerType {
-				if !advancedMode {
-					UpdateScrapeIntervalConfig(cadvisorDefaultFileRsSimple, cadvisorScrapeInterval)
-					if exists && cadvisorMetricsKeepListRegex != "" {
-						AppendMetricRelabelConfig(cadvisorDefaultFileRsSimple, cadvisorMetricsKeepListRegex)
-					}
-					defaultConfigs = append(defaultConfigs, cadvisorDefaultFileRsSimple)
-				} else if sendDSUpMetric {
-					UpdateScrapeIntervalConfig(cadvisorDefaultFileRsAdvanced, cadvisorScrapeInterval)
-					defaultConfigs = append(defaultConfigs, cadvisorDefaultFileRsAdvanced)
-				}
-			} else {
-				if advancedMode && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" && currentControllerType == daemonsetControllerType {
-					UpdateScrapeIntervalConfig(cadvisorDefaultFileDs, cadvisorScrapeInterval)
-					if exists && cadvisorMetricsKeepListRegex != "" {
-						AppendMetricRelabelConfig(cadvisorDefaultFileDs, cadvisorMetricsKeepListRegex)
-					}
-					contents, err := os.ReadFile(cadvisorDefaultFileDs)
-					if err == nil {
-						contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-						contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-						err = os.WriteFile(cadvisorDefaultFileDs, contents, 0644)
-						if err == nil {
-							defaultConfigs = append(defaultConfigs, cadvisorDefaultFileDs)
-						}
-					}
-				}
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_KUBEPROXY_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" && (isConfigReaderSidecar() || currentControllerType == replicasetControllerType) {
-		kubeproxyMetricsKeepListRegex, exists := regexHash["KUBEPROXY_METRICS_KEEP_LIST_REGEX"]
-		kubeproxyScrapeInterval, intervalExists := intervalHash["KUBEPROXY_SCRAPE_INTERVAL"]
-		if intervalExists {
-			UpdateScrapeIntervalConfig(kubeProxyDefaultFile, kubeproxyScrapeInterval)
-		}
-		if exists && kubeproxyMetricsKeepListRegex != "" {
-			AppendMetricRelabelConfig(kubeProxyDefaultFile, kubeproxyMetricsKeepListRegex)
-		}
-		defaultConfigs = append(defaultConfigs, kubeProxyDefaultFile)
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_APISERVER_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" && (isConfigReaderSidecar() || currentControllerType == replicasetControllerType) {
-		apiserverMetricsKeepListRegex, exists := regexHash["APISERVER_METRICS_KEEP_LIST_REGEX"]
-		apiserverScrapeInterval, intervalExists := intervalHash["APISERVER_SCRAPE_INTERVAL"]
-		if intervalExists {
-			UpdateScrapeIntervalConfig(apiserverDefaultFile, apiserverScrapeInterval)
-		}
-		if exists && apiserverMetricsKeepListRegex != "" {
-			AppendMetricRelabelConfig(apiserverDefaultFile, apiserverMetricsKeepListRegex)
-		}
-		defaultConfigs = append(defaultConfigs, apiserverDefaultFile)
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_KUBESTATE_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" && (isConfigReaderSidecar() || currentControllerType == replicasetControllerType) {
-		kubestateMetricsKeepListRegex, exists := regexHash["KUBESTATE_METRICS_KEEP_LIST_REGEX"]
-		kubestateScrapeInterval, intervalExists := intervalHash["KUBESTATE_SCRAPE_INTERVAL"]
-
-		if intervalExists {
-			UpdateScrapeIntervalConfig(kubeStateDefaultFile, kubestateScrapeInterval)
-		}
-		if exists && kubestateMetricsKeepListRegex != "" {
-			AppendMetricRelabelConfig(kubeStateDefaultFile, kubestateMetricsKeepListRegex)
-		}
-		contents, err := os.ReadFile(kubeStateDefaultFile)
-		if err == nil {
-			contents = []byte(strings.ReplaceAll(string(contents), "$$KUBE_STATE_NAME$$", os.Getenv("KUBE_STATE_NAME")))
-			contents = []byte(strings.ReplaceAll(string(contents), "$$POD_NAMESPACE$$", os.Getenv("POD_NAMESPACE")))
-			err = os.WriteFile(kubeStateDefaultFile, contents, 0644)
-			if err == nil {
-				defaultConfigs = append(defaultConfigs, kubeStateDefaultFile)
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_NODEEXPORTER_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		nodeexporterMetricsKeepListRegex, exists := regexHash["NODEEXPORTER_METRICS_KEEP_LIST_REGEX"]
-		nodeexporterScrapeInterval := intervalHash["NODEEXPORTER_SCRAPE_INTERVAL"]
-		if isConfigReaderSidecar() || currentControllerType == replicasetControllerType {
-			if advancedMode && sendDSUpMetric {
-				UpdateScrapeIntervalConfig(nodeExporterDefaultFileRsAdvanced, nodeexporterScrapeInterval)
-				contents, err := os.ReadFile(nodeExporterDefaultFileRsAdvanced)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_EXPORTER_NAME$$", os.Getenv("NODE_EXPORTER_NAME")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$POD_NAMESPACE$$", os.Getenv("POD_NAMESPACE")))
-					err = os.WriteFile(nodeExporterDefaultFileRsAdvanced, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, nodeExporterDefaultFileRsAdvanced)
-					}
-				}
-			} else if !advancedMode {
-				UpdateScrapeIntervalConfig(nodeExporterDefaultFileRsSimple, nodeexporterScrapeInterval)
-				if exists && nodeexporterMetricsKeepListRegex != "" {
-					AppendMetricRelabelConfig(nodeExporterDefaultFileRsSimple, nodeexporterMetricsKeepListRegex)
-				}
-				contents, err := os.ReadFile(nodeExporterDefaultFileRsSimple)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_EXPORTER_NAME$$", os.Getenv("NODE_EXPORTER_NAME")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$POD_NAMESPACE$$", os.Getenv("POD_NAMESPACE")))
-					err = os.WriteFile(nodeExporterDefaultFileRsSimple, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, nodeExporterDefaultFileRsSimple)
-					}
-				}
-			}
-		} else {
-			if advancedMode && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" && currentControllerType == daemonsetControllerType {
-				UpdateScrapeIntervalConfig(nodeExporterDefaultFileDs, nodeexporterScrapeInterval)
-				if exists && nodeexporterMetricsKeepListRegex != "" {
-					AppendMetricRelabelConfig(nodeExporterDefaultFileDs, nodeexporterMetricsKeepListRegex)
-				}
-				contents, err := os.ReadFile(nodeExporterDefaultFileDs)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_EXPORTER_TARGETPORT$$", os.Getenv("NODE_EXPORTER_TARGETPORT")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-					err = os.WriteFile(nodeExporterDefaultFileDs, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, nodeExporterDefaultFileDs)
-					}
-				}
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_KAPPIEBASIC_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		kappiebasicMetricsKeepListRegex, exists := regexHash["KAPPIEBASIC_METRICS_KEEP_LIST_REGEX"]
-		kappiebasicScrapeInterval := intervalHash["KAPPIEBASIC_SCRAPE_INTERVAL"]
-		if isConfigReaderSidecar() || currentControllerType == replicasetControllerType {
-			// Do nothing - Kappie is not supported to be scrapped automatically outside ds.
-			// If needed, the customer can disable this ds target and enable rs scraping through custom config map
-		} else {
-			if currentControllerType == daemonsetControllerType && advancedMode && strings.ToLower(os.Getenv("MAC")) == "true" {
-				UpdateScrapeIntervalConfig(kappieBasicDefaultFileDs, kappiebasicScrapeInterval)
-				if exists && kappiebasicMetricsKeepListRegex != "" {
-					AppendMetricRelabelConfig(kappieBasicDefaultFileDs, kappiebasicMetricsKeepListRegex)
-				}
-				contents, err := os.ReadFile(kappieBasicDefaultFileDs)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-					err = os.WriteFile(kappieBasicDefaultFileDs, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, kappieBasicDefaultFileDs)
-					}
-				}
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_NETWORKOBSERVABILITYRETINA_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		networkobservabilityRetinaMetricsKeepListRegex, exists := regexHash["NETWORKOBSERVABILITYRETINA_METRICS_KEEP_LIST_REGEX"]
-		networkobservabilityRetinaScrapeInterval, intervalExists := intervalHash["NETWORKOBSERVABILITYRETINA_SCRAPE_INTERVAL"]
-		if isConfigReaderSidecar() || currentControllerType == replicasetControllerType {
-			// Do nothing - Network observability Retina is not supported to be scrapped automatically outside ds.
-			// If needed, the customer can disable this ds target and enable rs scraping through custom config map
-		} else {
-			if advancedMode && strings.ToLower(os.Getenv("MAC")) == "true" {
-				if intervalExists {
-					UpdateScrapeIntervalConfig(networkObservabilityRetinaDefaultFileDs, networkobservabilityRetinaScrapeInterval)
-				}
-				if exists && networkobservabilityRetinaMetricsKeepListRegex != "" {
-					AppendMetricRelabelConfig(networkObservabilityRetinaDefaultFileDs, networkobservabilityRetinaMetricsKeepListRegex)
-				}
-				contents, err := os.ReadFile(networkObservabilityRetinaDefaultFileDs)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-					err = os.WriteFile(networkObservabilityRetinaDefaultFileDs, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, networkObservabilityRetinaDefaultFileDs)
-					}
-				}
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_NETWORKOBSERVABILITYHUBBLE_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		networkobservabilityHubbleMetricsKeepListRegex, exists := regexHash["NETWORKOBSERVABILITYHUBBLE_METRICS_KEEP_LIST_REGEX"]
-		networkobservabilityHubbleScrapeInterval, intervalExists := intervalHash["NETWORKOBSERVABILITYHUBBLE_SCRAPE_INTERVAL"]
-		if isConfigReaderSidecar() || currentControllerType == replicasetControllerType {
-			// Do nothing - Network observability Hubble is not supported to be scrapped automatically outside ds.
-			// If needed, the customer can disable this ds target and enable rs scraping through custom config map
-		} else {
-			if advancedMode && strings.ToLower(os.Getenv("MAC")) == "true" && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" {
-				if intervalExists {
-					UpdateScrapeIntervalConfig(networkObservabilityHubbleDefaultFileDs, networkobservabilityHubbleScrapeInterval)
-				}
-				if exists && networkobservabilityHubbleMetricsKeepListRegex != "" {
-					AppendMetricRelabelConfig(networkObservabilityHubbleDefaultFileDs, networkobservabilityHubbleMetricsKeepListRegex)
-				}
-				contents, err := os.ReadFile(networkObservabilityHubbleDefaultFileDs)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-					err = os.WriteFile(networkObservabilityHubbleDefaultFileDs, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, networkObservabilityHubbleDefaultFileDs)
-					}
-				}
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_NETWORKOBSERVABILITYCILIUM_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		networkobservabilityCiliumMetricsKeepListRegex, exists := regexHash["NETWORKOBSERVABILITYCILIUM_METRICS_KEEP_LIST_REGEX"]
-		networkobservabilityCiliumScrapeInterval, intervalExists := intervalHash["NETWORKOBSERVABILITYCILIUM_SCRAPE_INTERVAL"]
-		if isConfigReaderSidecar() || currentControllerType == replicasetControllerType {
-			// Do nothing - Network observability Cilium is not supported to be scrapped automatically outside ds.
-			// If needed, the customer can disable this ds target and enable rs scraping through custom config map
-		} else {
-			if advancedMode && strings.ToLower(os.Getenv("MAC")) == "true" && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" {
-				if intervalExists {
-					UpdateScrapeIntervalConfig(networkObservabilityCiliumDefaultFileDs, networkobservabilityCiliumScrapeInterval)
-				}
-				if exists && networkobservabilityCiliumMetricsKeepListRegex != "" {
-					AppendMetricRelabelConfig(networkObservabilityCiliumDefaultFileDs, networkobservabilityCiliumMetricsKeepListRegex)
-				}
-				contents, err := os.ReadFile(networkObservabilityCiliumDefaultFileDs)
-				if err == nil {
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-					contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-					err = os.WriteFile(networkObservabilityCiliumDefaultFileDs, contents, 0644)
-					if err == nil {
-						defaultConfigs = append(defaultConfigs, networkObservabilityCiliumDefaultFileDs)
-					}
-				}
-			}
-		}
-	}
-
-	// Add ztunnel support
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_ZTUNNEL_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		ztunnelMetricsKeepListRegex, exists := regexHash["ZTUNNEL_METRICS_KEEP_LIST_REGEX"]
-		ztunnelScrapeInterval, intervalExists := intervalHash["ZTUNNEL_SCRAPE_INTERVAL"]
-		if isConfigReaderSidecar() || (currentControllerType == replicasetControllerType && strings.ToLower(os.Getenv("OS_TYPE")) == "linux") {
-			fullZtunnelPath := fmt.Sprintf("%s%s", defaultPromConfigPathPrefix, ztunnelDefaultFile)
-			if intervalExists {
-				UpdateScrapeIntervalConfig(fullZtunnelPath, ztunnelScrapeInterval)
-			}
-			if exists && ztunnelMetricsKeepListRegex != "" {
-				AppendMetricRelabelConfig(fullZtunnelPath, ztunnelMetricsKeepListRegex)
-			}
-			contents, err := os.ReadFile(fullZtunnelPath)
-			if err == nil {
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-				err = os.WriteFile(fullZtunnelPath, contents, 0644)
-				if err == nil {
-					defaultConfigs = append(defaultConfigs, fullZtunnelPath)
-				}
-			}
-		}
-	}
-
-	// Add istio-cni support
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_ISTIOCNI_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		istiocniMetricsKeepListRegex, exists := regexHash["ISTIOCNI_METRICS_KEEP_LIST_REGEX"]
-		istiocniScrapeInterval, intervalExists := intervalHash["ISTIOCNI_SCRAPE_INTERVAL"]
-		if isConfigReaderSidecar() || (currentControllerType == replicasetControllerType && strings.ToLower(os.Getenv("OS_TYPE")) == "linux") {
-			fullIstioCniPath := fmt.Sprintf("%s%s", defaultPromConfigPathPrefix, istioCniDefaultFile)
-			if intervalExists {
-				UpdateScrapeIntervalConfig(fullIstioCniPath, istiocniScrapeInterval)
-			}
-			if exists && istiocniMetricsKeepListRegex != "" {
-				AppendMetricRelabelConfig(fullIstioCniPath, istiocniMetricsKeepListRegex)
-			}
-			contents, err := os.ReadFile(fullIstioCniPath)
-			if err == nil {
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-				err = os.WriteFile(fullIstioCniPath, contents, 0644)
-				if err == nil {
-					defaultConfigs = append(defaultConfigs, fullIstioCniPath)
-				}
-			}
-		}
-	}
-
-	// Add Istio Control Plane (MCP) metrics scraping support
-	// This uses the MESH_MEMBER_METRICS_FQDN environment variable passed from the AKS RP
-	// The FQDN is a full URL like "https://mcp.metrics.endpoint.example.com"
-	// The http_sd_configs endpoint is at /v1/targets and requires Bearer token auth
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_CONTROLPLANE_ISTIO_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		if isConfigReaderSidecar() || currentControllerType == replicasetControllerType {
-			meshMemberMetricsFqdn := os.Getenv("MESH_MEMBER_METRICS_FQDN")
-			if meshMemberMetricsFqdn != "" {
-				log.Printf("Istio control plane metrics enabled with FQDN: %s\n", meshMemberMetricsFqdn)
-
-				// Validate the URL format
-				parsedURL, parseErr := url.Parse(meshMemberMetricsFqdn)
-				if parseErr != nil {
-					log.Printf("Failed to parse MESH_MEMBER_METRICS_FQDN as URL: %s, skipping Istio scraping\n", parseErr)
-				} else if parsedURL.Host == "" {
-					log.Printf("MESH_MEMBER_METRICS_FQDN must include scheme and host (e.g., https://host): %s, skipping Istio scraping\n", meshMemberMetricsFqdn)
-				} else {
-					controlplaneIstioKeepListRegex, exists := regexHash["CONTROLPLANE_ISTIO_KEEP_LIST_REGEX"]
-					controlplaneIstioScrapeInterval, intervalExists := intervalHash["CONTROLPLANE_ISTIO_SCRAPE_INTERVAL"]
-					fullControlplaneIstioPath := fmt.Sprintf("%s%s", defaultPromConfigPathPrefix, controlplaneIstioDefaultFile)
-					if intervalExists {
-						UpdateScrapeIntervalConfig(fullControlplaneIstioPath, controlplaneIstioScrapeInterval)
-					}
-					if exists && controlplaneIstioKeepListRegex != "" {
-						AppendMetricRelabelConfig(fullControlplaneIstioPath, controlplaneIstioKeepListRegex)
-					}
-					contents, err := os.ReadFile(fullControlplaneIstioPath)
-					if err == nil {
-						contents = []byte(strings.ReplaceAll(string(contents), "$$MESH_MEMBER_METRICS_FQDN$$", meshMemberMetricsFqdn))
-						err = os.WriteFile(fullControlplaneIstioPath, contents, 0644)
-						if err == nil {
-							defaultConfigs = append(defaultConfigs, fullControlplaneIstioPath)
-						}
-					}
-				}
-			} else {
-				log.Println("Istio control plane metrics enabled but MESH_MEMBER_METRICS_FQDN environment variable is not set, skipping Istio scraping")
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_COLLECTOR_HEALTH_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		prometheusCollectorHealthInterval, intervalExists := intervalHash["PROMETHEUS_COLLECTOR_HEALTH_SCRAPE_INTERVAL"]
-		if intervalExists {
-			UpdateScrapeIntervalConfig(prometheusCollectorHealthDefaultFile, prometheusCollectorHealthInterval)
-		}
-		defaultConfigs = append(defaultConfigs, prometheusCollectorHealthDefaultFile)
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_WINDOWSEXPORTER_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		winexporterMetricsKeepListRegex, exists := regexHash["WINDOWSEXPORTER_METRICS_KEEP_LIST_REGEX"]
-		windowsexporterScrapeInterval, intervalExists := intervalHash["WINDOWSEXPORTER_SCRAPE_INTERVAL"]
-		// Not adding the isConfigReaderSidecar check instead of replicaset check since this is legacy 1P chart path and not relevant anymore.
-		if currentControllerType == replicasetControllerType && !advancedMode && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" {
-			if intervalExists {
-				UpdateScrapeIntervalConfig(windowsExporterDefaultRsSimpleFile, windowsexporterScrapeInterval)
-			}
-			if exists && winexporterMetricsKeepListRegex != "" {
-				AppendMetricRelabelConfig(windowsExporterDefaultRsSimpleFile, winexporterMetricsKeepListRegex)
-			}
-			contents, err := os.ReadFile(windowsExporterDefaultRsSimpleFile)
-			if err == nil {
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-				err = os.WriteFile(windowsExporterDefaultRsSimpleFile, contents, 0644)
-				if err == nil {
-					defaultConfigs = append(defaultConfigs, windowsExporterDefaultRsSimpleFile)
-				}
-			}
-		} else if currentControllerType == daemonsetControllerType && advancedMode && windowsDaemonset && strings.ToLower(os.Getenv("OS_TYPE")) == "windows" {
-			if intervalExists {
-				UpdateScrapeIntervalConfig(windowsExporterDefaultDsFile, windowsexporterScrapeInterval)
-			}
-			if exists && winexporterMetricsKeepListRegex != "" {
-				AppendMetricRelabelConfig(windowsExporterDefaultDsFile, winexporterMetricsKeepListRegex)
-			}
-			contents, err := os.ReadFile(windowsExporterDefaultDsFile)
-			if err == nil {
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-				err = os.WriteFile(windowsExporterDefaultDsFile, contents, 0644)
-				if err == nil {
-					defaultConfigs = append(defaultConfigs, windowsExporterDefaultDsFile)
-				}
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_WINDOWSKUBEPROXY_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		winkubeproxyMetricsKeepListRegex, exists := regexHash["WINDOWSKUBEPROXY_METRICS_KEEP_LIST_REGEX"]
-		windowskubeproxyScrapeInterval, intervalExists := intervalHash["WINDOWSKUBEPROXY_SCRAPE_INTERVAL"]
-		// Not adding the isConfigReaderSidecar check instead of replicaset check since this is legacy 1P chart path and not relevant anymore.
-		if currentControllerType == replicasetControllerType && !advancedMode && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" {
-			if intervalExists {
-				UpdateScrapeIntervalConfig(windowsKubeProxyDefaultFileRsSimpleFile, windowskubeproxyScrapeInterval)
-			}
-			if exists && winkubeproxyMetricsKeepListRegex != "" {
-				AppendMetricRelabelConfig(windowsKubeProxyDefaultFileRsSimpleFile, winkubeproxyMetricsKeepListRegex)
-			}
-			contents, err := os.ReadFile(windowsKubeProxyDefaultFileRsSimpleFile)
-			if err == nil {
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-				err = os.WriteFile(windowsKubeProxyDefaultFileRsSimpleFile, contents, 0644)
-				if err == nil {
-					defaultConfigs = append(defaultConfigs, windowsKubeProxyDefaultFileRsSimpleFile)
-				}
-			}
-		} else if currentControllerType == daemonsetControllerType && advancedMode && windowsDaemonset && strings.ToLower(os.Getenv("OS_TYPE")) == "windows" {
-			if intervalExists {
-				UpdateScrapeIntervalConfig(windowsKubeProxyDefaultDsFile, windowskubeproxyScrapeInterval)
-			}
-			if exists && winkubeproxyMetricsKeepListRegex != "" {
-				AppendMetricRelabelConfig(windowsKubeProxyDefaultDsFile, winkubeproxyMetricsKeepListRegex)
-			}
-			contents, err := os.ReadFile(windowsKubeProxyDefaultDsFile)
-			if err == nil {
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_IP$$", os.Getenv("NODE_IP")))
-				contents = []byte(strings.ReplaceAll(string(contents), "$$NODE_NAME$$", os.Getenv("NODE_NAME")))
-				err = os.WriteFile(windowsKubeProxyDefaultDsFile, contents, 0644)
-				if err == nil {
-					defaultConfigs = append(defaultConfigs, windowsKubeProxyDefaultDsFile)
-				}
-			}
-		}
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_POD_ANNOTATION_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" && (isConfigReaderSidecar() || currentControllerType == replicasetControllerType) {
-		if podannotationNamespacesRegex, exists := os.LookupEnv("AZMON_PROMETHEUS_POD_ANNOTATION_NAMESPACES_REGEX"); exists {
-			podannotationMetricsKeepListRegex := regexHash["POD_ANNOTATION_METRICS_KEEP_LIST_REGEX"]
-			podannotationScrapeInterval, intervalExists := intervalHash["POD_ANNOTATION_SCRAPE_INTERVAL"]
-
-			if intervalExists {
-				UpdateScrapeIntervalConfig(podAnnotationsDefaultFile, podannotationScrapeInterval)
-			}
-			if podannotationMetricsKeepListRegex != "" {
-				AppendMetricRelabelConfig(podAnnotationsDefaultFile, podannotationMetricsKeepListRegex)
-			}
-			// Trim the first and last escaped quotes if they exist
-			if len(podannotationNamespacesRegex) > 1 && podannotationNamespacesRegex[0] == '"' && podannotationNamespacesRegex[len(podannotationNamespacesRegex)-1] == '"' {
-				podannotationNamespacesRegex = podannotationNamespacesRegex[1 : len(podannotationNamespacesRegex)-1]
-			}
-			// Additional trim to remove single quotes if present
-			podannotationNamespacesRegex = strings.Trim(podannotationNamespacesRegex, "'")
-
-			if podannotationNamespacesRegex != "" {
-				relabelConfig := []map[string]interface{}{
-					{"source_labels": []string{"__meta_kubernetes_namespace"}, "action": "keep", "regex": podannotationNamespacesRegex},
-				}
-				AppendRelabelConfig(podAnnotationsDefaultFile, relabelConfig, podannotationNamespacesRegex)
-			}
-			defaultConfigs = append(defaultConfigs, podAnnotationsDefaultFile)
+	for _, target := range p.defaultTargets {
+		config := target.enabledConfigFor(p, agent)
+		if config == nil {
+			continue
 		}
-	}
 
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_ACSTORCAPACITYPROVISIONER_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" && (isConfigReaderSidecar() || currentControllerType == replicasetControllerType) {
-		acstorCapacityProvisionerKeepListRegex, exists := regexHash["ACSTORCAPACITYPROVISONER_KEEP_LIST_REGEX"]
-		acstorCapacityProvisionerScrapeInterval, intervalExists := intervalHash["ACSTORCAPACITYPROVISIONER_SCRAPE_INTERVAL"]
-		log.Printf("path %s: %s\n", "acstorCapacityProvisionerDefaultFile", acstorCapacityProvisionerDefaultFile)
-		if intervalExists {
-			UpdateScrapeIntervalConfig(acstorCapacityProvisionerDefaultFile, acstorCapacityProvisionerScrapeInterval)
+		configFile := filepath.Join(p.paths.defaultScrapeConfigsDir, config.File)
+		if config.inPlace {
+			configFile = filepath.Join(p.paths.defaultPromConfigsDir, config.File)
 		}
-		if exists && acstorCapacityProvisionerKeepListRegex != "" {
-			AppendMetricRelabelConfig(acstorCapacityProvisionerDefaultFile, acstorCapacityProvisionerKeepListRegex)
+		if scrapeInterval, set := target.scrapeIntervalSetting(p); set {
+			p.UpdateScrapeIntervalConfig(configFile, scrapeInterval)
 		}
-		defaultConfigs = append(defaultConfigs, acstorCapacityProvisionerDefaultFile)
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_ACSTORMETRICSEXPORTER_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" && (isConfigReaderSidecar() || currentControllerType == replicasetControllerType) {
-		acstorMetricsExporterKeepListRegex, exists := regexHash["ACSTORMETRICSEXPORTER_KEEP_LIST_REGEX"]
-		acstorMetricsExporterScrapeInterval, intervalExists := intervalHash["ACSTORMETRICSEXPORTER_SCRAPE_INTERVAL"]
-		log.Printf("path %s: %s\n", "acstorMetricsExporterDefaultFile", acstorMetricsExporterDefaultFile)
-		if intervalExists {
-			UpdateScrapeIntervalConfig(acstorMetricsExporterDefaultFile, acstorMetricsExporterScrapeInterval)
+		if limits := p.loadedScrapeLimits.forDefaultTarget(target.Name); len(limits) > 0 {
+			p.UpdateScrapeLimitsConfig(configFile, limits)
 		}
-		if exists && acstorMetricsExporterKeepListRegex != "" {
-			AppendMetricRelabelConfig(acstorMetricsExporterDefaultFile, acstorMetricsExporterKeepListRegex)
+		if keepListRegex := p.regexHash[target.KeepListEnvVar]; keepListRegex != "" && !config.noKeepList {
+			log.Printf("Using regex for %s: %s\n", target.Name, keepListRegex)
+			p.AppendMetricRelabelConfig(configFile, keepListRegex)
 		}
-		defaultConfigs = append(defaultConfigs, acstorMetricsExporterDefaultFile)
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_LOCALCSIDRIVER_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" && (isConfigReaderSidecar() || currentControllerType == replicasetControllerType) {
-		LocalCSIDriverKeepListRegex, exists := regexHash["LOCALCSIDRIVER_KEEP_LIST_REGEX"]
-		LocalCSIDriverScrapeInterval, intervalExists := intervalHash["LOCALCSIDRIVER_SCRAPE_INTERVAL"]
-		log.Printf("path %s: %s\n", "LocalCSIDriverDefaultFile", LocalCSIDriverDefaultFile)
-		if intervalExists {
-			UpdateScrapeIntervalConfig(LocalCSIDriverDefaultFile, LocalCSIDriverScrapeInterval)
+		// The drop lists apply to what the keep list kept, and to every metric of the configs without a keep list
+		if err := p.AppendDropListMetricRelabelConfigs(configFile, p.metricsDropListHash[target.Name], p.labelDropListHash[target.Name]); err != nil {
+			log.Printf("Error appending drop lists for %s: %v\n", target.Name, err)
 		}
-		if exists && LocalCSIDriverKeepListRegex != "" {
-			AppendMetricRelabelConfig(LocalCSIDriverDefaultFile, LocalCSIDriverKeepListRegex)
+		if target.customize != nil {
+			target.customize(p, configFile)
 		}
-		defaultConfigs = append(defaultConfigs, LocalCSIDriverDefaultFile)
-	}
-
-	if enabled, exists := os.LookupEnv("AZMON_PROMETHEUS_DCGMEXPORTER_SCRAPING_ENABLED"); exists && strings.ToLower(enabled) == "true" {
-		dcgmexporterMetricsKeepListRegex, exists := regexHash["DCGMEXPORTER_METRICS_KEEP_LIST_REGEX"]
-		dcgmexporterScrapeInterval := intervalHash["DCGMEXPORTER_SCRAPE_INTERVAL"]
-		if isConfigReaderSidecar() || currentControllerType == replicasetControllerType {
-			log.Printf("path %s: %s\n", "dcgmExporterDefaultFile", dcgmExporterDefaultFile)
-			UpdateScrapeIntervalConfig(dcgmExporterDefaultFile, dcgmexporterScrapeInterval)
-			if exists && dcgmexporterMetricsKeepListRegex != "" {
-				AppendMetricRelabelConfig(dcgmExporterDefaultFile, dcgmexporterMetricsKeepListRegex)
+		if len(config.Placeholders) > 0 {
+			if err := p.replacePlaceholders(configFile, config.Placeholders); err != nil {
+				log.Printf("Error replacing placeholders in %s: %v. The default target %s will not be scraped\n", configFile, err, target.Name)
+				continue
 			}
-			defaultConfigs = append(defaultConfigs, dcgmExporterDefaultFile)
 		}
+		p.recordDefaultTargetProvenance(target, config, configFile)
+		defaultConfigs = append(defaultConfigs, configFile)
 	}
 
-	mergedDefaultConfigs = mergeDefaultScrapeConfigs(defaultConfigs)
-	// if mergedDefaultConfigs != nil {
-	// 	fmt.Printf("Merged default scrape targets: %v\n", mergedDefaultConfigs)
-	// }
+	p.mergedDefaultConfigs = p.mergeDefaultScrapeConfigs(defaultConfigs)
 }
 
-func mergeDefaultScrapeConfigs(defaultScrapeConfigs []string) map[interface{}]interface{} {
+func (p *configPipeline) mergeDefaultScrapeConfigs(defaultScrapeConfigs []string) map[interface{}]interface{} {
 	mergedDefaultConfigs := make(map[interface{}]interface{})
 
 	if len(defaultScrapeConfigs) > 0 {
 		mergedDefaultConfigs["scrape_configs"] = make([]interface{}, 0)
 
 		for _, defaultScrapeConfig := range defaultScrapeConfigs {
-			defaultConfigYaml, err := loadYAMLFromFile(defaultScrapeConfig)
+			defaultConfigYaml, err := p.loadYAMLFromFile(defaultScrapeConfig)
 			if err != nil {
 				log.Printf("Error loading YAML from file %s: %s\n", defaultScrapeConfig, err)
 				continue
@@ -1417,8 +345,8 @@ func mergeDefaultScrapeConfigs(defaultScrapeConfigs []string) map[interface{}]in
 	return mergedDefaultConfigs
 }
 
-func loadYAMLFromFile(filename string) (map[interface{}]interface{}, error) {
-	fileContent, err := os.ReadFile(filename)
+func (p *configPipeline) loadYAMLFromFile(filename string) (map[interface{}]interface{}, error) {
+	fileContent, err := p.fs.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
@@ -1464,55 +392,53 @@ func deepMerge(target, source map[interface{}]interface{}) map[interface{}]inter
 	return target
 }
 
-func writeDefaultScrapeTargetsFile(operatorEnabled bool) map[interface{}]interface{} {
-	noDefaultScrapingEnabled := os.Getenv("AZMON_PROMETHEUS_NO_DEFAULT_SCRAPING_ENABLED")
+func (p *configPipeline) writeDefaultScrapeTargetsFile(operatorEnabled bool) map[interface{}]interface{} {
+	noDefaultScrapingEnabled := p.env.Getenv("AZMON_PROMETHEUS_NO_DEFAULT_SCRAPING_ENABLED")
 	if noDefaultScrapingEnabled != "" && strings.ToLower(noDefaultScrapingEnabled) == "false" {
-		loadRegexHash()
-		loadIntervalHash()
-		if operatorEnabled {
-			populateDefaultPrometheusConfigWithOperator()
-		} else {
-			populateDefaultPrometheusConfig()
-		}
-		if mergedDefaultConfigs != nil && len(mergedDefaultConfigs) > 0 {
+		p.loadRegexHash()
+		p.loadIntervalHash()
+		p.loadDropListHashes()
+		p.loadPodAnnotationSettings()
+		p.populateDefaultPrometheusConfig(operatorEnabled)
+		if p.mergedDefaultConfigs != nil && len(p.mergedDefaultConfigs) > 0 {
 			log.Printf("Starting to merge default prometheus config values in collector template as backup\n")
-			mergedDefaultConfigYaml, err := yaml.Marshal(mergedDefaultConfigs)
+			mergedDefaultConfigYaml, err := yaml.Marshal(p.mergedDefaultConfigs)
 			if err != nil {
 				log.Printf("Error marshalling merged default prometheus config: %v\n", err)
 				return nil
 			}
 
-			err = os.WriteFile(mergedDefaultConfigPath, mergedDefaultConfigYaml, fs.FileMode(0644))
+			err = p.fs.WriteFile(p.paths.mergedDefaultConfigPath, mergedDefaultConfigYaml, fs.FileMode(0644))
 			if err != nil {
 				log.Printf("Error writing merged default prometheus config to file: %v\n", err)
 				return nil
 			}
 
-			return mergedDefaultConfigs
+			return p.mergedDefaultConfigs
 		}
 	} else {
-		mergedDefaultConfigs = nil
+		p.mergedDefaultConfigs = nil
 	}
 	log.Printf("Done creating default targets file\n")
 	return nil
 }
 
-func setDefaultFileScrapeInterval(scrapeInterval string) {
-	defaultFilesArray := []string{
-		kubeletDefaultFileRsSimple, kubeletDefaultFileRsAdvanced, kubeletDefaultFileDs,
-		kubeletDefaultFileRsAdvancedWindowsDaemonset, coreDNSDefaultFile,
-		cadvisorDefaultFileRsSimple, cadvisorDefaultFileRsAdvanced, cadvisorDefaultFileDs,
-		kubeProxyDefaultFile, apiserverDefaultFile, kubeStateDefaultFile,
-		nodeExporterDefaultFileRsSimple, nodeExporterDefaultFileRsAdvanced, nodeExporterDefaultFileDs,
-		prometheusCollectorHealthDefaultFile, windowsExporterDefaultRsSimpleFile, windowsExporterDefaultDsFile,
-		windowsKubeProxyDefaultFileRsSimpleFile, windowsKubeProxyDefaultDsFile, podAnnotationsDefaultFile,
-		kappieBasicDefaultFileDs, networkObservabilityRetinaDefaultFileDs, networkObservabilityHubbleDefaultFileDs,
-		networkObservabilityCiliumDefaultFileDs, acstorMetricsExporterDefaultFile, acstorCapacityProvisionerDefaultFile,
-		LocalCSIDriverDefaultFile, dcgmExporterDefaultFile,
+func (p *configPipeline) setDefaultFileScrapeInterval(scrapeInterval string) {
+	defaultFilesArray := []string{}
+	for _, target := range p.defaultTargets {
+		for _, config := range target.Configs {
+			if !config.inPlace && !slices.Contains(defaultFilesArray, config.File) {
+				defaultFilesArray = append(defaultFilesArray, config.File)
+			}
+		}
 	}
 
+	if err := p.fs.MkdirAll(p.paths.defaultScrapeConfigsDir, fs.FileMode(0755)); err != nil {
+		log.Printf("Error creating the default scrape configs directory %s: %v\n", p.paths.defaultScrapeConfigsDir, err)
+		return
+	}
 	for _, currentFile := range defaultFilesArray {
-		contents, err := os.ReadFile(fmt.Sprintf("%s%s", defaultPromConfigPathPrefix, currentFile))
+		contents, err := p.fs.ReadFile(filepath.Join(p.paths.defaultPromConfigsDir, currentFile))
 		if err != nil {
 			log.Printf("Error reading file %s: %v\n", currentFile, err)
 			continue
@@ -1520,14 +446,14 @@ func setDefaultFileScrapeInterval(scrapeInterval string) {
 
 		contents = []byte(strings.Replace(string(contents), "$$SCRAPE_INTERVAL$$", scrapeInterval, -1))
 
-		err = os.WriteFile(currentFile, contents, fs.FileMode(0644))
+		err = p.fs.WriteFile(filepath.Join(p.paths.defaultScrapeConfigsDir, currentFile), contents, fs.FileMode(0644))
 		if err != nil {
 			log.Printf("Error writing to file %s: %v\n", currentFile, err)
 		}
 	}
 }
 
-func mergeDefaultAndCustomScrapeConfigs(customPromConfig string, mergedDefaultConfigs map[interface{}]interface{}) {
+func (p *configPipeline) mergeDefaultAndCustomScrapeConfigs(customPromConfig string, mergedDefaultConfigs map[interface{}]interface{}) {
 	var mergedConfigYaml []byte
 
 	if mergedDefaultConfigs != nil && len(mergedDefaultConfigs) > 0 {
@@ -1559,14 +485,16 @@ func mergeDefaultAndCustomScrapeConfigs(customPromConfig string, mergedDefaultCo
 		mergedConfigYaml = []byte(customPromConfig)
 	}
 
-	err := os.WriteFile(promMergedConfigPath, mergedConfigYaml, fs.FileMode(0644))
+	err := p.fs.WriteFile(p.paths.promMergedConfigPath, mergedConfigYaml, fs.FileMode(0644))
 	if err != nil {
 		shared.EchoError(fmt.Sprintf("Error writing merged config to file: %v", err))
 		return
 	}
 }
 
-func setLabelLimitsPerScrape(prometheusConfigString string) string {
+// setScrapeLimitsPerScrape sets the scrape limits of the scrape-limits settings section on every custom scrape job,
+// falling back to the agent's label limits
+func (p *configPipeline) setScrapeLimitsPerScrape(prometheusConfigString string) string {
 	customConfig := prometheusConfigString
 
 	var limitedCustomConfig map[interface{}]interface{}
@@ -1579,14 +507,25 @@ func setLabelLimitsPerScrape(prometheusConfigString string) string {
 	if limitedCustomConfig != nil && len(limitedCustomConfig) > 0 {
 		limitedCustomScrapes, _ := limitedCustomConfig["scrape_configs"].([]interface{})
 		if limitedCustomScrapes != nil && len(limitedCustomScrapes) > 0 {
+			customJobNames := []string{}
 			for _, scrape := range limitedCustomScrapes {
 				scrapeMap, _ := scrape.(map[interface{}]interface{})
-				scrapeMap["label_limit"] = 63
-				scrapeMap["label_name_length_limit"] = 511
-				scrapeMap["label_value_length_limit"] = 1023
-				shared.EchoVar(fmt.Sprintf("Successfully set label limits in custom scrape config for job %s", scrapeMap["job_name"]), "")
+				if scrapeMap == nil {
+					continue
+				}
+				jobName, _ := scrapeMap["job_name"].(string)
+				customJobNames = append(customJobNames, jobName)
+				limits := p.loadedScrapeLimits.forCustomJob(jobName, scrapeMap)
+				p.recordLabelLimitChanges(jobName, scrapeMap, limits)
+				setScrapeLimits(scrapeMap, limits)
+				shared.EchoVar(fmt.Sprintf("Successfully set scrape limits in custom scrape config for job %s", jobName), "")
+			}
+			for jobName := range p.loadedScrapeLimits.Jobs {
+				if !slices.Contains(customJobNames, jobName) {
+					shared.EchoWarning(fmt.Sprintf("No custom scrape job %s found for its limits in the %s section", jobName, scrapeLimitsSection))
+				}
 			}
-			shared.EchoWarning("Done setting label limits for custom scrape config ...")
+			shared.EchoWarning("Done setting scrape limits for custom scrape config ...")
 			updatedConfig, err := yaml.Marshal(limitedCustomConfig)
 			if err != nil {
 				shared.EchoError(fmt.Sprintf("Error marshalling custom config: %v", err))
@@ -1594,16 +533,81 @@ func setLabelLimitsPerScrape(prometheusConfigString string) string {
 			}
 			return string(updatedConfig)
 		} else {
-			shared.EchoWarning("No Jobs found to set label limits while processing custom scrape config")
+			shared.EchoWarning("No Jobs found to set scrape limits while processing custom scrape config")
 			return prometheusConfigString
 		}
 	} else {
-		shared.EchoWarning("Nothing to set for label limits while processing custom scrape config")
+		shared.EchoWarning("Nothing to set for scrape limits while processing custom scrape config")
 		return prometheusConfigString
 	}
 }
 
-func setGlobalScrapeConfigInDefaultFilesIfExists(configString string) string {
+// UpdateScrapeLimitsConfig sets the scrape limits on every job of the scrape config file of a default target
+func (p *configPipeline) UpdateScrapeLimitsConfig(yamlConfigFile string, limits scrapeLimits) {
+	log.Printf("Updating scrape limits config for %s\n", yamlConfigFile)
+
+	config, err := p.loadYAMLFromFile(yamlConfigFile)
+	if err != nil {
+		log.Printf("Error loading config file %s: %v. The scrape limits will not be updated\n", yamlConfigFile, err)
+		return
+	}
+
+	scrapeConfigs, ok := config["scrape_configs"].([]interface{})
+	if !ok {
+		log.Printf("No 'scrape_configs' found in the YAML. The scrape limits will not be updated.\n")
+		return
+	}
+	for _, scfg := range scrapeConfigs {
+		if scfgMap, ok := scfg.(map[interface{}]interface{}); ok {
+			setScrapeLimits(scfgMap, limits)
+		}
+	}
+
+	cfgYamlWithScrapeLimits, err := yaml.Marshal(config)
+	if err != nil {
+		log.Printf("Error marshalling YAML for %s: %v. The scrape limits will not be updated\n", yamlConfigFile, err)
+		return
+	}
+	err = p.fs.WriteFile(yamlConfigFile, cfgYamlWithScrapeLimits, fs.FileMode(0644))
+	if err != nil {
+		log.Printf("Error writing to file %s: %v. The scrape limits will not be updated\n", yamlConfigFile, err)
+	}
+}
+
+// recordLabelLimitChanges records the label limits that setting the limits on a custom scrape job sets or overrides,
+// reported by the config validator
+func (p *configPipeline) recordLabelLimitChanges(jobName string, scrapeMap map[interface{}]interface{}, limits scrapeLimits) {
+	for _, field := range []string{"label_limit", "label_name_length_limit", "label_value_length_limit"} {
+		value, isInt := limits[field].(int)
+		if !isInt {
+			continue
+		}
+		change := shared.LabelLimitChange{Job: jobName, Field: field, Value: value}
+		if previous, exists := scrapeMap[field]; exists {
+			previous, isInt := previous.(int)
+			if isInt && previous == value {
+				continue
+			}
+			change.Previous = &previous
+		}
+		p.labelLimitChanges = append(p.labelLimitChanges, change)
+	}
+}
+
+// writeLabelLimitChanges writes the label limits set on the custom scrape jobs for the config validator
+func (p *configPipeline) writeLabelLimitChanges() {
+	if err := shared.WriteLabelLimitChanges(p.paths.labelLimitChangesEnvVarPath, p.labelLimitChanges); err != nil {
+		log.Printf("Error writing the label limit changes: %v\n", err)
+	}
+}
+
+func setScrapeLimits(scrapeMap map[interface{}]interface{}, limits scrapeLimits) {
+	for field, value := range limits {
+		scrapeMap[field] = value
+	}
+}
+
+func (p *configPipeline) setGlobalScrapeConfigInDefaultFilesIfExists(configString string) string {
 	var customConfig map[interface{}]interface{}
 	err := yaml.Unmarshal([]byte(configString), &customConfig)
 	if err != nil {
@@ -1628,7 +632,7 @@ func setGlobalScrapeConfigInDefaultFilesIfExists(configString string) string {
 		}
 	}
 
-	setDefaultFileScrapeInterval(scrapeInterval)
+	p.setDefaultFileScrapeInterval(scrapeInterval)
 
 	updatedConfig, err := yaml.Marshal(customConfig)
 	if err != nil {
@@ -1639,21 +643,26 @@ func setGlobalScrapeConfigInDefaultFilesIfExists(configString string) string {
 	return string(updatedConfig)
 }
 
-func prometheusConfigMerger(operatorEnabled bool) {
+func (p *configPipeline) prometheusConfigMerger(operatorEnabled bool) {
 	shared.EchoSectionDivider("Start Processing - prometheusConfigMerger")
-	mergedDefaultConfigs = make(map[interface{}]interface{}) // Initialize mergedDefaultConfigs
-	prometheusConfigMap := parseConfigMap()
+	p.mergedDefaultConfigs = make(map[interface{}]interface{}) // Initialize mergedDefaultConfigs
+	p.scrapeJobProvenance = &shared.ConfigProvenance{Jobs: []shared.ScrapeJobProvenance{}}
+	defer p.writeConfigProvenance()
+	p.labelLimitChanges = []shared.LabelLimitChange{}
+	defer p.writeLabelLimitChanges()
+	p.loadScrapeLimits()
+	prometheusConfigMap := p.parseConfigFragments(operatorEnabled)
 
 	if len(prometheusConfigMap) > 0 {
-		modifiedPrometheusConfigString := setGlobalScrapeConfigInDefaultFilesIfExists(prometheusConfigMap)
-		writeDefaultScrapeTargetsFile(operatorEnabled)
-		// Set label limits for every custom scrape job, before merging the default & custom config
-		labellimitedconfigString := setLabelLimitsPerScrape(modifiedPrometheusConfigString)
-		mergeDefaultAndCustomScrapeConfigs(labellimitedconfigString, mergedDefaultConfigs)
+		modifiedPrometheusConfigString := p.setGlobalScrapeConfigInDefaultFilesIfExists(prometheusConfigMap)
+		p.writeDefaultScrapeTargetsFile(operatorEnabled)
+		// Set scrape limits for every custom scrape job, before merging the default & custom config
+		labellimitedconfigString := p.setScrapeLimitsPerScrape(modifiedPrometheusConfigString)
+		p.mergeDefaultAndCustomScrapeConfigs(labellimitedconfigString, p.mergedDefaultConfigs)
 		shared.EchoSectionDivider("End Processing - prometheusConfigMerger, Done Merging Default and Custom Prometheus Config")
 	} else {
-		setDefaultFileScrapeInterval("30s")
-		writeDefaultScrapeTargetsFile(operatorEnabled)
+		p.setDefaultFileScrapeInterval("30s")
+		p.writeDefaultScrapeTargetsFile(operatorEnabled)
 		shared.EchoSectionDivider("End Processing - prometheusConfigMerger, Done Writing Default Prometheus Config")
 	}
 }
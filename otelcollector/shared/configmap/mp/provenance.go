@@ -0,0 +1,118 @@
+package configmapsettings
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/prometheus-collector/shared"
+	"gopkg.in/yaml.v2"
+)
+
+// recordDefaultTargetProvenance records the jobs of the scrape config file of a default target, once the settings
+// were applied to it
+func (p *configPipeline) recordDefaultTargetProvenance(target DefaultTarget, config *DefaultTargetConfig, configFile string) {
+	defaultConfig, err := p.loadYAMLFromFile(configFile)
+	if err != nil {
+		log.Printf("Error loading YAML from file %s: %v. The provenance of its jobs will not be recorded\n", configFile, err)
+		return
+	}
+
+	source := shared.ProvenanceSourceDefaultTarget
+	modifiedBy := []shared.SettingProvenance{}
+	if target.Name == "podannotations" {
+		source = shared.ProvenanceSourcePodAnnotations
+		modifiedBy = append(modifiedBy, p.podAnnotationSettingsProvenance()...)
+	}
+	if scrapeInterval, exists := p.intervalHash[target.ScrapeIntervalEnvVar]; exists {
+		modifiedBy = append(modifiedBy, settingProvenance("default-targets-scrape-interval-settings", target.Name, scrapeInterval))
+	}
+	if limits := p.loadedScrapeLimits.forDefaultTarget(target.Name); len(limits) > 0 {
+		modifiedBy = append(modifiedBy, settingProvenance(scrapeLimitsSection, target.Name, formatScrapeLimits(limits)))
+	}
+	if keepListRegex := p.regexHash[target.KeepListEnvVar]; keepListRegex != "" && !config.noKeepList {
+		modifiedBy = append(modifiedBy, settingProvenance("default-targets-metrics-keep-list", target.Name, keepListRegex))
+	}
+	if regex := p.metricsDropListHash[target.Name]; regex != "" {
+		modifiedBy = append(modifiedBy, settingProvenance(metricsDropListSection, target.Name, regex))
+	}
+	if regex := p.labelDropListHash[target.Name]; regex != "" {
+		modifiedBy = append(modifiedBy, settingProvenance(labelDropListSection, target.Name, regex))
+	}
+	p.scrapeJobProvenance.AddJobs(scrapeJobNames(defaultConfig), source, target.Name, config.File, modifiedBy)
+}
+
+// podAnnotationSettingsProvenance returns the pod-annotation-based-scraping settings applied to the pod annotations jobs
+func (p *configPipeline) podAnnotationSettingsProvenance() []shared.SettingProvenance {
+	modifiedBy := []shared.SettingProvenance{}
+	if regex := p.env.Getenv(envVariableTemplateName); regex != "" {
+		modifiedBy = append(modifiedBy, settingProvenance(LOGGING_PREFIX, podAnnotationNamespaceRegexKey, regex))
+	}
+	if p.loadedPodAnnotationSettings.LabelSelector != "" {
+		modifiedBy = append(modifiedBy, settingProvenance(LOGGING_PREFIX, podAnnotationLabelSelectorKey, p.loadedPodAnnotationSettings.LabelSelector))
+	}
+	if p.loadedPodAnnotationSettings.IndexedPortCount > 0 {
+		modifiedBy = append(modifiedBy, settingProvenance(LOGGING_PREFIX, podAnnotationIndexedPortCountKey, fmt.Sprint(p.loadedPodAnnotationSettings.IndexedPortCount)))
+	}
+	for _, namespace := range slices.Sorted(maps.Keys(p.loadedPodAnnotationSettings.Namespaces)) {
+		settings, err := yaml.Marshal(p.loadedPodAnnotationSettings.Namespaces[namespace])
+		if err != nil {
+			continue
+		}
+		modifiedBy = append(modifiedBy, settingProvenance(LOGGING_PREFIX, namespace, strings.TrimSpace(string(settings))))
+	}
+	return modifiedBy
+}
+
+// recordCustomConfigProvenance records the jobs of the custom config fragments that were merged
+func (p *configPipeline) recordCustomConfigProvenance(fragments []configFragment, skipped map[string]error) {
+	for _, fragment := range fragments {
+		if _, exists := skipped[fragment.name]; exists {
+			continue
+		}
+		var config map[interface{}]interface{}
+		if err := yaml.Unmarshal([]byte(fragment.contents), &config); err != nil {
+			continue
+		}
+		source := shared.ProvenanceSourceConfigFragment
+		if fragment.name == "prometheus-config" {
+			source = shared.ProvenanceSourceCustomConfig
+		}
+		scrapeConfigs, _ := config["scrape_configs"].([]interface{})
+		for _, scrapeConfig := range scrapeConfigs {
+			scrapeMap, _ := scrapeConfig.(map[interface{}]interface{})
+			jobName, ok := scrapeMap["job_name"].(string)
+			if !ok {
+				continue
+			}
+			modifiedBy := []shared.SettingProvenance{}
+			if len(p.loadedScrapeLimits.Cluster) > 0 || len(p.loadedScrapeLimits.Jobs[jobName]) > 0 {
+				modifiedBy = append(modifiedBy, settingProvenance(scrapeLimitsSection, "job."+jobName, formatScrapeLimits(p.loadedScrapeLimits.forCustomJob(jobName, scrapeMap))))
+			}
+			p.scrapeJobProvenance.AddJobs([]string{jobName}, source, "", fragment.name, modifiedBy)
+		}
+	}
+}
+
+// writeConfigProvenance writes the provenance of the merged jobs for the explain endpoints
+func (p *configPipeline) writeConfigProvenance() {
+	p.scrapeJobProvenance.ConfigVersion = p.env.Getenv("AZMON_AGENT_CFG_FILE_VERSION")
+	if err := shared.WriteConfigProvenance(p.paths.configProvenanceEnvVarPath, p.scrapeJobProvenance); err != nil {
+		log.Printf("Error writing the config provenance: %v\n", err)
+	}
+}
+
+func settingProvenance(section, key, value string) shared.SettingProvenance {
+	return shared.SettingProvenance{Setting: fmt.Sprintf("%s.%s", section, key), Value: value}
+}
+
+// formatScrapeLimits returns the limits as field=value pairs in field order
+func formatScrapeLimits(limits scrapeLimits) string {
+	fields := []string{}
+	for _, field := range slices.Sorted(maps.Keys(limits)) {
+		fields = append(fields, fmt.Sprintf("%s=%v", field, limits[field]))
+	}
+	return strings.Join(fields, ",")
+}
@@ -0,0 +1,51 @@
+package configmapsettings
+
+import (
+	"github.com/prometheus-collector/shared"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ConfigProvenance", func() {
+	It("should record the source of the merged jobs and the settings that modified them", func() {
+		result, err := DryRun(DryRunOptions{
+			Settings: map[string]string{
+				"schema-version": "v1",
+				"config-version": "ver1",
+				"default-targets-metrics-keep-list": `
+					kubelet = "kubelet_running_pods"
+					minimalingestionprofile = false
+				`,
+			},
+			PrometheusConfig: `
+scrape_configs:
+  - job_name: my-app
+    static_configs:
+      - targets: ["my-app:8080"]
+`,
+			DefaultPromConfigsDir: "../../../configmapparser/default-prom-configs",
+			Env: map[string]string{
+				"CONTROLLER_TYPE": "ReplicaSet",
+				"OS_TYPE":         "linux",
+				"MODE":            "simple",
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		kubelet := result.Provenance.Job("kubelet")
+		Expect(kubelet).NotTo(BeNil())
+		Expect(kubelet.Source).To(Equal(shared.ProvenanceSourceDefaultTarget))
+		Expect(kubelet.DefaultTarget).To(Equal("kubelet"))
+		Expect(kubelet.ModifiedBy).To(ContainElement(shared.SettingProvenance{
+			Setting: "default-targets-metrics-keep-list.kubelet",
+			Value:   "kubelet_running_pods",
+		}))
+
+		customJob := result.Provenance.Job("my-app")
+		Expect(customJob).NotTo(BeNil())
+		Expect(customJob.Source).To(Equal(shared.ProvenanceSourceCustomConfig))
+		Expect(customJob.File).To(Equal("prometheus-config"))
+		Expect(customJob.ModifiedBy).To(BeEmpty())
+	})
+})
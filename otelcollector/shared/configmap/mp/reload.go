@@ -0,0 +1,258 @@
+package configmapsettings
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus-collector/shared"
+	"gopkg.in/yaml.v2"
+)
+
+// ErrRestartRequired is returned by ReloadConfig when the new settings cannot be applied without restarting the
+// container, for example because they are used by other processes than the otelcollector.
+var ErrRestartRequired = errors.New("restart required to apply the settings")
+
+// ConfigRejectedError is returned by ReloadConfig when the new config failed validation and the current config is kept.
+type ConfigRejectedError struct {
+	Message string
+}
+
+func (e *ConfigRejectedError) Error() string {
+	return "the new config was rejected, keeping the current config: " + e.Message
+}
+
+// restartRequiredSections are the settings sections used outside of the otelcollector config, by ME, mdsd,
+// fluent-bit or the other containers.
+var restartRequiredSections = []string{
+	"prometheus-collector-settings",
+	"debug-mode",
+	"opentelemetry-metrics",
+	"ksm-config",
+}
+
+// saveReloadBaseline keeps what ReloadConfig needs to run the config pipeline again from the start. The default
+// scrape configs are only saved once, since they were already modified in place when the baseline exists.
+func (p *configPipeline) saveReloadBaseline() {
+	if fileExists(p.fs, p.paths.defaultPromConfigsBaselineDir) {
+		return
+	}
+	if err := copyDir(p.fs, p.paths.defaultPromConfigsDir, p.paths.defaultPromConfigsBaselineDir); err != nil {
+		log.Printf("Error copying the default scrape configs to %s: %v. Config changes will require a restart\n", p.paths.defaultPromConfigsBaselineDir, err)
+	}
+}
+
+// ReloadConfig runs the config pipeline again for the current settings and prometheus configmaps and, when the
+// resulting config is valid, writes it to collectorConfig so the running otelcollector can be signaled to reload it.
+// A *ConfigRejectedError is returned when the config is invalid and ErrRestartRequired when the changed settings
+// cannot be reloaded.
+func (a *AgentConfigPipeline) ReloadConfig(collectorConfig string) error {
+	return a.pipeline.reloadConfig(collectorConfig)
+}
+
+func (p *configPipeline) reloadConfig(collectorConfig string) error {
+	if p.startupEnv == nil {
+		return fmt.Errorf("%w: the config pipeline has not run yet", ErrRestartRequired)
+	}
+	opts, err := p.reloadDryRunOptions()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRestartRequired, err)
+	}
+	result, err := DryRun(opts)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRestartRequired, err)
+	}
+
+	if result.Env["AZMON_AGENT_CFG_SCHEMA_VERSION"] != p.appliedEnv["AZMON_AGENT_CFG_SCHEMA_VERSION"] {
+		return fmt.Errorf("%w: the schema version changed", ErrRestartRequired)
+	}
+	for _, section := range restartRequiredSections {
+		if !maps.Equal(result.Sections[section], p.appliedSections[section]) {
+			return fmt.Errorf("%w: the %s section changed", ErrRestartRequired, section)
+		}
+	}
+
+	promConfig, useDefaultConfig := result.MergedConfig, "false"
+	if promConfig == nil {
+		promConfig, useDefaultConfig = result.DefaultsMergedConfig, "true"
+	}
+	if promConfig == nil {
+		return fmt.Errorf("%w: no scrape configs are enabled", ErrRestartRequired)
+	}
+
+	stagingDir, err := os.MkdirTemp("", "prom-config-reload")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRestartRequired, err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	stagedCollectorConfig, err := p.validateReloadedConfig(stagingDir, promConfig, result.ExternalLabels, result.CollectorProcessors)
+	if err != nil {
+		return err
+	}
+
+	// Rename so the otelcollector never reads a partially written config
+	tmpCollectorConfig := collectorConfig + ".reload"
+	if err := copyFile(p.fs, stagedCollectorConfig, tmpCollectorConfig); err != nil {
+		return fmt.Errorf("%w: %v", ErrRestartRequired, err)
+	}
+	if err := p.fs.Rename(tmpCollectorConfig, collectorConfig); err != nil {
+		return fmt.Errorf("%w: %v", ErrRestartRequired, err)
+	}
+
+	if useDefaultConfig == "false" {
+		p.saveLastKnownGoodConfig(collectorConfig)
+	} else {
+		p.clearLastKnownGoodConfig()
+	}
+
+	newEnv := result.Env
+	newEnv["AZMON_INVALID_CUSTOM_PROMETHEUS_CONFIG"] = "false"
+	newEnv[usingLastKnownGoodConfigEnvVar] = "false"
+	newEnv["CONFIG_VALIDATOR_RUNNING_IN_AGENT"] = "true"
+	newEnv["AZMON_USE_DEFAULT_PROMETHEUS_CONFIG"] = useDefaultConfig
+	p.applyReloadedEnv(newEnv)
+	p.appliedSections = result.Sections
+	p.recordEffectiveConfigDiff(collectorConfig)
+
+	log.Printf("Reloaded the config into %s\n", collectorConfig)
+	return nil
+}
+
+// reloadDryRunOptions reads the mounted configmaps the same way the agent does at startup.
+func (p *configPipeline) reloadDryRunOptions() (DryRunOptions, error) {
+	opts := DryRunOptions{
+		Settings:              make(map[string]string),
+		DefaultPromConfigsDir: p.paths.defaultPromConfigsBaselineDir,
+		Env:                   p.startupEnv,
+	}
+	if _, err := p.fs.Stat(p.paths.defaultPromConfigsBaselineDir); err != nil {
+		return opts, fmt.Errorf("the default scrape configs were not saved: %v", err)
+	}
+
+	entries, err := p.fs.ReadDir(p.paths.configMapSettingsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return opts, fmt.Errorf("reading settings: %v", err)
+	}
+	for _, entry := range entries {
+		// Skip the ..data links of the configmap volume and the mounted prometheus config
+		path := filepath.Join(p.paths.configMapSettingsDir, entry.Name())
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if info, err := p.fs.Stat(path); err != nil || info.IsDir() {
+			continue
+		}
+		contents, err := p.fs.ReadFile(path)
+		if err != nil {
+			return opts, fmt.Errorf("reading setting %s: %v", entry.Name(), err)
+		}
+		opts.Settings[entry.Name()] = string(contents)
+	}
+
+	if contents, err := p.fs.ReadFile(p.paths.configMapMountPath); err == nil {
+		opts.PrometheusConfig = string(contents)
+	} else if !os.IsNotExist(err) {
+		return opts, fmt.Errorf("reading prometheus config: %v", err)
+	}
+	opts.PrometheusConfigFragments = make(map[string]string)
+	for _, fragment := range p.readConfigFragments() {
+		if fragment.name != filepath.Base(p.paths.configMapMountPath) {
+			opts.PrometheusConfigFragments[fragment.name] = fragment.contents
+		}
+	}
+
+	if fileExists(p.fs, p.paths.defaultTargetsFilePath) {
+		opts.DefaultTargetsFile = p.paths.defaultTargetsFilePath
+	}
+	return opts, nil
+}
+
+// validateReloadedConfig runs the config validator on the merged prometheus config, the external labels and the
+// collector processors and returns the collector config it generated.
+func (p *configPipeline) validateReloadedConfig(stagingDir string, promConfig map[string]interface{}, externalLabels map[string]string, processors *shared.CollectorProcessors) (string, error) {
+	contents, err := yaml.Marshal(promConfig)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRestartRequired, err)
+	}
+	stagedPromConfig := filepath.Join(stagingDir, "promMergedConfig.yml")
+	if err := p.fs.WriteFile(stagedPromConfig, contents, fs.FileMode(0644)); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRestartRequired, err)
+	}
+
+	labelsContents, err := yaml.Marshal(externalLabels)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRestartRequired, err)
+	}
+	stagedExternalLabels := filepath.Join(stagingDir, "external-labels.yml")
+	if err := p.fs.WriteFile(stagedExternalLabels, labelsContents, fs.FileMode(0644)); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRestartRequired, err)
+	}
+
+	stagedProcessors := filepath.Join(stagingDir, "collector-processors.yml")
+	processorsContents := []byte{}
+	if processors != nil {
+		if processorsContents, err = yaml.Marshal(processors); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrRestartRequired, err)
+		}
+	}
+	if err := p.fs.WriteFile(stagedProcessors, processorsContents, fs.FileMode(0644)); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRestartRequired, err)
+	}
+
+	// Remove the previous report so a stale one is not mistaken for the result of this validation
+	p.fs.Remove(shared.PromConfigValidatorReportPath)
+	stagedCollectorConfig := filepath.Join(stagingDir, "collector-config.yml")
+	err = shared.StartCommandAndWait(p.paths.promConfigValidatorPath,
+		"--config", stagedPromConfig,
+		"--output", stagedCollectorConfig,
+		"--otelTemplate", p.paths.collectorConfigTemplatePath,
+		"--externalLabels", stagedExternalLabels,
+		"--processors", stagedProcessors,
+		"--labelLimitChanges", p.paths.labelLimitChangesEnvVarPath,
+	)
+	if err != nil {
+		return "", &ConfigRejectedError{Message: validationErrorMessage(err)}
+	}
+	return stagedCollectorConfig, nil
+}
+
+// validationErrorMessage returns the errors from the validation report, or err when there is no report.
+func validationErrorMessage(err error) string {
+	report, reportErr := shared.ReadValidationReport(shared.PromConfigValidatorReportPath)
+	if reportErr != nil {
+		return err.Error()
+	}
+	messages := []string{}
+	for _, finding := range report.Findings {
+		if finding.Severity == shared.ValidationSeverityError {
+			messages = append(messages, finding.Message)
+		}
+	}
+	if len(messages) == 0 {
+		return err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// applyReloadedEnv sets the environment of the reloaded config in the current process and removes the variables that
+// are no longer set. The variables are not published to .bashrc again: the settings used by the other processes
+// are in restartRequiredSections, so a reload never changes them.
+func (p *configPipeline) applyReloadedEnv(newEnv map[string]string) {
+	for key := range p.appliedEnv {
+		if _, exists := newEnv[key]; !exists {
+			p.env.Unsetenv(key)
+		}
+	}
+	for key, value := range newEnv {
+		if current, exists := p.env.LookupEnv(key); !exists || current != value {
+			p.env.SetenvInProcess(key, value)
+		}
+	}
+	p.appliedEnv = newEnv
+}
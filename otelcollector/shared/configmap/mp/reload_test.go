@@ -0,0 +1,149 @@
+package configmapsettings
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReloadConfig", func() {
+	const clusterMetrics = `default-targets-scrape-enabled: |-
+  kubelet = true
+  cadvisor = false
+  kubestate = false
+  nodeexporter = false
+`
+	const customConfig = `scrape_configs:
+- job_name: my-job
+  static_configs:
+  - targets: ['localhost:9090']
+`
+
+	var (
+		workDir         string
+		collectorConfig string
+		mapEnv          *MapEnvironment
+	)
+
+	writeFile := func(path string, contents string) {
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		workDir = GinkgoT().TempDir()
+		testPipeline.paths.configMapSettingsDir = filepath.Join(workDir, "settings")
+		testPipeline.paths.configMapMountPath = filepath.Join(testPipeline.paths.configMapSettingsDir, "prometheus", "prometheus-config")
+		testPipeline.paths.schemaVersionFile = filepath.Join(testPipeline.paths.configMapSettingsDir, "schema-version")
+		testPipeline.paths.configVersionFile = filepath.Join(testPipeline.paths.configMapSettingsDir, "config-version")
+		testPipeline.paths.collectorConfigTemplatePath = filepath.Join(workDir, "collector-config-template.yml")
+		testPipeline.paths.lastKnownGoodConfigDir = filepath.Join(workDir, "last-known-good")
+		testPipeline.paths.defaultPromConfigsBaselineDir = "../../../configmapparser/default-prom-configs/"
+
+		// The validator stub copies the prometheus config and the external labels and rejects the configs with an invalid job
+		testPipeline.paths.promConfigValidatorPath = filepath.Join(workDir, "promconfigvalidator")
+		writeFile(testPipeline.paths.promConfigValidatorPath, `#!/bin/sh
+while [ $# -gt 0 ]; do
+  case $1 in
+    --config) config=$2 ;;
+    --output) output=$2 ;;
+    --externalLabels) labels=$2 ;;
+  esac
+  shift 2
+done
+grep -q invalid-job "$config" && exit 1
+cp "$config" "$output"
+[ -n "$labels" ] && cat "$labels" >> "$output"
+exit 0
+`)
+		Expect(os.Chmod(testPipeline.paths.promConfigValidatorPath, 0755)).To(Succeed())
+
+		writeFile(testPipeline.paths.schemaVersionFile, "v2")
+		writeFile(filepath.Join(testPipeline.paths.configMapSettingsDir, "cluster-metrics"), clusterMetrics)
+		writeFile(filepath.Join(testPipeline.paths.configMapSettingsDir, "prometheus-collector-settings"), "cluster_alias = \"\"\n")
+		collectorConfig = filepath.Join(workDir, "collector-config.yml")
+		writeFile(collectorConfig, "current config")
+
+		testPipeline.startupEnv = map[string]string{"CONTROLLER_TYPE": "ReplicaSet", "OS_TYPE": "linux", "MODE": "simple"}
+		testPipeline.appliedEnv = map[string]string{"AZMON_AGENT_CFG_SCHEMA_VERSION": "v2", "STALE_SETTING": "true"}
+		testPipeline.appliedSections = map[string]map[string]string{"prometheus-collector-settings": {"cluster_alias": ""}}
+		mapEnv = NewMapEnvironment(testPipeline.appliedEnv)
+		testPipeline.env = mapEnv
+	})
+
+	It("should write the validated config and update the environment", func() {
+		writeFile(testPipeline.paths.configMapMountPath, customConfig)
+
+		Expect(testPipeline.reloadConfig(collectorConfig)).To(Succeed())
+
+		contents, err := os.ReadFile(collectorConfig)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(ContainSubstring("job_name: my-job"))
+		Expect(string(contents)).To(ContainSubstring("job_name: kubelet"))
+		Expect(mapEnv.Vars()).To(HaveKeyWithValue("AZMON_USE_DEFAULT_PROMETHEUS_CONFIG", "false"))
+		Expect(mapEnv.Vars()).To(HaveKeyWithValue("AZMON_PROMETHEUS_KUBELET_SCRAPING_ENABLED", "true"))
+		Expect(mapEnv.Vars()).NotTo(HaveKey("STALE_SETTING"))
+
+		snapshot, err := os.ReadFile(filepath.Join(testPipeline.paths.lastKnownGoodConfigDir, lastKnownGoodCollectorConfigFile))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(snapshot).To(Equal(contents))
+	})
+
+	It("should use the default scrape configs when there is no custom config", func() {
+		Expect(testPipeline.reloadConfig(collectorConfig)).To(Succeed())
+		Expect(mapEnv.Vars()).To(HaveKeyWithValue("AZMON_USE_DEFAULT_PROMETHEUS_CONFIG", "true"))
+	})
+
+	It("should pass the external labels to the validator", func() {
+		writeFile(filepath.Join(testPipeline.paths.configMapSettingsDir, "cluster-metrics"), clusterMetrics+"external-labels: |-\n  environment = \"prod\"\n")
+
+		Expect(testPipeline.reloadConfig(collectorConfig)).To(Succeed())
+
+		contents, err := os.ReadFile(collectorConfig)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(ContainSubstring("environment: prod"))
+	})
+
+	It("should keep the current config when the new config is rejected", func() {
+		writeFile(testPipeline.paths.configMapMountPath, `scrape_configs:
+- job_name: invalid-job
+`)
+
+		err := testPipeline.reloadConfig(collectorConfig)
+		var rejectedErr *ConfigRejectedError
+		Expect(errors.As(err, &rejectedErr)).To(BeTrue())
+
+		contents, err := os.ReadFile(collectorConfig)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal("current config"))
+		Expect(mapEnv.Vars()).To(HaveKey("STALE_SETTING"))
+	})
+
+	It("should require a restart when settings used outside the collector change", func() {
+		writeFile(filepath.Join(testPipeline.paths.configMapSettingsDir, "prometheus-collector-settings"), "cluster_alias = \"my-cluster\"\n")
+
+		Expect(testPipeline.reloadConfig(collectorConfig)).To(MatchError(ErrRestartRequired))
+
+		contents, err := os.ReadFile(collectorConfig)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal("current config"))
+	})
+})
+
+var _ = Describe("processEnvironment", func() {
+	It("should only report the variables used by the pipeline with their startup values", func() {
+		GinkgoT().Setenv("PROCESS_ENV_TEST_USED", "startup")
+		GinkgoT().Setenv("PROCESS_ENV_TEST_UNUSED", "value")
+		env := newProcessEnvironment()
+
+		Expect(env.SetenvInProcess("PROCESS_ENV_TEST_USED", "reloaded")).To(Succeed())
+		Expect(env.SetenvInProcess("PROCESS_ENV_TEST_NEW", "reloaded")).To(Succeed())
+		defer os.Unsetenv("PROCESS_ENV_TEST_NEW")
+
+		Expect(env.Vars()).To(Equal(map[string]string{"PROCESS_ENV_TEST_USED": "reloaded", "PROCESS_ENV_TEST_NEW": "reloaded"}))
+		Expect(env.StartupVars()).To(Equal(map[string]string{"PROCESS_ENV_TEST_USED": "startup"}))
+	})
+})
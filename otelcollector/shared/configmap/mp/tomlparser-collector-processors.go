@@ -0,0 +1,77 @@
+package configmapsettings
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/prometheus-collector/shared"
+	"gopkg.in/yaml.v2"
+)
+
+// invalidCollectorProcessorsEnvVar is set to true by the config validator when the collector rejected the processors
+// and the default pipeline is used
+const invalidCollectorProcessorsEnvVar = "AZMON_INVALID_COLLECTOR_PROCESSORS"
+
+// parseCollectorProcessors reads the processors of the collector-processors key of the settings configmap. The key is
+// YAML, in the format of shared.CollectorProcessors, since the processor configs are nested.
+func (p *configPipeline) parseCollectorProcessors() (*shared.CollectorProcessors, error) {
+	contents, err := p.fs.ReadFile(p.paths.collectorProcessorsMountPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var processors shared.CollectorProcessors
+	if err := yaml.UnmarshalStrict(contents, &processors); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %v", err)
+	}
+	if err := processors.Validate(); err != nil {
+		return nil, err
+	}
+	return &processors, nil
+}
+
+func (p *configPipeline) tomlparserCollectorProcessors() {
+	shared.EchoSectionDivider("Start Processing - tomlparserCollectorProcessors")
+	processors, err := p.parseCollectorProcessors()
+	if err != nil {
+		shared.EchoError(fmt.Sprintf("Skipping the collector processors, the default pipeline will be used: %v", err))
+		processors = nil
+	}
+	out := []byte{}
+	if processors != nil && len(processors.Processors) > 0 {
+		log.Printf("Adding %d processors to the collector pipeline\n", len(processors.Processors))
+		if out, err = yaml.Marshal(processors); err != nil {
+			log.Printf("Error marshalling the collector processors: %v\n", err)
+			return
+		}
+	}
+	if err := p.fs.WriteFile(p.paths.collectorProcessorsEnvVarPath, out, fs.FileMode(0644)); err != nil {
+		log.Printf("Error writing to file: %v\n", err)
+		return
+	}
+	shared.EchoSectionDivider("End Processing - tomlparserCollectorProcessors")
+}
+
+// SetCollectorProcessorsInCollectorConfig adds the collector processors to the replicaset collector config used with
+// the target allocator, unless the config validator rejected them.
+func (a *AgentConfigPipeline) SetCollectorProcessorsInCollectorConfig() {
+	a.pipeline.setCollectorProcessorsInCollectorConfig()
+}
+
+func (p *configPipeline) setCollectorProcessorsInCollectorConfig() {
+	if p.env.Getenv(invalidCollectorProcessorsEnvVar) == "true" {
+		log.Println("The collector processors were rejected by the config validator, using the default pipeline")
+		return
+	}
+	processors, err := shared.ReadCollectorProcessors(p.paths.collectorProcessorsEnvVarPath)
+	if err != nil {
+		log.Printf("Exception in SetCollectorProcessorsInCollectorConfig: %v. Using the default pipeline\n", err)
+		return
+	}
+	if processors != nil {
+		shared.ApplyCollectorProcessorsInCollectorConfig(p.paths.replicaSetCollectorConfig, processors)
+	}
+}
@@ -0,0 +1,70 @@
+package configmapsettings
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+)
+
+var _ = Describe("CollectorProcessors", func() {
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		testPipeline.paths.collectorProcessorsMountPath = filepath.Join(dir, "collector-processors")
+		testPipeline.paths.collectorProcessorsEnvVarPath = filepath.Join(dir, "config_collector_processors")
+		testPipeline.paths.replicaSetCollectorConfig = filepath.Join(dir, "collector-config-replicaset.yml")
+		contents, err := os.ReadFile("./testdata/collector-config-replicaset.yml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(testPipeline.paths.replicaSetCollectorConfig, contents, 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		testPipeline.env.Unsetenv(invalidCollectorProcessorsEnvVar)
+	})
+
+	readPipeline := func() []interface{} {
+		contents, err := os.ReadFile(testPipeline.paths.replicaSetCollectorConfig)
+		Expect(err).NotTo(HaveOccurred())
+		var otelConfig map[string]interface{}
+		Expect(yaml.Unmarshal(contents, &otelConfig)).To(Succeed())
+		service := otelConfig["service"].(map[interface{}]interface{})
+		metrics := service["pipelines"].(map[interface{}]interface{})["metrics"].(map[interface{}]interface{})
+		return metrics["processors"].([]interface{})
+	}
+
+	It("should add the processors to the replicaset collector config used with the target allocator", func() {
+		Expect(os.WriteFile(testPipeline.paths.collectorProcessorsMountPath, []byte(`position: after_resource
+processors:
+  - name: attributes/hash-user
+    config:
+      actions:
+        - key: user_id
+          action: hash
+`), 0644)).To(Succeed())
+		testPipeline.tomlparserCollectorProcessors()
+
+		testPipeline.setCollectorProcessorsInCollectorConfig()
+		Expect(readPipeline()).To(Equal([]interface{}{"batch", "resource", "attributes/hash-user"}))
+	})
+
+	It("should use the default pipeline for invalid processors", func() {
+		Expect(os.WriteFile(testPipeline.paths.collectorProcessorsMountPath, []byte("processors:\n  - name: batch/other\n    config: {}\n"), 0644)).To(Succeed())
+		_, err := testPipeline.parseCollectorProcessors()
+		Expect(err).To(HaveOccurred())
+		testPipeline.tomlparserCollectorProcessors()
+
+		testPipeline.setCollectorProcessorsInCollectorConfig()
+		Expect(readPipeline()).To(Equal([]interface{}{"batch", "resource"}))
+	})
+
+	It("should use the default pipeline when the config validator rejected the processors", func() {
+		Expect(os.WriteFile(testPipeline.paths.collectorProcessorsMountPath, []byte("processors:\n  - name: filter/a\n    config: {}\n"), 0644)).To(Succeed())
+		testPipeline.tomlparserCollectorProcessors()
+		testPipeline.env.Setenv(invalidCollectorProcessorsEnvVar, "true", false)
+
+		testPipeline.setCollectorProcessorsInCollectorConfig()
+		Expect(readPipeline()).To(Equal([]interface{}{"batch", "resource"}))
+	})
+})
@@ -0,0 +1,317 @@
+package configmapsettings
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/prometheus-collector/shared"
+	"gopkg.in/yaml.v2"
+)
+
+const customDefaultTargetsSection = "custom-default-targets"
+
+// customDefaultTargetNameRegex restricts names to what can be used as a key in the other settings sections
+var customDefaultTargetNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9-]*$`)
+
+// customDefaultTargetRoles maps the supported kubernetes_sd_configs roles to the meta labels used to select the port
+// by name and to scope the targets to the node on the daemonset. Roles without a node name label can only be
+// scraped from the replicaset.
+var customDefaultTargetRoles = map[string]struct {
+	portNameLabel string
+	nodeNameLabel string
+}{
+	"pod":           {portNameLabel: "__meta_kubernetes_pod_container_port_name", nodeNameLabel: "__meta_kubernetes_pod_node_name"},
+	"service":       {portNameLabel: "__meta_kubernetes_service_port_name"},
+	"endpoints":     {portNameLabel: "__meta_kubernetes_endpoint_port_name", nodeNameLabel: "__meta_kubernetes_endpoint_node_name"},
+	"endpointslice": {portNameLabel: "__meta_kubernetes_endpointslice_port_name", nodeNameLabel: "__meta_kubernetes_endpointslice_endpoint_node_name"},
+	"node":          {nodeNameLabel: "__meta_kubernetes_node_name"},
+}
+
+// customDefaultTarget is a default target declared in the custom-default-targets section of the settings configmap
+// with keys of the form <name>.<field>, for example:
+//
+//	my-app.role = "pod"
+//	my-app.namespaces = "apps,tools"
+//	my-app.label_selector = "app.kubernetes.io/name=my-app"
+//	my-app.port_name = "metrics"
+type customDefaultTarget struct {
+	name                         string
+	role                         string
+	namespaces                   []string
+	labelSelector                string
+	portName                     string
+	path                         string
+	scheme                       string
+	controllerType               string
+	enabled                      bool
+	minimalIngestionProfileRegex string
+}
+
+type customScrapeConfigFile struct {
+	ScrapeConfigs []customScrapeConfig `yaml:"scrape_configs"`
+}
+
+type customScrapeConfig struct {
+	JobName               string                     `yaml:"job_name"`
+	Scheme                string                     `yaml:"scheme"`
+	MetricsPath           string                     `yaml:"metrics_path"`
+	ScrapeInterval        string                     `yaml:"scrape_interval"`
+	LabelLimit            int                        `yaml:"label_limit"`
+	LabelNameLengthLimit  int                        `yaml:"label_name_length_limit"`
+	LabelValueLengthLimit int                        `yaml:"label_value_length_limit"`
+	KubernetesSDConfigs   []customKubernetesSDConfig `yaml:"kubernetes_sd_configs"`
+	RelabelConfigs        []customRelabelConfig      `yaml:"relabel_configs,omitempty"`
+}
+
+type customKubernetesSDConfig struct {
+	Role       string               `yaml:"role"`
+	Namespaces *customNamespaces    `yaml:"namespaces,omitempty"`
+	Selectors  []customRoleSelector `yaml:"selectors,omitempty"`
+}
+
+type customNamespaces struct {
+	Names []string `yaml:"names"`
+}
+
+type customRoleSelector struct {
+	Role  string `yaml:"role"`
+	Label string `yaml:"label,omitempty"`
+	Field string `yaml:"field,omitempty"`
+}
+
+type customRelabelConfig struct {
+	SourceLabels []string `yaml:"source_labels"`
+	Action       string   `yaml:"action"`
+	Regex        string   `yaml:"regex"`
+}
+
+// parseCustomDefaultTargets reads the custom-default-targets section and adds the declared targets to defaultTargets,
+// so they are configured through the other settings sections and merged like the built-in targets. The scrape config
+// files of the targets are generated in the default-prom-configs directory. Invalid targets are logged and skipped.
+func (p *configPipeline) parseCustomDefaultTargets(metricsConfigBySection map[string]map[string]string) {
+	settings, ok := metricsConfigBySection[customDefaultTargetsSection]
+	if !ok || len(settings) == 0 {
+		return
+	}
+	shared.EchoSectionDivider("Start Processing - parseCustomDefaultTargets")
+
+	customTargets, err := parseCustomDefaultTargetSettings(settings)
+	if err != nil {
+		shared.EchoError(fmt.Sprintf("Error parsing the %s section: %v. No custom default targets will be used", customDefaultTargetsSection, err))
+		return
+	}
+
+	targets := slices.Clone(p.defaultTargets)
+	for _, customTarget := range customTargets {
+		target, err := customTarget.defaultTarget()
+		if err == nil {
+			err = validateDefaultTarget(target, targets)
+		}
+		if err == nil {
+			err = customTarget.writeScrapeConfigFiles(p)
+		}
+		if err != nil {
+			shared.EchoError(fmt.Sprintf("Skipping custom default target %q: %v", customTarget.name, err))
+			continue
+		}
+		log.Printf("Adding custom default target %s scraped from the %s\n", customTarget.name, customTarget.controllerType)
+		targets = append(targets, target)
+	}
+	p.defaultTargets = targets
+
+	shared.EchoSectionDivider("End Processing - parseCustomDefaultTargets")
+}
+
+// parseCustomDefaultTargetSettings groups the <name>.<field> keys by name. The targets are returned sorted by name.
+func parseCustomDefaultTargetSettings(settings map[string]string) ([]*customDefaultTarget, error) {
+	targetsByName := make(map[string]*customDefaultTarget)
+	for key, value := range settings {
+		name, field, found := strings.Cut(key, ".")
+		if !found {
+			return nil, fmt.Errorf("key %q is not of the form <target name>.<field>", key)
+		}
+		target, exists := targetsByName[name]
+		if !exists {
+			target = &customDefaultTarget{
+				name:           name,
+				role:           "pod",
+				path:           "/metrics",
+				scheme:         "http",
+				controllerType: replicasetControllerType,
+				enabled:        true,
+			}
+			targetsByName[name] = target
+		}
+
+		value = strings.TrimSpace(value)
+		switch field {
+		case "role":
+			target.role = strings.ToLower(value)
+		case "namespaces":
+			target.namespaces = nil
+			for _, namespace := range strings.Split(value, ",") {
+				if namespace = strings.TrimSpace(namespace); namespace != "" {
+					target.namespaces = append(target.namespaces, namespace)
+				}
+			}
+		case "label_selector":
+			target.labelSelector = value
+		case "port_name":
+			target.portName = value
+		case "path":
+			target.path = value
+		case "scheme":
+			target.scheme = strings.ToLower(value)
+		case "controller_type":
+			target.controllerType = strings.ToLower(value)
+		case "enabled":
+			target.enabled = strings.ToLower(value) == "true"
+		case "minimal_ingestion_profile_regex":
+			target.minimalIngestionProfileRegex = value
+		default:
+			return nil, fmt.Errorf("unknown field %q in key %q", field, key)
+		}
+	}
+
+	targets := make([]*customDefaultTarget, 0, len(targetsByName))
+	for _, name := range slices.Sorted(maps.Keys(targetsByName)) {
+		targets = append(targets, targetsByName[name])
+	}
+	return targets, nil
+}
+
+func (c *customDefaultTarget) validate() error {
+	if !customDefaultTargetNameRegex.MatchString(c.name) {
+		return fmt.Errorf("the name must start with a letter and contain only letters, digits and hyphens")
+	}
+	role, ok := customDefaultTargetRoles[c.role]
+	if !ok {
+		return fmt.Errorf("role %q is not one of pod, service, endpoints, endpointslice or node", c.role)
+	}
+	if c.portName != "" && role.portNameLabel == "" {
+		return fmt.Errorf("port_name is not supported for role %s", c.role)
+	}
+	if c.scheme != "http" && c.scheme != "https" {
+		return fmt.Errorf("scheme %q is not http or https", c.scheme)
+	}
+	if !strings.HasPrefix(c.path, "/") {
+		return fmt.Errorf("path %q does not start with /", c.path)
+	}
+	switch c.controllerType {
+	case replicasetControllerType:
+	case daemonsetControllerType:
+		if role.nodeNameLabel == "" {
+			return fmt.Errorf("role %s cannot be scraped from the daemonset", c.role)
+		}
+	default:
+		return fmt.Errorf("controller_type %q is not replicaset or daemonset", c.controllerType)
+	}
+	if c.minimalIngestionProfileRegex != "" && !isValidRegex(c.minimalIngestionProfileRegex) {
+		return fmt.Errorf("minimal_ingestion_profile_regex %q is not a valid regex", c.minimalIngestionProfileRegex)
+	}
+	return nil
+}
+
+// defaultTarget returns the registry entry of the custom target. A daemonset target is scraped from the replicaset
+// in simple mode, the same as the built-in daemonset targets.
+func (c *customDefaultTarget) defaultTarget() (DefaultTarget, error) {
+	if err := c.validate(); err != nil {
+		return DefaultTarget{}, err
+	}
+	target := DefaultTarget{
+		Name:                         c.name,
+		EnabledByDefault:             c.enabled,
+		MinimalIngestionProfileRegex: c.minimalIngestionProfileRegex,
+	}
+	if c.controllerType == daemonsetControllerType {
+		target.Configs = []DefaultTargetConfig{
+			{File: c.scrapeConfigFile(true), ControllerType: daemonsetControllerType, Mode: "advanced", Placeholders: []string{"NODE_NAME"}},
+			{File: c.scrapeConfigFile(false), ControllerType: replicasetControllerType, Mode: "simple"},
+		}
+	} else {
+		target.Configs = []DefaultTargetConfig{
+			{File: c.scrapeConfigFile(false), ControllerType: replicasetControllerType},
+		}
+	}
+	target.setDefaultEnvVars()
+	return target, nil
+}
+
+func (c *customDefaultTarget) scrapeConfigFile(nodeScoped bool) string {
+	if nodeScoped {
+		return fmt.Sprintf("customDefaultTarget-%s-Ds.yml", c.name)
+	}
+	return fmt.Sprintf("customDefaultTarget-%s.yml", c.name)
+}
+
+// writeScrapeConfigFiles writes the scrape config files of the target to the default-prom-configs directory, with
+// the same placeholders as the built-in files.
+func (c *customDefaultTarget) writeScrapeConfigFiles(p *configPipeline) error {
+	nodeScopedValues := []bool{false}
+	if c.controllerType == daemonsetControllerType {
+		nodeScopedValues = append(nodeScopedValues, true)
+	}
+	for _, nodeScoped := range nodeScopedValues {
+		contents, err := yaml.Marshal(customScrapeConfigFile{ScrapeConfigs: []customScrapeConfig{c.scrapeConfig(nodeScoped)}})
+		if err != nil {
+			return fmt.Errorf("marshalling scrape config: %w", err)
+		}
+		if err := p.fs.WriteFile(filepath.Join(p.paths.defaultPromConfigsDir, c.scrapeConfigFile(nodeScoped)), contents, fs.FileMode(0644)); err != nil {
+			return fmt.Errorf("writing scrape config: %w", err)
+		}
+	}
+	return nil
+}
+
+// scrapeConfig returns the scrape config of the target. Node scoped configs only discover the targets on the node
+// the daemonset pod runs on.
+func (c *customDefaultTarget) scrapeConfig(nodeScoped bool) customScrapeConfig {
+	role := customDefaultTargetRoles[c.role]
+	sdConfig := customKubernetesSDConfig{Role: c.role}
+	if len(c.namespaces) > 0 && c.role != "node" {
+		sdConfig.Namespaces = &customNamespaces{Names: c.namespaces}
+	}
+	selector := customRoleSelector{Role: c.role, Label: c.labelSelector}
+	switch {
+	case nodeScoped && c.role == "pod":
+		selector.Field = "spec.nodeName=$$NODE_NAME$$"
+	case nodeScoped && c.role == "node":
+		selector.Field = "metadata.name=$$NODE_NAME$$"
+	}
+	if selector.Label != "" || selector.Field != "" {
+		sdConfig.Selectors = []customRoleSelector{selector}
+	}
+
+	scrapeConfig := customScrapeConfig{
+		JobName:               c.name,
+		Scheme:                c.scheme,
+		MetricsPath:           c.path,
+		ScrapeInterval:        "$$SCRAPE_INTERVAL$$",
+		LabelLimit:            63,
+		LabelNameLengthLimit:  511,
+		LabelValueLengthLimit: 1023,
+		KubernetesSDConfigs:   []customKubernetesSDConfig{sdConfig},
+	}
+	if c.portName != "" {
+		scrapeConfig.RelabelConfigs = append(scrapeConfig.RelabelConfigs, customRelabelConfig{
+			SourceLabels: []string{role.portNameLabel},
+			Action:       "keep",
+			Regex:        regexp.QuoteMeta(c.portName),
+		})
+	}
+	// Endpoints have no field selector for the node, so they are filtered after discovery
+	if nodeScoped && selector.Field == "" {
+		scrapeConfig.RelabelConfigs = append(scrapeConfig.RelabelConfigs, customRelabelConfig{
+			SourceLabels: []string{role.nodeNameLabel},
+			Action:       "keep",
+			Regex:        "$$NODE_NAME$$",
+		})
+	}
+	return scrapeConfig
+}
@@ -0,0 +1,137 @@
+package configmapsettings
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CustomDefaultTargets", func() {
+	findTarget := func(name string) *DefaultTarget {
+		for i := range testPipeline.defaultTargets {
+			if testPipeline.defaultTargets[i].Name == name {
+				return &testPipeline.defaultTargets[i]
+			}
+		}
+		return nil
+	}
+
+	BeforeEach(func() {
+		testPipeline.paths.defaultPromConfigsDir = GinkgoT().TempDir()
+	})
+
+	AfterEach(func() {
+		cleanupEnvVars()
+	})
+
+	Context("when the section declares targets", func() {
+		BeforeEach(func() {
+			testPipeline.parseCustomDefaultTargets(map[string]map[string]string{
+				"custom-default-targets": {
+					"my-app.namespaces":                      "apps, tools",
+					"my-app.label_selector":                  "app=my-app",
+					"my-app.port_name":                       "metrics",
+					"my-app.minimal_ingestion_profile_regex": "my_app_.*",
+					"node-agent.role":                        "endpoints",
+					"node-agent.controller_type":             "daemonset",
+					"node-agent.enabled":                     "false",
+					"kubelet.role":                           "node",
+					"bad-ds.role":                            "service",
+					"bad-ds.controller_type":                 "daemonset",
+				},
+			})
+		})
+
+		It("should add the valid targets to the default targets", func() {
+			Expect(testPipeline.defaultTargets).To(HaveLen(len(builtInDefaultTargets) + 2))
+
+			target := findTarget("my-app")
+			Expect(target).NotTo(BeNil())
+			Expect(target.EnabledByDefault).To(BeTrue())
+			Expect(target.EnabledEnvVar).To(Equal("AZMON_PROMETHEUS_MYAPP_SCRAPING_ENABLED"))
+			Expect(target.MinimalIngestionProfileRegex).To(Equal("my_app_.*"))
+			Expect(target.configFor(defaultTargetAgent{controllerType: replicasetControllerType})).NotTo(BeNil())
+			Expect(target.configFor(defaultTargetAgent{controllerType: daemonsetControllerType, advancedMode: true})).To(BeNil())
+
+			target = findTarget("node-agent")
+			Expect(target).NotTo(BeNil())
+			Expect(target.EnabledByDefault).To(BeFalse())
+			Expect(target.configFor(defaultTargetAgent{controllerType: daemonsetControllerType, advancedMode: true}).File).To(Equal("customDefaultTarget-node-agent-Ds.yml"))
+			Expect(target.configFor(defaultTargetAgent{controllerType: replicasetControllerType}).File).To(Equal("customDefaultTarget-node-agent.yml"))
+			Expect(target.configFor(defaultTargetAgent{controllerType: replicasetControllerType, advancedMode: true})).To(BeNil())
+
+			Expect(findTarget("bad-ds")).To(BeNil())
+		})
+
+		It("should generate the scrape config files", func() {
+			contents, err := os.ReadFile(filepath.Join(testPipeline.paths.defaultPromConfigsDir, "customDefaultTarget-my-app.yml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(`scrape_configs:
+- job_name: my-app
+  scheme: http
+  metrics_path: /metrics
+  scrape_interval: $$SCRAPE_INTERVAL$$
+  label_limit: 63
+  label_name_length_limit: 511
+  label_value_length_limit: 1023
+  kubernetes_sd_configs:
+  - role: pod
+    namespaces:
+      names:
+      - apps
+      - tools
+    selectors:
+    - role: pod
+      label: app=my-app
+  relabel_configs:
+  - source_labels:
+    - __meta_kubernetes_pod_container_port_name
+    action: keep
+    regex: metrics
+`))
+
+			contents, err = os.ReadFile(filepath.Join(testPipeline.paths.defaultPromConfigsDir, "customDefaultTarget-node-agent-Ds.yml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(ContainSubstring(`  relabel_configs:
+  - source_labels:
+    - __meta_kubernetes_endpoint_node_name
+    action: keep
+    regex: $$NODE_NAME$$
+`))
+		})
+
+		It("should configure the targets through the other sections", func() {
+			metricsConfigBySection := map[string]map[string]string{
+				"default-targets-scrape-enabled":           {"node-agent": "true"},
+				"default-targets-scrape-interval-settings": {"my-app": "45s"},
+			}
+			settings, err := (&FilesystemConfigLoader{pipeline: testPipeline}).ParseConfigMapForDefaultScrapeSettings(metricsConfigBySection, "v2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(settings).To(HaveKeyWithValue("my-app", "true"))
+			Expect(settings).To(HaveKeyWithValue("node-agent", "true"))
+
+			keepListRegexes := testPipeline.populateRegexValuesWithMinimalIngestionProfile(RegexValues{
+				keepLists:               map[string]string{"my-app": "foo_.*"},
+				minimalingestionprofile: "true",
+			})
+			Expect(keepListRegexes).To(HaveKeyWithValue("MYAPP_METRICS_KEEP_LIST_REGEX", "foo_.*|my_app_.*"))
+
+			setEnvVars(map[string]string{"AZMON_AGENT_CFG_SCHEMA_VERSION": "v2"})
+			Expect(testPipeline.processConfigMap(metricsConfigBySection)).To(HaveKeyWithValue("MYAPP_SCRAPE_INTERVAL", "45s"))
+		})
+	})
+
+	Context("when a key is not of the form <name>.<field>", func() {
+		It("should not add any targets", func() {
+			testPipeline.parseCustomDefaultTargets(map[string]map[string]string{
+				"custom-default-targets": {
+					"my-app.role": "pod",
+					"other":       "true",
+				},
+			})
+			Expect(testPipeline.defaultTargets).To(HaveLen(len(builtInDefaultTargets)))
+		})
+	})
+})
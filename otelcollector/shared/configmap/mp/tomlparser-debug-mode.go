@@ -19,40 +19,40 @@ const (
 // ConfigureDebugModeSettings reads debug mode settings from the parsed config map,
 // sets default values if necessary, writes environment variables to a file,
 // and modifies a YAML configuration file based on debug mode settings.
-func ConfigureDebugModeSettings(metricsConfigBySection map[string]map[string]string) error {
+func (p *configPipeline) ConfigureDebugModeSettings(metricsConfigBySection map[string]map[string]string) error {
 	if metricsConfigBySection == nil {
 		return fmt.Errorf("configmap section not mounted, using defaults")
 	}
 
-	enabled := populateSettingValuesFromConfigMap(metricsConfigBySection)
+	enabled := p.populateSettingValuesFromConfigMap(metricsConfigBySection)
 
-	configSchemaVersion := os.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION")
+	configSchemaVersion := p.env.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION")
 	if configSchemaVersion != "" && strings.TrimSpace(configSchemaVersion) == "v1" {
-		if _, err := os.Stat(configMapDebugMountPath); os.IsNotExist(err) {
+		if _, err := p.fs.Stat(p.paths.configMapDebugMountPath); os.IsNotExist(err) {
 			log.Printf("Unsupported/missing config schema version - '%s', using defaults, please use supported schema version\n", configSchemaVersion)
 		}
 	}
 
-	file, err := os.Create(debugModeEnvVarPath)
+	file, err := p.fs.Create(p.paths.debugModeEnvVarPath)
 	if err != nil {
 		return fmt.Errorf("Exception while opening file for writing prometheus-collector config environment variables: %v\n", err)
 	}
 	defer file.Close()
 
-	//if os.Getenv("OS_TYPE") != "" && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" {
+	//if env.Getenv("OS_TYPE") != "" && strings.ToLower(env.Getenv("OS_TYPE")) == "linux" {
 	//file.WriteString(fmt.Sprintf("export DEBUG_MODE_ENABLED=%v\n", defaultEnabled))
 	//} else {
-	file.WriteString(fmt.Sprintf("DEBUG_MODE_ENABLED=%v\n", enabled))
+	fmt.Fprintf(file, "DEBUG_MODE_ENABLED=%v\n", enabled)
 
 	log.Printf("Setting debug mode environment variable: %v\n", enabled)
 	//}
 
 	if enabled {
-		controllerType := os.Getenv("CONTROLLER_TYPE")
+		controllerType := p.env.Getenv("CONTROLLER_TYPE")
 		if controllerType != "" && controllerType == "ReplicaSet" {
 			log.Println("Setting prometheus in the exporter metrics for service pipeline since debug mode is enabled ...")
 			var config shared.OtelConfig
-			content, err := os.ReadFile(replicaSetCollectorConfig)
+			content, err := p.fs.ReadFile(p.paths.replicaSetCollectorConfig)
 			if err != nil {
 				return fmt.Errorf("Exception while setting prometheus in the exporter metrics for service pipeline when debug mode is enabled - %v\n", err)
 			}
@@ -63,7 +63,7 @@ func ConfigureDebugModeSettings(metricsConfigBySection map[string]map[string]str
 			}
 
 			config.Service.Pipelines.Metrics.Exporters = []interface{}{"otlp_grpc", "prometheus"}
-			if os.Getenv("CCP_METRICS_ENABLED") != "true" {
+			if p.env.Getenv("CCP_METRICS_ENABLED") != "true" {
 				config.Service.Pipelines.MetricsTelemetry.Receivers = []interface{}{"prometheus"}
 				config.Service.Pipelines.MetricsTelemetry.Exporters = []interface{}{"prometheus/telemetry"}
 				config.Service.Pipelines.MetricsTelemetry.Processors = []interface{}{"filter/telemetry"}
@@ -73,7 +73,7 @@ func ConfigureDebugModeSettings(metricsConfigBySection map[string]map[string]str
 				return fmt.Errorf("Exception while setting prometheus in the exporter metrics for service pipeline when debug mode is enabled - %v\n", err)
 			}
 
-			err = os.WriteFile(replicaSetCollectorConfig, []byte(cfgYamlWithDebugModeSettings), fs.FileMode(0644))
+			err = p.fs.WriteFile(p.paths.replicaSetCollectorConfig, []byte(cfgYamlWithDebugModeSettings), fs.FileMode(0644))
 			if err != nil {
 				return fmt.Errorf("Exception while setting prometheus in the exporter metrics for service pipeline when debug mode is enabled - %v\n", err)
 			}
@@ -85,9 +85,9 @@ func ConfigureDebugModeSettings(metricsConfigBySection map[string]map[string]str
 	return nil
 }
 
-func populateSettingValuesFromConfigMap(metricsConfigBySection map[string]map[string]string) bool {
+func (p *configPipeline) populateSettingValuesFromConfigMap(metricsConfigBySection map[string]map[string]string) bool {
 	debugSettings := "false"
-	configSchemaVersion := os.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION")
+	configSchemaVersion := p.env.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION")
 
 	if configSchemaVersion == "" {
 		log.Println("AZMON_AGENT_CFG_SCHEMA_VERSION environment variable is not set. Using default value: false")
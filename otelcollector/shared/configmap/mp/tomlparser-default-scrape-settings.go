@@ -3,7 +3,6 @@ package configmapsettings
 import (
 	"fmt"
 	"log"
-	"os"
 	"regexp"
 	"strings"
 )
@@ -11,57 +10,17 @@ import (
 func (fcl *FilesystemConfigLoader) SetDefaultScrapeSettings() (map[string]string, error) {
 	config := make(map[string]string)
 	// Set default values
-	config["kubelet"] = "true"
-	config["coredns"] = "false"
-	config["cadvisor"] = "true"
-	config["kubeproxy"] = "false"
-	config["apiserver"] = "false"
-	config["kubestate"] = "true"
-	config["nodeexporter"] = "true"
-	config["prometheuscollectorhealth"] = "false"
-	config["windowsexporter"] = "false"
-	config["windowskubeproxy"] = "false"
-	config["kappiebasic"] = "true"
-	config["networkobservabilityRetina"] = "true"
-	config["networkobservabilityHubble"] = "true"
-	config["networkobservabilityCilium"] = "true"
-	config["noDefaultsEnabled"] = "false"
-	config["acstor-capacity-provisioner"] = "true"
-	config["acstor-metrics-exporter"] = "true"
-	config["local-csi-driver"] = "true"
-	config["ztunnel"] = "false"
-	config["istio-cni"] = "false"
-	config["dcgmexporter"] = "true"
-	config["controlplane-istio"] = "false"
+	for _, target := range fcl.pipeline.defaultTargets {
+		if !target.enabledExternally {
+			config[target.Name] = fmt.Sprintf("%t", target.EnabledByDefault)
+		}
+	}
 
 	return config, nil
 }
 
 func (fcl *FilesystemConfigLoader) ParseConfigMapForDefaultScrapeSettings(metricsConfigBySection map[string]map[string]string, schemaVersion string) (map[string]string, error) {
-	config := make(map[string]string)
-	// Set default values
-	config["kubelet"] = "true"
-	config["coredns"] = "false"
-	config["cadvisor"] = "true"
-	config["kubeproxy"] = "false"
-	config["apiserver"] = "false"
-	config["kubestate"] = "true"
-	config["nodeexporter"] = "true"
-	config["prometheuscollectorhealth"] = "false"
-	config["windowsexporter"] = "false"
-	config["windowskubeproxy"] = "false"
-	config["kappiebasic"] = "true"
-	config["networkobservabilityRetina"] = "true"
-	config["networkobservabilityHubble"] = "true"
-	config["networkobservabilityCilium"] = "true"
-	config["noDefaultsEnabled"] = "false"
-	config["acstor-capacity-provisioner"] = "true"
-	config["acstor-metrics-exporter"] = "true"
-	config["local-csi-driver"] = "true"
-	config["ztunnel"] = "false"
-	config["istio-cni"] = "false"
-	config["dcgmexporter"] = "true"
-	config["controlplane-istio"] = "false"
+	config, _ := fcl.SetDefaultScrapeSettings()
 
 	configSectionName := "default-scrape-settings-enabled"
 	if schemaVersion == "v2" {
@@ -81,120 +40,26 @@ func (fcl *FilesystemConfigLoader) ParseConfigMapForDefaultScrapeSettings(metric
 }
 
 func (cp *ConfigProcessor) PopulateSettingValues(parsedConfig map[string]string) {
-	if val, ok := parsedConfig["kubelet"]; ok && val != "" {
-		cp.Kubelet = val
-		log.Printf("config::Using scrape settings for kubelet: %v\n", cp.Kubelet)
-	}
-
-	if val, ok := parsedConfig["coredns"]; ok && val != "" {
-		cp.Coredns = val
-		log.Printf("config::Using scrape settings for coredns: %v\n", cp.Coredns)
-	}
-
-	if val, ok := parsedConfig["cadvisor"]; ok && val != "" {
-		cp.Cadvisor = val
-		log.Printf("config::Using scrape settings for cadvisor: %v\n", cp.Cadvisor)
-	}
-
-	if val, ok := parsedConfig["kubeproxy"]; ok && val != "" {
-		cp.Kubeproxy = val
-		log.Printf("config::Using scrape settings for kubeproxy: %v\n", cp.Kubeproxy)
-	}
-
-	if val, ok := parsedConfig["apiserver"]; ok && val != "" {
-		cp.Apiserver = val
-		log.Printf("config::Using scrape settings for apiserver: %v\n", cp.Apiserver)
-	}
-
-	if val, ok := parsedConfig["kubestate"]; ok && val != "" {
-		cp.Kubestate = val
-		log.Printf("config::Using scrape settings for kubestate: %v\n", cp.Kubestate)
-	}
-
-	if val, ok := parsedConfig["nodeexporter"]; ok && val != "" {
-		cp.NodeExporter = val
-		log.Printf("config::Using scrape settings for nodeexporter: %v\n", cp.NodeExporter)
-	}
-
-	if val, ok := parsedConfig["prometheuscollectorhealth"]; ok && val != "" {
-		cp.PrometheusCollectorHealth = val
-		log.Printf("config::Using scrape settings for prometheuscollectorhealth: %v\n", cp.PrometheusCollectorHealth)
-	}
-
-	if val, ok := parsedConfig["windowsexporter"]; ok && val != "" {
-		cp.Windowsexporter = val
-		log.Printf("config::Using scrape settings for windowsexporter: %v\n", cp.Windowsexporter)
-	}
-
-	if val, ok := parsedConfig["windowskubeproxy"]; ok && val != "" {
-		cp.Windowskubeproxy = val
-		log.Printf("config::Using scrape settings for windowskubeproxy: %v\n", cp.Windowskubeproxy)
-	}
-
-	if val, ok := parsedConfig["kappiebasic"]; ok && val != "" {
-		cp.Kappiebasic = val
-		log.Printf("config::Using scrape settings for kappiebasic: %v\n", cp.Kappiebasic)
-	}
-
-	if val, ok := parsedConfig["networkobservabilityRetina"]; ok && val != "" {
-		cp.NetworkObservabilityRetina = val
-		log.Printf("config::Using scrape settings for networkobservabilityRetina: %v\n", cp.NetworkObservabilityRetina)
-	}
-
-	if val, ok := parsedConfig["networkobservabilityHubble"]; ok && val != "" {
-		cp.NetworkObservabilityHubble = val
-		log.Printf("config::Using scrape settings for networkobservabilityHubble: %v\n", cp.NetworkObservabilityHubble)
-	}
-
-	if val, ok := parsedConfig["networkobservabilityCilium"]; ok && val != "" {
-		cp.NetworkObservabilityCilium = val
-		log.Printf("config::Using scrape settings for networkobservabilityCilium: %v\n", cp.NetworkObservabilityCilium)
-	}
-
-	if val, ok := parsedConfig["acstor-capacity-provisioner"]; ok && val != "" {
-		cp.AcstorCapacityProvisioner = val
-		log.Printf("config:: Using scrape settings for acstor-capacity-provisioner: %v\n", cp.AcstorCapacityProvisioner)
-	}
-
-	if val, ok := parsedConfig["acstor-metrics-exporter"]; ok && val != "" {
-		cp.AcstorMetricsExporter = val
-		log.Printf("config:: Using scrape settings for acstor-metrics-exporter: %v\n", cp.AcstorMetricsExporter)
-	}
-
-	if val, ok := parsedConfig["local-csi-driver"]; ok && val != "" {
-		cp.LocalCSIDriver = val
-		log.Printf("config:: Using scrape settings for local-csi-driver: %v\n", cp.LocalCSIDriver)
-	}
-
-	if val, ok := parsedConfig["ztunnel"]; ok && val != "" {
-		cp.Ztunnel = val
-		fmt.Printf("config:: Using scrape settings for ztunnel: %v\n", cp.Ztunnel)
-	}
-
-	if val, ok := parsedConfig["istio-cni"]; ok && val != "" {
-		cp.IstioCni = val
-		fmt.Printf("config:: Using scrape settings for istio-cni: %v\n", cp.IstioCni)
-	}
-
-	if val, ok := parsedConfig["dcgmexporter"]; ok && val != "" {
-		cp.DcgmExporter = val
-		fmt.Printf("config:: Using scrape settings for dcgmexporter: %v\n", cp.DcgmExporter)
+	cp.DefaultTargetSettings = make(map[string]string)
+	for _, target := range cp.pipeline.defaultTargets {
+		if val, ok := parsedConfig[target.Name]; ok && val != "" {
+			cp.DefaultTargetSettings[target.Name] = val
+			log.Printf("config::Using scrape settings for %s: %v\n", target.Name, val)
+		}
 	}
 
-	if val, ok := parsedConfig["controlplane-istio"]; ok && val != "" {
-		cp.ControlplaneIstio = val
-		fmt.Printf("config:: Using scrape settings for controlplane-istio: %v\n", cp.ControlplaneIstio)
+	noDefaultsEnabled := true
+	for _, name := range []string{"kubelet", "cadvisor", "nodeexporter", "prometheuscollectorhealth", "kappiebasic"} {
+		if cp.DefaultTargetSettings[name] != "" {
+			noDefaultsEnabled = false
+		}
 	}
-
-	if os.Getenv("MODE") != "" && strings.ToLower(strings.TrimSpace(os.Getenv("MODE"))) == "advanced" {
-		controllerType := os.Getenv("CONTROLLER_TYPE")
-		if controllerType == "ReplicaSet" && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" &&
-			cp.Kubelet == "" && cp.Cadvisor == "" &&
-			cp.NodeExporter == "" && cp.PrometheusCollectorHealth == "" && cp.Kappiebasic == "" {
+	if cp.pipeline.env.Getenv("MODE") != "" && strings.ToLower(strings.TrimSpace(cp.pipeline.env.Getenv("MODE"))) == "advanced" {
+		controllerType := cp.pipeline.env.Getenv("CONTROLLER_TYPE")
+		if controllerType == "ReplicaSet" && strings.ToLower(cp.pipeline.env.Getenv("OS_TYPE")) == "linux" && noDefaultsEnabled {
 			cp.NoDefaultsEnabled = true
 		}
-	} else if cp.Kubelet == "" && cp.Cadvisor == "" &&
-		cp.NodeExporter == "" && cp.PrometheusCollectorHealth == "" && cp.Kappiebasic == "" {
+	} else if noDefaultsEnabled {
 		cp.NoDefaultsEnabled = true
 	}
 
@@ -204,40 +69,24 @@ func (cp *ConfigProcessor) PopulateSettingValues(parsedConfig map[string]string)
 }
 
 func (fcw *FileConfigWriter) WriteDefaultScrapeSettingsToFile(filename string, cp *ConfigProcessor) error {
-	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	file, err := fcw.pipeline.fs.Create(filename)
 	if err != nil {
 		return fmt.Errorf("Exception while opening file for writing prometheus-collector config environment variables: %s", err)
 	}
 	defer file.Close()
 
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_KUBELET_SCRAPING_ENABLED=%v\n", cp.Kubelet))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_COREDNS_SCRAPING_ENABLED=%v\n", cp.Coredns))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_CADVISOR_SCRAPING_ENABLED=%v\n", cp.Cadvisor))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_KUBEPROXY_SCRAPING_ENABLED=%v\n", cp.Kubeproxy))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_APISERVER_SCRAPING_ENABLED=%v\n", cp.Apiserver))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_KUBESTATE_SCRAPING_ENABLED=%v\n", cp.Kubestate))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_NODEEXPORTER_SCRAPING_ENABLED=%v\n", cp.NodeExporter))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_COLLECTOR_HEALTH_SCRAPING_ENABLED=%v\n", cp.PrometheusCollectorHealth))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_WINDOWSEXPORTER_SCRAPING_ENABLED=%v\n", cp.Windowsexporter))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_WINDOWSKUBEPROXY_SCRAPING_ENABLED=%v\n", cp.Windowskubeproxy))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_KAPPIEBASIC_SCRAPING_ENABLED=%v\n", cp.Kappiebasic))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_NETWORKOBSERVABILITYRETINA_SCRAPING_ENABLED=%v\n", cp.NetworkObservabilityRetina))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_NETWORKOBSERVABILITYHUBBLE_SCRAPING_ENABLED=%v\n", cp.NetworkObservabilityHubble))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_NETWORKOBSERVABILITYCILIUM_SCRAPING_ENABLED=%v\n", cp.NetworkObservabilityCilium))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_NO_DEFAULT_SCRAPING_ENABLED=%v\n", cp.NoDefaultsEnabled))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_ACSTORCAPACITYPROVISIONER_SCRAPING_ENABLED=%v\n", cp.AcstorCapacityProvisioner))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_ACSTORMETRICSEXPORTER_SCRAPING_ENABLED=%v\n", cp.AcstorMetricsExporter))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_LOCALCSIDRIVER_SCRAPING_ENABLED=%v\n", cp.LocalCSIDriver))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_ZTUNNEL_SCRAPING_ENABLED=%v\n", cp.Ztunnel))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_ISTIOCNI_SCRAPING_ENABLED=%v\n", cp.IstioCni))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_DCGMEXPORTER_SCRAPING_ENABLED=%v\n", cp.DcgmExporter))
-	file.WriteString(fmt.Sprintf("AZMON_PROMETHEUS_CONTROLPLANE_ISTIO_ENABLED=%v\n", cp.ControlplaneIstio))
+	for _, target := range fcw.pipeline.defaultTargets {
+		if !target.enabledExternally {
+			fmt.Fprintf(file, "%s=%v\n", target.EnabledEnvVar, cp.DefaultTargetSettings[target.Name])
+		}
+	}
+	fmt.Fprintf(file, "AZMON_PROMETHEUS_NO_DEFAULT_SCRAPING_ENABLED=%v\n", cp.NoDefaultsEnabled)
 
 	return nil
 }
 
 func (c *Configurator) ConfigureDefaultScrapeSettings(metricsConfigBySection map[string]map[string]string) {
-	configSchemaVersion := os.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION")
+	configSchemaVersion := c.pipeline.env.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION")
 
 	log.Printf("Start prometheus-collector-settings Processing\n")
 
@@ -259,11 +108,11 @@ func (c *Configurator) ConfigureDefaultScrapeSettings(metricsConfigBySection map
 	c.ConfigParser.PopulateSettingValues(defaultSettings)
 
 	// Set cluster alias
-	if mac := os.Getenv("MAC"); mac != "" && strings.TrimSpace(mac) == "true" {
-		clusterArray := strings.Split(strings.TrimSpace(os.Getenv("CLUSTER")), "/")
+	if mac := c.pipeline.env.Getenv("MAC"); mac != "" && strings.TrimSpace(mac) == "true" {
+		clusterArray := strings.Split(strings.TrimSpace(c.pipeline.env.Getenv("CLUSTER")), "/")
 		c.ConfigParser.ClusterAlias = clusterArray[len(clusterArray)-1]
 	} else {
-		c.ConfigParser.ClusterAlias = os.Getenv("CLUSTER")
+		c.ConfigParser.ClusterAlias = c.pipeline.env.Getenv("CLUSTER")
 	}
 
 	if c.ConfigParser.ClusterAlias != "" && len(c.ConfigParser.ClusterAlias) > 0 {
@@ -282,19 +131,20 @@ func (c *Configurator) ConfigureDefaultScrapeSettings(metricsConfigBySection map
 	log.Printf("End prometheus-collector-settings Processing\n")
 }
 
-func tomlparserDefaultScrapeSettings(metricsConfigBySection map[string]map[string]string) {
+func (p *configPipeline) tomlparserDefaultScrapeSettings(metricsConfigBySection map[string]map[string]string) {
 
-	configSchemaVersion := os.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION")
-	configLoaderPath := defaultSettingsMountPath
+	configSchemaVersion := p.env.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION")
+	configLoaderPath := p.paths.defaultSettingsMountPath
 	if configSchemaVersion != "" && strings.TrimSpace(configSchemaVersion) == "v2" {
-		configLoaderPath = defaultSettingsMountPathv2
+		configLoaderPath = p.paths.defaultSettingsMountPathv2
 	}
 
 	configurator := &Configurator{
-		ConfigLoader:   &FilesystemConfigLoader{ConfigMapMountPath: configLoaderPath},
-		ConfigWriter:   &FileConfigWriter{},
-		ConfigFilePath: defaultSettingsEnvVarPath,
-		ConfigParser:   &ConfigProcessor{},
+		ConfigLoader:   &FilesystemConfigLoader{ConfigMapMountPath: configLoaderPath, pipeline: p},
+		ConfigWriter:   &FileConfigWriter{pipeline: p},
+		ConfigFilePath: p.paths.defaultSettingsEnvVarPath,
+		ConfigParser:   &ConfigProcessor{pipeline: p},
+		pipeline:       p,
 	}
 
 	configurator.ConfigureDefaultScrapeSettings(metricsConfigBySection)
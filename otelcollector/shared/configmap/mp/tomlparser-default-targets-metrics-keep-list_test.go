@@ -0,0 +1,130 @@
+package configmapsettings
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DropLists", func() {
+	BeforeEach(func() {
+		setEnvVars(map[string]string{"AZMON_AGENT_CFG_SCHEMA_VERSION": "v2"})
+	})
+
+	AfterEach(func() {
+		cleanupEnvVars()
+	})
+
+	Context("when the drop list sections are set", func() {
+		It("should keep the drop lists of the default targets", func() {
+			regexValues, err := testPipeline.populateKeepList(map[string]map[string]string{
+				"default-targets-metrics-keep-list": {"kubelet": "kubelet_.*"},
+				"default-targets-metrics-drop-list": {"cadvisor": "container_tasks_state|container_memory_failures_total", "unknown": "foo"},
+				"default-targets-label-drop-list":   {"cadvisor": "id|image"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(regexValues.keepLists).To(HaveKeyWithValue("kubelet", "kubelet_.*"))
+			Expect(regexValues.metricsDropLists).To(Equal(map[string]string{"cadvisor": "container_tasks_state|container_memory_failures_total"}))
+			Expect(regexValues.labelDropLists).To(Equal(map[string]string{"cadvisor": "id|image"}))
+		})
+
+		It("should skip invalid drop list regexes and keep everything else", func() {
+			regexValues, err := testPipeline.populateKeepList(map[string]map[string]string{
+				"default-targets-metrics-keep-list": {"kubelet": "kubelet_.*"},
+				"default-targets-metrics-drop-list": {"cadvisor": "container_(tasks", "kubelet": "kubelet_runtime_.*"},
+				"default-targets-label-drop-list":   {"cadvisor": "id|(image"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(regexValues.keepLists).To(HaveKeyWithValue("kubelet", "kubelet_.*"))
+			Expect(regexValues.metricsDropLists).To(Equal(map[string]string{"kubelet": "kubelet_runtime_.*"}))
+			Expect(regexValues.labelDropLists).To(BeEmpty())
+		})
+
+		It("should skip label drop lists dropping the labels identifying a series", func() {
+			regexValues, err := testPipeline.populateKeepList(map[string]map[string]string{
+				"default-targets-label-drop-list": {"kubelet": "id|inst.*", "cadvisor": "id"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(regexValues.labelDropLists).To(Equal(map[string]string{"cadvisor": "id"}))
+		})
+	})
+
+	Context("when appending the drop lists to a scrape config", func() {
+		It("should add the drop rules after the existing metric relabel configs", func() {
+			configFile := createTempFile("cadvisor", `scrape_configs:
+- job_name: cadvisor
+  metric_relabel_configs:
+  - source_labels:
+    - __name__
+    action: keep
+    regex: container_.*
+`)
+			Expect(testPipeline.AppendDropListMetricRelabelConfigs(configFile, "container_tasks_state", "id|image")).To(Succeed())
+			contents, err := os.ReadFile(configFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(`scrape_configs:
+- job_name: cadvisor
+  metric_relabel_configs:
+  - action: keep
+    regex: container_.*
+    source_labels:
+    - __name__
+  - action: drop
+    regex: container_tasks_state
+    source_labels:
+    - __name__
+  - action: labeldrop
+    regex: id|image
+`))
+		})
+
+		It("should leave the scrape config unchanged without drop lists", func() {
+			configFile := createTempFile("kubelet", "scrape_configs:\n- job_name: kubelet\n")
+			Expect(testPipeline.AppendDropListMetricRelabelConfigs(configFile, "", "")).To(Succeed())
+			contents, err := os.ReadFile(configFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("scrape_configs:\n- job_name: kubelet\n"))
+		})
+	})
+
+	Context("when merging the default targets", func() {
+		var defaultTargets []DefaultTarget
+
+		BeforeEach(func() {
+			defaultTargets = testPipeline.defaultTargets
+			testPipeline.paths.defaultScrapeConfigsDir = GinkgoT().TempDir()
+		})
+
+		AfterEach(func() {
+			testPipeline.defaultTargets = defaultTargets
+			testPipeline.metricsDropListHash = nil
+			testPipeline.labelDropListHash = nil
+		})
+
+		It("should apply the drop lists to the configs without a keep list", func() {
+			Expect(os.WriteFile(filepath.Join(testPipeline.paths.defaultScrapeConfigsDir, "kubelet.yml"), []byte("scrape_configs:\n- job_name: kubelet\n"), 0644)).To(Succeed())
+			setEnvVars(map[string]string{"CONTROLLER_TYPE": "ReplicaSet", "AZMON_PROMETHEUS_KUBELET_SCRAPING_ENABLED": "true"})
+			testPipeline.defaultTargets = []DefaultTarget{{
+				Name:           "kubelet",
+				EnabledEnvVar:  "AZMON_PROMETHEUS_KUBELET_SCRAPING_ENABLED",
+				KeepListEnvVar: "KUBELET_METRICS_KEEP_LIST_REGEX",
+				Configs:        []DefaultTargetConfig{{File: "kubelet.yml", ControllerType: replicasetControllerType, noKeepList: true}},
+			}}
+			testPipeline.metricsDropListHash = map[string]string{"kubelet": "kubelet_runtime_operations_total"}
+			testPipeline.labelDropListHash = map[string]string{"kubelet": "id"}
+
+			testPipeline.populateDefaultPrometheusConfig(false)
+			Expect(testPipeline.mergedDefaultConfigs["scrape_configs"]).To(Equal([]interface{}{
+				map[interface{}]interface{}{
+					"job_name": "kubelet",
+					"metric_relabel_configs": []interface{}{
+						map[interface{}]interface{}{"action": "drop", "regex": "kubelet_runtime_operations_total", "source_labels": []interface{}{"__name__"}},
+						map[interface{}]interface{}{"action": "labeldrop", "regex": "id"},
+					},
+				},
+			}))
+		})
+	})
+})
@@ -0,0 +1,75 @@
+package configmapsettings
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/prometheus-collector/shared"
+	"gopkg.in/yaml.v2"
+)
+
+const externalLabelsSection = "external-labels"
+
+// parseExternalLabels returns the labels of the external-labels section of the settings configmap, for example:
+//
+//	environment = "prod"
+//	region = "westus2"
+//	cost_center = "1234"
+//
+// The labels are added to every series scraped by the agent, from the default targets, the custom scrape jobs and the
+// pod and service monitors. Invalid and reserved labels are logged and skipped, so a typo does not drop the others.
+func parseExternalLabels(settings map[string]string) map[string]string {
+	labels := make(map[string]string)
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		name, value := strings.TrimSpace(key), strings.TrimSpace(settings[key])
+		if err := shared.ValidateExternalLabel(name, value); err != nil {
+			shared.EchoError(fmt.Sprintf("Skipping %s setting %q: %v", externalLabelsSection, name, err))
+			continue
+		}
+		labels[name] = value
+	}
+	return labels
+}
+
+func (p *configPipeline) tomlparserExternalLabels(metricsConfigBySection map[string]map[string]string) {
+	shared.EchoSectionDivider("Start Processing - tomlparserExternalLabels")
+	labels := parseExternalLabels(metricsConfigBySection[externalLabelsSection])
+	if len(labels) > 0 {
+		log.Printf("Using external labels: %v\n", labels)
+	}
+	out, err := yaml.Marshal(labels)
+	if err != nil {
+		log.Printf("Error marshalling external labels: %v\n", err)
+		return
+	}
+	if err := p.fs.WriteFile(p.paths.externalLabelsEnvVarPath, out, fs.FileMode(0644)); err != nil {
+		log.Printf("Error writing to file: %v\n", err)
+		return
+	}
+	shared.EchoSectionDivider("End Processing - tomlparserExternalLabels")
+}
+
+func (p *configPipeline) loadExternalLabels() map[string]string {
+	labels, err := shared.ReadExternalLabels(p.paths.externalLabelsEnvVarPath)
+	if err != nil {
+		log.Printf("Exception in loadExternalLabels: %v. No external labels will be added\n", err)
+		return nil
+	}
+	return labels
+}
+
+// SetExternalLabelsInCollectorConfig adds the external labels to the replicaset collector config used with the target
+// allocator, which is not generated by the config validator.
+func (a *AgentConfigPipeline) SetExternalLabelsInCollectorConfig() {
+	a.pipeline.setExternalLabelsInCollectorConfig()
+}
+
+func (p *configPipeline) setExternalLabelsInCollectorConfig() {
+	if labels := p.loadExternalLabels(); len(labels) > 0 {
+		shared.ApplyExternalLabelsInCollectorConfig(p.paths.replicaSetCollectorConfig, labels)
+	}
+}
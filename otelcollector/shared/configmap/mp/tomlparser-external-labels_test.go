@@ -0,0 +1,62 @@
+package configmapsettings
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+)
+
+var _ = Describe("ExternalLabels", func() {
+	Context("when parsing the external-labels section", func() {
+		It("should keep the valid labels and skip the invalid and reserved ones", func() {
+			Expect(parseExternalLabels(map[string]string{
+				"environment": "prod",
+				" region ":    " westus2 ",
+				"cost-center": "1234",
+				"cluster":     "other",
+				"__name__":    "up",
+				"team":        "",
+				"cost_center": "1234",
+			})).To(Equal(map[string]string{"environment": "prod", "region": "westus2", "cost_center": "1234"}))
+		})
+	})
+
+	Context("when applying the labels", func() {
+		BeforeEach(func() {
+			dir := GinkgoT().TempDir()
+			testPipeline.paths.externalLabelsEnvVarPath = filepath.Join(dir, "external-labels")
+			testPipeline.paths.replicaSetCollectorConfig = filepath.Join(dir, "collector-config-replicaset.yml")
+			contents, err := os.ReadFile("./testdata/collector-config-replicaset.yml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(testPipeline.paths.replicaSetCollectorConfig, contents, 0644)).To(Succeed())
+		})
+
+		It("should add them to the replicaset collector config used with the target allocator", func() {
+			testPipeline.tomlparserExternalLabels(map[string]map[string]string{
+				"external-labels": {"environment": "prod"},
+			})
+			Expect(testPipeline.loadExternalLabels()).To(Equal(map[string]string{"environment": "prod"}))
+
+			testPipeline.setExternalLabelsInCollectorConfig()
+			contents, err := os.ReadFile(testPipeline.paths.replicaSetCollectorConfig)
+			Expect(err).NotTo(HaveOccurred())
+			var otelConfig map[string]interface{}
+			Expect(yaml.Unmarshal(contents, &otelConfig)).To(Succeed())
+			attributes := otelConfig["processors"].(map[interface{}]interface{})["resource"].(map[interface{}]interface{})["attributes"]
+			Expect(attributes).To(ContainElement(map[interface{}]interface{}{"key": "environment", "value": "prod", "action": "upsert"}))
+			Expect(otelConfig["receivers"]).To(HaveKey("prometheus"))
+		})
+
+		It("should leave the collector config unchanged without labels", func() {
+			testPipeline.tomlparserExternalLabels(map[string]map[string]string{})
+			before, err := os.ReadFile(testPipeline.paths.replicaSetCollectorConfig)
+			Expect(err).NotTo(HaveOccurred())
+
+			testPipeline.setExternalLabelsInCollectorConfig()
+			Expect(os.ReadFile(testPipeline.paths.replicaSetCollectorConfig)).To(Equal(before))
+		})
+	})
+})
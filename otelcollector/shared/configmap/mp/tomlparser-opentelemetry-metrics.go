@@ -3,24 +3,23 @@ package configmapsettings
 import (
 	"fmt"
 	"log"
-	"os"
 	"strconv"
 )
 
-func ConfigureOpentelemetryMetricsSettings(metricsConfigBySection map[string]map[string]string) error {
+func (p *configPipeline) ConfigureOpentelemetryMetricsSettings(metricsConfigBySection map[string]map[string]string) error {
 	if metricsConfigBySection == nil {
 		return fmt.Errorf("configmap section not mounted, using defaults")
 	}
 
 	enabled := populateOpentelemetryMetricsSettingValuesFromConfigMap(metricsConfigBySection)
 
-	file, err := os.Create(opentelemetryMetricsEnvVarPath)
+	file, err := p.fs.Create(p.paths.opentelemetryMetricsEnvVarPath)
 	if err != nil {
 		return fmt.Errorf("Exception while opening file for writing prometheus-collector config environment variables: %v\n", err)
 	}
 	defer file.Close()
 
-	file.WriteString(fmt.Sprintf("AZMON_FULL_OTLP_ENABLED=%v\n", enabled))
+	fmt.Fprintf(file, "AZMON_FULL_OTLP_ENABLED=%v\n", enabled)
 
 	log.Printf("Setting AZMON_FULL_OTLP_ENABLED environment variable: %v\n", enabled)
 
@@ -39,7 +38,7 @@ func populateOpentelemetryMetricsSettingValuesFromConfigMap(metricsConfigBySecti
 	if val, ok := innerMap["enabled"]; ok {
 		enabledBool, err := strconv.ParseBool(val)
 		if err != nil {
-			log.Printf("Invalid value for opentelemetry-metrics enabled: %s, defaulting to %b\n", enabled)
+			log.Printf("Invalid value for opentelemetry-metrics enabled: %s, defaulting to %t\n", val, enabled)
 			return enabled
 		}
 		enabled = enabledBool
@@ -2,25 +2,75 @@ package configmapsettings
 
 import (
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"maps"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus-collector/shared"
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 const (
 	LOGGING_PREFIX                    = "pod-annotation-based-scraping"
 	envVariableTemplateName           = "AZMON_PROMETHEUS_POD_ANNOTATION_NAMESPACES_REGEX"
 	envVariableAnnotationsEnabledName = "AZMON_PROMETHEUS_POD_ANNOTATION_SCRAPING_ENABLED"
+
+	podAnnotationNamespaceRegexKey   = "podannotationnamespaceregex"
+	podAnnotationLabelSelectorKey    = "podlabelselector"
+	podAnnotationIndexedPortCountKey = "indexedportcount"
+	// maxPodAnnotationIndexedPorts is the highest index of the prometheus.io/port-<index> annotations
+	maxPodAnnotationIndexedPorts = 10
 )
 
+// namespaceNameRegex matches the names of namespaces, the prefix of the keys of the namespace settings
+var namespaceNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// podAnnotationSettings are the settings of the pod-annotation-based-scraping section besides the namespace regex,
+// for example:
+//
+//	podlabelselector = "team=payments,tier!=test"
+//	indexedportcount = 2
+//	payments.scrape_interval = "15s"
+//	payments.scheme = "https"
+//	payments.tls_ca_file = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+//	payments.bearer_token_file = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+//
+// The keys prefixed with a namespace name override the scrape config of the pods of that namespace.
+type podAnnotationSettings struct {
+	// LabelSelector restricts the scraped pods to the ones matching the Kubernetes label selector
+	LabelSelector string `yaml:"label_selector,omitempty"`
+	// IndexedPortCount is the number of prometheus.io/port-<index> annotations scraped for each pod, with the
+	// prometheus.io/path-<index> annotations as their paths
+	IndexedPortCount int                                       `yaml:"indexed_port_count,omitempty"`
+	Namespaces       map[string]podAnnotationNamespaceSettings `yaml:"namespaces,omitempty"`
+}
+
+// podAnnotationNamespaceSettings override the scrape config of the pods of a namespace
+type podAnnotationNamespaceSettings struct {
+	ScrapeInterval        string `yaml:"scrape_interval,omitempty"`
+	Scheme                string `yaml:"scheme,omitempty"`
+	TLSCAFile             string `yaml:"tls_ca_file,omitempty"`
+	TLSServerName         string `yaml:"tls_server_name,omitempty"`
+	TLSInsecureSkipVerify bool   `yaml:"tls_insecure_skip_verify,omitempty"`
+	BearerTokenFile       string `yaml:"bearer_token_file,omitempty"`
+}
+
 func isValidRegex(str string) bool {
 	_, err := regexp.Compile(str)
 	return err == nil
 }
 
-func writeConfigToFile(podannotationNamespaceRegex string) error {
-	log.Printf("Writing configuration to file: %s\n", podAnnotationEnvVarPath)
-	file, err := os.Create(podAnnotationEnvVarPath)
+func (p *configPipeline) writeConfigToFile(podannotationNamespaceRegex string) error {
+	log.Printf("Writing configuration to file: %s\n", p.paths.podAnnotationEnvVarPath)
+	file, err := p.fs.Create(p.paths.podAnnotationEnvVarPath)
 	if err != nil {
 		return fmt.Errorf("error opening file: %v", err)
 	}
@@ -28,7 +78,7 @@ func writeConfigToFile(podannotationNamespaceRegex string) error {
 
 	if podannotationNamespaceRegex != "" {
 		linuxPrefix := ""
-		//if os.Getenv("OS_TYPE") != "" && strings.ToLower(os.Getenv("OS_TYPE")) == "linux" {
+		//if env.Getenv("OS_TYPE") != "" && strings.ToLower(env.Getenv("OS_TYPE")) == "linux" {
 		//	linuxPrefix = "export "
 		//}
 		// Writes the variable to the file in the format: AZMON_PROMETHEUS_POD_ANNOTATION_NAMESPACES_REGEX='value'
@@ -36,10 +86,10 @@ func writeConfigToFile(podannotationNamespaceRegex string) error {
 		envVarAnnotationsEnabled := fmt.Sprintf("%s%s=%s\n", linuxPrefix, envVariableAnnotationsEnabledName, "true")
 		log.Printf("Writing to file: %s%s", envVarString, envVarAnnotationsEnabled)
 
-		if _, err := file.WriteString(envVarString); err != nil {
+		if _, err := io.WriteString(file, envVarString); err != nil {
 			return fmt.Errorf("error writing to file: %v", err)
 		}
-		if _, err := file.WriteString(envVarAnnotationsEnabled); err != nil {
+		if _, err := io.WriteString(file, envVarAnnotationsEnabled); err != nil {
 			return fmt.Errorf("error writing to file: %v", err)
 		}
 
@@ -48,7 +98,7 @@ func writeConfigToFile(podannotationNamespaceRegex string) error {
 	return nil
 }
 
-func configurePodAnnotationSettings(metricsConfigBySection map[string]map[string]string) error {
+func (p *configPipeline) configurePodAnnotationSettings(metricsConfigBySection map[string]map[string]string) error {
 	if metricsConfigBySection == nil {
 		return fmt.Errorf("configmap section not mounted, using defaults")
 	}
@@ -56,7 +106,11 @@ func configurePodAnnotationSettings(metricsConfigBySection map[string]map[string
 	if err != nil {
 		return err
 	}
-	if err := writeConfigToFile(podannotationNamespaceRegex); err != nil {
+	if err := p.writeConfigToFile(podannotationNamespaceRegex); err != nil {
+		return err
+	}
+	settings := parsePodAnnotationSettings(metricsConfigBySection[LOGGING_PREFIX])
+	if err := p.writePodAnnotationSettings(settings); err != nil {
 		return err
 	}
 	return nil
@@ -64,13 +118,13 @@ func configurePodAnnotationSettings(metricsConfigBySection map[string]map[string
 
 func populatePodAnnotationNamespaceFromConfigMap(metricsConfigBySection map[string]map[string]string) (string, error) {
 	// Access the nested map and value
-	innerMap, ok := metricsConfigBySection["pod-annotation-based-scraping"]
+	innerMap, ok := metricsConfigBySection[LOGGING_PREFIX]
 	if !ok {
 		log.Println("Pod annotation namespace regex configuration not found")
 		return "", fmt.Errorf("pod annotation namespace regex configuration not found")
 	}
 
-	regex, ok := innerMap["podannotationnamespaceregex"]
+	regex, ok := innerMap[podAnnotationNamespaceRegexKey]
 	if !ok || regex == "" {
 		log.Println("Pod annotation namespace regex does not have a value")
 		return "", fmt.Errorf("pod annotation namespace regex does not have a value")
@@ -84,3 +138,102 @@ func populatePodAnnotationNamespaceFromConfigMap(metricsConfigBySection map[stri
 		return "", fmt.Errorf("Invalid namespace regex for pod annotations: %s", regex)
 	}
 }
+
+// parsePodAnnotationSettings parses the settings of the pod-annotation-based-scraping section besides the namespace
+// regex. Invalid settings are logged and skipped.
+func parsePodAnnotationSettings(section map[string]string) podAnnotationSettings {
+	settings := podAnnotationSettings{Namespaces: map[string]podAnnotationNamespaceSettings{}}
+	for _, key := range slices.Sorted(maps.Keys(section)) {
+		if err := settings.set(key, strings.TrimSpace(section[key])); err != nil {
+			shared.EchoError(fmt.Sprintf("Skipping %s setting %q: %v", LOGGING_PREFIX, key, err))
+		}
+	}
+	return settings
+}
+
+func (s *podAnnotationSettings) set(key, value string) error {
+	switch key {
+	case podAnnotationNamespaceRegexKey:
+		return nil
+	case podAnnotationLabelSelectorKey:
+		if _, err := labels.Parse(value); err != nil {
+			return err
+		}
+		s.LabelSelector = value
+		return nil
+	case podAnnotationIndexedPortCountKey:
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 || count > maxPodAnnotationIndexedPorts {
+			return fmt.Errorf("%q is not a number from 0 to %d", value, maxPodAnnotationIndexedPorts)
+		}
+		s.IndexedPortCount = count
+		return nil
+	}
+
+	namespace, field, found := strings.Cut(key, ".")
+	if !found {
+		return fmt.Errorf("unknown setting")
+	}
+	if !namespaceNameRegex.MatchString(namespace) {
+		return fmt.Errorf("%q is not a namespace name", namespace)
+	}
+	namespaceSettings := s.Namespaces[namespace]
+	switch field {
+	case "scrape_interval":
+		if value == "" || !MATCHER.MatchString(value) {
+			return fmt.Errorf("%q is not a duration such as 30s", value)
+		}
+		namespaceSettings.ScrapeInterval = value
+	case "scheme":
+		if value != "http" && value != "https" {
+			return fmt.Errorf("the scheme must be http or https")
+		}
+		namespaceSettings.Scheme = value
+	case "tls_insecure_skip_verify":
+		insecureSkipVerify, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		namespaceSettings.TLSInsecureSkipVerify = insecureSkipVerify
+	case "tls_server_name":
+		namespaceSettings.TLSServerName = value
+	case "tls_ca_file", "bearer_token_file":
+		if !filepath.IsAbs(value) {
+			return fmt.Errorf("%q is not an absolute file path", value)
+		}
+		if field == "tls_ca_file" {
+			namespaceSettings.TLSCAFile = value
+		} else {
+			namespaceSettings.BearerTokenFile = value
+		}
+	default:
+		return fmt.Errorf("unknown field %q", field)
+	}
+	s.Namespaces[namespace] = namespaceSettings
+	return nil
+}
+
+func (p *configPipeline) writePodAnnotationSettings(settings podAnnotationSettings) error {
+	out, err := yaml.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("error marshalling pod annotation settings: %v", err)
+	}
+	if err := p.fs.WriteFile(p.paths.podAnnotationSettingsEnvVarPath, out, fs.FileMode(0644)); err != nil {
+		return fmt.Errorf("error writing pod annotation settings: %v", err)
+	}
+	return nil
+}
+
+func (p *configPipeline) loadPodAnnotationSettings() {
+	p.loadedPodAnnotationSettings = podAnnotationSettings{}
+	data, err := p.fs.ReadFile(p.paths.podAnnotationSettingsEnvVarPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Exception in loadPodAnnotationSettings for prometheus config: %v. Only the namespace regex will be used for pod annotations\n", err)
+		}
+		return
+	}
+	if err := yaml.Unmarshal(data, &p.loadedPodAnnotationSettings); err != nil {
+		log.Printf("Exception in loadPodAnnotationSettings for prometheus config: %v. Only the namespace regex will be used for pod annotations\n", err)
+	}
+}
@@ -3,6 +3,7 @@ package configmapsettings
 import (
 	"bufio"
 	"fmt"
+	"log"
 	"os"
 	"strings"
 
@@ -16,20 +17,18 @@ var _ = Describe("ConfigMapSettings", func() {
 			cleanupEnvVars()
 		})
 
+		var metricsConfigBySection map[string]map[string]string
+
 		Context("when the config map file exists", func() {
 			BeforeEach(func() {
-				// Create a temporary file with the desired content
-				fileContent := `podannotationnamespaceregex = "^namespace-regex|namespace-regex-2$"`
-				file, err := os.CreateTemp("", "configmap")
-				Expect(err).NotTo(HaveOccurred())
-				defer file.Close()
+				metricsConfigBySection = map[string]map[string]string{
+					"pod-annotation-based-scraping": {"podannotationnamespaceregex": "^namespace-regex|namespace-regex-2$"},
+				}
 
-				_, err = file.WriteString(fileContent)
-				Expect(err).NotTo(HaveOccurred())
-
-				// Set the configMapMountPathForPodAnnotation to the temporary file path
-				configMapMountPathForPodAnnotation = file.Name()
-				podAnnotationEnvVarPath = fmt.Sprintf("%s_out", configMapMountPathForPodAnnotation)
+				// Set the output files to temporary file paths
+				testPipeline.paths.configMapMountPathForPodAnnotation = createTempFile("configmap", "")
+				testPipeline.paths.podAnnotationEnvVarPath = fmt.Sprintf("%s_out", testPipeline.paths.configMapMountPathForPodAnnotation)
+				testPipeline.paths.podAnnotationSettingsEnvVarPath = fmt.Sprintf("%s_settings", testPipeline.paths.configMapMountPathForPodAnnotation)
 
 				setEnvVars(map[string]string {
 					"AZMON_OPERATOR_ENABLED": "true",
@@ -44,23 +43,23 @@ var _ = Describe("ConfigMapSettings", func() {
 			})
 
 			AfterEach(func() {
-				Expect(os.Remove(configMapMountPathForPodAnnotation)).To(Succeed())
+				Expect(os.Remove(testPipeline.paths.configMapMountPathForPodAnnotation)).To(Succeed())
 			})
 
 			It("should print the configmap namespace regex", func() {
 				capturedOutput := captureOutput(func() {
-					err := configurePodAnnotationSettings()
+					err := testPipeline.configurePodAnnotationSettings(metricsConfigBySection)
 					Expect(err).NotTo(HaveOccurred())
 				})
 
-				Expect(capturedOutput).To(ContainSubstring("Using configmap namespace regex for podannotations: ^namespace-regex|namespace-regex-2$"))
+				Expect(capturedOutput).To(ContainSubstring("Using configmap namespace regex for pod annotations: ^namespace-regex|namespace-regex-2$"))
 			})
 
 			It("should write the config to the output file", func() {
-				err := configurePodAnnotationSettings()
+				err := testPipeline.configurePodAnnotationSettings(metricsConfigBySection)
 				Expect(err).NotTo(HaveOccurred())
 
-				content, err := os.ReadFile(podAnnotationEnvVarPath)
+				content, err := os.ReadFile(testPipeline.paths.podAnnotationEnvVarPath)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(string(content)).To(Equal("AZMON_PROMETHEUS_POD_ANNOTATION_NAMESPACES_REGEX='^namespace-regex|namespace-regex-2$'\nAZMON_PROMETHEUS_POD_ANNOTATION_SCRAPING_ENABLED=true\n"))
 			})
@@ -68,7 +67,8 @@ var _ = Describe("ConfigMapSettings", func() {
 
 		Context("when the config map file does not exist", func() {
 			BeforeEach(func() {
-				configMapMountPathForPodAnnotation = "/path/to/nonexistent/file"
+				metricsConfigBySection = nil
+				testPipeline.paths.configMapMountPathForPodAnnotation = "/path/to/nonexistent/file"
 				setEnvVars(map[string]string {
 					"AZMON_OPERATOR_ENABLED": "true",
 					"CONTAINER_TYPE": "ConfigReaderSidecar",
@@ -82,7 +82,7 @@ var _ = Describe("ConfigMapSettings", func() {
 			})
 
 			It("should return an error", func() {
-				err := configurePodAnnotationSettings()
+				err := testPipeline.configurePodAnnotationSettings(metricsConfigBySection)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("configmap section not mounted, using defaults"))
 			})
@@ -90,18 +90,13 @@ var _ = Describe("ConfigMapSettings", func() {
 
 		Context("when the out file does not exist", func() {
 			BeforeEach(func() {
-				// Create a temporary file with the desired content
-				fileContent := `podannotationnamespaceregex = "^namespace-regex|namespace-regex-2$"`
-				file, err := os.CreateTemp("", "configmap")
-				Expect(err).NotTo(HaveOccurred())
-				defer file.Close()
+				metricsConfigBySection = map[string]map[string]string{
+					"pod-annotation-based-scraping": {"podannotationnamespaceregex": "^namespace-regex|namespace-regex-2$"},
+				}
 
-				_, err = file.WriteString(fileContent)
-				Expect(err).NotTo(HaveOccurred())
-
-				// Set the configMapMountPathForPodAnnotation to the temporary file path
-				configMapMountPathForPodAnnotation = file.Name()
-				podAnnotationEnvVarPath = "/path/to/nonexistent/file"
+				// Set the configMapMountPathForPodAnnotation to a temporary file path
+				testPipeline.paths.configMapMountPathForPodAnnotation = createTempFile("configmap", "")
+				testPipeline.paths.podAnnotationEnvVarPath = "/path/to/nonexistent/file"
 
 				setEnvVars(map[string]string {
 					"AZMON_OPERATOR_ENABLED": "true",
@@ -116,25 +111,137 @@ var _ = Describe("ConfigMapSettings", func() {
 			})
 
 			It("should return an error", func() {
-				err := configurePodAnnotationSettings()
+				err := testPipeline.configurePodAnnotationSettings(metricsConfigBySection)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("error opening file"))
 			})
 		})
 
-		Context("when the config map file contains an invalid namespace regex", func() {
+		Context("when the section has more settings", func() {
+			It("should write the valid settings to the settings file", func() {
+				testPipeline.paths.podAnnotationEnvVarPath = createTempFile("podannotation-envvar", "")
+				testPipeline.paths.podAnnotationSettingsEnvVarPath = createTempFile("podannotation-settings-envvar", "")
+				err := testPipeline.configurePodAnnotationSettings(map[string]map[string]string{
+					"pod-annotation-based-scraping": {
+						"podannotationnamespaceregex":    "app-.*",
+						"podlabelselector":               "team=payments,tier!=test",
+						"indexedportcount":               "2",
+						"app-a.scrape_interval":          "15s",
+						"app-a.scheme":                   "https",
+						"app-a.tls_ca_file":              "/etc/certs/ca.crt",
+						"app-a.bearer_token_file":        "/var/run/secrets/token",
+						"app-b.tls_insecure_skip_verify": "true",
+						"app-b.scrape_interval":          "often",
+						"App_C.scheme":                   "https",
+						"app-d.unknown":                  "value",
+						"unknown":                        "value",
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				testPipeline.loadPodAnnotationSettings()
+				Expect(testPipeline.loadedPodAnnotationSettings).To(Equal(podAnnotationSettings{
+					LabelSelector:    "team=payments,tier!=test",
+					IndexedPortCount: 2,
+					Namespaces: map[string]podAnnotationNamespaceSettings{
+						"app-a": {ScrapeInterval: "15s", Scheme: "https", TLSCAFile: "/etc/certs/ca.crt", BearerTokenFile: "/var/run/secrets/token"},
+						"app-b": {TLSInsecureSkipVerify: true},
+					},
+				}))
+			})
+
+			It("should skip an invalid label selector and indexed port count", func() {
+				settings := parsePodAnnotationSettings(map[string]string{"podlabelselector": "team in (a", "indexedportcount": "11"})
+				Expect(settings.LabelSelector).To(BeEmpty())
+				Expect(settings.IndexedPortCount).To(BeZero())
+			})
+		})
+
+		Context("when applying the settings to the pod annotations job", func() {
+			var configFile string
+
 			BeforeEach(func() {
-				// Create a temporary file with an invalid regex
-				fileContent := `podannotationnamespaceregex = "invalid-regex("`
-				file, err := os.CreateTemp("", "configmap")
+				contents, err := os.ReadFile("../../../configmapparser/default-prom-configs/podannotationsDefault.yml")
 				Expect(err).NotTo(HaveOccurred())
-				defer file.Close()
+				configFile = createTempFile("podannotations", string(contents))
+			})
+
+			It("should leave the job unchanged without settings", func() {
+				before, _ := os.ReadFile(configFile)
+				Expect(testPipeline.applyPodAnnotationSettings(configFile, podAnnotationSettings{})).To(Succeed())
+				after, _ := os.ReadFile(configFile)
+				Expect(after).To(Equal(before))
+			})
 
-				_, err = file.WriteString(fileContent)
+			It("should add the jobs of the namespaces and indexed ports", func() {
+				Expect(testPipeline.applyPodAnnotationSettings(configFile, podAnnotationSettings{
+					LabelSelector:    "team=payments",
+					IndexedPortCount: 1,
+					Namespaces: map[string]podAnnotationNamespaceSettings{
+						"secure": {ScrapeInterval: "15s", Scheme: "https", TLSInsecureSkipVerify: true, BearerTokenFile: "/var/run/secrets/token"},
+					},
+				})).To(Succeed())
+
+				config, err := testPipeline.loadYAMLFromFile(configFile)
 				Expect(err).NotTo(HaveOccurred())
+				Expect(scrapeJobNames(config)).To(Equal([]string{"kubernetes-pods", "kubernetes-pods-ns-secure", "kubernetes-pods-port-1", "kubernetes-pods-ns-secure-port-1"}))
+
+				jobs := config["scrape_configs"].([]interface{})
+				base := jobs[0].(map[interface{}]interface{})
+				Expect(kubernetesSDConfigs(base)[0]["selectors"]).To(Equal([]interface{}{map[interface{}]interface{}{"role": "pod", "label": "team=payments"}}))
+				Expect(relabelConfigs(base)).To(ContainElement(map[interface{}]interface{}{
+					"source_labels": []interface{}{"__meta_kubernetes_namespace"}, "action": "drop", "regex": "secure",
+				}))
+
+				secure := jobs[1].(map[interface{}]interface{})
+				Expect(secure["scrape_interval"]).To(Equal("15s"))
+				Expect(secure["scheme"]).To(Equal("https"))
+				Expect(secure["tls_config"]).To(Equal(map[interface{}]interface{}{"insecure_skip_verify": true}))
+				Expect(secure["authorization"]).To(Equal(map[interface{}]interface{}{"type": "Bearer", "credentials_file": "/var/run/secrets/token"}))
+				Expect(kubernetesSDConfigs(secure)[0]["namespaces"]).To(Equal(map[interface{}]interface{}{"names": []interface{}{"secure"}}))
+				Expect(kubernetesSDConfigs(secure)[0]["selectors"]).NotTo(BeNil())
+
+				secureIndexed := jobs[3].(map[interface{}]interface{})
+				Expect(secureIndexed["scheme"]).To(Equal("https"))
+				Expect(relabelConfigs(secureIndexed)).To(ContainElement(HaveKeyWithValue("source_labels", []interface{}{"__meta_kubernetes_pod_annotation_prometheus_io_path_1"})))
+				Expect(relabelConfigs(secureIndexed)).To(ContainElement(HaveKeyWithValue("source_labels", []interface{}{"__address__", "__meta_kubernetes_pod_annotation_prometheus_io_port_1"})))
+				Expect(relabelConfigs(secureIndexed)).To(ContainElement(map[interface{}]interface{}{
+					"source_labels": []interface{}{"__meta_kubernetes_pod_annotation_prometheus_io_port_1"}, "action": "keep", "regex": `\d+`,
+				}))
+				Expect(relabelConfigs(base)).NotTo(ContainElement(HaveKeyWithValue("source_labels", []interface{}{"__meta_kubernetes_pod_annotation_prometheus_io_path_1"})))
+			})
+
+			It("should reject the settings when two jobs get the same name", func() {
+				before, _ := os.ReadFile(configFile)
+				Expect(testPipeline.applyPodAnnotationSettings(configFile, podAnnotationSettings{
+					IndexedPortCount: 1,
+					Namespaces: map[string]podAnnotationNamespaceSettings{
+						"a":        {ScrapeInterval: "15s"},
+						"a-port-1": {ScrapeInterval: "30s"},
+					},
+				})).To(MatchError(ContainSubstring("kubernetes-pods-ns-a-port-1")))
+				after, _ := os.ReadFile(configFile)
+				Expect(after).To(Equal(before))
+			})
+
+			It("should map the param annotations to query params", func() {
+				config, err := testPipeline.loadYAMLFromFile(configFile)
+				Expect(err).NotTo(HaveOccurred())
+				job := config["scrape_configs"].([]interface{})[0].(map[interface{}]interface{})
+				Expect(relabelConfigs(job)).To(ContainElement(map[interface{}]interface{}{
+					"action": "labelmap", "regex": "__meta_kubernetes_pod_annotation_prometheus_io_param_(.+)", "replacement": "__param_$1",
+				}))
+			})
+		})
+
+		Context("when the config map file contains an invalid namespace regex", func() {
+			BeforeEach(func() {
+				metricsConfigBySection = map[string]map[string]string{
+					"pod-annotation-based-scraping": {"podannotationnamespaceregex": "invalid-regex("},
+				}
 
-				// Set the configMapMountPathForPodAnnotation to the temporary file path
-				configMapMountPathForPodAnnotation = file.Name()
+				// Set the configMapMountPathForPodAnnotation to a temporary file path
+				testPipeline.paths.configMapMountPathForPodAnnotation = createTempFile("configmap", "")
 
 				setEnvVars(map[string]string {
 					"AZMON_OPERATOR_ENABLED": "true",
@@ -149,28 +256,30 @@ var _ = Describe("ConfigMapSettings", func() {
 			})
 
 			AfterEach(func() {
-				Expect(os.Remove(configMapMountPathForPodAnnotation)).To(Succeed())
+				Expect(os.Remove(testPipeline.paths.configMapMountPathForPodAnnotation)).To(Succeed())
 			})
 
 			It("should return an error", func() {
-				err := configurePodAnnotationSettings()
+				err := testPipeline.configurePodAnnotationSettings(metricsConfigBySection)
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("Invalid namespace regex for podannotations"))
+				Expect(err.Error()).To(ContainSubstring("Invalid namespace regex for pod annotations"))
 			})
 		})
 	})
 })
 
-// Helper function to capture the output of fmt.Printf
+// Helper function to capture the output of fmt.Printf and log.Printf
 func captureOutput(f func()) string {
 	old := os.Stdout
 	r, w, _ := os.Pipe()
 	os.Stdout = w
+	log.SetOutput(w)
 
 	f()
 
 	w.Close()
 	os.Stdout = old
+	log.SetOutput(os.Stderr)
 
 	var buf strings.Builder
 	scanner := bufio.NewScanner(r)
@@ -3,7 +3,6 @@ package configmapsettings
 import (
 	"fmt"
 	"log"
-	"os"
 	"regexp"
 	"strings"
 )
@@ -31,7 +30,7 @@ func (cp *ConfigProcessor) PopulateSettingValuesFromConfigMap(metricsConfigBySec
 	}
 
 	// Populate operator settings
-	if operatorEnabled := os.Getenv("AZMON_OPERATOR_ENABLED"); operatorEnabled != "" && strings.ToLower(operatorEnabled) == "true" {
+	if operatorEnabled := cp.pipeline.env.Getenv("AZMON_OPERATOR_ENABLED"); operatorEnabled != "" && strings.ToLower(operatorEnabled) == "true" {
 		cp.IsOperatorEnabledChartSetting = true
 		if settings, ok := metricsConfigBySection["prometheus-collector-settings"]; ok {
 			if value, ok := settings["operator_enabled"]; ok {
@@ -65,7 +64,7 @@ func (cp *ConfigProcessor) PopulateSettingValuesFromConfigMap(metricsConfigBySec
 		}
 	}
 
-	if operatorHttpsEnabled := os.Getenv("OPERATOR_TARGETS_HTTPS_ENABLED"); operatorHttpsEnabled != "" && strings.ToLower(operatorHttpsEnabled) == "true" {
+	if operatorHttpsEnabled := cp.pipeline.env.Getenv("OPERATOR_TARGETS_HTTPS_ENABLED"); operatorHttpsEnabled != "" && strings.ToLower(operatorHttpsEnabled) == "true" {
 		cp.TargetallocatorHttpsEnabledChartSetting = true
 		cp.TargetallocatorHttpsEnabled = true
 		if settings, ok := metricsConfigBySection["prometheus-collector-settings"]; ok {
@@ -84,30 +83,30 @@ func (cp *ConfigProcessor) PopulateSettingValuesFromConfigMap(metricsConfigBySec
 }
 
 func (fcw *FileConfigWriter) WriteConfigToFile(filename string, configParser *ConfigProcessor) error {
-	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	file, err := fcw.pipeline.fs.Create(filename)
 	if err != nil {
 		return fmt.Errorf("exception while opening file for writing prometheus-collector config environment variables: %s", err)
 	}
 	defer file.Close()
 
-	file.WriteString(fmt.Sprintf("AZMON_DEFAULT_METRIC_ACCOUNT_NAME=%s\n", configParser.DefaultMetricAccountName))
-	file.WriteString(fmt.Sprintf("AZMON_CLUSTER_LABEL=%s\n", configParser.ClusterLabel))
-	file.WriteString(fmt.Sprintf("AZMON_CLUSTER_ALIAS=%s\n", configParser.ClusterAlias))
-	file.WriteString(fmt.Sprintf("AZMON_OPERATOR_ENABLED_CHART_SETTING=%t\n", configParser.IsOperatorEnabledChartSetting))
+	fmt.Fprintf(file, "AZMON_DEFAULT_METRIC_ACCOUNT_NAME=%s\n", configParser.DefaultMetricAccountName)
+	fmt.Fprintf(file, "AZMON_CLUSTER_LABEL=%s\n", configParser.ClusterLabel)
+	fmt.Fprintf(file, "AZMON_CLUSTER_ALIAS=%s\n", configParser.ClusterAlias)
+	fmt.Fprintf(file, "AZMON_OPERATOR_ENABLED_CHART_SETTING=%t\n", configParser.IsOperatorEnabledChartSetting)
 	if configParser.IsOperatorEnabled {
-		file.WriteString(fmt.Sprintf("AZMON_OPERATOR_ENABLED=%t\n", configParser.IsOperatorEnabled))
-		file.WriteString(fmt.Sprintf("AZMON_OPERATOR_ENABLED_CFG_MAP_SETTING=%t\n", configParser.IsOperatorEnabled))
+		fmt.Fprintf(file, "AZMON_OPERATOR_ENABLED=%t\n", configParser.IsOperatorEnabled)
+		fmt.Fprintf(file, "AZMON_OPERATOR_ENABLED_CFG_MAP_SETTING=%t\n", configParser.IsOperatorEnabled)
 	}
-	file.WriteString(fmt.Sprintf("AZMON_OPERATOR_HTTPS_ENABLED_CHART_SETTING=%t\n", configParser.TargetallocatorHttpsEnabledChartSetting))
-	file.WriteString(fmt.Sprintf("AZMON_OPERATOR_HTTPS_ENABLED=%t\n", configParser.TargetallocatorHttpsEnabled))
+	fmt.Fprintf(file, "AZMON_OPERATOR_HTTPS_ENABLED_CHART_SETTING=%t\n", configParser.TargetallocatorHttpsEnabledChartSetting)
+	fmt.Fprintf(file, "AZMON_OPERATOR_HTTPS_ENABLED=%t\n", configParser.TargetallocatorHttpsEnabled)
 	if len(configParser.SecretsAccessNamespaces) > 0 {
-		file.WriteString(fmt.Sprintf("AZMON_SECRETS_ACCESS_NAMESPACES=%s\n", strings.Join(configParser.SecretsAccessNamespaces, ",")))
+		fmt.Fprintf(file, "AZMON_SECRETS_ACCESS_NAMESPACES=%s\n", strings.Join(configParser.SecretsAccessNamespaces, ","))
 	}
 	return nil
 }
 
 func (c *Configurator) Configure(metricsConfigBySection map[string]map[string]string) {
-	configSchemaVersion := os.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION")
+	configSchemaVersion := c.pipeline.env.Getenv("AZMON_AGENT_CFG_SCHEMA_VERSION")
 	log.Printf("Configure:Print the value of AZMON_AGENT_CFG_SCHEMA_VERSION: %s\n", configSchemaVersion)
 
 	if configSchemaVersion != "" && (strings.TrimSpace(configSchemaVersion) == "v1" || strings.TrimSpace(configSchemaVersion) == "v2") {
@@ -115,16 +114,16 @@ func (c *Configurator) Configure(metricsConfigBySection map[string]map[string]st
 			c.ConfigParser.PopulateSettingValuesFromConfigMap(metricsConfigBySection)
 		}
 	} else {
-		if _, err := os.Stat(c.ConfigLoader.ConfigMapMountPath); err == nil {
+		if _, err := c.pipeline.fs.Stat(c.ConfigLoader.ConfigMapMountPath); err == nil {
 			log.Printf("Unsupported/missing config schema version - '%s', using defaults, please use supported schema version\n", configSchemaVersion)
 		}
 	}
 
-	if mac := os.Getenv("MAC"); mac != "" && strings.TrimSpace(mac) == "true" {
-		clusterArray := strings.Split(strings.TrimSpace(os.Getenv("CLUSTER")), "/")
+	if mac := c.pipeline.env.Getenv("MAC"); mac != "" && strings.TrimSpace(mac) == "true" {
+		clusterArray := strings.Split(strings.TrimSpace(c.pipeline.env.Getenv("CLUSTER")), "/")
 		c.ConfigParser.ClusterLabel = clusterArray[len(clusterArray)-1]
 	} else {
-		c.ConfigParser.ClusterLabel = os.Getenv("CLUSTER")
+		c.ConfigParser.ClusterLabel = c.pipeline.env.Getenv("CLUSTER")
 	}
 
 	if c.ConfigParser.ClusterAlias != "" && len(c.ConfigParser.ClusterAlias) > 0 {
@@ -142,18 +141,19 @@ func (c *Configurator) Configure(metricsConfigBySection map[string]map[string]st
 	}
 }
 
-func parseConfigAndSetEnvInFile(metricsConfigBySection map[string]map[string]string) {
+func (p *configPipeline) parseConfigAndSetEnvInFile(metricsConfigBySection map[string]map[string]string) {
 
 	operatorHttpsEnabled := false
-	if strings.ToLower(os.Getenv("OPERATOR_TARGETS_HTTPS_ENABLED")) == "true" {
+	if strings.ToLower(p.env.Getenv("OPERATOR_TARGETS_HTTPS_ENABLED")) == "true" {
 		operatorHttpsEnabled = true
 	}
 
 	configurator := &Configurator{
-		ConfigLoader:   &FilesystemConfigLoader{ConfigMapMountPath: collectorSettingsMountPath},
-		ConfigParser:   &ConfigProcessor{TargetallocatorHttpsEnabled: operatorHttpsEnabled},
-		ConfigWriter:   &FileConfigWriter{ConfigProcessor: &ConfigProcessor{}},
-		ConfigFilePath: collectorSettingsEnvVarPath,
+		ConfigLoader:   &FilesystemConfigLoader{ConfigMapMountPath: p.paths.collectorSettingsMountPath, pipeline: p},
+		ConfigParser:   &ConfigProcessor{TargetallocatorHttpsEnabled: operatorHttpsEnabled, pipeline: p},
+		ConfigWriter:   &FileConfigWriter{ConfigProcessor: &ConfigProcessor{pipeline: p}, pipeline: p},
+		ConfigFilePath: p.paths.collectorSettingsEnvVarPath,
+		pipeline:       p,
 	}
 
 	configurator.Configure(metricsConfigBySection)
@@ -0,0 +1,190 @@
+package configmapsettings
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus-collector/shared"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	scrapeLimitsSection   = "scrape-limits"
+	scrapeLimitsJobPrefix = "job."
+)
+
+// bodySizeLimitRegex matches the sizes accepted for body_size_limit in the prometheus config
+var bodySizeLimitRegex = regexp.MustCompile(`^(0|[0-9]+(B|KB|MB|GB|TB|PB|EB))$`)
+
+// scrapeLimitFields are the scrape config fields that can be set in the scrape-limits section, with their parser
+var scrapeLimitFields = map[string]func(string) (interface{}, error){
+	"sample_limit":             parseScrapeLimit,
+	"target_limit":             parseScrapeLimit,
+	"label_limit":              parseScrapeLimit,
+	"label_name_length_limit":  parseScrapeLimit,
+	"label_value_length_limit": parseScrapeLimit,
+	"keep_dropped_targets":     parseScrapeLimit,
+	"body_size_limit":          parseBodySizeLimit,
+}
+
+// scrapeLimits holds the scrape config fields to set, keyed by field name
+type scrapeLimits map[string]interface{}
+
+// scrapeLimitsSettings are the limits of the scrape-limits section of the settings configmap, for example:
+//
+//	sample_limit = 100000
+//	kubelet.sample_limit = 200000
+//	job.my-noisy-exporter.sample_limit = 10000
+//	job.my-noisy-exporter.body_size_limit = "10MB"
+//
+// Keys without a prefix set cluster-wide defaults, keys prefixed with a default target name override them for the
+// jobs of that default target and keys prefixed with job.<job name> override them for a custom scrape job. The most
+// specific limit wins. Target and job limits replace the value in the scrape config of the job, cluster-wide defaults
+// only fill the fields a custom scrape job leaves unset.
+//
+// The limits are applied to the default targets and the jobs of the custom prometheus config only, not to the jobs
+// generated by the target allocator from pod and service monitors.
+type scrapeLimitsSettings struct {
+	Cluster        scrapeLimits            `yaml:"cluster,omitempty"`
+	DefaultTargets map[string]scrapeLimits `yaml:"default_targets,omitempty"`
+	Jobs           map[string]scrapeLimits `yaml:"jobs,omitempty"`
+}
+
+func parseScrapeLimit(value string) (interface{}, error) {
+	limit, err := strconv.ParseUint(value, 10, 31)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a non-negative integer", value)
+	}
+	return int(limit), nil
+}
+
+func parseBodySizeLimit(value string) (interface{}, error) {
+	if !bodySizeLimitRegex.MatchString(value) {
+		return nil, fmt.Errorf("%q is not a size such as 10MB", value)
+	}
+	return value, nil
+}
+
+// parseScrapeLimitsSettings parses the keys of the scrape-limits section. Invalid keys are logged and skipped, so a
+// typo does not drop the other limits.
+func parseScrapeLimitsSettings(settings map[string]string, defaultTargets []DefaultTarget) scrapeLimitsSettings {
+	limitsSettings := scrapeLimitsSettings{
+		Cluster:        scrapeLimits{},
+		DefaultTargets: map[string]scrapeLimits{},
+		Jobs:           map[string]scrapeLimits{},
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		limits, field, err := limitsSettings.limitsForKey(key, defaultTargets)
+		if err == nil {
+			parse, exists := scrapeLimitFields[field]
+			if !exists {
+				err = fmt.Errorf("unknown field %q", field)
+			} else {
+				var value interface{}
+				if value, err = parse(strings.TrimSpace(settings[key])); err == nil {
+					limits[field] = value
+				}
+			}
+		}
+		if err != nil {
+			shared.EchoError(fmt.Sprintf("Skipping %s setting %q: %v", scrapeLimitsSection, key, err))
+		}
+	}
+	return limitsSettings
+}
+
+// limitsForKey returns the limits a key of the scrape-limits section sets a field of, and the field name
+func (s scrapeLimitsSettings) limitsForKey(key string, defaultTargets []DefaultTarget) (scrapeLimits, string, error) {
+	if jobAndField, found := strings.CutPrefix(key, scrapeLimitsJobPrefix); found {
+		// Job names can contain dots, the field is after the last one
+		index := strings.LastIndex(jobAndField, ".")
+		if index <= 0 {
+			return nil, "", fmt.Errorf("key is not of the form %s<job name>.<field>", scrapeLimitsJobPrefix)
+		}
+		job := jobAndField[:index]
+		if _, exists := s.Jobs[job]; !exists {
+			s.Jobs[job] = scrapeLimits{}
+		}
+		return s.Jobs[job], jobAndField[index+1:], nil
+	}
+
+	targetName, field, found := strings.Cut(key, ".")
+	if !found {
+		return s.Cluster, key, nil
+	}
+	if !slices.ContainsFunc(defaultTargets, func(target DefaultTarget) bool { return target.Name == targetName }) {
+		return nil, "", fmt.Errorf("%q is not a default target", targetName)
+	}
+	if _, exists := s.DefaultTargets[targetName]; !exists {
+		s.DefaultTargets[targetName] = scrapeLimits{}
+	}
+	return s.DefaultTargets[targetName], field, nil
+}
+
+// forDefaultTarget returns the limits to set on the jobs of a default target
+func (s scrapeLimitsSettings) forDefaultTarget(targetName string) scrapeLimits {
+	limits := scrapeLimits{}
+	mergeScrapeLimits(limits, s.Cluster)
+	mergeScrapeLimits(limits, s.DefaultTargets[targetName])
+	return limits
+}
+
+// forCustomJob returns the limits to set on a custom scrape job. The label limits the agent always set on custom jobs
+// apply unless they are overridden, and the cluster-wide defaults only apply to the fields the scrape config of the job
+// does not set.
+func (s scrapeLimitsSettings) forCustomJob(jobName string, scrapeConfig map[interface{}]interface{}) scrapeLimits {
+	limits := scrapeLimits{
+		"label_limit":              shared.CustomScrapeLabelLimit,
+		"label_name_length_limit":  shared.CustomScrapeLabelNameLengthLimit,
+		"label_value_length_limit": shared.CustomScrapeLabelValueLengthLimit,
+	}
+	for field, value := range s.Cluster {
+		if _, exists := scrapeConfig[field]; !exists {
+			limits[field] = value
+		}
+	}
+	mergeScrapeLimits(limits, s.Jobs[jobName])
+	return limits
+}
+
+func mergeScrapeLimits(target, source scrapeLimits) {
+	for field, value := range source {
+		target[field] = value
+	}
+}
+
+func (p *configPipeline) tomlparserScrapeLimits(metricsConfigBySection map[string]map[string]string) {
+	shared.EchoSectionDivider("Start Processing - tomlparserScrapeLimits")
+	limitsSettings := parseScrapeLimitsSettings(metricsConfigBySection[scrapeLimitsSection], p.defaultTargets)
+	out, err := yaml.Marshal(limitsSettings)
+	if err != nil {
+		log.Printf("Error marshalling scrape limits: %v\n", err)
+		return
+	}
+	if err := p.fs.WriteFile(p.paths.scrapeLimitsEnvVarPath, out, fs.FileMode(0644)); err != nil {
+		log.Printf("Error writing to file: %v\n", err)
+		return
+	}
+	shared.EchoSectionDivider("End Processing - tomlparserScrapeLimits")
+}
+
+func (p *configPipeline) loadScrapeLimits() {
+	p.loadedScrapeLimits = scrapeLimitsSettings{}
+	data, err := p.fs.ReadFile(p.paths.scrapeLimitsEnvVarPath)
+	if err != nil {
+		log.Printf("Exception in loadScrapeLimits for prometheus config: %v. Only the default label limits will be used\n", err)
+		return
+	}
+
+	err = yaml.Unmarshal(data, &p.loadedScrapeLimits)
+	if err != nil {
+		log.Printf("Exception in loadScrapeLimits for prometheus config: %v. Only the default label limits will be used\n", err)
+	}
+}
@@ -0,0 +1,130 @@
+package configmapsettings
+
+import (
+	"os"
+
+	"github.com/prometheus-collector/shared"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+)
+
+var _ = Describe("ScrapeLimits", func() {
+	Context("when parsing the scrape-limits section", func() {
+		var limitsSettings scrapeLimitsSettings
+
+		BeforeEach(func() {
+			limitsSettings = parseScrapeLimitsSettings(map[string]string{
+				"sample_limit":                         "100000",
+				"body_size_limit":                      "50MB",
+				"label_limit":                          "100",
+				"kubelet.sample_limit":                 "200000",
+				"job.team.app.v1.sample_limit":         "10000",
+				"job.team.app.v1.keep_dropped_targets": "50",
+				"target_limit":                         "-1",
+				"job.other.body_size_limit":            "10 megabytes",
+				"unknown-target.sample_limit":          "10",
+				"job.other.scrape_interval":            "30s",
+				"job.nofield":                          "10",
+			}, builtInDefaultTargets)
+		})
+
+		It("should keep the valid limits at each level", func() {
+			Expect(limitsSettings.Cluster).To(Equal(scrapeLimits{"sample_limit": 100000, "body_size_limit": "50MB", "label_limit": 100}))
+			Expect(limitsSettings.DefaultTargets).To(Equal(map[string]scrapeLimits{"kubelet": {"sample_limit": 200000}}))
+			Expect(limitsSettings.Jobs).To(HaveKeyWithValue("team.app.v1", scrapeLimits{"sample_limit": 10000, "keep_dropped_targets": 50}))
+			Expect(limitsSettings.Jobs["other"]).To(BeEmpty())
+		})
+
+		It("should apply the most specific limit", func() {
+			Expect(limitsSettings.forDefaultTarget("kubelet")).To(Equal(scrapeLimits{"sample_limit": 200000, "body_size_limit": "50MB", "label_limit": 100}))
+			Expect(limitsSettings.forDefaultTarget("coredns")).To(Equal(limitsSettings.Cluster))
+			Expect(limitsSettings.forCustomJob("team.app.v1", nil)).To(Equal(scrapeLimits{
+				"sample_limit":             10000,
+				"keep_dropped_targets":     50,
+				"body_size_limit":          "50MB",
+				"label_limit":              100,
+				"label_name_length_limit":  511,
+				"label_value_length_limit": 1023,
+			}))
+		})
+
+		It("should not overwrite the fields a custom job sets with the cluster-wide defaults", func() {
+			Expect(limitsSettings.forCustomJob("my-job", map[interface{}]interface{}{"job_name": "my-job", "sample_limit": 500})).To(Equal(scrapeLimits{
+				"body_size_limit":          "50MB",
+				"label_limit":              100,
+				"label_name_length_limit":  511,
+				"label_value_length_limit": 1023,
+			}))
+		})
+	})
+
+	Context("when no limits are set", func() {
+		It("should only set the agent's label limits on custom jobs", func() {
+			limitsSettings := parseScrapeLimitsSettings(nil, builtInDefaultTargets)
+			Expect(limitsSettings.forDefaultTarget("kubelet")).To(BeEmpty())
+			Expect(limitsSettings.forCustomJob("my-job", nil)).To(Equal(scrapeLimits{
+				"label_limit":              63,
+				"label_name_length_limit":  511,
+				"label_value_length_limit": 1023,
+			}))
+		})
+	})
+
+	Context("when merging the prometheus config", func() {
+		BeforeEach(func() {
+			testPipeline.paths.scrapeLimitsEnvVarPath = GinkgoT().TempDir() + "/scrape-limits"
+
+			testPipeline.tomlparserScrapeLimits(map[string]map[string]string{
+				"scrape-limits": {
+					"sample_limit":           "1000",
+					"coredns.sample_limit":   "2000",
+					"job.noisy.sample_limit": "10",
+					"job.noisy.label_limit":  "20",
+				},
+			})
+			testPipeline.loadScrapeLimits()
+		})
+
+		It("should set the limits on the custom jobs", func() {
+			config := testPipeline.setScrapeLimitsPerScrape(`scrape_configs:
+- job_name: noisy
+  sample_limit: 50000
+- job_name: quiet
+  label_limit: 500
+- job_name: own
+  sample_limit: 50
+`)
+			var limitedConfig map[string][]map[string]interface{}
+			Expect(yaml.Unmarshal([]byte(config), &limitedConfig)).To(Succeed())
+			Expect(limitedConfig["scrape_configs"]).To(Equal([]map[string]interface{}{
+				{"job_name": "noisy", "sample_limit": 10, "label_limit": 20, "label_name_length_limit": 511, "label_value_length_limit": 1023},
+				{"job_name": "quiet", "sample_limit": 1000, "label_limit": 63, "label_name_length_limit": 511, "label_value_length_limit": 1023},
+				{"job_name": "own", "sample_limit": 50, "label_limit": 63, "label_name_length_limit": 511, "label_value_length_limit": 1023},
+			}))
+
+			overridden := 500
+			Expect(testPipeline.labelLimitChanges).To(ContainElements(
+				shared.LabelLimitChange{Job: "noisy", Field: "label_limit", Value: 20},
+				shared.LabelLimitChange{Job: "quiet", Field: "label_limit", Value: 63, Previous: &overridden},
+				shared.LabelLimitChange{Job: "own", Field: "label_value_length_limit", Value: 1023},
+			))
+			Expect(testPipeline.labelLimitChanges).To(HaveLen(9))
+		})
+
+		It("should set the limits on the jobs of a default target", func() {
+			configFile := createTempFile("coredns", `scrape_configs:
+- job_name: kube-dns
+  sample_limit: 50000
+`)
+			testPipeline.UpdateScrapeLimitsConfig(configFile, testPipeline.loadedScrapeLimits.forDefaultTarget("coredns"))
+			contents, err := os.ReadFile(configFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(`scrape_configs:
+- job_name: kube-dns
+  sample_limit: 2000
+`))
+		})
+	})
+})
@@ -2,18 +2,21 @@ package configmapsettings
 
 import (
 	"log"
-	"os"
 
 	"github.com/prometheus-collector/shared"
 	yaml "gopkg.in/yaml.v2"
 )
 
-func SetGlobalSettingsInCollectorConfig() {
-	azmonSetGlobalSettings := os.Getenv("AZMON_SET_GLOBAL_SETTINGS")
+func (a *AgentConfigPipeline) SetGlobalSettingsInCollectorConfig() {
+	a.pipeline.setGlobalSettingsInCollectorConfig()
+}
+
+func (p *configPipeline) setGlobalSettingsInCollectorConfig() {
+	azmonSetGlobalSettings := p.env.Getenv("AZMON_SET_GLOBAL_SETTINGS")
 
 	if azmonSetGlobalSettings == "true" {
-		mergedCollectorConfigPath := "/opt/microsoft/otelcollector/collector-config.yml"
-		mergedCollectorConfigFileContents, err := os.ReadFile(mergedCollectorConfigPath)
+		mergedCollectorConfigPath := p.paths.collectorConfigPath
+		mergedCollectorConfigFileContents, err := p.fs.ReadFile(mergedCollectorConfigPath)
 		if err != nil {
 			log.Printf("Unable to read file contents from: %s - %v\n", mergedCollectorConfigPath, err)
 			return
@@ -32,7 +35,7 @@ func SetGlobalSettingsInCollectorConfig() {
 		if globalSettingsFromMergedOtelConfig != nil {
 			log.Println("Found global settings in merged otel config, triyng to replace replicaset collector config")
 			collectorConfigReplicasetPath := "/opt/microsoft/otelcollector/collector-config-replicaset.yml"
-			replicasetCollectorConfigFileContents, err := os.ReadFile(collectorConfigReplicasetPath)
+			replicasetCollectorConfigFileContents, err := p.fs.ReadFile(collectorConfigReplicasetPath)
 			if err != nil {
 				log.Printf("Unable to read file contents from: %s - %v\n", replicasetCollectorConfigFileContents, err)
 				return
@@ -46,7 +49,7 @@ func SetGlobalSettingsInCollectorConfig() {
 			otelConfigReplicaset.Receivers.Prometheus.Config = map[string]interface{}{"global": ""}
 			otelConfigReplicaset.Receivers.Prometheus.Config["global"] = globalSettingsFromMergedOtelConfig
 			otelReplacedConfigYaml, _ := yaml.Marshal(otelConfigReplicaset)
-			if err := os.WriteFile(collectorConfigReplicasetPath, otelReplacedConfigYaml, 0644); err != nil {
+			if err := p.fs.WriteFile(collectorConfigReplicasetPath, otelReplacedConfigYaml, 0644); err != nil {
 				log.Printf("Unable to write to: %s - %v\n", collectorConfigReplicasetPath, err)
 				return
 			}
@@ -13,95 +13,99 @@ import (
 
 var _ = ginkgo.Describe("When parsing debug mode settings", func() {
 	ginkgo.Context("when debug mode is enabled", func() {
+		var metricsConfigBySection map[string]map[string]string
 
 		ginkgo.BeforeEach(func() {
 			suffix := createRandomString(5)
 			err := createTempFiles(suffix, `enabled = true`)
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			testPipeline.env.Setenv("AZMON_AGENT_CFG_SCHEMA_VERSION", "v1", false)
+			metricsConfigBySection = debugModeSections("true")
 		})
 
 		ginkgo.It("should configure debug mode settings for a linux replica", func() {
-			os.Setenv("CONTROLLER_TYPE", "ReplicaSet")
-			os.Setenv("OS_TYPE", "linux")
+			testPipeline.env.Setenv("CONTROLLER_TYPE", "ReplicaSet", false)
+			testPipeline.env.Setenv("OS_TYPE", "linux", false)
 
-			err := ConfigureDebugModeSettings()
+			err := testPipeline.ConfigureDebugModeSettings(metricsConfigBySection)
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 
 			// Verify that the environment variable file is created
-			_, err = os.Stat(debugModeEnvVarPath)
+			_, err = os.Stat(testPipeline.paths.debugModeEnvVarPath)
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 
 			// Verify the content of the environment variable file
-			content, err := os.ReadFile(debugModeEnvVarPath)
+			content, err := os.ReadFile(testPipeline.paths.debugModeEnvVarPath)
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 			gomega.Expect(string(content)).To(gomega.Equal("DEBUG_MODE_ENABLED=true\n"))
 
 			// Verify the modification of the YAML configuration file
-			config, err := parseYAMLConfigFile(replicaSetCollectorConfig)
+			config, err := parseYAMLConfigFile(testPipeline.paths.replicaSetCollectorConfig)
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 			gomega.Expect(config).NotTo(gomega.BeNil())
 			gomega.Expect(config["service"].(map[interface{}]interface{})["pipelines"].(map[interface{}]interface{})["metrics"].(map[interface{}]interface{})["exporters"]).To(gomega.Equal([]interface{}{"otlp_grpc", "prometheus"}))
 		})
 
 		ginkgo.It("should configure debug mode settings for a linux daemonset", func() {
-			os.Setenv("CONTROLLER_TYPE", "DaemonSet")
-			os.Setenv("OS_TYPE", "linux")
+			testPipeline.env.Setenv("CONTROLLER_TYPE", "DaemonSet", false)
+			testPipeline.env.Setenv("OS_TYPE", "linux", false)
 
-			err := ConfigureDebugModeSettings()
+			err := testPipeline.ConfigureDebugModeSettings(metricsConfigBySection)
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 
 			// Verify that the environment variable file is created
-			_, err = os.Stat(debugModeEnvVarPath)
+			_, err = os.Stat(testPipeline.paths.debugModeEnvVarPath)
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 
 			// Verify the content of the environment variable file
-			content, err := os.ReadFile(debugModeEnvVarPath)
+			content, err := os.ReadFile(testPipeline.paths.debugModeEnvVarPath)
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 			gomega.Expect(string(content)).To(gomega.Equal("DEBUG_MODE_ENABLED=true\n"))
 
 			// Verify the modification of the YAML configuration file
-			config, err := parseYAMLConfigFile(replicaSetCollectorConfig)
+			config, err := parseYAMLConfigFile(testPipeline.paths.replicaSetCollectorConfig)
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 			gomega.Expect(config).NotTo(gomega.BeNil())
 			gomega.Expect(config["service"].(map[interface{}]interface{})["pipelines"].(map[interface{}]interface{})["metrics"].(map[interface{}]interface{})["exporters"]).To(gomega.Equal([]interface{}{"otlp_grpc", "prometheus"}))
 		})
 
 		ginkgo.It("should configure debug mode settings for a windows daemonset", func() {
-			os.Setenv("CONTROLLER_TYPE", "DaemonSet")
-			os.Setenv("OS_TYPE", "windows")
+			testPipeline.env.Setenv("CONTROLLER_TYPE", "DaemonSet", false)
+			testPipeline.env.Setenv("OS_TYPE", "windows", false)
 
-			err := ConfigureDebugModeSettings()
+			err := testPipeline.ConfigureDebugModeSettings(metricsConfigBySection)
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 
 			// Verify that the environment variable file is created
-			_, err = os.Stat(debugModeEnvVarPath)
+			_, err = os.Stat(testPipeline.paths.debugModeEnvVarPath)
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 
 			// Verify the content of the environment variable file
-			content, err := os.ReadFile(debugModeEnvVarPath)
+			content, err := os.ReadFile(testPipeline.paths.debugModeEnvVarPath)
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 			gomega.Expect(string(content)).To(gomega.Equal("DEBUG_MODE_ENABLED=true\n"))
 
 			// Verify the modification of the YAML configuration file
-			config, err := parseYAMLConfigFile(replicaSetCollectorConfig)
+			config, err := parseYAMLConfigFile(testPipeline.paths.replicaSetCollectorConfig)
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 			gomega.Expect(config).NotTo(gomega.BeNil())
 			gomega.Expect(config["service"].(map[interface{}]interface{})["pipelines"].(map[interface{}]interface{})["metrics"].(map[interface{}]interface{})["exporters"]).To(gomega.Equal([]interface{}{"otlp_grpc", "prometheus"}))
 		})
 
 		ginkgo.AfterEach(func() {
-			os.Unsetenv("CONTROLLER_TYPE")
-			os.Unsetenv("OS_TYPE")
-			configMapDebugMountPath = ""
-			debugModeEnvVarPath = ""
-			replicaSetCollectorConfig = ""
+			testPipeline.env.Unsetenv("CONTROLLER_TYPE")
+			testPipeline.env.Unsetenv("OS_TYPE")
+			testPipeline.env.Unsetenv("AZMON_AGENT_CFG_SCHEMA_VERSION")
+			testPipeline.paths.configMapDebugMountPath = ""
+			testPipeline.paths.debugModeEnvVarPath = ""
+			testPipeline.paths.replicaSetCollectorConfig = ""
 			fmt.Println("Cleaning up temp files")
 			cleanupTempFiles()
 		})
 	})
 
 	ginkgo.It("should handle a missing config map file", func() {
-		os.Setenv("CONTROLLER_TYPE", "ReplicaSet")
+		testPipeline.env.Setenv("CONTROLLER_TYPE", "ReplicaSet", false)
 		suffix := createRandomString(5)
 		ginkgo.DeferCleanup(func() {
 			cleanupTempFiles()
@@ -111,56 +115,71 @@ var _ = ginkgo.Describe("When parsing debug mode settings", func() {
 		err = os.Remove(fmt.Sprintf("temp/debug-mode-%s", suffix))
 		gomega.Expect(err).NotTo(gomega.HaveOccurred())
 
-		err = ConfigureDebugModeSettings()
+		err = testPipeline.ConfigureDebugModeSettings(nil)
 		gomega.Expect(err).To(gomega.HaveOccurred())
 		gomega.Expect(err.Error()).To(gomega.ContainSubstring("configmap section not mounted, using defaults"))
 	})
 
-	ginkgo.It("should handle an error while parsing config map", func() {
-		os.Setenv("CONTROLLER_TYPE", "ReplicaSet")
+	ginkgo.It("should disable debug mode for a non-boolean config map value", func() {
+		testPipeline.env.Setenv("CONTROLLER_TYPE", "ReplicaSet", false)
+		testPipeline.env.Setenv("AZMON_AGENT_CFG_SCHEMA_VERSION", "v1", false)
 		suffix := createRandomString(5)
 		ginkgo.DeferCleanup(func() {
+			testPipeline.env.Unsetenv("AZMON_AGENT_CFG_SCHEMA_VERSION")
 			cleanupTempFiles()
 		})
-		err := createTempFiles(suffix, `		[ invalid_key = true		`)
+		err := createTempFiles(suffix, "")
 		gomega.Expect(err).NotTo(gomega.HaveOccurred())
 
-		err = ConfigureDebugModeSettings()
-		gomega.Expect(err).To(gomega.HaveOccurred())
-		gomega.Expect(err.Error()).To(gomega.ContainSubstring("exception while parsing config map for debug mode"))
+		err = testPipeline.ConfigureDebugModeSettings(debugModeSections("invalid"))
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		content, err := os.ReadFile(testPipeline.paths.debugModeEnvVarPath)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(string(content)).To(gomega.Equal("DEBUG_MODE_ENABLED=false\n"))
 	})
 
 	ginkgo.It("should handle an error while opening environment variable file", func() {
-		os.Setenv("CONTROLLER_TYPE", "ReplicaSet")
+		testPipeline.env.Setenv("CONTROLLER_TYPE", "ReplicaSet", false)
+		testPipeline.env.Setenv("AZMON_AGENT_CFG_SCHEMA_VERSION", "v1", false)
 		suffix := createRandomString(5)
 		ginkgo.DeferCleanup(func() {
+			testPipeline.env.Unsetenv("AZMON_AGENT_CFG_SCHEMA_VERSION")
 			cleanupTempFiles()
 		})
 		err := createTempFiles(suffix, `enabled = true`)
 		gomega.Expect(err).NotTo(gomega.HaveOccurred())
-		debugModeEnvVarPath = "/nonexistant-path/envvarpath"
+		testPipeline.paths.debugModeEnvVarPath = "/nonexistant-path/envvarpath"
 
-		err = ConfigureDebugModeSettings()
+		err = testPipeline.ConfigureDebugModeSettings(debugModeSections("true"))
 		gomega.Expect(err).To(gomega.HaveOccurred())
 		gomega.Expect(err.Error()).To(gomega.ContainSubstring("Exception while opening file for writing prometheus-collector config environment variables"))
 	})
 
 	ginkgo.It("should handle an error while reading the replicaset collector config file", func() {
-		os.Setenv("CONTROLLER_TYPE", "ReplicaSet")
+		testPipeline.env.Setenv("CONTROLLER_TYPE", "ReplicaSet", false)
+		testPipeline.env.Setenv("AZMON_AGENT_CFG_SCHEMA_VERSION", "v1", false)
 		suffix := createRandomString(5)
 		ginkgo.DeferCleanup(func() {
+			testPipeline.env.Unsetenv("AZMON_AGENT_CFG_SCHEMA_VERSION")
 			cleanupTempFiles()
 		})
 		err := createTempFiles(suffix, `enabled = true`)
 		gomega.Expect(err).NotTo(gomega.HaveOccurred())
-		replicaSetCollectorConfig = "/nonexistant-path/replicasetconfig"
+		testPipeline.paths.replicaSetCollectorConfig = "/nonexistant-path/replicasetconfig"
 
-		err = ConfigureDebugModeSettings()
+		err = testPipeline.ConfigureDebugModeSettings(debugModeSections("true"))
 		gomega.Expect(err).To(gomega.HaveOccurred())
-		gomega.Expect(err.Error()).To(gomega.ContainSubstring("Exception while setting otlp in the exporter metrics for service pipeline when debug mode is enabled"))
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring("Exception while setting prometheus in the exporter metrics for service pipeline when debug mode is enabled"))
 	})
 })
 
+func debugModeSections(enabled string) map[string]map[string]string {
+	return map[string]map[string]string{
+		"debug-mode": {"enabled": enabled},
+	}
+}
+
 func parseYAMLConfigFile(filePath string) (map[string]interface{}, error) {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
@@ -182,9 +201,9 @@ func createTempFiles(suffix string, debugModeString string) error {
 		return err
 	}
 
-	configMapDebugMountPath = fmt.Sprintf("temp/debug-mode-%s", suffix)
-	debugModeEnvVarPath = fmt.Sprintf("temp/config_debug_mode_env_var-%s", suffix)
-	replicaSetCollectorConfig = fmt.Sprintf("temp/collector-config-replicaset-%s.yml", suffix)
+	testPipeline.paths.configMapDebugMountPath = fmt.Sprintf("temp/debug-mode-%s", suffix)
+	testPipeline.paths.debugModeEnvVarPath = fmt.Sprintf("temp/config_debug_mode_env_var-%s", suffix)
+	testPipeline.paths.replicaSetCollectorConfig = fmt.Sprintf("temp/collector-config-replicaset-%s.yml", suffix)
 	sourceFile := "testdata/collector-config-replicaset.yml"
 
 	source, err := os.Open(sourceFile)
@@ -192,7 +211,7 @@ func createTempFiles(suffix string, debugModeString string) error {
 		return err
 	}
 	defer source.Close()
-	destination, err := os.Create(replicaSetCollectorConfig)
+	destination, err := os.Create(testPipeline.paths.replicaSetCollectorConfig)
 	if err != nil {
 		return err
 	}
@@ -202,7 +221,7 @@ func createTempFiles(suffix string, debugModeString string) error {
 		return err
 	}
 
-	debugModeFile, err := os.Create(configMapDebugMountPath)
+	debugModeFile, err := os.Create(testPipeline.paths.configMapDebugMountPath)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,158 @@
+package shared
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ExternalLabelsPath is where the settings parser writes the labels of the external-labels section, read by the
+// config validator and the config reader
+const ExternalLabelsPath = "/opt/microsoft/configmapparser/config_external_labels"
+
+// reservedExternalLabels are set by the agent or by prometheus and cannot be used as external labels. The cluster
+// label is set with cluster_alias in the prometheus-collector-settings section.
+var reservedExternalLabels = []string{"cluster", "job", "instance", "le", "quantile"}
+
+var externalLabelNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ValidateExternalLabel returns an error when a label cannot be added to every series as an external label
+func ValidateExternalLabel(name, value string) error {
+	if !externalLabelNameRegex.MatchString(name) {
+		return fmt.Errorf("%q is not a valid label name", name)
+	}
+	if strings.HasPrefix(name, "__") {
+		return fmt.Errorf("label names starting with __ are reserved")
+	}
+	if slices.Contains(reservedExternalLabels, name) {
+		if name == "cluster" {
+			return fmt.Errorf("the cluster label is reserved, use cluster_alias in prometheus-collector-settings instead")
+		}
+		return fmt.Errorf("the %s label is reserved", name)
+	}
+	if len(name) > CustomScrapeLabelNameLengthLimit {
+		return fmt.Errorf("the label name is longer than %d characters", CustomScrapeLabelNameLengthLimit)
+	}
+	if value == "" {
+		return fmt.Errorf("the label value is empty")
+	}
+	if len(value) > CustomScrapeLabelValueLengthLimit {
+		return fmt.Errorf("the label value is longer than %d characters", CustomScrapeLabelValueLengthLimit)
+	}
+	return nil
+}
+
+// ReadExternalLabels reads the external labels written by the settings parser. No labels are returned when the file
+// does not exist.
+func ReadExternalLabels(path string) (map[string]string, error) {
+	contents, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var labels map[string]string
+	if err := yaml.Unmarshal(contents, &labels); err != nil {
+		return nil, err
+	}
+	for name, value := range labels {
+		if err := ValidateExternalLabel(name, value); err != nil {
+			return nil, fmt.Errorf("external label %s: %v", name, err)
+		}
+	}
+	return labels, nil
+}
+
+// ApplyExternalLabels upserts the external labels as attributes in the resource processor, next to the cluster label,
+// and adds them to the const labels of the prometheus exporter used in debug mode, so every series scraped by the
+// collector gets them.
+func ApplyExternalLabels(otelConfig *OtelConfig, labels map[string]string) error {
+	if len(labels) == 0 {
+		return nil
+	}
+	names := slices.Sorted(maps.Keys(labels))
+
+	processors, _ := otelConfig.Processors.(map[interface{}]interface{})
+	resourceProcessor, _ := processors["resource"].(map[interface{}]interface{})
+	if resourceProcessor == nil {
+		return fmt.Errorf("the collector config has no resource processor to add the external labels to")
+	}
+	attributes, _ := resourceProcessor["attributes"].([]interface{})
+	for _, name := range names {
+		// Labels set by a previous run are replaced
+		attributes = slices.DeleteFunc(attributes, func(attribute interface{}) bool {
+			attributeMap, _ := attribute.(map[interface{}]interface{})
+			return attributeMap["key"] == name
+		})
+	}
+	// Keep them next to the cluster label, or first when the config has none
+	index := slices.IndexFunc(attributes, func(attribute interface{}) bool {
+		attributeMap, _ := attribute.(map[interface{}]interface{})
+		return attributeMap["key"] == "cluster"
+	}) + 1
+	externalAttributes := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		externalAttributes = append(externalAttributes, map[interface{}]interface{}{
+			"key":    name,
+			"value":  escapeConfmapValue(labels[name]),
+			"action": "upsert",
+		})
+	}
+	resourceProcessor["attributes"] = slices.Insert(attributes, index, externalAttributes...)
+
+	exporters, _ := otelConfig.Exporters.(map[interface{}]interface{})
+	if prometheusExporter, ok := exporters["prometheus"].(map[interface{}]interface{}); ok {
+		constLabels, _ := prometheusExporter["const_labels"].(map[interface{}]interface{})
+		if constLabels == nil {
+			constLabels = make(map[interface{}]interface{})
+			prometheusExporter["const_labels"] = constLabels
+		}
+		for _, name := range names {
+			constLabels[name] = escapeConfmapValue(labels[name])
+		}
+	}
+	return nil
+}
+
+// escapeConfmapValue doubles every $ so a label value is not treated as env substitution by the otel confmap loader
+func escapeConfmapValue(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
+
+// ApplyExternalLabelsInCollectorConfig adds the external labels to a collector config file that is not generated by
+// the config validator, such as the replicaset config used with the target allocator.
+func ApplyExternalLabelsInCollectorConfig(configPath string, labels map[string]string) error {
+	if len(labels) == 0 {
+		return nil
+	}
+	return updateCollectorConfigFile(configPath, "external labels", func(otelConfig *OtelConfig) error {
+		return ApplyExternalLabels(otelConfig, labels)
+	})
+}
+
+// SetPrometheusExternalLabels sets the external labels in the global section of a prometheus config. The target
+// allocator does not add them to the targets, the collectors do, but its config then describes the same labels as the
+// collectors receiving the targets.
+func SetPrometheusExternalLabels(promConfig map[string]interface{}, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	global, _ := promConfig["global"].(map[interface{}]interface{})
+	if global == nil {
+		global = make(map[interface{}]interface{})
+		promConfig["global"] = global
+	}
+	externalLabels, _ := global["external_labels"].(map[interface{}]interface{})
+	if externalLabels == nil {
+		externalLabels = make(map[interface{}]interface{})
+		global["external_labels"] = externalLabels
+	}
+	for name, value := range labels {
+		externalLabels[name] = value
+	}
+}
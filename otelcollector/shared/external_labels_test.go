@@ -0,0 +1,133 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const testExternalLabelsOtelConfig = `exporters:
+  prometheus:
+    endpoint: "127.0.0.1:9091"
+    const_labels:
+      cluster: ${env:AZMON_CLUSTER_LABEL}
+processors:
+  resource:
+    attributes:
+      - key: cluster
+        value: "${env:AZMON_CLUSTER_LABEL}"
+        action: "upsert"
+      - key: job
+        from_attribute: service.name
+        action: insert
+receivers:
+  prometheus:
+    config: {}
+`
+
+func TestValidateExternalLabel(t *testing.T) {
+	tests := map[string]bool{
+		"environment": true,
+		"cost_center": true,
+		"_team":       true,
+		"cost-center": false,
+		"1region":     false,
+		"__region":    false,
+		"cluster":     false,
+		"job":         false,
+		"instance":    false,
+	}
+	for name, valid := range tests {
+		if err := ValidateExternalLabel(name, "value"); (err == nil) != valid {
+			t.Errorf("ValidateExternalLabel(%q) = %v, expected valid %v", name, err, valid)
+		}
+	}
+	if err := ValidateExternalLabel("environment", ""); err == nil {
+		t.Error("expected an error for an empty value")
+	}
+}
+
+func TestApplyExternalLabels(t *testing.T) {
+	var otelConfig OtelConfig
+	if err := yaml.Unmarshal([]byte(testExternalLabelsOtelConfig), &otelConfig); err != nil {
+		t.Fatalf("failed to parse otel config: %v", err)
+	}
+	labels := map[string]string{"region": "westus", "environment": "prod", "team": "a$b"}
+	if err := ApplyExternalLabels(&otelConfig, labels); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Applying twice must not duplicate the attributes
+	if err := ApplyExternalLabels(&otelConfig, labels); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	attributes := otelConfig.Processors.(map[interface{}]interface{})["resource"].(map[interface{}]interface{})["attributes"].([]interface{})
+	keys := []interface{}{}
+	for _, attribute := range attributes {
+		keys = append(keys, attribute.(map[interface{}]interface{})["key"])
+	}
+	if expected := []interface{}{"cluster", "environment", "region", "team", "job"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected attributes %v, got %v", expected, keys)
+	}
+	if value := attributes[3].(map[interface{}]interface{})["value"]; value != "a$$b" {
+		t.Errorf("expected the $ in the value to be escaped, got %v", value)
+	}
+
+	constLabels := otelConfig.Exporters.(map[interface{}]interface{})["prometheus"].(map[interface{}]interface{})["const_labels"].(map[interface{}]interface{})
+	if constLabels["environment"] != "prod" || constLabels["cluster"] != "${env:AZMON_CLUSTER_LABEL}" {
+		t.Errorf("unexpected const labels %v", constLabels)
+	}
+}
+
+func TestApplyExternalLabels_NoResourceProcessor(t *testing.T) {
+	otelConfig := OtelConfig{Processors: map[interface{}]interface{}{}}
+	if err := ApplyExternalLabels(&otelConfig, map[string]string{"environment": "prod"}); err == nil {
+		t.Fatal("expected an error without a resource processor")
+	}
+	if err := ApplyExternalLabels(&otelConfig, nil); err != nil {
+		t.Errorf("expected no error without labels, got %v", err)
+	}
+}
+
+func TestReadExternalLabels(t *testing.T) {
+	dir := t.TempDir()
+	labels, err := ReadExternalLabels(filepath.Join(dir, "missing"))
+	if err != nil || labels != nil {
+		t.Errorf("expected no labels for a missing file, got %v, %v", labels, err)
+	}
+
+	path := filepath.Join(dir, "labels")
+	if err := os.WriteFile(path, []byte("environment: prod\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	labels, err = ReadExternalLabels(path)
+	if err != nil || !reflect.DeepEqual(labels, map[string]string{"environment": "prod"}) {
+		t.Errorf("unexpected labels %v, %v", labels, err)
+	}
+
+	if err := os.WriteFile(path, []byte("job: prod\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadExternalLabels(path); err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("expected an error for a reserved label, got %v", err)
+	}
+}
+
+func TestSetPrometheusExternalLabels(t *testing.T) {
+	promConfig := parsePromConfig(t, "global:\n  scrape_interval: 30s\n  external_labels:\n    source: custom\n")
+	SetPrometheusExternalLabels(promConfig, map[string]string{"environment": "prod"})
+	expected := map[interface{}]interface{}{"source": "custom", "environment": "prod"}
+	if externalLabels := promConfig["global"].(map[interface{}]interface{})["external_labels"]; !reflect.DeepEqual(externalLabels, expected) {
+		t.Errorf("expected external labels %v, got %v", expected, externalLabels)
+	}
+
+	promConfig = parsePromConfig(t, "scrape_configs: []\n")
+	SetPrometheusExternalLabels(promConfig, map[string]string{"environment": "prod"})
+	if _, exists := promConfig["global"]; !exists {
+		t.Error("expected a global section to be added")
+	}
+}
@@ -88,6 +88,15 @@ var (
 		[]string{"computer", "release", "controller_type", "error"},
 	)
 
+	// promConfigValidationFindingsMetric is the number of findings in the prometheus config validation report per job and severity
+	promConfigValidationFindingsMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "prometheus_config_validation_findings",
+			Help: "Number of findings per job and severity from validating the custom prometheus config",
+		},
+		[]string{"computer", "release", "controller_type", "job", "severity"},
+	)
+
 	// --- OtelCol (sub-component) metrics ---
 
 	// otelcolReceivedRateMetric is what otelcol's receiver accepted per minute (= overall input)
@@ -154,6 +163,7 @@ func ExposePrometheusCollectorHealthMetrics() {
 	r.MustRegister(otelcolExportFailuresMetric)
 	// Config validation
 	r.MustRegister(invalidSettingsConfigMetric)
+	r.MustRegister(promConfigValidationFindingsMetric)
 
 	handler := promhttp.HandlerFor(r, promhttp.HandlerOpts{})
 	http.Handle("/metrics", handler)
@@ -189,6 +199,8 @@ func ExposePrometheusCollectorHealthMetrics() {
 			}
 			invalidSettingsConfigMetric.With(prometheus.Labels{"computer": computer, "release": helmReleaseName, "controller_type": controllerType, "error": settingsConfigErrorString}).Set(float64(isInvalidSettingsConfig))
 
+			setPromConfigValidationFindingsMetric(computer, helmReleaseName, controllerType)
+
 			MEDroppedMutex.Lock()
 			meDroppedMetric.With(prometheus.Labels{"computer": computer, "release": helmReleaseName, "controller_type": controllerType}).Add(MEDroppedCount)
 			MEDroppedCount = 0
@@ -217,3 +229,15 @@ func ExposePrometheusCollectorHealthMetrics() {
 		log.Printf("Error for Prometheus Collector Health endpoint: %s\n", err.Error())
 	}
 }
+
+// setPromConfigValidationFindingsMetric sets the findings metric from the latest report written by the config validator
+func setPromConfigValidationFindingsMetric(computer string, helmReleaseName string, controllerType string) {
+	promConfigValidationFindingsMetric.Reset()
+	report, err := ReadValidationReport(PromConfigValidatorReportPath)
+	if err != nil {
+		return
+	}
+	for _, finding := range report.Findings {
+		promConfigValidationFindingsMetric.With(prometheus.Labels{"computer": computer, "release": helmReleaseName, "controller_type": controllerType, "job": finding.Job, "severity": finding.Severity}).Inc()
+	}
+}
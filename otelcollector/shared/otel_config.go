@@ -28,6 +28,7 @@ type Config struct {
 	PrometheusCR            PrometheusCRConfig     `yaml:"prometheus_cr,omitempty"`
 	FilterStrategy          string                 `yaml:"filter_strategy,omitempty"`
 	HTTPS                   HTTPSServerConfig      `yaml:"https,omitempty"`
+	ProvenanceFile          string                 `yaml:"provenance_file,omitempty"`
 }
 
 type OtelConfig struct {
@@ -0,0 +1,198 @@
+package shared
+
+import (
+	"fmt"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// escapeDollarSigns doubles every $ so the otel confmap loader does not treat it as env substitution,
+// while still letting NODE_NAME and NODE_IP be resolved from the environment.
+func escapeDollarSigns(value string) string {
+	modified := strings.ReplaceAll(value, "$$", "$")
+	modified = strings.ReplaceAll(modified, "$", "$$")
+	// Doing the below since we dont want to substitute $ with $$ for env variables NODE_NAME and NODE_IP.
+	modified = strings.ReplaceAll(modified, "$$NODE_NAME", "${env:NODE_NAME}")
+	modified = strings.ReplaceAll(modified, "$$NODE_IP", "${env:NODE_IP}")
+	return modified
+}
+
+func escapeRelabelConfigs(relabelConfigs []interface{}) {
+	for _, relabelConfig := range relabelConfigs {
+		relabelConfig, ok := relabelConfig.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		// Adding this check here since regex can be boolean and the conversion will fail
+		if regexString, isString := relabelConfig["regex"].(string); isString {
+			relabelConfig["regex"] = escapeDollarSigns(regexString)
+		}
+		if replacement, isString := relabelConfig["replacement"].(string); isString {
+			relabelConfig["replacement"] = escapeDollarSigns(replacement)
+		}
+	}
+}
+
+func unescapeRelabelConfigs(relabelConfigs []interface{}) {
+	for _, relabelConfig := range relabelConfigs {
+		relabelConfig, ok := relabelConfig.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		if regexString, isString := relabelConfig["regex"].(string); isString {
+			relabelConfig["regex"] = strings.ReplaceAll(regexString, "$$", "$")
+		}
+		if replacement, isString := relabelConfig["replacement"].(string); isString {
+			relabelConfig["replacement"] = strings.ReplaceAll(replacement, "$$", "$")
+		}
+	}
+}
+
+// UnsupportedPrometheusFeatures returns the top level prometheus config sections that the collector does not support.
+func UnsupportedPrometheusFeatures(prometheusConfig map[string]interface{}) []string {
+	unsupportedFeatures := make([]string, 0, 4)
+	for _, feature := range []string{"remote_write", "remote_read", "rule_files", "alerting"} {
+		if prometheusConfig[feature] != nil {
+			unsupportedFeatures = append(unsupportedFeatures, feature)
+		}
+	}
+	return unsupportedFeatures
+}
+
+// IsGlobalSettingsConfigured returns true if the prometheus config sets any global setting other than the default scrape interval.
+func IsGlobalSettingsConfigured(prometheusConfig map[string]interface{}) bool {
+	globalSettings, ok := prometheusConfig["global"].(map[interface{}]interface{})
+	if !ok {
+		return false
+	}
+	scrapeInterval := globalSettings["scrape_interval"]
+	return (len(globalSettings) > 1) || (len(globalSettings) == 1 && scrapeInterval != "15s")
+}
+
+// GenerateOtelConfig embeds the prometheus config into the otel collector config template, escaping the
+// $ characters in relabel configs and substituting node env variables in static targets.
+func GenerateOtelConfig(prometheusConfig map[string]interface{}, otelConfigTemplate []byte, debugModeEnabled bool, ccpMetricsEnabled bool) (*OtelConfig, error) {
+	var otelConfig OtelConfig
+	if err := yaml.Unmarshal(otelConfigTemplate, &otelConfig); err != nil {
+		return nil, err
+	}
+
+	if scrapeConfigs, ok := prometheusConfig["scrape_configs"].([]interface{}); ok {
+		for _, scrapeConfig := range scrapeConfigs {
+			scrapeConfig, ok := scrapeConfig.(map[interface{}]interface{})
+			if !ok {
+				continue
+			}
+			if relabelConfigs, ok := scrapeConfig["relabel_configs"].([]interface{}); ok {
+				escapeRelabelConfigs(relabelConfigs)
+			}
+			if metricRelabelConfigs, ok := scrapeConfig["metric_relabel_configs"].([]interface{}); ok {
+				escapeRelabelConfigs(metricRelabelConfigs)
+			}
+			if staticConfigs, ok := scrapeConfig["static_configs"].([]interface{}); ok {
+				for _, staticConfig := range staticConfigs {
+					staticConfig, ok := staticConfig.(map[interface{}]interface{})
+					if !ok {
+						continue
+					}
+					targets, ok := staticConfig["targets"].([]interface{})
+					if !ok {
+						continue
+					}
+					for i, target := range targets {
+						if targetValue, isString := target.(string); isString {
+							modifiedtargetValue := strings.ReplaceAll(targetValue, "$$NODE_NAME", "$NODE_NAME")
+							modifiedtargetValue = strings.ReplaceAll(modifiedtargetValue, "$$NODE_IP", "$NODE_IP")
+							modifiedtargetValue = strings.ReplaceAll(modifiedtargetValue, "$NODE_NAME", "${env:NODE_NAME}")
+							modifiedtargetValue = strings.ReplaceAll(modifiedtargetValue, "$NODE_IP", "${env:NODE_IP}")
+							targets[i] = modifiedtargetValue
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Need this here even though it is present in the receiver's config validate method since we only do the $ manipulation for regex and replacement fields
+	// in scrape configs sections and the load method which is called before the validate method fails to unmarshal due to single $.
+	// Either approach will fail but the receiver's config load wont return the right error message
+	if unsupportedFeatures := UnsupportedPrometheusFeatures(prometheusConfig); len(unsupportedFeatures) != 0 {
+		return nil, fmt.Errorf("unsupported features:\n\t%s", strings.Join(unsupportedFeatures, "\n\t"))
+	}
+
+	otelConfig.Receivers.Prometheus.Config = prometheusConfig
+
+	if debugModeEnabled {
+		otelConfig.Service.Pipelines.Metrics.Exporters = []interface{}{"otlp_grpc", "prometheus"}
+		if !ccpMetricsEnabled {
+			otelConfig.Service.Pipelines.MetricsTelemetry.Receivers = []interface{}{"prometheus"}
+			otelConfig.Service.Pipelines.MetricsTelemetry.Exporters = []interface{}{"prometheus/telemetry"}
+			otelConfig.Service.Pipelines.MetricsTelemetry.Processors = []interface{}{"filter/telemetry"}
+		}
+	}
+
+	return &otelConfig, nil
+}
+
+// GetTargetAllocatorScrapeConfig returns the prometheus config from a generated otel collector config with the
+// $$ escaping added by the config validator removed. The $$ are required by the validator's otel get method,
+// but the TA doesnt do env substitution and hence needs them removed, else TA crashes.
+func GetTargetAllocatorScrapeConfig(otelConfigContents []byte) (map[string]interface{}, error) {
+	var otelConfig OtelConfig
+	if err := yaml.Unmarshal(otelConfigContents, &otelConfig); err != nil {
+		return nil, err
+	}
+
+	promScrapeConfig := otelConfig.Receivers.Prometheus.Config
+	if scrapeConfigs, ok := promScrapeConfig["scrape_configs"].([]interface{}); ok {
+		for _, scrapeConfig := range scrapeConfigs {
+			scrapeConfig, ok := scrapeConfig.(map[interface{}]interface{})
+			if !ok {
+				continue
+			}
+			if relabelConfigs, ok := scrapeConfig["relabel_configs"].([]interface{}); ok {
+				unescapeRelabelConfigs(relabelConfigs)
+			}
+			if metricRelabelConfigs, ok := scrapeConfig["metric_relabel_configs"].([]interface{}); ok {
+				unescapeRelabelConfigs(metricRelabelConfigs)
+			}
+		}
+	}
+	return promScrapeConfig, nil
+}
+
+// NewTargetAllocatorConfig returns the target allocator config used for the ama-metrics ReplicaSet collectors. The
+// topology-aware strategy and the replica recommendation of the target allocator are not used by ama-metrics, and are
+// only available to a standalone target allocator.
+func NewTargetAllocatorConfig(promScrapeConfig map[string]interface{}, httpsEnabled bool, secretsAccessNamespaces []string) Config {
+	targetAllocatorConfig := Config{
+		AllocationStrategy: "consistent-hashing",
+		FilterStrategy:     "relabel-config",
+		CollectorSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				"rsName":                         "ama-metrics",
+				"kubernetes.azure.com/managedby": "aks",
+			},
+		},
+		Config: promScrapeConfig,
+		// Copied next to the config by the config reader
+		ProvenanceFile: "/conf/" + TargetAllocatorConfigProvenanceFile,
+		PrometheusCR: PrometheusCRConfig{
+			ServiceMonitorSelector:  &metav1.LabelSelector{},
+			PodMonitorSelector:      &metav1.LabelSelector{},
+			SecretsAccessNamespaces: secretsAccessNamespaces,
+		},
+	}
+	if httpsEnabled {
+		targetAllocatorConfig.HTTPS = HTTPSServerConfig{
+			Enabled:         true,
+			ListenAddr:      ":8443",
+			TLSCertFilePath: "/etc/operator-targets/server/certs/server.crt",
+			TLSKeyFilePath:  "/etc/operator-targets/server/certs/server.key",
+			CAFilePath:      "/etc/operator-targets/server/certs/ca.crt",
+		}
+	}
+	return targetAllocatorConfig
+}
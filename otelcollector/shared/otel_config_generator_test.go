@@ -0,0 +1,96 @@
+package shared
+
+import (
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const testOtelConfigTemplate = `receivers:
+  prometheus:
+    config: {}
+service:
+  pipelines:
+    metrics:
+      receivers: [prometheus]
+      exporters: [otlp_grpc]
+`
+
+func parsePromConfig(t *testing.T, config string) map[string]interface{} {
+	t.Helper()
+	promConfig := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(config), &promConfig); err != nil {
+		t.Fatalf("failed to parse prometheus config: %v", err)
+	}
+	return promConfig
+}
+
+// TestGenerateOtelConfig_EscapesDollarSigns checks that relabel regex and replacement values are escaped for the
+// otel confmap loader, and that the escaping is undone for the target allocator.
+func TestGenerateOtelConfig_EscapesDollarSigns(t *testing.T) {
+	promConfig := parsePromConfig(t, `scrape_configs:
+- job_name: test
+  static_configs:
+  - targets: ["$NODE_IP:9100"]
+  relabel_configs:
+  - source_labels: [__address__]
+    regex: (.*)
+    replacement: $1
+    target_label: instance
+`)
+
+	otelConfig, err := GenerateOtelConfig(promConfig, []byte(testOtelConfigTemplate), false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	otelConfigYaml, err := yaml.Marshal(otelConfig)
+	if err != nil {
+		t.Fatalf("failed to marshal otel config: %v", err)
+	}
+	if !strings.Contains(string(otelConfigYaml), "replacement: $$1") {
+		t.Errorf("expected escaped replacement in otel config, got:\n%s", otelConfigYaml)
+	}
+	if !strings.Contains(string(otelConfigYaml), "${env:NODE_IP}:9100") {
+		t.Errorf("expected NODE_IP env substitution in static target, got:\n%s", otelConfigYaml)
+	}
+
+	taScrapeConfig, err := GetTargetAllocatorScrapeConfig(otelConfigYaml)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	relabelConfig := taScrapeConfig["scrape_configs"].([]interface{})[0].(map[interface{}]interface{})["relabel_configs"].([]interface{})[0].(map[interface{}]interface{})
+	if relabelConfig["replacement"] != "$1" {
+		t.Errorf("expected unescaped replacement for target allocator, got %v", relabelConfig["replacement"])
+	}
+}
+
+func TestGenerateOtelConfig_UnsupportedFeatures(t *testing.T) {
+	promConfig := parsePromConfig(t, `remote_write:
+- url: http://localhost
+rule_files: [rules.yml]
+scrape_configs: []
+`)
+
+	_, err := GenerateOtelConfig(promConfig, []byte(testOtelConfigTemplate), false, false)
+	if err == nil {
+		t.Fatal("expected an error for unsupported features")
+	}
+	if !strings.Contains(err.Error(), "remote_write") || !strings.Contains(err.Error(), "rule_files") {
+		t.Errorf("expected remote_write and rule_files in error, got: %v", err)
+	}
+}
+
+func TestIsGlobalSettingsConfigured(t *testing.T) {
+	tests := map[string]bool{
+		"scrape_configs: []":                             false,
+		"global:\n  scrape_interval: 15s":                false,
+		"global:\n  scrape_interval: 30s":                true,
+		"global:\n  external_labels:\n    cluster: test": true,
+	}
+	for config, expected := range tests {
+		if actual := IsGlobalSettingsConfigured(parsePromConfig(t, config)); actual != expected {
+			t.Errorf("IsGlobalSettingsConfigured(%q) = %v, expected %v", config, actual, expected)
+		}
+	}
+}